@@ -18,6 +18,7 @@ import (
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
@@ -25,7 +26,11 @@ import (
 
 	"robot-path-editor/internal/app"
 	"robot-path-editor/internal/config"
+	"robot-path-editor/internal/database"
+	"robot-path-editor/internal/repositories"
+	"robot-path-editor/internal/services"
 	"robot-path-editor/pkg/logger"
+	"robot-path-editor/pkg/secrets"
 )
 
 var (
@@ -36,6 +41,10 @@ var (
 
 	// 配置文件路径
 	configPath string
+
+	// 报告命令参数
+	reportOutput string
+	reportOpts   services.ReportOptions
 )
 
 func main() {
@@ -51,7 +60,7 @@ func main() {
 	rootCmd.PersistentFlags().StringVarP(&configPath, "config", "c", "./configs", "配置文件路径")
 	rootCmd.PersistentFlags().String("host", "0.0.0.0", "服务监听地址")
 	rootCmd.PersistentFlags().Int("port", 8080, "服务监听端口")
-	rootCmd.PersistentFlags().String("db-type", "sqlite", "数据库类型(sqlite, mysql)")
+	rootCmd.PersistentFlags().String("db-type", "sqlite", "数据库类型(sqlite, mysql, postgres)")
 	rootCmd.PersistentFlags().String("db-dsn", "data.db", "数据库连接字符串")
 	rootCmd.PersistentFlags().String("log-level", "info", "日志级别")
 
@@ -62,6 +71,30 @@ func main() {
 	viper.BindPFlag("database.dsn", rootCmd.PersistentFlags().Lookup("db-dsn"))
 	viper.BindPFlag("logger.level", rootCmd.PersistentFlags().Lookup("log-level"))
 
+	// 子命令：生成地图报告
+	reportCmd := &cobra.Command{
+		Use:   "report",
+		Short: "生成地图PDF报告",
+		Long:  `读取数据库中的节点和路径，生成包含地图、图例、站点坐标表、连通性和校验结果的PDF报告。`,
+		RunE:  runReport,
+	}
+	reportCmd.Flags().StringVarP(&reportOutput, "output", "o", "", "输出文件路径（默认按生成时间命名）")
+	reportCmd.Flags().StringVar(&reportOpts.Title, "title", "", "报告标题")
+	reportCmd.Flags().BoolVar(&reportOpts.Labels, "labels", false, "地图上标注节点名称")
+	reportCmd.Flags().BoolVar(&reportOpts.FlipY, "flip-y", false, "编辑器坐标Y轴向上时翻转地图")
+	rootCmd.AddCommand(reportCmd)
+
+	// 子命令：轮换主密钥
+	rotateKeyCmd := &cobra.Command{
+		Use:   "rotate-key",
+		Short: "用当前主密钥重新加密数据库连接密码",
+		Long: `把明文保存和用历史密钥（security.previous_master_keys）加密的数据库连接密码、敏感连接属性改用当前主密钥加密。
+轮换步骤：把旧主密钥移到 previous_master_keys，设置新的 master_key，运行本命令后即可删除旧密钥。
+未配置 master_key 时把密文解密为明文保存。`,
+		RunE: runRotateKey,
+	}
+	rootCmd.AddCommand(rotateKeyCmd)
+
 	// 执行命令
 	if err := rootCmd.Execute(); err != nil {
 		fmt.Fprintf(os.Stderr, "启动失败: %v\n", err)
@@ -118,6 +151,85 @@ func runServer(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
+	// 6. 优雅关闭 - 等待进行中的请求和后台同步任务结束
+	shutdownTimeout := cfg.Server.ShutdownTimeout
+	if shutdownTimeout <= 0 {
+		shutdownTimeout = 30 * time.Second
+	}
+	stopCtx, stopCancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer stopCancel()
+	if err := application.Stop(stopCtx); err != nil {
+		log.WithError(err).Error("服务器关闭错误")
+		return err
+	}
+
 	log.Info("服务器已优雅关闭")
 	return nil
 }
+
+// runReport 生成地图报告并写入文件
+func runReport(cmd *cobra.Command, args []string) error {
+	cfg, err := config.Load(configPath)
+	if err != nil {
+		return fmt.Errorf("加载配置失败: %w", err)
+	}
+	logger.Init(cfg.Logger)
+
+	db, err := database.New(cfg.Database)
+	if err != nil {
+		return fmt.Errorf("连接数据库失败: %w", err)
+	}
+	defer db.Close()
+
+	reportService := services.NewReportService(
+		repositories.NewNodeRepository(db),
+		repositories.NewPathRepository(db),
+	)
+	report, err := reportService.GenerateMapReport(cmd.Context(), reportOpts)
+	if err != nil {
+		return err
+	}
+
+	output := reportOutput
+	if output == "" {
+		output = report.Filename
+	}
+	if err := os.WriteFile(output, report.Data, 0o644); err != nil {
+		return fmt.Errorf("写入报告失败: %w", err)
+	}
+
+	fmt.Printf("报告已生成: %s（节点 %d，路径 %d，问题 %d）\n",
+		output, report.Summary.NodeCount, report.Summary.PathCount, len(report.Summary.Issues))
+	return nil
+}
+
+// runRotateKey 重新加密全部数据库连接的敏感信息
+func runRotateKey(cmd *cobra.Command, args []string) error {
+	cfg, err := config.Load(configPath)
+	if err != nil {
+		return fmt.Errorf("加载配置失败: %w", err)
+	}
+	logger.Init(cfg.Logger)
+
+	keyring, err := secrets.NewKeyring(cfg.Security.MasterKey, cfg.Security.PreviousMasterKeys)
+	if err != nil {
+		return fmt.Errorf("初始化主密钥失败: %w", err)
+	}
+
+	db, err := database.New(cfg.Database)
+	if err != nil {
+		return fmt.Errorf("连接数据库失败: %w", err)
+	}
+	defer db.Close()
+
+	rotated, err := repositories.NewDatabaseConnectionRepository(db, keyring).RotateSecrets(cmd.Context())
+	if keyring.Enabled() {
+		fmt.Printf("已用当前主密钥重新加密 %d 个数据库连接\n", rotated)
+	} else {
+		fmt.Printf("未配置主密钥，已把 %d 个数据库连接的敏感信息改为明文保存\n", rotated)
+	}
+	if err != nil {
+		return fmt.Errorf("重新加密失败: %w", err)
+	}
+	return nil
+}
@@ -13,6 +13,7 @@ package app
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/http"
 	"time"
@@ -27,6 +28,7 @@ import (
 	"robot-path-editor/internal/services"
 	"robot-path-editor/pkg/logger"
 	"robot-path-editor/pkg/middleware"
+	"robot-path-editor/pkg/secrets"
 	"robot-path-editor/web"
 )
 
@@ -53,6 +55,7 @@ type Application struct {
 	layoutService   services.LayoutService
 	pluginService   services.PluginService
 	databaseService services.DatabaseService
+	syncJobService  services.SyncJobService
 
 	// HTTP处理器
 	handlers *handlers.Handlers
@@ -76,9 +79,22 @@ func New(cfg *config.Config) (*Application, error) {
 	var pathRepo repositories.PathRepository
 	var dbConnRepo repositories.DatabaseConnectionRepository
 	var tableMappingRepo repositories.TableMappingRepository
+	var syncSnapshotRepo repositories.SyncSnapshotRepository
+	var syncQuarantineRepo repositories.SyncQuarantineRepository
+	var syncJobRepo repositories.SyncJobRepository
 	var templateRepo repositories.TemplateRepository
+	var mapRepo repositories.OccupancyMapRepository
 	var db database.Database
 
+	// 数据库连接密码等敏感信息用主密钥加密保存
+	keyring, err := secrets.NewKeyring(cfg.Security.MasterKey, cfg.Security.PreviousMasterKeys)
+	if err != nil {
+		return nil, fmt.Errorf("初始化主密钥失败: %w", err)
+	}
+	if !keyring.Enabled() {
+		log.Warn("未配置主密钥（security.master_key），数据库连接密码将明文保存")
+	}
+
 	// 尝试初始化数据库
 	database, err := database.New(cfg.Database)
 	if err != nil {
@@ -91,16 +107,33 @@ func New(cfg *config.Config) (*Application, error) {
 		pathRepo = nil
 		dbConnRepo = nil
 		tableMappingRepo = nil
+		syncSnapshotRepo = nil
+		syncQuarantineRepo = nil
+		syncJobRepo = nil
 		templateRepo = nil
+		mapRepo = nil
 		db = nil
 	} else {
 		// 使用数据库仓储
 		nodeRepo = repositories.NewNodeRepository(database)
 		pathRepo = repositories.NewPathRepository(database)
-		dbConnRepo = repositories.NewDatabaseConnectionRepository(database)
+		dbConnRepo = repositories.NewDatabaseConnectionRepository(database, keyring)
 		tableMappingRepo = repositories.NewTableMappingRepository(database)
+		syncSnapshotRepo = repositories.NewSyncSnapshotRepository(database)
+		syncQuarantineRepo = repositories.NewSyncQuarantineRepository(database)
+		syncJobRepo = repositories.NewSyncJobRepository(database)
 		templateRepo = repositories.NewTemplateRepository(database)
+		mapRepo = repositories.NewOccupancyMapRepository(database)
 		db = database
+
+		// 把明文和轮换前密钥加密的敏感信息改用当前主密钥加密；失败时（如缺少历史密钥）不影响其他功能
+		rotated, err := dbConnRepo.RotateSecrets(context.Background())
+		if rotated > 0 {
+			log.WithField("connections", rotated).Info("已重新加密数据库连接的敏感信息")
+		}
+		if err != nil {
+			log.WithError(err).Error("重新加密数据库连接失败，请检查主密钥和历史密钥配置")
+		}
 	}
 
 	// 3. 初始化业务服务层
@@ -110,7 +143,20 @@ func New(cfg *config.Config) (*Application, error) {
 	var pluginService services.PluginService
 	var databaseService services.DatabaseService
 	var dataSyncService services.DataSyncService
+	var syncJobService services.SyncJobService
+	var tableEditorService services.TableEditorService
 	var templateService services.TemplateService
+	var poseInterpolationService services.PoseInterpolationService
+	var robotProgramService services.RobotProgramService
+	var gcodeService services.GCodeService
+	var vda5050Service services.VDA5050Service
+	var nav2Service services.Nav2Service
+	var occupancyMapService services.OccupancyMapService
+	var dxfService services.DXFService
+	var spreadsheetService services.SpreadsheetService
+	var graphService services.GraphService
+	var renderService services.RenderService
+	var reportService services.ReportService
 
 	if pathRepo == nil {
 		// 如果使用内存模式，创建简化的服务
@@ -120,16 +166,48 @@ func New(cfg *config.Config) (*Application, error) {
 		pluginService = services.NewPluginService()
 		databaseService = &services.MockDatabaseService{}
 		dataSyncService = &services.MockDataSyncService{}
+		syncJobService = &services.MockSyncJobService{}
+		tableEditorService = &services.MockTableEditorService{}
 		templateService = &services.MockTemplateService{}
+		poseInterpolationService = &services.MockPoseInterpolationService{}
+		robotProgramService = &services.MockRobotProgramService{}
+		gcodeService = &services.MockGCodeService{}
+		vda5050Service = &services.MockVDA5050Service{}
+		nav2Service = &services.MockNav2Service{}
+		occupancyMapService = &services.MockOccupancyMapService{}
+		dxfService = &services.MockDXFService{}
+		spreadsheetService = &services.MockSpreadsheetService{}
+		graphService = &services.MockGraphService{}
+		renderService = &services.MockRenderService{}
+		reportService = &services.MockReportService{}
 	} else {
 		// 内存模式下的简化服务
 		nodeService = services.NewNodeService(nodeRepo, pathRepo)
 		pathService = services.NewPathService(pathRepo, nodeRepo)
 		layoutService = services.NewLayoutService()
 		pluginService = services.NewPluginService()
-		databaseService = services.NewDatabaseService(dbConnRepo, tableMappingRepo)
-		dataSyncService = services.NewDataSyncService(dbConnRepo, tableMappingRepo, nodeRepo, pathRepo)
-		templateService = services.NewTemplateService(templateRepo, nodeRepo, pathRepo)
+		// 外部 SQLite 连接不能打开应用自身的数据库文件
+		appSQLite := ""
+		if cfg.Database.Type == "sqlite" {
+			appSQLite = cfg.Database.DSN
+		}
+		connector := services.NewExternalConnector(cfg.Security.CertificateDir, cfg.Security.SQLiteDir, appSQLite)
+		databaseService = services.NewDatabaseService(dbConnRepo, tableMappingRepo, connector)
+		dataSyncService = services.NewDataSyncService(dbConnRepo, tableMappingRepo, syncSnapshotRepo, syncQuarantineRepo, nodeRepo, pathRepo, repositories.NewTransactor(db), connector)
+		syncJobService = services.NewSyncJobService(syncJobRepo, tableMappingRepo, dataSyncService)
+		tableEditorService = services.NewTableEditorService(dbConnRepo, connector)
+		templateService = services.NewTemplateService(templateRepo, nodeRepo, pathRepo, repositories.NewTransactor(db))
+		poseInterpolationService = services.NewPoseInterpolationService(nodeRepo, pathRepo)
+		robotProgramService = services.NewRobotProgramService(nodeRepo, pathRepo)
+		gcodeService = services.NewGCodeService(nodeRepo, pathRepo)
+		vda5050Service = services.NewVDA5050Service(nodeRepo, pathRepo)
+		nav2Service = services.NewNav2Service(nodeRepo, pathRepo)
+		occupancyMapService = services.NewOccupancyMapService(mapRepo, nodeRepo, pathRepo)
+		dxfService = services.NewDXFService(nodeRepo, pathRepo)
+		spreadsheetService = services.NewSpreadsheetService(nodeRepo, pathRepo)
+		graphService = services.NewGraphService(nodeRepo, pathRepo)
+		renderService = services.NewRenderService(nodeRepo, pathRepo, templateRepo)
+		reportService = services.NewReportService(nodeRepo, pathRepo)
 	}
 
 	// 4. 初始化处理器层 - API接口
@@ -139,7 +217,20 @@ func New(cfg *config.Config) (*Application, error) {
 		layoutService,
 		databaseService,
 		dataSyncService,
+		syncJobService,
+		tableEditorService,
 		templateService,
+		poseInterpolationService,
+		robotProgramService,
+		gcodeService,
+		vda5050Service,
+		nav2Service,
+		occupancyMapService,
+		dxfService,
+		spreadsheetService,
+		graphService,
+		renderService,
+		reportService,
 	)
 
 	// 5. 创建HTTP服务器
@@ -162,6 +253,7 @@ func New(cfg *config.Config) (*Application, error) {
 	app.layoutService = layoutService
 	app.pluginService = pluginService
 	app.databaseService = databaseService
+	app.syncJobService = syncJobService
 	app.handlers = handlers
 	app.router = router
 	app.server = server
@@ -180,6 +272,11 @@ func New(cfg *config.Config) (*Application, error) {
 func (a *Application) Start(ctx context.Context) error {
 	a.log.Info("启动应用程序...")
 
+	// 启动后台同步任务调度
+	if err := a.syncJobService.Start(ctx); err != nil {
+		return fmt.Errorf("启动同步任务调度失败: %w", err)
+	}
+
 	// 启动后台服务
 	go func() {
 		if err := a.server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
@@ -197,18 +294,30 @@ func (a *Application) Start(ctx context.Context) error {
 func (a *Application) Stop(ctx context.Context) error {
 	a.log.Info("停止应用程序...")
 
-	// 1. 停止HTTP服务器
+	// 1. 停止HTTP服务器；失败时仍继续停止后台任务和关闭数据库
+	var errs []error
 	if err := a.server.Shutdown(ctx); err != nil {
 		a.log.WithError(err).Error("HTTP服务器关闭失败")
-		return err
+		errs = append(errs, fmt.Errorf("关闭HTTP服务器失败: %w", err))
 	}
 
-	// 2. 关闭数据库连接
-	if err := a.db.Close(); err != nil {
-		a.log.WithError(err).Error("数据库关闭失败")
-		return err
+	// 2. 停止后台同步任务，等待正在进行的运行记录结果
+	if err := a.syncJobService.Stop(ctx); err != nil {
+		a.log.WithError(err).Error("同步任务调度停止失败")
+		errs = append(errs, fmt.Errorf("停止同步任务调度失败: %w", err))
 	}
 
+	// 3. 关闭数据库连接
+	if a.db != nil {
+		if err := a.db.Close(); err != nil {
+			a.log.WithError(err).Error("数据库关闭失败")
+			errs = append(errs, fmt.Errorf("关闭数据库失败: %w", err))
+		}
+	}
+
+	if len(errs) > 0 {
+		return errors.Join(errs...)
+	}
 	a.log.Info("应用程序已停止")
 	return nil
 }
@@ -265,6 +374,75 @@ func (a *Application) setupRoutes() error {
 			paths.PUT("/:id", a.handlers.UpdatePath)
 			paths.DELETE("/:id", a.handlers.DeletePath)
 			paths.GET("/node/:nodeId", a.handlers.GetPathsByNode)
+			paths.POST("/:id/interpolate", a.handlers.InterpolatePath)
+		}
+
+		// 路线相关处理器
+		routes := api.Group("/routes")
+		{
+			routes.POST("/interpolate", a.handlers.InterpolateRoute)
+			routes.GET("/export/robot-program/dialects", a.handlers.ListRobotDialects)
+			routes.POST("/export/robot-program", a.handlers.ExportRobotProgram)
+			routes.POST("/export/gcode", a.handlers.ExportGCode)
+			routes.POST("/export/vda5050", a.handlers.ExportVDA5050Order)
+			routes.POST("/export/vda5050/validate", a.handlers.ValidateVDA5050Order)
+			routes.POST("/export/nav-path", a.handlers.ExportNavPath)
+		}
+
+		// ROS 2 Nav2 路由图
+		nav2 := api.Group("/nav2")
+		{
+			nav2.GET("/graph", a.handlers.ExportNav2Graph)
+			nav2.POST("/graph", a.handlers.ImportNav2Graph)
+		}
+
+		// CAD 图纸
+		dxf := api.Group("/dxf")
+		{
+			dxf.POST("/import", a.handlers.ImportDXF)
+			dxf.POST("/export", a.handlers.ExportDXF)
+		}
+
+		// 表格导入导出
+		tables := api.Group("/tables")
+		{
+			tables.GET("/export", a.handlers.ExportTables)
+			tables.POST("/import", a.handlers.ImportTables)
+		}
+
+		// 图格式（GraphML、GEXF、DOT）
+		graphs := api.Group("/graphs")
+		{
+			graphs.GET("/export", a.handlers.ExportGraph)
+			graphs.POST("/import", a.handlers.ImportGraph)
+		}
+
+		// 地图渲染（SVG、PNG）
+		render := api.Group("/render")
+		{
+			render.GET("/map", a.handlers.RenderMap)
+		}
+
+		// 地图报告（PDF）
+		reports := api.Group("/reports")
+		{
+			reports.GET("/map", a.handlers.GenerateMapReport)
+		}
+
+		// 占据栅格地图
+		maps := api.Group("/maps")
+		{
+			maps.GET("", a.handlers.ListMaps)
+			maps.POST("", a.handlers.ImportMap)
+			maps.GET("/validate", a.handlers.ValidateMapLayout)
+			maps.GET("/:id", a.handlers.GetMap)
+			maps.DELETE("/:id", a.handlers.DeleteMap)
+			maps.PUT("/:id/activate", a.handlers.ActivateMap)
+			maps.GET("/:id/image", a.handlers.GetMapImage)
+			maps.GET("/:id/tiles/:z/:x/:y", a.handlers.GetMapTile)
+			maps.GET("/:id/obstacles", a.handlers.GetMapObstacles)
+			maps.POST("/:id/obstacles", a.handlers.RegenerateMapObstacles)
+			maps.GET("/:id/validate", a.handlers.ValidateMapLayout)
 		}
 
 		// 布局算法
@@ -294,6 +472,13 @@ func (a *Application) setupRoutes() error {
 			db.PUT("/connections/:id", a.handlers.UpdateDatabaseConnection)
 			db.DELETE("/connections/:id", a.handlers.DeleteDatabaseConnection)
 			db.POST("/connections/:id/test", a.handlers.TestDatabaseConnection)
+
+			// 外部表浏览和编辑
+			db.GET("/connections/:id/schemas", a.handlers.ListExternalSchemas)
+			db.GET("/connections/:id/tables", a.handlers.ListExternalTables)
+			db.GET("/connections/:id/tables/:table", a.handlers.DescribeExternalTable)
+			db.POST("/connections/:id/tables/:table/rows/query", a.handlers.QueryExternalRows)
+			db.POST("/connections/:id/tables/:table/rows", a.handlers.WriteExternalRows)
 		}
 
 		// 表映射管理
@@ -319,7 +504,25 @@ func (a *Application) setupRoutes() error {
 			sync.POST("/mappings/:mappingId/nodes", a.handlers.SyncNodesFromExternal)
 			sync.POST("/mappings/:mappingId/paths", a.handlers.SyncPathsFromExternal)
 			sync.POST("/mappings/:mappingId/all", a.handlers.SyncAllDataFromExternal)
+			sync.GET("/mappings/:mappingId/plan", a.handlers.PlanSync)
+			sync.POST("/mappings/:mappingId/apply", a.handlers.ApplySyncPlan)
+			sync.POST("/mappings/:mappingId/push", a.handlers.PushToExternal)
+			sync.GET("/mappings/:mappingId/quarantine", a.handlers.ListSyncQuarantine)
+			sync.DELETE("/mappings/:mappingId/quarantine/:pathId", a.handlers.DiscardSyncQuarantine)
 			sync.GET("/validate-table", a.handlers.ValidateExternalTable)
+
+			// 后台同步任务
+			sync.GET("/jobs", a.handlers.ListSyncJobs)
+			sync.POST("/jobs", a.handlers.CreateSyncJob)
+			sync.GET("/jobs/:id", a.handlers.GetSyncJob)
+			sync.PUT("/jobs/:id", a.handlers.UpdateSyncJob)
+			sync.DELETE("/jobs/:id", a.handlers.DeleteSyncJob)
+			sync.POST("/jobs/:id/trigger", a.handlers.TriggerSyncJob)
+			sync.POST("/jobs/:id/pause", a.handlers.PauseSyncJob)
+			sync.POST("/jobs/:id/resume", a.handlers.ResumeSyncJob)
+			sync.POST("/jobs/:id/cancel", a.handlers.CancelSyncJob)
+			sync.GET("/jobs/:id/progress", a.handlers.WatchSyncJob)
+			sync.GET("/jobs/:id/runs", a.handlers.ListSyncJobRuns)
 		}
 
 		// 模板相关处理器
@@ -337,8 +540,15 @@ func (a *Application) setupRoutes() error {
 			templates.POST("/:id/apply", a.handlers.ApplyTemplate)
 			templates.POST("/:id/clone", a.handlers.CloneTemplate)
 			templates.GET("/:id/export", a.handlers.ExportTemplate)
+			templates.GET("/:id/render", a.handlers.RenderTemplate)
+			templates.GET("/:id/revisions", a.handlers.ListTemplateRevisions)
+			templates.GET("/:id/revisions/:revision", a.handlers.GetTemplateRevision)
+			templates.POST("/:id/revisions/:revision/restore", a.handlers.RestoreTemplateRevision)
+			templates.GET("/:id/diff", a.handlers.DiffTemplateRevisions)
 			templates.POST("/import", a.handlers.ImportTemplate)
 			templates.POST("/save-as", a.handlers.SaveAsTemplate)
+			templates.GET("/pack/export", a.handlers.ExportTemplatePack)
+			templates.POST("/pack/import", a.handlers.ImportTemplatePack)
 		}
 	}
 
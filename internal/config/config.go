@@ -28,6 +28,7 @@ type Config struct {
 	Logger   LoggerConfig   `mapstructure:"logger"`
 	Canvas   CanvasConfig   `mapstructure:"canvas"`
 	Metrics  MetricsConfig  `mapstructure:"metrics"`
+	Security SecurityConfig `mapstructure:"security"`
 }
 
 // ServerConfig HTTP服务器配置
@@ -87,6 +88,15 @@ type MetricsConfig struct {
 	Path    string `mapstructure:"path"`    // 监控路径
 }
 
+// SecurityConfig 安全配置
+// 主密钥用于加密数据库连接的密码等敏感信息，建议通过环境变量 ROBOT_PATH_SECURITY_MASTER_KEY 提供
+type SecurityConfig struct {
+	MasterKey          string   `mapstructure:"master_key"`           // 主密钥：32字节的base64或十六进制编码，为空时不加密
+	PreviousMasterKeys []string `mapstructure:"previous_master_keys"` // 轮换前的密钥，只用于解密
+	CertificateDir     string   `mapstructure:"certificate_dir"`      // 外部数据库连接的证书和私钥目录，连接属性只能引用其中的文件
+	SQLiteDir          string   `mapstructure:"sqlite_dir"`           // 外部 SQLite 连接的数据库文件目录，连接只能打开其中已存在的文件
+}
+
 // Load 加载配置
 // 参考Viper的最佳实践和Grafana的配置加载流程
 func Load(configPath string) (*Config, error) {
@@ -171,6 +181,12 @@ func setDefaults() {
 	viper.SetDefault("metrics.enabled", false)
 	viper.SetDefault("metrics.port", 9090)
 	viper.SetDefault("metrics.path", "/metrics")
+
+	// 安全配置默认值 - 设置后环境变量才能覆盖
+	viper.SetDefault("security.master_key", "")
+	viper.SetDefault("security.previous_master_keys", []string{})
+	viper.SetDefault("security.certificate_dir", "")
+	viper.SetDefault("security.sqlite_dir", "")
 }
 
 // Validate 验证配置的有效性
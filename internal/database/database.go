@@ -18,6 +18,7 @@ import (
 	"time"
 
 	"gorm.io/driver/mysql"
+	"gorm.io/driver/postgres"
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
@@ -41,6 +42,10 @@ type Database interface {
 	// 数据库操作
 	AutoMigrate(dst ...interface{}) error
 	Transaction(ctx context.Context, fn func(tx interface{}) error) error
+
+	// 跨仓储事务：fn 收到的上下文携带事务，仓储通过 Session 取得的会话自动加入该事务
+	WithinTransaction(ctx context.Context, fn func(ctx context.Context) error) error
+	Session(ctx context.Context) *gorm.DB
 }
 
 // database 数据库实现
@@ -72,6 +77,8 @@ func (d *database) Connect() error {
 		dialector = sqlite.Open(d.config.DSN)
 	case "mysql":
 		dialector = mysql.Open(d.config.DSN)
+	case "postgres":
+		dialector = postgres.Open(d.config.DSN)
 	default:
 		return fmt.Errorf("不支持的数据库类型: %s", d.config.Type)
 	}
@@ -114,7 +121,13 @@ func (d *database) Connect() error {
 		&domain.Path{},
 		&domain.DatabaseConnection{},
 		&domain.TableMapping{},
+		&domain.SyncSnapshot{},
+		&domain.SyncQuarantine{},
+		&domain.SyncJob{},
+		&domain.SyncJobRun{},
 		&domain.Template{},
+		&domain.TemplateRevision{},
+		&domain.OccupancyMap{},
 	); err != nil {
 		return fmt.Errorf("数据库迁移失败: %w", err)
 	}
@@ -169,13 +182,35 @@ func (d *database) AutoMigrate(dst ...interface{}) error {
 	return d.db.AutoMigrate(dst...)
 }
 
-// Transaction 执行事务
+// Transaction 执行事务，上下文中已有事务时作为保存点嵌套执行
 func (d *database) Transaction(ctx context.Context, fn func(tx interface{}) error) error {
 	if d.db == nil {
 		return fmt.Errorf("数据库未初始化")
 	}
 
-	return d.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+	return d.Session(ctx).Transaction(func(tx *gorm.DB) error {
 		return fn(tx)
 	})
 }
+
+// txContextKey 上下文中事务的键
+type txContextKey struct{}
+
+// WithinTransaction 在事务中执行 fn，fn 返回错误时整体回滚
+func (d *database) WithinTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	if d.db == nil {
+		return fmt.Errorf("数据库未初始化")
+	}
+
+	return d.Session(ctx).Transaction(func(tx *gorm.DB) error {
+		return fn(context.WithValue(ctx, txContextKey{}, tx))
+	})
+}
+
+// Session 返回绑定上下文的会话，上下文中有事务时返回该事务
+func (d *database) Session(ctx context.Context) *gorm.DB {
+	if tx, ok := ctx.Value(txContextKey{}).(*gorm.DB); ok {
+		return tx.WithContext(ctx)
+	}
+	return d.db.WithContext(ctx)
+}
@@ -0,0 +1,65 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"robot-path-editor/internal/config"
+	"robot-path-editor/internal/domain"
+	"robot-path-editor/internal/testutil"
+)
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  func(t *testing.T) config.DatabaseConfig
+	}{
+		{"sqlite", func(t *testing.T) config.DatabaseConfig {
+			return config.DatabaseConfig{Type: "sqlite", DSN: filepath.Join(t.TempDir(), "test.db")}
+		}},
+		{"postgres", func(t *testing.T) config.DatabaseConfig {
+			return config.DatabaseConfig{Type: "postgres", DSN: testutil.Postgres(t).DSN(), MaxOpenConns: 4, MaxIdleConns: 2}
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, err := New(tt.cfg(t))
+			if err != nil {
+				t.Fatalf("New() error = %v", err)
+			}
+			defer db.Close()
+
+			// 迁移后的表可读写，事务回滚后不留下数据
+			ctx := context.Background()
+			node := domain.NewNode("dock", "point")
+			if err := db.Session(ctx).Create(node).Error; err != nil {
+				t.Fatalf("写入节点失败: %v", err)
+			}
+			rollback := errors.New("rollback")
+			err = db.WithinTransaction(ctx, func(ctx context.Context) error {
+				if err := db.Session(ctx).Delete(&domain.Node{}, "id = ?", node.ID).Error; err != nil {
+					return err
+				}
+				return rollback
+			})
+			if !errors.Is(err, rollback) {
+				t.Fatalf("WithinTransaction() error = %v, want %v", err, rollback)
+			}
+			var count int64
+			if err := db.Session(ctx).Model(&domain.Node{}).Where("id = ?", node.ID).Count(&count).Error; err != nil {
+				t.Fatalf("查询节点失败: %v", err)
+			}
+			if count != 1 {
+				t.Errorf("回滚后节点数 = %d, want 1", count)
+			}
+		})
+	}
+}
+
+func TestNewUnsupportedType(t *testing.T) {
+	if _, err := New(config.DatabaseConfig{Type: "oracle"}); err == nil {
+		t.Fatal("New() 不支持的类型应返回错误")
+	}
+}
@@ -50,6 +50,16 @@ func (db *memoryDatabase) Transaction(ctx context.Context, fn func(tx interface{
 	return fn(db)
 }
 
+// WithinTransaction 跨仓储事务（内存数据库直接执行）
+func (db *memoryDatabase) WithinTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	return fn(ctx)
+}
+
+// Session 返回nil（内存数据库不使用GORM）
+func (db *memoryDatabase) Session(ctx context.Context) *gorm.DB {
+	return nil
+}
+
 // DB 返回nil（内存数据库不需要GORM）
 func (db *memoryDatabase) DB() interface{} {
 	return nil
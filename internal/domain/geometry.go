@@ -0,0 +1,356 @@
+// Package domain 路径曲线几何
+//
+// 路径的几何形状由起点、Waypoints 和终点共同决定：
+// - linear：依次经过各途经点的折线
+// - bezier：以起点、途经点、终点为控制点的单段贝塞尔曲线
+// - spline：经过起点、途经点、终点的 Catmull-Rom 样条
+// - arc：经过起点、第一个途经点、终点的圆弧（XY平面）
+package domain
+
+import "math"
+
+// ControlPoints 返回路径的控制点序列：起点、途经点、终点
+func (p *Path) ControlPoints(start, end Position) []Position {
+	points := make([]Position, 0, len(p.Waypoints)+2)
+	points = append(points, start)
+	points = append(points, p.Waypoints...)
+	points = append(points, end)
+	return points
+}
+
+// Arc XY平面上的圆弧，Z沿弧长线性变化
+type Arc struct {
+	Start     Position `json:"start"`
+	End       Position `json:"end"`
+	Center    Position `json:"center"`
+	Radius    float64  `json:"radius"`
+	Clockwise bool     `json:"clockwise"`
+	Sweep     float64  `json:"sweep"` // 扫过的角度（弧度，正值）
+}
+
+// ArcThrough 求经过三点的圆弧（按 XY 投影计算），三点共线时返回 false
+func ArcThrough(a, b, c Position) (Arc, bool) {
+	d := 2 * (a.X*(b.Y-c.Y) + b.X*(c.Y-a.Y) + c.X*(a.Y-b.Y))
+	if math.Abs(d) < 1e-9 {
+		return Arc{}, false
+	}
+
+	a2 := a.X*a.X + a.Y*a.Y
+	b2 := b.X*b.X + b.Y*b.Y
+	c2 := c.X*c.X + c.Y*c.Y
+	center := Position{
+		X: (a2*(b.Y-c.Y) + b2*(c.Y-a.Y) + c2*(a.Y-b.Y)) / d,
+		Y: (a2*(c.X-b.X) + b2*(a.X-c.X) + c2*(b.X-a.X)) / d,
+	}
+	radius := math.Hypot(a.X-center.X, a.Y-center.Y)
+
+	// 三点的绕行方向决定顺/逆时针（数学坐标系，Y轴向上）
+	cross := (b.X-a.X)*(c.Y-a.Y) - (b.Y-a.Y)*(c.X-a.X)
+	clockwise := cross < 0
+
+	startAngle := math.Atan2(a.Y-center.Y, a.X-center.X)
+	endAngle := math.Atan2(c.Y-center.Y, c.X-center.X)
+	sweep := endAngle - startAngle
+	if clockwise {
+		sweep = -sweep
+	}
+	for sweep <= 0 {
+		sweep += 2 * math.Pi
+	}
+
+	return Arc{Start: a, End: c, Center: center, Radius: radius, Clockwise: clockwise, Sweep: sweep}, true
+}
+
+// Reverse 返回方向相反的同一圆弧
+func (a Arc) Reverse() Arc {
+	a.Start, a.End = a.End, a.Start
+	a.Clockwise = !a.Clockwise
+	return a
+}
+
+// PointAt 返回圆弧上参数 t∈[0,1] 处的点
+func (a Arc) PointAt(t float64) Position {
+	startAngle := math.Atan2(a.Start.Y-a.Center.Y, a.Start.X-a.Center.X)
+	angle := startAngle + a.Sweep*t
+	if a.Clockwise {
+		angle = startAngle - a.Sweep*t
+	}
+	return Position{
+		X: a.Center.X + a.Radius*math.Cos(angle),
+		Y: a.Center.Y + a.Radius*math.Sin(angle),
+		Z: a.Start.Z + (a.End.Z-a.Start.Z)*t,
+	}
+}
+
+// SampleCurve 按曲线类型对控制点采样，segments 为曲线段的细分数（折线不细分）
+func SampleCurve(points []Position, curveType CurveType, segments int) []Position {
+	if len(points) < 2 {
+		return points
+	}
+	if segments < 1 {
+		segments = 1
+	}
+
+	switch curveType {
+	case CurveTypeBezier:
+		if len(points) == 2 {
+			return points
+		}
+		samples := make([]Position, 0, segments+1)
+		for i := 0; i <= segments; i++ {
+			samples = append(samples, BezierPoint(points, float64(i)/float64(segments)))
+		}
+		return samples
+
+	case CurveTypeSpline:
+		if len(points) == 2 {
+			return points
+		}
+		samples := []Position{points[0]}
+		for i := 0; i < len(points)-1; i++ {
+			p0 := points[max(i-1, 0)]
+			p1, p2 := points[i], points[i+1]
+			p3 := points[min(i+2, len(points)-1)]
+			for j := 1; j <= segments; j++ {
+				samples = append(samples, catmullRomPoint(p0, p1, p2, p3, float64(j)/float64(segments)))
+			}
+		}
+		return samples
+
+	case CurveTypeArc:
+		arc, ok := ArcThrough(points[0], points[1], points[len(points)-1])
+		if len(points) < 3 || !ok {
+			return []Position{points[0], points[len(points)-1]}
+		}
+		samples := make([]Position, 0, segments+1)
+		for i := 0; i <= segments; i++ {
+			samples = append(samples, arc.PointAt(float64(i)/float64(segments)))
+		}
+		return samples
+
+	default:
+		return points
+	}
+}
+
+// BezierPoint 使用 de Casteljau 算法计算贝塞尔曲线上 t 处的点
+func BezierPoint(points []Position, t float64) Position {
+	work := make([]Position, len(points))
+	copy(work, points)
+	for n := len(work) - 1; n > 0; n-- {
+		for i := 0; i < n; i++ {
+			work[i] = LerpPosition(work[i], work[i+1], t)
+		}
+	}
+	return work[0]
+}
+
+// CatmullRomToBezier 将经过 p1、p2 的 Catmull-Rom 段转换为等价的三次贝塞尔控制点
+func CatmullRomToBezier(p0, p1, p2, p3 Position) [4]Position {
+	return [4]Position{
+		p1,
+		{X: p1.X + (p2.X-p0.X)/6, Y: p1.Y + (p2.Y-p0.Y)/6, Z: p1.Z + (p2.Z-p0.Z)/6},
+		{X: p2.X - (p3.X-p1.X)/6, Y: p2.Y - (p3.Y-p1.Y)/6, Z: p2.Z - (p3.Z-p1.Z)/6},
+		p2,
+	}
+}
+
+// catmullRomPoint 计算 Catmull-Rom 段（p1→p2）上 t 处的点
+func catmullRomPoint(p0, p1, p2, p3 Position, t float64) Position {
+	bezier := CatmullRomToBezier(p0, p1, p2, p3)
+	return BezierPoint(bezier[:], t)
+}
+
+// PolylineLength 计算折线长度
+func PolylineLength(points []Position) float64 {
+	length := 0.0
+	for i := 1; i < len(points); i++ {
+		length += points[i-1].DistanceTo(points[i])
+	}
+	return length
+}
+
+// NURBS 非均匀有理B样条，ControlPoints 与 Weights 一一对应
+type NURBS struct {
+	Degree        int        `json:"degree"`
+	Knots         []float64  `json:"knots"`
+	ControlPoints []Position `json:"control_points"`
+	Weights       []float64  `json:"weights"`
+}
+
+// CurveNURBS 将路径曲线精确转换为 NURBS（参数域 [0,1]）
+// - linear：1次，控制点即折线顶点，节点按弦长分布
+// - bezier：与控制点数对应次数的单段 NURBS
+// - spline：每段 Catmull-Rom 转为三次贝塞尔后拼接，内部节点重数为3
+// - arc：按不超过90°分段的有理二次曲线，内部节点重数为2
+func CurveNURBS(points []Position, curveType CurveType) NURBS {
+	if len(points) < 2 {
+		return NURBS{}
+	}
+
+	switch curveType {
+	case CurveTypeBezier:
+		degree := len(points) - 1
+		knots := make([]float64, 0, 2*(degree+1))
+		for i := 0; i <= degree; i++ {
+			knots = append(knots, 0)
+		}
+		for i := 0; i <= degree; i++ {
+			knots = append(knots, 1)
+		}
+		return NURBS{Degree: degree, Knots: knots, ControlPoints: points, Weights: unitWeights(len(points))}
+
+	case CurveTypeSpline:
+		if len(points) == 2 {
+			break
+		}
+		spans := len(points) - 1
+		controls := []Position{points[0]}
+		for i := 0; i < spans; i++ {
+			p0 := points[max(i-1, 0)]
+			p3 := points[min(i+2, len(points)-1)]
+			bezier := CatmullRomToBezier(p0, points[i], points[i+1], p3)
+			controls = append(controls, bezier[1], bezier[2], bezier[3])
+		}
+		return NURBS{Degree: 3, Knots: clampedKnots(3, spans), ControlPoints: controls, Weights: unitWeights(len(controls))}
+
+	case CurveTypeArc:
+		if len(points) < 3 {
+			break
+		}
+		arc, ok := ArcThrough(points[0], points[1], points[len(points)-1])
+		if !ok {
+			return CurveNURBS([]Position{points[0], points[len(points)-1]}, CurveTypeLinear)
+		}
+		return arc.NURBS()
+	}
+
+	// 折线：1次 NURBS，节点按弦长参数化
+	total := PolylineLength(points)
+	knots := []float64{0, 0}
+	acc := 0.0
+	for i := 1; i < len(points)-1; i++ {
+		acc += points[i-1].DistanceTo(points[i])
+		u := float64(i) / float64(len(points)-1)
+		if total > 0 {
+			u = acc / total
+		}
+		knots = append(knots, u)
+	}
+	knots = append(knots, 1, 1)
+	return NURBS{Degree: 1, Knots: knots, ControlPoints: points, Weights: unitWeights(len(points))}
+}
+
+// NURBS 将圆弧转换为有理二次 NURBS
+func (a Arc) NURBS() NURBS {
+	spans := int(math.Ceil(a.Sweep / (math.Pi / 2)))
+	if spans < 1 {
+		spans = 1
+	}
+	delta := a.Sweep / float64(spans)
+	w := math.Cos(delta / 2)
+
+	controls := []Position{a.Start}
+	weights := []float64{1}
+	for i := 0; i < spans; i++ {
+		t0 := float64(i) / float64(spans)
+		t1 := float64(i+1) / float64(spans)
+		p0, p2 := a.PointAt(t0), a.PointAt(t1)
+		if i == spans-1 {
+			p2 = a.End
+		}
+		// 中间控制点为两端切线交点：弧中点沿径向外推到 r/cos(Δ/2)
+		mid := a.PointAt((t0 + t1) / 2)
+		scale := 1 / w
+		p1 := Position{
+			X: a.Center.X + (mid.X-a.Center.X)*scale,
+			Y: a.Center.Y + (mid.Y-a.Center.Y)*scale,
+			Z: (p0.Z + p2.Z) / 2,
+		}
+		controls = append(controls, p1, p2)
+		weights = append(weights, w, 1)
+	}
+
+	return NURBS{Degree: 2, Knots: clampedKnots(2, spans), ControlPoints: controls, Weights: weights}
+}
+
+// clampedKnots 生成分段贝塞尔形式的节点向量：两端重数 degree+1，内部节点重数 degree
+func clampedKnots(degree, spans int) []float64 {
+	knots := make([]float64, 0, degree*spans+2)
+	for i := 0; i <= degree; i++ {
+		knots = append(knots, 0)
+	}
+	for s := 1; s < spans; s++ {
+		u := float64(s) / float64(spans)
+		for i := 0; i < degree; i++ {
+			knots = append(knots, u)
+		}
+	}
+	for i := 0; i <= degree; i++ {
+		knots = append(knots, 1)
+	}
+	return knots
+}
+
+// unitWeights 生成全为1的权重
+func unitWeights(n int) []float64 {
+	weights := make([]float64, n)
+	for i := range weights {
+		weights[i] = 1
+	}
+	return weights
+}
+
+// PointAt 用 de Boor 算法求参数 t∈[0,1] 处的点，t 线性映射到节点向量的有效区间
+// 节点向量长度必须为控制点数+次数+1，权重缺失时按1处理
+func (n NURBS) PointAt(t float64) Position {
+	p, count := n.Degree, len(n.ControlPoints)
+	if count == 0 {
+		return Position{}
+	}
+	if p < 1 || len(n.Knots) != count+p+1 {
+		return n.ControlPoints[0]
+	}
+
+	lo, hi := n.Knots[p], n.Knots[count]
+	u := lo + (hi-lo)*math.Max(0, math.Min(1, t))
+
+	// 找到 u 所在的节点区间 [k_span, k_span+1)，u 取终点时落在最后一个非空区间
+	span := p
+	for span < count-1 && u >= n.Knots[span+1] {
+		span++
+	}
+
+	// 齐次坐标下插值
+	type homogeneous struct{ x, y, z, w float64 }
+	d := make([]homogeneous, p+1)
+	for j := 0; j <= p; j++ {
+		cp := n.ControlPoints[span-p+j]
+		w := 1.0
+		if i := span - p + j; i < len(n.Weights) {
+			w = n.Weights[i]
+		}
+		d[j] = homogeneous{cp.X * w, cp.Y * w, cp.Z * w, w}
+	}
+	for r := 1; r <= p; r++ {
+		for j := p; j >= r; j-- {
+			i := span - p + j
+			denominator := n.Knots[i+p-r+1] - n.Knots[i]
+			alpha := 0.0
+			if denominator != 0 {
+				alpha = (u - n.Knots[i]) / denominator
+			}
+			d[j] = homogeneous{
+				x: (1-alpha)*d[j-1].x + alpha*d[j].x,
+				y: (1-alpha)*d[j-1].y + alpha*d[j].y,
+				z: (1-alpha)*d[j-1].z + alpha*d[j].z,
+				w: (1-alpha)*d[j-1].w + alpha*d[j].w,
+			}
+		}
+	}
+
+	if d[p].w == 0 {
+		return Position{X: d[p].x, Y: d[p].y, Z: d[p].z}
+	}
+	return Position{X: d[p].x / d[p].w, Y: d[p].y / d[p].w, Z: d[p].z / d[p].w}
+}
@@ -0,0 +1,263 @@
+package domain
+
+import (
+	"math"
+	"testing"
+)
+
+func TestArcThrough(t *testing.T) {
+	tests := []struct {
+		name      string
+		a, b, c   Position
+		ok        bool
+		center    Position
+		radius    float64
+		clockwise bool
+		sweep     float64
+	}{
+		{"逆时针半圆", Position{X: 1}, Position{Y: 1}, Position{X: -1}, true, Position{}, 1, false, math.Pi},
+		{"顺时针半圆", Position{X: -1}, Position{Y: 1}, Position{X: 1}, true, Position{}, 1, true, math.Pi},
+		{"四分之一圆", Position{X: 2, Y: 0}, Position{X: math.Sqrt2, Y: math.Sqrt2}, Position{X: 0, Y: 2}, true, Position{}, 2, false, math.Pi / 2},
+		{"大于半圆", Position{X: 1}, Position{X: -1}, Position{Y: -1}, true, Position{}, 1, false, 3 * math.Pi / 2},
+		{"共线", Position{}, Position{X: 1, Y: 1}, Position{X: 2, Y: 2}, false, Position{}, 0, false, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			arc, ok := ArcThrough(tt.a, tt.b, tt.c)
+			if ok != tt.ok {
+				t.Fatalf("ArcThrough() ok = %v, want %v", ok, tt.ok)
+			}
+			if !ok {
+				return
+			}
+			if !approxPosition(arc.Center, tt.center, 1e-9) || !approx(arc.Radius, tt.radius, 1e-9) {
+				t.Errorf("圆心/半径 = %+v/%v, want %+v/%v", arc.Center, arc.Radius, tt.center, tt.radius)
+			}
+			if arc.Clockwise != tt.clockwise || !approx(arc.Sweep, tt.sweep, 1e-9) {
+				t.Errorf("方向/扫角 = %v/%v, want %v/%v", arc.Clockwise, arc.Sweep, tt.clockwise, tt.sweep)
+			}
+			if !approxPosition(arc.PointAt(0), tt.a, 1e-9) || !approxPosition(arc.PointAt(1), tt.c, 1e-9) {
+				t.Errorf("端点 = %+v → %+v, want %+v → %+v", arc.PointAt(0), arc.PointAt(1), tt.a, tt.c)
+			}
+		})
+	}
+}
+
+func TestArcReverse(t *testing.T) {
+	arc, _ := ArcThrough(Position{X: 1}, Position{Y: 1}, Position{X: -1, Z: 4})
+	rev := arc.Reverse()
+	for _, u := range []float64{0, 0.3, 0.5, 1} {
+		if got, want := rev.PointAt(u), arc.PointAt(1-u); !approxPosition(got, want, 1e-9) {
+			t.Errorf("Reverse().PointAt(%v) = %+v, want %+v", u, got, want)
+		}
+	}
+}
+
+func TestSampleCurve(t *testing.T) {
+	semicircle := []Position{{X: 1}, {Y: 1}, {X: -1}}
+	tests := []struct {
+		name      string
+		points    []Position
+		curveType CurveType
+		segments  int
+		wantLen   int
+		check     func(t *testing.T, samples []Position)
+	}{
+		{
+			name: "折线不细分", points: []Position{{}, {X: 1}, {X: 1, Y: 1}}, curveType: CurveTypeLinear, segments: 8, wantLen: 3,
+		},
+		{
+			name: "圆弧采样点在圆上", points: semicircle, curveType: CurveTypeArc, segments: 16, wantLen: 17,
+			check: func(t *testing.T, samples []Position) {
+				for _, p := range samples {
+					if r := math.Hypot(p.X, p.Y); !approx(r, 1, 1e-9) {
+						t.Errorf("采样点 %+v 到圆心距离 %v, want 1", p, r)
+					}
+				}
+				if !approxPosition(samples[8], Position{Y: 1}, 1e-9) {
+					t.Errorf("中点 = %+v, want (0, 1)", samples[8])
+				}
+			},
+		},
+		{
+			name: "圆弧只用第一个途经点", points: []Position{{X: 1}, {Y: 1}, {X: 5, Y: 5}, {X: -1}}, curveType: CurveTypeArc, segments: 4, wantLen: 5,
+			check: func(t *testing.T, samples []Position) {
+				if !approxPosition(samples[2], Position{Y: 1}, 1e-9) {
+					t.Errorf("中点 = %+v, want (0, 1)", samples[2])
+				}
+			},
+		},
+		{
+			name: "共线圆弧退化为直线", points: []Position{{}, {X: 1}, {X: 2}}, curveType: CurveTypeArc, segments: 8, wantLen: 2,
+		},
+		{
+			name: "贝塞尔经过端点", points: []Position{{}, {X: 1, Y: 2}, {X: 2}}, curveType: CurveTypeBezier, segments: 4, wantLen: 5,
+			check: func(t *testing.T, samples []Position) {
+				if !approxPosition(samples[2], Position{X: 1, Y: 1}, 1e-9) {
+					t.Errorf("t=0.5 = %+v, want (1, 1)", samples[2])
+				}
+			},
+		},
+		{
+			name: "样条经过每个控制点", points: []Position{{}, {X: 1, Y: 1}, {X: 2}, {X: 3, Y: -1}}, curveType: CurveTypeSpline, segments: 5, wantLen: 16,
+			check: func(t *testing.T, samples []Position) {
+				for i, want := range []Position{{}, {X: 1, Y: 1}, {X: 2}, {X: 3, Y: -1}} {
+					if !approxPosition(samples[i*5], want, 1e-9) {
+						t.Errorf("样本 %d = %+v, want %+v", i*5, samples[i*5], want)
+					}
+				}
+			},
+		},
+		{
+			name: "细分数小于1按1处理", points: semicircle, curveType: CurveTypeArc, segments: 0, wantLen: 2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			samples := SampleCurve(tt.points, tt.curveType, tt.segments)
+			if len(samples) != tt.wantLen {
+				t.Fatalf("len = %d, want %d", len(samples), tt.wantLen)
+			}
+			if !approxPosition(samples[0], tt.points[0], 1e-9) || !approxPosition(samples[len(samples)-1], tt.points[len(tt.points)-1], 1e-9) {
+				t.Errorf("端点 = %+v → %+v, want %+v → %+v", samples[0], samples[len(samples)-1], tt.points[0], tt.points[len(tt.points)-1])
+			}
+			if tt.check != nil {
+				tt.check(t, samples)
+			}
+		})
+	}
+}
+
+func TestPolylineLength(t *testing.T) {
+	tests := []struct {
+		name   string
+		points []Position
+		want   float64
+	}{
+		{"空", nil, 0},
+		{"单点", []Position{{X: 1}}, 0},
+		{"3-4-5", []Position{{}, {X: 3, Y: 4}}, 5},
+		{"三维折线", []Position{{}, {X: 1}, {X: 1, Y: 2}, {X: 1, Y: 2, Z: 2}}, 5},
+	}
+	for _, tt := range tests {
+		if got := PolylineLength(tt.points); !approx(got, tt.want, 1e-9) {
+			t.Errorf("%s: PolylineLength() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+// TestCurveNURBS 精确转换得到的 NURBS 与原曲线一致
+func TestCurveNURBS(t *testing.T) {
+	tests := []struct {
+		name       string
+		points     []Position
+		curveType  CurveType
+		wantDegree int
+	}{
+		{"折线", []Position{{}, {X: 1}, {X: 1, Y: 3}}, CurveTypeLinear, 1},
+		{"贝塞尔", []Position{{}, {X: 1, Y: 2}, {X: 3, Y: 2}, {X: 4}}, CurveTypeBezier, 3},
+		{"样条", []Position{{}, {X: 1, Y: 1}, {X: 2}, {X: 3, Y: -1}}, CurveTypeSpline, 3},
+		{"半圆", []Position{{X: 1}, {Y: 1}, {X: -1}}, CurveTypeArc, 2},
+		{"大于半圆", []Position{{X: 1}, {X: -1}, {Y: -1, Z: 2}}, CurveTypeArc, 2},
+		{"顺时针圆弧", []Position{{X: -2}, {Y: 2}, {X: 2}}, CurveTypeArc, 2},
+		{"共线圆弧退化为直线", []Position{{}, {X: 1}, {X: 2}}, CurveTypeArc, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			n := CurveNURBS(tt.points, tt.curveType)
+			if n.Degree != tt.wantDegree {
+				t.Fatalf("Degree = %d, want %d", n.Degree, tt.wantDegree)
+			}
+			if len(n.Knots) != len(n.ControlPoints)+n.Degree+1 || len(n.Weights) != len(n.ControlPoints) {
+				t.Fatalf("节点 %d、控制点 %d、权重 %d 数量不匹配", len(n.Knots), len(n.ControlPoints), len(n.Weights))
+			}
+			for i := 1; i < len(n.Knots); i++ {
+				if n.Knots[i] < n.Knots[i-1] {
+					t.Fatalf("节点向量不单调: %v", n.Knots)
+				}
+			}
+			if !approxPosition(n.PointAt(0), tt.points[0], 1e-9) || !approxPosition(n.PointAt(1), tt.points[len(tt.points)-1], 1e-9) {
+				t.Errorf("端点 = %+v → %+v", n.PointAt(0), n.PointAt(1))
+			}
+
+			switch tt.curveType {
+			case CurveTypeArc:
+				// 有理二次曲线精确落在圆上
+				arc, ok := ArcThrough(tt.points[0], tt.points[1], tt.points[len(tt.points)-1])
+				if !ok {
+					break
+				}
+				for i := 0; i <= 64; i++ {
+					p := n.PointAt(float64(i) / 64)
+					if r := math.Hypot(p.X-arc.Center.X, p.Y-arc.Center.Y); !approx(r, arc.Radius, 1e-9) {
+						t.Fatalf("t=%v 处 %+v 到圆心距离 %v, want %v", float64(i)/64, p, r, arc.Radius)
+					}
+				}
+				if mid := n.PointAt(0.5); !approxPosition(mid, arc.PointAt(0.5), 1e-9) {
+					t.Errorf("中点 = %+v, want %+v", mid, arc.PointAt(0.5))
+				}
+			case CurveTypeBezier:
+				for _, u := range []float64{0.25, 0.5, 0.75} {
+					if got, want := n.PointAt(u), BezierPoint(tt.points, u); !approxPosition(got, want, 1e-9) {
+						t.Errorf("PointAt(%v) = %+v, want %+v", u, got, want)
+					}
+				}
+			case CurveTypeSpline:
+				// 每段参数长度相同，内部节点处经过控制点
+				spans := len(tt.points) - 1
+				for i, want := range tt.points {
+					if got := n.PointAt(float64(i) / float64(spans)); !approxPosition(got, want, 1e-9) {
+						t.Errorf("PointAt(%d/%d) = %+v, want %+v", i, spans, got, want)
+					}
+				}
+			case CurveTypeLinear:
+				// 弦长参数化：参数与弧长成正比
+				length := PolylineLength(tt.points)
+				if got := n.PointAt(1 / length); !approxPosition(got, Position{X: 1}, 1e-9) {
+					t.Errorf("PointAt(1/L) = %+v, want (1, 0)", got)
+				}
+			}
+		})
+	}
+}
+
+func TestArcNURBSSpans(t *testing.T) {
+	tests := []struct {
+		name      string
+		sweep     float64
+		wantSpans int
+	}{
+		{"小于90°", math.Pi / 3, 1},
+		{"正好90°", math.Pi / 2, 1},
+		{"半圆", math.Pi, 2},
+		{"270°", 3 * math.Pi / 2, 3},
+		{"接近整圆", 2*math.Pi - 0.01, 4},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			arc := Arc{
+				Start:  Position{X: 1},
+				End:    Position{X: math.Cos(tt.sweep), Y: math.Sin(tt.sweep)},
+				Radius: 1,
+				Sweep:  tt.sweep,
+			}
+			n := arc.NURBS()
+			if spans := (len(n.ControlPoints) - 1) / 2; spans != tt.wantSpans {
+				t.Errorf("分段数 = %d, want %d", spans, tt.wantSpans)
+			}
+			for i, w := range n.Weights {
+				want := 1.0
+				if i%2 == 1 {
+					want = math.Cos(tt.sweep / float64(tt.wantSpans) / 2)
+				}
+				if !approx(w, want, 1e-12) {
+					t.Errorf("Weights[%d] = %v, want %v", i, w, want)
+				}
+			}
+		})
+	}
+}
+
+func approxPosition(a, b Position, tolerance float64) bool {
+	return approx(a.X, b.X, tolerance) && approx(a.Y, b.Y, tolerance) && approx(a.Z, b.Z, tolerance)
+}
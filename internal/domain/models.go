@@ -13,8 +13,10 @@
 package domain
 
 import (
+	"encoding/json"
 	"fmt"
 	"math"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -96,36 +98,174 @@ type DatabaseConnection struct {
 	Port       int               `json:"port" gorm:"type:int;not null"`
 	Database   string            `json:"database" gorm:"type:varchar(100);not null"`
 	Username   string            `json:"username" gorm:"type:varchar(100);not null"`
-	Password   string            `json:"password" gorm:"type:varchar(255);not null"`
+	Password   string            `json:"-" gorm:"type:varchar(512);not null"` // 只写：不出现在JSON输出中，配置主密钥时加密保存
 	Properties map[string]string `json:"properties,omitempty" gorm:"serializer:json"`
+
+	// 读取时密码或敏感属性无法解密的原因（如缺少密钥）；此时这些值已清空，需要重新填写
+	SecretError string `json:"secret_error,omitempty" gorm:"-"`
+}
+
+// RedactedSecret JSON输出中敏感属性的掩码；更新时提交掩码表示保留原值
+const RedactedSecret = "******"
+
+// IsSecretConnectionProperty 连接属性是否为敏感信息（键以 password 或 secret 结尾，如 sslpassword）
+func IsSecretConnectionProperty(key string) bool {
+	key = strings.ToLower(key)
+	return strings.HasSuffix(key, "password") || strings.HasSuffix(key, "secret")
+}
+
+// MarshalJSON 省略密码，用 password_set 表示是否已设置；敏感属性的值替换为掩码
+func (c DatabaseConnection) MarshalJSON() ([]byte, error) {
+	type connection DatabaseConnection
+	out := struct {
+		connection
+		PasswordSet bool `json:"password_set"`
+	}{connection(c), c.Password != ""}
+	if len(c.Properties) > 0 {
+		out.Properties = make(map[string]string, len(c.Properties))
+		for k, v := range c.Properties {
+			if IsSecretConnectionProperty(k) && v != "" {
+				v = RedactedSecret
+			}
+			out.Properties[k] = v
+		}
+	}
+	return json.Marshal(out)
 }
 
 // TableMapping 表示表字段映射配置
 type TableMapping struct {
 	ID           string            `json:"id" gorm:"primaryKey;type:varchar(36)"`
 	ConnectionID string            `json:"connection_id" gorm:"type:varchar(36);not null"`
-	TableName    string            `json:"table_name" gorm:"type:varchar(100);not null"`
+	SchemaName   string            `json:"schema_name,omitempty" gorm:"type:varchar(100)"` // 为空时使用连接的默认模式
+	TableName    string            `json:"table_name" gorm:"type:varchar(100);not null"`   // 节点和路径未单独指定表时使用
 	NodeMapping  *NodeTableMapping `json:"node_mapping,omitempty" gorm:"serializer:json"`
 	PathMapping  *PathTableMapping `json:"path_mapping,omitempty" gorm:"serializer:json"`
+	Filter       []TableCondition  `json:"filter,omitempty" gorm:"serializer:json"`   // 只同步满足全部条件的行
+	OrderBy      []TableOrder      `json:"order_by,omitempty" gorm:"serializer:json"` // 为空时按ID列排序
+	// 外部删除的处理：soft（默认，状态改为 deleted）、hard（删除本地记录）、report（只报告）
+	DeletionPolicy string `json:"deletion_policy,omitempty" gorm:"type:varchar(20)"`
+	// 路径端点缺失的处理：quarantine（默认，隔离后自动重试）、skip（只报告）
+	OrphanPolicy string `json:"orphan_policy,omitempty" gorm:"type:varchar(20)"`
+}
+
+// TableCondition 外部表筛选条件，多个条件以 AND 连接
+type TableCondition struct {
+	Column   string      `json:"column"`
+	Operator string      `json:"operator"` // eq, ne, lt, le, gt, ge, like, in, is_null, not_null
+	Value    interface{} `json:"value,omitempty"`
+}
+
+// TableOrder 外部表排序
+type TableOrder struct {
+	Column string `json:"column"`
+	Desc   bool   `json:"desc,omitempty"`
 }
 
 // NodeTableMapping 节点表映射
+// 旧的 *_field 写法与 Fields 中对应字段等价，Fields 中同名字段优先
 type NodeTableMapping struct {
-	IDField   string `json:"id_field"`
-	NameField string `json:"name_field"`
-	TypeField string `json:"type_field"`
-	XField    string `json:"x_field"`
-	YField    string `json:"y_field"`
-	ZField    string `json:"z_field"`
+	TableName string           `json:"table_name,omitempty"` // 为空时使用表映射的表；单独指定时不继承表映射的筛选和排序
+	IDField   string           `json:"id_field"`
+	NameField string           `json:"name_field"`
+	TypeField string           `json:"type_field"`
+	XField    string           `json:"x_field"`
+	YField    string           `json:"y_field"`
+	ZField    string           `json:"z_field"`
+	Fields    []FieldMapping   `json:"fields,omitempty"`
+	Filter    []TableCondition `json:"filter,omitempty"`
+	OrderBy   []TableOrder     `json:"order_by,omitempty"`
 }
 
 // PathTableMapping 路径表映射
 type PathTableMapping struct {
-	IDField        string `json:"id_field"`
-	NameField      string `json:"name_field"`
-	StartNodeField string `json:"start_node_field"`
-	EndNodeField   string `json:"end_node_field"`
-	WeightField    string `json:"weight_field"`
+	TableName      string           `json:"table_name,omitempty"`
+	IDField        string           `json:"id_field"`
+	NameField      string           `json:"name_field"`
+	StartNodeField string           `json:"start_node_field"`
+	EndNodeField   string           `json:"end_node_field"`
+	WeightField    string           `json:"weight_field"`
+	Fields         []FieldMapping   `json:"fields,omitempty"`
+	Filter         []TableCondition `json:"filter,omitempty"`
+	OrderBy        []TableOrder     `json:"order_by,omitempty"`
+}
+
+// FieldMapping 本地字段与外部列的映射
+// Field 为 "properties.*" 时 Column 是通配模式（如 "*"、"attr_*"），匹配且未被其他字段映射的列写入 Properties，键为列名
+type FieldMapping struct {
+	Field  string            `json:"field"` // 如 status、position.x、robot_coords.yaw、style.color、waypoints、properties.批次
+	Column string            `json:"column"`
+	Scale  float64           `json:"scale,omitempty"` // 单位换算：外部值 × scale = 本地值
+	Enum   map[string]string `json:"enum,omitempty"`  // 外部值 → 本地值，未列出的值原样使用
+	JSON   bool              `json:"json,omitempty"`  // 列中存 JSON，结构化字段必须设置
+}
+
+// SyncSnapshot 上次同步时外部表一行的映射列值，作为三方比较的基准
+type SyncSnapshot struct {
+	MappingID string            `json:"mapping_id" gorm:"primaryKey;type:varchar(36)"`
+	Entity    string            `json:"entity" gorm:"primaryKey;type:varchar(10)"` // node 或 path
+	RecordID  string            `json:"record_id" gorm:"primaryKey;type:varchar(191)"`
+	Fields    map[string]string `json:"fields" gorm:"serializer:json"` // 列名 → 规范化后的值
+	SyncedAt  time.Time         `json:"synced_at"`
+}
+
+// SyncQuarantine 起点或终点节点在本地不存在而未写入的外部路径行，节点同步后自动重试
+type SyncQuarantine struct {
+	MappingID    string            `json:"mapping_id" gorm:"primaryKey;type:varchar(36)"`
+	PathID       string            `json:"path_id" gorm:"primaryKey;type:varchar(191)"`
+	StartNodeID  string            `json:"start_node_id" gorm:"type:varchar(191)"`
+	EndNodeID    string            `json:"end_node_id" gorm:"type:varchar(191)"`
+	MissingNodes []string          `json:"missing_nodes" gorm:"serializer:json"`
+	Fields       map[string]string `json:"fields" gorm:"serializer:json"` // 外部行的映射列值
+	DetectedAt   time.Time         `json:"detected_at"`
+}
+
+// SyncJob 表映射的后台同步任务，按 cron 表达式或轮询间隔从外部表拉取
+type SyncJob struct {
+	ID              string            `json:"id" gorm:"primaryKey;type:varchar(36)"`
+	Name            string            `json:"name" gorm:"type:varchar(100)"`
+	MappingID       string            `json:"mapping_id" gorm:"type:varchar(36);not null;index"`
+	Cron            string            `json:"cron,omitempty" gorm:"type:varchar(100)"` // 分 时 日 月 周，与 IntervalSeconds 二选一
+	IntervalSeconds int               `json:"interval_seconds,omitempty"`
+	Entities        []string          `json:"entities,omitempty" gorm:"serializer:json"`           // node、path，为空时同步已配置的全部实体
+	WatermarkColumn string            `json:"watermark_column,omitempty" gorm:"type:varchar(128)"` // 更新时间或版本号列，为空时每次全量拉取
+	Watermarks      map[string]string `json:"watermarks,omitempty" gorm:"serializer:json"`         // 实体 → 已同步到的水位
+	Paused          bool              `json:"paused"`
+	NextRunAt       *time.Time        `json:"next_run_at,omitempty"`
+	LastRunAt       *time.Time        `json:"last_run_at,omitempty"`
+	LastStatus      string            `json:"last_status,omitempty" gorm:"type:varchar(20)"`
+	CreatedAt       time.Time         `json:"created_at"`
+	UpdatedAt       time.Time         `json:"updated_at"`
+}
+
+// 同步任务运行状态
+const (
+	SyncRunRunning  = "running"
+	SyncRunSuccess  = "success"
+	SyncRunPartial  = "partial" // 完成但部分记录失败
+	SyncRunFailed   = "failed"
+	SyncRunCanceled = "canceled"
+)
+
+// SyncJobRun 同步任务的一次运行记录
+type SyncJobRun struct {
+	ID           string            `json:"id" gorm:"primaryKey;type:varchar(36)"`
+	JobID        string            `json:"job_id" gorm:"type:varchar(36);not null;index"`
+	MappingID    string            `json:"mapping_id" gorm:"type:varchar(36)"`
+	Trigger      string            `json:"trigger" gorm:"type:varchar(20)"` // schedule 或 manual
+	Status       string            `json:"status" gorm:"type:varchar(20)"`
+	StartedAt    time.Time         `json:"started_at" gorm:"index"`
+	FinishedAt   *time.Time        `json:"finished_at,omitempty"`
+	DurationMs   int64             `json:"duration_ms"`
+	NodesCreated int               `json:"nodes_created"`
+	NodesUpdated int               `json:"nodes_updated"`
+	PathsCreated int               `json:"paths_created"`
+	PathsUpdated int               `json:"paths_updated"`
+	NodesDeleted int               `json:"nodes_deleted"`
+	PathsDeleted int               `json:"paths_deleted"`
+	Orphans      int               `json:"orphans"` // 端点缺失未写入的路径数
+	Errors       []string          `json:"errors,omitempty" gorm:"serializer:json"`
+	Watermarks   map[string]string `json:"watermarks,omitempty" gorm:"serializer:json"` // 本次运行结束时的水位
 }
 
 // === 值对象定义 ===
@@ -256,8 +396,39 @@ const (
 	CurveTypeLinear CurveType = "linear" // 线性
 	CurveTypeBezier CurveType = "bezier" // 贝塞尔曲线
 	CurveTypeSpline CurveType = "spline" // 样条曲线
+	CurveTypeArc    CurveType = "arc"    // 圆弧（经过第一个途经点）
+)
+
+// 路径通行方向（Path.Direction）；为空等同 bidirectional，one-way、unidirectional 是 forward 的别名，reverse 是 backward 的别名
+const (
+	DirectionBidirectional  = "bidirectional"  // 双向
+	DirectionForward        = "forward"        // 起点→终点
+	DirectionOneWay         = "one-way"        // 同 forward
+	DirectionUnidirectional = "unidirectional" // 同 forward
+	DirectionBackward       = "backward"       // 终点→起点
+	DirectionReverse        = "reverse"        // 同 backward
 )
 
+// Directions 全部合法的方向取值（空值另外视为双向）
+var Directions = []string{
+	DirectionBidirectional, DirectionForward, DirectionOneWay, DirectionUnidirectional, DirectionBackward, DirectionReverse,
+}
+
+// NormalizeDirection 把方向归一为 bidirectional、forward、backward 之一；
+// 取值不合法时按双向处理并返回 false
+func NormalizeDirection(direction string) (string, bool) {
+	switch direction {
+	case "", DirectionBidirectional:
+		return DirectionBidirectional, true
+	case DirectionForward, DirectionOneWay, DirectionUnidirectional:
+		return DirectionForward, true
+	case DirectionBackward, DirectionReverse:
+		return DirectionBackward, true
+	default:
+		return DirectionBidirectional, false
+	}
+}
+
 // === 工厂方法 ===
 
 // NewNode 创建新节点
@@ -292,7 +463,7 @@ func NewPath(name string, startNodeID, endNodeID NodeID) *Path {
 		Status:      PathStatusActive,
 		StartNodeID: startNodeID,
 		EndNodeID:   endNodeID,
-		Direction:   "bidirectional",
+		Direction:   DirectionBidirectional,
 		CurveType:   CurveTypeLinear,
 		Style: PathStyle{
 			Color:   "#6c757d",
@@ -346,3 +517,17 @@ func (p *Path) UpdatedAt() {
 	p.Metadata.UpdatedAt = time.Now()
 	p.Metadata.Version++
 }
+
+// CanTraverse 判断路径是否允许从指定节点出发通行
+// 方向按 NormalizeDirection 归一：双向两端都可出发；forward 仅允许起点→终点；backward 仅允许终点→起点
+func (p *Path) CanTraverse(from NodeID) bool {
+	direction, _ := NormalizeDirection(p.Direction)
+	switch direction {
+	case DirectionForward:
+		return from == p.StartNodeID
+	case DirectionBackward:
+		return from == p.EndNodeID
+	default:
+		return from == p.StartNodeID || from == p.EndNodeID
+	}
+}
@@ -0,0 +1,61 @@
+package domain
+
+import "testing"
+
+func TestNormalizeDirection(t *testing.T) {
+	tests := []struct {
+		direction string
+		want      string
+		ok        bool
+	}{
+		{"", DirectionBidirectional, true},
+		{DirectionBidirectional, DirectionBidirectional, true},
+		{DirectionForward, DirectionForward, true},
+		{DirectionOneWay, DirectionForward, true},
+		{DirectionUnidirectional, DirectionForward, true},
+		{DirectionBackward, DirectionBackward, true},
+		{DirectionReverse, DirectionBackward, true},
+		{"Forward", DirectionBidirectional, false},
+		{"sideways", DirectionBidirectional, false},
+	}
+	for _, tt := range tests {
+		got, ok := NormalizeDirection(tt.direction)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("NormalizeDirection(%q) = %q, %v, want %q, %v", tt.direction, got, ok, tt.want, tt.ok)
+		}
+	}
+	for _, d := range Directions {
+		if _, ok := NormalizeDirection(d); !ok {
+			t.Errorf("Directions 中的 %q 未被 NormalizeDirection 接受", d)
+		}
+	}
+}
+
+func TestPathCanTraverse(t *testing.T) {
+	tests := []struct {
+		direction          string
+		fromStart, fromEnd bool
+	}{
+		{"", true, true},
+		{DirectionBidirectional, true, true},
+		{DirectionForward, true, false},
+		{DirectionOneWay, true, false},
+		{DirectionUnidirectional, true, false},
+		{DirectionBackward, false, true},
+		{DirectionReverse, false, true},
+		{"unknown", true, true},
+	}
+	for _, tt := range tests {
+		p := NewPath("p", "a", "b")
+		p.Direction = tt.direction
+		if got := p.CanTraverse("a"); got != tt.fromStart {
+			t.Errorf("%q: CanTraverse(起点) = %v, want %v", tt.direction, got, tt.fromStart)
+		}
+		if got := p.CanTraverse("b"); got != tt.fromEnd {
+			t.Errorf("%q: CanTraverse(终点) = %v, want %v", tt.direction, got, tt.fromEnd)
+		}
+		if p.CanTraverse("c") {
+			t.Errorf("%q: CanTraverse(其他节点) = true", tt.direction)
+		}
+	}
+}
@@ -0,0 +1,269 @@
+// Package domain 占据栅格地图
+//
+// 设计参考：
+// - ROS map_server 的 map.yaml + PGM/PNG 地图格式
+// - nav_msgs/OccupancyGrid 的取值约定（-1未知，0空闲，100占据）
+//
+// 坐标约定：
+// - 节点坐标为地图坐标系下的米，与栅格对齐
+// - 栅格左下角为 origin，图像第0行对应地图的最上方
+package domain
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// 地图解释模式，与 map_server 一致
+const (
+	MapModeTrinary = "trinary"
+	MapModeScale   = "scale"
+	MapModeRaw     = "raw"
+)
+
+// 栅格取值
+const (
+	CellUnknown  int8 = -1
+	CellFree     int8 = 0
+	CellOccupied int8 = 100
+)
+
+// OccupancyMap 占据栅格地图
+type OccupancyMap struct {
+	ID          string `json:"id" gorm:"primaryKey;type:varchar(36)"`
+	Name        string `json:"name" gorm:"type:varchar(100);not null"`
+	Description string `json:"description,omitempty" gorm:"type:text"`
+
+	// 原始图像（PGM或PNG），列表查询时不加载
+	ImageFormat string `json:"image_format" gorm:"type:varchar(10)"`
+	Image       []byte `json:"-"`
+
+	// 栅格参数
+	Width          int       `json:"width"`
+	Height         int       `json:"height"`
+	Resolution     float64   `json:"resolution" gorm:"type:decimal(12,6)"` // 米/栅格
+	Origin         MapOrigin `json:"origin" gorm:"embedded;embeddedPrefix:origin_"`
+	OccupiedThresh float64   `json:"occupied_thresh" gorm:"type:decimal(6,4)"`
+	FreeThresh     float64   `json:"free_thresh" gorm:"type:decimal(6,4)"`
+	Negate         bool      `json:"negate"`
+	Mode           string    `json:"mode" gorm:"type:varchar(10)"`
+
+	// 是否为当前使用的地图（路径生成等功能据此避障）
+	Active bool `json:"active" gorm:"index"`
+
+	// 由栅格提取的障碍物多边形（地图坐标，米）
+	Obstacles []ObstaclePolygon `json:"obstacles,omitempty" gorm:"serializer:json"`
+
+	Metadata ObjectMeta `json:"metadata" gorm:"embedded"`
+}
+
+// MapOrigin 地图左下角栅格在地图坐标系中的位姿
+type MapOrigin struct {
+	X   float64 `json:"x"`
+	Y   float64 `json:"y"`
+	Yaw float64 `json:"yaw"` // 弧度
+}
+
+// MapPoint 地图坐标系中的二维点（米）
+type MapPoint struct {
+	X float64 `json:"x"`
+	Y float64 `json:"y"`
+}
+
+// MapBounds 地图坐标系中的包围盒
+type MapBounds struct {
+	Min MapPoint `json:"min"`
+	Max MapPoint `json:"max"`
+}
+
+// ObstaclePolygon 障碍物轮廓，外轮廓逆时针、内孔顺时针
+type ObstaclePolygon struct {
+	Points []MapPoint `json:"points"`
+	Hole   bool       `json:"hole,omitempty"`
+	Area   float64    `json:"area"`
+	Bounds MapBounds  `json:"bounds"`
+}
+
+// NewOccupancyMap 创建地图，阈值和模式使用 map_server 的默认值
+func NewOccupancyMap(name string) *OccupancyMap {
+	return &OccupancyMap{
+		ID:             uuid.New().String(),
+		Name:           name,
+		Resolution:     0.05,
+		OccupiedThresh: 0.65,
+		FreeThresh:     0.196,
+		Mode:           MapModeTrinary,
+		Metadata: ObjectMeta{
+			CreatedAt: time.Now(),
+			UpdatedAt: time.Now(),
+			Version:   1,
+		},
+	}
+}
+
+// IsValid 验证地图参数
+func (m *OccupancyMap) IsValid() error {
+	if m.Name == "" {
+		return fmt.Errorf("地图名称不能为空")
+	}
+	if m.Resolution <= 0 {
+		return fmt.Errorf("地图分辨率必须大于0")
+	}
+	if m.FreeThresh < 0 || m.OccupiedThresh > 1 || m.FreeThresh >= m.OccupiedThresh {
+		return fmt.Errorf("阈值必须满足 0 <= free_thresh < occupied_thresh <= 1")
+	}
+	switch m.Mode {
+	case MapModeTrinary, MapModeScale, MapModeRaw:
+	default:
+		return fmt.Errorf("不支持的地图模式: %s", m.Mode)
+	}
+	return nil
+}
+
+// CellToWorld 栅格角点坐标（列、行，行从图像顶部起算）转换为地图坐标
+func (m *OccupancyMap) CellToWorld(col, row float64) MapPoint {
+	lx := col * m.Resolution
+	ly := (float64(m.Height) - row) * m.Resolution
+	sin, cos := math.Sincos(m.Origin.Yaw)
+	return MapPoint{
+		X: m.Origin.X + lx*cos - ly*sin,
+		Y: m.Origin.Y + lx*sin + ly*cos,
+	}
+}
+
+// WorldToCell 地图坐标转换为所在栅格（列、行），超出地图时 ok 为 false
+func (m *OccupancyMap) WorldToCell(x, y float64) (col, row int, ok bool) {
+	dx, dy := x-m.Origin.X, y-m.Origin.Y
+	sin, cos := math.Sincos(m.Origin.Yaw)
+	lx := dx*cos + dy*sin
+	ly := -dx*sin + dy*cos
+
+	col = int(math.Floor(lx / m.Resolution))
+	row = m.Height - 1 - int(math.Floor(ly/m.Resolution))
+	ok = col >= 0 && col < m.Width && row >= 0 && row < m.Height
+	return col, row, ok
+}
+
+// Bounds 地图在地图坐标系中的包围盒
+func (m *OccupancyMap) Bounds() MapBounds {
+	corners := []MapPoint{
+		m.CellToWorld(0, 0),
+		m.CellToWorld(float64(m.Width), 0),
+		m.CellToWorld(0, float64(m.Height)),
+		m.CellToWorld(float64(m.Width), float64(m.Height)),
+	}
+	return boundsOf(corners)
+}
+
+// NewObstaclePolygon 由轮廓点创建障碍物多边形，按有向面积判断是否为内孔
+func NewObstaclePolygon(points []MapPoint) ObstaclePolygon {
+	area := signedArea(points)
+	return ObstaclePolygon{
+		Points: points,
+		Hole:   area < 0,
+		Area:   math.Abs(area),
+		Bounds: boundsOf(points),
+	}
+}
+
+// crossings 统计从 p 向 +X 方向的射线与多边形边的交点数
+func (o ObstaclePolygon) crossings(p MapPoint) int {
+	if p.Y < o.Bounds.Min.Y || p.Y > o.Bounds.Max.Y || p.X > o.Bounds.Max.X {
+		return 0
+	}
+	count := 0
+	n := len(o.Points)
+	for i, j := 0, n-1; i < n; j, i = i, i+1 {
+		a, b := o.Points[i], o.Points[j]
+		if (a.Y > p.Y) != (b.Y > p.Y) {
+			x := a.X + (p.Y-a.Y)*(b.X-a.X)/(b.Y-a.Y)
+			if p.X < x {
+				count++
+			}
+		}
+	}
+	return count
+}
+
+// segmentCrosses 判断线段是否与多边形的任一条边相交
+func (o ObstaclePolygon) segmentCrosses(a, b MapPoint) bool {
+	if math.Max(a.X, b.X) < o.Bounds.Min.X || math.Min(a.X, b.X) > o.Bounds.Max.X ||
+		math.Max(a.Y, b.Y) < o.Bounds.Min.Y || math.Min(a.Y, b.Y) > o.Bounds.Max.Y {
+		return false
+	}
+	n := len(o.Points)
+	for i, j := 0, n-1; i < n; j, i = i, i+1 {
+		if segmentsIntersect(a, b, o.Points[j], o.Points[i]) {
+			return true
+		}
+	}
+	return false
+}
+
+// PointInObstacles 判断点是否位于障碍物内（奇偶规则，自动处理内孔）
+func PointInObstacles(obstacles []ObstaclePolygon, p MapPoint) bool {
+	count := 0
+	for _, obstacle := range obstacles {
+		count += obstacle.crossings(p)
+	}
+	return count%2 == 1
+}
+
+// SegmentHitsObstacles 判断线段是否穿过或位于障碍物内
+func SegmentHitsObstacles(obstacles []ObstaclePolygon, a, b MapPoint) bool {
+	for _, obstacle := range obstacles {
+		if obstacle.segmentCrosses(a, b) {
+			return true
+		}
+	}
+	return PointInObstacles(obstacles, a) || PointInObstacles(obstacles, b)
+}
+
+// segmentsIntersect 判断两条线段是否相交（含端点接触）
+func segmentsIntersect(p1, p2, q1, q2 MapPoint) bool {
+	d1 := cross(q1, q2, p1)
+	d2 := cross(q1, q2, p2)
+	d3 := cross(p1, p2, q1)
+	d4 := cross(p1, p2, q2)
+	if ((d1 > 0 && d2 < 0) || (d1 < 0 && d2 > 0)) && ((d3 > 0 && d4 < 0) || (d3 < 0 && d4 > 0)) {
+		return true
+	}
+	return (d1 == 0 && onSegment(q1, q2, p1)) || (d2 == 0 && onSegment(q1, q2, p2)) ||
+		(d3 == 0 && onSegment(p1, p2, q1)) || (d4 == 0 && onSegment(p1, p2, q2))
+}
+
+func cross(o, a, b MapPoint) float64 {
+	return (a.X-o.X)*(b.Y-o.Y) - (a.Y-o.Y)*(b.X-o.X)
+}
+
+func onSegment(a, b, p MapPoint) bool {
+	return math.Min(a.X, b.X) <= p.X && p.X <= math.Max(a.X, b.X) &&
+		math.Min(a.Y, b.Y) <= p.Y && p.Y <= math.Max(a.Y, b.Y)
+}
+
+// signedArea 多边形有向面积，逆时针为正
+func signedArea(points []MapPoint) float64 {
+	area := 0.0
+	n := len(points)
+	for i, j := 0, n-1; i < n; j, i = i, i+1 {
+		area += points[j].X*points[i].Y - points[i].X*points[j].Y
+	}
+	return area / 2
+}
+
+func boundsOf(points []MapPoint) MapBounds {
+	if len(points) == 0 {
+		return MapBounds{}
+	}
+	b := MapBounds{Min: points[0], Max: points[0]}
+	for _, p := range points[1:] {
+		b.Min.X = math.Min(b.Min.X, p.X)
+		b.Min.Y = math.Min(b.Min.Y, p.Y)
+		b.Max.X = math.Max(b.Max.X, p.X)
+		b.Max.Y = math.Max(b.Max.Y, p.Y)
+	}
+	return b
+}
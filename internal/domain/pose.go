@@ -0,0 +1,152 @@
+// Package domain 位姿值对象
+//
+// 设计参考：
+// - ROS geometry_msgs/Pose 的位置+四元数表示
+// - 工业机器人控制器的RPY（ZYX欧拉角）约定
+package domain
+
+import "math"
+
+// Quaternion 单位四元数 - 值对象，用于表示姿态
+type Quaternion struct {
+	W float64 `json:"w"`
+	X float64 `json:"x"`
+	Y float64 `json:"y"`
+	Z float64 `json:"z"`
+}
+
+// Pose 笛卡尔位姿：位置 + 姿态
+type Pose struct {
+	Position    Position   `json:"position"`
+	Orientation Quaternion `json:"orientation"`
+}
+
+// QuaternionFromEuler 由RPY欧拉角（角度制，ZYX顺序）构造四元数
+func QuaternionFromEuler(roll, pitch, yaw float64) Quaternion {
+	r := roll * math.Pi / 180 / 2
+	p := pitch * math.Pi / 180 / 2
+	y := yaw * math.Pi / 180 / 2
+
+	cr, sr := math.Cos(r), math.Sin(r)
+	cp, sp := math.Cos(p), math.Sin(p)
+	cy, sy := math.Cos(y), math.Sin(y)
+
+	return Quaternion{
+		W: cr*cp*cy + sr*sp*sy,
+		X: sr*cp*cy - cr*sp*sy,
+		Y: cr*sp*cy + sr*cp*sy,
+		Z: cr*cp*sy - sr*sp*cy,
+	}.Normalize()
+}
+
+// ToEuler 转换为RPY欧拉角（角度制，ZYX顺序）
+func (q Quaternion) ToEuler() (roll, pitch, yaw float64) {
+	sinrCosp := 2 * (q.W*q.X + q.Y*q.Z)
+	cosrCosp := 1 - 2*(q.X*q.X+q.Y*q.Y)
+	roll = math.Atan2(sinrCosp, cosrCosp)
+
+	sinp := 2 * (q.W*q.Y - q.Z*q.X)
+	if math.Abs(sinp) >= 1 {
+		pitch = math.Copysign(math.Pi/2, sinp) // 万向节锁
+	} else {
+		pitch = math.Asin(sinp)
+	}
+
+	sinyCosp := 2 * (q.W*q.Z + q.X*q.Y)
+	cosyCosp := 1 - 2*(q.Y*q.Y+q.Z*q.Z)
+	yaw = math.Atan2(sinyCosp, cosyCosp)
+
+	return roll * 180 / math.Pi, pitch * 180 / math.Pi, yaw * 180 / math.Pi
+}
+
+// Dot 四元数点积
+func (q Quaternion) Dot(other Quaternion) float64 {
+	return q.W*other.W + q.X*other.X + q.Y*other.Y + q.Z*other.Z
+}
+
+// Negate 取反（表示同一姿态）
+func (q Quaternion) Negate() Quaternion {
+	return Quaternion{W: -q.W, X: -q.X, Y: -q.Y, Z: -q.Z}
+}
+
+// Normalize 归一化
+func (q Quaternion) Normalize() Quaternion {
+	n := math.Sqrt(q.Dot(q))
+	if n == 0 {
+		return Quaternion{W: 1}
+	}
+	return Quaternion{W: q.W / n, X: q.X / n, Y: q.Y / n, Z: q.Z / n}
+}
+
+// AngleTo 计算到另一个姿态的最短旋转角（角度制，0-180）
+func (q Quaternion) AngleTo(other Quaternion) float64 {
+	d := math.Abs(q.Dot(other))
+	if d > 1 {
+		d = 1
+	}
+	return 2 * math.Acos(d) * 180 / math.Pi
+}
+
+// Slerp 球面线性插值，t∈[0,1]，始终沿最短弧插值
+func Slerp(a, b Quaternion, t float64) Quaternion {
+	d := a.Dot(b)
+	if d < 0 {
+		b = b.Negate()
+		d = -d
+	}
+
+	// 两个姿态非常接近时退化为线性插值，避免除零
+	if d > 0.9995 {
+		return Quaternion{
+			W: a.W + t*(b.W-a.W),
+			X: a.X + t*(b.X-a.X),
+			Y: a.Y + t*(b.Y-a.Y),
+			Z: a.Z + t*(b.Z-a.Z),
+		}.Normalize()
+	}
+
+	theta0 := math.Acos(d)
+	theta := theta0 * t
+	sinTheta0 := math.Sin(theta0)
+	s0 := math.Cos(theta) - d*math.Sin(theta)/sinTheta0
+	s1 := math.Sin(theta) / sinTheta0
+
+	return Quaternion{
+		W: s0*a.W + s1*b.W,
+		X: s0*a.X + s1*b.X,
+		Y: s0*a.Y + s1*b.Y,
+		Z: s0*a.Z + s1*b.Z,
+	}.Normalize()
+}
+
+// LerpPosition 位置线性插值
+func LerpPosition(a, b Position, t float64) Position {
+	return Position{
+		X: a.X + t*(b.X-a.X),
+		Y: a.Y + t*(b.Y-a.Y),
+		Z: a.Z + t*(b.Z-a.Z),
+	}
+}
+
+// Pose 返回机器人坐标对应的笛卡尔位姿
+func (rc RobotCoordinates) Pose() Pose {
+	return Pose{
+		Position:    Position{X: rc.X, Y: rc.Y, Z: rc.Z},
+		Orientation: QuaternionFromEuler(rc.Roll, rc.Pitch, rc.Yaw),
+	}
+}
+
+// RotationVector 转换为旋转向量（轴角表示，弧度），与UR机器人的 rx, ry, rz 一致
+func (q Quaternion) RotationVector() (rx, ry, rz float64) {
+	q = q.Normalize()
+	if q.W < 0 {
+		q = q.Negate()
+	}
+
+	angle := 2 * math.Acos(math.Min(q.W, 1))
+	s := math.Sqrt(1 - q.W*q.W)
+	if s < 1e-9 {
+		return 0, 0, 0
+	}
+	return q.X / s * angle, q.Y / s * angle, q.Z / s * angle
+}
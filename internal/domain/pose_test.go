@@ -0,0 +1,139 @@
+package domain
+
+import (
+	"math"
+	"testing"
+)
+
+const epsilon = 1e-9
+
+func approx(a, b, tolerance float64) bool {
+	return math.Abs(a-b) <= tolerance
+}
+
+func TestQuaternionEulerRoundTrip(t *testing.T) {
+	tests := []struct {
+		name             string
+		roll, pitch, yaw float64
+	}{
+		{"单位姿态", 0, 0, 0},
+		{"仅偏航", 0, 0, 90},
+		{"负偏航", 0, 0, -135},
+		{"组合", 10, -20, 30},
+		{"大翻滚", 170, 45, -60},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q := QuaternionFromEuler(tt.roll, tt.pitch, tt.yaw)
+			if !approx(q.Dot(q), 1, epsilon) {
+				t.Errorf("|q|² = %v, want 1", q.Dot(q))
+			}
+			roll, pitch, yaw := q.ToEuler()
+			if !approx(roll, tt.roll, 1e-6) || !approx(pitch, tt.pitch, 1e-6) || !approx(yaw, tt.yaw, 1e-6) {
+				t.Errorf("ToEuler() = (%v, %v, %v), want (%v, %v, %v)", roll, pitch, yaw, tt.roll, tt.pitch, tt.yaw)
+			}
+		})
+	}
+}
+
+func TestQuaternionAngleTo(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b Quaternion
+		want float64
+	}{
+		{"相同姿态", QuaternionFromEuler(0, 0, 30), QuaternionFromEuler(0, 0, 30), 0},
+		{"偏航90°", QuaternionFromEuler(0, 0, 0), QuaternionFromEuler(0, 0, 90), 90},
+		{"跨越±180°取最短弧", QuaternionFromEuler(0, 0, 170), QuaternionFromEuler(0, 0, -170), 20},
+		{"q 与 -q 表示同一姿态", QuaternionFromEuler(0, 0, 45), QuaternionFromEuler(0, 0, 45).Negate(), 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.a.AngleTo(tt.b); !approx(got, tt.want, 1e-6) {
+				t.Errorf("AngleTo() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSlerp(t *testing.T) {
+	tests := []struct {
+		name    string
+		a, b    Quaternion
+		t       float64
+		wantYaw float64
+	}{
+		{"起点", QuaternionFromEuler(0, 0, 0), QuaternionFromEuler(0, 0, 90), 0, 0},
+		{"终点", QuaternionFromEuler(0, 0, 0), QuaternionFromEuler(0, 0, 90), 1, 90},
+		{"中点", QuaternionFromEuler(0, 0, 0), QuaternionFromEuler(0, 0, 90), 0.5, 45},
+		{"四分之一", QuaternionFromEuler(0, 0, 0), QuaternionFromEuler(0, 0, 120), 0.25, 30},
+		{"跨越±180°沿最短弧", QuaternionFromEuler(0, 0, 170), QuaternionFromEuler(0, 0, -170), 0.5, 180},
+		{"终点取反仍沿最短弧", QuaternionFromEuler(0, 0, 10), QuaternionFromEuler(0, 0, 50).Negate(), 0.5, 30},
+		{"非常接近时线性插值", QuaternionFromEuler(0, 0, 1), QuaternionFromEuler(0, 0, 1.01), 0.5, 1.005},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q := Slerp(tt.a, tt.b, tt.t)
+			if !approx(q.Dot(q), 1, epsilon) {
+				t.Errorf("|q|² = %v, want 1", q.Dot(q))
+			}
+			want := QuaternionFromEuler(0, 0, tt.wantYaw)
+			if angle := q.AngleTo(want); angle > 1e-4 {
+				t.Errorf("Slerp(t=%v) 与偏航 %v° 相差 %v°", tt.t, tt.wantYaw, angle)
+			}
+		})
+	}
+}
+
+func TestSlerpConstantAngularVelocity(t *testing.T) {
+	a := QuaternionFromEuler(0, 0, 0)
+	b := QuaternionFromEuler(30, 60, 90)
+	total := a.AngleTo(b)
+	const steps = 8
+	prev := a
+	for i := 1; i <= steps; i++ {
+		q := Slerp(a, b, float64(i)/steps)
+		if step := prev.AngleTo(q); !approx(step, total/steps, 1e-6) {
+			t.Errorf("第 %d 步旋转 %v°, want %v°", i, step, total/steps)
+		}
+		prev = q
+	}
+}
+
+func TestLerpPosition(t *testing.T) {
+	a, b := Position{X: 0, Y: 10, Z: -2}, Position{X: 4, Y: 0, Z: 2}
+	tests := []struct {
+		t    float64
+		want Position
+	}{
+		{0, a},
+		{1, b},
+		{0.25, Position{X: 1, Y: 7.5, Z: -1}},
+	}
+	for _, tt := range tests {
+		if got := LerpPosition(a, b, tt.t); got != tt.want {
+			t.Errorf("LerpPosition(t=%v) = %+v, want %+v", tt.t, got, tt.want)
+		}
+	}
+}
+
+func TestRotationVector(t *testing.T) {
+	tests := []struct {
+		name       string
+		q          Quaternion
+		rx, ry, rz float64
+	}{
+		{"单位姿态", Quaternion{W: 1}, 0, 0, 0},
+		{"绕Z轴90°", QuaternionFromEuler(0, 0, 90), 0, 0, math.Pi / 2},
+		{"绕X轴-90°", QuaternionFromEuler(-90, 0, 0), -math.Pi / 2, 0, 0},
+		{"取反后结果相同", QuaternionFromEuler(0, 0, 90).Negate(), 0, 0, math.Pi / 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rx, ry, rz := tt.q.RotationVector()
+			if !approx(rx, tt.rx, 1e-9) || !approx(ry, tt.ry, 1e-9) || !approx(rz, tt.rz, 1e-9) {
+				t.Errorf("RotationVector() = (%v, %v, %v), want (%v, %v, %v)", rx, ry, rz, tt.rx, tt.ry, tt.rz)
+			}
+		})
+	}
+}
@@ -2,7 +2,9 @@
 package domain
 
 import (
+	"encoding/json"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -48,6 +50,64 @@ type TemplateData struct {
 
 	// 布局参数
 	LayoutParams map[string]interface{} `json:"layout_params,omitempty"`
+
+	// 参数化：参数定义和重复块，应用模板时按参数值展开为具体的节点和路径
+	Parameters []TemplateParameter `json:"parameters,omitempty"`
+	Repeats    []TemplateRepeat    `json:"repeats,omitempty"`
+}
+
+// TemplateParameter 模板参数定义
+type TemplateParameter struct {
+	Name        string            `json:"name"`
+	Type        TemplateParamType `json:"type"`
+	Label       string            `json:"label,omitempty"`
+	Description string            `json:"description,omitempty"`
+	Default     interface{}       `json:"default,omitempty"`
+	Min         *float64          `json:"min,omitempty"`     // 数值参数的下限
+	Max         *float64          `json:"max,omitempty"`     // 数值参数的上限
+	Options     []string          `json:"options,omitempty"` // 字符串参数的可选值
+}
+
+// TemplateRepeat 重复块：循环变量从 From 开始取 Count 个连续整数，每次展开块内的节点、路径和嵌套重复块
+type TemplateRepeat struct {
+	Var     string           `json:"var,omitempty"` // 循环变量名，默认 i
+	From    TemplateExpr     `json:"from,omitempty"`
+	Count   TemplateExpr     `json:"count"`
+	Nodes   []TemplateNode   `json:"nodes,omitempty"`
+	Paths   []TemplatePath   `json:"paths,omitempty"`
+	Repeats []TemplateRepeat `json:"repeats,omitempty"`
+}
+
+// TemplateExpr 模板表达式，例如 "0.1 + i * spacing"；JSON/YAML 中也可以直接写数值或布尔值
+type TemplateExpr string
+
+// UnmarshalJSON 接受字符串、数值和布尔值
+func (e *TemplateExpr) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		*e = TemplateExpr(s)
+		return nil
+	}
+	var v interface{}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	switch v.(type) {
+	case float64, bool:
+		*e = TemplateExpr(strings.TrimSpace(string(data)))
+		return nil
+	case nil:
+		*e = ""
+		return nil
+	}
+	return fmt.Errorf("表达式必须是字符串、数值或布尔值")
+}
+
+// PositionExpression 相对位置表达式，为空的分量使用 RelativePosition 中的值
+type PositionExpression struct {
+	X TemplateExpr `json:"x,omitempty"`
+	Y TemplateExpr `json:"y,omitempty"`
+	Z TemplateExpr `json:"z,omitempty"`
 }
 
 // TemplateNode 模板中的节点定义
@@ -58,13 +118,17 @@ type TemplateNode struct {
 	Type       NodeType `json:"type"`
 
 	// 相对位置（0-1之间的比例）
-	RelativePosition RelativePosition `json:"relative_position"`
+	RelativePosition RelativePosition    `json:"relative_position"`
+	PositionExpr     *PositionExpression `json:"position_expr,omitempty"`
 
 	// 样式配置
 	Style NodeStyle `json:"style"`
 
 	// 额外属性
 	Properties map[string]interface{} `json:"properties,omitempty"`
+
+	// 条件表达式，为假时不生成该节点
+	When TemplateExpr `json:"when,omitempty"`
 }
 
 // TemplatePath 模板中的路径定义
@@ -78,6 +142,7 @@ type TemplatePath struct {
 	CurveType       CurveType              `json:"curve_type"`
 	Style           PathStyle              `json:"style"`
 	Properties      map[string]interface{} `json:"properties,omitempty"`
+	When            TemplateExpr           `json:"when,omitempty"` // 条件表达式，为假时不生成该路径
 }
 
 // RelativePosition 相对位置（0-1之间的比例坐标）
@@ -117,6 +182,43 @@ type TemplateMetadata struct {
 	Annotations map[string]string `json:"annotations,omitempty" gorm:"serializer:json"`
 }
 
+// TemplateRevision 模板修订：每次创建和更新模板时保存一份不可变的快照
+type TemplateRevision struct {
+	ID           string                 `json:"id" gorm:"primaryKey;type:varchar(36)"`
+	TemplateID   TemplateID             `json:"template_id" gorm:"type:varchar(36);not null;uniqueIndex:idx_template_revision"`
+	Revision     int                    `json:"revision" gorm:"not null;uniqueIndex:idx_template_revision"`
+	Name         string                 `json:"name" gorm:"type:varchar(100);not null"`
+	Description  string                 `json:"description" gorm:"type:text"`
+	Category     string                 `json:"category" gorm:"type:varchar(50)"`
+	Tags         []string               `json:"tags" gorm:"serializer:json"`
+	LayoutType   LayoutType             `json:"layout_type" gorm:"type:varchar(30)"`
+	LayoutConfig map[string]interface{} `json:"layout_config" gorm:"serializer:json"`
+	TemplateData TemplateData           `json:"template_data" gorm:"serializer:json"`
+	Message      string                 `json:"message" gorm:"type:text"`         // 修改说明
+	Changes      string                 `json:"changes" gorm:"type:varchar(255)"` // 与上一修订相比的变更摘要
+	CreatedBy    string                 `json:"created_by" gorm:"type:varchar(100)"`
+	CreatedAt    time.Time              `json:"created_at" gorm:"autoCreateTime"`
+}
+
+// NewTemplateRevision 由模板当前内容创建修订，修订号为模板版本号
+func NewTemplateRevision(t *Template, message string) *TemplateRevision {
+	return &TemplateRevision{
+		ID:           uuid.New().String(),
+		TemplateID:   t.ID,
+		Revision:     t.Metadata.Version,
+		Name:         t.Name,
+		Description:  t.Description,
+		Category:     t.Category,
+		Tags:         t.Tags,
+		LayoutType:   t.LayoutType,
+		LayoutConfig: t.LayoutConfig,
+		TemplateData: t.TemplateData,
+		Message:      message,
+		CreatedBy:    t.Metadata.CreatedBy,
+		CreatedAt:    time.Now(),
+	}
+}
+
 // === 枚举类型定义 ===
 
 // TemplateID 模板唯一标识符
@@ -146,6 +248,16 @@ const (
 	LayoutTypeCustom    LayoutType = "custom"    // 自定义布局
 )
 
+// TemplateParamType 模板参数类型
+type TemplateParamType string
+
+const (
+	TemplateParamInt    TemplateParamType = "int"    // 整数
+	TemplateParamFloat  TemplateParamType = "float"  // 浮点数
+	TemplateParamString TemplateParamType = "string" // 字符串
+	TemplateParamBool   TemplateParamType = "bool"   // 布尔值
+)
+
 // TemplateStatus 模板状态
 type TemplateStatus string
 
@@ -193,6 +305,11 @@ func NewTemplate(name, description string, layoutType LayoutType) *Template {
 
 // === 业务方法 ===
 
+// IsParameterized 模板是否包含参数或重复块
+func (d *TemplateData) IsParameterized() bool {
+	return len(d.Parameters) > 0 || len(d.Repeats) > 0
+}
+
 // IsValid 验证模板有效性
 func (t *Template) IsValid() error {
 	if t.Name == "" {
@@ -0,0 +1,58 @@
+// Package handlers DXF 导入导出相关的HTTP处理器
+package handlers
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+
+	"robot-path-editor/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ImportDXF 导入 DXF 图纸（multipart 表单：文件字段 file，可选的 options 为 JSON 格式的导入选项）
+func (h *Handlers) ImportDXF(c *gin.Context) {
+	data, err := readFormFile(c, "file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var opts services.DXFImportOptions
+	if raw := c.PostForm("options"); raw != "" {
+		if err := json.Unmarshal([]byte(raw), &opts); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "导入选项无效: " + err.Error()})
+			return
+		}
+	}
+
+	result, err := h.dxfService.ImportDXF(c.Request.Context(), data, opts)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"result": result})
+}
+
+// ExportDXF 将全部节点和路径导出为 DXF
+// 默认以附件形式下载，format=json 时直接返回JSON
+func (h *Handlers) ExportDXF(c *gin.Context) {
+	var opts services.DXFExportOptions
+	if err := c.ShouldBindJSON(&opts); err != nil && err != io.EOF {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	file, err := h.dxfService.ExportDXF(c.Request.Context(), opts)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if c.Query("format") == "json" {
+		c.JSON(http.StatusOK, gin.H{"export": file})
+		return
+	}
+	writeAttachment(c, file.Filename, "image/vnd.dxf", []byte(file.Content))
+}
@@ -0,0 +1,113 @@
+// Package handlers 导出相关的HTTP处理器
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+
+	"robot-path-editor/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ListRobotDialects 列出支持的机器人程序方言
+func (h *Handlers) ListRobotDialects(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"dialects": h.robotProgramService.ListDialects()})
+}
+
+// ExportRobotProgram 将路线导出为机器人程序
+// 默认以附件形式下载，format=json 时返回包含中间表示的JSON
+func (h *Handlers) ExportRobotProgram(c *gin.Context) {
+	var req services.ExportRobotProgramRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	file, err := h.robotProgramService.ExportRobotProgram(c.Request.Context(), req)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if c.Query("format") == "json" {
+		c.JSON(http.StatusOK, gin.H{"export": file})
+		return
+	}
+	writeAttachment(c, file.Filename, "text/plain; charset=utf-8", []byte(file.Content))
+}
+
+// ExportGCode 将路线或整个路径集导出为G-code
+// 默认以附件形式下载，format=json 时返回JSON
+func (h *Handlers) ExportGCode(c *gin.Context) {
+	var req services.ExportGCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	file, err := h.gcodeService.ExportGCode(c.Request.Context(), req)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if c.Query("format") == "json" {
+		c.JSON(http.StatusOK, gin.H{"export": file})
+		return
+	}
+	writeAttachment(c, file.Filename, "text/plain; charset=utf-8", []byte(file.Content))
+}
+
+// ExportVDA5050Order 将路线转换为 VDA 5050 order 消息
+func (h *Handlers) ExportVDA5050Order(c *gin.Context) {
+	var req services.VDA5050OrderRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	order, err := h.vda5050Service.GenerateOrder(c.Request.Context(), req)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"order": order})
+}
+
+// ValidateVDA5050Order 校验请求体中的 VDA 5050 order 消息
+func (h *Handlers) ValidateVDA5050Order(c *gin.Context) {
+	data, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.vda5050Service.ValidateOrder(data); err != nil {
+		var validationErr *services.VDA5050ValidationError
+		if errors.As(err, &validationErr) {
+			c.JSON(http.StatusOK, gin.H{"valid": false, "violations": validationErr.Violations})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"valid": true})
+}
+
+// writeAttachment 以附件形式返回文件内容
+func writeAttachment(c *gin.Context, filename, contentType string, data []byte) {
+	setAttachmentHeader(c, filename)
+	c.Data(http.StatusOK, contentType, data)
+}
+
+// setAttachmentHeader 设置附件下载文件名，兼容非ASCII文件名
+func setAttachmentHeader(c *gin.Context, filename string) {
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q; filename*=UTF-8''%s",
+		filename, url.PathEscape(filename)))
+}
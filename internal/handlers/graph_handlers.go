@@ -0,0 +1,55 @@
+// Package handlers 图格式导入导出相关的HTTP处理器
+package handlers
+
+import (
+	"io"
+	"net/http"
+
+	"robot-path-editor/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ExportGraph 将全部节点和路径导出为 GraphML、GEXF 或 DOT
+// 默认以附件形式下载，output=json 时直接返回JSON
+func (h *Handlers) ExportGraph(c *gin.Context) {
+	var opts services.GraphExportOptions
+	if err := c.ShouldBindQuery(&opts); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	file, err := h.graphService.ExportGraph(c.Request.Context(), opts)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if c.Query("output") == "json" {
+		c.JSON(http.StatusOK, gin.H{"export": file})
+		return
+	}
+	writeAttachment(c, file.Filename, file.ContentType, []byte(file.Content))
+}
+
+// ImportGraph 导入请求体中的 GraphML 或 DOT 图，选项通过查询参数传递
+func (h *Handlers) ImportGraph(c *gin.Context) {
+	var opts services.GraphImportOptions
+	if err := c.ShouldBindQuery(&opts); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	data, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	result, err := h.graphService.ImportGraph(c.Request.Context(), data, opts)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"result": result})
+}
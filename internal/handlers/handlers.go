@@ -13,7 +13,12 @@
 package handlers
 
 import (
+	"context"
+	"errors"
+	"io"
 	"net/http"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 
@@ -23,12 +28,25 @@ import (
 
 // Handlers HTTP处理器集合
 type Handlers struct {
-	nodeService     services.NodeService
-	pathService     services.PathService
-	layoutService   services.LayoutService
-	databaseService services.DatabaseService
-	dataSyncService services.DataSyncService
-	templateService services.TemplateService
+	nodeService              services.NodeService
+	pathService              services.PathService
+	layoutService            services.LayoutService
+	databaseService          services.DatabaseService
+	dataSyncService          services.DataSyncService
+	syncJobService           services.SyncJobService
+	tableEditorService       services.TableEditorService
+	templateService          services.TemplateService
+	poseInterpolationService services.PoseInterpolationService
+	robotProgramService      services.RobotProgramService
+	gcodeService             services.GCodeService
+	vda5050Service           services.VDA5050Service
+	nav2Service              services.Nav2Service
+	occupancyMapService      services.OccupancyMapService
+	dxfService               services.DXFService
+	spreadsheetService       services.SpreadsheetService
+	graphService             services.GraphService
+	renderService            services.RenderService
+	reportService            services.ReportService
 }
 
 // New 创建新的处理器实例
@@ -38,15 +56,41 @@ func New(
 	layoutService services.LayoutService,
 	databaseService services.DatabaseService,
 	dataSyncService services.DataSyncService,
+	syncJobService services.SyncJobService,
+	tableEditorService services.TableEditorService,
 	templateService services.TemplateService,
+	poseInterpolationService services.PoseInterpolationService,
+	robotProgramService services.RobotProgramService,
+	gcodeService services.GCodeService,
+	vda5050Service services.VDA5050Service,
+	nav2Service services.Nav2Service,
+	occupancyMapService services.OccupancyMapService,
+	dxfService services.DXFService,
+	spreadsheetService services.SpreadsheetService,
+	graphService services.GraphService,
+	renderService services.RenderService,
+	reportService services.ReportService,
 ) *Handlers {
 	return &Handlers{
-		nodeService:     nodeService,
-		pathService:     pathService,
-		layoutService:   layoutService,
-		databaseService: databaseService,
-		dataSyncService: dataSyncService,
-		templateService: templateService,
+		nodeService:              nodeService,
+		pathService:              pathService,
+		layoutService:            layoutService,
+		databaseService:          databaseService,
+		dataSyncService:          dataSyncService,
+		syncJobService:           syncJobService,
+		tableEditorService:       tableEditorService,
+		templateService:          templateService,
+		poseInterpolationService: poseInterpolationService,
+		robotProgramService:      robotProgramService,
+		gcodeService:             gcodeService,
+		vda5050Service:           vda5050Service,
+		nav2Service:              nav2Service,
+		occupancyMapService:      occupancyMapService,
+		dxfService:               dxfService,
+		spreadsheetService:       spreadsheetService,
+		graphService:             graphService,
+		renderService:            renderService,
+		reportService:            reportService,
 	}
 }
 
@@ -447,27 +491,121 @@ func (h *Handlers) CanvasWebSocket(c *gin.Context) {
 // 数据同步相关处理器
 func (h *Handlers) SyncNodesFromExternal(c *gin.Context) {
 	mappingID := c.Param("mappingId")
-	result, err := h.dataSyncService.SyncNodesFromExternal(c.Request.Context(), mappingID)
+	runSync(c, func(ctx context.Context) (*services.SyncResult, error) {
+		return h.dataSyncService.SyncNodesFromExternal(ctx, mappingID)
+	})
+}
+
+func (h *Handlers) SyncPathsFromExternal(c *gin.Context) {
+	mappingID := c.Param("mappingId")
+	runSync(c, func(ctx context.Context) (*services.SyncResult, error) {
+		return h.dataSyncService.SyncPathsFromExternal(ctx, mappingID)
+	})
+}
+
+func (h *Handlers) SyncAllDataFromExternal(c *gin.Context) {
+	mappingID := c.Param("mappingId")
+	runSync(c, func(ctx context.Context) (*services.SyncResult, error) {
+		return h.dataSyncService.SyncAllDataFromExternal(ctx, mappingID)
+	})
+}
+
+// runSync 执行一次拉取。?stream=true 或 Accept: text/event-stream 时以 SSE 推送 progress 事件，
+// 最后发送 result 或 error 事件；客户端断开时拉取随请求取消，已提交的批保留
+func runSync(c *gin.Context, pull func(ctx context.Context) (*services.SyncResult, error)) {
+	if c.Query("stream") != "true" && !strings.Contains(c.GetHeader("Accept"), "text/event-stream") {
+		result, err := pull(c.Request.Context())
+		if err != nil {
+			body := gin.H{"error": err.Error()}
+			if result != nil {
+				body["result"] = result
+			}
+			c.JSON(http.StatusInternalServerError, body)
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"result": result})
+		return
+	}
+
+	type outcome struct {
+		result *services.SyncResult
+		err    error
+	}
+	// 进度回调不阻塞拉取，推送跟不上时丢弃中间的进度
+	progress := make(chan services.SyncProgress, 16)
+	done := make(chan outcome, 1)
+	ctx := services.WithSyncProgress(c.Request.Context(), func(p services.SyncProgress) {
+		select {
+		case progress <- p:
+		default:
+		}
+	})
+	go func() {
+		result, err := pull(ctx)
+		done <- outcome{result, err}
+	}()
+
+	// 长时间的推送不受服务器写超时限制
+	_ = http.NewResponseController(c.Writer).SetWriteDeadline(time.Time{})
+	c.Header("Cache-Control", "no-cache")
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case p := <-progress:
+			c.SSEvent("progress", p)
+			return true
+		case out := <-done:
+			for len(progress) > 0 {
+				c.SSEvent("progress", <-progress)
+			}
+			if out.err != nil {
+				c.SSEvent("error", gin.H{"error": out.err.Error(), "result": out.result})
+			} else {
+				c.SSEvent("result", gin.H{"result": out.result})
+			}
+			return false
+		}
+	})
+}
+
+// PlanSync 生成三方比较的同步计划
+func (h *Handlers) PlanSync(c *gin.Context) {
+	plan, err := h.dataSyncService.PlanSync(c.Request.Context(), c.Param("mappingId"))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
-	c.JSON(http.StatusOK, gin.H{"result": result})
+	c.JSON(http.StatusOK, gin.H{"plan": plan})
 }
 
-func (h *Handlers) SyncPathsFromExternal(c *gin.Context) {
-	mappingID := c.Param("mappingId")
-	result, err := h.dataSyncService.SyncPathsFromExternal(c.Request.Context(), mappingID)
+// ApplySyncPlan 按冲突解决策略应用同步计划
+func (h *Handlers) ApplySyncPlan(c *gin.Context) {
+	var req services.ApplySyncPlanRequest
+	if err := c.ShouldBindJSON(&req); err != nil && err != io.EOF {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	result, err := h.dataSyncService.ApplySyncPlan(c.Request.Context(), c.Param("mappingId"), req)
 	if err != nil {
+		var conflictErr *services.SyncConflictError
+		if errors.As(err, &conflictErr) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error(), "unresolved": conflictErr.Keys})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"result": result})
 }
 
-func (h *Handlers) SyncAllDataFromExternal(c *gin.Context) {
-	mappingID := c.Param("mappingId")
-	result, err := h.dataSyncService.SyncAllDataFromExternal(c.Request.Context(), mappingID)
+// PushToExternal 把本地数据的变化写回外部表；选项由请求体传入，请求体可为空
+func (h *Handlers) PushToExternal(c *gin.Context) {
+	var opts services.PushOptions
+	if err := c.ShouldBindJSON(&opts); err != nil && err != io.EOF {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	result, err := h.dataSyncService.PushToExternal(c.Request.Context(), c.Param("mappingId"), opts)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -475,16 +613,37 @@ func (h *Handlers) SyncAllDataFromExternal(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"result": result})
 }
 
+// ListSyncQuarantine 列出表映射隔离中的路径（端点节点在本地缺失而未写入）
+func (h *Handlers) ListSyncQuarantine(c *gin.Context) {
+	entries, err := h.dataSyncService.ListSyncQuarantine(c.Request.Context(), c.Param("mappingId"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"quarantine": entries, "total": len(entries)})
+}
+
+// DiscardSyncQuarantine 移出一条隔离记录
+func (h *Handlers) DiscardSyncQuarantine(c *gin.Context) {
+	err := h.dataSyncService.DiscardSyncQuarantine(c.Request.Context(), c.Param("mappingId"), c.Param("pathId"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "隔离记录已移出"})
+}
+
 func (h *Handlers) ValidateExternalTable(c *gin.Context) {
 	connectionID := c.Query("connection_id")
 	tableName := c.Query("table_name")
+	schema := c.Query("schema")
 
 	if connectionID == "" || tableName == "" {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "需要提供connection_id和table_name参数"})
 		return
 	}
 
-	result, err := h.dataSyncService.ValidateExternalTable(c.Request.Context(), connectionID, tableName)
+	result, err := h.dataSyncService.ValidateExternalTable(c.Request.Context(), connectionID, schema, tableName)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -0,0 +1,47 @@
+// Package handlers 位姿插值相关的HTTP处理器
+package handlers
+
+import (
+	"net/http"
+
+	"robot-path-editor/internal/domain"
+	"robot-path-editor/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// InterpolatePath 对单条路径进行位姿插值
+func (h *Handlers) InterpolatePath(c *gin.Context) {
+	var opts services.InterpolationOptions
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&opts); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	result, err := h.poseInterpolationService.InterpolatePath(c.Request.Context(), domain.PathID(c.Param("id")), opts)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"result": result})
+}
+
+// InterpolateRoute 对路线进行位姿插值
+func (h *Handlers) InterpolateRoute(c *gin.Context) {
+	var req services.InterpolateRouteRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	result, err := h.poseInterpolationService.InterpolateRoute(c.Request.Context(), req)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"result": result})
+}
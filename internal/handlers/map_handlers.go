@@ -0,0 +1,183 @@
+// Package handlers 占据栅格地图相关的HTTP处理器
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"robot-path-editor/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// mapUploadLimit 单个上传文件的大小上限
+const mapUploadLimit = 64 << 20
+
+// mapRequestLimit 地图导入请求的大小上限：yaml 和图像两个文件加表单字段
+const mapRequestLimit = 2*mapUploadLimit + 1<<20
+
+// ImportMap 上传 map.yaml 和 PGM/PNG 图像（multipart 表单，文件字段 yaml 和 image）
+func (h *Handlers) ImportMap(c *gin.Context) {
+	// 解析表单前限制请求体大小，超大的请求不会整体写入临时文件
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, mapRequestLimit)
+
+	yamlData, err := readFormFile(c, "yaml")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	imageData, err := readFormFile(c, "image")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	req := services.ImportMapRequest{
+		Name:        c.PostForm("name"),
+		Description: c.PostForm("description"),
+		YAML:        yamlData,
+		Image:       imageData,
+		Activate:    c.PostForm("activate") == "true",
+	}
+	if err := c.ShouldBind(&req.ObstacleOptions); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	m, err := h.occupancyMapService.ImportMap(c.Request.Context(), req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"map": m})
+}
+
+// ListMaps 列出地图
+func (h *Handlers) ListMaps(c *gin.Context) {
+	maps, err := h.occupancyMapService.ListMaps(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"maps": maps})
+}
+
+// GetMap 获取地图详情和瓦片布局
+func (h *Handlers) GetMap(c *gin.Context) {
+	detail, err := h.occupancyMapService.GetMap(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, detail)
+}
+
+// DeleteMap 删除地图
+func (h *Handlers) DeleteMap(c *gin.Context) {
+	if err := h.occupancyMapService.DeleteMap(c.Request.Context(), c.Param("id")); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "地图删除成功"})
+}
+
+// ActivateMap 设为当前地图
+func (h *Handlers) ActivateMap(c *gin.Context) {
+	if err := h.occupancyMapService.ActivateMap(c.Request.Context(), c.Param("id")); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "已设为当前地图"})
+}
+
+// GetMapImage 获取整张地图的PNG渲染
+func (h *Handlers) GetMapImage(c *gin.Context) {
+	data, err := h.occupancyMapService.RenderImage(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.Data(http.StatusOK, "image/png", data)
+}
+
+// GetMapTile 获取地图瓦片 /:z/:x/:y，y 可带 .png 后缀
+func (h *Handlers) GetMapTile(c *gin.Context) {
+	var coords [3]int
+	for i, name := range []string{"z", "x", "y"} {
+		value, err := strconv.Atoi(strings.TrimSuffix(c.Param(name), ".png"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "无效的瓦片坐标: " + c.Param(name)})
+			return
+		}
+		coords[i] = value
+	}
+
+	data, err := h.occupancyMapService.GetTile(c.Request.Context(), c.Param("id"), coords[0], coords[1], coords[2])
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.Header("Cache-Control", "public, max-age=86400")
+	c.Data(http.StatusOK, "image/png", data)
+}
+
+// GetMapObstacles 获取地图的障碍物多边形
+func (h *Handlers) GetMapObstacles(c *gin.Context) {
+	detail, err := h.occupancyMapService.GetMap(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"obstacles": detail.Map.Obstacles, "count": len(detail.Map.Obstacles)})
+}
+
+// RegenerateMapObstacles 按新的简化参数重新提取障碍物
+func (h *Handlers) RegenerateMapObstacles(c *gin.Context) {
+	var opts services.ObstacleOptions
+	if err := c.ShouldBindJSON(&opts); err != nil && err != io.EOF {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	m, err := h.occupancyMapService.RegenerateObstacles(c.Request.Context(), c.Param("id"), opts)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"obstacles": m.Obstacles, "count": len(m.Obstacles)})
+}
+
+// ValidateMapLayout 校验节点和路径是否与地图冲突，未指定地图时使用当前地图
+func (h *Handlers) ValidateMapLayout(c *gin.Context) {
+	report, err := h.occupancyMapService.ValidateLayout(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"report": report})
+}
+
+// readFormFile 读取 multipart 表单中的文件
+func readFormFile(c *gin.Context, field string) ([]byte, error) {
+	header, err := c.FormFile(field)
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			return nil, fmt.Errorf("请求超过大小上限 %d 字节", tooLarge.Limit)
+		}
+		return nil, fmt.Errorf("缺少文件 %s: %w", field, err)
+	}
+	if header.Size > mapUploadLimit {
+		return nil, fmt.Errorf("文件 %s 超过大小上限 %d 字节", field, mapUploadLimit)
+	}
+	file, err := header.Open()
+	if err != nil {
+		return nil, fmt.Errorf("读取文件 %s 失败: %w", field, err)
+	}
+	defer file.Close()
+	return io.ReadAll(file)
+}
@@ -0,0 +1,89 @@
+// Package handlers ROS 2 Nav2 互操作相关的HTTP处理器
+package handlers
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+
+	"robot-path-editor/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ExportNav2Graph 将全部节点和路径导出为 Nav2 路由图（GeoJSON）
+// 默认以附件形式下载，format=json 时直接返回JSON
+func (h *Handlers) ExportNav2Graph(c *gin.Context) {
+	var opts services.Nav2GraphOptions
+	if err := c.ShouldBindQuery(&opts); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	graph, err := h.nav2Service.ExportGraph(c.Request.Context(), opts)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if c.Query("format") == "json" {
+		c.JSON(http.StatusOK, gin.H{"graph": graph})
+		return
+	}
+
+	data, err := json.MarshalIndent(graph, "", "  ")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	writeAttachment(c, graph.Name+".geojson", "application/geo+json", data)
+}
+
+// ImportNav2Graph 导入请求体中的 Nav2 路由图，选项通过查询参数传递
+func (h *Handlers) ImportNav2Graph(c *gin.Context) {
+	var opts services.Nav2GraphOptions
+	if err := c.ShouldBindQuery(&opts); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	data, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	result, err := h.nav2Service.ImportGraph(c.Request.Context(), data, opts)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"result": result})
+}
+
+// ExportNavPath 将路线导出为 nav_msgs/Path 风格的 YAML 或 JSON
+func (h *Handlers) ExportNavPath(c *gin.Context) {
+	var req services.NavPathRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	file, err := h.nav2Service.ExportNavPath(c.Request.Context(), req)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if c.Query("format") == "json" {
+		c.JSON(http.StatusOK, gin.H{"export": file})
+		return
+	}
+
+	contentType := "application/x-yaml"
+	if file.Format == "json" {
+		contentType = "application/json"
+	}
+	writeAttachment(c, file.Filename, contentType, []byte(file.Content))
+}
@@ -0,0 +1,42 @@
+// Package handlers 地图与模板渲染相关的HTTP处理器
+package handlers
+
+import (
+	"net/http"
+
+	"robot-path-editor/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RenderMap 将当前地图渲染为 SVG 或 PNG 图像
+func (h *Handlers) RenderMap(c *gin.Context) {
+	var opts services.RenderOptions
+	if err := c.ShouldBindQuery(&opts); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	image, err := h.renderService.RenderMap(c.Request.Context(), opts)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.Data(http.StatusOK, image.ContentType, image.Data)
+}
+
+// RenderTemplate 将模板渲染为 SVG 或 PNG 图像
+func (h *Handlers) RenderTemplate(c *gin.Context) {
+	var opts services.RenderOptions
+	if err := c.ShouldBindQuery(&opts); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	image, err := h.renderService.RenderTemplate(c.Request.Context(), c.Param("id"), opts)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.Data(http.StatusOK, image.ContentType, image.Data)
+}
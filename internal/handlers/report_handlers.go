@@ -0,0 +1,32 @@
+// Package handlers 地图报告相关的HTTP处理器
+package handlers
+
+import (
+	"net/http"
+
+	"robot-path-editor/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GenerateMapReport 生成当前地图的 PDF 报告
+// 默认以附件形式下载，output=json 时返回报告汇总
+func (h *Handlers) GenerateMapReport(c *gin.Context) {
+	var opts services.ReportOptions
+	if err := c.ShouldBindQuery(&opts); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	report, err := h.reportService.GenerateMapReport(c.Request.Context(), opts)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if c.Query("output") == "json" {
+		c.JSON(http.StatusOK, gin.H{"report": report})
+		return
+	}
+	writeAttachment(c, report.Filename, report.ContentType, report.Data)
+}
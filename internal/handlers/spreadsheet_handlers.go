@@ -0,0 +1,60 @@
+// Package handlers 表格导入导出相关的HTTP处理器
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"robot-path-editor/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ExportTables 导出节点表和路径表
+// 查询参数：format=csv|xlsx，table=nodes|paths|all，properties=键1,键2 或 *，headers=key|label
+func (h *Handlers) ExportTables(c *gin.Context) {
+	var opts services.TableExportOptions
+	if err := c.ShouldBindQuery(&opts); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	export, err := h.spreadsheetService.ExportTables(c.Request.Context(), opts)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// 数据已读取完成，之后的错误只能中断响应
+	setAttachmentHeader(c, export.Filename)
+	c.Header("Content-Type", export.ContentType)
+	c.Status(http.StatusOK)
+	if err := export.Stream(c.Writer); err != nil {
+		_ = c.Error(err)
+	}
+}
+
+// ImportTables 导入节点表和路径表（multipart 表单：文件字段 file，可选的 options 为 JSON 格式的导入选项）
+// 存在行错误时仍返回200，错误明细在 result.errors 中
+func (h *Handlers) ImportTables(c *gin.Context) {
+	data, err := readFormFile(c, "file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var opts services.TableImportOptions
+	if raw := c.PostForm("options"); raw != "" {
+		if err := json.Unmarshal([]byte(raw), &opts); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "导入选项无效: " + err.Error()})
+			return
+		}
+	}
+
+	result, err := h.spreadsheetService.ImportTables(c.Request.Context(), data, opts)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"result": result})
+}
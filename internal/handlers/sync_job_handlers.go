@@ -0,0 +1,174 @@
+// Package handlers 后台同步任务相关的HTTP处理器
+package handlers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"robot-path-editor/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ListSyncJobs 列出同步任务
+func (h *Handlers) ListSyncJobs(c *gin.Context) {
+	jobs, err := h.syncJobService.ListJobs(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"jobs": jobs})
+}
+
+// CreateSyncJob 创建同步任务
+func (h *Handlers) CreateSyncJob(c *gin.Context) {
+	var req services.CreateSyncJobRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	job, err := h.syncJobService.CreateJob(c.Request.Context(), req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"job": job})
+}
+
+// GetSyncJob 获取同步任务
+func (h *Handlers) GetSyncJob(c *gin.Context) {
+	job, err := h.syncJobService.GetJob(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"job": job})
+}
+
+// UpdateSyncJob 更新同步任务
+func (h *Handlers) UpdateSyncJob(c *gin.Context) {
+	var req services.UpdateSyncJobRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	req.ID = c.Param("id")
+	job, err := h.syncJobService.UpdateJob(c.Request.Context(), req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"job": job})
+}
+
+// DeleteSyncJob 删除同步任务
+func (h *Handlers) DeleteSyncJob(c *gin.Context) {
+	if err := h.syncJobService.DeleteJob(c.Request.Context(), c.Param("id")); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "同步任务已删除"})
+}
+
+// TriggerSyncJob 立即在后台运行一次，返回 202 和运行记录；任务正在运行时返回 409
+func (h *Handlers) TriggerSyncJob(c *gin.Context) {
+	run, err := h.syncJobService.TriggerJob(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, services.ErrSyncJobRunning) {
+			status = http.StatusConflict
+		}
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusAccepted, gin.H{"run": run})
+}
+
+// PauseSyncJob 暂停同步任务
+func (h *Handlers) PauseSyncJob(c *gin.Context) {
+	job, err := h.syncJobService.PauseJob(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"job": job})
+}
+
+// ResumeSyncJob 恢复同步任务
+func (h *Handlers) ResumeSyncJob(c *gin.Context) {
+	job, err := h.syncJobService.ResumeJob(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"job": job})
+}
+
+// ListSyncJobRuns 列出运行记录，?limit= 限制条数
+func (h *Handlers) ListSyncJobRuns(c *gin.Context) {
+	limit, _ := strconv.Atoi(c.Query("limit"))
+	runs, err := h.syncJobService.ListJobRuns(c.Request.Context(), c.Param("id"), limit)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"runs": runs})
+}
+
+// CancelSyncJob 取消正在进行的运行；没有正在进行的运行时返回 409
+func (h *Handlers) CancelSyncJob(c *gin.Context) {
+	if err := h.syncJobService.CancelJob(c.Request.Context(), c.Param("id")); err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, services.ErrSyncJobNotRunning) {
+			status = http.StatusConflict
+		}
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusAccepted, gin.H{"message": "已请求取消同步运行"})
+}
+
+// WatchSyncJob 以 SSE 推送正在进行的运行的 progress 事件，运行结束后发送 run 事件（运行记录）；
+// 没有正在进行的运行时返回 409
+func (h *Handlers) WatchSyncJob(c *gin.Context) {
+	id := c.Param("id")
+	progress, unwatch, err := h.syncJobService.WatchJob(c.Request.Context(), id)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, services.ErrSyncJobNotRunning) {
+			status = http.StatusConflict
+		}
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+	defer unwatch()
+
+	_ = http.NewResponseController(c.Writer).SetWriteDeadline(time.Time{})
+	c.Header("Cache-Control", "no-cache")
+	c.Stream(func(w io.Writer) bool {
+		var p services.SyncProgress
+		ok := false
+		select {
+		case p, ok = <-progress:
+		case <-c.Request.Context().Done():
+			return false
+		}
+		if ok {
+			c.SSEvent("progress", p)
+			return true
+		}
+		// 通道关闭时运行已结束并记录了结果
+		runs, err := h.syncJobService.ListJobRuns(c.Request.Context(), id, 1)
+		if err != nil || len(runs) == 0 {
+			c.SSEvent("error", gin.H{"error": "获取运行记录失败"})
+			return false
+		}
+		c.SSEvent("run", gin.H{"run": runs[0]})
+		return false
+	})
+}
@@ -0,0 +1,73 @@
+// Package handlers 外部表浏览和编辑相关的HTTP处理器
+package handlers
+
+import (
+	"io"
+	"net/http"
+
+	"robot-path-editor/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ListExternalSchemas 列出数据库连接中的模式
+func (h *Handlers) ListExternalSchemas(c *gin.Context) {
+	schemas, err := h.tableEditorService.ListSchemas(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"schemas": schemas})
+}
+
+// ListExternalTables 列出表和视图，?schema= 指定模式
+func (h *Handlers) ListExternalTables(c *gin.Context) {
+	tables, err := h.tableEditorService.ListTables(c.Request.Context(), c.Param("id"), c.Query("schema"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"tables": tables})
+}
+
+// DescribeExternalTable 读取列、主键和索引，?schema= 指定模式
+func (h *Handlers) DescribeExternalTable(c *gin.Context) {
+	table, err := h.tableEditorService.DescribeTable(c.Request.Context(), c.Param("id"), c.Query("schema"), c.Param("table"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"table": table})
+}
+
+// QueryExternalRows 按筛选条件和排序分页读取行，请求体可省略
+func (h *Handlers) QueryExternalRows(c *gin.Context) {
+	var req services.TableRowsQuery
+	if err := c.ShouldBindJSON(&req); err != nil && err != io.EOF {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	page, err := h.tableEditorService.QueryRows(c.Request.Context(), c.Param("id"), c.Param("table"), req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, page)
+}
+
+// WriteExternalRows 在一个事务中执行行修改，dry_run 时执行后回滚作为预览
+func (h *Handlers) WriteExternalRows(c *gin.Context) {
+	var req services.TableWriteRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	result, err := h.tableEditorService.WriteRows(c.Request.Context(), c.Param("id"), c.Param("table"), req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"result": result})
+}
@@ -2,9 +2,14 @@
 package handlers
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"net/http"
+	"strconv"
+	"strings"
 
-	"robot-path-editor/internal/domain"
 	"robot-path-editor/internal/services"
 
 	"github.com/gin-gonic/gin"
@@ -120,26 +125,32 @@ func (h *Handlers) GetTemplatesByCategory(c *gin.Context) {
 func (h *Handlers) ApplyTemplate(c *gin.Context) {
 	templateID := c.Param("id")
 
-	var canvasConfig struct {
-		Width  int `json:"width"`
-		Height int `json:"height"`
+	// 获取画布配置、参数值和放置选项，画布尺寸没有提供时使用默认值
+	var req services.ApplyTemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil && err != io.EOF {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
 	}
-
-	// 获取画布配置，如果没有提供则使用默认值
-	if err := c.ShouldBindJSON(&canvasConfig); err != nil {
-		canvasConfig.Width = 1920
-		canvasConfig.Height = 1080
+	if req.Width <= 0 || req.Height <= 0 {
+		req.Width = 1920
+		req.Height = 1080
 	}
 
-	response, err := h.templateService.ApplyTemplate(c.Request.Context(), templateID, domain.CanvasConfig{
-		Width:  canvasConfig.Width,
-		Height: canvasConfig.Height,
-	})
+	response, err := h.templateService.ApplyTemplate(c.Request.Context(), templateID, req)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		var expandErr *services.TemplateExpandError
+		if errors.As(err, &expandErr) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "problems": expandErr.Problems})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
+	if response.Persisted {
+		c.JSON(http.StatusCreated, gin.H{"result": response})
+		return
+	}
 	c.JSON(http.StatusOK, gin.H{"result": response})
 }
 
@@ -179,34 +190,213 @@ func (h *Handlers) CloneTemplate(c *gin.Context) {
 	c.JSON(http.StatusCreated, gin.H{"template": template})
 }
 
+// ExportTemplate 导出模板文件（?format=json|yaml），download=true 时直接下载文件
 func (h *Handlers) ExportTemplate(c *gin.Context) {
 	templateID := c.Param("id")
 
-	response, err := h.templateService.ExportTemplate(c.Request.Context(), templateID)
+	response, err := h.templateService.ExportTemplate(c.Request.Context(), templateID, c.Query("format"))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
+	if c.Query("download") == "true" {
+		writeAttachment(c, response.Filename, response.ContentType+"; charset=utf-8", []byte(response.Content))
+		return
+	}
 	c.JSON(http.StatusOK, gin.H{"export": response})
 }
 
+// ImportTemplate 导入模板文件
+// 请求体可以是 {"content": "...", "format": "...", "name": "...", "dry_run": false}，
+// 也可以直接是模板文件本身（JSON/YAML 或 multipart 文件字段 file），此时选项从查询参数读取
 func (h *Handlers) ImportTemplate(c *gin.Context) {
-	var req services.ImportTemplateRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
+	req, err := readImportTemplateRequest(c)
+	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
 	template, err := h.templateService.ImportTemplate(c.Request.Context(), req)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		var validationErr *services.TemplateValidationError
+		if errors.As(err, &validationErr) {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error(), "problems": validationErr.Problems})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
+	if req.DryRun {
+		c.JSON(http.StatusOK, gin.H{"valid": true, "template": template})
+		return
+	}
 	c.JSON(http.StatusCreated, gin.H{"template": template})
 }
 
+// readImportTemplateRequest 解析导入请求的两种形式
+func readImportTemplateRequest(c *gin.Context) (services.ImportTemplateRequest, error) {
+	req := services.ImportTemplateRequest{
+		Format: c.Query("format"),
+		Name:   c.Query("name"),
+		DryRun: c.Query("dry_run") == "true",
+	}
+
+	var data []byte
+	var err error
+	if strings.HasPrefix(c.ContentType(), "multipart/") {
+		data, err = readFormFile(c, "file")
+	} else {
+		data, err = io.ReadAll(c.Request.Body)
+	}
+	if err != nil {
+		return req, err
+	}
+
+	var wrapped struct {
+		Content *string `json:"content"`
+		Format  string  `json:"format"`
+		Name    string  `json:"name"`
+		DryRun  bool    `json:"dry_run"`
+	}
+	if json.Unmarshal(data, &wrapped) == nil && wrapped.Content != nil && !strings.Contains(wrapped.Format, "/") {
+		req.Content = *wrapped.Content
+		if wrapped.Format != "" {
+			req.Format = wrapped.Format
+		}
+		if wrapped.Name != "" {
+			req.Name = wrapped.Name
+		}
+		req.DryRun = req.DryRun || wrapped.DryRun
+	} else {
+		req.Content = string(data)
+	}
+
+	if strings.TrimSpace(req.Content) == "" {
+		return req, fmt.Errorf("模板文件内容为空")
+	}
+	return req, nil
+}
+
+// ExportTemplatePack 导出模板包（zip）
+func (h *Handlers) ExportTemplatePack(c *gin.Context) {
+	var opts services.TemplatePackExportOptions
+	if err := c.ShouldBindQuery(&opts); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	pack, err := h.templateService.ExportTemplatePack(c.Request.Context(), opts)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	writeAttachment(c, pack.Filename, pack.ContentType, pack.Data)
+}
+
+// ImportTemplatePack 导入模板包（multipart 文件字段 file 或原始 zip 请求体）
+// 单个模板失败时仍返回200，明细在 result.items 中
+func (h *Handlers) ImportTemplatePack(c *gin.Context) {
+	var opts services.TemplatePackImportOptions
+	if err := c.ShouldBindQuery(&opts); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var data []byte
+	var err error
+	if strings.HasPrefix(c.ContentType(), "multipart/") {
+		data, err = readFormFile(c, "file")
+	} else {
+		data, err = io.ReadAll(c.Request.Body)
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	result, err := h.templateService.ImportTemplatePack(c.Request.Context(), data, opts)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"result": result})
+}
+
+// ListTemplateRevisions 模板修订历史（变更日志）
+func (h *Handlers) ListTemplateRevisions(c *gin.Context) {
+	revisions, err := h.templateService.ListTemplateRevisions(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"revisions": revisions})
+}
+
+// GetTemplateRevision 获取指定修订的完整内容
+func (h *Handlers) GetTemplateRevision(c *gin.Context) {
+	revision, err := strconv.Atoi(c.Param("revision"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "修订号必须是整数"})
+		return
+	}
+
+	result, err := h.templateService.GetTemplateRevision(c.Request.Context(), c.Param("id"), revision)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"revision": result})
+}
+
+// DiffTemplateRevisions 比较两个修订（?from=1&to=3），省略 to 时与当前版本比较
+func (h *Handlers) DiffTemplateRevisions(c *gin.Context) {
+	from, err := strconv.Atoi(c.Query("from"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "from 必须是修订号"})
+		return
+	}
+	to := 0
+	if value := c.Query("to"); value != "" {
+		if to, err = strconv.Atoi(value); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "to 必须是修订号"})
+			return
+		}
+	}
+
+	diff, err := h.templateService.DiffTemplateRevisions(c.Request.Context(), c.Param("id"), from, to)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"diff": diff})
+}
+
+// RestoreTemplateRevision 恢复到旧修订，生成新版本
+func (h *Handlers) RestoreTemplateRevision(c *gin.Context) {
+	revision, err := strconv.Atoi(c.Param("revision"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "修订号必须是整数"})
+		return
+	}
+
+	var req struct {
+		Message string `json:"message"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil && err != io.EOF {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	template, err := h.templateService.RestoreTemplateRevision(c.Request.Context(), c.Param("id"), revision, req.Message)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"template": template})
+}
+
 // 获取模板统计信息
 func (h *Handlers) GetTemplateStats(c *gin.Context) {
 	// 这里可以实现模板统计功能
@@ -1,11 +1,16 @@
 // Package repositories 数据库连接仓储实现
+//
+// 密码和敏感连接属性在写入前用主密钥加密，读取时解密；仓储之外只接触明文
 package repositories
 
 import (
 	"context"
+	"fmt"
+	"strings"
 
 	"robot-path-editor/internal/database"
 	"robot-path-editor/internal/domain"
+	"robot-path-editor/pkg/secrets"
 )
 
 // DatabaseConnectionRepository 数据库连接仓储接口
@@ -22,55 +27,75 @@ type DatabaseConnectionRepository interface {
 
 	// 连接测试
 	TestConnection(ctx context.Context, id string) error
+
+	// 用当前主密钥重新加密全部连接的敏感信息（明文和历史密钥的密文），返回重新加密的连接数；
+	// 无法解密的连接跳过，其余连接照常提交，并返回列出跳过连接的错误
+	RotateSecrets(ctx context.Context) (int, error)
 }
 
 // databaseConnectionRepository GORM实现
 type databaseConnectionRepository struct {
-	db database.Database
+	db      database.Database
+	keyring *secrets.Keyring
 }
 
-// NewDatabaseConnectionRepository 创建新的数据库连接仓储实例
-func NewDatabaseConnectionRepository(db database.Database) DatabaseConnectionRepository {
-	return &databaseConnectionRepository{db: db}
+// NewDatabaseConnectionRepository 创建新的数据库连接仓储实例，keyring 为 nil 或未配置主密钥时明文保存
+func NewDatabaseConnectionRepository(db database.Database, keyring *secrets.Keyring) DatabaseConnectionRepository {
+	return &databaseConnectionRepository{db: db, keyring: keyring}
 }
 
 // Create 创建数据库连接配置
 func (r *databaseConnectionRepository) Create(ctx context.Context, conn *domain.DatabaseConnection) error {
-	return r.db.GORMDB().WithContext(ctx).Create(conn).Error
+	stored, err := r.seal(conn)
+	if err != nil {
+		return err
+	}
+	return r.db.Session(ctx).Create(stored).Error
 }
 
 // GetByID 根据ID获取数据库连接配置
 func (r *databaseConnectionRepository) GetByID(ctx context.Context, id string) (*domain.DatabaseConnection, error) {
 	var conn domain.DatabaseConnection
-	err := r.db.GORMDB().WithContext(ctx).Where("id = ?", id).First(&conn).Error
+	err := r.db.Session(ctx).Where("id = ?", id).First(&conn).Error
 	if err != nil {
 		return nil, err
 	}
+	r.openOrMark(&conn)
 	return &conn, nil
 }
 
 // Update 更新数据库连接配置
 func (r *databaseConnectionRepository) Update(ctx context.Context, conn *domain.DatabaseConnection) error {
-	return r.db.GORMDB().WithContext(ctx).Save(conn).Error
+	stored, err := r.seal(conn)
+	if err != nil {
+		return err
+	}
+	return r.db.Session(ctx).Save(stored).Error
 }
 
 // Delete 删除数据库连接配置
 func (r *databaseConnectionRepository) Delete(ctx context.Context, id string) error {
-	return r.db.GORMDB().WithContext(ctx).Delete(&domain.DatabaseConnection{}, "id = ?", id).Error
+	return r.db.Session(ctx).Delete(&domain.DatabaseConnection{}, "id = ?", id).Error
 }
 
 // List 列出所有数据库连接配置
 func (r *databaseConnectionRepository) List(ctx context.Context) ([]*domain.DatabaseConnection, error) {
 	var connections []*domain.DatabaseConnection
-	err := r.db.GORMDB().WithContext(ctx).Find(&connections).Error
-	return connections, err
+	if err := r.db.Session(ctx).Find(&connections).Error; err != nil {
+		return nil, err
+	}
+	r.openAll(connections)
+	return connections, nil
 }
 
 // GetByType 根据数据库类型获取连接配置
 func (r *databaseConnectionRepository) GetByType(ctx context.Context, dbType string) ([]*domain.DatabaseConnection, error) {
 	var connections []*domain.DatabaseConnection
-	err := r.db.GORMDB().WithContext(ctx).Where("db_type = ?", dbType).Find(&connections).Error
-	return connections, err
+	if err := r.db.Session(ctx).Where("db_type = ?", dbType).Find(&connections).Error; err != nil {
+		return nil, err
+	}
+	r.openAll(connections)
+	return connections, nil
 }
 
 // TestConnection 测试数据库连接
@@ -86,3 +111,108 @@ func (r *databaseConnectionRepository) TestConnection(ctx context.Context, id st
 	_ = conn
 	return nil
 }
+
+// RotateSecrets 在一个事务中重新加密需要轮换的连接
+func (r *databaseConnectionRepository) RotateSecrets(ctx context.Context) (int, error) {
+	rotated := 0
+	var skipped []string
+	err := r.db.WithinTransaction(ctx, func(ctx context.Context) error {
+		var connections []*domain.DatabaseConnection
+		if err := r.db.Session(ctx).Find(&connections).Error; err != nil {
+			return err
+		}
+		for _, conn := range connections {
+			if !r.needsRotation(conn) {
+				continue
+			}
+			if err := r.open(conn); err != nil {
+				skipped = append(skipped, err.Error())
+				continue
+			}
+			if err := r.Update(ctx, conn); err != nil {
+				return fmt.Errorf("保存数据库连接 %s 失败: %w", conn.ID, err)
+			}
+			rotated++
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	if len(skipped) > 0 {
+		return rotated, fmt.Errorf("%d 个连接无法解密，未重新加密: %s", len(skipped), strings.Join(skipped, "; "))
+	}
+	return rotated, nil
+}
+
+// seal 返回敏感信息加密后的副本，调用方的对象保持明文
+func (r *databaseConnectionRepository) seal(conn *domain.DatabaseConnection) (*domain.DatabaseConnection, error) {
+	stored := *conn
+	var err error
+	if stored.Password, err = r.keyring.Seal(conn.Password, conn.ID); err != nil {
+		return nil, fmt.Errorf("加密数据库连接密码失败: %w", err)
+	}
+	if len(conn.Properties) > 0 {
+		stored.Properties = make(map[string]string, len(conn.Properties))
+		for k, v := range conn.Properties {
+			if domain.IsSecretConnectionProperty(k) {
+				if v, err = r.keyring.Seal(v, conn.ID+"/"+k); err != nil {
+					return nil, fmt.Errorf("加密连接属性 %s 失败: %w", k, err)
+				}
+			}
+			stored.Properties[k] = v
+		}
+	}
+	return &stored, nil
+}
+
+// open 就地解密读出的连接
+func (r *databaseConnectionRepository) open(conn *domain.DatabaseConnection) error {
+	var err error
+	if conn.Password, err = r.keyring.Open(conn.Password, conn.ID); err != nil {
+		return fmt.Errorf("解密数据库连接 %s 的密码失败: %w", conn.ID, err)
+	}
+	for k, v := range conn.Properties {
+		if !domain.IsSecretConnectionProperty(k) {
+			continue
+		}
+		if conn.Properties[k], err = r.keyring.Open(v, conn.ID+"/"+k); err != nil {
+			return fmt.Errorf("解密数据库连接 %s 的属性 %s 失败: %w", conn.ID, k, err)
+		}
+	}
+	return nil
+}
+
+// openOrMark 解密读出的连接；失败时清空密码和敏感属性并记录原因，连接仍然返回，方便修改或删除
+func (r *databaseConnectionRepository) openOrMark(conn *domain.DatabaseConnection) {
+	err := r.open(conn)
+	if err == nil {
+		return
+	}
+	conn.Password = ""
+	for k := range conn.Properties {
+		if domain.IsSecretConnectionProperty(k) {
+			conn.Properties[k] = ""
+		}
+	}
+	conn.SecretError = err.Error()
+}
+
+func (r *databaseConnectionRepository) openAll(connections []*domain.DatabaseConnection) {
+	for _, conn := range connections {
+		r.openOrMark(conn)
+	}
+}
+
+// needsRotation 连接是否有明文或非当前密钥加密的敏感信息
+func (r *databaseConnectionRepository) needsRotation(conn *domain.DatabaseConnection) bool {
+	if r.keyring.NeedsRotation(conn.Password) {
+		return true
+	}
+	for k, v := range conn.Properties {
+		if domain.IsSecretConnectionProperty(k) && r.keyring.NeedsRotation(v) {
+			return true
+		}
+	}
+	return false
+}
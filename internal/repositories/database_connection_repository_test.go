@@ -0,0 +1,84 @@
+package repositories
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"robot-path-editor/internal/config"
+	"robot-path-editor/internal/database"
+	"robot-path-editor/internal/domain"
+	"robot-path-editor/pkg/secrets"
+)
+
+func newTestKeyring(t *testing.T, current string, previous ...string) *secrets.Keyring {
+	t.Helper()
+	keyring, err := secrets.NewKeyring(current, previous)
+	if err != nil {
+		t.Fatalf("NewKeyring() error = %v", err)
+	}
+	return keyring
+}
+
+func TestDatabaseConnectionRepositoryUndecryptable(t *testing.T) {
+	ctx := context.Background()
+	db, err := database.New(config.DatabaseConfig{Type: "sqlite", DSN: filepath.Join(t.TempDir(), "data.db")})
+	if err != nil {
+		t.Fatalf("database.New() error = %v", err)
+	}
+	defer db.Close()
+
+	oldKey := strings.Repeat("a", 64)
+	newKey := strings.Repeat("b", 64)
+	connection := func(id string) *domain.DatabaseConnection {
+		return &domain.DatabaseConnection{
+			ID: id, Name: id, Type: "postgres", Host: "db", Port: 5432, Database: "robots", Username: "robot",
+			Password: "secret-" + id, Properties: map[string]string{"sslmode": "require", "sslpassword": "key-" + id},
+		}
+	}
+	// lost 用丢失的旧密钥加密，plain 是配置主密钥前保存的明文
+	if err := NewDatabaseConnectionRepository(db, newTestKeyring(t, oldKey)).Create(ctx, connection("lost")); err != nil {
+		t.Fatalf("Create(lost) error = %v", err)
+	}
+	if err := NewDatabaseConnectionRepository(db, nil).Create(ctx, connection("plain")); err != nil {
+		t.Fatalf("Create(plain) error = %v", err)
+	}
+
+	repo := NewDatabaseConnectionRepository(db, newTestKeyring(t, newKey))
+	connections, err := repo.List(ctx)
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if len(connections) != 2 {
+		t.Fatalf("List() = %d connections, want 2", len(connections))
+	}
+	for _, conn := range connections {
+		switch conn.ID {
+		case "lost":
+			if conn.SecretError == "" || conn.Password != "" || conn.Properties["sslpassword"] != "" {
+				t.Errorf("lost = password %q, sslpassword %q, secret_error %q, want cleared and flagged",
+					conn.Password, conn.Properties["sslpassword"], conn.SecretError)
+			}
+			if conn.Properties["sslmode"] != "require" {
+				t.Errorf("lost sslmode = %q, want require", conn.Properties["sslmode"])
+			}
+		case "plain":
+			if conn.SecretError != "" || conn.Password != "secret-plain" {
+				t.Errorf("plain = password %q, secret_error %q", conn.Password, conn.SecretError)
+			}
+		}
+	}
+
+	rotated, err := repo.RotateSecrets(ctx)
+	if err == nil || !strings.Contains(err.Error(), "lost") {
+		t.Errorf("RotateSecrets() error = %v, want one naming the skipped connection", err)
+	}
+	if rotated != 1 {
+		t.Errorf("RotateSecrets() rotated = %d, want 1", rotated)
+	}
+	plain, err := repo.GetByID(ctx, "plain")
+	if err != nil || plain.Password != "secret-plain" || plain.SecretError != "" {
+		t.Errorf("GetByID(plain) = %+v, %v", plain, err)
+	}
+}
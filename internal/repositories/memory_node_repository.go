@@ -98,6 +98,24 @@ func (r *memoryNodeRepository) GetByIDs(ctx context.Context, ids []domain.NodeID
 	return nodes, nil
 }
 
+// CreateBatch 批量创建节点，有重复ID时不创建任何节点
+func (r *memoryNodeRepository) CreateBatch(ctx context.Context, nodes []*domain.Node) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, node := range nodes {
+		if _, exists := r.nodes[node.ID]; exists {
+			return fmt.Errorf("节点已存在: %s", node.ID)
+		}
+	}
+	for _, node := range nodes {
+		nodeCopy := *node
+		r.nodes[node.ID] = &nodeCopy
+	}
+
+	return nil
+}
+
 // UpdateBatch 批量更新节点
 func (r *memoryNodeRepository) UpdateBatch(ctx context.Context, nodes []*domain.Node) error {
 	r.mu.Lock()
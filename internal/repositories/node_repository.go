@@ -33,6 +33,7 @@ type NodeRepository interface {
 
 	// 批量操作
 	GetByIDs(ctx context.Context, ids []domain.NodeID) ([]*domain.Node, error)
+	CreateBatch(ctx context.Context, nodes []*domain.Node) error
 	UpdateBatch(ctx context.Context, nodes []*domain.Node) error
 	DeleteBatch(ctx context.Context, ids []domain.NodeID) error
 
@@ -86,13 +87,17 @@ func (r *nodeRepository) Create(ctx context.Context, node *domain.Node) error {
 		return memDB.CreateNode(node)
 	}
 
-	return fmt.Errorf("不支持的数据库类型")
+	if err := r.db.Session(ctx).Create(node).Error; err != nil {
+		return fmt.Errorf("创建节点失败: %w", err)
+	}
+
+	return nil
 }
 
 // GetByID 根据ID获取节点
 func (r *nodeRepository) GetByID(ctx context.Context, id domain.NodeID) (*domain.Node, error) {
 	var node domain.Node
-	err := r.db.GORMDB().WithContext(ctx).Where("id = ?", id).First(&node).Error
+	err := r.db.Session(ctx).Where("id = ?", id).First(&node).Error
 	if err != nil {
 		if err == gorm.ErrRecordNotFound {
 			return nil, fmt.Errorf("节点不存在: %s", id)
@@ -108,7 +113,7 @@ func (r *nodeRepository) Update(ctx context.Context, node *domain.Node) error {
 		return fmt.Errorf("节点验证失败: %w", err)
 	}
 
-	result := r.db.GORMDB().WithContext(ctx).Save(node)
+	result := r.db.Session(ctx).Save(node)
 	if result.Error != nil {
 		return result.Error
 	}
@@ -122,7 +127,7 @@ func (r *nodeRepository) Update(ctx context.Context, node *domain.Node) error {
 
 // Delete 删除节点
 func (r *nodeRepository) Delete(ctx context.Context, id domain.NodeID) error {
-	result := r.db.GORMDB().WithContext(ctx).Delete(&domain.Node{}, "id = ?", id)
+	result := r.db.Session(ctx).Delete(&domain.Node{}, "id = ?", id)
 	if result.Error != nil {
 		return result.Error
 	}
@@ -148,10 +153,26 @@ func (r *nodeRepository) GetByIDs(ctx context.Context, ids []domain.NodeID) ([]*
 		stringIDs[i] = string(id)
 	}
 
-	err := r.db.GORMDB().WithContext(ctx).Where("id IN ?", stringIDs).Find(&nodes).Error
+	err := r.db.Session(ctx).Where("id IN ?", stringIDs).Find(&nodes).Error
 	return nodes, err
 }
 
+// CreateBatch 批量创建节点，全部校验通过后按批插入
+func (r *nodeRepository) CreateBatch(ctx context.Context, nodes []*domain.Node) error {
+	if len(nodes) == 0 {
+		return nil
+	}
+	for _, node := range nodes {
+		if err := node.IsValid(); err != nil {
+			return fmt.Errorf("节点 %s 验证失败: %w", node.ID, err)
+		}
+	}
+	if err := r.db.Session(ctx).CreateInBatches(nodes, 100).Error; err != nil {
+		return fmt.Errorf("批量创建节点失败: %w", err)
+	}
+	return nil
+}
+
 // UpdateBatch 批量更新节点
 func (r *nodeRepository) UpdateBatch(ctx context.Context, nodes []*domain.Node) error {
 	return r.db.Transaction(ctx, func(tx interface{}) error {
@@ -189,7 +210,7 @@ func (r *nodeRepository) DeleteBatch(ctx context.Context, ids []domain.NodeID) e
 func (r *nodeRepository) List(ctx context.Context, filter NodeFilter) ([]*domain.Node, error) {
 	var nodes []*domain.Node
 
-	query := r.db.GORMDB().WithContext(ctx)
+	query := r.db.Session(ctx)
 
 	// 应用过滤条件
 	if len(filter.IDs) > 0 {
@@ -238,7 +259,7 @@ func (r *nodeRepository) List(ctx context.Context, filter NodeFilter) ([]*domain
 func (r *nodeRepository) Count(ctx context.Context, filter NodeFilter) (int64, error) {
 	var count int64
 
-	query := r.db.GORMDB().WithContext(ctx).Model(&domain.Node{})
+	query := r.db.Session(ctx).Model(&domain.Node{})
 
 	// 应用过滤条件（复用List方法的逻辑）
 	if len(filter.IDs) > 0 {
@@ -280,7 +301,7 @@ func (r *nodeRepository) GetConnectedNodes(ctx context.Context, nodeID domain.No
 
 	// 通过路径表查找连接的节点
 	// 这里需要联合查询paths表
-	err := r.db.GORMDB().WithContext(ctx).
+	err := r.db.Session(ctx).
 		Table("nodes").
 		Joins("JOIN paths ON (nodes.id = paths.start_node_id OR nodes.id = paths.end_node_id)").
 		Where("(paths.start_node_id = ? OR paths.end_node_id = ?) AND nodes.id != ?",
@@ -293,13 +314,13 @@ func (r *nodeRepository) GetConnectedNodes(ctx context.Context, nodeID domain.No
 // GetNodesByType 根据类型获取节点
 func (r *nodeRepository) GetNodesByType(ctx context.Context, nodeType domain.NodeType) ([]*domain.Node, error) {
 	var nodes []*domain.Node
-	err := r.db.GORMDB().WithContext(ctx).Where("type = ?", nodeType).Find(&nodes).Error
+	err := r.db.Session(ctx).Where("type = ?", nodeType).Find(&nodes).Error
 	return nodes, err
 }
 
 // GetNodesByStatus 根据状态获取节点
 func (r *nodeRepository) GetNodesByStatus(ctx context.Context, status domain.NodeStatus) ([]*domain.Node, error) {
 	var nodes []*domain.Node
-	err := r.db.GORMDB().WithContext(ctx).Where("status = ?", status).Find(&nodes).Error
+	err := r.db.Session(ctx).Where("status = ?", status).Find(&nodes).Error
 	return nodes, err
 }
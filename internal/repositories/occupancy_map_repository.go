@@ -0,0 +1,104 @@
+// Package repositories 占据栅格地图仓储实现
+package repositories
+
+import (
+	"context"
+	"fmt"
+
+	"gorm.io/gorm"
+
+	"robot-path-editor/internal/database"
+	"robot-path-editor/internal/domain"
+)
+
+// OccupancyMapRepository 占据栅格地图仓储接口
+type OccupancyMapRepository interface {
+	// 基础CRUD操作
+	Create(ctx context.Context, m *domain.OccupancyMap) error
+	GetByID(ctx context.Context, id string) (*domain.OccupancyMap, error)
+	Update(ctx context.Context, m *domain.OccupancyMap) error
+	Delete(ctx context.Context, id string) error
+
+	// 查询操作（列表不加载原始图像）
+	List(ctx context.Context) ([]*domain.OccupancyMap, error)
+	GetActive(ctx context.Context) (*domain.OccupancyMap, error)
+
+	// SetActive 将指定地图设为当前地图，其他地图自动取消
+	SetActive(ctx context.Context, id string) error
+}
+
+// occupancyMapRepository GORM实现
+type occupancyMapRepository struct {
+	db database.Database
+}
+
+// NewOccupancyMapRepository 创建新的地图仓储实例
+func NewOccupancyMapRepository(db database.Database) OccupancyMapRepository {
+	return &occupancyMapRepository{db: db}
+}
+
+// Create 创建地图
+func (r *occupancyMapRepository) Create(ctx context.Context, m *domain.OccupancyMap) error {
+	return r.db.Session(ctx).Create(m).Error
+}
+
+// GetByID 根据ID获取地图
+func (r *occupancyMapRepository) GetByID(ctx context.Context, id string) (*domain.OccupancyMap, error) {
+	var m domain.OccupancyMap
+	err := r.db.Session(ctx).Where("id = ?", id).First(&m).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, fmt.Errorf("地图不存在: %s", id)
+		}
+		return nil, err
+	}
+	return &m, nil
+}
+
+// Update 更新地图
+func (r *occupancyMapRepository) Update(ctx context.Context, m *domain.OccupancyMap) error {
+	return r.db.Session(ctx).Save(m).Error
+}
+
+// Delete 删除地图
+func (r *occupancyMapRepository) Delete(ctx context.Context, id string) error {
+	result := r.db.Session(ctx).Delete(&domain.OccupancyMap{}, "id = ?", id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("地图不存在: %s", id)
+	}
+	return nil
+}
+
+// List 列出地图
+func (r *occupancyMapRepository) List(ctx context.Context) ([]*domain.OccupancyMap, error) {
+	var maps []*domain.OccupancyMap
+	err := r.db.Session(ctx).Omit("image").Order("updated_at DESC").Find(&maps).Error
+	return maps, err
+}
+
+// GetActive 获取当前地图，没有时返回 nil
+func (r *occupancyMapRepository) GetActive(ctx context.Context) (*domain.OccupancyMap, error) {
+	var maps []*domain.OccupancyMap
+	err := r.db.Session(ctx).Where("active = ?", true).Limit(1).Find(&maps).Error
+	if err != nil || len(maps) == 0 {
+		return nil, err
+	}
+	return maps[0], nil
+}
+
+// SetActive 设置当前地图
+func (r *occupancyMapRepository) SetActive(ctx context.Context, id string) error {
+	return r.db.Session(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&domain.OccupancyMap{}).Where("id = ?", id).Update("active", true)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return fmt.Errorf("地图不存在: %s", id)
+		}
+		return tx.Model(&domain.OccupancyMap{}).Where("id <> ?", id).Update("active", false).Error
+	})
+}
@@ -83,7 +83,7 @@ func (r *pathRepository) Create(ctx context.Context, path *domain.Path) error {
 		return fmt.Errorf("路径验证失败: %w", err)
 	}
 
-	err := r.db.GORMDB().WithContext(ctx).Create(path).Error
+	err := r.db.Session(ctx).Create(path).Error
 	if err != nil {
 		return fmt.Errorf("创建路径失败: %w", err)
 	}
@@ -94,7 +94,7 @@ func (r *pathRepository) Create(ctx context.Context, path *domain.Path) error {
 // GetByID 根据ID获取路径
 func (r *pathRepository) GetByID(ctx context.Context, id domain.PathID) (*domain.Path, error) {
 	var path domain.Path
-	err := r.db.GORMDB().WithContext(ctx).Where("id = ?", id).First(&path).Error
+	err := r.db.Session(ctx).Where("id = ?", id).First(&path).Error
 	if err != nil {
 		if err == gorm.ErrRecordNotFound {
 			return nil, fmt.Errorf("路径不存在: %s", id)
@@ -110,7 +110,7 @@ func (r *pathRepository) Update(ctx context.Context, path *domain.Path) error {
 		return fmt.Errorf("路径验证失败: %w", err)
 	}
 
-	result := r.db.GORMDB().WithContext(ctx).Save(path)
+	result := r.db.Session(ctx).Save(path)
 	if result.Error != nil {
 		return result.Error
 	}
@@ -124,7 +124,7 @@ func (r *pathRepository) Update(ctx context.Context, path *domain.Path) error {
 
 // Delete 删除路径
 func (r *pathRepository) Delete(ctx context.Context, id domain.PathID) error {
-	result := r.db.GORMDB().WithContext(ctx).Delete(&domain.Path{}, "id = ?", id)
+	result := r.db.Session(ctx).Delete(&domain.Path{}, "id = ?", id)
 	if result.Error != nil {
 		return result.Error
 	}
@@ -150,24 +150,21 @@ func (r *pathRepository) GetByIDs(ctx context.Context, ids []domain.PathID) ([]*
 		stringIDs[i] = string(id)
 	}
 
-	err := r.db.GORMDB().WithContext(ctx).Where("id IN ?", stringIDs).Find(&paths).Error
+	err := r.db.Session(ctx).Where("id IN ?", stringIDs).Find(&paths).Error
 	return paths, err
 }
 
 // CreateBatch 批量创建路径
 func (r *pathRepository) CreateBatch(ctx context.Context, paths []*domain.Path) error {
-	return r.db.Transaction(ctx, func(tx interface{}) error {
-		gormTx := tx.(*gorm.DB)
-		for _, path := range paths {
-			if err := path.IsValid(); err != nil {
-				return fmt.Errorf("路径验证失败: %w", err)
-			}
-			if err := gormTx.Create(path).Error; err != nil {
-				return err
-			}
-		}
+	if len(paths) == 0 {
 		return nil
-	})
+	}
+	for _, path := range paths {
+		if err := path.IsValid(); err != nil {
+			return fmt.Errorf("路径验证失败: %w", err)
+		}
+	}
+	return r.db.Session(ctx).CreateInBatches(paths, 100).Error
 }
 
 // DeleteBatch 批量删除路径
@@ -181,7 +178,7 @@ func (r *pathRepository) DeleteBatch(ctx context.Context, ids []domain.PathID) e
 		stringIDs[i] = string(id)
 	}
 
-	err := r.db.GORMDB().WithContext(ctx).Where("id IN ?", stringIDs).Delete(&domain.Path{}).Error
+	err := r.db.Session(ctx).Where("id IN ?", stringIDs).Delete(&domain.Path{}).Error
 	return err
 }
 
@@ -189,7 +186,7 @@ func (r *pathRepository) DeleteBatch(ctx context.Context, ids []domain.PathID) e
 func (r *pathRepository) List(ctx context.Context, filter PathFilter) ([]*domain.Path, error) {
 	var paths []*domain.Path
 
-	query := r.db.GORMDB().WithContext(ctx)
+	query := r.db.Session(ctx)
 
 	// 应用过滤条件
 	if len(filter.IDs) > 0 {
@@ -254,7 +251,7 @@ func (r *pathRepository) List(ctx context.Context, filter PathFilter) ([]*domain
 func (r *pathRepository) Count(ctx context.Context, filter PathFilter) (int64, error) {
 	var count int64
 
-	query := r.db.GORMDB().WithContext(ctx).Model(&domain.Path{})
+	query := r.db.Session(ctx).Model(&domain.Path{})
 
 	// 应用过滤条件（复用List方法的逻辑）
 	if len(filter.IDs) > 0 {
@@ -301,7 +298,7 @@ func (r *pathRepository) Count(ctx context.Context, filter PathFilter) (int64, e
 func (r *pathRepository) GetByNode(ctx context.Context, nodeID domain.NodeID) ([]*domain.Path, error) {
 	var paths []*domain.Path
 
-	err := r.db.GORMDB().WithContext(ctx).
+	err := r.db.Session(ctx).
 		Where("start_node_id = ? OR end_node_id = ?", nodeID, nodeID).
 		Find(&paths).Error
 
@@ -312,7 +309,7 @@ func (r *pathRepository) GetByNode(ctx context.Context, nodeID domain.NodeID) ([
 func (r *pathRepository) GetByNodes(ctx context.Context, startNodeID, endNodeID domain.NodeID) ([]*domain.Path, error) {
 	var paths []*domain.Path
 
-	err := r.db.GORMDB().WithContext(ctx).
+	err := r.db.Session(ctx).
 		Where("(start_node_id = ? AND end_node_id = ?) OR (start_node_id = ? AND end_node_id = ?)",
 			startNodeID, endNodeID, endNodeID, startNodeID).
 		Find(&paths).Error
@@ -0,0 +1,135 @@
+// Package repositories 同步任务仓储实现
+package repositories
+
+import (
+	"context"
+
+	"robot-path-editor/internal/database"
+	"robot-path-editor/internal/domain"
+)
+
+// SyncJobRepository 同步任务仓储接口
+type SyncJobRepository interface {
+	// 基础CRUD操作
+	Create(ctx context.Context, job *domain.SyncJob) error
+	GetByID(ctx context.Context, id string) (*domain.SyncJob, error)
+	Update(ctx context.Context, job *domain.SyncJob) error
+	// UpdateColumns 只更新指定的列，调度器写运行状态时不覆盖用户同时修改的配置
+	UpdateColumns(ctx context.Context, job *domain.SyncJob, columns ...string) error
+	// AdvanceWatermarks 在事务中重新读取任务，按 advance 返回的水位更新，不覆盖运行期间对水位的修改
+	AdvanceWatermarks(ctx context.Context, id string, advance func(job *domain.SyncJob) map[string]string) error
+	Delete(ctx context.Context, id string) error
+	List(ctx context.Context) ([]*domain.SyncJob, error)
+
+	// 运行记录
+	CreateRun(ctx context.Context, run *domain.SyncJobRun) error
+	UpdateRun(ctx context.Context, run *domain.SyncJobRun) error
+	ListRuns(ctx context.Context, jobID string, limit int) ([]*domain.SyncJobRun, error)
+	ListRunsByStatus(ctx context.Context, status string) ([]*domain.SyncJobRun, error)
+	// PruneRuns 只保留任务最近的 keep 条运行记录
+	PruneRuns(ctx context.Context, jobID string, keep int) error
+}
+
+// syncJobRepository GORM实现
+type syncJobRepository struct {
+	db database.Database
+}
+
+// NewSyncJobRepository 创建新的同步任务仓储实例
+func NewSyncJobRepository(db database.Database) SyncJobRepository {
+	return &syncJobRepository{db: db}
+}
+
+// Create 创建同步任务
+func (r *syncJobRepository) Create(ctx context.Context, job *domain.SyncJob) error {
+	return r.db.Session(ctx).Create(job).Error
+}
+
+// GetByID 根据ID获取同步任务
+func (r *syncJobRepository) GetByID(ctx context.Context, id string) (*domain.SyncJob, error) {
+	var job domain.SyncJob
+	err := r.db.Session(ctx).Where("id = ?", id).First(&job).Error
+	if err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// Update 更新同步任务
+func (r *syncJobRepository) Update(ctx context.Context, job *domain.SyncJob) error {
+	return r.db.Session(ctx).Save(job).Error
+}
+
+// UpdateColumns 只更新指定的列
+func (r *syncJobRepository) UpdateColumns(ctx context.Context, job *domain.SyncJob, columns ...string) error {
+	return r.db.Session(ctx).Model(job).Select(columns).Updates(job).Error
+}
+
+// AdvanceWatermarks 重新读取任务后只更新水位列
+func (r *syncJobRepository) AdvanceWatermarks(ctx context.Context, id string, advance func(job *domain.SyncJob) map[string]string) error {
+	return r.db.WithinTransaction(ctx, func(ctx context.Context) error {
+		job, err := r.GetByID(ctx, id)
+		if err != nil {
+			return err
+		}
+		job.Watermarks = advance(job)
+		return r.UpdateColumns(ctx, job, "watermarks")
+	})
+}
+
+// Delete 删除同步任务及其运行记录
+func (r *syncJobRepository) Delete(ctx context.Context, id string) error {
+	return r.db.WithinTransaction(ctx, func(ctx context.Context) error {
+		if err := r.db.Session(ctx).Delete(&domain.SyncJobRun{}, "job_id = ?", id).Error; err != nil {
+			return err
+		}
+		return r.db.Session(ctx).Delete(&domain.SyncJob{}, "id = ?", id).Error
+	})
+}
+
+// List 列出所有同步任务
+func (r *syncJobRepository) List(ctx context.Context) ([]*domain.SyncJob, error) {
+	var jobs []*domain.SyncJob
+	err := r.db.Session(ctx).Order("created_at").Find(&jobs).Error
+	return jobs, err
+}
+
+// CreateRun 创建运行记录
+func (r *syncJobRepository) CreateRun(ctx context.Context, run *domain.SyncJobRun) error {
+	return r.db.Session(ctx).Create(run).Error
+}
+
+// UpdateRun 更新运行记录；任务已删除时记录也已删除，不再重新插入
+func (r *syncJobRepository) UpdateRun(ctx context.Context, run *domain.SyncJobRun) error {
+	return r.db.Session(ctx).Model(run).Select("*").Updates(run).Error
+}
+
+// ListRuns 按开始时间倒序列出任务的运行记录
+func (r *syncJobRepository) ListRuns(ctx context.Context, jobID string, limit int) ([]*domain.SyncJobRun, error) {
+	var runs []*domain.SyncJobRun
+	query := r.db.Session(ctx).Where("job_id = ?", jobID).Order("started_at DESC")
+	if limit > 0 {
+		query = query.Limit(limit)
+	}
+	err := query.Find(&runs).Error
+	return runs, err
+}
+
+// ListRunsByStatus 列出指定状态的运行记录
+func (r *syncJobRepository) ListRunsByStatus(ctx context.Context, status string) ([]*domain.SyncJobRun, error) {
+	var runs []*domain.SyncJobRun
+	err := r.db.Session(ctx).Where("status = ?", status).Find(&runs).Error
+	return runs, err
+}
+
+// PruneRuns 只保留任务最近的 keep 条运行记录
+func (r *syncJobRepository) PruneRuns(ctx context.Context, jobID string, keep int) error {
+	var ids []string
+	err := r.db.Session(ctx).Model(&domain.SyncJobRun{}).
+		Where("job_id = ?", jobID).Order("started_at DESC").
+		Pluck("id", &ids).Error
+	if err != nil || len(ids) <= keep {
+		return err
+	}
+	return r.db.Session(ctx).Delete(&domain.SyncJobRun{}, "id IN ?", ids[keep:]).Error
+}
@@ -0,0 +1,69 @@
+// Package repositories 同步隔离区仓储实现
+package repositories
+
+import (
+	"context"
+
+	"robot-path-editor/internal/database"
+	"robot-path-editor/internal/domain"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// SyncQuarantineRepository 同步隔离区仓储接口
+type SyncQuarantineRepository interface {
+	// List 获取表映射隔离中的全部路径，按发现时间排序
+	List(ctx context.Context, mappingID string) ([]*domain.SyncQuarantine, error)
+	// Save 写入（覆盖）隔离记录并释放指定路径
+	Save(ctx context.Context, mappingID string, entries []*domain.SyncQuarantine, releaseIDs []string) error
+	// Delete 删除一条隔离记录，不存在时返回 gorm.ErrRecordNotFound
+	Delete(ctx context.Context, mappingID, pathID string) error
+}
+
+// syncQuarantineRepository GORM实现
+type syncQuarantineRepository struct {
+	db database.Database
+}
+
+// NewSyncQuarantineRepository 创建新的同步隔离区仓储实例
+func NewSyncQuarantineRepository(db database.Database) SyncQuarantineRepository {
+	return &syncQuarantineRepository{db: db}
+}
+
+// List 获取表映射隔离中的全部路径
+func (r *syncQuarantineRepository) List(ctx context.Context, mappingID string) ([]*domain.SyncQuarantine, error) {
+	var entries []*domain.SyncQuarantine
+	err := r.db.Session(ctx).Where("mapping_id = ?", mappingID).Order("detected_at, path_id").Find(&entries).Error
+	return entries, err
+}
+
+// Save 写入（覆盖）隔离记录并释放指定路径
+func (r *syncQuarantineRepository) Save(ctx context.Context, mappingID string, entries []*domain.SyncQuarantine, releaseIDs []string) error {
+	return r.db.WithinTransaction(ctx, func(ctx context.Context) error {
+		if len(releaseIDs) > 0 {
+			err := r.db.Session(ctx).
+				Where("mapping_id = ? AND path_id IN ?", mappingID, releaseIDs).
+				Delete(&domain.SyncQuarantine{}).Error
+			if err != nil {
+				return err
+			}
+		}
+		if len(entries) == 0 {
+			return nil
+		}
+		return r.db.Session(ctx).Clauses(clause.OnConflict{UpdateAll: true}).CreateInBatches(entries, 200).Error
+	})
+}
+
+// Delete 删除一条隔离记录
+func (r *syncQuarantineRepository) Delete(ctx context.Context, mappingID, pathID string) error {
+	result := r.db.Session(ctx).Delete(&domain.SyncQuarantine{}, "mapping_id = ? AND path_id = ?", mappingID, pathID)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
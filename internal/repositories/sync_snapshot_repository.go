@@ -0,0 +1,61 @@
+// Package repositories 同步快照仓储实现
+package repositories
+
+import (
+	"context"
+
+	"robot-path-editor/internal/database"
+	"robot-path-editor/internal/domain"
+
+	"gorm.io/gorm/clause"
+)
+
+// SyncSnapshotRepository 同步快照仓储接口
+type SyncSnapshotRepository interface {
+	// List 获取表映射某类实体的全部快照
+	List(ctx context.Context, mappingID, entity string) ([]*domain.SyncSnapshot, error)
+	// Save 写入（覆盖）快照并删除指定记录的快照
+	Save(ctx context.Context, mappingID, entity string, snapshots []*domain.SyncSnapshot, deleteIDs []string) error
+	// DeleteByMapping 删除表映射的全部快照
+	DeleteByMapping(ctx context.Context, mappingID string) error
+}
+
+// syncSnapshotRepository GORM实现
+type syncSnapshotRepository struct {
+	db database.Database
+}
+
+// NewSyncSnapshotRepository 创建新的同步快照仓储实例
+func NewSyncSnapshotRepository(db database.Database) SyncSnapshotRepository {
+	return &syncSnapshotRepository{db: db}
+}
+
+// List 获取表映射某类实体的全部快照
+func (r *syncSnapshotRepository) List(ctx context.Context, mappingID, entity string) ([]*domain.SyncSnapshot, error) {
+	var snapshots []*domain.SyncSnapshot
+	err := r.db.Session(ctx).Where("mapping_id = ? AND entity = ?", mappingID, entity).Find(&snapshots).Error
+	return snapshots, err
+}
+
+// Save 写入（覆盖）快照并删除指定记录的快照
+func (r *syncSnapshotRepository) Save(ctx context.Context, mappingID, entity string, snapshots []*domain.SyncSnapshot, deleteIDs []string) error {
+	return r.db.WithinTransaction(ctx, func(ctx context.Context) error {
+		if len(deleteIDs) > 0 {
+			err := r.db.Session(ctx).
+				Where("mapping_id = ? AND entity = ? AND record_id IN ?", mappingID, entity, deleteIDs).
+				Delete(&domain.SyncSnapshot{}).Error
+			if err != nil {
+				return err
+			}
+		}
+		if len(snapshots) == 0 {
+			return nil
+		}
+		return r.db.Session(ctx).Clauses(clause.OnConflict{UpdateAll: true}).CreateInBatches(snapshots, 200).Error
+	})
+}
+
+// DeleteByMapping 删除表映射的全部快照
+func (r *syncSnapshotRepository) DeleteByMapping(ctx context.Context, mappingID string) error {
+	return r.db.Session(ctx).Delete(&domain.SyncSnapshot{}, "mapping_id = ?", mappingID).Error
+}
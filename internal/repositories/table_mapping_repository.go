@@ -34,13 +34,13 @@ func NewTableMappingRepository(db database.Database) TableMappingRepository {
 
 // Create 创建表映射
 func (r *tableMappingRepository) Create(ctx context.Context, mapping *domain.TableMapping) error {
-	return r.db.GORMDB().WithContext(ctx).Create(mapping).Error
+	return r.db.Session(ctx).Create(mapping).Error
 }
 
 // GetByID 根据ID获取表映射
 func (r *tableMappingRepository) GetByID(ctx context.Context, id string) (*domain.TableMapping, error) {
 	var mapping domain.TableMapping
-	err := r.db.GORMDB().WithContext(ctx).Where("id = ?", id).First(&mapping).Error
+	err := r.db.Session(ctx).Where("id = ?", id).First(&mapping).Error
 	if err != nil {
 		return nil, err
 	}
@@ -49,25 +49,39 @@ func (r *tableMappingRepository) GetByID(ctx context.Context, id string) (*domai
 
 // Update 更新表映射
 func (r *tableMappingRepository) Update(ctx context.Context, mapping *domain.TableMapping) error {
-	return r.db.GORMDB().WithContext(ctx).Save(mapping).Error
+	return r.db.Session(ctx).Save(mapping).Error
 }
 
-// Delete 删除表映射
+// Delete 删除表映射及其同步快照、隔离区、同步任务和运行记录
 func (r *tableMappingRepository) Delete(ctx context.Context, id string) error {
-	return r.db.GORMDB().WithContext(ctx).Delete(&domain.TableMapping{}, "id = ?", id).Error
+	return r.db.WithinTransaction(ctx, func(ctx context.Context) error {
+		if err := r.db.Session(ctx).Delete(&domain.SyncSnapshot{}, "mapping_id = ?", id).Error; err != nil {
+			return err
+		}
+		if err := r.db.Session(ctx).Delete(&domain.SyncQuarantine{}, "mapping_id = ?", id).Error; err != nil {
+			return err
+		}
+		if err := r.db.Session(ctx).Delete(&domain.SyncJobRun{}, "mapping_id = ?", id).Error; err != nil {
+			return err
+		}
+		if err := r.db.Session(ctx).Delete(&domain.SyncJob{}, "mapping_id = ?", id).Error; err != nil {
+			return err
+		}
+		return r.db.Session(ctx).Delete(&domain.TableMapping{}, "id = ?", id).Error
+	})
 }
 
 // List 列出所有表映射
 func (r *tableMappingRepository) List(ctx context.Context) ([]*domain.TableMapping, error) {
 	var mappings []*domain.TableMapping
-	err := r.db.GORMDB().WithContext(ctx).Find(&mappings).Error
+	err := r.db.Session(ctx).Find(&mappings).Error
 	return mappings, err
 }
 
 // GetByTableName 根据表名获取映射
 func (r *tableMappingRepository) GetByTableName(ctx context.Context, tableName string) (*domain.TableMapping, error) {
 	var mapping domain.TableMapping
-	err := r.db.GORMDB().WithContext(ctx).Where("table_name = ?", tableName).First(&mapping).Error
+	err := r.db.Session(ctx).Where("table_name = ?", tableName).First(&mapping).Error
 	if err != nil {
 		return nil, err
 	}
@@ -77,6 +91,6 @@ func (r *tableMappingRepository) GetByTableName(ctx context.Context, tableName s
 // GetByConnectionID 根据连接ID获取所有映射
 func (r *tableMappingRepository) GetByConnectionID(ctx context.Context, connectionID string) ([]*domain.TableMapping, error) {
 	var mappings []*domain.TableMapping
-	err := r.db.GORMDB().WithContext(ctx).Where("connection_id = ?", connectionID).Find(&mappings).Error
+	err := r.db.Session(ctx).Where("connection_id = ?", connectionID).Find(&mappings).Error
 	return mappings, err
 }
@@ -13,9 +13,15 @@ type TemplateRepository interface {
 	// 基础CRUD操作
 	Create(ctx context.Context, template *domain.Template) error
 	GetByID(ctx context.Context, id string) (*domain.Template, error)
+	GetByName(ctx context.Context, name string) (*domain.Template, error)
 	Update(ctx context.Context, template *domain.Template) error
 	Delete(ctx context.Context, id string) error
 
+	// 修订（只追加）
+	CreateRevision(ctx context.Context, revision *domain.TemplateRevision) error
+	GetRevision(ctx context.Context, templateID string, revision int) (*domain.TemplateRevision, error)
+	ListRevisions(ctx context.Context, templateID string) ([]*domain.TemplateRevision, error)
+
 	// 查询操作
 	List(ctx context.Context, opts ListTemplatesOptions) ([]*domain.Template, error)
 	GetByCategory(ctx context.Context, category string) ([]*domain.Template, error)
@@ -54,34 +60,77 @@ func NewTemplateRepository(db database.Database) TemplateRepository {
 
 // Create 创建模板
 func (r *templateRepository) Create(ctx context.Context, template *domain.Template) error {
-	return r.db.GORMDB().WithContext(ctx).Create(template).Error
+	return r.db.Session(ctx).Create(template).Error
 }
 
 // GetByID 根据ID获取模板
 func (r *templateRepository) GetByID(ctx context.Context, id string) (*domain.Template, error) {
 	var template domain.Template
-	err := r.db.GORMDB().WithContext(ctx).Where("id = ?", id).First(&template).Error
+	err := r.db.Session(ctx).Where("id = ?", id).First(&template).Error
 	if err != nil {
 		return nil, err
 	}
 	return &template, nil
 }
 
+// GetByName 根据名称获取模板，不存在时返回 nil
+func (r *templateRepository) GetByName(ctx context.Context, name string) (*domain.Template, error) {
+	var templates []*domain.Template
+	err := r.db.Session(ctx).Where("name = ?", name).Limit(1).Find(&templates).Error
+	if err != nil {
+		return nil, err
+	}
+	if len(templates) == 0 {
+		return nil, nil
+	}
+	return templates[0], nil
+}
+
 // Update 更新模板
 func (r *templateRepository) Update(ctx context.Context, template *domain.Template) error {
-	return r.db.GORMDB().WithContext(ctx).Save(template).Error
+	return r.db.Session(ctx).Save(template).Error
 }
 
-// Delete 删除模板
+// Delete 删除模板及其修订
 func (r *templateRepository) Delete(ctx context.Context, id string) error {
-	return r.db.GORMDB().WithContext(ctx).Delete(&domain.Template{}, "id = ?", id).Error
+	return r.db.WithinTransaction(ctx, func(ctx context.Context) error {
+		if err := r.db.Session(ctx).Delete(&domain.TemplateRevision{}, "template_id = ?", id).Error; err != nil {
+			return err
+		}
+		return r.db.Session(ctx).Delete(&domain.Template{}, "id = ?", id).Error
+	})
+}
+
+// CreateRevision 保存修订
+func (r *templateRepository) CreateRevision(ctx context.Context, revision *domain.TemplateRevision) error {
+	return r.db.Session(ctx).Create(revision).Error
+}
+
+// GetRevision 获取指定修订，不存在时返回 nil
+func (r *templateRepository) GetRevision(ctx context.Context, templateID string, revision int) (*domain.TemplateRevision, error) {
+	var revisions []*domain.TemplateRevision
+	err := r.db.Session(ctx).Where("template_id = ? AND revision = ?", templateID, revision).Limit(1).Find(&revisions).Error
+	if err != nil {
+		return nil, err
+	}
+	if len(revisions) == 0 {
+		return nil, nil
+	}
+	return revisions[0], nil
+}
+
+// ListRevisions 列出模板的全部修订，新的在前
+func (r *templateRepository) ListRevisions(ctx context.Context, templateID string) ([]*domain.TemplateRevision, error) {
+	var revisions []*domain.TemplateRevision
+	err := r.db.Session(ctx).Where("template_id = ?", templateID).Order("revision DESC").Find(&revisions).Error
+	return revisions, err
 }
 
 // List 列出模板
 func (r *templateRepository) List(ctx context.Context, opts ListTemplatesOptions) ([]*domain.Template, error) {
 	var templates []*domain.Template
 
-	query := r.db.GORMDB().WithContext(ctx)
+	query := r.db.Session(ctx)
 
 	// 应用过滤条件
 	if opts.Category != "" {
@@ -133,21 +182,21 @@ func (r *templateRepository) List(ctx context.Context, opts ListTemplatesOptions
 // GetByCategory 根据分类获取模板
 func (r *templateRepository) GetByCategory(ctx context.Context, category string) ([]*domain.Template, error) {
 	var templates []*domain.Template
-	err := r.db.GORMDB().WithContext(ctx).Where("category = ? AND status = ?", category, domain.TemplateStatusActive).Find(&templates).Error
+	err := r.db.Session(ctx).Where("category = ? AND status = ?", category, domain.TemplateStatusActive).Find(&templates).Error
 	return templates, err
 }
 
 // GetByLayoutType 根据布局类型获取模板
 func (r *templateRepository) GetByLayoutType(ctx context.Context, layoutType domain.LayoutType) ([]*domain.Template, error) {
 	var templates []*domain.Template
-	err := r.db.GORMDB().WithContext(ctx).Where("layout_type = ? AND status = ?", layoutType, domain.TemplateStatusActive).Find(&templates).Error
+	err := r.db.Session(ctx).Where("layout_type = ? AND status = ?", layoutType, domain.TemplateStatusActive).Find(&templates).Error
 	return templates, err
 }
 
 // GetPublicTemplates 获取公开模板
 func (r *templateRepository) GetPublicTemplates(ctx context.Context) ([]*domain.Template, error) {
 	var templates []*domain.Template
-	err := r.db.GORMDB().WithContext(ctx).Where("is_public = ? AND status = ?", true, domain.TemplateStatusActive).Find(&templates).Error
+	err := r.db.Session(ctx).Where("is_public = ? AND status = ?", true, domain.TemplateStatusActive).Find(&templates).Error
 	return templates, err
 }
 
@@ -155,7 +204,7 @@ func (r *templateRepository) GetPublicTemplates(ctx context.Context) ([]*domain.
 func (r *templateRepository) Search(ctx context.Context, query string) ([]*domain.Template, error) {
 	var templates []*domain.Template
 	searchPattern := "%" + query + "%"
-	err := r.db.GORMDB().WithContext(ctx).Where(
+	err := r.db.Session(ctx).Where(
 		"(name LIKE ? OR description LIKE ?) AND status = ?",
 		searchPattern, searchPattern, domain.TemplateStatusActive,
 	).Find(&templates).Error
@@ -165,13 +214,13 @@ func (r *templateRepository) Search(ctx context.Context, query string) ([]*domai
 // Count 统计模板总数
 func (r *templateRepository) Count(ctx context.Context) (int64, error) {
 	var count int64
-	err := r.db.GORMDB().WithContext(ctx).Model(&domain.Template{}).Count(&count).Error
+	err := r.db.Session(ctx).Model(&domain.Template{}).Count(&count).Error
 	return count, err
 }
 
 // CountByCategory 按分类统计模板数量
 func (r *templateRepository) CountByCategory(ctx context.Context, category string) (int64, error) {
 	var count int64
-	err := r.db.GORMDB().WithContext(ctx).Model(&domain.Template{}).Where("category = ?", category).Count(&count).Error
+	err := r.db.Session(ctx).Model(&domain.Template{}).Where("category = ?", category).Count(&count).Error
 	return count, err
 }
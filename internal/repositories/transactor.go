@@ -0,0 +1,18 @@
+// Package repositories 跨仓储事务
+package repositories
+
+import (
+	"context"
+
+	"robot-path-editor/internal/database"
+)
+
+// Transactor 跨仓储事务，fn 中使用传入的上下文调用各仓储即加入同一事务
+type Transactor interface {
+	WithinTransaction(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+// NewTransactor 创建事务执行器
+func NewTransactor(db database.Database) Transactor {
+	return db
+}
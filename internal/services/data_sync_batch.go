@@ -0,0 +1,251 @@
+// Package services 拉取的批处理、检查点和进度
+//
+// - 外部行按批处理：一次查询读取本批的本地记录，实体、快照和隔离区在一个本地事务中提交，提交后的批即为检查点
+// - 批事务失败时逐行重试（每行一个事务），只有出错的行记为失败，其余行照常写入
+// - 每批开始前检查 ctx，取消后不再开始新批；进行中的事务随 ctx 回滚，已提交的批和推进到该批的水位保留
+// - 进度通过 WithSyncProgress 放入 ctx 的回调报告，每提交一批报告一次
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"robot-path-editor/internal/domain"
+)
+
+// syncBatchSize 每批处理的外部行数
+const syncBatchSize = 500
+
+// 拉取阶段
+const (
+	SyncPhaseWrite  = "write"  // 写入外部行
+	SyncPhaseDelete = "delete" // 处理外部删除
+	SyncPhaseDone   = "done"   // 该实体处理完成
+)
+
+// SyncProgress 一类实体的拉取进度：Total、Processed 为当前阶段的行数，其余为该实体已提交的累计数
+type SyncProgress struct {
+	Entity    string `json:"entity"`
+	Phase     string `json:"phase"`
+	Total     int    `json:"total"`
+	Processed int    `json:"processed"`
+	Created   int    `json:"created"`
+	Updated   int    `json:"updated"`
+	Skipped   int    `json:"skipped"`
+	Deleted   int    `json:"deleted"`
+	Orphans   int    `json:"orphans"`
+	Failed    int    `json:"failed"`
+}
+
+type syncProgressKey struct{}
+
+// WithSyncProgress 返回带进度回调的上下文；回调在拉取的协程中同步调用，应尽快返回
+func WithSyncProgress(ctx context.Context, report func(SyncProgress)) context.Context {
+	return context.WithValue(ctx, syncProgressKey{}, report)
+}
+
+func reportSyncProgress(ctx context.Context, p SyncProgress) {
+	if report, ok := ctx.Value(syncProgressKey{}).(func(SyncProgress)); ok {
+		report(p)
+	}
+}
+
+// syncRow 一行外部数据在本地的处理方式
+type syncRow struct {
+	id     string
+	fields map[string]string
+	entity interface{} // 待保存的本地实体；为 nil 时不写入（跳过、端点缺失或转换失败）
+	exists bool
+	orphan *SyncOrphan
+	err    error
+
+	complete bool // 删除阶段：已彻底删除，可以删除快照
+}
+
+// loadSyncLocals 一次查询读取一批ID的本地实体
+func (s *dataSyncService) loadSyncLocals(ctx context.Context, entity string, ids []string) (map[string]interface{}, error) {
+	locals := make(map[string]interface{}, len(ids))
+	if entity == "node" {
+		nodeIDs := make([]domain.NodeID, len(ids))
+		for i, id := range ids {
+			nodeIDs[i] = domain.NodeID(id)
+		}
+		nodes, err := s.nodeRepo.GetByIDs(ctx, nodeIDs)
+		if err != nil {
+			return nil, fmt.Errorf("获取本地节点失败: %w", err)
+		}
+		for _, n := range nodes {
+			locals[string(n.ID)] = n
+		}
+		return locals, nil
+	}
+	pathIDs := make([]domain.PathID, len(ids))
+	for i, id := range ids {
+		pathIDs[i] = domain.PathID(id)
+	}
+	paths, err := s.pathRepo.GetByIDs(ctx, pathIDs)
+	if err != nil {
+		return nil, fmt.Errorf("获取本地路径失败: %w", err)
+	}
+	for _, p := range paths {
+		locals[string(p.ID)] = p
+	}
+	return locals, nil
+}
+
+// prepareSyncRows 确定一批外部行的处理方式，需要写入的行把列值写到本地实体
+func (s *dataSyncService) prepareSyncRows(ctx context.Context, mapping *domain.TableMapping, spec *syncSpec, ids []string, remote map[string]map[string]interface{}, base map[string]map[string]string, nodes map[domain.NodeID]bool, skipUnchanged bool) ([]*syncRow, error) {
+	locals, err := s.loadSyncLocals(ctx, spec.entity, ids)
+	if err != nil {
+		return nil, err
+	}
+	batch := make([]*syncRow, 0, len(ids))
+	for _, id := range ids {
+		r := &syncRow{id: id, fields: spec.remoteFields(remote[id])}
+		local, exists := locals[id]
+		r.exists = exists
+		var orphan *SyncOrphan
+		if nodes != nil {
+			orphan = syncPathOrphan(spec, id, local, r.fields, nodes)
+		}
+		switch {
+		case skipUnchanged && exists && syncFieldsEqual(base[id], r.fields):
+		case orphan != nil:
+			orphan.Quarantined = syncOrphanPolicy(mapping) == SyncOrphanQuarantine
+			r.orphan = orphan
+		default:
+			if !exists {
+				local = newSyncedEntity(spec.entity, id)
+			}
+			if err := prepareSyncedEntity(spec, local, r.fields, exists); err != nil {
+				r.err = err
+			} else {
+				r.entity = local
+			}
+		}
+		batch = append(batch, r)
+	}
+	return batch, nil
+}
+
+// writeSyncRows 保存一批行的实体、快照和隔离区，应在事务中调用
+func (s *dataSyncService) writeSyncRows(ctx context.Context, mapping *domain.TableMapping, spec *syncSpec, batch []*syncRow, remote map[string]map[string]interface{}, quarantined map[string]*domain.SyncQuarantine) error {
+	if err := s.saveSyncedEntities(ctx, batch); err != nil {
+		return err
+	}
+	var synced, released []string
+	var entries []*domain.SyncQuarantine
+	now := time.Now()
+	for _, r := range batch {
+		if r.entity != nil {
+			synced = append(synced, r.id)
+		}
+		switch {
+		case r.orphan != nil && r.orphan.Quarantined:
+			entry := &domain.SyncQuarantine{
+				MappingID:    mapping.ID,
+				PathID:       r.id,
+				StartNodeID:  r.orphan.StartNodeID,
+				EndNodeID:    r.orphan.EndNodeID,
+				MissingNodes: r.orphan.MissingNodes,
+				Fields:       r.fields,
+				DetectedAt:   now,
+			}
+			if prev := quarantined[r.id]; prev != nil {
+				entry.DetectedAt = prev.DetectedAt
+			}
+			entries = append(entries, entry)
+		case quarantined[r.id] != nil:
+			released = append(released, r.id)
+		}
+	}
+	if err := s.saveSyncSnapshots(ctx, mapping.ID, spec, remote, synced, nil); err != nil {
+		return err
+	}
+	if len(entries) > 0 || len(released) > 0 {
+		if err := s.quarantineRepo.Save(ctx, mapping.ID, entries, released); err != nil {
+			return fmt.Errorf("保存同步隔离区失败: %w", err)
+		}
+	}
+	return nil
+}
+
+// saveSyncedEntities 保存一批已写入列值的实体：新建的批量插入，已有的逐条更新；只有一行时错误信息带ID
+func (s *dataSyncService) saveSyncedEntities(ctx context.Context, batch []*syncRow) error {
+	if len(batch) == 1 {
+		if r := batch[0]; r.entity != nil {
+			return s.writeSyncedEntity(ctx, r.entity, r.exists)
+		}
+		return nil
+	}
+	var nodes []*domain.Node
+	var paths []*domain.Path
+	for _, r := range batch {
+		switch e := r.entity.(type) {
+		case nil:
+		case *domain.Node:
+			if !r.exists {
+				nodes = append(nodes, e)
+				continue
+			}
+			if err := s.writeSyncedEntity(ctx, e, true); err != nil {
+				return err
+			}
+		case *domain.Path:
+			if !r.exists {
+				paths = append(paths, e)
+				continue
+			}
+			if err := s.writeSyncedEntity(ctx, e, true); err != nil {
+				return err
+			}
+		}
+	}
+	if err := s.nodeRepo.CreateBatch(ctx, nodes); err != nil {
+		return fmt.Errorf("批量写入节点失败: %w", err)
+	}
+	if err := s.pathRepo.CreateBatch(ctx, paths); err != nil {
+		return fmt.Errorf("批量创建路径失败: %w", err)
+	}
+	return nil
+}
+
+// commitSyncBatch 在一个本地事务中执行 write；事务失败时逐行重试，返回失败的行。ctx 取消时返回 ctx 的错误
+func (s *dataSyncService) commitSyncBatch(ctx context.Context, batch []*syncRow, write func(ctx context.Context, batch []*syncRow) error) (map[string]error, error) {
+	err := s.transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		return write(ctx, batch)
+	})
+	if err == nil || len(batch) == 0 {
+		return nil, err
+	}
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	failed := make(map[string]error)
+	for _, r := range batch {
+		err := s.transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+			return write(ctx, []*syncRow{r})
+		})
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		if err != nil {
+			failed[r.id] = err
+		}
+	}
+	return failed, nil
+}
+
+// cloneSyncEntity 实体的浅拷贝，事务回滚后重试时使用未修改的原值
+func cloneSyncEntity(entity interface{}) interface{} {
+	switch e := entity.(type) {
+	case *domain.Node:
+		c := *e
+		return &c
+	case *domain.Path:
+		c := *e
+		return &c
+	}
+	return entity
+}
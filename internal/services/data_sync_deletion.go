@@ -0,0 +1,309 @@
+// Package services 同步中的外部删除和路径端点校验
+//
+// - 外部删除：上次同步有快照、本次外部表中已没有的记录，按表映射的删除策略处理；从未同步过的本地记录不受影响
+// - soft 把本地状态改为 deleted 并删除快照，外部重新出现时恢复为 active；hard 删除本地记录；report 只在结果中列出并保留快照
+// - hard 策略下仍有未删除路径连接的节点先软删除并保留快照，路径删除后的下一次同步再删除
+// - 软删除的本地记录在外部也不存在时视为不存在：计划不会把它当作本地新增，推送不会插入
+// - 路径的起点或终点在本地不存在或已删除时不写入；quarantine 策略下记入隔离区，以后每次拉取都重试，写入成功或外部删除后移出
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"robot-path-editor/internal/domain"
+	"robot-path-editor/internal/repositories"
+)
+
+// 外部删除的处理策略
+const (
+	SyncDeleteSoft   = "soft"
+	SyncDeleteHard   = "hard"
+	SyncDeleteReport = "report"
+)
+
+// 路径端点缺失的处理策略
+const (
+	SyncOrphanQuarantine = "quarantine"
+	SyncOrphanSkip       = "skip"
+)
+
+// syncIDChunk 按ID重读外部行时每条查询的ID数
+const syncIDChunk = 500
+
+// SyncOrphan 端点节点缺失而未写入的路径
+type SyncOrphan struct {
+	ID           string   `json:"id"`
+	StartNodeID  string   `json:"start_node_id"`
+	EndNodeID    string   `json:"end_node_id"`
+	MissingNodes []string `json:"missing_nodes"`
+	Quarantined  bool     `json:"quarantined"`
+}
+
+// checkSyncPolicies 校验表映射的删除和端点缺失策略
+func checkSyncPolicies(mapping *domain.TableMapping) error {
+	switch mapping.DeletionPolicy {
+	case "", SyncDeleteSoft, SyncDeleteHard, SyncDeleteReport:
+	default:
+		return fmt.Errorf("不支持的删除策略: %s", mapping.DeletionPolicy)
+	}
+	switch mapping.OrphanPolicy {
+	case "", SyncOrphanQuarantine, SyncOrphanSkip:
+	default:
+		return fmt.Errorf("不支持的端点缺失策略: %s", mapping.OrphanPolicy)
+	}
+	return nil
+}
+
+func syncDeletionPolicy(mapping *domain.TableMapping) string {
+	if mapping.DeletionPolicy == "" {
+		return SyncDeleteSoft
+	}
+	return mapping.DeletionPolicy
+}
+
+func syncOrphanPolicy(mapping *domain.TableMapping) string {
+	if mapping.OrphanPolicy == "" {
+		return SyncOrphanQuarantine
+	}
+	return mapping.OrphanPolicy
+}
+
+// isSoftDeleted 本地实体是否已软删除
+func isSoftDeleted(entity interface{}) bool {
+	switch e := entity.(type) {
+	case *domain.Node:
+		return e.Status == domain.NodeStatusDeleted
+	case *domain.Path:
+		return e.Status == domain.PathStatusDeleted
+	}
+	return false
+}
+
+// deleteSyncedEntity 按策略删除外部已删除的本地实体（report 策略不应调用）；
+// complete 为 false 表示节点仍有路径连接，只做了软删除
+func (s *dataSyncService) deleteSyncedEntity(ctx context.Context, policy string, entity interface{}) (complete bool, err error) {
+	if policy == SyncDeleteHard {
+		switch e := entity.(type) {
+		case *domain.Node:
+			paths, err := s.pathRepo.GetByNode(ctx, e.ID)
+			if err != nil {
+				return false, fmt.Errorf("获取节点 %s 的路径失败: %w", e.ID, err)
+			}
+			connected := false
+			for _, p := range paths {
+				connected = connected || p.Status != domain.PathStatusDeleted
+			}
+			if !connected {
+				if err := s.nodeRepo.Delete(ctx, e.ID); err != nil {
+					return false, fmt.Errorf("删除节点 %s 失败: %w", e.ID, err)
+				}
+				return true, nil
+			}
+		case *domain.Path:
+			if err := s.pathRepo.Delete(ctx, e.ID); err != nil {
+				return false, fmt.Errorf("删除路径 %s 失败: %w", e.ID, err)
+			}
+			return true, nil
+		}
+	}
+
+	if !isSoftDeleted(entity) {
+		switch e := entity.(type) {
+		case *domain.Node:
+			e.Status = domain.NodeStatusDeleted
+			e.Metadata.UpdatedAt = time.Now()
+			e.Metadata.Version++
+			if err := s.nodeRepo.Update(ctx, e); err != nil {
+				return false, fmt.Errorf("软删除节点 %s 失败: %w", e.ID, err)
+			}
+		case *domain.Path:
+			e.Status = domain.PathStatusDeleted
+			e.Metadata.UpdatedAt = time.Now()
+			e.Metadata.Version++
+			if err := s.pathRepo.Update(ctx, e); err != nil {
+				return false, fmt.Errorf("软删除路径 %s 失败: %w", e.ID, err)
+			}
+		}
+	}
+	return policy != SyncDeleteHard, nil
+}
+
+// propagateDeletions 处理有快照但外部表中已不存在的记录：按批在事务中删除本地记录和快照，
+// report 策略只在结果中列出
+func (s *dataSyncService) propagateDeletions(ctx context.Context, mapping *domain.TableMapping, spec *syncSpec, snapshots []*domain.SyncSnapshot, present map[string]bool, progress *SyncProgress, result *SyncResult) error {
+	policy := syncDeletionPolicy(mapping)
+	var gone []string
+	for _, snap := range snapshots {
+		if present[snap.RecordID] {
+			continue
+		}
+		if policy == SyncDeleteReport {
+			result.Missing = append(result.Missing, spec.entity+":"+snap.RecordID)
+			continue
+		}
+		gone = append(gone, snap.RecordID)
+	}
+	if len(gone) == 0 {
+		return nil
+	}
+
+	progress.Phase, progress.Total, progress.Processed = SyncPhaseDelete, len(gone), 0
+	reportSyncProgress(ctx, *progress)
+	for start := 0; start < len(gone); start += syncBatchSize {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		chunk := gone[start:min(start+syncBatchSize, len(gone))]
+		locals, err := s.loadSyncLocals(ctx, spec.entity, chunk)
+		if err != nil {
+			return err
+		}
+		batch := make([]*syncRow, 0, len(chunk))
+		for _, id := range chunk {
+			local, exists := locals[id]
+			batch = append(batch, &syncRow{id: id, entity: local, exists: exists})
+		}
+		// 删除的是实体的拷贝，事务回滚后重试仍从原值开始
+		failures, err := s.commitSyncBatch(ctx, batch, func(ctx context.Context, batch []*syncRow) error {
+			var dropped []string
+			for _, r := range batch {
+				r.complete = true
+				if r.exists {
+					complete, err := s.deleteSyncedEntity(ctx, policy, cloneSyncEntity(r.entity))
+					if err != nil {
+						return err
+					}
+					r.complete = complete
+				}
+				if r.complete {
+					dropped = append(dropped, r.id)
+				}
+			}
+			return s.saveSyncSnapshots(ctx, mapping.ID, spec, nil, nil, dropped)
+		})
+		if err != nil {
+			return err
+		}
+		for _, r := range batch {
+			if err := failures[r.id]; err != nil {
+				result.Errors = append(result.Errors, err.Error())
+				progress.Failed++
+				continue
+			}
+			// 只统计有变化的记录：新软删除的，或 hard 策略下真正删除的
+			if !r.exists || (isSoftDeleted(r.entity) && !(policy == SyncDeleteHard && r.complete)) {
+				continue
+			}
+			progress.Deleted++
+			if spec.entity == "node" {
+				result.NodesDeleted++
+			} else {
+				result.PathsDeleted++
+			}
+		}
+		progress.Processed += len(batch)
+		reportSyncProgress(ctx, *progress)
+	}
+	return nil
+}
+
+// activeSyncNodes 本地存在且未删除的节点ID
+func (s *dataSyncService) activeSyncNodes(ctx context.Context) (map[domain.NodeID]bool, error) {
+	nodes, err := s.nodeRepo.List(ctx, repositories.NodeFilter{})
+	if err != nil {
+		return nil, fmt.Errorf("获取节点列表失败: %w", err)
+	}
+	active := make(map[domain.NodeID]bool, len(nodes))
+	for _, n := range nodes {
+		if n.Status != domain.NodeStatusDeleted {
+			active[n.ID] = true
+		}
+	}
+	return active, nil
+}
+
+// syncPathOrphan 路径写入后的起点或终点不在 nodes 中时返回缺失信息；local 为本地已有的路径，可为 nil
+func syncPathOrphan(spec *syncSpec, id string, local interface{}, fields map[string]string, nodes map[domain.NodeID]bool) *SyncOrphan {
+	probe := newSyncedEntity("path", id).(*domain.Path)
+	if p, ok := local.(*domain.Path); ok && p != nil {
+		probe.StartNodeID, probe.EndNodeID = p.StartNodeID, p.EndNodeID
+	}
+	// 只关心端点，其余字段的值无效时由写入报告错误
+	if err := spec.apply(probe, fields); err != nil {
+		return nil
+	}
+	var missing []string
+	for _, n := range []domain.NodeID{probe.StartNodeID, probe.EndNodeID} {
+		if !nodes[n] && !containsValue(missing, string(n)) {
+			missing = append(missing, string(n))
+		}
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+	return &SyncOrphan{
+		ID:           id,
+		StartNodeID:  string(probe.StartNodeID),
+		EndNodeID:    string(probe.EndNodeID),
+		MissingNodes: missing,
+	}
+}
+
+// ListSyncQuarantine 列出表映射隔离中的路径
+func (s *dataSyncService) ListSyncQuarantine(ctx context.Context, mappingID string) ([]*domain.SyncQuarantine, error) {
+	if _, err := s.tableMappingRepo.GetByID(ctx, mappingID); err != nil {
+		return nil, fmt.Errorf("获取表映射失败: %w", err)
+	}
+	entries, err := s.quarantineRepo.List(ctx, mappingID)
+	if err != nil {
+		return nil, fmt.Errorf("获取同步隔离区失败: %w", err)
+	}
+	return entries, nil
+}
+
+// DiscardSyncQuarantine 移出一条隔离记录；外部行仍然端点缺失时下次拉取会重新隔离
+func (s *dataSyncService) DiscardSyncQuarantine(ctx context.Context, mappingID, pathID string) error {
+	if err := s.quarantineRepo.Delete(ctx, mappingID, pathID); err != nil {
+		return fmt.Errorf("移出隔离记录失败: %w", err)
+	}
+	return nil
+}
+
+// readIDs 外部表中满足筛选条件的全部ID
+func (sp *syncSpec) readIDs(ctx context.Context) (map[string]bool, error) {
+	ids, _, err := sp.queryRows(ctx, []string{sp.id.name}, sp.filter, nil)
+	if err != nil {
+		return nil, err
+	}
+	present := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		present[id] = true
+	}
+	return present, nil
+}
+
+// readRowsByID 按ID读取满足筛选条件的行，已不存在的ID不返回
+func (sp *syncSpec) readRowsByID(ctx context.Context, ids []string) ([]string, map[string]map[string]interface{}, error) {
+	var found []string
+	rows := make(map[string]map[string]interface{})
+	for start := 0; start < len(ids); start += syncIDChunk {
+		end := min(start+syncIDChunk, len(ids))
+		values := make([]interface{}, 0, end-start)
+		for _, id := range ids[start:end] {
+			values = append(values, id)
+		}
+		filter := append(append([]domain.TableCondition(nil), sp.filter...),
+			domain.TableCondition{Column: sp.id.name, Operator: "in", Value: values})
+		chunkIDs, chunkRows, err := sp.queryRows(ctx, sp.columnNames(), filter, nil)
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, id := range chunkIDs {
+			found = append(found, id)
+			rows[id] = chunkRows[id]
+		}
+	}
+	return found, rows, nil
+}
@@ -0,0 +1,579 @@
+// Package services 同步字段映射：节点和路径的可映射字段、值转换和通配规则
+//
+// - 每个本地字段有固定名称（position.x、robot_coords.yaw、style.color、waypoints、properties.批次 等），映射到外部表的一列
+// - 旧的 id_field、x_field 等写法等价于对应字段的映射，fields 中同名字段优先
+// - 转换：scale 单位换算（外部值 × scale = 本地值）、enum 枚举对照（外部值 → 本地值）、json 列中存 JSON
+// - 通配规则 {"field": "properties.*", "column": "attr_*"}：匹配且未被其他字段映射的列写入 Properties，键为列名
+// - 比较、快照和推送统一使用外部表的表示：本地值先按映射转换为外部表示，JSON 列按规范化后的文本比较
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"path"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"robot-path-editor/internal/domain"
+)
+
+// 字段值的类型
+const (
+	syncKindText   = "text"
+	syncKindNumber = "number"
+	syncKindObject = "object" // 结构化值，只能映射到 JSON 列
+	syncKindAny    = "any"    // 扩展属性，值原样保存
+)
+
+// syncField 实体的一个可映射字段；get 返回 nil 表示值不存在（对应外部的 NULL）
+type syncField struct {
+	kind string
+	get  func(entity interface{}) interface{}
+	set  func(entity interface{}, value interface{}) error
+}
+
+func textField[T any, S ~string](ref func(T) *S) syncField {
+	return syncField{
+		kind: syncKindText,
+		get:  func(e interface{}) interface{} { return string(*ref(e.(T))) },
+		set: func(e interface{}, v interface{}) error {
+			*ref(e.(T)) = S(v.(string))
+			return nil
+		},
+	}
+}
+
+func numberField[T any](ref func(T) *float64) syncField {
+	return syncField{
+		kind: syncKindNumber,
+		get:  func(e interface{}) interface{} { return *ref(e.(T)) },
+		set: func(e interface{}, v interface{}) error {
+			*ref(e.(T)) = v.(float64)
+			return nil
+		},
+	}
+}
+
+// objectField 结构化字段，零值视为不存在；set 收到的是 JSON 文本
+func objectField[T any, V any](ref func(T) *V) syncField {
+	return syncField{
+		kind: syncKindObject,
+		get: func(e interface{}) interface{} {
+			v := *ref(e.(T))
+			if reflect.ValueOf(&v).Elem().IsZero() {
+				return nil
+			}
+			return v
+		},
+		set: func(e interface{}, v interface{}) error {
+			var value V
+			if err := json.Unmarshal([]byte(v.(string)), &value); err != nil {
+				return err
+			}
+			*ref(e.(T)) = value
+			return nil
+		},
+	}
+}
+
+// robotField 机器人坐标的一个分量；节点没有机器人坐标时视为不存在，写入 0 时不创建机器人坐标
+func robotField(ref func(*domain.RobotCoordinates) *float64) syncField {
+	return syncField{
+		kind: syncKindNumber,
+		get: func(e interface{}) interface{} {
+			n := e.(*domain.Node)
+			if n.RobotCoords == nil {
+				return nil
+			}
+			return *ref(n.RobotCoords)
+		},
+		set: func(e interface{}, v interface{}) error {
+			n := e.(*domain.Node)
+			if n.RobotCoords == nil {
+				if v.(float64) == 0 {
+					return nil
+				}
+				n.RobotCoords = &domain.RobotCoordinates{}
+			}
+			*ref(n.RobotCoords) = v.(float64)
+			return nil
+		},
+	}
+}
+
+// propertyField 扩展属性中的一个键；值为 nil 时删除该键
+func propertyField[T any](props func(T) *map[string]interface{}, key string) syncField {
+	return syncField{
+		kind: syncKindAny,
+		get: func(e interface{}) interface{} {
+			return (*props(e.(T)))[key]
+		},
+		set: func(e interface{}, v interface{}) error {
+			m := props(e.(T))
+			if v == nil {
+				delete(*m, key)
+				return nil
+			}
+			if *m == nil {
+				*m = make(map[string]interface{})
+			}
+			(*m)[key] = v
+			return nil
+		},
+	}
+}
+
+// syncEntityDef 一类实体的可映射字段
+type syncEntityDef struct {
+	entity   string
+	fields   map[string]syncField
+	property func(key string) syncField
+	required []string // 必须映射的字段
+}
+
+// lookup 按名称查找字段，properties.<键> 为扩展属性
+func (d *syncEntityDef) lookup(name string) (syncField, bool) {
+	if key, ok := strings.CutPrefix(name, "properties."); ok && key != "" && key != "*" {
+		return d.property(key), true
+	}
+	f, ok := d.fields[name]
+	return f, ok
+}
+
+var nodeSyncDef = &syncEntityDef{
+	entity: "node",
+	fields: map[string]syncField{
+		"id":                 textField(func(n *domain.Node) *domain.NodeID { return &n.ID }),
+		"name":               textField(func(n *domain.Node) *string { return &n.Name }),
+		"type":               textField(func(n *domain.Node) *domain.NodeType { return &n.Type }),
+		"status":             textField(func(n *domain.Node) *domain.NodeStatus { return &n.Status }),
+		"position.x":         numberField(func(n *domain.Node) *float64 { return &n.Position.X }),
+		"position.y":         numberField(func(n *domain.Node) *float64 { return &n.Position.Y }),
+		"position.z":         numberField(func(n *domain.Node) *float64 { return &n.Position.Z }),
+		"robot_coords":       objectField(func(n *domain.Node) **domain.RobotCoordinates { return &n.RobotCoords }),
+		"robot_coords.x":     robotField(func(r *domain.RobotCoordinates) *float64 { return &r.X }),
+		"robot_coords.y":     robotField(func(r *domain.RobotCoordinates) *float64 { return &r.Y }),
+		"robot_coords.z":     robotField(func(r *domain.RobotCoordinates) *float64 { return &r.Z }),
+		"robot_coords.roll":  robotField(func(r *domain.RobotCoordinates) *float64 { return &r.Roll }),
+		"robot_coords.pitch": robotField(func(r *domain.RobotCoordinates) *float64 { return &r.Pitch }),
+		"robot_coords.yaw":   robotField(func(r *domain.RobotCoordinates) *float64 { return &r.Yaw }),
+		"style":              objectField(func(n *domain.Node) *domain.NodeStyle { return &n.Style }),
+		"style.color":        textField(func(n *domain.Node) *string { return &n.Style.Color }),
+		"style.size":         numberField(func(n *domain.Node) *float64 { return &n.Style.Size }),
+		"style.shape":        textField(func(n *domain.Node) *string { return &n.Style.Shape }),
+		"style.border_color": textField(func(n *domain.Node) *string { return &n.Style.BorderColor }),
+		"style.border_width": numberField(func(n *domain.Node) *float64 { return &n.Style.BorderWidth }),
+		"style.opacity":      numberField(func(n *domain.Node) *float64 { return &n.Style.Opacity }),
+		"properties":         objectField(func(n *domain.Node) *map[string]interface{} { return &n.Properties }),
+	},
+	property: func(key string) syncField {
+		return propertyField(func(n *domain.Node) *map[string]interface{} { return &n.Properties }, key)
+	},
+	required: []string{"id"},
+}
+
+var pathSyncDef = &syncEntityDef{
+	entity: "path",
+	fields: map[string]syncField{
+		"id":            textField(func(p *domain.Path) *domain.PathID { return &p.ID }),
+		"name":          textField(func(p *domain.Path) *string { return &p.Name }),
+		"type":          textField(func(p *domain.Path) *domain.PathType { return &p.Type }),
+		"status":        textField(func(p *domain.Path) *domain.PathStatus { return &p.Status }),
+		"start_node_id": textField(func(p *domain.Path) *domain.NodeID { return &p.StartNodeID }),
+		"end_node_id":   textField(func(p *domain.Path) *domain.NodeID { return &p.EndNodeID }),
+		"weight":        numberField(func(p *domain.Path) *float64 { return &p.Weight }),
+		"length":        numberField(func(p *domain.Path) *float64 { return &p.Length }),
+		"direction":     textField(func(p *domain.Path) *string { return &p.Direction }),
+		"curve_type":    textField(func(p *domain.Path) *domain.CurveType { return &p.CurveType }),
+		"waypoints":     objectField(func(p *domain.Path) *[]domain.Position { return &p.Waypoints }),
+		"style":         objectField(func(p *domain.Path) *domain.PathStyle { return &p.Style }),
+		"style.color":   textField(func(p *domain.Path) *string { return &p.Style.Color }),
+		"style.width":   numberField(func(p *domain.Path) *float64 { return &p.Style.Width }),
+		"style.style":   textField(func(p *domain.Path) *string { return &p.Style.Style }),
+		"style.opacity": numberField(func(p *domain.Path) *float64 { return &p.Style.Opacity }),
+		"properties":    objectField(func(p *domain.Path) *map[string]interface{} { return &p.Properties }),
+	},
+	property: func(key string) syncField {
+		return propertyField(func(p *domain.Path) *map[string]interface{} { return &p.Properties }, key)
+	},
+	required: []string{"id", "start_node_id", "end_node_id"},
+}
+
+// syncEntityMapping 一类实体的映射：旧字段写法和 fields 合并后的结果
+type syncEntityMapping struct {
+	table  string
+	filter []domain.TableCondition
+	order  []domain.TableOrder
+	fields []domain.FieldMapping
+}
+
+func nodeEntityMapping(mapping *domain.TableMapping) *syncEntityMapping {
+	m := mapping.NodeMapping
+	legacy := []domain.FieldMapping{
+		{Field: "id", Column: m.IDField},
+		{Field: "name", Column: m.NameField},
+		{Field: "type", Column: m.TypeField},
+		{Field: "position.x", Column: m.XField},
+		{Field: "position.y", Column: m.YField},
+		{Field: "position.z", Column: m.ZField},
+	}
+	return newSyncEntityMapping(mapping, m.TableName, m.Filter, m.OrderBy, legacy, m.Fields)
+}
+
+func pathEntityMapping(mapping *domain.TableMapping) *syncEntityMapping {
+	m := mapping.PathMapping
+	legacy := []domain.FieldMapping{
+		{Field: "id", Column: m.IDField},
+		{Field: "start_node_id", Column: m.StartNodeField},
+		{Field: "end_node_id", Column: m.EndNodeField},
+		{Field: "name", Column: m.NameField},
+		{Field: "weight", Column: m.WeightField},
+	}
+	return newSyncEntityMapping(mapping, m.TableName, m.Filter, m.OrderBy, legacy, m.Fields)
+}
+
+func newSyncEntityMapping(mapping *domain.TableMapping, table string, filter []domain.TableCondition, order []domain.TableOrder, legacy, fields []domain.FieldMapping) *syncEntityMapping {
+	em := &syncEntityMapping{table: mapping.TableName, filter: mapping.Filter, order: mapping.OrderBy}
+	if table != "" && table != mapping.TableName {
+		em.table, em.filter, em.order = table, nil, nil
+	}
+	if len(filter) > 0 {
+		em.filter = filter
+	}
+	if len(order) > 0 {
+		em.order = order
+	}
+
+	explicit := make(map[string]bool, len(fields))
+	for _, f := range fields {
+		explicit[f.Field] = true
+	}
+	for _, f := range legacy {
+		if f.Column != "" && !explicit[f.Field] {
+			em.fields = append(em.fields, f)
+		}
+	}
+	em.fields = append(em.fields, fields...)
+	return em
+}
+
+// syncColumn 外部表的一列与本地字段的对应关系
+type syncColumn struct {
+	name    string // 映射中的列名，也是比较和快照中的键
+	field   string
+	access  syncField
+	scale   float64
+	enum    map[string]string // 外部值 → 本地值
+	reverse map[string]string // 本地值 → 外部值
+	json    bool
+}
+
+func (c *syncColumn) numeric() bool { return c.access.kind == syncKindNumber && !c.json }
+
+// external 本地实体的字段值转换为外部表的值
+func (c *syncColumn) external(entity interface{}) interface{} {
+	v := c.access.get(entity)
+	if v == nil {
+		return nil
+	}
+	if c.json {
+		data, err := json.Marshal(v)
+		if err != nil || string(data) == "null" {
+			return nil
+		}
+		return string(data)
+	}
+	switch c.access.kind {
+	case syncKindNumber:
+		f := v.(float64)
+		if c.scale != 0 {
+			f = math.Round(f/c.scale*1e9) / 1e9
+		}
+		return f
+	case syncKindText:
+		s := v.(string)
+		if e, ok := c.reverse[s]; ok {
+			return e
+		}
+		return s
+	}
+	return v
+}
+
+// normalize 统一比较用的字符串形式；数值列的 NULL 视为 0
+func (c *syncColumn) normalize(v interface{}) string {
+	if v == nil && c.numeric() {
+		return "0"
+	}
+	s := normalizeSyncValue(v, c.numeric())
+	if c.json && s != "" {
+		return canonicalJSON(s)
+	}
+	return s
+}
+
+// apply 把外部表示的值写到本地实体，空字符串表示外部为 NULL
+func (c *syncColumn) apply(entity interface{}, value string) error {
+	var err error
+	switch {
+	case c.json && c.access.kind == syncKindAny:
+		var v interface{}
+		if value != "" {
+			err = json.Unmarshal([]byte(value), &v)
+		}
+		if err == nil {
+			err = c.access.set(entity, v)
+		}
+	case c.json:
+		if value == "" {
+			value = "null"
+		}
+		err = c.access.set(entity, value)
+	case c.access.kind == syncKindNumber:
+		var f float64
+		if value != "" {
+			if f, err = strconv.ParseFloat(value, 64); err != nil {
+				return fmt.Errorf("列 %s 的值 %q 不是数值", c.name, value)
+			}
+		}
+		if c.scale != 0 {
+			f *= c.scale
+		}
+		err = c.access.set(entity, f)
+	case c.access.kind == syncKindText:
+		if v, ok := c.enum[value]; ok {
+			value = v
+		}
+		err = c.access.set(entity, value)
+	default:
+		var v interface{}
+		if value != "" {
+			v = value
+		}
+		err = c.access.set(entity, v)
+	}
+	if err != nil {
+		return fmt.Errorf("列 %s 写入字段 %s 失败: %w", c.name, c.field, err)
+	}
+	return nil
+}
+
+// syncSpec 一类实体在外部表中的映射
+type syncSpec struct {
+	entity    string
+	table     *externalTable
+	tableName string
+	filter    []domain.TableCondition
+	order     []domain.TableOrder
+	id        *syncColumn
+	columns   []*syncColumn // 不含ID列，绑定外部表后包含通配规则展开的列
+	wildcards []domain.FieldMapping
+}
+
+// newSyncSpec 校验映射并生成列定义，不需要连接外部数据库
+func newSyncSpec(def *syncEntityDef, em *syncEntityMapping) (*syncSpec, error) {
+	if em.table == "" {
+		return nil, fmt.Errorf("%s映射未指定表名", syncEntityLabel(def.entity))
+	}
+	if err := checkIdentifier(em.table); err != nil {
+		return nil, fmt.Errorf("%s映射的表名无效: %w", syncEntityLabel(def.entity), err)
+	}
+	if err := checkTableQuery(em.filter, em.order); err != nil {
+		return nil, err
+	}
+
+	spec := &syncSpec{entity: def.entity, tableName: em.table, filter: em.filter, order: em.order}
+	fields := make(map[string]bool)
+	columns := make(map[string]string)
+	for _, fm := range em.fields {
+		if fm.Column == "" {
+			return nil, fmt.Errorf("字段 %s 未指定列", fm.Field)
+		}
+		if fm.Field == "properties.*" {
+			if _, err := path.Match(fm.Column, ""); err != nil {
+				return nil, fmt.Errorf("通配规则 %q 无效: %w", fm.Column, err)
+			}
+			spec.wildcards = append(spec.wildcards, fm)
+			continue
+		}
+		access, ok := def.lookup(fm.Field)
+		if !ok {
+			return nil, fmt.Errorf("%s没有字段 %s", syncEntityLabel(def.entity), fm.Field)
+		}
+		if fields[fm.Field] {
+			return nil, fmt.Errorf("字段 %s 重复映射", fm.Field)
+		}
+		key := strings.ToLower(fm.Column)
+		if other, ok := columns[key]; ok {
+			return nil, fmt.Errorf("列 %s 同时映射到字段 %s 和 %s", fm.Column, other, fm.Field)
+		}
+		fields[fm.Field], columns[key] = true, fm.Field
+
+		c, err := newSyncColumn(fm, access)
+		if err != nil {
+			return nil, err
+		}
+		if fm.Field == "id" {
+			if c.scale != 0 || len(c.enum) > 0 || c.json {
+				return nil, fmt.Errorf("ID列不支持值转换")
+			}
+			spec.id = c
+		} else {
+			spec.columns = append(spec.columns, c)
+		}
+	}
+	for _, f := range def.required {
+		if !fields[f] {
+			return nil, fmt.Errorf("%s映射缺少字段 %s", syncEntityLabel(def.entity), f)
+		}
+	}
+	return spec, nil
+}
+
+func newSyncColumn(fm domain.FieldMapping, access syncField) (*syncColumn, error) {
+	c := &syncColumn{name: fm.Column, field: fm.Field, access: access, scale: fm.Scale, json: fm.JSON}
+	switch {
+	case access.kind == syncKindObject && !fm.JSON:
+		return nil, fmt.Errorf("字段 %s 是结构化值，只能映射到 JSON 列", fm.Field)
+	case fm.JSON && access.kind != syncKindObject && access.kind != syncKindAny:
+		return nil, fmt.Errorf("字段 %s 不能映射到 JSON 列", fm.Field)
+	case fm.Scale != 0 && access.kind != syncKindNumber:
+		return nil, fmt.Errorf("字段 %s 不是数值，不能设置 scale", fm.Field)
+	case fm.Scale < 0 || math.IsNaN(fm.Scale) || math.IsInf(fm.Scale, 0):
+		return nil, fmt.Errorf("字段 %s 的 scale 必须为正数", fm.Field)
+	case len(fm.Enum) > 0 && access.kind != syncKindText:
+		return nil, fmt.Errorf("字段 %s 不是文本，不能设置 enum", fm.Field)
+	}
+	if len(fm.Enum) > 0 {
+		c.enum = fm.Enum
+		c.reverse = make(map[string]string, len(fm.Enum))
+		for external, local := range fm.Enum {
+			if prev, ok := c.reverse[local]; ok && prev != external {
+				return nil, fmt.Errorf("字段 %s 的 enum 中 %s 对应多个外部值", fm.Field, local)
+			}
+			c.reverse[local] = external
+		}
+	}
+	return c, nil
+}
+
+// bind 按外部表的实际结构校验映射的列，并展开通配规则
+func (sp *syncSpec) bind(def *syncEntityDef, table *externalTable) error {
+	sp.table = table
+	names := sp.columnNames()
+	for _, c := range sp.filter {
+		names = append(names, c.Column)
+	}
+	for _, o := range sp.order {
+		names = append(names, o.Column)
+	}
+	if err := table.checkColumns(names...); err != nil {
+		return err
+	}
+	if len(sp.wildcards) == 0 {
+		return nil
+	}
+
+	used := make(map[string]bool)
+	for _, name := range sp.columnNames() {
+		column, _ := table.column(name)
+		used[column] = true
+	}
+	for _, column := range table.columns {
+		if used[column] {
+			continue
+		}
+		for _, w := range sp.wildcards {
+			if ok, _ := path.Match(w.Column, column); !ok {
+				continue
+			}
+			c, err := newSyncColumn(domain.FieldMapping{Field: "properties." + column, Column: column, JSON: w.JSON}, def.property(column))
+			if err != nil {
+				return err
+			}
+			sp.columns = append(sp.columns, c)
+			break
+		}
+	}
+	return nil
+}
+
+// columnNames 映射的全部列名，第一列为ID列
+func (sp *syncSpec) columnNames() []string {
+	names := []string{sp.id.name}
+	for _, c := range sp.columns {
+		names = append(names, c.name)
+	}
+	return names
+}
+
+// localFields 本地实体按映射转换后的列值
+func (sp *syncSpec) localFields(entity interface{}) map[string]string {
+	fields := make(map[string]string, len(sp.columns))
+	for _, c := range sp.columns {
+		fields[c.name] = c.normalize(c.external(entity))
+	}
+	return fields
+}
+
+// remoteFields 外部行的列值
+func (sp *syncSpec) remoteFields(raw map[string]interface{}) map[string]string {
+	fields := make(map[string]string, len(sp.columns))
+	for _, c := range sp.columns {
+		fields[c.name] = c.normalize(raw[c.name])
+	}
+	return fields
+}
+
+// apply 把列值写到本地实体，未映射的字段不变
+func (sp *syncSpec) apply(entity interface{}, fields map[string]string) error {
+	for _, c := range sp.columns {
+		if v, ok := fields[c.name]; ok {
+			if err := c.apply(entity, v); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// row 由列值生成外部表的行；非文本列的空值写为 NULL
+func (sp *syncSpec) row(id string, fields map[string]string) pushRow {
+	row := pushRow{entity: sp.entity, columns: []pushColumn{{name: sp.id.name, value: id}}}
+	for _, c := range sp.columns {
+		v := fields[c.name]
+		var value interface{} = v
+		switch {
+		case v == "" && (c.json || c.access.kind != syncKindText):
+			value = nil
+		case c.numeric():
+			if f, err := strconv.ParseFloat(v, 64); err == nil {
+				value = f
+			}
+		}
+		row.columns = append(row.columns, pushColumn{name: c.name, value: value, numeric: c.numeric(), json: c.json})
+	}
+	return row
+}
+
+func syncEntityLabel(entity string) string {
+	if entity == "path" {
+		return "路径"
+	}
+	return "节点"
+}
+
+// canonicalJSON JSON 文本的规范形式（键排序、无多余空白），无法解析时原样返回
+func canonicalJSON(s string) string {
+	var v interface{}
+	if err := json.Unmarshal([]byte(s), &v); err != nil {
+		return s
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return s
+	}
+	return string(data)
+}
@@ -0,0 +1,255 @@
+// Package services 增量拉取：按更新时间或版本号列的水位只读取变化的行
+//
+// - 水位列为空时读取全部行；有水位时只读取水位列不小于上次水位的行，按水位列排序
+// - 用"不小于"而不是"大于"，同一时刻稍后提交的行不会漏掉；边界上已同步的行与快照一致，直接跳过
+// - 与上次同步快照一致且本地存在的行跳过，不覆盖本地对这些记录的修改
+// - 有行写入失败时水位停在失败之前，下次从失败的行重试；水位随每批提交推进，中途取消时保留已提交的部分
+// - 外部删除需要全部ID，有快照时另外只读取ID列；隔离中的路径每次按ID重读
+package services
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"robot-path-editor/internal/domain"
+)
+
+// IncrementalSyncRequest 增量拉取请求
+type IncrementalSyncRequest struct {
+	Entities        []string          `json:"entities,omitempty"`         // node、path，为空时拉取已配置的全部实体
+	WatermarkColumn string            `json:"watermark_column,omitempty"` // 更新时间或版本号列，为空时全量读取
+	Watermarks      map[string]string `json:"watermarks,omitempty"`       // 实体 → 上次同步到的水位
+}
+
+// IncrementalSyncResult 增量拉取结果
+type IncrementalSyncResult struct {
+	SyncResult
+	Skipped    int               `json:"skipped"`              // 与上次同步快照一致而跳过的行
+	Watermarks map[string]string `json:"watermarks,omitempty"` // 本次拉取后的水位
+}
+
+// SyncChangesFromExternal 增量拉取节点和路径；中途取消或失败时返回已提交部分的结果和推进到的水位
+func (s *dataSyncService) SyncChangesFromExternal(ctx context.Context, mappingID string, req IncrementalSyncRequest) (*IncrementalSyncResult, error) {
+	for _, entity := range req.Entities {
+		if entity != "node" && entity != "path" {
+			return nil, fmt.Errorf("不支持的同步实体: %s", entity)
+		}
+	}
+	mapping, err := s.tableMappingRepo.GetByID(ctx, mappingID)
+	if err != nil {
+		return nil, fmt.Errorf("获取表映射失败: %w", err)
+	}
+	if mapping.NodeMapping == nil && mapping.PathMapping == nil {
+		return nil, fmt.Errorf("表映射中未配置节点映射或路径映射")
+	}
+	source, err := s.openSyncSource(ctx, mapping)
+	if err != nil {
+		return nil, err
+	}
+	defer source.Close()
+
+	result := &IncrementalSyncResult{Watermarks: make(map[string]string)}
+	for entity, mark := range req.Watermarks {
+		result.Watermarks[entity] = mark
+	}
+	for _, spec := range source.specs() {
+		if len(req.Entities) > 0 && !containsValue(req.Entities, spec.entity) {
+			continue
+		}
+		if err := s.pullEntity(ctx, mapping, spec, req.WatermarkColumn, true, result); err != nil {
+			return result, fmt.Errorf("拉取%s数据失败: %w", syncEntityLabel(spec.entity), err)
+		}
+	}
+	return result, nil
+}
+
+// pullEntity 拉取一类实体：写入外部行、处理外部删除和端点缺失的路径并记录快照，有水位列时推进水位。
+// column 为空时全量读取；skipUnchanged 为 true 时跳过与快照一致且本地存在的行
+func (s *dataSyncService) pullEntity(ctx context.Context, mapping *domain.TableMapping, spec *syncSpec, column string, skipUnchanged bool, result *IncrementalSyncResult) error {
+	ids, rows, marks, err := spec.readChangedRows(ctx, column, result.Watermarks[spec.entity])
+	if err != nil {
+		return err
+	}
+	snapshots, err := s.snapshotRepo.List(ctx, mapping.ID, spec.entity)
+	if err != nil {
+		return fmt.Errorf("获取同步快照失败: %w", err)
+	}
+	base := make(map[string]map[string]string, len(snapshots))
+	for _, snap := range snapshots {
+		base[snap.RecordID] = snap.Fields
+	}
+
+	// 路径按本地未删除的节点校验端点；增量读取时隔离中的行不一定有变化，按ID重读
+	var nodes map[domain.NodeID]bool
+	quarantined := make(map[string]*domain.SyncQuarantine)
+	if spec.entity == "path" {
+		if nodes, err = s.activeSyncNodes(ctx); err != nil {
+			return err
+		}
+		entries, err := s.quarantineRepo.List(ctx, mapping.ID)
+		if err != nil {
+			return fmt.Errorf("获取同步隔离区失败: %w", err)
+		}
+		var retry []string
+		for _, entry := range entries {
+			quarantined[entry.PathID] = entry
+			if _, ok := rows[entry.PathID]; !ok {
+				retry = append(retry, entry.PathID)
+			}
+		}
+		if column != "" && len(retry) > 0 {
+			retryIDs, retryRows, err := spec.readRowsByID(ctx, retry)
+			if err != nil {
+				return err
+			}
+			for _, id := range retryIDs {
+				ids = append(ids, id)
+				rows[id] = retryRows[id]
+			}
+		}
+	}
+
+	// 写入阶段：逐批提交，提交后推进水位；第一条失败之后不再推进，下次从失败前的水位重读
+	progress := SyncProgress{Entity: spec.entity, Phase: SyncPhaseWrite, Total: len(ids)}
+	reportSyncProgress(ctx, progress)
+	failed := false
+	for start := 0; start < len(ids); start += syncBatchSize {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		batch, err := s.prepareSyncRows(ctx, mapping, spec, ids[start:min(start+syncBatchSize, len(ids))], rows, base, nodes, skipUnchanged)
+		if err != nil {
+			return err
+		}
+		failures, err := s.commitSyncBatch(ctx, batch, func(ctx context.Context, batch []*syncRow) error {
+			return s.writeSyncRows(ctx, mapping, spec, batch, rows, quarantined)
+		})
+		if err != nil {
+			return err
+		}
+		for _, r := range batch {
+			err := r.err
+			if err == nil {
+				err = failures[r.id]
+			}
+			switch {
+			case err != nil:
+				result.Errors = append(result.Errors, err.Error())
+				progress.Failed++
+				failed = true
+			case r.orphan != nil:
+				result.Orphans = append(result.Orphans, *r.orphan)
+				progress.Orphans++
+			case r.entity == nil:
+				result.Skipped++
+				progress.Skipped++
+			case r.exists:
+				result.count(spec.entity, true)
+				progress.Updated++
+			default:
+				result.count(spec.entity, false)
+				progress.Created++
+			}
+			if !failed && marks[r.id] != "" {
+				result.Watermarks[spec.entity] = marks[r.id]
+			}
+		}
+		progress.Processed += len(batch)
+		reportSyncProgress(ctx, progress)
+	}
+
+	// 外部删除：全量读取时已有全部ID，增量读取时另外只读ID列
+	present := make(map[string]bool, len(rows))
+	if column == "" {
+		for id := range rows {
+			present[id] = true
+		}
+	} else if len(snapshots) > 0 {
+		if present, err = spec.readIDs(ctx); err != nil {
+			return err
+		}
+	}
+	if err := s.propagateDeletions(ctx, mapping, spec, snapshots, present, &progress, &result.SyncResult); err != nil {
+		return err
+	}
+
+	// 本次没有读到的隔离记录（外部已删除或不再满足筛选条件）移出隔离区
+	if spec.entity == "path" {
+		var released []string
+		for id := range quarantined {
+			if _, ok := rows[id]; !ok {
+				released = append(released, id)
+			}
+		}
+		if len(released) > 0 {
+			if err := s.quarantineRepo.Save(ctx, mapping.ID, nil, released); err != nil {
+				result.Errors = append(result.Errors, fmt.Sprintf("保存同步隔离区失败: %v", err))
+			}
+		}
+	}
+	progress.Phase = SyncPhaseDone
+	reportSyncProgress(ctx, progress)
+	return nil
+}
+
+// readChangedRows 读取水位列不小于 since 的行（since 为空时读取全部），按水位列、ID列排序；
+// 返回按查询顺序的ID、按ID索引的行和每行的水位。column 为空时等同于 readRows
+func (sp *syncSpec) readChangedRows(ctx context.Context, column, since string) ([]string, map[string]map[string]interface{}, map[string]string, error) {
+	if column == "" {
+		ids, rows, err := sp.readRows(ctx)
+		return ids, rows, nil, err
+	}
+	column, err := sp.table.column(column)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("水位列无效: %w", err)
+	}
+
+	filter := append([]domain.TableCondition(nil), sp.filter...)
+	if since != "" {
+		filter = append(filter, domain.TableCondition{Column: column, Operator: "ge", Value: sp.watermarkArg(column, since)})
+	}
+	order := []domain.TableOrder{{Column: column}, {Column: sp.id.name}}
+	names := append(sp.columnNames(), column)
+	ids, rows, err := sp.queryRows(ctx, names, filter, order)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	marks := make(map[string]string, len(ids))
+	for _, id := range ids {
+		marks[id] = watermarkString(rows[id][column])
+	}
+	return ids, rows, marks, nil
+}
+
+// watermarkString 水位的存储形式，时间统一为 UTC 的 RFC 3339
+func watermarkString(v interface{}) string {
+	switch t := v.(type) {
+	case nil:
+		return ""
+	case time.Time:
+		return t.UTC().Format(time.RFC3339Nano)
+	case []byte:
+		return string(t)
+	default:
+		return fmt.Sprint(t)
+	}
+}
+
+// watermarkArg 水位作为查询参数：日期时间列还原为时间，SQLite 以文本存时间，按其常用格式传入
+func (sp *syncSpec) watermarkArg(column, mark string) interface{} {
+	typ := strings.ToLower(sp.table.types[column])
+	if !strings.Contains(typ, "date") && !strings.Contains(typ, "time") {
+		return mark
+	}
+	t, err := time.Parse(time.RFC3339Nano, mark)
+	if err != nil {
+		return mark
+	}
+	if sp.table.dialect.Name() == "sqlite" {
+		return t.Format("2006-01-02 15:04:05.999999999")
+	}
+	return t
+}
@@ -0,0 +1,814 @@
+// Package services 同步计划：上次同步快照、本地数据、外部数据三方比较
+//
+// 设计参考：
+// - git merge 的三方合并：以共同基准判断每一方是否修改，两方修改不同字段时自动合并
+// - Terraform 的 plan/apply：先生成计划供确认，应用时重新计算并可用计划ID校验数据未变
+//
+// 特点：
+// - 只比较表映射中配置的列；本地的样式、扩展属性、机器人坐标等未映射字段保持不变
+// - 只比较属于该映射的记录：有同步快照或外部表中存在；从未同步过的本地记录不列入计划
+// - 每条记录确定一个目标状态，本地与外部分别写到目标状态，快照更新为目标状态
+// - 外部写入在外部事务中执行，本地写入和快照在本地事务中执行；本地失败时外部事务回滚
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"robot-path-editor/internal/domain"
+	"robot-path-editor/internal/repositories"
+)
+
+// 同步变更类型
+const (
+	SyncChangeAdded    = "added"
+	SyncChangeRemoved  = "removed"
+	SyncChangeModified = "modified"
+	SyncChangeConflict = "conflict"
+)
+
+// 变更所在的一方
+const (
+	SyncSideLocal  = "local"
+	SyncSideRemote = "remote"
+	SyncSideBoth   = "both"
+)
+
+// 冲突解决策略
+const (
+	SyncPolicyLocalWins  = "local_wins"
+	SyncPolicyRemoteWins = "remote_wins"
+	SyncPolicyMerge      = "merge"
+	SyncPolicyManual     = "manual"
+)
+
+// 单条记录的解决方式
+const (
+	SyncResolveLocal  = "local"
+	SyncResolveRemote = "remote"
+	SyncResolveSkip   = "skip"
+)
+
+// SyncPlan 同步计划
+type SyncPlan struct {
+	MappingID string          `json:"mapping_id"`
+	PlanID    string          `json:"plan_id"` // 三方数据的指纹，应用时传入可确认数据未变
+	Summary   SyncPlanSummary `json:"summary"`
+	Items     []SyncPlanItem  `json:"items"`
+}
+
+// SyncPlanSummary 计划统计
+type SyncPlanSummary struct {
+	Added     int `json:"added"`
+	Removed   int `json:"removed"`
+	Modified  int `json:"modified"`
+	Conflicts int `json:"conflicts"`
+	Unchanged int `json:"unchanged"`
+}
+
+// SyncPlanItem 一条有变化的记录；Base、Local、Remote 为 nil 表示该方不存在
+type SyncPlanItem struct {
+	Key            string            `json:"key"` // entity:id，用于指定解决方式
+	Entity         string            `json:"entity"`
+	ID             string            `json:"id"`
+	Change         string            `json:"change"`
+	Side           string            `json:"side"`
+	ChangedFields  []string          `json:"changed_fields,omitempty"`
+	ConflictFields []string          `json:"conflict_fields,omitempty"`
+	Base           map[string]string `json:"base"`
+	Local          map[string]string `json:"local"`
+	Remote         map[string]string `json:"remote"`
+	Merged         map[string]string `json:"merged,omitempty"` // 两方修改不同字段时的合并结果
+}
+
+// ApplySyncPlanRequest 应用同步计划请求
+type ApplySyncPlanRequest struct {
+	Policy      string            `json:"policy"`                // 冲突解决策略，默认 manual
+	Resolutions map[string]string `json:"resolutions,omitempty"` // 记录Key → local|remote|skip
+	PlanID      string            `json:"plan_id,omitempty"`     // 非空时要求与当前计划一致
+}
+
+// SyncApplyResult 应用同步计划结果
+type SyncApplyResult struct {
+	PlanID         string          `json:"plan_id"`
+	LocalCreated   int             `json:"local_created"`
+	LocalUpdated   int             `json:"local_updated"`
+	LocalDeleted   int             `json:"local_deleted"`
+	RemoteInserted int             `json:"remote_inserted"`
+	RemoteUpdated  int             `json:"remote_updated"`
+	RemoteDeleted  int             `json:"remote_deleted"`
+	Skipped        []string        `json:"skipped,omitempty"`    // 指定跳过的记录
+	Unresolved     []string        `json:"unresolved,omitempty"` // merge 策略下无法自动合并且未指定解决方式的冲突
+	Missing        []string        `json:"missing,omitempty"`    // report 删除策略下外部已删除、本地保留的记录
+	Orphans        []SyncOrphan    `json:"orphans,omitempty"`    // 端点节点缺失而未写入本地的路径，快照保持不变
+	Statements     []PushStatement `json:"statements"`           // 对外部表执行的语句
+}
+
+// SyncConflictError 手动策略下存在未指定解决方式的冲突
+type SyncConflictError struct {
+	Keys []string
+}
+
+func (e *SyncConflictError) Error() string {
+	return fmt.Sprintf("%d 个冲突未指定解决方式: %s", len(e.Keys), strings.Join(e.Keys, ", "))
+}
+
+// syncRecord 一条记录在三方的映射列值，nil 表示该方不存在
+type syncRecord struct {
+	spec                *syncSpec
+	id                  string
+	base, local, remote map[string]string
+}
+
+func (r *syncRecord) key() string { return r.spec.entity + ":" + r.id }
+
+// tracked 记录是否属于该映射：有同步快照或外部表中存在
+func (r *syncRecord) tracked() bool { return r.base != nil || r.remote != nil }
+
+// syncState 一次三方比较所需的数据
+type syncState struct {
+	mapping *domain.TableMapping
+	source  *syncSource
+	specs   []*syncSpec
+	records []*syncRecord
+	nodes   map[string]*domain.Node
+	paths   map[string]*domain.Path
+}
+
+// PlanSync 生成同步计划，不写入任何数据
+func (s *dataSyncService) PlanSync(ctx context.Context, mappingID string) (*SyncPlan, error) {
+	state, err := s.loadSyncState(ctx, mappingID, false)
+	if err != nil {
+		return nil, err
+	}
+	defer state.source.Close()
+	return buildSyncPlan(state), nil
+}
+
+// ApplySyncPlan 按策略和逐条解决方式应用同步计划
+func (s *dataSyncService) ApplySyncPlan(ctx context.Context, mappingID string, req ApplySyncPlanRequest) (*SyncApplyResult, error) {
+	if req.Policy == "" {
+		req.Policy = SyncPolicyManual
+	}
+	switch req.Policy {
+	case SyncPolicyLocalWins, SyncPolicyRemoteWins, SyncPolicyMerge, SyncPolicyManual:
+	default:
+		return nil, fmt.Errorf("不支持的冲突解决策略: %s", req.Policy)
+	}
+	for key, res := range req.Resolutions {
+		if res != SyncResolveLocal && res != SyncResolveRemote && res != SyncResolveSkip {
+			return nil, fmt.Errorf("记录 %s 的解决方式无效: %s", key, res)
+		}
+	}
+
+	state, err := s.loadSyncState(ctx, mappingID, false)
+	if err != nil {
+		return nil, err
+	}
+	defer state.source.Close()
+
+	plan := buildSyncPlan(state)
+	if req.PlanID != "" && req.PlanID != plan.PlanID {
+		return nil, fmt.Errorf("数据在生成计划后已发生变化，请重新生成计划")
+	}
+	items := make(map[string]*SyncPlanItem, len(plan.Items))
+	for i := range plan.Items {
+		items[plan.Items[i].Key] = &plan.Items[i]
+	}
+
+	// 手动策略要求每个冲突都有解决方式
+	var missing []string
+	if req.Policy == SyncPolicyManual {
+		for _, item := range plan.Items {
+			if item.Change == SyncChangeConflict && req.Resolutions[item.Key] == "" {
+				missing = append(missing, item.Key)
+			}
+		}
+	}
+	if len(missing) > 0 {
+		return nil, &SyncConflictError{Keys: missing}
+	}
+
+	// 确定每条记录的目标状态
+	result := &SyncApplyResult{PlanID: plan.PlanID, Statements: []PushStatement{}}
+	targets := make(map[*syncRecord]map[string]string, len(state.records))
+	for _, r := range state.records {
+		item := items[r.key()]
+		if item == nil {
+			targets[r] = r.local
+			continue
+		}
+		target, status := resolveSyncItem(item, req.Policy, req.Resolutions[item.Key])
+		if status == "" && target == nil && r.local != nil && syncDeletionPolicy(state.mapping) == SyncDeleteReport {
+			status = SyncDeleteReport
+		}
+		switch status {
+		case SyncDeleteReport:
+			result.Missing = append(result.Missing, item.Key)
+		case SyncResolveSkip:
+			result.Skipped = append(result.Skipped, item.Key)
+		case "unresolved":
+			result.Unresolved = append(result.Unresolved, item.Key)
+		default:
+			targets[r] = target
+		}
+	}
+
+	// 外部写入：插入和更新按节点、路径顺序，删除按相反顺序
+	var deletes [][]PushStatement
+	for _, spec := range state.specs {
+		var group []PushStatement
+		for _, r := range state.records {
+			target, ok := targets[r]
+			if r.spec != spec || !ok || syncFieldsEqual(target, r.remote) {
+				continue
+			}
+			switch {
+			case target == nil:
+				group = append(group, buildPushDelete(spec.table, spec.entity, spec.id.name, r.id))
+				result.RemoteDeleted++
+			case r.remote == nil:
+				result.Statements = append(result.Statements, buildPushInsert(spec.table, spec.row(r.id, target)))
+				result.RemoteInserted++
+			default:
+				current := make(map[string]interface{}, len(r.remote))
+				for k, v := range r.remote {
+					current[k] = v
+				}
+				if stmt, changed := buildPushUpdate(spec.table, spec.row(r.id, target), current); changed {
+					result.Statements = append(result.Statements, stmt)
+					result.RemoteUpdated++
+				}
+			}
+		}
+		deletes = append(deletes, group)
+	}
+	for i := len(deletes) - 1; i >= 0; i-- {
+		result.Statements = append(result.Statements, deletes[i]...)
+	}
+
+	var tx *sql.Tx
+	if len(result.Statements) > 0 {
+		if tx, err = state.source.db.BeginTx(ctx, nil); err != nil {
+			return nil, fmt.Errorf("开始外部数据库事务失败: %w", err)
+		}
+		for _, stmt := range result.Statements {
+			if _, err := tx.ExecContext(ctx, stmt.query, stmt.args...); err != nil {
+				tx.Rollback()
+				return nil, fmt.Errorf("执行 %s %s 失败，已回滚: %w", stmt.Op, stmt.ID, err)
+			}
+		}
+	}
+
+	// 本地写入和快照，失败时回滚外部事务
+	err = s.transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		if err := s.applyLocalSyncTargets(ctx, state, targets, result); err != nil {
+			return err
+		}
+		return s.saveSyncTargets(ctx, state, targets)
+	})
+	if err != nil {
+		if tx != nil {
+			tx.Rollback()
+		}
+		return nil, fmt.Errorf("应用同步计划失败，已回滚: %w", err)
+	}
+	if tx != nil {
+		if err := tx.Commit(); err != nil {
+			return nil, fmt.Errorf("提交外部数据库事务失败（本地已更新，请重新生成计划核对）: %w", err)
+		}
+	}
+	return result, nil
+}
+
+// loadSyncState 读取快照、本地数据和外部数据；untracked 为 true 时包含从未同步过的本地记录
+func (s *dataSyncService) loadSyncState(ctx context.Context, mappingID string, untracked bool) (*syncState, error) {
+	mapping, err := s.tableMappingRepo.GetByID(ctx, mappingID)
+	if err != nil {
+		return nil, fmt.Errorf("获取表映射失败: %w", err)
+	}
+	if mapping.NodeMapping == nil && mapping.PathMapping == nil {
+		return nil, fmt.Errorf("表映射中未配置节点映射或路径映射")
+	}
+	source, err := s.openSyncSource(ctx, mapping)
+	if err != nil {
+		return nil, err
+	}
+
+	state := &syncState{
+		mapping: mapping,
+		source:  source,
+		specs:   source.specs(),
+		nodes:   make(map[string]*domain.Node),
+		paths:   make(map[string]*domain.Path),
+	}
+	if err := s.loadSyncRecords(ctx, state, untracked); err != nil {
+		source.Close()
+		return nil, err
+	}
+	return state, nil
+}
+
+func (s *dataSyncService) loadSyncRecords(ctx context.Context, state *syncState, untracked bool) error {
+	local := make(map[*syncSpec]map[string]map[string]string)
+	if spec := state.source.node; spec != nil {
+		nodes, err := s.nodeRepo.List(ctx, repositories.NodeFilter{})
+		if err != nil {
+			return fmt.Errorf("获取节点列表失败: %w", err)
+		}
+		local[spec] = make(map[string]map[string]string, len(nodes))
+		for _, n := range nodes {
+			state.nodes[string(n.ID)] = n
+			local[spec][string(n.ID)] = spec.localFields(n)
+		}
+	}
+	if spec := state.source.path; spec != nil {
+		paths, err := s.pathRepo.List(ctx, repositories.PathFilter{})
+		if err != nil {
+			return fmt.Errorf("获取路径列表失败: %w", err)
+		}
+		local[spec] = make(map[string]map[string]string, len(paths))
+		for _, p := range paths {
+			state.paths[string(p.ID)] = p
+			local[spec][string(p.ID)] = spec.localFields(p)
+		}
+	}
+
+	for _, spec := range state.specs {
+		snapshots, err := s.snapshotRepo.List(ctx, state.mapping.ID, spec.entity)
+		if err != nil {
+			return fmt.Errorf("获取同步快照失败: %w", err)
+		}
+		_, remote, err := spec.readRows(ctx)
+		if err != nil {
+			return err
+		}
+
+		records := make(map[string]*syncRecord)
+		record := func(id string) *syncRecord {
+			if records[id] == nil {
+				records[id] = &syncRecord{spec: spec, id: id}
+			}
+			return records[id]
+		}
+		for _, snap := range snapshots {
+			record(snap.RecordID).base = snap.Fields
+		}
+		for id, fields := range local[spec] {
+			// 软删除且外部也没有的记录视为不存在，不当作本地新增
+			if remote[id] == nil && state.softDeleted(spec.entity, id) {
+				continue
+			}
+			record(id).local = fields
+		}
+		for id, raw := range remote {
+			record(id).remote = spec.remoteFields(raw)
+		}
+
+		ids := make([]string, 0, len(records))
+		for id, r := range records {
+			if untracked || r.tracked() {
+				ids = append(ids, id)
+			}
+		}
+		sort.Strings(ids)
+		for _, id := range ids {
+			state.records = append(state.records, records[id])
+		}
+	}
+	return nil
+}
+
+func (state *syncState) softDeleted(entity, id string) bool {
+	if entity == "node" {
+		return state.nodes[id] != nil && isSoftDeleted(state.nodes[id])
+	}
+	return state.paths[id] != nil && isSoftDeleted(state.paths[id])
+}
+
+// buildSyncPlan 对每条记录做三方比较
+func buildSyncPlan(state *syncState) *SyncPlan {
+	plan := &SyncPlan{MappingID: state.mapping.ID, Items: []SyncPlanItem{}}
+	for _, r := range state.records {
+		item, changed := classifySyncRecord(r)
+		if !changed {
+			plan.Summary.Unchanged++
+			continue
+		}
+		switch item.Change {
+		case SyncChangeAdded:
+			plan.Summary.Added++
+		case SyncChangeRemoved:
+			plan.Summary.Removed++
+		case SyncChangeModified:
+			plan.Summary.Modified++
+		case SyncChangeConflict:
+			plan.Summary.Conflicts++
+		}
+		plan.Items = append(plan.Items, item)
+	}
+
+	fingerprint, _ := json.Marshal(plan.Items)
+	sum := sha256.Sum256(fingerprint)
+	plan.PlanID = hex.EncodeToString(sum[:8])
+	return plan
+}
+
+// classifySyncRecord 判断记录的变化；两方一致时返回 false
+func classifySyncRecord(r *syncRecord) (SyncPlanItem, bool) {
+	if syncFieldsEqual(r.local, r.remote) {
+		return SyncPlanItem{}, false
+	}
+	item := SyncPlanItem{
+		Key:    r.key(),
+		Entity: r.spec.entity,
+		ID:     r.id,
+		Base:   r.base,
+		Local:  r.local,
+		Remote: r.remote,
+	}
+	localChanged := !syncFieldsEqual(r.local, r.base)
+	remoteChanged := !syncFieldsEqual(r.remote, r.base)
+
+	oneSide := func(side string, changed map[string]string) {
+		item.Side = side
+		switch {
+		case r.base == nil:
+			item.Change = SyncChangeAdded
+		case changed == nil:
+			item.Change = SyncChangeRemoved
+		default:
+			item.Change = SyncChangeModified
+			item.ChangedFields = changedSyncFields(r.base, changed)
+		}
+	}
+	switch {
+	case !remoteChanged:
+		oneSide(SyncSideLocal, r.local)
+	case !localChanged:
+		oneSide(SyncSideRemote, r.remote)
+	default:
+		item.Side = SyncSideBoth
+		item.Change = SyncChangeConflict
+		if r.base == nil || r.local == nil || r.remote == nil {
+			// 两方都新增但内容不同，或一方删除另一方修改
+			if r.local != nil && r.remote != nil {
+				item.ConflictFields = changedSyncFields(r.local, r.remote)
+			}
+			break
+		}
+		item.ChangedFields = mergeFieldNames(changedSyncFields(r.base, r.local), changedSyncFields(r.base, r.remote))
+		for _, f := range item.ChangedFields {
+			if r.local[f] != r.base[f] && r.remote[f] != r.base[f] && r.local[f] != r.remote[f] {
+				item.ConflictFields = append(item.ConflictFields, f)
+			}
+		}
+		if len(item.ConflictFields) == 0 {
+			item.Change = SyncChangeModified
+			item.Merged = mergeSyncFields(r.base, r.local, r.remote, "")
+		}
+	}
+	return item, true
+}
+
+// resolveSyncItem 确定记录的目标状态；status 为 skip 或 unresolved 时不处理该记录
+func resolveSyncItem(item *SyncPlanItem, policy, resolution string) (target map[string]string, status string) {
+	fieldMergeable := item.Base != nil && item.Local != nil && item.Remote != nil
+	switch {
+	case resolution == SyncResolveSkip:
+		return nil, SyncResolveSkip
+	case item.Change != SyncChangeConflict && resolution == "":
+		switch item.Side {
+		case SyncSideLocal:
+			return item.Local, ""
+		case SyncSideRemote:
+			return item.Remote, ""
+		default:
+			return item.Merged, ""
+		}
+	case item.Change == SyncChangeConflict && policy == SyncPolicyMerge && fieldMergeable && resolution != "":
+		// 字段级合并：只有两方都修改的字段按指定方取值
+		return mergeSyncFields(item.Base, item.Local, item.Remote, resolution), ""
+	case resolution == SyncResolveLocal, resolution == "" && policy == SyncPolicyLocalWins:
+		return item.Local, ""
+	case resolution == SyncResolveRemote, resolution == "" && policy == SyncPolicyRemoteWins:
+		return item.Remote, ""
+	default:
+		return nil, "unresolved"
+	}
+}
+
+// applyLocalSyncTargets 把本地数据写到目标状态，只修改映射的字段；删除按表映射的删除策略执行，
+// 端点节点缺失的路径不写入并从 targets 中移除，快照保持不变
+func (s *dataSyncService) applyLocalSyncTargets(ctx context.Context, state *syncState, targets map[*syncRecord]map[string]string, result *SyncApplyResult) error {
+	policy := syncDeletionPolicy(state.mapping)
+	removed := make(map[domain.NodeID]bool)
+	var deletes []func() error
+	for _, spec := range state.specs {
+		// 节点已写入，待删除的节点按不存在处理
+		var nodes map[domain.NodeID]bool
+		if spec.entity == "path" {
+			active, err := s.activeSyncNodes(ctx)
+			if err != nil {
+				return err
+			}
+			for id := range removed {
+				delete(active, id)
+			}
+			nodes = active
+		}
+
+		var group []func() error
+		for _, r := range state.records {
+			target, ok := targets[r]
+			if r.spec != spec || !ok || syncFieldsEqual(target, r.local) {
+				continue
+			}
+			var entity interface{}
+			var exists bool
+			if spec.entity == "node" {
+				entity, exists = state.nodes[r.id]
+			} else {
+				entity, exists = state.paths[r.id]
+			}
+			switch {
+			case target == nil:
+				if spec.entity == "node" {
+					removed[domain.NodeID(r.id)] = true
+				}
+				group = append(group, func() error {
+					_, err := s.deleteSyncedEntity(ctx, policy, entity)
+					return err
+				})
+				result.LocalDeleted++
+			default:
+				if nodes != nil {
+					if orphan := syncPathOrphan(spec, r.id, entity, target, nodes); orphan != nil {
+						result.Orphans = append(result.Orphans, *orphan)
+						delete(targets, r)
+						continue
+					}
+				}
+				if !exists {
+					entity = newSyncedEntity(spec.entity, r.id)
+				}
+				if err := s.saveSyncedEntity(ctx, spec, entity, target, exists); err != nil {
+					return err
+				}
+				result.countLocal(exists)
+			}
+		}
+		deletes = append(group, deletes...)
+	}
+	for _, del := range deletes {
+		if err := del(); err != nil {
+			return fmt.Errorf("删除本地数据失败: %w", err)
+		}
+	}
+	return nil
+}
+
+func (r *SyncApplyResult) countLocal(updated bool) {
+	if updated {
+		r.LocalUpdated++
+	} else {
+		r.LocalCreated++
+	}
+}
+
+// newSyncedEntity 外部新增的记录在本地创建的实体，未映射的字段取默认值
+func newSyncedEntity(entity, id string) interface{} {
+	if entity == "node" {
+		node := domain.NewNode(id, string(domain.NodeTypePoint))
+		node.ID = domain.NodeID(id)
+		return node
+	}
+	path := domain.NewPath(fmt.Sprintf("路径_%s", id), "", "")
+	path.ID = domain.PathID(id)
+	return path
+}
+
+// saveSyncedEntity 把列值写到本地实体（只修改映射的字段）并保存
+func (s *dataSyncService) saveSyncedEntity(ctx context.Context, spec *syncSpec, entity interface{}, fields map[string]string, exists bool) error {
+	if err := prepareSyncedEntity(spec, entity, fields, exists); err != nil {
+		return err
+	}
+	return s.writeSyncedEntity(ctx, entity, exists)
+}
+
+// prepareSyncedEntity 把列值写到本地实体，补齐默认值；已有的实体更新版本号
+func prepareSyncedEntity(spec *syncSpec, entity interface{}, fields map[string]string, exists bool) error {
+	// 软删除的记录在外部重新出现时恢复；状态列有映射时以外部值为准
+	switch e := entity.(type) {
+	case *domain.Node:
+		if e.Status == domain.NodeStatusDeleted {
+			e.Status = domain.NodeStatusActive
+		}
+	case *domain.Path:
+		if e.Status == domain.PathStatusDeleted {
+			e.Status = domain.PathStatusActive
+		}
+	}
+	if err := spec.apply(entity, fields); err != nil {
+		return err
+	}
+	switch e := entity.(type) {
+	case *domain.Node:
+		if e.Name == "" {
+			e.Name = string(e.ID)
+		}
+		if e.Type == "" {
+			e.Type = domain.NodeTypePoint
+		}
+		if exists {
+			e.Metadata.UpdatedAt = time.Now()
+			e.Metadata.Version++
+		}
+	case *domain.Path:
+		if e.Name == "" {
+			e.Name = fmt.Sprintf("路径_%s", e.ID)
+		}
+		if exists {
+			e.Metadata.UpdatedAt = time.Now()
+			e.Metadata.Version++
+		}
+	}
+	return nil
+}
+
+// writeSyncedEntity 保存 prepareSyncedEntity 处理过的实体
+func (s *dataSyncService) writeSyncedEntity(ctx context.Context, entity interface{}, exists bool) error {
+	switch e := entity.(type) {
+	case *domain.Node:
+		if !exists {
+			if err := s.nodeRepo.Create(ctx, e); err != nil {
+				return fmt.Errorf("创建节点 %s 失败: %w", e.ID, err)
+			}
+			return nil
+		}
+		if err := s.nodeRepo.Update(ctx, e); err != nil {
+			return fmt.Errorf("更新节点 %s 失败: %w", e.ID, err)
+		}
+	case *domain.Path:
+		if !exists {
+			if err := s.pathRepo.Create(ctx, e); err != nil {
+				return fmt.Errorf("创建路径 %s 失败: %w", e.ID, err)
+			}
+			return nil
+		}
+		if err := s.pathRepo.Update(ctx, e); err != nil {
+			return fmt.Errorf("更新路径 %s 失败: %w", e.ID, err)
+		}
+	}
+	return nil
+}
+
+// saveSyncTargets 快照更新为目标状态，跳过和未解决的记录保持原快照
+func (s *dataSyncService) saveSyncTargets(ctx context.Context, state *syncState, targets map[*syncRecord]map[string]string) error {
+	now := time.Now()
+	for _, spec := range state.specs {
+		var snapshots []*domain.SyncSnapshot
+		var deleted []string
+		for _, r := range state.records {
+			target, ok := targets[r]
+			if r.spec != spec || !ok {
+				continue
+			}
+			if target == nil {
+				if r.base != nil {
+					deleted = append(deleted, r.id)
+				}
+				continue
+			}
+			snapshots = append(snapshots, &domain.SyncSnapshot{
+				MappingID: state.mapping.ID, Entity: spec.entity, RecordID: r.id, Fields: target, SyncedAt: now,
+			})
+		}
+		if err := s.snapshotRepo.Save(ctx, state.mapping.ID, spec.entity, snapshots, deleted); err != nil {
+			return fmt.Errorf("保存同步快照失败: %w", err)
+		}
+	}
+	return nil
+}
+
+// recordSyncSnapshots 拉取或推送成功后把外部表当前的值记为基准
+func (s *dataSyncService) recordSyncSnapshots(ctx context.Context, mappingID string, spec *syncSpec, ids []string, deleted []string) error {
+	if len(ids) == 0 && len(deleted) == 0 {
+		return nil
+	}
+	_, remote, err := spec.readRows(ctx)
+	if err != nil {
+		return err
+	}
+	return s.saveSyncSnapshots(ctx, mappingID, spec, remote, ids, deleted)
+}
+
+// saveSyncSnapshots 以已读取的外部行记录快照，rows 中没有的ID不记录
+func (s *dataSyncService) saveSyncSnapshots(ctx context.Context, mappingID string, spec *syncSpec, remote map[string]map[string]interface{}, ids []string, deleted []string) error {
+	now := time.Now()
+	var snapshots []*domain.SyncSnapshot
+	for _, id := range ids {
+		if raw, ok := remote[id]; ok {
+			snapshots = append(snapshots, &domain.SyncSnapshot{
+				MappingID: mappingID, Entity: spec.entity, RecordID: id, Fields: spec.remoteFields(raw), SyncedAt: now,
+			})
+		}
+	}
+	if err := s.snapshotRepo.Save(ctx, mappingID, spec.entity, snapshots, deleted); err != nil {
+		return fmt.Errorf("保存同步快照失败: %w", err)
+	}
+	return nil
+}
+
+// normalizeSyncValue 统一值的字符串形式，数值列按数值格式化，NULL 视为空字符串
+func normalizeSyncValue(v interface{}, numeric bool) string {
+	if v == nil {
+		return ""
+	}
+	if b, ok := v.([]byte); ok {
+		v = string(b)
+	}
+	s := fmt.Sprint(v)
+	if numeric {
+		if f, err := strconv.ParseFloat(strings.TrimSpace(s), 64); err == nil {
+			return strconv.FormatFloat(f, 'f', -1, 64)
+		}
+	}
+	return s
+}
+
+// syncFieldsEqual 比较两方的值，nil 只与 nil 相等
+func syncFieldsEqual(a, b map[string]string) bool {
+	if (a == nil) != (b == nil) || len(a) != len(b) {
+		return false
+	}
+	for k, v := range a {
+		if w, ok := b[k]; !ok || w != v {
+			return false
+		}
+	}
+	return true
+}
+
+// changedSyncFields 返回值不同的列名
+func changedSyncFields(a, b map[string]string) []string {
+	var changed []string
+	for k, v := range a {
+		if w, ok := b[k]; !ok || w != v {
+			changed = append(changed, k)
+		}
+	}
+	for k := range b {
+		if _, ok := a[k]; !ok {
+			changed = append(changed, k)
+		}
+	}
+	sort.Strings(changed)
+	return changed
+}
+
+func mergeFieldNames(a, b []string) []string {
+	seen := make(map[string]bool, len(a)+len(b))
+	var merged []string
+	for _, f := range append(a, b...) {
+		if !seen[f] {
+			seen[f] = true
+			merged = append(merged, f)
+		}
+	}
+	sort.Strings(merged)
+	return merged
+}
+
+// mergeSyncFields 以基准为起点合并两方的修改，两方都修改的字段按 prefer 取值
+func mergeSyncFields(base, local, remote map[string]string, prefer string) map[string]string {
+	merged := make(map[string]string, len(base))
+	for k, v := range base {
+		merged[k] = v
+	}
+	for _, k := range mergeFieldNames(changedSyncFields(base, local), changedSyncFields(base, remote)) {
+		localChanged := local[k] != base[k]
+		remoteChanged := remote[k] != base[k]
+		switch {
+		case localChanged && remoteChanged && local[k] != remote[k]:
+			if prefer == SyncResolveRemote {
+				merged[k] = remote[k]
+			} else {
+				merged[k] = local[k]
+			}
+		case localChanged:
+			merged[k] = local[k]
+		default:
+			merged[k] = remote[k]
+		}
+	}
+	return merged
+}
@@ -0,0 +1,292 @@
+package services
+
+import (
+	"reflect"
+	"testing"
+
+	"robot-path-editor/internal/domain"
+)
+
+var testNodeSpec = &syncSpec{entity: "node"}
+
+// fields 由键值对构造一方的映射列值
+func fields(kv ...string) map[string]string {
+	m := make(map[string]string, len(kv)/2)
+	for i := 0; i+1 < len(kv); i += 2 {
+		m[kv[i]] = kv[i+1]
+	}
+	return m
+}
+
+func TestClassifySyncRecord(t *testing.T) {
+	base := fields("name", "dock", "x", "1", "y", "2")
+	tests := []struct {
+		name                string
+		base, local, remote map[string]string
+		wantChanged         bool
+		wantChange          string
+		wantSide            string
+		wantChangedFields   []string
+		wantConflictFields  []string
+		wantMerged          map[string]string
+	}{
+		{
+			name: "三方一致", base: base, local: base, remote: base,
+		},
+		{
+			name: "两方做了相同修改", base: base, local: fields("name", "dock", "x", "5", "y", "2"), remote: fields("name", "dock", "x", "5", "y", "2"),
+		},
+		{
+			name: "两方都已删除", base: base,
+		},
+		{
+			name: "本地修改", base: base, local: fields("name", "dock2", "x", "1", "y", "2"), remote: base,
+			wantChanged: true, wantChange: SyncChangeModified, wantSide: SyncSideLocal, wantChangedFields: []string{"name"},
+		},
+		{
+			name: "外部修改", base: base, local: base, remote: fields("name", "dock", "x", "3", "y", "4"),
+			wantChanged: true, wantChange: SyncChangeModified, wantSide: SyncSideRemote, wantChangedFields: []string{"x", "y"},
+		},
+		{
+			name: "本地新增", local: base,
+			wantChanged: true, wantChange: SyncChangeAdded, wantSide: SyncSideLocal,
+		},
+		{
+			name: "外部新增", remote: base,
+			wantChanged: true, wantChange: SyncChangeAdded, wantSide: SyncSideRemote,
+		},
+		{
+			name: "本地删除", base: base, remote: base,
+			wantChanged: true, wantChange: SyncChangeRemoved, wantSide: SyncSideLocal,
+		},
+		{
+			name: "外部删除", base: base, local: base,
+			wantChanged: true, wantChange: SyncChangeRemoved, wantSide: SyncSideRemote,
+		},
+		{
+			name: "两方修改不同字段时自动合并", base: base, local: fields("name", "dock2", "x", "1", "y", "2"), remote: fields("name", "dock", "x", "9", "y", "2"),
+			wantChanged: true, wantChange: SyncChangeModified, wantSide: SyncSideBoth, wantChangedFields: []string{"name", "x"},
+			wantMerged: fields("name", "dock2", "x", "9", "y", "2"),
+		},
+		{
+			name: "两方修改同一字段", base: base, local: fields("name", "a", "x", "1", "y", "7"), remote: fields("name", "b", "x", "1", "y", "7"),
+			wantChanged: true, wantChange: SyncChangeConflict, wantSide: SyncSideBoth, wantChangedFields: []string{"name", "y"}, wantConflictFields: []string{"name"},
+		},
+		{
+			name: "两方新增内容不同", local: fields("name", "a", "x", "1"), remote: fields("name", "b", "x", "1"),
+			wantChanged: true, wantChange: SyncChangeConflict, wantSide: SyncSideBoth, wantConflictFields: []string{"name"},
+		},
+		{
+			name: "本地删除外部修改", base: base, remote: fields("name", "dock", "x", "3", "y", "2"),
+			wantChanged: true, wantChange: SyncChangeConflict, wantSide: SyncSideBoth,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			item, changed := classifySyncRecord(&syncRecord{spec: testNodeSpec, id: "n1", base: tt.base, local: tt.local, remote: tt.remote})
+			if changed != tt.wantChanged {
+				t.Fatalf("changed = %v, want %v", changed, tt.wantChanged)
+			}
+			if !changed {
+				return
+			}
+			if item.Key != "node:n1" || item.Entity != "node" || item.ID != "n1" {
+				t.Errorf("Key/Entity/ID = %s/%s/%s", item.Key, item.Entity, item.ID)
+			}
+			if item.Change != tt.wantChange || item.Side != tt.wantSide {
+				t.Errorf("Change/Side = %s/%s, want %s/%s", item.Change, item.Side, tt.wantChange, tt.wantSide)
+			}
+			if !reflect.DeepEqual(item.ChangedFields, tt.wantChangedFields) {
+				t.Errorf("ChangedFields = %v, want %v", item.ChangedFields, tt.wantChangedFields)
+			}
+			if !reflect.DeepEqual(item.ConflictFields, tt.wantConflictFields) {
+				t.Errorf("ConflictFields = %v, want %v", item.ConflictFields, tt.wantConflictFields)
+			}
+			if !reflect.DeepEqual(item.Merged, tt.wantMerged) {
+				t.Errorf("Merged = %v, want %v", item.Merged, tt.wantMerged)
+			}
+		})
+	}
+}
+
+func TestBuildSyncPlan(t *testing.T) {
+	base := fields("name", "a")
+	state := &syncState{
+		mapping: &domain.TableMapping{ID: "m1"},
+		records: []*syncRecord{
+			{spec: testNodeSpec, id: "same", base: base, local: base, remote: base},
+			{spec: testNodeSpec, id: "added", remote: base},
+			{spec: testNodeSpec, id: "removed", base: base, local: base},
+			{spec: testNodeSpec, id: "modified", base: base, local: fields("name", "b"), remote: base},
+			{spec: &syncSpec{entity: "path"}, id: "conflict", base: base, local: fields("name", "b"), remote: fields("name", "c")},
+		},
+	}
+	plan := buildSyncPlan(state)
+	want := SyncPlanSummary{Added: 1, Removed: 1, Modified: 1, Conflicts: 1, Unchanged: 1}
+	if plan.Summary != want {
+		t.Errorf("Summary = %+v, want %+v", plan.Summary, want)
+	}
+	if plan.MappingID != "m1" || len(plan.Items) != 4 || plan.Items[3].Key != "path:conflict" {
+		t.Errorf("plan = %+v", plan)
+	}
+
+	// 计划ID由变化内容决定：数据不变时相同，任一方变化时不同
+	if again := buildSyncPlan(state); again.PlanID != plan.PlanID {
+		t.Errorf("PlanID 不稳定: %s != %s", again.PlanID, plan.PlanID)
+	}
+	state.records[1].remote = fields("name", "z")
+	if changed := buildSyncPlan(state); changed.PlanID == plan.PlanID {
+		t.Error("外部数据变化后 PlanID 未变化")
+	}
+}
+
+func TestResolveSyncItem(t *testing.T) {
+	base := fields("name", "a", "x", "1")
+	local := fields("name", "b", "x", "2")
+	remote := fields("name", "c", "x", "1")
+	conflict := SyncPlanItem{Change: SyncChangeConflict, Side: SyncSideBoth, Base: base, Local: local, Remote: remote}
+	added := SyncPlanItem{Change: SyncChangeConflict, Side: SyncSideBoth, Local: local, Remote: remote}
+	merged := fields("name", "a", "x", "2")
+
+	tests := []struct {
+		name       string
+		item       SyncPlanItem
+		policy     string
+		resolution string
+		wantTarget map[string]string
+		wantStatus string
+	}{
+		{"本地变化写到外部", SyncPlanItem{Change: SyncChangeModified, Side: SyncSideLocal, Local: local}, SyncPolicyManual, "", local, ""},
+		{"外部变化写到本地", SyncPlanItem{Change: SyncChangeModified, Side: SyncSideRemote, Remote: remote}, SyncPolicyManual, "", remote, ""},
+		{"外部删除", SyncPlanItem{Change: SyncChangeRemoved, Side: SyncSideRemote, Base: base, Local: base}, SyncPolicyManual, "", nil, ""},
+		{"自动合并", SyncPlanItem{Change: SyncChangeModified, Side: SyncSideBoth, Merged: merged}, SyncPolicyManual, "", merged, ""},
+		{"非冲突也可指定取本地", SyncPlanItem{Change: SyncChangeModified, Side: SyncSideRemote, Local: local, Remote: remote}, SyncPolicyManual, SyncResolveLocal, local, ""},
+		{"跳过", conflict, SyncPolicyLocalWins, SyncResolveSkip, nil, SyncResolveSkip},
+		{"手动策略未指定", conflict, SyncPolicyManual, "", nil, "unresolved"},
+		{"手动策略取外部", conflict, SyncPolicyManual, SyncResolveRemote, remote, ""},
+		{"本地优先", conflict, SyncPolicyLocalWins, "", local, ""},
+		{"外部优先", conflict, SyncPolicyRemoteWins, "", remote, ""},
+		{"指定方式优先于策略", conflict, SyncPolicyLocalWins, SyncResolveRemote, remote, ""},
+		{"合并策略未指定", conflict, SyncPolicyMerge, "", nil, "unresolved"},
+		{"合并策略冲突字段取本地", conflict, SyncPolicyMerge, SyncResolveLocal, fields("name", "b", "x", "2"), ""},
+		{"合并策略冲突字段取外部", conflict, SyncPolicyMerge, SyncResolveRemote, fields("name", "c", "x", "2"), ""},
+		{"两方新增时合并策略整条取值", added, SyncPolicyMerge, SyncResolveRemote, remote, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			item := tt.item
+			target, status := resolveSyncItem(&item, tt.policy, tt.resolution)
+			if status != tt.wantStatus {
+				t.Errorf("status = %q, want %q", status, tt.wantStatus)
+			}
+			if !reflect.DeepEqual(target, tt.wantTarget) {
+				t.Errorf("target = %v, want %v", target, tt.wantTarget)
+			}
+		})
+	}
+}
+
+func TestMergeSyncFields(t *testing.T) {
+	base := fields("name", "a", "x", "1", "y", "1")
+	tests := []struct {
+		name          string
+		local, remote map[string]string
+		prefer        string
+		want          map[string]string
+	}{
+		{"各改各的", fields("name", "b", "x", "1", "y", "1"), fields("name", "a", "x", "2", "y", "1"), "", fields("name", "b", "x", "2", "y", "1")},
+		{"同一字段相同修改", fields("name", "b", "x", "1", "y", "1"), fields("name", "b", "x", "1", "y", "1"), SyncResolveRemote, fields("name", "b", "x", "1", "y", "1")},
+		{"冲突默认取本地", fields("name", "b", "x", "1", "y", "1"), fields("name", "c", "x", "1", "y", "3"), "", fields("name", "b", "x", "1", "y", "3")},
+		{"冲突取外部", fields("name", "b", "x", "1", "y", "1"), fields("name", "c", "x", "1", "y", "3"), SyncResolveRemote, fields("name", "c", "x", "1", "y", "3")},
+		{"新增列", fields("name", "a", "x", "1", "y", "1", "z", "5"), base, "", fields("name", "a", "x", "1", "y", "1", "z", "5")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := mergeSyncFields(base, tt.local, tt.remote, tt.prefer)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("mergeSyncFields() = %v, want %v", got, tt.want)
+			}
+			if base["name"] != "a" {
+				t.Fatal("mergeSyncFields() 修改了基准")
+			}
+		})
+	}
+}
+
+func TestNormalizeSyncValue(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   interface{}
+		numeric bool
+		want    string
+	}{
+		{"NULL", nil, false, ""},
+		{"字节", []byte("dock"), false, "dock"},
+		{"数值列的小数格式统一", "1.500000", true, "1.5"},
+		{"数值列的整数", int64(3), true, "3"},
+		{"数值列的浮点", 2.25, true, "2.25"},
+		{"数值列的字节", []byte(" 10.0 "), true, "10"},
+		{"数值列的非数值原样保留", "n/a", true, "n/a"},
+		{"文本列不转换", "1.50", false, "1.50"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := normalizeSyncValue(tt.value, tt.numeric); got != tt.want {
+				t.Errorf("normalizeSyncValue(%v) = %q, want %q", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSyncRecordTracked(t *testing.T) {
+	base := fields("name", "a")
+	tests := []struct {
+		name   string
+		record *syncRecord
+		want   bool
+	}{
+		{"有快照", &syncRecord{spec: testNodeSpec, id: "n1", base: base, local: base}, true},
+		{"外部存在", &syncRecord{spec: testNodeSpec, id: "n2", local: base, remote: base}, true},
+		{"外部已删除", &syncRecord{spec: testNodeSpec, id: "n3", base: base}, true},
+		{"只有本地", &syncRecord{spec: testNodeSpec, id: "n4", local: base}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.record.tracked(); got != tt.want {
+				t.Errorf("tracked() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPushAction(t *testing.T) {
+	base := fields("name", "a")
+	edited := fields("name", "b")
+	tests := []struct {
+		name   string
+		record *syncRecord
+		opts   PushOptions
+		want   string
+	}{
+		{"两方一致", &syncRecord{base: base, local: base, remote: base}, PushOptions{}, pushSkipUnchanged},
+		{"本地修改", &syncRecord{base: base, local: edited, remote: base}, PushOptions{}, PushOpUpdate},
+		{"外部修改不覆盖", &syncRecord{base: base, local: base, remote: edited}, PushOptions{}, pushSkipRemote},
+		{"外部删除不重新插入", &syncRecord{base: base, local: base}, PushOptions{IncludeUntracked: true}, pushSkipRemote},
+		{"两方修改为冲突", &syncRecord{base: base, local: edited, remote: fields("name", "c")}, PushOptions{}, pushSkipConflict},
+		{"两方修改不同字段也不写入", &syncRecord{base: fields("name", "a", "x", "1"), local: fields("name", "b", "x", "1"), remote: fields("name", "a", "x", "2")}, PushOptions{}, pushSkipConflict},
+		{"无快照且外部内容不同", &syncRecord{local: edited, remote: base}, PushOptions{IncludeUntracked: true}, pushSkipConflict},
+		{"未同步的本地记录默认不插入", &syncRecord{local: base}, PushOptions{}, pushSkipUntracked},
+		{"未同步的本地记录按选项插入", &syncRecord{local: base}, PushOptions{IncludeUntracked: true}, PushOpInsert},
+		{"本地删除默认保留", &syncRecord{base: base, remote: base}, PushOptions{}, pushSkipKeep},
+		{"本地删除按选项删除", &syncRecord{base: base, remote: base}, PushOptions{Delete: true}, PushOpDelete},
+		{"外部新增的行忽略", &syncRecord{remote: base}, PushOptions{Delete: true}, pushSkipIgnore},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.record.spec, tt.record.id = testNodeSpec, "n1"
+			if got := pushAction(tt.record, tt.opts); got != tt.want {
+				t.Errorf("pushAction() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,378 @@
+// Package services 推送同步：把编辑器中的节点和路径写回外部映射表
+//
+// - 以上次同步的快照为基准三方比较，只写入本地一方的变化，不覆盖外部在上次同步后的修改
+// - 本地修改的行 UPDATE，只更新变化的列；本地删除的行仅在 Delete 为 true 时 DELETE
+// - 从未同步到该映射的本地记录仅在 IncludeUntracked 为 true 时 INSERT，否则列在 Untracked 中
+// - 两方都修改过的记录列在 Conflicts 中，外部已修改或删除的记录列在 RemoteChanged 中，都不写入，需用同步计划处理
+// - 全部语句在外部数据库的一个事务中执行，任一失败整体回滚
+// - DryRun 只比较不写入，返回将执行的 SQL（参数已内联，仅供查看）和每行变化的列
+// - 节点和路径映射到同一张表时，两者的ID都视为本地已有，不会互相删除
+// - 软删除的本地记录外部没有时不插入，外部有时照常更新，也不会被当作本地已不存在而删除
+package services
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+
+	"robot-path-editor/internal/domain"
+)
+
+// PushOptions 推送选项，由请求体传入
+type PushOptions struct {
+	DryRun           bool `json:"dry_run"`
+	Delete           bool `json:"delete"`            // 删除上次同步后本地已删除的行
+	IncludeUntracked bool `json:"include_untracked"` // 插入从未同步到该映射的本地记录
+}
+
+// 推送语句类型
+const (
+	PushOpInsert = "insert"
+	PushOpUpdate = "update"
+	PushOpDelete = "delete"
+)
+
+// 推送时不写入的原因
+const (
+	pushSkipUnchanged = "unchanged"
+	pushSkipConflict  = "conflict"
+	pushSkipRemote    = "remote"
+	pushSkipUntracked = "untracked"
+	pushSkipKeep      = "keep"
+	pushSkipIgnore    = "ignore"
+)
+
+// PushResult 推送结果
+type PushResult struct {
+	DryRun        bool            `json:"dry_run"`
+	Table         string          `json:"table"`
+	Inserted      int             `json:"inserted"`
+	Updated       int             `json:"updated"`
+	Deleted       int             `json:"deleted"`
+	Unchanged     int             `json:"unchanged"`
+	Statements    []PushStatement `json:"statements"`
+	Conflicts     []string        `json:"conflicts"`      // 两方都修改过的记录
+	RemoteChanged []string        `json:"remote_changed"` // 上次同步后外部已修改或删除的记录
+	Untracked     []string        `json:"untracked"`      // 从未同步到该映射、未插入的本地记录
+}
+
+// PushStatement 一条写入语句及对应的行变化
+type PushStatement struct {
+	Op      string                `json:"op"`
+	Entity  string                `json:"entity"` // node 或 path
+	ID      string                `json:"id"`
+	SQL     string                `json:"sql"` // 参数内联后的 SQL，仅用于展示
+	Changes map[string]PushChange `json:"changes,omitempty"`
+
+	query string
+	args  []interface{}
+}
+
+// PushChange 单列的变化
+type PushChange struct {
+	Old interface{} `json:"old"`
+	New interface{} `json:"new"`
+}
+
+// pushColumn 外部表的一列及本地值
+type pushColumn struct {
+	name    string
+	value   interface{}
+	numeric bool
+	json    bool
+}
+
+// pushRow 一个本地实体对应的外部表行，第一列为ID列
+type pushRow struct {
+	entity  string
+	columns []pushColumn
+}
+
+func (r pushRow) id() string { return fmt.Sprint(r.columns[0].value) }
+
+// PushToExternal 把本地节点和路径的变化写回外部映射表
+func (s *dataSyncService) PushToExternal(ctx context.Context, mappingID string, opts PushOptions) (*PushResult, error) {
+	state, err := s.loadSyncState(ctx, mappingID, true)
+	if err != nil {
+		return nil, err
+	}
+	defer state.source.Close()
+
+	result := &PushResult{
+		DryRun:        opts.DryRun,
+		Table:         state.source.tables(),
+		Statements:    []PushStatement{},
+		Conflicts:     []string{},
+		RemoteChanged: []string{},
+		Untracked:     []string{},
+	}
+	// 写入或与外部一致的记录更新快照，删除的记录移除快照
+	synced := make(map[*syncSpec][]string)
+	removed := make(map[*syncSpec][]string)
+	// 删除语句放在最后且路径先于节点，避免外部表的外键引用先被删掉
+	var deletes [][]PushStatement
+	for _, spec := range state.specs {
+		var group []PushStatement
+		for _, r := range state.records {
+			if r.spec != spec {
+				continue
+			}
+			action := pushAction(r, opts)
+			switch action {
+			case PushOpInsert:
+				result.Statements = append(result.Statements, buildPushInsert(spec.table, spec.row(r.id, r.local)))
+				result.Inserted++
+			case PushOpUpdate:
+				current := make(map[string]interface{}, len(r.remote))
+				for k, v := range r.remote {
+					current[k] = v
+				}
+				if stmt, changed := buildPushUpdate(spec.table, spec.row(r.id, r.local), current); changed {
+					result.Statements = append(result.Statements, stmt)
+					result.Updated++
+				} else {
+					result.Unchanged++
+				}
+			case PushOpDelete:
+				if state.sharedLocal(spec, r.id) {
+					continue
+				}
+				group = append(group, buildPushDelete(spec.table, spec.entity, spec.id.name, r.id))
+				result.Deleted++
+				removed[spec] = append(removed[spec], r.id)
+				continue
+			case pushSkipUnchanged:
+				if r.remote != nil {
+					result.Unchanged++
+				}
+			case pushSkipConflict:
+				result.Conflicts = append(result.Conflicts, r.key())
+			case pushSkipRemote:
+				result.RemoteChanged = append(result.RemoteChanged, r.key())
+			case pushSkipUntracked:
+				result.Untracked = append(result.Untracked, r.key())
+			}
+			switch {
+			case action == pushSkipUnchanged && r.remote == nil:
+				// 两方都已删除
+				removed[spec] = append(removed[spec], r.id)
+			case action == PushOpInsert || action == PushOpUpdate || action == pushSkipUnchanged:
+				synced[spec] = append(synced[spec], r.id)
+			}
+		}
+		deletes = append(deletes, group)
+	}
+	for i := len(deletes) - 1; i >= 0; i-- {
+		result.Statements = append(result.Statements, deletes[i]...)
+	}
+
+	if opts.DryRun {
+		return result, nil
+	}
+
+	if len(result.Statements) > 0 {
+		tx, err := state.source.db.BeginTx(ctx, nil)
+		if err != nil {
+			return nil, fmt.Errorf("开始外部数据库事务失败: %w", err)
+		}
+		for _, stmt := range result.Statements {
+			if _, err := tx.ExecContext(ctx, stmt.query, stmt.args...); err != nil {
+				tx.Rollback()
+				return nil, fmt.Errorf("执行 %s %s 失败，已回滚: %w", stmt.Op, stmt.ID, err)
+			}
+		}
+		if err := tx.Commit(); err != nil {
+			return nil, fmt.Errorf("提交外部数据库事务失败: %w", err)
+		}
+	}
+
+	// 推送后这些记录外部表与本地一致，记为下次三方比较的基准；冲突和未写入的记录保持原快照
+	for _, spec := range state.specs {
+		if err := s.recordSyncSnapshots(ctx, mappingID, spec, synced[spec], removed[spec]); err != nil {
+			return nil, fmt.Errorf("推送已完成，但%w", err)
+		}
+	}
+	return result, nil
+}
+
+// pushAction 按三方比较决定推送对一条记录执行的语句，不写入时返回原因
+func pushAction(r *syncRecord, opts PushOptions) string {
+	item, changed := classifySyncRecord(r)
+	switch {
+	case !changed:
+		return pushSkipUnchanged
+	case item.Side == SyncSideBoth:
+		return pushSkipConflict
+	case item.Side == SyncSideRemote:
+		// 外部新增的行不属于本地数据，不列出
+		if r.base == nil {
+			return pushSkipIgnore
+		}
+		return pushSkipRemote
+	}
+	switch item.Change {
+	case SyncChangeAdded:
+		if !opts.IncludeUntracked {
+			return pushSkipUntracked
+		}
+		return PushOpInsert
+	case SyncChangeRemoved:
+		if !opts.Delete {
+			return pushSkipKeep
+		}
+		return PushOpDelete
+	default:
+		return PushOpUpdate
+	}
+}
+
+// sharedLocal 节点和路径映射到同一张表时，ID在另一实体中本地存在
+func (state *syncState) sharedLocal(spec *syncSpec, id string) bool {
+	for _, other := range state.specs {
+		if other == spec || other.tableName != spec.tableName {
+			continue
+		}
+		if other.entity == "node" && state.nodes[id] != nil || other.entity == "path" && state.paths[id] != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// readRows 读取外部表中映射的列（按筛选条件和排序），返回按查询顺序的ID和按ID索引的行；行以映射中的列名为键
+func (sp *syncSpec) readRows(ctx context.Context) ([]string, map[string]map[string]interface{}, error) {
+	return sp.queryRows(ctx, sp.columnNames(), sp.filter, sp.order)
+}
+
+// queryRows 按给定的列、筛选和排序查询，names 的第一列为ID列，ID为 NULL 的行忽略，ID重复时保留最后一行
+func (sp *syncSpec) queryRows(ctx context.Context, names []string, filter []domain.TableCondition, order []domain.TableOrder) ([]string, map[string]map[string]interface{}, error) {
+	query, err := sp.table.selectQuery(names, filter, order, names[0])
+	if err != nil {
+		return nil, nil, err
+	}
+	rows, err := sp.table.db.QueryContext(ctx, query.query.String(), query.args...)
+	if err != nil {
+		return nil, nil, fmt.Errorf("查询外部数据库失败: %w", err)
+	}
+	defer rows.Close()
+
+	var ids []string
+	existing := make(map[string]map[string]interface{})
+	for rows.Next() {
+		values := make([]interface{}, len(names))
+		ptrs := make([]interface{}, len(names))
+		for i := range values {
+			ptrs[i] = &values[i]
+		}
+		if err := rows.Scan(ptrs...); err != nil {
+			return nil, nil, fmt.Errorf("读取外部数据失败: %w", err)
+		}
+		if values[0] == nil {
+			continue
+		}
+		row := make(map[string]interface{}, len(names))
+		for i, name := range names {
+			if b, ok := values[i].([]byte); ok {
+				values[i] = string(b)
+			}
+			row[name] = values[i]
+		}
+		id := fmt.Sprint(values[0])
+		if _, dup := existing[id]; !dup {
+			ids = append(ids, id)
+		}
+		existing[id] = row
+	}
+	return ids, existing, rows.Err()
+}
+
+// buildPushInsert 生成 INSERT 语句
+func buildPushInsert(table *externalTable, row pushRow) PushStatement {
+	b := newSQLBuilder(table.dialect).sql("INSERT INTO ")
+	table.tableRef(b).sql(" (")
+	changes := make(map[string]PushChange, len(row.columns))
+	for i, c := range row.columns {
+		if i > 0 {
+			b.sql(", ")
+		}
+		table.columnRef(b, c.name)
+		changes[c.name] = PushChange{New: c.value}
+	}
+	b.sql(") VALUES (")
+	for i, c := range row.columns {
+		if i > 0 {
+			b.sql(", ")
+		}
+		b.arg(c.value)
+	}
+	b.sql(")")
+	return newPushStatement(PushOpInsert, row.entity, row.id(), b, changes)
+}
+
+// buildPushUpdate 生成只包含变化列的 UPDATE 语句，没有变化时返回 false
+func buildPushUpdate(table *externalTable, row pushRow, current map[string]interface{}) (PushStatement, bool) {
+	b := newSQLBuilder(table.dialect).sql("UPDATE ")
+	table.tableRef(b).sql(" SET ")
+	changes := make(map[string]PushChange)
+	for _, c := range row.columns[1:] {
+		old := current[c.name]
+		if pushValueEqual(old, c.value, c) {
+			continue
+		}
+		if len(changes) > 0 {
+			b.sql(", ")
+		}
+		table.columnRef(b, c.name).sql(" = ").arg(c.value)
+		changes[c.name] = PushChange{Old: old, New: c.value}
+	}
+	if len(changes) == 0 {
+		return PushStatement{}, false
+	}
+	idColumn := row.columns[0]
+	table.columnRef(b.sql(" WHERE "), idColumn.name).sql(" = ").arg(idColumn.value)
+	return newPushStatement(PushOpUpdate, row.entity, row.id(), b, changes), true
+}
+
+// buildPushDelete 生成 DELETE 语句
+func buildPushDelete(table *externalTable, entity, idColumn, id string) PushStatement {
+	b := newSQLBuilder(table.dialect).sql("DELETE FROM ")
+	table.tableRef(b).sql(" WHERE ")
+	table.columnRef(b, idColumn).sql(" = ").arg(id)
+	return newPushStatement(PushOpDelete, entity, id, b, nil)
+}
+
+func newPushStatement(op, entity, id string, b *sqlBuilder, changes map[string]PushChange) PushStatement {
+	return PushStatement{
+		Op:      op,
+		Entity:  entity,
+		ID:      id,
+		SQL:     b.display.String(),
+		Changes: changes,
+		query:   b.query.String(),
+		args:    b.args,
+	}
+}
+
+// pushValueEqual 比较外部值和本地值；数值列按数值比较（NULL 视为 0），JSON 列按规范化文本比较，其余按字符串比较
+func pushValueEqual(external, local interface{}, c pushColumn) bool {
+	if c.numeric {
+		a, err1 := strconv.ParseFloat(fmt.Sprint(nullAsZero(external)), 64)
+		b, err2 := strconv.ParseFloat(fmt.Sprint(nullAsZero(local)), 64)
+		return err1 == nil && err2 == nil && math.Abs(a-b) <= 1e-9*math.Max(1, math.Abs(b))
+	}
+	if external == nil || local == nil {
+		return external == nil && local == nil
+	}
+	if c.json {
+		return canonicalJSON(fmt.Sprint(external)) == canonicalJSON(fmt.Sprint(local))
+	}
+	return fmt.Sprint(external) == fmt.Sprint(local)
+}
+
+func nullAsZero(v interface{}) interface{} {
+	if v == nil {
+		return 0
+	}
+	return v
+}
@@ -56,33 +56,48 @@ type UpdateConnectionRequest struct {
 // CreateTableMappingRequest 创建表映射请求
 type CreateTableMappingRequest struct {
 	ConnectionID string                   `json:"connection_id" binding:"required"`
-	TableName    string                   `json:"table_name" binding:"required"`
+	SchemaName   string                   `json:"schema_name,omitempty"`
+	TableName    string                   `json:"table_name"` // 节点和路径映射都单独指定表时可为空
 	NodeMapping  *domain.NodeTableMapping `json:"node_mapping,omitempty"`
 	PathMapping  *domain.PathTableMapping `json:"path_mapping,omitempty"`
+	Filter       []domain.TableCondition  `json:"filter,omitempty"`
+	OrderBy      []domain.TableOrder      `json:"order_by,omitempty"`
+	// 外部删除和路径端点缺失的处理策略，为空时分别为 soft 和 quarantine
+	DeletionPolicy string `json:"deletion_policy,omitempty"`
+	OrphanPolicy   string `json:"orphan_policy,omitempty"`
 }
 
 // UpdateTableMappingRequest 更新表映射请求
 type UpdateTableMappingRequest struct {
 	ID          string                   `json:"id" binding:"required"`
+	SchemaName  *string                  `json:"schema_name,omitempty"`
 	TableName   *string                  `json:"table_name,omitempty"`
 	NodeMapping *domain.NodeTableMapping `json:"node_mapping,omitempty"`
 	PathMapping *domain.PathTableMapping `json:"path_mapping,omitempty"`
+	Filter      []domain.TableCondition  `json:"filter,omitempty"`
+	OrderBy     []domain.TableOrder      `json:"order_by,omitempty"`
+	// 外部删除和路径端点缺失的处理策略
+	DeletionPolicy *string `json:"deletion_policy,omitempty"`
+	OrphanPolicy   *string `json:"orphan_policy,omitempty"`
 }
 
 // databaseService 数据库服务实现
 type databaseService struct {
 	dbConnRepo       repositories.DatabaseConnectionRepository
 	tableMappingRepo repositories.TableMappingRepository
+	connector        *ExternalConnector
 }
 
 // NewDatabaseService 创建新的数据库服务实例
 func NewDatabaseService(
 	dbConnRepo repositories.DatabaseConnectionRepository,
 	tableMappingRepo repositories.TableMappingRepository,
+	connector *ExternalConnector,
 ) DatabaseService {
 	return &databaseService{
 		dbConnRepo:       dbConnRepo,
 		tableMappingRepo: tableMappingRepo,
+		connector:        connector,
 	}
 }
 
@@ -100,6 +115,9 @@ func (s *databaseService) CreateConnection(ctx context.Context, req CreateConnec
 		Password:   req.Password,
 		Properties: req.Properties,
 	}
+	if err := s.connector.check(conn, true); err != nil {
+		return nil, err
+	}
 
 	// 保存到数据库
 	err := s.dbConnRepo.Create(ctx, conn)
@@ -144,9 +162,17 @@ func (s *databaseService) UpdateConnection(ctx context.Context, req UpdateConnec
 	}
 	if req.Password != nil {
 		conn.Password = *req.Password
+		conn.SecretError = ""
+	} else if conn.SecretError != "" {
+		// 无法解密的密码已清空，只改其他字段会把空密码保存下来
+		return nil, fmt.Errorf("连接的密码无法解密（%s），请在更新时重新填写密码", conn.SecretError)
 	}
 	if req.Properties != nil {
-		conn.Properties = req.Properties
+		conn.Properties = mergeConnectionProperties(conn.Properties, req.Properties)
+	}
+	// 未提交属性时保留原属性，其中不再支持的旧属性不影响更新其他字段
+	if err := s.connector.check(conn, req.Properties != nil); err != nil {
+		return nil, err
 	}
 
 	// 保存更新
@@ -158,6 +184,21 @@ func (s *databaseService) UpdateConnection(ctx context.Context, req UpdateConnec
 	return conn, nil
 }
 
+// mergeConnectionProperties 用提交的属性替换原属性；敏感属性提交掩码时保留原值，
+// 这样客户端可以把读到的属性原样提交
+func mergeConnectionProperties(old, props map[string]string) map[string]string {
+	merged := make(map[string]string, len(props))
+	for k, v := range props {
+		if v == domain.RedactedSecret && domain.IsSecretConnectionProperty(k) {
+			if prev, ok := old[k]; ok {
+				v = prev
+			}
+		}
+		merged[k] = v
+	}
+	return merged
+}
+
 // DeleteConnection 删除数据库连接
 func (s *databaseService) DeleteConnection(ctx context.Context, id string) error {
 	return s.dbConnRepo.Delete(ctx, id)
@@ -185,9 +226,18 @@ func (s *databaseService) CreateTableMapping(ctx context.Context, req CreateTabl
 	mapping := &domain.TableMapping{
 		ID:           generateID(),
 		ConnectionID: req.ConnectionID,
+		SchemaName:   req.SchemaName,
 		TableName:    req.TableName,
 		NodeMapping:  req.NodeMapping,
 		PathMapping:  req.PathMapping,
+		Filter:       req.Filter,
+		OrderBy:      req.OrderBy,
+
+		DeletionPolicy: req.DeletionPolicy,
+		OrphanPolicy:   req.OrphanPolicy,
+	}
+	if err := checkMappingQuery(mapping); err != nil {
+		return nil, err
 	}
 
 	// 保存到数据库
@@ -213,15 +263,33 @@ func (s *databaseService) UpdateTableMapping(ctx context.Context, req UpdateTabl
 	}
 
 	// 更新非空字段
+	if req.SchemaName != nil {
+		mapping.SchemaName = *req.SchemaName
+	}
 	if req.TableName != nil {
 		mapping.TableName = *req.TableName
 	}
+	if req.Filter != nil {
+		mapping.Filter = req.Filter
+	}
+	if req.OrderBy != nil {
+		mapping.OrderBy = req.OrderBy
+	}
+	if req.DeletionPolicy != nil {
+		mapping.DeletionPolicy = *req.DeletionPolicy
+	}
+	if req.OrphanPolicy != nil {
+		mapping.OrphanPolicy = *req.OrphanPolicy
+	}
 	if req.NodeMapping != nil {
 		mapping.NodeMapping = req.NodeMapping
 	}
 	if req.PathMapping != nil {
 		mapping.PathMapping = req.PathMapping
 	}
+	if err := checkMappingQuery(mapping); err != nil {
+		return nil, err
+	}
 
 	// 保存更新
 	err = s.tableMappingRepo.Update(ctx, mapping)
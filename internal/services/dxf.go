@@ -0,0 +1,242 @@
+// Package services DXF 组码读写
+//
+// - 读取 ASCII DXF（R12 至 R2018），只解析 HEADER 的单位和 ENTITIES 段
+// - POLYLINE 后续的 VERTEX、INSERT 后续的 ATTRIB 归并到所属实体
+// - 写出 R12（AC1009）格式，无需句柄和 OBJECTS 段，绝大多数 CAD 软件都能打开
+package services
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"robot-path-editor/internal/domain"
+)
+
+// dxfGroup 组码和值
+type dxfGroup struct {
+	code  int
+	value string
+}
+
+// dxfEntity 图形实体
+type dxfEntity struct {
+	kind     string
+	groups   []dxfGroup
+	vertices []*dxfEntity // POLYLINE 的 VERTEX
+	attribs  []*dxfEntity // INSERT 的 ATTRIB
+}
+
+// dxfDrawing 解析结果
+type dxfDrawing struct {
+	insUnits int // $INSUNITS，0 表示未指定
+	entities []*dxfEntity
+}
+
+// parseDXF 解析 ASCII DXF
+func parseDXF(data []byte) (*dxfDrawing, error) {
+	if bytes.HasPrefix(data, []byte("AutoCAD Binary DXF")) {
+		return nil, fmt.Errorf("不支持二进制DXF，请另存为ASCII格式")
+	}
+
+	groups, err := readDXFGroups(data)
+	if err != nil {
+		return nil, err
+	}
+
+	drawing := &dxfDrawing{}
+	section := ""
+	var current *dxfEntity
+	var owner *dxfEntity // 正在收集 VERTEX/ATTRIB 的实体
+
+	for i := 0; i < len(groups); i++ {
+		g := groups[i]
+
+		if g.code != 0 {
+			switch {
+			case current != nil:
+				current.groups = append(current.groups, g)
+			case section == "" && g.code == 2 && i > 0 && groups[i-1].value == "SECTION":
+				section = g.value
+			case section == "HEADER" && g.code == 9 && g.value == "$INSUNITS" && i+1 < len(groups):
+				drawing.insUnits, _ = strconv.Atoi(groups[i+1].value)
+			}
+			continue
+		}
+
+		current = nil
+		switch g.value {
+		case "SECTION":
+			section = ""
+			continue
+		case "ENDSEC":
+			section = "ENDSEC"
+			owner = nil
+			continue
+		case "EOF":
+			return drawing, nil
+		}
+		if section != "ENTITIES" {
+			continue
+		}
+
+		entity := &dxfEntity{kind: g.value}
+		switch {
+		case g.value == "SEQEND":
+			owner = nil
+		case g.value == "VERTEX" && owner != nil && owner.kind == "POLYLINE":
+			owner.vertices = append(owner.vertices, entity)
+			current = entity
+		case g.value == "ATTRIB" && owner != nil && owner.kind == "INSERT":
+			owner.attribs = append(owner.attribs, entity)
+			current = entity
+		default:
+			owner = nil
+			drawing.entities = append(drawing.entities, entity)
+			current = entity
+			if g.value == "POLYLINE" || g.value == "INSERT" {
+				owner = entity
+			}
+		}
+	}
+
+	return drawing, nil
+}
+
+// readDXFGroups 按行读取组码/值对
+func readDXFGroups(data []byte) ([]dxfGroup, error) {
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+
+	var groups []dxfGroup
+	line := 0
+	for scanner.Scan() {
+		line++
+		codeText := strings.TrimSpace(scanner.Text())
+		if codeText == "" {
+			continue // 文件末尾的空行
+		}
+		code, err := strconv.Atoi(codeText)
+		if err != nil {
+			return nil, fmt.Errorf("DXF第%d行组码无效: %q", line, codeText)
+		}
+		if !scanner.Scan() {
+			return nil, fmt.Errorf("DXF第%d行缺少组码%d的值", line, code)
+		}
+		line++
+		groups = append(groups, dxfGroup{code: code, value: strings.TrimRight(scanner.Text(), "\r")})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("读取DXF失败: %w", err)
+	}
+	return groups, nil
+}
+
+// str 返回组码的第一个值
+func (e *dxfEntity) str(code int) string {
+	for _, g := range e.groups {
+		if g.code == code {
+			return strings.TrimSpace(g.value)
+		}
+	}
+	return ""
+}
+
+// float 返回组码的第一个数值
+func (e *dxfEntity) float(code int) float64 {
+	v, _ := strconv.ParseFloat(e.str(code), 64)
+	return v
+}
+
+// point 读取以 code 为X组码的点（Y、Z 组码分别加10、20）
+func (e *dxfEntity) point(code int) domain.Position {
+	return domain.Position{X: e.float(code), Y: e.float(code + 10), Z: e.float(code + 20)}
+}
+
+// points 按出现顺序读取以 code 为X组码的所有点
+func (e *dxfEntity) points(code int) []domain.Position {
+	var points []domain.Position
+	for _, g := range e.groups {
+		v, _ := strconv.ParseFloat(strings.TrimSpace(g.value), 64)
+		switch g.code {
+		case code:
+			points = append(points, domain.Position{X: v})
+		case code + 10:
+			if n := len(points); n > 0 {
+				points[n-1].Y = v
+			}
+		case code + 20:
+			if n := len(points); n > 0 {
+				points[n-1].Z = v
+			}
+		}
+	}
+	return points
+}
+
+// floats 按出现顺序读取组码的所有数值
+func (e *dxfEntity) floats(code int) []float64 {
+	var values []float64
+	for _, g := range e.groups {
+		if g.code == code {
+			v, _ := strconv.ParseFloat(strings.TrimSpace(g.value), 64)
+			values = append(values, v)
+		}
+	}
+	return values
+}
+
+// layer 实体所在图层
+func (e *dxfEntity) layer() string {
+	if layer := e.str(8); layer != "" {
+		return layer
+	}
+	return "0"
+}
+
+// mirrored 拉伸方向为 -Z 时，实体坐标系（OCS）相对世界坐标系沿X镜像
+func (e *dxfEntity) mirrored() bool {
+	return e.float(230) < 0
+}
+
+// === 写出 ===
+
+// dxfWriter R12 DXF 写出器
+type dxfWriter struct {
+	buf bytes.Buffer
+}
+
+func (w *dxfWriter) group(code int, value string) {
+	fmt.Fprintf(&w.buf, "%3d\n%s\n", code, value)
+}
+
+func (w *dxfWriter) int(code, value int) {
+	w.group(code, strconv.Itoa(value))
+}
+
+func (w *dxfWriter) float(code int, value float64) {
+	w.group(code, strconv.FormatFloat(math.Round(value*1e6)/1e6, 'f', -1, 64))
+}
+
+func (w *dxfWriter) point(code int, p domain.Position) {
+	w.float(code, p.X)
+	w.float(code+10, p.Y)
+	w.float(code+20, p.Z)
+}
+
+func (w *dxfWriter) beginSection(name string) {
+	w.group(0, "SECTION")
+	w.group(2, name)
+}
+
+func (w *dxfWriter) endSection() {
+	w.group(0, "ENDSEC")
+}
+
+func (w *dxfWriter) bytes() []byte {
+	w.group(0, "EOF")
+	return w.buf.Bytes()
+}
@@ -0,0 +1,898 @@
+// Package services DXF 导入导出服务
+//
+// 设计参考：
+// - AutoCAD DXF 参考手册（POINT/INSERT/LINE/LWPOLYLINE/POLYLINE/ARC/SPLINE 实体）
+// - 设施规划图纸常用的“按图层区分对象类型”的组织方式
+//
+// 特点：
+// 1. POINT/INSERT 导入为节点，图层规则决定节点类型
+// 2. LINE/POLYLINE/ARC/SPLINE 导入为路径，端点吸附到节点，几何保存为 Waypoints 和 CurveType
+// 3. 导出为 R12 DXF，节点为带属性的图块，便于叠加到设施图纸
+// 4. 图纸单位、原点、Y轴方向可配置
+package services
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"robot-path-editor/internal/domain"
+	"robot-path-editor/internal/repositories"
+)
+
+// DXFService DXF 导入导出服务接口
+type DXFService interface {
+	ImportDXF(ctx context.Context, data []byte, opts DXFImportOptions) (*DXFImportResult, error)
+	ExportDXF(ctx context.Context, opts DXFExportOptions) (*DXFFile, error)
+}
+
+// DXFCoordinateOptions 图纸坐标与编辑器坐标的换算
+// 图纸坐标 = 原点 + 编辑器坐标 × scale（米）÷ 图纸单位（米）
+type DXFCoordinateOptions struct {
+	Units   string  `json:"units,omitempty"`    // 图纸单位：mm、cm、m、in、ft；导入时默认读取 $INSUNITS，否则为 m
+	Scale   float64 `json:"scale,omitempty"`    // 编辑器坐标到米的比例，默认1
+	OriginX float64 `json:"origin_x,omitempty"` // 编辑器原点在图纸中的坐标（图纸单位）
+	OriginY float64 `json:"origin_y,omitempty"`
+	FlipY   bool    `json:"flip_y,omitempty"` // 画布Y轴向下时翻转
+}
+
+// DXFLayerRule 图层映射规则，按顺序匹配第一条
+type DXFLayerRule struct {
+	Layer    string          `json:"layer"`               // 图层名，支持 * ? 通配符，不区分大小写
+	NodeType domain.NodeType `json:"node_type,omitempty"` // 该图层上节点的类型
+	PathType domain.PathType `json:"path_type,omitempty"` // 该图层上路径的类型
+	Ignore   bool            `json:"ignore,omitempty"`    // 忽略该图层上的全部实体
+}
+
+// DXFImportOptions 导入选项
+type DXFImportOptions struct {
+	DXFCoordinateOptions
+	LayerRules         []DXFLayerRule `json:"layer_rules,omitempty"`
+	SnapTolerance      float64        `json:"snap_tolerance,omitempty"`       // 路径端点吸附到节点的距离（图纸单位），默认0.01米
+	CreateMissingNodes *bool          `json:"create_missing_nodes,omitempty"` // 端点附近没有节点时创建路径点节点，默认true
+	CurveSegments      int            `json:"curve_segments,omitempty"`       // 凸度圆弧和一般 NURBS 的细分段数，默认16
+}
+
+// DXFImportResult 导入结果
+type DXFImportResult struct {
+	Units        string         `json:"units"`
+	NodesCreated int            `json:"nodes_created"`
+	PathsCreated int            `json:"paths_created"`
+	Skipped      map[string]int `json:"skipped,omitempty"` // 按实体类型统计的跳过数量
+	Warnings     []string       `json:"warnings,omitempty"`
+}
+
+// DXFExportOptions 导出选项
+type DXFExportOptions struct {
+	DXFCoordinateOptions
+	Name          string         `json:"name,omitempty"`
+	LayerRules    []DXFLayerRule `json:"layer_rules,omitempty"`    // 按节点/路径类型反查图层名（仅使用不含通配符的规则）
+	NodeSize      float64        `json:"node_size,omitempty"`      // 节点符号半径（图纸单位），默认0.25米
+	CurveSegments int            `json:"curve_segments,omitempty"` // 贝塞尔和样条的细分段数，默认16
+}
+
+// DXFFile 导出的 DXF 文件
+type DXFFile struct {
+	Filename string `json:"filename"`
+	Units    string `json:"units"`
+	Nodes    int    `json:"nodes"`
+	Paths    int    `json:"paths"`
+	Content  string `json:"content"`
+}
+
+// 扩展属性键
+const (
+	dxfLayerKey  = "dxf_layer"
+	dxfHandleKey = "dxf_handle"
+	dxfBlockKey  = "dxf_block"
+)
+
+// dxfUnits 图纸单位：名称、$INSUNITS 代码、每单位米数
+var dxfUnits = []struct {
+	name   string
+	code   int
+	meters float64
+}{
+	{"in", 1, 0.0254},
+	{"ft", 2, 0.3048},
+	{"mm", 4, 0.001},
+	{"cm", 5, 0.01},
+	{"m", 6, 1},
+}
+
+// dxfService DXF 服务实现
+type dxfService struct {
+	nodeRepo repositories.NodeRepository
+	pathRepo repositories.PathRepository
+}
+
+// NewDXFService 创建新的 DXF 服务实例
+func NewDXFService(nodeRepo repositories.NodeRepository, pathRepo repositories.PathRepository) DXFService {
+	return &dxfService{
+		nodeRepo: nodeRepo,
+		pathRepo: pathRepo,
+	}
+}
+
+// === 坐标换算 ===
+
+// dxfTransform 编辑器坐标与图纸坐标的换算
+type dxfTransform struct {
+	units  string
+	unit   float64 // 米/图纸单位
+	scale  float64 // 米/编辑器单位
+	origin domain.Position
+	flipY  bool
+}
+
+// newDXFTransform 创建坐标换算，units 为空时使用 insUnits（$INSUNITS），再退回到米
+func newDXFTransform(opts DXFCoordinateOptions, insUnits int) (dxfTransform, error) {
+	t := dxfTransform{units: "m", unit: 1, scale: 1, origin: domain.Position{X: opts.OriginX, Y: opts.OriginY}, flipY: opts.FlipY}
+	if opts.Scale > 0 {
+		t.scale = opts.Scale
+	}
+
+	units := strings.ToLower(opts.Units)
+	for _, u := range dxfUnits {
+		if u.name == units || (units == "" && u.code == insUnits) {
+			t.units, t.unit = u.name, u.meters
+			return t, nil
+		}
+	}
+	if units != "" {
+		return t, fmt.Errorf("不支持的图纸单位: %s", opts.Units)
+	}
+	return t, nil
+}
+
+func (t dxfTransform) insUnits() int {
+	for _, u := range dxfUnits {
+		if u.name == t.units {
+			return u.code
+		}
+	}
+	return 0
+}
+
+// toDrawing 编辑器坐标转换为图纸坐标
+func (t dxfTransform) toDrawing(p domain.Position) domain.Position {
+	k := t.scale / t.unit
+	y := p.Y * k
+	if t.flipY {
+		y = -y
+	}
+	return domain.Position{X: t.origin.X + p.X*k, Y: t.origin.Y + y, Z: p.Z * k}
+}
+
+// fromDrawing 图纸坐标转换为编辑器坐标
+func (t dxfTransform) fromDrawing(p domain.Position) domain.Position {
+	k := t.unit / t.scale
+	y := (p.Y - t.origin.Y) * k
+	if t.flipY {
+		y = -y
+	}
+	return domain.Position{X: roundNav2((p.X - t.origin.X) * k), Y: roundNav2(y), Z: roundNav2(p.Z * k)}
+}
+
+// matchDXFLayer 返回图层匹配的第一条规则
+func matchDXFLayer(rules []DXFLayerRule, layer string) (DXFLayerRule, bool) {
+	for _, rule := range rules {
+		if ok, _ := path.Match(strings.ToUpper(rule.Layer), strings.ToUpper(layer)); ok {
+			return rule, true
+		}
+	}
+	return DXFLayerRule{}, false
+}
+
+// === 导入 ===
+
+// dxfImportNode 导入过程中的节点（图纸坐标）
+type dxfImportNode struct {
+	node     *domain.Node
+	position domain.Position
+}
+
+// dxfImport 单次导入的状态
+type dxfImport struct {
+	opts      DXFImportOptions
+	transform dxfTransform
+	tolerance float64
+	segments  int
+	createNew bool
+	nodes     []*dxfImportNode
+	paths     []*domain.Path
+	result    *DXFImportResult
+}
+
+// ImportDXF 导入 DXF 图纸，创建节点和路径
+func (s *dxfService) ImportDXF(ctx context.Context, data []byte, opts DXFImportOptions) (*DXFImportResult, error) {
+	drawing, err := parseDXF(data)
+	if err != nil {
+		return nil, err
+	}
+	transform, err := newDXFTransform(opts.DXFCoordinateOptions, drawing.insUnits)
+	if err != nil {
+		return nil, err
+	}
+
+	imp := &dxfImport{
+		opts:      opts,
+		transform: transform,
+		tolerance: opts.SnapTolerance,
+		segments:  opts.CurveSegments,
+		createNew: opts.CreateMissingNodes == nil || *opts.CreateMissingNodes,
+		result:    &DXFImportResult{Units: transform.units, Skipped: make(map[string]int)},
+	}
+	if imp.tolerance <= 0 {
+		imp.tolerance = 0.01 / transform.unit
+	}
+	if imp.segments <= 0 {
+		imp.segments = 16
+	}
+
+	// 先导入全部节点，路径端点才能吸附到后出现的节点上
+	var curves []*dxfEntity
+	for _, entity := range drawing.entities {
+		rule, _ := matchDXFLayer(opts.LayerRules, entity.layer())
+		if rule.Ignore {
+			imp.result.Skipped[entity.kind]++
+			continue
+		}
+		switch entity.kind {
+		case "POINT", "INSERT":
+			imp.addNode(entity, rule)
+		case "LINE", "LWPOLYLINE", "POLYLINE", "ARC", "SPLINE":
+			curves = append(curves, entity)
+		default:
+			imp.result.Skipped[entity.kind]++
+		}
+	}
+	for _, entity := range curves {
+		rule, _ := matchDXFLayer(opts.LayerRules, entity.layer())
+		imp.addPath(entity, rule)
+	}
+
+	for _, n := range imp.nodes {
+		if err := s.nodeRepo.Create(ctx, n.node); err != nil {
+			return nil, fmt.Errorf("创建节点 %s 失败: %w", n.node.Name, err)
+		}
+		imp.result.NodesCreated++
+	}
+	for _, p := range imp.paths {
+		if err := s.pathRepo.Create(ctx, p); err != nil {
+			return nil, fmt.Errorf("创建路径 %s 失败: %w", p.Name, err)
+		}
+		imp.result.PathsCreated++
+	}
+
+	if len(imp.result.Skipped) == 0 {
+		imp.result.Skipped = nil
+	}
+	return imp.result, nil
+}
+
+// addNode 由 POINT 或 INSERT 创建节点
+func (imp *dxfImport) addNode(entity *dxfEntity, rule DXFLayerRule) {
+	position := entity.point(10)
+	if entity.kind == "INSERT" && entity.mirrored() {
+		position.X = -position.X
+	}
+
+	if existing := imp.nearestNode(position); existing != nil {
+		imp.warn(entity, fmt.Sprintf("与节点 %s 重合，已合并", existing.node.Name))
+		return
+	}
+
+	nodeType := rule.NodeType
+	if nodeType == "" {
+		nodeType = defaultDXFNodeType(entity.layer())
+	}
+	name := ""
+	properties := map[string]interface{}{dxfLayerKey: entity.layer()}
+	if handle := entity.str(5); handle != "" {
+		properties[dxfHandleKey] = handle
+	}
+
+	if entity.kind == "INSERT" {
+		properties[dxfBlockKey] = entity.str(2)
+		for _, attrib := range entity.attribs {
+			tag, value := strings.ToLower(attrib.str(2)), attrib.str(1)
+			switch tag {
+			case "":
+			case "name":
+				name = value
+			case "id":
+				// 导出时写入的编辑器ID仅用于对照，导入时重新生成
+			default:
+				properties[tag] = value
+			}
+		}
+		if rotation := entity.float(50); rotation != 0 {
+			theta := rotation * math.Pi / 180
+			if imp.transform.flipY != entity.mirrored() {
+				theta = -theta
+			}
+			properties["theta"] = roundNav2(theta)
+		}
+	}
+	if name == "" {
+		name = fmt.Sprintf("%s_%d", entity.layer(), len(imp.nodes)+1)
+	}
+
+	node := domain.NewNode(name, string(nodeType))
+	node.Position = imp.transform.fromDrawing(position)
+	node.Properties = properties
+	imp.nodes = append(imp.nodes, &dxfImportNode{node: node, position: position})
+}
+
+// defaultDXFNodeType 没有匹配规则时按导出约定的图层名 NODES_<TYPE> 推断节点类型
+func defaultDXFNodeType(layer string) domain.NodeType {
+	upper := strings.ToUpper(layer)
+	if strings.HasPrefix(upper, "NODES_") {
+		switch t := domain.NodeType(strings.ToLower(upper[len("NODES_"):])); t {
+		case domain.NodeTypePoint, domain.NodeTypeWaypoint, domain.NodeTypeStation, domain.NodeTypeCharging:
+			return t
+		}
+	}
+	return domain.NodeTypePoint
+}
+
+// defaultDXFPathType 没有匹配规则时按导出约定的图层名 PATHS_<TYPE> 推断路径类型
+func defaultDXFPathType(layer string) domain.PathType {
+	upper := strings.ToUpper(layer)
+	if strings.HasPrefix(upper, "PATHS_") {
+		switch t := domain.PathType(strings.ToLower(upper[len("PATHS_"):])); t {
+		case domain.PathTypeCurved, domain.PathTypeRestricted:
+			return t
+		}
+	}
+	return domain.PathTypeNormal
+}
+
+// addPath 由曲线实体创建路径
+func (imp *dxfImport) addPath(entity *dxfEntity, rule DXFLayerRule) {
+	points, curveType, ok := imp.curveGeometry(entity)
+	if !ok {
+		imp.result.Skipped[entity.kind]++
+		return
+	}
+
+	start, end := points[0], points[len(points)-1]
+	if start.DistanceTo(end) <= imp.tolerance {
+		imp.warn(entity, "首尾重合的闭合曲线不能作为路径，已跳过")
+		imp.result.Skipped[entity.kind]++
+		return
+	}
+
+	startNode := imp.snapNode(start, entity)
+	endNode := imp.snapNode(end, entity)
+	if startNode == nil || endNode == nil {
+		imp.warn(entity, "端点附近没有节点，已跳过")
+		imp.result.Skipped[entity.kind]++
+		return
+	}
+
+	name := fmt.Sprintf("%s_%d", entity.layer(), len(imp.paths)+1)
+	p := domain.NewPath(name, startNode.node.ID, endNode.node.ID)
+	p.CurveType = curveType
+	if rule.PathType != "" {
+		p.Type = rule.PathType
+	} else {
+		p.Type = defaultDXFPathType(entity.layer())
+	}
+	for _, waypoint := range points[1 : len(points)-1] {
+		p.Waypoints = append(p.Waypoints, imp.transform.fromDrawing(waypoint))
+	}
+	p.Properties = map[string]interface{}{dxfLayerKey: entity.layer()}
+	if handle := entity.str(5); handle != "" {
+		p.Properties[dxfHandleKey] = handle
+	}
+
+	controls := p.ControlPoints(startNode.node.Position, endNode.node.Position)
+	p.Length = roundNav2(domain.PolylineLength(domain.SampleCurve(controls, p.CurveType, imp.segments)))
+	p.Weight = p.Length
+	imp.paths = append(imp.paths, p)
+}
+
+// curveGeometry 将曲线实体转换为控制点序列（图纸坐标）和曲线类型
+func (imp *dxfImport) curveGeometry(entity *dxfEntity) ([]domain.Position, domain.CurveType, bool) {
+	switch entity.kind {
+	case "LINE":
+		return []domain.Position{entity.point(10), entity.point(11)}, domain.CurveTypeLinear, true
+
+	case "ARC":
+		center, radius := entity.point(10), entity.float(40)
+		if radius <= 0 {
+			return nil, "", false
+		}
+		startAngle := entity.float(50) * math.Pi / 180
+		sweep := math.Mod(entity.float(51)*math.Pi/180-startAngle, 2*math.Pi)
+		if sweep <= 0 {
+			sweep += 2 * math.Pi
+		}
+		var points []domain.Position
+		for _, angle := range []float64{startAngle, startAngle + sweep/2, startAngle + sweep} {
+			p := domain.Position{X: center.X + radius*math.Cos(angle), Y: center.Y + radius*math.Sin(angle), Z: center.Z}
+			if entity.mirrored() {
+				p.X = -p.X
+			}
+			points = append(points, p)
+		}
+		return points, domain.CurveTypeArc, true
+
+	case "LWPOLYLINE", "POLYLINE":
+		return imp.polylineGeometry(entity)
+
+	case "SPLINE":
+		return imp.splineGeometry(entity)
+	}
+	return nil, "", false
+}
+
+// polylineGeometry 多段线：单段凸度圆弧转为 arc，其余凸度段细分为折线
+func (imp *dxfImport) polylineGeometry(entity *dxfEntity) ([]domain.Position, domain.CurveType, bool) {
+	var vertices []domain.Position
+	var bulges []float64
+
+	if entity.kind == "LWPOLYLINE" {
+		elevation := entity.float(38)
+		bulgeAt := make(map[int]float64)
+		index := -1
+		for _, g := range entity.groups {
+			switch g.code {
+			case 10:
+				index++
+			case 42:
+				if index >= 0 {
+					bulgeAt[index], _ = strconv.ParseFloat(strings.TrimSpace(g.value), 64)
+				}
+			}
+		}
+		vertices = entity.points(10)
+		for i := range vertices {
+			vertices[i].Z = elevation
+			bulges = append(bulges, bulgeAt[i])
+		}
+	} else {
+		if flags := int(entity.float(70)); flags&(16|64) != 0 {
+			return nil, "", false // 多边形网格和多面网格不是路径
+		}
+		for _, vertex := range entity.vertices {
+			vertices = append(vertices, vertex.point(10))
+			bulges = append(bulges, vertex.float(42))
+		}
+	}
+
+	if int(entity.float(70))&1 != 0 && len(vertices) > 0 {
+		vertices = append(vertices, vertices[0]) // 闭合多段线
+	}
+	if len(vertices) < 2 {
+		return nil, "", false
+	}
+	if entity.mirrored() {
+		for i := range vertices {
+			vertices[i].X = -vertices[i].X
+		}
+		for i := range bulges {
+			bulges[i] = -bulges[i]
+		}
+	}
+
+	if len(vertices) == 2 && bulges[0] != 0 {
+		return []domain.Position{vertices[0], bulgeMidpoint(vertices[0], vertices[1], bulges[0]), vertices[1]}, domain.CurveTypeArc, true
+	}
+
+	points := []domain.Position{vertices[0]}
+	for i := 1; i < len(vertices); i++ {
+		if b := bulges[i-1]; b != 0 {
+			arc, ok := domain.ArcThrough(vertices[i-1], bulgeMidpoint(vertices[i-1], vertices[i], b), vertices[i])
+			if ok {
+				for j := 1; j < imp.segments; j++ {
+					points = append(points, arc.PointAt(float64(j)/float64(imp.segments)))
+				}
+			}
+		}
+		points = append(points, vertices[i])
+	}
+	return points, domain.CurveTypeLinear, true
+}
+
+// bulgeMidpoint 凸度圆弧的中点：凸度为圆心角四分之一的正切，正值为逆时针
+func bulgeMidpoint(a, b domain.Position, bulge float64) domain.Position {
+	dx, dy := b.X-a.X, b.Y-a.Y
+	sagitta := bulge / 2 // 拱高与弦长之比
+	return domain.Position{
+		X: (a.X+b.X)/2 + dy*sagitta,
+		Y: (a.Y+b.Y)/2 - dx*sagitta,
+		Z: (a.Z + b.Z) / 2,
+	}
+}
+
+// splineGeometry 样条：单段贝塞尔形式转为 bezier，有拟合点时转为经过拟合点的 spline，否则按 NURBS 采样
+func (imp *dxfImport) splineGeometry(entity *dxfEntity) ([]domain.Position, domain.CurveType, bool) {
+	degree := int(entity.float(71))
+	controls := entity.points(10)
+	fits := entity.points(11)
+	knots := entity.floats(40)
+	weights := entity.floats(41)
+
+	if degree >= 1 && len(controls) == degree+1 && len(knots) == 2*(degree+1) && unitWeightsOnly(weights) {
+		return controls, domain.CurveTypeBezier, true
+	}
+	if len(fits) >= 2 {
+		if len(fits) == 2 {
+			return fits, domain.CurveTypeLinear, true
+		}
+		return fits, domain.CurveTypeSpline, true
+	}
+	if degree < 1 || len(controls) < 2 || len(knots) != len(controls)+degree+1 {
+		imp.warn(entity, "样条数据不完整，已跳过")
+		return nil, "", false
+	}
+
+	curve := domain.NURBS{Degree: degree, Knots: knots, ControlPoints: controls, Weights: weights}
+	count := imp.segments * (len(controls) - degree)
+	points := make([]domain.Position, 0, count+1)
+	for i := 0; i <= count; i++ {
+		points = append(points, curve.PointAt(float64(i)/float64(count)))
+	}
+	return points, domain.CurveTypeSpline, true
+}
+
+func unitWeightsOnly(weights []float64) bool {
+	for _, w := range weights {
+		if w != 1 {
+			return false
+		}
+	}
+	return true
+}
+
+// nearestNode 吸附容差内最近的节点
+func (imp *dxfImport) nearestNode(p domain.Position) *dxfImportNode {
+	var best *dxfImportNode
+	bestDist := imp.tolerance
+	for _, n := range imp.nodes {
+		if d := math.Hypot(n.position.X-p.X, n.position.Y-p.Y); d <= bestDist {
+			best, bestDist = n, d
+		}
+	}
+	return best
+}
+
+// snapNode 端点吸附到节点，附近没有节点时按选项创建路径点节点
+func (imp *dxfImport) snapNode(p domain.Position, entity *dxfEntity) *dxfImportNode {
+	if n := imp.nearestNode(p); n != nil {
+		return n
+	}
+	if !imp.createNew {
+		return nil
+	}
+
+	node := domain.NewNode(fmt.Sprintf("%s_%d", entity.layer(), len(imp.nodes)+1), string(domain.NodeTypeWaypoint))
+	node.Position = imp.transform.fromDrawing(p)
+	node.Properties = map[string]interface{}{dxfLayerKey: entity.layer()}
+	n := &dxfImportNode{node: node, position: p}
+	imp.nodes = append(imp.nodes, n)
+	return n
+}
+
+func (imp *dxfImport) warn(entity *dxfEntity, message string) {
+	label := entity.kind
+	if handle := entity.str(5); handle != "" {
+		label += " " + handle
+	}
+	imp.result.Warnings = append(imp.result.Warnings, fmt.Sprintf("%s（图层 %s）：%s", label, entity.layer(), message))
+}
+
+// === 导出 ===
+
+// ExportDXF 将全部节点和路径导出为 R12 DXF
+func (s *dxfService) ExportDXF(ctx context.Context, opts DXFExportOptions) (*DXFFile, error) {
+	transform, err := newDXFTransform(opts.DXFCoordinateOptions, 0)
+	if err != nil {
+		return nil, err
+	}
+	nodes, err := s.nodeRepo.List(ctx, repositories.NodeFilter{})
+	if err != nil {
+		return nil, fmt.Errorf("获取节点列表失败: %w", err)
+	}
+	paths, err := s.pathRepo.List(ctx, repositories.PathFilter{})
+	if err != nil {
+		return nil, fmt.Errorf("获取路径列表失败: %w", err)
+	}
+	sort.Slice(nodes, func(i, j int) bool { return nodes[i].Name < nodes[j].Name })
+	sort.Slice(paths, func(i, j int) bool { return paths[i].Name < paths[j].Name })
+
+	nodeSize := opts.NodeSize
+	if nodeSize <= 0 {
+		nodeSize = 0.25 / transform.unit
+	}
+	segments := opts.CurveSegments
+	if segments <= 0 {
+		segments = 16
+	}
+
+	// 图层和图块
+	nodeLayers := make(map[domain.NodeType]string)
+	layerColors := map[string]int{}
+	for _, node := range nodes {
+		if _, ok := nodeLayers[node.Type]; !ok {
+			nodeLayers[node.Type] = exportDXFLayer(opts.LayerRules, "NODES_"+strings.ToUpper(string(node.Type)),
+				func(rule DXFLayerRule) bool { return rule.NodeType == node.Type })
+			layerColors[nodeLayers[node.Type]] = dxfNodeColor(node.Type)
+		}
+	}
+	pathLayers := make(map[domain.PathType]string)
+	for _, p := range paths {
+		if _, ok := pathLayers[p.Type]; !ok {
+			fallback := "PATHS"
+			if p.Type != "" && p.Type != domain.PathTypeNormal {
+				fallback += "_" + strings.ToUpper(string(p.Type))
+			}
+			pathLayers[p.Type] = exportDXFLayer(opts.LayerRules, fallback,
+				func(rule DXFLayerRule) bool { return rule.PathType == p.Type })
+			layerColors[pathLayers[p.Type]] = 8
+		}
+	}
+
+	w := &dxfWriter{}
+	writeDXFHeader(w, transform)
+	writeDXFTables(w, layerColors)
+	writeDXFBlocks(w, nodeLayers, nodeSize)
+
+	w.beginSection("ENTITIES")
+	positions := make(map[domain.NodeID]domain.Position, len(nodes))
+	for _, node := range nodes {
+		positions[node.ID] = node.Position
+		writeDXFNode(w, node, nodeLayers[node.Type], transform, nodeSize)
+	}
+	exported := 0
+	for _, p := range paths {
+		start, okStart := positions[p.StartNodeID]
+		end, okEnd := positions[p.EndNodeID]
+		if !okStart || !okEnd {
+			continue
+		}
+		var points []domain.Position
+		for _, point := range p.ControlPoints(start, end) {
+			points = append(points, transform.toDrawing(point))
+		}
+		writeDXFPath(w, pathLayers[p.Type], points, p.CurveType, segments)
+		exported++
+	}
+	w.endSection()
+
+	name := opts.Name
+	if name == "" {
+		name = "layout"
+	}
+	content := w.bytes()
+	return &DXFFile{
+		Filename: name + ".dxf",
+		Units:    transform.units,
+		Nodes:    len(nodes),
+		Paths:    exported,
+		Content:  string(content),
+	}, nil
+}
+
+// exportDXFLayer 在不含通配符的规则中查找图层名，没有时使用默认图层
+func exportDXFLayer(rules []DXFLayerRule, fallback string, match func(DXFLayerRule) bool) string {
+	for _, rule := range rules {
+		if !rule.Ignore && !strings.ContainsAny(rule.Layer, "*?[") && rule.Layer != "" && match(rule) {
+			return rule.Layer
+		}
+	}
+	return fallback
+}
+
+// dxfNodeColor 节点类型对应的 ACI 颜色
+func dxfNodeColor(nodeType domain.NodeType) int {
+	switch nodeType {
+	case domain.NodeTypeStation:
+		return 3 // 绿
+	case domain.NodeTypeCharging:
+		return 2 // 黄
+	case domain.NodeTypeWaypoint:
+		return 4 // 青
+	default:
+		return 5 // 蓝
+	}
+}
+
+func writeDXFHeader(w *dxfWriter, transform dxfTransform) {
+	w.beginSection("HEADER")
+	w.group(9, "$ACADVER")
+	w.group(1, "AC1009")
+	w.group(9, "$INSUNITS")
+	w.int(70, transform.insUnits())
+	w.endSection()
+}
+
+func writeDXFTables(w *dxfWriter, layerColors map[string]int) {
+	w.beginSection("TABLES")
+
+	w.group(0, "TABLE")
+	w.group(2, "LTYPE")
+	w.int(70, 1)
+	w.group(0, "LTYPE")
+	w.group(2, "CONTINUOUS")
+	w.int(70, 0)
+	w.group(3, "Solid line")
+	w.int(72, 65)
+	w.int(73, 0)
+	w.float(40, 0)
+	w.group(0, "ENDTAB")
+
+	names := make([]string, 0, len(layerColors))
+	for name := range layerColors {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	w.group(0, "TABLE")
+	w.group(2, "LAYER")
+	w.int(70, len(names)+1)
+	for _, name := range append([]string{"0"}, names...) {
+		color, ok := layerColors[name]
+		if !ok {
+			color = 7
+		}
+		w.group(0, "LAYER")
+		w.group(2, name)
+		w.int(70, 0)
+		w.int(62, color)
+		w.group(6, "CONTINUOUS")
+	}
+	w.group(0, "ENDTAB")
+
+	w.endSection()
+}
+
+// writeDXFBlocks 每种节点类型一个图块：圆形符号加名称、ID两个属性定义
+func writeDXFBlocks(w *dxfWriter, nodeLayers map[domain.NodeType]string, nodeSize float64) {
+	types := make([]string, 0, len(nodeLayers))
+	for t := range nodeLayers {
+		types = append(types, string(t))
+	}
+	sort.Strings(types)
+
+	w.beginSection("BLOCKS")
+	for _, t := range types {
+		name := dxfBlockName(domain.NodeType(t))
+		w.group(0, "BLOCK")
+		w.group(8, "0")
+		w.group(2, name)
+		w.int(70, 2)
+		w.point(10, domain.Position{})
+		w.group(3, name)
+
+		w.group(0, "CIRCLE")
+		w.group(8, "0")
+		w.point(10, domain.Position{})
+		w.float(40, nodeSize)
+
+		// 朝向标记
+		w.group(0, "LINE")
+		w.group(8, "0")
+		w.point(10, domain.Position{})
+		w.point(11, domain.Position{X: nodeSize * 1.5})
+
+		for _, attr := range []struct {
+			tag   string
+			flags int
+		}{{"NAME", 0}, {"ID", 1}} {
+			w.group(0, "ATTDEF")
+			w.group(8, "0")
+			w.point(10, domain.Position{X: nodeSize * 1.2, Y: nodeSize * 1.2})
+			w.float(40, nodeSize*0.8)
+			w.group(1, "")
+			w.group(3, attr.tag)
+			w.group(2, attr.tag)
+			w.int(70, attr.flags)
+		}
+
+		w.group(0, "ENDBLK")
+		w.group(8, "0")
+	}
+	w.endSection()
+}
+
+func dxfBlockName(nodeType domain.NodeType) string {
+	if nodeType == "" {
+		nodeType = domain.NodeTypePoint
+	}
+	return "NODE_" + strings.ToUpper(string(nodeType))
+}
+
+// writeDXFNode 节点写为图块引用，theta 属性（弧度）转换为旋转角
+func writeDXFNode(w *dxfWriter, node *domain.Node, layer string, transform dxfTransform, nodeSize float64) {
+	position := transform.toDrawing(node.Position)
+	rotation := 0.0
+	if theta, ok := propertyFloat(node.Properties, "theta"); ok {
+		if transform.flipY {
+			theta = -theta
+		}
+		rotation = theta * 180 / math.Pi
+	}
+
+	w.group(0, "INSERT")
+	w.group(8, layer)
+	w.int(66, 1)
+	w.group(2, dxfBlockName(node.Type))
+	w.point(10, position)
+	if rotation != 0 {
+		w.float(50, rotation)
+	}
+
+	for _, attr := range []struct {
+		tag, value string
+		flags      int
+	}{{"NAME", node.Name, 0}, {"ID", string(node.ID), 1}} {
+		w.group(0, "ATTRIB")
+		w.group(8, layer)
+		w.point(10, domain.Position{X: position.X + nodeSize*1.2, Y: position.Y + nodeSize*1.2, Z: position.Z})
+		w.float(40, nodeSize*0.8)
+		w.group(1, attr.value)
+		w.group(2, attr.tag)
+		w.int(70, attr.flags)
+	}
+	w.group(0, "SEQEND")
+	w.group(8, layer)
+}
+
+// writeDXFPath 路径写为 LINE、ARC 或三维 POLYLINE（曲线按 segments 细分）
+func writeDXFPath(w *dxfWriter, layer string, points []domain.Position, curveType domain.CurveType, segments int) {
+	if curveType == domain.CurveTypeArc && len(points) >= 3 {
+		if arc, ok := domain.ArcThrough(points[0], points[1], points[len(points)-1]); ok && points[0].Z == points[len(points)-1].Z {
+			// DXF 圆弧总是逆时针，顺时针圆弧交换起止角
+			if arc.Clockwise {
+				arc = arc.Reverse()
+			}
+			w.group(0, "ARC")
+			w.group(8, layer)
+			w.point(10, domain.Position{X: arc.Center.X, Y: arc.Center.Y, Z: points[0].Z})
+			w.float(40, arc.Radius)
+			w.float(50, dxfAngle(arc.Start, arc.Center))
+			w.float(51, dxfAngle(arc.End, arc.Center))
+			return
+		}
+	}
+
+	samples := domain.SampleCurve(points, curveType, segments)
+	if len(samples) == 2 {
+		w.group(0, "LINE")
+		w.group(8, layer)
+		w.point(10, samples[0])
+		w.point(11, samples[1])
+		return
+	}
+
+	w.group(0, "POLYLINE")
+	w.group(8, layer)
+	w.int(66, 1)
+	w.point(10, domain.Position{})
+	w.int(70, 8) // 三维多段线
+	for _, p := range samples {
+		w.group(0, "VERTEX")
+		w.group(8, layer)
+		w.point(10, p)
+		w.int(70, 32)
+	}
+	w.group(0, "SEQEND")
+	w.group(8, layer)
+}
+
+// dxfAngle 点相对圆心的角度（度，0-360）
+func dxfAngle(p, center domain.Position) float64 {
+	angle := math.Atan2(p.Y-center.Y, p.X-center.X) * 180 / math.Pi
+	if angle < 0 {
+		angle += 360
+	}
+	return angle
+}
@@ -0,0 +1,519 @@
+// Package services G-code导出服务
+//
+// 设计参考：
+// - LinuxCNC / GRBL 的 RS-274 子集（G0/G1/G2/G3、G20/G21、G90、G17）
+// - CAM 后处理器的页眉/页脚模板
+//
+// 特点：
+// 1. 导出一条路线，或按遍历顺序导出整个路径集
+// 2. 圆弧路径输出 G2/G3，贝塞尔/样条路径按细分数采样为 G1
+// 3. 不相连的段之间抬刀到安全高度后快速移动；新的一段优先从奇数度节点（含端点）出发，减少抬刀次数
+// 4. 进给速度可由路径扩展属性 feed_rate 覆盖
+package services
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"robot-path-editor/internal/domain"
+	"robot-path-editor/internal/repositories"
+)
+
+// GCodeService G-code导出服务接口
+type GCodeService interface {
+	ExportGCode(ctx context.Context, req ExportGCodeRequest) (*GCodeFile, error)
+}
+
+// G-code单位
+const (
+	GCodeUnitsMM   = "mm"   // G21
+	GCodeUnitsInch = "inch" // G20
+)
+
+// ExportGCodeRequest 导出G-code请求
+type ExportGCodeRequest struct {
+	RouteRequest
+	AllPaths    bool   `json:"all_paths,omitempty"` // 按遍历顺序导出全部路径，此时忽略 path_ids/node_ids
+	ProgramName string `json:"program_name,omitempty"`
+
+	Units          string   `json:"units,omitempty"`            // mm（默认）或 inch
+	Scale          float64  `json:"scale,omitempty"`            // 画布坐标到输出单位的比例，默认1
+	FlipY          bool     `json:"flip_y,omitempty"`           // 画布Y轴向下时翻转，圆弧方向随之反转
+	FeedRate       float64  `json:"feed_rate,omitempty"`        // 切削进给（单位/分钟），默认1000
+	PlungeFeedRate float64  `json:"plunge_feed_rate,omitempty"` // 下刀进给，默认同切削进给
+	SafeZ          *float64 `json:"safe_z,omitempty"`           // 安全高度，为空时段间不抬刀
+	CurveSegments  int      `json:"curve_segments,omitempty"`   // 贝塞尔/样条的细分段数，默认16
+
+	Header string `json:"header,omitempty"` // 页眉模板（text/template），字段见 GCodeTemplateData
+	Footer string `json:"footer,omitempty"` // 页脚模板
+}
+
+// GCodeTemplateData 页眉/页脚模板可用的字段
+type GCodeTemplateData struct {
+	ProgramName   string
+	Units         string
+	UnitsCode     string
+	FeedRate      float64
+	SafeZ         float64
+	HasSafeZ      bool
+	Segments      int
+	CuttingLength float64
+	Date          string
+}
+
+// GCodeFile 生成的G-code文件
+type GCodeFile struct {
+	Filename      string  `json:"filename"`
+	Content       string  `json:"content"`
+	Units         string  `json:"units"`
+	Segments      int     `json:"segments"`       // 连续加工段数
+	Lines         int     `json:"lines"`          // 程序行数
+	CuttingLength float64 `json:"cutting_length"` // 切削移动总长度（输出单位）
+}
+
+const (
+	defaultGCodeFeedRate      = 1000.0
+	defaultGCodeCurveSegments = 16
+
+	defaultGCodeHeader = "; {{.ProgramName}}\n; Generated by robot-path-editor at {{.Date}}"
+	defaultGCodeFooter = "M2"
+)
+
+// gcodeSegment 连续加工段：从起点出发依次通过的一组路段
+type gcodeSegment struct {
+	legs []RouteLeg
+}
+
+// gcodeService G-code导出服务实现
+type gcodeService struct {
+	nodeRepo repositories.NodeRepository
+	pathRepo repositories.PathRepository
+	routes   *routeResolver
+}
+
+// NewGCodeService 创建新的G-code导出服务实例
+func NewGCodeService(nodeRepo repositories.NodeRepository, pathRepo repositories.PathRepository) GCodeService {
+	return &gcodeService{
+		nodeRepo: nodeRepo,
+		pathRepo: pathRepo,
+		routes:   newRouteResolver(nodeRepo, pathRepo),
+	}
+}
+
+// ExportGCode 导出G-code
+func (s *gcodeService) ExportGCode(ctx context.Context, req ExportGCodeRequest) (*GCodeFile, error) {
+	units, unitsCode, err := gcodeUnits(req.Units)
+	if err != nil {
+		return nil, err
+	}
+
+	var segments []gcodeSegment
+	if req.AllPaths {
+		segments, err = s.traversePaths(ctx)
+	} else {
+		var route *Route
+		route, err = s.routes.Resolve(ctx, req.RouteRequest)
+		if err == nil && len(route.Legs) == 0 {
+			err = fmt.Errorf("路线至少需要包含两个节点")
+		}
+		if err == nil {
+			segments = []gcodeSegment{{legs: route.Legs}}
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(segments) == 0 {
+		return nil, fmt.Errorf("没有可导出的路径")
+	}
+
+	w := newGCodeWriter(req)
+	for _, segment := range segments {
+		w.writeSegment(segment)
+	}
+	w.finish()
+
+	data := GCodeTemplateData{
+		ProgramName:   sanitizeProgramIdentifier(req.ProgramName, "RoutePath"),
+		Units:         units,
+		UnitsCode:     unitsCode,
+		FeedRate:      w.feedRate,
+		HasSafeZ:      req.SafeZ != nil,
+		Segments:      len(segments),
+		CuttingLength: math.Round(w.cuttingLength*1000) / 1000,
+		Date:          time.Now().Format(time.RFC3339),
+	}
+	if req.SafeZ != nil {
+		data.SafeZ = *req.SafeZ
+	}
+
+	header, err := renderGCodeTemplate("header", req.Header, defaultGCodeHeader, data)
+	if err != nil {
+		return nil, err
+	}
+	footer, err := renderGCodeTemplate("footer", req.Footer, defaultGCodeFooter, data)
+	if err != nil {
+		return nil, err
+	}
+
+	var b strings.Builder
+	b.WriteString(header)
+	// 模态设置总是输出，保证程序语义不依赖自定义页眉
+	fmt.Fprintf(&b, "%s\nG90\nG17\nG94\n", unitsCode)
+	b.WriteString(w.body.String())
+	b.WriteString(footer)
+
+	content := b.String()
+	return &GCodeFile{
+		Filename:      data.ProgramName + ".nc",
+		Content:       content,
+		Units:         units,
+		Segments:      len(segments),
+		Lines:         strings.Count(content, "\n"),
+		CuttingLength: data.CuttingLength,
+	}, nil
+}
+
+// traversePaths 按遍历顺序排列全部路径
+// 贪心策略：优先沿当前节点继续走未访问的路径，走不通时开始新的一段（见 nearestLeg）
+func (s *gcodeService) traversePaths(ctx context.Context) ([]gcodeSegment, error) {
+	paths, err := s.pathRepo.List(ctx, repositories.PathFilter{})
+	if err != nil {
+		return nil, fmt.Errorf("获取路径列表失败: %w", err)
+	}
+	if len(paths) == 0 {
+		return nil, nil
+	}
+	sort.Slice(paths, func(i, j int) bool { return paths[i].ID < paths[j].ID })
+
+	nodeIDs := make([]domain.NodeID, 0, len(paths)*2)
+	for _, path := range paths {
+		nodeIDs = append(nodeIDs, path.StartNodeID, path.EndNodeID)
+	}
+	nodes, err := s.routes.loadNodes(ctx, nodeIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	visited := make(map[domain.PathID]bool, len(paths))
+	var segments []gcodeSegment
+	var current *domain.Node
+
+	for len(visited) < len(paths) {
+		leg, ok := nextConnectedLeg(paths, visited, nodes, current)
+		if !ok {
+			leg = nearestLeg(paths, visited, nodes, current)
+			segments = append(segments, gcodeSegment{})
+		}
+
+		visited[leg.Path.ID] = true
+		last := &segments[len(segments)-1]
+		last.legs = append(last.legs, leg)
+		current = leg.To
+	}
+
+	return segments, nil
+}
+
+// nextConnectedLeg 查找从当前节点出发、可通行的第一条未访问路径
+func nextConnectedLeg(paths []*domain.Path, visited map[domain.PathID]bool, nodes map[domain.NodeID]*domain.Node, current *domain.Node) (RouteLeg, bool) {
+	if current == nil {
+		return RouteLeg{}, false
+	}
+	for _, path := range paths {
+		if visited[path.ID] || !path.CanTraverse(current.ID) {
+			continue
+		}
+		if path.StartNodeID == current.ID {
+			return RouteLeg{Path: path, From: current, To: nodes[path.EndNodeID]}, true
+		}
+		if path.EndNodeID == current.ID {
+			return RouteLeg{Path: path, From: current, To: nodes[path.StartNodeID], Reversed: true}, true
+		}
+	}
+	return RouteLeg{}, false
+}
+
+// nearestLeg 选择新一段的第一条未访问路径
+// 起点优先取未访问路径中度数为奇数的节点（含只连一条路径的端点）：从偶数度节点出发的一笔画会在奇数度节点中途断开
+// 同等条件下取离当前位置最近的（第一段取第一条路径）
+func nearestLeg(paths []*domain.Path, visited map[domain.PathID]bool, nodes map[domain.NodeID]*domain.Node, current *domain.Node) RouteLeg {
+	degree := make(map[domain.NodeID]int)
+	for _, path := range paths {
+		if !visited[path.ID] {
+			degree[path.StartNodeID]++
+			degree[path.EndNodeID]++
+		}
+	}
+
+	var best RouteLeg
+	bestOdd := false
+	bestDistance := math.Inf(1)
+
+	for _, path := range paths {
+		if visited[path.ID] {
+			continue
+		}
+		candidates := []RouteLeg{
+			{Path: path, From: nodes[path.StartNodeID], To: nodes[path.EndNodeID]},
+			{Path: path, From: nodes[path.EndNodeID], To: nodes[path.StartNodeID], Reversed: true},
+		}
+		for _, leg := range candidates {
+			if !path.CanTraverse(leg.From.ID) {
+				continue
+			}
+			odd := degree[leg.From.ID]%2 == 1
+			distance := 0.0
+			if current != nil {
+				distance = current.Position.DistanceTo(leg.From.Position)
+			}
+			if (odd && !bestOdd) || (odd == bestOdd && distance < bestDistance) {
+				best, bestOdd, bestDistance = leg, odd, distance
+			}
+		}
+	}
+
+	return best
+}
+
+// gcodeWriter G-code程序体生成器，记录模态状态以省略重复字
+type gcodeWriter struct {
+	body          strings.Builder
+	scale         float64
+	flipY         bool
+	feedRate      float64
+	plungeFeed    float64
+	safeZ         *float64
+	curveSegments int
+
+	position      *domain.Position
+	lastFeed      float64
+	cuttingLength float64
+}
+
+// newGCodeWriter 按请求参数创建生成器
+func newGCodeWriter(req ExportGCodeRequest) *gcodeWriter {
+	w := &gcodeWriter{
+		scale:         1,
+		flipY:         req.FlipY,
+		feedRate:      defaultGCodeFeedRate,
+		safeZ:         req.SafeZ,
+		curveSegments: defaultGCodeCurveSegments,
+	}
+	if req.Scale > 0 {
+		w.scale = req.Scale
+	}
+	if req.FeedRate > 0 {
+		w.feedRate = req.FeedRate
+	}
+	w.plungeFeed = w.feedRate
+	if req.PlungeFeedRate > 0 {
+		w.plungeFeed = req.PlungeFeedRate
+	}
+	if req.CurveSegments > 0 {
+		w.curveSegments = req.CurveSegments
+	}
+	return w
+}
+
+// writeSegment 写入一个连续加工段：抬刀、快移到起点、下刀，然后依次加工各路段
+func (w *gcodeWriter) writeSegment(segment gcodeSegment) {
+	if len(segment.legs) == 0 {
+		return
+	}
+
+	start := w.scaled(segment.legs[0].From.Position)
+	fmt.Fprintf(&w.body, "; Segment from %s\n", segment.legs[0].From.Name)
+	if w.safeZ != nil {
+		w.rapidZ(*w.safeZ)
+		w.rapidXY(start)
+		w.plunge(start.Z)
+	} else {
+		w.rapid(start)
+	}
+
+	for _, leg := range segment.legs {
+		w.writeLeg(leg)
+	}
+}
+
+// writeLeg 写入一个路段
+func (w *gcodeWriter) writeLeg(leg RouteLeg) {
+	start := w.scaled(leg.From.Position)
+	end := w.scaled(leg.To.Position)
+	if leg.Path == nil {
+		w.linear(end, w.feedRate)
+		return
+	}
+
+	feed := w.feedRate
+	if v, ok := propertyFloat(leg.Path.Properties, "feed_rate"); ok && v > 0 {
+		feed = v
+	}
+	fmt.Fprintf(&w.body, "; %s\n", leg.Path.Name)
+
+	// From/To 已是通行顺序，逆向通行时只需反转途经点
+	points := make([]domain.Position, 0, len(leg.Path.Waypoints)+2)
+	points = append(points, start)
+	for i := range leg.Path.Waypoints {
+		waypoint := leg.Path.Waypoints[i]
+		if leg.Reversed {
+			waypoint = leg.Path.Waypoints[len(leg.Path.Waypoints)-1-i]
+		}
+		points = append(points, w.scaled(waypoint))
+	}
+	points = append(points, end)
+
+	if leg.Path.CurveType == domain.CurveTypeArc && len(points) >= 3 {
+		if arc, ok := domain.ArcThrough(points[0], points[1], points[len(points)-1]); ok {
+			w.arc(arc, feed)
+			return
+		}
+	}
+
+	samples := domain.SampleCurve(points, leg.Path.CurveType, w.curveSegments)
+	for _, point := range samples[1:] {
+		w.linear(point, feed)
+	}
+}
+
+// finish 程序结束前抬刀
+func (w *gcodeWriter) finish() {
+	if w.safeZ != nil {
+		w.rapidZ(*w.safeZ)
+	}
+}
+
+// scaled 画布坐标转换为输出坐标；翻转Y轴在圆弧拟合之前进行，G2/G3 的方向由翻转后的点确定
+func (w *gcodeWriter) scaled(p domain.Position) domain.Position {
+	out := domain.Position{X: p.X * w.scale, Y: p.Y * w.scale, Z: p.Z * w.scale}
+	if w.flipY {
+		out.Y = -out.Y
+	}
+	return out
+}
+
+func (w *gcodeWriter) rapidZ(z float64) {
+	if w.position != nil && w.position.Z == z {
+		return
+	}
+	fmt.Fprintf(&w.body, "G0 Z%s\n", gcodeNumber(z))
+	if w.position != nil {
+		w.position.Z = z
+	} else {
+		w.position = &domain.Position{Z: z}
+	}
+}
+
+func (w *gcodeWriter) rapidXY(p domain.Position) {
+	fmt.Fprintf(&w.body, "G0 X%s Y%s\n", gcodeNumber(p.X), gcodeNumber(p.Y))
+	w.position.X, w.position.Y = p.X, p.Y
+}
+
+func (w *gcodeWriter) rapid(p domain.Position) {
+	fmt.Fprintf(&w.body, "G0 X%s Y%s Z%s\n", gcodeNumber(p.X), gcodeNumber(p.Y), gcodeNumber(p.Z))
+	w.position = &p
+}
+
+func (w *gcodeWriter) plunge(z float64) {
+	if w.position.Z == z {
+		return
+	}
+	fmt.Fprintf(&w.body, "G1 Z%s%s\n", gcodeNumber(z), w.feedWord(w.plungeFeed))
+	w.position.Z = z
+}
+
+func (w *gcodeWriter) linear(p domain.Position, feed float64) {
+	if w.position != nil && *w.position == p {
+		return
+	}
+	fmt.Fprintf(&w.body, "G1%s%s\n", w.axes(p), w.feedWord(feed))
+	if w.position != nil {
+		w.cuttingLength += w.position.DistanceTo(p)
+	}
+	w.position = &p
+}
+
+// arc 输出圆弧插补，I/J 为圆心相对起点的增量（G17 XY平面，Z方向为螺旋插补）
+func (w *gcodeWriter) arc(arc domain.Arc, feed float64) {
+	code := "G3"
+	if arc.Clockwise {
+		code = "G2"
+	}
+	fmt.Fprintf(&w.body, "%s%s I%s J%s%s\n", code, w.axes(arc.End),
+		gcodeNumber(arc.Center.X-arc.Start.X), gcodeNumber(arc.Center.Y-arc.Start.Y), w.feedWord(feed))
+
+	dz := arc.End.Z - arc.Start.Z
+	w.cuttingLength += math.Hypot(arc.Radius*arc.Sweep, dz)
+	end := arc.End
+	w.position = &end
+}
+
+// axes 生成坐标字，Z不变时省略
+func (w *gcodeWriter) axes(p domain.Position) string {
+	s := fmt.Sprintf(" X%s Y%s", gcodeNumber(p.X), gcodeNumber(p.Y))
+	if w.position == nil || w.position.Z != p.Z {
+		s += " Z" + gcodeNumber(p.Z)
+	}
+	return s
+}
+
+// feedWord 生成进给字，与上一次相同时省略（F为模态）
+func (w *gcodeWriter) feedWord(feed float64) string {
+	if feed == w.lastFeed {
+		return ""
+	}
+	w.lastFeed = feed
+	return " F" + gcodeNumber(feed)
+}
+
+// gcodeNumber 格式化坐标值：最多保留3位小数并去掉多余的0
+func gcodeNumber(v float64) string {
+	s := strconv.FormatFloat(v, 'f', 3, 64)
+	s = strings.TrimRight(strings.TrimRight(s, "0"), ".")
+	if s == "-0" || s == "" {
+		return "0"
+	}
+	return s
+}
+
+// gcodeUnits 解析单位，返回规范名称和对应的G代码
+func gcodeUnits(units string) (string, string, error) {
+	switch strings.ToLower(strings.TrimSpace(units)) {
+	case "", "mm", "metric", "g21":
+		return GCodeUnitsMM, "G21", nil
+	case "inch", "in", "imperial", "g20":
+		return GCodeUnitsInch, "G20", nil
+	default:
+		return "", "", fmt.Errorf("不支持的单位: %s", units)
+	}
+}
+
+// renderGCodeTemplate 渲染页眉/页脚模板，结果以换行结尾
+func renderGCodeTemplate(name, text, fallback string, data GCodeTemplateData) (string, error) {
+	if strings.TrimSpace(text) == "" {
+		text = fallback
+	}
+	tmpl, err := template.New(name).Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("解析%s模板失败: %w", name, err)
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("渲染%s模板失败: %w", name, err)
+	}
+
+	out := buf.String()
+	if out != "" && !strings.HasSuffix(out, "\n") {
+		out += "\n"
+	}
+	return out, nil
+}
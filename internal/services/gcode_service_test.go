@@ -0,0 +1,66 @@
+package services
+
+import (
+	"strings"
+	"testing"
+
+	"robot-path-editor/internal/domain"
+)
+
+func TestNearestLegStartsAtOddNode(t *testing.T) {
+	// 折线 A-B-C：从端点 A 或 C 出发才能一笔画出两条
+	nodes := map[domain.NodeID]*domain.Node{}
+	for id, x := range map[domain.NodeID]float64{"A": 0, "B": 1, "C": 3} {
+		nodes[id] = &domain.Node{ID: id, Position: domain.Position{X: x}}
+	}
+	paths := []*domain.Path{
+		{ID: "p1", StartNodeID: "B", EndNodeID: "A"},
+		{ID: "p2", StartNodeID: "B", EndNodeID: "C"},
+	}
+	at := func(x float64) *domain.Node { return &domain.Node{ID: "X", Position: domain.Position{X: x}} }
+
+	tests := []struct {
+		name     string
+		current  *domain.Node
+		wantFrom domain.NodeID
+	}{
+		{"第一段从端点出发", nil, "A"},
+		{"端点优先于更近的中间节点", at(1.2), "A"},
+		{"同为端点时取最近的", at(2.8), "C"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			leg := nearestLeg(paths, map[domain.PathID]bool{}, nodes, tt.current)
+			if leg.From.ID != tt.wantFrom {
+				t.Errorf("nearestLeg().From = %v, want %v", leg.From.ID, tt.wantFrom)
+			}
+		})
+	}
+}
+
+func TestGCodeWriterFlipY(t *testing.T) {
+	from := &domain.Node{Name: "A", Position: domain.Position{X: 0, Y: 0}}
+	to := &domain.Node{Name: "B", Position: domain.Position{X: 2, Y: 0}}
+	path := &domain.Path{Name: "arc", CurveType: domain.CurveTypeArc, Waypoints: []domain.Position{{X: 1, Y: 1}}}
+	leg := RouteLeg{Path: path, From: from, To: to}
+
+	tests := []struct {
+		name  string
+		flipY bool
+		want  []string
+	}{
+		{"不翻转", false, []string{"G0 X0 Y0 Z0", "G2 X2 Y0 I1 J0"}},
+		{"翻转后圆弧方向相反", true, []string{"G0 X0 Y0 Z0", "G3 X2 Y0 I1 J0"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := newGCodeWriter(ExportGCodeRequest{FlipY: tt.flipY})
+			w.writeSegment(gcodeSegment{legs: []RouteLeg{leg}})
+			for _, line := range tt.want {
+				if !strings.Contains(w.body.String(), line) {
+					t.Errorf("body = %q, want line %q", w.body.String(), line)
+				}
+			}
+		})
+	}
+}
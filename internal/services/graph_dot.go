@@ -0,0 +1,669 @@
+// Package services Graphviz DOT 读写
+//
+// - 写出 digraph，节点带 pos="x,y!"（单位为点），可直接用 neato -n 按原坐标渲染
+// - Graphviz 不认识的属性（type、status、properties.* 等）原样写出，Graphviz 会忽略它们
+// - 解析器支持 DOT 语言的主要语法：默认属性、子图、边链（a -> b -> c）、端口和注释
+package services
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"robot-path-editor/internal/domain"
+)
+
+// === 写出 ===
+
+// dotQuote 写为带引号的 ID
+func dotQuote(s string) string {
+	s = strings.ReplaceAll(s, `"`, `\"`)
+	s = strings.ReplaceAll(s, "\n", `\n`)
+	return `"` + s + `"`
+}
+
+// dotID 简单标识符和数字原样写出，其余加引号
+func dotID(s string) string {
+	if s == "" {
+		return `""`
+	}
+	if _, err := strconv.ParseFloat(s, 64); err == nil && !strings.ContainsAny(s, "eE+") {
+		return s
+	}
+	for i, r := range s {
+		if !(r == '_' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || i > 0 && r >= '0' && r <= '9') {
+			return dotQuote(s)
+		}
+	}
+	if p := strings.ToLower(s); p == "node" || p == "edge" || p == "graph" || p == "digraph" || p == "subgraph" || p == "strict" {
+		return dotQuote(s)
+	}
+	return s
+}
+
+// dotAttrs 写出属性列表
+func dotAttrs(buf *bytes.Buffer, attrs [][2]string) {
+	buf.WriteString(" [")
+	for i, attr := range attrs {
+		if i > 0 {
+			buf.WriteString(", ")
+		}
+		buf.WriteString(dotID(attr[0]) + "=" + dotID(attr[1]))
+	}
+	buf.WriteString("];\n")
+}
+
+// dotLineStyle 线型对应的 Graphviz style
+func dotLineStyle(style string) string {
+	if style == "dashed" || style == "dotted" {
+		return style
+	}
+	return "solid"
+}
+
+func writeDOT(buf *bytes.Buffer, g *graphExport) {
+	fmt.Fprintf(buf, "digraph %s {\n", dotQuote(g.name))
+	buf.WriteString("  graph [layout=neato, splines=true, outputorder=edgesfirst];\n")
+	buf.WriteString("  node [style=filled, fixedsize=true, fontsize=10];\n")
+	buf.WriteString("  edge [fontsize=8];\n\n")
+
+	// Graphviz 以点为坐标单位，以英寸为节点尺寸单位
+	for _, node := range g.nodes {
+		p := g.transform.to(node.Position)
+		inches := graphCoord(graphNodeDiameter(node) * g.transform.scale / 72)
+		attrs := [][2]string{
+			{"label", node.Name},
+			{"pos", graphCoord(p.X) + "," + graphCoord(p.Y) + "!"},
+			{"shape", graphShape(node.Style.Shape, func(_, _, dot string) string { return dot })},
+			{"width", inches},
+			{"height", inches},
+			{"fillcolor", node.Style.Color},
+			{"color", node.Style.BorderColor},
+			{"penwidth", graphCoord(node.Style.BorderWidth)},
+		}
+		for _, column := range g.nodeColumns {
+			switch column.key {
+			case "name", "position.x", "position.y", "style.color", "style.shape", "style.border_color", "style.border_width":
+				continue
+			}
+			if v := column.get(node); v != "" {
+				attrs = append(attrs, [2]string{column.key, v})
+			}
+		}
+		buf.WriteString("  " + dotQuote(string(node.ID)))
+		dotAttrs(buf, attrs)
+	}
+	buf.WriteString("\n")
+
+	for _, path := range g.paths {
+		dir := "forward"
+		switch graphDirection(path) {
+		case domain.DirectionBidirectional:
+			dir = "both"
+		case domain.DirectionBackward:
+			dir = "back"
+		}
+		// weight 在 dot 布局中必须是整数，路径权重写为 cost
+		attrs := [][2]string{
+			{"id", string(path.ID)},
+			{"label", path.Name},
+			{"dir", dir},
+			{"color", path.Style.Color},
+			{"penwidth", graphCoord(path.Style.Width)},
+			{"style", dotLineStyle(path.Style.Style)},
+			{"cost", graphCoord(path.Weight)},
+		}
+		for _, column := range g.pathColumns {
+			switch column.key {
+			case "name", "weight", "direction", "style.color", "style.width", "style.style":
+				continue
+			}
+			if v := column.get(path); v != "" {
+				attrs = append(attrs, [2]string{column.key, v})
+			}
+		}
+		buf.WriteString("  " + dotQuote(string(path.StartNodeID)) + " -> " + dotQuote(string(path.EndNodeID)))
+		dotAttrs(buf, attrs)
+	}
+	buf.WriteString("}\n")
+}
+
+// === 词法分析 ===
+
+type dotTokenKind int
+
+const (
+	dotEOF    dotTokenKind = iota
+	dotIdent               // 未加引号的标识符或数字
+	dotString              // 引号字符串或 HTML 字符串
+	dotPunct               // { } [ ] ; , = : 以及 -> --
+)
+
+type dotToken struct {
+	kind dotTokenKind
+	text string
+	line int
+}
+
+// lexDOT 将 DOT 文本切分为记号，跳过注释和 # 开头的预处理行
+func lexDOT(src string) ([]dotToken, error) {
+	var tokens []dotToken
+	line := 1
+	for i := 0; i < len(src); {
+		c := src[i]
+		switch {
+		case c == '\n':
+			line++
+			i++
+		case c == ' ' || c == '\t' || c == '\r':
+			i++
+		case c == '#' && strings.TrimLeft(src[strings.LastIndexByte(src[:i], '\n')+1:i], " \t") == "":
+			for i < len(src) && src[i] != '\n' {
+				i++
+			}
+		case strings.HasPrefix(src[i:], "//"):
+			for i < len(src) && src[i] != '\n' {
+				i++
+			}
+		case strings.HasPrefix(src[i:], "/*"):
+			end := strings.Index(src[i+2:], "*/")
+			if end < 0 {
+				return nil, fmt.Errorf("DOT第%d行的注释没有结束", line)
+			}
+			line += strings.Count(src[i:i+2+end], "\n")
+			i += end + 4
+		case strings.HasPrefix(src[i:], "->"), strings.HasPrefix(src[i:], "--"):
+			tokens = append(tokens, dotToken{kind: dotPunct, text: src[i : i+2], line: line})
+			i += 2
+		case strings.ContainsRune("{}[];,=:", rune(c)):
+			tokens = append(tokens, dotToken{kind: dotPunct, text: string(c), line: line})
+			i++
+		case c == '"':
+			text, n, err := lexDOTString(src[i:])
+			if err != nil {
+				return nil, fmt.Errorf("DOT第%d行: %w", line, err)
+			}
+			line += strings.Count(src[i:i+n], "\n")
+			i += n
+			// "a" + "b" 拼接
+			for {
+				j := i
+				for j < len(src) && (src[j] == ' ' || src[j] == '\t' || src[j] == '\r' || src[j] == '\n') {
+					j++
+				}
+				if j >= len(src) || src[j] != '+' {
+					break
+				}
+				k := j + 1
+				for k < len(src) && (src[k] == ' ' || src[k] == '\t' || src[k] == '\r' || src[k] == '\n') {
+					k++
+				}
+				if k >= len(src) || src[k] != '"' {
+					break
+				}
+				more, m, err := lexDOTString(src[k:])
+				if err != nil {
+					return nil, fmt.Errorf("DOT第%d行: %w", line, err)
+				}
+				line += strings.Count(src[i:k+m], "\n")
+				text += more
+				i = k + m
+			}
+			tokens = append(tokens, dotToken{kind: dotString, text: text, line: line})
+		case c == '<':
+			depth, j := 0, i
+			for ; j < len(src); j++ {
+				if src[j] == '<' {
+					depth++
+				} else if src[j] == '>' {
+					depth--
+					if depth == 0 {
+						break
+					}
+				}
+			}
+			if depth != 0 {
+				return nil, fmt.Errorf("DOT第%d行的HTML字符串没有结束", line)
+			}
+			tokens = append(tokens, dotToken{kind: dotString, text: src[i+1 : j], line: line})
+			line += strings.Count(src[i:j], "\n")
+			i = j + 1
+		default:
+			j := i
+			for j < len(src) {
+				r, size := utf8.DecodeRuneInString(src[j:])
+				if !(r == '_' || r == '.' || r == '-' && j == i || unicode.IsLetter(r) || unicode.IsDigit(r) || r >= 0x80) {
+					break
+				}
+				j += size
+			}
+			if j == i {
+				return nil, fmt.Errorf("DOT第%d行有无法识别的字符 %q", line, c)
+			}
+			tokens = append(tokens, dotToken{kind: dotIdent, text: src[i:j], line: line})
+			i = j
+		}
+	}
+	return append(tokens, dotToken{kind: dotEOF, line: line}), nil
+}
+
+// lexDOTString 读取以引号开头的字符串，返回内容和消耗的字节数
+func lexDOTString(src string) (string, int, error) {
+	var b strings.Builder
+	for i := 1; i < len(src); i++ {
+		switch src[i] {
+		case '"':
+			return b.String(), i + 1, nil
+		case '\\':
+			if i+1 < len(src) {
+				switch src[i+1] {
+				case '"':
+					b.WriteByte('"')
+					i++
+					continue
+				case '\n':
+					i++ // 续行
+					continue
+				case '\r':
+					if i+2 < len(src) && src[i+2] == '\n' {
+						i += 2
+						continue
+					}
+				}
+			}
+			b.WriteByte('\\')
+		default:
+			b.WriteByte(src[i])
+		}
+	}
+	return "", 0, fmt.Errorf("字符串没有结束")
+}
+
+// === 语法分析 ===
+
+// dotScope 当前作用域的默认属性
+type dotScope struct {
+	node map[string]string
+	edge map[string]string
+}
+
+func (s dotScope) child() dotScope {
+	return dotScope{node: copyStringMap(s.node), edge: copyStringMap(s.edge)}
+}
+
+func copyStringMap(m map[string]string) map[string]string {
+	copied := make(map[string]string, len(m))
+	for k, v := range m {
+		copied[k] = v
+	}
+	return copied
+}
+
+// dotParser DOT 语法分析器，先得到原始属性，再转换为导入用的节点和边
+type dotParser struct {
+	tokens   []dotToken
+	pos      int
+	directed bool
+	nodes    map[string]map[string]string
+	order    []string
+	edges    []dotEdge
+}
+
+type dotEdge struct {
+	source, target string
+	attrs          map[string]string
+}
+
+// parseDOT 解析 DOT 图
+func parseDOT(data []byte) (*graphImportDoc, error) {
+	tokens, err := lexDOT(string(bytes.TrimPrefix(data, []byte("\ufeff"))))
+	if err != nil {
+		return nil, err
+	}
+	p := &dotParser{tokens: tokens, nodes: make(map[string]map[string]string)}
+	if err := p.parseGraph(); err != nil {
+		return nil, err
+	}
+	return p.document(), nil
+}
+
+func (p *dotParser) peek() dotToken {
+	return p.tokens[p.pos]
+}
+
+func (p *dotParser) next() dotToken {
+	t := p.tokens[p.pos]
+	if t.kind != dotEOF {
+		p.pos++
+	}
+	return t
+}
+
+// accept 下一个记号是指定标点时消耗它
+func (p *dotParser) accept(punct string) bool {
+	if t := p.peek(); t.kind == dotPunct && t.text == punct {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *dotParser) expect(punct string) error {
+	if !p.accept(punct) {
+		t := p.peek()
+		return fmt.Errorf("DOT第%d行应为 %q，实际为 %q", t.line, punct, t.text)
+	}
+	return nil
+}
+
+// keyword 下一个记号是否为关键字（不区分大小写，引号字符串不算）
+func (p *dotParser) keyword(words ...string) string {
+	t := p.peek()
+	if t.kind != dotIdent {
+		return ""
+	}
+	for _, w := range words {
+		if strings.EqualFold(t.text, w) {
+			return w
+		}
+	}
+	return ""
+}
+
+func (p *dotParser) id() (string, error) {
+	t := p.next()
+	if t.kind != dotIdent && t.kind != dotString {
+		return "", fmt.Errorf("DOT第%d行应为标识符，实际为 %q", t.line, t.text)
+	}
+	return t.text, nil
+}
+
+// parseGraph graph : [strict] (graph | digraph) [ID] '{' stmt_list '}'
+func (p *dotParser) parseGraph() error {
+	if p.keyword("strict") != "" {
+		p.next()
+	}
+	switch p.keyword("graph", "digraph") {
+	case "graph":
+	case "digraph":
+		p.directed = true
+	default:
+		return fmt.Errorf("DOT应以 graph 或 digraph 开头")
+	}
+	p.next()
+	if t := p.peek(); t.kind == dotIdent || t.kind == dotString {
+		p.next()
+	}
+	if err := p.expect("{"); err != nil {
+		return err
+	}
+	if _, err := p.parseStmtList(dotScope{node: map[string]string{}, edge: map[string]string{}}); err != nil {
+		return err
+	}
+	return p.expect("}")
+}
+
+// parseStmtList 解析语句直到 '}'，返回其中出现的节点
+func (p *dotParser) parseStmtList(scope dotScope) ([]string, error) {
+	var members []string
+	for {
+		t := p.peek()
+		if t.kind == dotEOF || (t.kind == dotPunct && t.text == "}") {
+			return members, nil
+		}
+		if err := p.parseStmt(&scope, &members); err != nil {
+			return nil, err
+		}
+		p.accept(";")
+	}
+}
+
+func (p *dotParser) parseStmt(scope *dotScope, members *[]string) error {
+	// 默认属性：graph/node/edge [attrs]
+	if word := p.keyword("graph", "node", "edge"); word != "" {
+		p.next()
+		attrs, err := p.parseAttrLists()
+		if err != nil {
+			return err
+		}
+		var target map[string]string
+		switch word {
+		case "node":
+			target = scope.node
+		case "edge":
+			target = scope.edge
+		default:
+			return nil // 图属性不影响导入
+		}
+		for k, v := range attrs {
+			target[k] = v
+		}
+		return nil
+	}
+
+	// 图属性：ID = ID
+	if t := p.peek(); (t.kind == dotIdent || t.kind == dotString) && p.tokens[p.pos+1].kind == dotPunct && p.tokens[p.pos+1].text == "=" {
+		p.pos += 2
+		_, err := p.id()
+		return err
+	}
+
+	// 节点语句或边语句
+	subgraph := p.isSubgraph()
+	first, err := p.parseEndpoint(*scope, members)
+	if err != nil {
+		return err
+	}
+	groups := [][]string{first}
+	for {
+		t := p.peek()
+		if t.kind != dotPunct || (t.text != "->" && t.text != "--") {
+			break
+		}
+		p.next()
+		group, err := p.parseEndpoint(*scope, members)
+		if err != nil {
+			return err
+		}
+		groups = append(groups, group)
+	}
+
+	attrs, err := p.parseAttrLists()
+	if err != nil {
+		return err
+	}
+
+	if len(groups) == 1 {
+		// 节点语句；单独出现的子图已在解析时处理
+		if !subgraph {
+			for k, v := range attrs {
+				p.nodes[first[0]][k] = v
+			}
+		}
+		return nil
+	}
+	for i := 0; i+1 < len(groups); i++ {
+		for _, source := range groups[i] {
+			for _, target := range groups[i+1] {
+				edgeAttrs := copyStringMap(scope.edge)
+				for k, v := range attrs {
+					edgeAttrs[k] = v
+				}
+				p.edges = append(p.edges, dotEdge{source: source, target: target, attrs: edgeAttrs})
+			}
+		}
+	}
+	return nil
+}
+
+// isSubgraph 下一个端点是否为子图
+func (p *dotParser) isSubgraph() bool {
+	t := p.peek()
+	return p.keyword("subgraph") != "" || (t.kind == dotPunct && t.text == "{")
+}
+
+// parseEndpoint 节点ID（可带端口）或子图，返回涉及的节点
+func (p *dotParser) parseEndpoint(scope dotScope, members *[]string) ([]string, error) {
+	if p.isSubgraph() {
+		if p.keyword("subgraph") != "" {
+			p.next()
+			if t := p.peek(); t.kind == dotIdent || t.kind == dotString {
+				p.next()
+			}
+		}
+		if err := p.expect("{"); err != nil {
+			return nil, err
+		}
+		nodes, err := p.parseStmtList(scope.child())
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect("}"); err != nil {
+			return nil, err
+		}
+		*members = append(*members, nodes...)
+		return nodes, nil
+	}
+
+	id, err := p.id()
+	if err != nil {
+		return nil, err
+	}
+	// 端口 ID[:port[:compass]] 不影响连接关系
+	for p.accept(":") {
+		if _, err := p.id(); err != nil {
+			return nil, err
+		}
+	}
+	if _, exists := p.nodes[id]; !exists {
+		p.nodes[id] = copyStringMap(scope.node)
+		p.order = append(p.order, id)
+	}
+	*members = append(*members, id)
+	return []string{id}, nil
+}
+
+// parseAttrLists 零个或多个 [a=b, c=d; e=f]
+func (p *dotParser) parseAttrLists() (map[string]string, error) {
+	attrs := make(map[string]string)
+	for p.accept("[") {
+		for !p.accept("]") {
+			key, err := p.id()
+			if err != nil {
+				return nil, err
+			}
+			value := "true"
+			if p.accept("=") {
+				if value, err = p.id(); err != nil {
+					return nil, err
+				}
+			}
+			attrs[key] = value
+			if !p.accept(",") {
+				p.accept(";")
+			}
+		}
+	}
+	return attrs, nil
+}
+
+// dotIgnoredAttrs 只影响 Graphviz 渲染、导入时丢弃的属性
+var dotIgnoredAttrs = map[string]bool{
+	"pos": true, "width": true, "height": true, "fixedsize": true, "fontsize": true, "fontname": true,
+	"fontcolor": true, "lp": true, "xlp": true, "xlabel": true, "arrowhead": true, "arrowtail": true,
+	"arrowsize": true, "dir": true, "id": true, "cost": true, "style": true, "fillcolor": true,
+	"color": true, "penwidth": true, "shape": true, "weight": true, "len": true, "constraint": true,
+}
+
+// document 把 Graphviz 属性转换为列键
+func (p *dotParser) document() *graphImportDoc {
+	doc := &graphImportDoc{directed: p.directed}
+	for _, id := range p.order {
+		raw := p.nodes[id]
+		node := &graphImportNode{id: id, attrs: make(map[string]string)}
+		if label := raw["label"]; label == `\N` {
+			delete(raw, "label")
+		}
+		if position, ok := parseDOTPoint(raw["pos"]); ok {
+			node.position = &position
+		} else if raw["pos"] != "" {
+			doc.warnings = append(doc.warnings, fmt.Sprintf("节点 %s 的 pos 无效: %s", id, raw["pos"]))
+		}
+		if color := raw["fillcolor"]; color != "" {
+			node.attrs["style.color"] = color
+		} else if color := raw["color"]; color != "" && strings.Contains(raw["style"], "filled") {
+			node.attrs["style.color"] = color
+		}
+		if color := raw["color"]; color != "" && raw["fillcolor"] != "" {
+			node.attrs["style.border_color"] = color
+		}
+		if width := raw["penwidth"]; width != "" {
+			node.attrs["style.border_width"] = width
+		}
+		if shape := styleShape(raw["shape"], func(_, _, dot string) string { return dot }); shape != "" {
+			node.attrs["style.shape"] = shape
+		}
+		for k, v := range raw {
+			if !dotIgnoredAttrs[k] {
+				node.attrs[k] = v
+			}
+		}
+		doc.nodes = append(doc.nodes, node)
+	}
+
+	for _, e := range p.edges {
+		edge := &graphImportEdge{source: e.source, target: e.target, attrs: make(map[string]string)}
+		raw := e.attrs
+		if dir, ok := raw["dir"]; ok {
+			switch dir {
+			case "forward":
+				edge.attrs["direction"] = domain.DirectionForward
+			case "back":
+				edge.attrs["direction"] = domain.DirectionBackward
+			case "both", "none":
+				edge.attrs["direction"] = domain.DirectionBidirectional
+			}
+		}
+		if color := raw["color"]; color != "" {
+			edge.attrs["style.color"] = color
+		}
+		if width := raw["penwidth"]; width != "" {
+			edge.attrs["style.width"] = width
+		}
+		if style := raw["style"]; style == "dashed" || style == "dotted" || style == "solid" {
+			edge.attrs["style.style"] = style
+		}
+		if cost := raw["cost"]; cost != "" {
+			edge.attrs["weight"] = cost
+		} else if weight := raw["weight"]; weight != "" {
+			edge.attrs["weight"] = weight
+		}
+		for k, v := range raw {
+			if !dotIgnoredAttrs[k] {
+				edge.attrs[k] = v
+			}
+		}
+		doc.edges = append(doc.edges, edge)
+	}
+	return doc
+}
+
+// parseDOTPoint 解析 "x,y" 或 "x,y!"（也接受 "x,y,z"）
+func parseDOTPoint(pos string) (domain.Position, bool) {
+	parts := strings.Split(strings.TrimSuffix(strings.TrimSpace(pos), "!"), ",")
+	if len(parts) < 2 || len(parts) > 3 {
+		return domain.Position{}, false
+	}
+	var values [3]float64
+	for i, part := range parts {
+		v, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+		if err != nil {
+			return domain.Position{}, false
+		}
+		values[i] = v
+	}
+	return domain.Position{X: values[0], Y: values[1], Z: values[2]}, true
+}
@@ -0,0 +1,490 @@
+// Package services 图格式导入导出服务
+//
+// 设计参考：
+// - GraphML（yEd 图形扩展 y:ShapeNode/y:PolyLineEdge）
+// - GEXF 1.3（Gephi 的 viz 扩展：位置、颜色、大小、形状）
+// - Graphviz DOT（pos 属性配合 neato -n 保持坐标）
+//
+// 特点：
+// 1. 节点和路径的属性与表格导出使用同一套列键（position.x、style.color、properties.<key>）
+// 2. 坐标按 scale 缩放，可翻转Y轴，以适配各工具的坐标系
+// 3. GraphML 和 DOT 可导入为节点和路径，没有坐标的图按网格排布
+package services
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"robot-path-editor/internal/domain"
+	"robot-path-editor/internal/repositories"
+)
+
+// GraphService 图格式导入导出服务接口
+type GraphService interface {
+	ExportGraph(ctx context.Context, opts GraphExportOptions) (*GraphFile, error)
+	ImportGraph(ctx context.Context, data []byte, opts GraphImportOptions) (*GraphImportResult, error)
+}
+
+// 图格式
+const (
+	GraphFormatGraphML = "graphml"
+	GraphFormatGEXF    = "gexf"
+	GraphFormatDOT     = "dot"
+)
+
+// GraphExportOptions 导出选项
+type GraphExportOptions struct {
+	Format string  `json:"format,omitempty" form:"format"` // graphml（默认）、gexf 或 dot
+	Name   string  `json:"name,omitempty" form:"name"`
+	Scale  float64 `json:"scale,omitempty" form:"scale"`   // 编辑器坐标到输出坐标的比例，默认1（DOT 中为点）
+	FlipY  bool    `json:"flip_y,omitempty" form:"flip_y"` // 输出坐标系Y轴与编辑器相反时翻转
+}
+
+// GraphImportOptions 导入选项
+type GraphImportOptions struct {
+	Format   string  `json:"format,omitempty" form:"format"` // graphml 或 dot，默认按内容识别
+	Scale    float64 `json:"scale,omitempty" form:"scale"`   // 与导出相同的比例，导入时编辑器坐标 = 输入坐标 ÷ scale
+	FlipY    bool    `json:"flip_y,omitempty" form:"flip_y"`
+	NodeType string  `json:"node_type,omitempty" form:"node_type"` // 未指定类型的节点，默认 point
+	Spacing  float64 `json:"spacing,omitempty" form:"spacing"`     // 没有坐标的节点按网格排布的间距，默认100
+}
+
+// GraphFile 导出的图文件
+type GraphFile struct {
+	Filename    string `json:"filename"`
+	Format      string `json:"format"`
+	ContentType string `json:"content_type"`
+	Nodes       int    `json:"nodes"`
+	Paths       int    `json:"paths"`
+	Content     string `json:"content"`
+}
+
+// GraphImportResult 导入结果
+type GraphImportResult struct {
+	Format       string                   `json:"format"`
+	NodesCreated int                      `json:"nodes_created"`
+	PathsCreated int                      `json:"paths_created"`
+	NodeIDs      map[string]domain.NodeID `json:"node_ids"` // 源文件节点ID → 新节点ID
+	Warnings     []string                 `json:"warnings,omitempty"`
+}
+
+// graphService 图格式服务实现
+type graphService struct {
+	nodeRepo repositories.NodeRepository
+	pathRepo repositories.PathRepository
+}
+
+// NewGraphService 创建新的图格式服务实例
+func NewGraphService(nodeRepo repositories.NodeRepository, pathRepo repositories.PathRepository) GraphService {
+	return &graphService{
+		nodeRepo: nodeRepo,
+		pathRepo: pathRepo,
+	}
+}
+
+// graphTransform 编辑器坐标与输出坐标的换算
+type graphTransform struct {
+	scale float64
+	flipY bool
+}
+
+func newGraphTransform(scale float64, flipY bool) graphTransform {
+	if scale <= 0 {
+		scale = 1
+	}
+	return graphTransform{scale: scale, flipY: flipY}
+}
+
+// to 编辑器坐标 → 输出坐标
+func (t graphTransform) to(p domain.Position) domain.Position {
+	out := domain.Position{X: p.X * t.scale, Y: p.Y * t.scale, Z: p.Z * t.scale}
+	if t.flipY {
+		out.Y = -out.Y
+	}
+	return out
+}
+
+// from 输入坐标 → 编辑器坐标
+func (t graphTransform) from(p domain.Position) domain.Position {
+	if t.flipY {
+		p.Y = -p.Y
+	}
+	return domain.Position{X: p.X / t.scale, Y: p.Y / t.scale, Z: p.Z / t.scale}
+}
+
+// === 导出 ===
+
+// graphExport 导出所需的数据
+type graphExport struct {
+	name        string
+	nodes       []*domain.Node
+	paths       []*domain.Path
+	nodeByID    map[domain.NodeID]*domain.Node
+	nodeColumns []tableColumn[*domain.Node]
+	pathColumns []tableColumn[*domain.Path]
+	transform   graphTransform
+}
+
+// graphDirection 路径在导出格式中的方向，与 CanTraverse 一致：bidirectional、forward 或 backward
+func graphDirection(path *domain.Path) string {
+	direction, _ := domain.NormalizeDirection(path.Direction)
+	return direction
+}
+
+// ExportGraph 将全部节点和路径导出为 GraphML、GEXF 或 DOT
+func (s *graphService) ExportGraph(ctx context.Context, opts GraphExportOptions) (*GraphFile, error) {
+	format := strings.ToLower(opts.Format)
+	if format == "" {
+		format = GraphFormatGraphML
+	}
+
+	nodes, err := s.nodeRepo.List(ctx, repositories.NodeFilter{})
+	if err != nil {
+		return nil, fmt.Errorf("获取节点列表失败: %w", err)
+	}
+	paths, err := s.pathRepo.List(ctx, repositories.PathFilter{})
+	if err != nil {
+		return nil, fmt.Errorf("获取路径列表失败: %w", err)
+	}
+	sort.SliceStable(nodes, func(i, j int) bool { return nodes[i].Name < nodes[j].Name })
+	sort.SliceStable(paths, func(i, j int) bool { return paths[i].Name < paths[j].Name })
+
+	g := &graphExport{
+		name:      opts.Name,
+		nodes:     nodes,
+		nodeByID:  make(map[domain.NodeID]*domain.Node, len(nodes)),
+		transform: newGraphTransform(opts.Scale, opts.FlipY),
+	}
+	if g.name == "" {
+		g.name = "layout"
+	}
+	for _, node := range nodes {
+		g.nodeByID[node.ID] = node
+	}
+	for _, path := range paths {
+		if g.nodeByID[path.StartNodeID] != nil && g.nodeByID[path.EndNodeID] != nil {
+			g.paths = append(g.paths, path)
+		}
+	}
+	g.nodeColumns = graphNodeColumns(nodes)
+	g.pathColumns = graphPathColumns(g.paths)
+
+	var buf bytes.Buffer
+	file := &GraphFile{Format: format, Nodes: len(g.nodes), Paths: len(g.paths)}
+	switch format {
+	case GraphFormatGraphML:
+		writeGraphML(&buf, g)
+		file.ContentType = "application/graphml+xml"
+	case GraphFormatGEXF:
+		writeGEXF(&buf, g)
+		file.ContentType = "application/gexf+xml"
+	case GraphFormatDOT:
+		writeDOT(&buf, g)
+		file.ContentType = "text/vnd.graphviz"
+	default:
+		return nil, fmt.Errorf("不支持的图格式: %s", opts.Format)
+	}
+	file.Filename = g.name + "." + format
+	file.Content = buf.String()
+	return file, nil
+}
+
+// graphNodeColumns 导出的节点属性：表格列中可写的列（不含ID）和全部扩展属性
+func graphNodeColumns(nodes []*domain.Node) []tableColumn[*domain.Node] {
+	var columns []tableColumn[*domain.Node]
+	for _, column := range nodeTableColumns() {
+		if column.key != "id" && !column.readOnly {
+			columns = append(columns, column)
+		}
+	}
+	for _, key := range selectPropertyKeys([]string{"*"}, nodes, func(n *domain.Node) map[string]interface{} { return n.Properties }) {
+		columns = append(columns, propertyColumn(key, func(n *domain.Node) *map[string]interface{} { return &n.Properties }))
+	}
+	return columns
+}
+
+// graphPathColumns 导出的路径属性，起止节点由边的端点表示
+func graphPathColumns(paths []*domain.Path) []tableColumn[*domain.Path] {
+	var columns []tableColumn[*domain.Path]
+	for _, column := range pathTableColumns(nil) {
+		switch {
+		case column.readOnly, column.key == "id", column.key == "start_node_id", column.key == "end_node_id":
+		default:
+			columns = append(columns, column)
+		}
+	}
+	for _, key := range selectPropertyKeys([]string{"*"}, paths, func(p *domain.Path) map[string]interface{} { return p.Properties }) {
+		columns = append(columns, propertyColumn(key, func(p *domain.Path) *map[string]interface{} { return &p.Properties }))
+	}
+	return columns
+}
+
+// pathGeometry 路径采样后的中间点（不含端点），用于各工具中的折线显示
+func (g *graphExport) pathGeometry(p *domain.Path) []domain.Position {
+	controls := p.ControlPoints(g.nodeByID[p.StartNodeID].Position, g.nodeByID[p.EndNodeID].Position)
+	points := controls
+	if p.CurveType != domain.CurveTypeLinear && p.CurveType != "" {
+		points = domain.SampleCurve(controls, p.CurveType, 16)
+	}
+	if len(points) <= 2 {
+		return nil
+	}
+	return points[1 : len(points)-1]
+}
+
+// graphNodeDiameter 节点符号直径（编辑器单位），样式大小为半径
+func graphNodeDiameter(n *domain.Node) float64 {
+	if n.Style.Size > 0 {
+		return n.Style.Size * 2
+	}
+	return 20
+}
+
+// parseHexColor 解析 #rrggbb 颜色
+func parseHexColor(color string) (r, g, b int, ok bool) {
+	if len(color) != 7 || color[0] != '#' {
+		return 0, 0, 0, false
+	}
+	if _, err := fmt.Sscanf(color[1:], "%02x%02x%02x", &r, &g, &b); err != nil {
+		return 0, 0, 0, false
+	}
+	return r, g, b, true
+}
+
+// graphCoord 输出坐标保留6位小数，避免翻转后出现 -0
+func graphCoord(v float64) string {
+	if v = roundNav2(v); v == 0 {
+		v = 0
+	}
+	return formatTableFloat(v)
+}
+
+// === 导入 ===
+
+// graphImportDoc 解析得到的图，坐标为源文件坐标
+type graphImportDoc struct {
+	directed bool
+	nodes    []*graphImportNode
+	edges    []*graphImportEdge
+	warnings []string
+}
+
+// graphImportNode 源文件中的节点，attrs 的键为列键或扩展属性名
+type graphImportNode struct {
+	id       string
+	attrs    map[string]string
+	position *domain.Position
+}
+
+// graphImportEdge 源文件中的边，directed 为空时取图的默认方向
+type graphImportEdge struct {
+	id       string
+	source   string
+	target   string
+	directed *bool
+	attrs    map[string]string
+	points   []domain.Position
+}
+
+// graphAttrAliases 各工具常用的属性名到列键的映射
+var graphAttrAliases = map[string]string{
+	"label":       "name",
+	"description": "properties.description",
+}
+
+// ImportGraph 导入 GraphML 或 DOT 图
+func (s *graphService) ImportGraph(ctx context.Context, data []byte, opts GraphImportOptions) (*GraphImportResult, error) {
+	format := strings.ToLower(opts.Format)
+	if format == "" {
+		format = detectGraphFormat(data)
+	}
+
+	var doc *graphImportDoc
+	var err error
+	switch format {
+	case GraphFormatGraphML:
+		doc, err = parseGraphML(data)
+	case GraphFormatDOT:
+		doc, err = parseDOT(data)
+	case GraphFormatGEXF:
+		return nil, fmt.Errorf("暂不支持导入GEXF，请在 Gephi 中另存为 GraphML")
+	default:
+		return nil, fmt.Errorf("不支持的图格式: %s", opts.Format)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	transform := newGraphTransform(opts.Scale, opts.FlipY)
+	nodeType := opts.NodeType
+	if nodeType == "" {
+		nodeType = string(domain.NodeTypePoint)
+	}
+	spacing := opts.Spacing
+	if spacing <= 0 {
+		spacing = 100
+	}
+	result := &GraphImportResult{Format: format, NodeIDs: make(map[string]domain.NodeID), Warnings: doc.warnings}
+	warn := func(format string, args ...interface{}) {
+		result.Warnings = append(result.Warnings, fmt.Sprintf(format, args...))
+	}
+
+	// 节点：没有坐标的节点排布在已有坐标节点的下方
+	nodeColumns := nodeTableColumns()
+	nodes := make(map[string]*domain.Node, len(doc.nodes))
+	ordered := make([]*domain.Node, 0, len(doc.nodes))
+	var unplaced []*domain.Node
+	minX, maxY := math.Inf(1), math.Inf(-1)
+	for _, source := range doc.nodes {
+		if _, exists := nodes[source.id]; exists {
+			return nil, fmt.Errorf("节点ID重复: %s", source.id)
+		}
+
+		node := domain.NewNode(source.id, nodeType)
+		if source.position != nil {
+			node.Position = transform.from(*source.position)
+		}
+		applyGraphAttrs(node, source.attrs, nodeColumns, func(n *domain.Node) *map[string]interface{} { return &n.Properties },
+			func(key string, err error) { warn("节点 %s 的属性 %s 无效: %v", source.id, key, err) })
+		if err := node.IsValid(); err != nil {
+			node.Name = source.id
+		}
+
+		if source.position == nil && (source.attrs["position.x"] == "" || source.attrs["position.y"] == "") {
+			unplaced = append(unplaced, node)
+		} else {
+			minX, maxY = math.Min(minX, node.Position.X), math.Max(maxY, node.Position.Y)
+		}
+		nodes[source.id] = node
+		ordered = append(ordered, node)
+		result.NodeIDs[source.id] = node.ID
+	}
+	if len(unplaced) > 0 {
+		origin := domain.Position{}
+		if !math.IsInf(minX, 1) {
+			origin = domain.Position{X: minX, Y: maxY + spacing}
+		}
+		perRow := int(math.Ceil(math.Sqrt(float64(len(unplaced)))))
+		for i, node := range unplaced {
+			node.Position = domain.Position{X: origin.X + float64(i%perRow)*spacing, Y: origin.Y + float64(i/perRow)*spacing}
+		}
+		warn("%d 个节点没有坐标，已按网格排布", len(unplaced))
+	}
+	for _, node := range ordered {
+		if err := s.nodeRepo.Create(ctx, node); err != nil {
+			return nil, fmt.Errorf("创建节点 %s 失败: %w", node.Name, err)
+		}
+		result.NodesCreated++
+	}
+
+	// 路径
+	pathColumns := pathTableColumns(nil)
+	for i, edge := range doc.edges {
+		start, end := nodes[edge.source], nodes[edge.target]
+		if start == nil || end == nil {
+			warn("边 %s 引用了不存在的节点，已跳过", edge.source+" → "+edge.target)
+			continue
+		}
+
+		path := domain.NewPath(start.Name+"-"+end.Name, start.ID, end.ID)
+		directed := doc.directed
+		if edge.directed != nil {
+			directed = *edge.directed
+		}
+		if directed {
+			path.Direction = domain.DirectionForward
+		}
+		for _, point := range edge.points {
+			path.Waypoints = append(path.Waypoints, transform.from(point))
+		}
+		applyGraphAttrs(path, edge.attrs, pathColumns, func(p *domain.Path) *map[string]interface{} { return &p.Properties },
+			func(key string, err error) { warn("第%d条边的属性 %s 无效: %v", i+1, key, err) })
+		if path.Name == "" {
+			path.Name = start.Name + "-" + end.Name
+		}
+
+		if path.Length == 0 {
+			controls := path.ControlPoints(start.Position, end.Position)
+			path.Length = roundNav2(domain.PolylineLength(domain.SampleCurve(controls, path.CurveType, 16)))
+		}
+		if path.Weight == 0 {
+			path.Weight = path.Length
+		}
+		if err := s.pathRepo.Create(ctx, path); err != nil {
+			return nil, fmt.Errorf("创建路径 %s 失败: %w", path.Name, err)
+		}
+		result.PathsCreated++
+	}
+
+	return result, nil
+}
+
+// applyGraphAttrs 按列键写入属性，未知的属性写入扩展属性
+func applyGraphAttrs[T any](target T, attrs map[string]string, columns []tableColumn[T],
+	props func(T) *map[string]interface{}, invalid func(key string, err error)) {
+	byKey := make(map[string]tableColumn[T], len(columns))
+	for _, column := range columns {
+		switch {
+		case column.readOnly, column.key == "id", column.key == "start_node_id", column.key == "end_node_id":
+		default:
+			byKey[column.key] = column
+		}
+	}
+
+	keys := make([]string, 0, len(attrs))
+	for key := range attrs {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		value := attrs[key]
+		if alias, ok := graphAttrAliases[key]; ok {
+			if _, explicit := attrs[alias]; explicit {
+				continue
+			}
+			key = alias
+		}
+
+		column, ok := byKey[key]
+		if !ok {
+			if isReservedGraphAttr(key, columns) {
+				continue
+			}
+			column = propertyColumn(strings.TrimPrefix(key, tablePropertyPrefix), props)
+		}
+		if err := column.set(target, value); err != nil {
+			invalid(key, err)
+		}
+	}
+}
+
+// isReservedGraphAttr 只读列和ID列不作为扩展属性导入
+func isReservedGraphAttr[T any](key string, columns []tableColumn[T]) bool {
+	for _, column := range columns {
+		if column.key == key {
+			return true
+		}
+	}
+	return false
+}
+
+// detectGraphFormat 按内容识别：XML 为 GraphML（根元素为 gexf 时为 GEXF），否则为 DOT
+func detectGraphFormat(data []byte) string {
+	trimmed := bytes.TrimSpace(bytes.TrimPrefix(data, []byte("\ufeff")))
+	if !bytes.HasPrefix(trimmed, []byte("<")) {
+		return GraphFormatDOT
+	}
+	head := trimmed
+	if len(head) > 1024 {
+		head = head[:1024]
+	}
+	if bytes.Contains(head, []byte("<gexf")) {
+		return GraphFormatGEXF
+	}
+	return GraphFormatGraphML
+}
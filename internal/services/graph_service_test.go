@@ -0,0 +1,66 @@
+package services
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	"robot-path-editor/internal/domain"
+)
+
+func TestGraphExportDirection(t *testing.T) {
+	tests := []struct {
+		direction    string
+		wantDOT      string
+		wantGEXFType string
+		wantReversed bool
+		wantArrows   string
+	}{
+		{direction: "", wantDOT: "dir=both", wantGEXFType: "undirected", wantArrows: `source="none" target="none"`},
+		{direction: domain.DirectionBidirectional, wantDOT: "dir=both", wantGEXFType: "undirected", wantArrows: `source="none" target="none"`},
+		{direction: domain.DirectionForward, wantDOT: "dir=forward", wantGEXFType: "directed", wantArrows: `source="none" target="standard"`},
+		{direction: domain.DirectionOneWay, wantDOT: "dir=forward", wantGEXFType: "directed", wantArrows: `source="none" target="standard"`},
+		{direction: domain.DirectionUnidirectional, wantDOT: "dir=forward", wantGEXFType: "directed", wantArrows: `source="none" target="standard"`},
+		{direction: domain.DirectionBackward, wantDOT: "dir=back", wantGEXFType: "directed", wantReversed: true, wantArrows: `source="standard" target="none"`},
+		{direction: domain.DirectionReverse, wantDOT: "dir=back", wantGEXFType: "directed", wantReversed: true, wantArrows: `source="standard" target="none"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.direction, func(t *testing.T) {
+			a := domain.NewNode("A", string(domain.NodeTypeStation))
+			b := domain.NewNode("B", string(domain.NodeTypeStation))
+			path := domain.NewPath("AB", a.ID, b.ID)
+			path.Direction = tt.direction
+			g := &graphExport{
+				nodes:     []*domain.Node{a, b},
+				paths:     []*domain.Path{path},
+				nodeByID:  map[domain.NodeID]*domain.Node{a.ID: a, b.ID: b},
+				transform: newGraphTransform(1, false),
+			}
+
+			var dot bytes.Buffer
+			writeDOT(&dot, g)
+			if !strings.Contains(dot.String(), tt.wantDOT) {
+				t.Errorf("writeDOT() missing %s:\n%s", tt.wantDOT, dot.String())
+			}
+
+			var gexf bytes.Buffer
+			writeGEXF(&gexf, g)
+			source, target := a.ID, b.ID
+			if tt.wantReversed {
+				source, target = target, source
+			}
+			wantEdge := fmt.Sprintf(`source="%s" target="%s" label="AB" type="%s"`, source, target, tt.wantGEXFType)
+			if !strings.Contains(gexf.String(), wantEdge) {
+				t.Errorf("writeGEXF() missing %s:\n%s", wantEdge, gexf.String())
+			}
+
+			var graphml bytes.Buffer
+			writeGraphML(&graphml, g)
+			if !strings.Contains(graphml.String(), tt.wantArrows) {
+				t.Errorf("writeGraphML() missing arrows %s:\n%s", tt.wantArrows, graphml.String())
+			}
+		})
+	}
+}
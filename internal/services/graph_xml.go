@@ -0,0 +1,561 @@
+// Package services GraphML 与 GEXF 读写
+//
+// - GraphML 写出属性键和 yEd 图形扩展，yEd 打开即可按原坐标显示
+// - GEXF 写出 1.3 版本的属性和 viz 扩展，供 Gephi 分析
+// - GraphML 导入识别 attr.name 属性、yEd 图形（位置、标签、颜色、折线点）以及 Gephi 的 x/y/r/g/b 属性
+package services
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"robot-path-editor/internal/domain"
+)
+
+// xmlEscape 转义 XML 文本和属性值
+func xmlEscape(s string) string {
+	var b strings.Builder
+	_ = xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
+
+// 样式形状与各工具形状名的对应
+var graphShapes = []struct {
+	style, yed, gexf, dot string
+}{
+	{"circle", "ellipse", "disc", "circle"},
+	{"square", "rectangle", "square", "box"},
+	{"rectangle", "rectangle", "square", "box"},
+	{"triangle", "triangle", "triangle", "triangle"},
+	{"diamond", "diamond", "diamond", "diamond"},
+}
+
+// graphShape 按样式形状查找工具中的形状名，pick 选择对应工具的列
+func graphShape(style string, pick func(yed, gexf, dot string) string) string {
+	for _, s := range graphShapes {
+		if s.style == style {
+			return pick(s.yed, s.gexf, s.dot)
+		}
+	}
+	return pick(graphShapes[0].yed, graphShapes[0].gexf, graphShapes[0].dot)
+}
+
+// styleShape 由工具中的形状名反查样式形状
+func styleShape(shape string, pick func(yed, gexf, dot string) string) string {
+	for _, s := range graphShapes {
+		if strings.EqualFold(pick(s.yed, s.gexf, s.dot), shape) {
+			return s.style
+		}
+	}
+	return ""
+}
+
+// === GraphML 写出 ===
+
+func writeGraphML(buf *bytes.Buffer, g *graphExport) {
+	buf.WriteString(`<?xml version="1.0" encoding="UTF-8"?>` + "\n")
+	buf.WriteString(`<graphml xmlns="http://graphml.graphdrawing.org/xmlns" xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance"` +
+		` xmlns:y="http://www.yworks.com/xml/graphml"` +
+		` xsi:schemaLocation="http://graphml.graphdrawing.org/xmlns http://www.yworks.com/xml/schema/graphml/1.1/ygraphml.xsd">` + "\n")
+
+	for i, column := range g.nodeColumns {
+		fmt.Fprintf(buf, "  <key id=\"n%d\" for=\"node\" attr.name=\"%s\" attr.type=\"%s\"/>\n", i, xmlEscape(column.key), graphMLType(column.numeric))
+	}
+	for i, column := range g.pathColumns {
+		fmt.Fprintf(buf, "  <key id=\"e%d\" for=\"edge\" attr.name=\"%s\" attr.type=\"%s\"/>\n", i, xmlEscape(column.key), graphMLType(column.numeric))
+	}
+	buf.WriteString("  <key id=\"ng\" for=\"node\" yfiles.type=\"nodegraphics\"/>\n")
+	buf.WriteString("  <key id=\"eg\" for=\"edge\" yfiles.type=\"edgegraphics\"/>\n")
+
+	fmt.Fprintf(buf, "  <graph id=\"%s\" edgedefault=\"directed\">\n", xmlEscape(g.name))
+	for _, node := range g.nodes {
+		fmt.Fprintf(buf, "    <node id=\"%s\">\n", xmlEscape(string(node.ID)))
+		for i, column := range g.nodeColumns {
+			if v := column.get(node); v != "" {
+				fmt.Fprintf(buf, "      <data key=\"n%d\">%s</data>\n", i, xmlEscape(v))
+			}
+		}
+
+		center := g.transform.to(node.Position)
+		size := graphNodeDiameter(node) * g.transform.scale
+		shape := graphShape(node.Style.Shape, func(yed, _, _ string) string { return yed })
+		fmt.Fprintf(buf, "      <data key=\"ng\"><y:ShapeNode>"+
+			"<y:Geometry x=\"%s\" y=\"%s\" width=\"%s\" height=\"%s\"/>"+
+			"<y:Fill color=\"%s\" transparent=\"false\"/>"+
+			"<y:BorderStyle color=\"%s\" type=\"line\" width=\"%s\"/>"+
+			"<y:NodeLabel>%s</y:NodeLabel><y:Shape type=\"%s\"/></y:ShapeNode></data>\n",
+			graphCoord(center.X-size/2), graphCoord(center.Y-size/2), graphCoord(size), graphCoord(size),
+			xmlEscape(node.Style.Color), xmlEscape(node.Style.BorderColor), graphCoord(node.Style.BorderWidth),
+			xmlEscape(node.Name), shape)
+		buf.WriteString("    </node>\n")
+	}
+
+	for _, path := range g.paths {
+		direction := graphDirection(path)
+		directed := ""
+		if direction == domain.DirectionBidirectional {
+			directed = ` directed="false"`
+		}
+		fmt.Fprintf(buf, "    <edge id=\"%s\" source=\"%s\" target=\"%s\"%s>\n",
+			xmlEscape(string(path.ID)), xmlEscape(string(path.StartNodeID)), xmlEscape(string(path.EndNodeID)), directed)
+		for i, column := range g.pathColumns {
+			if v := column.get(path); v != "" {
+				fmt.Fprintf(buf, "      <data key=\"e%d\">%s</data>\n", i, xmlEscape(v))
+			}
+		}
+
+		buf.WriteString("      <data key=\"eg\"><y:PolyLineEdge><y:Path sx=\"0\" sy=\"0\" tx=\"0\" ty=\"0\">")
+		for _, point := range g.pathGeometry(path) {
+			p := g.transform.to(point)
+			fmt.Fprintf(buf, "<y:Point x=\"%s\" y=\"%s\"/>", graphCoord(p.X), graphCoord(p.Y))
+		}
+		sourceArrow, targetArrow := "none", "none"
+		switch direction {
+		case domain.DirectionForward:
+			targetArrow = "standard"
+		case domain.DirectionBackward:
+			sourceArrow = "standard"
+		}
+		fmt.Fprintf(buf, "</y:Path><y:LineStyle color=\"%s\" type=\"%s\" width=\"%s\"/>"+
+			"<y:Arrows source=\"%s\" target=\"%s\"/><y:EdgeLabel>%s</y:EdgeLabel></y:PolyLineEdge></data>\n",
+			xmlEscape(path.Style.Color), yEdLineType(path.Style.Style), graphCoord(path.Style.Width),
+			sourceArrow, targetArrow, xmlEscape(path.Name))
+		buf.WriteString("    </edge>\n")
+	}
+	buf.WriteString("  </graph>\n</graphml>\n")
+}
+
+func graphMLType(numeric bool) string {
+	if numeric {
+		return "double"
+	}
+	return "string"
+}
+
+// yEdLineType 线型对应 yEd 的 LineStyle type
+func yEdLineType(style string) string {
+	switch style {
+	case "dashed":
+		return "dashed"
+	case "dotted":
+		return "dotted"
+	}
+	return "line"
+}
+
+// === GEXF 写出 ===
+
+func writeGEXF(buf *bytes.Buffer, g *graphExport) {
+	buf.WriteString(`<?xml version="1.0" encoding="UTF-8"?>` + "\n")
+	buf.WriteString(`<gexf xmlns="http://gexf.net/1.3" xmlns:viz="http://gexf.net/1.3/viz" version="1.3">` + "\n")
+	fmt.Fprintf(buf, "  <meta lastmodifieddate=\"%s\">\n    <creator>robot-path-editor</creator>\n    <description>%s</description>\n  </meta>\n",
+		time.Now().Format("2006-01-02"), xmlEscape(g.name))
+	buf.WriteString("  <graph defaultedgetype=\"directed\" mode=\"static\">\n")
+
+	writeGEXFAttributes(buf, "node", len(g.nodeColumns), func(i int) (string, bool) { return g.nodeColumns[i].key, g.nodeColumns[i].numeric })
+	writeGEXFAttributes(buf, "edge", len(g.pathColumns), func(i int) (string, bool) { return g.pathColumns[i].key, g.pathColumns[i].numeric })
+
+	buf.WriteString("    <nodes>\n")
+	for _, node := range g.nodes {
+		fmt.Fprintf(buf, "      <node id=\"%s\" label=\"%s\">\n", xmlEscape(string(node.ID)), xmlEscape(node.Name))
+		writeGEXFValues(buf, len(g.nodeColumns), func(i int) string { return g.nodeColumns[i].get(node) })
+		writeGEXFColor(buf, node.Style.Color, node.Style.Opacity)
+		p := g.transform.to(node.Position)
+		fmt.Fprintf(buf, "        <viz:position x=\"%s\" y=\"%s\" z=\"%s\"/>\n", graphCoord(p.X), graphCoord(p.Y), graphCoord(p.Z))
+		fmt.Fprintf(buf, "        <viz:size value=\"%s\"/>\n", graphCoord(graphNodeDiameter(node)/2*g.transform.scale))
+		fmt.Fprintf(buf, "        <viz:shape value=\"%s\"/>\n", graphShape(node.Style.Shape, func(_, gexf, _ string) string { return gexf }))
+		buf.WriteString("      </node>\n")
+	}
+	buf.WriteString("    </nodes>\n    <edges>\n")
+	for _, path := range g.paths {
+		source, target := path.StartNodeID, path.EndNodeID
+		edgeType := "directed"
+		switch graphDirection(path) {
+		case domain.DirectionBackward:
+			source, target = target, source
+		case domain.DirectionBidirectional:
+			edgeType = "undirected"
+		}
+		fmt.Fprintf(buf, "      <edge id=\"%s\" source=\"%s\" target=\"%s\" label=\"%s\" type=\"%s\" weight=\"%s\">\n",
+			xmlEscape(string(path.ID)), xmlEscape(string(source)), xmlEscape(string(target)), xmlEscape(path.Name), edgeType, graphCoord(path.Weight))
+		writeGEXFValues(buf, len(g.pathColumns), func(i int) string { return g.pathColumns[i].get(path) })
+		writeGEXFColor(buf, path.Style.Color, path.Style.Opacity)
+		fmt.Fprintf(buf, "        <viz:thickness value=\"%s\"/>\n", graphCoord(path.Style.Width))
+		if path.Style.Style == "dashed" || path.Style.Style == "dotted" {
+			fmt.Fprintf(buf, "        <viz:shape value=\"%s\"/>\n", path.Style.Style)
+		}
+		buf.WriteString("      </edge>\n")
+	}
+	buf.WriteString("    </edges>\n  </graph>\n</gexf>\n")
+}
+
+func writeGEXFAttributes(buf *bytes.Buffer, class string, n int, column func(i int) (string, bool)) {
+	if n == 0 {
+		return
+	}
+	fmt.Fprintf(buf, "    <attributes class=\"%s\">\n", class)
+	for i := 0; i < n; i++ {
+		key, numeric := column(i)
+		attrType := "string"
+		if numeric {
+			attrType = "double"
+		}
+		fmt.Fprintf(buf, "      <attribute id=\"%d\" title=\"%s\" type=\"%s\"/>\n", i, xmlEscape(key), attrType)
+	}
+	buf.WriteString("    </attributes>\n")
+}
+
+func writeGEXFValues(buf *bytes.Buffer, n int, value func(i int) string) {
+	var values []string
+	for i := 0; i < n; i++ {
+		if v := value(i); v != "" {
+			values = append(values, fmt.Sprintf("          <attvalue for=\"%d\" value=\"%s\"/>\n", i, xmlEscape(v)))
+		}
+	}
+	if len(values) == 0 {
+		return
+	}
+	buf.WriteString("        <attvalues>\n")
+	for _, v := range values {
+		buf.WriteString(v)
+	}
+	buf.WriteString("        </attvalues>\n")
+}
+
+func writeGEXFColor(buf *bytes.Buffer, color string, opacity float64) {
+	r, g, b, ok := parseHexColor(color)
+	if !ok {
+		return
+	}
+	if opacity <= 0 || opacity > 1 {
+		opacity = 1
+	}
+	fmt.Fprintf(buf, "        <viz:color r=\"%d\" g=\"%d\" b=\"%d\" a=\"%s\"/>\n", r, g, b, graphCoord(opacity))
+}
+
+// === GraphML 解析 ===
+
+type graphMLDocument struct {
+	Keys   []graphMLKey   `xml:"key"`
+	Graphs []graphMLGraph `xml:"graph"`
+}
+
+type graphMLKey struct {
+	ID      string `xml:"id,attr"`
+	For     string `xml:"for,attr"`
+	Name    string `xml:"attr.name,attr"`
+	YType   string `xml:"yfiles.type,attr"`
+	Default string `xml:"default"`
+}
+
+type graphMLGraph struct {
+	EdgeDefault string        `xml:"edgedefault,attr"`
+	Nodes       []graphMLNode `xml:"node"`
+	Edges       []graphMLEdge `xml:"edge"`
+}
+
+type graphMLNode struct {
+	ID    string        `xml:"id,attr"`
+	Data  []graphMLData `xml:"data"`
+	Graph *graphMLGraph `xml:"graph"`
+}
+
+type graphMLEdge struct {
+	ID       string        `xml:"id,attr"`
+	Source   string        `xml:"source,attr"`
+	Target   string        `xml:"target,attr"`
+	Directed string        `xml:"directed,attr"`
+	Data     []graphMLData `xml:"data"`
+}
+
+type graphMLData struct {
+	Key   string `xml:"key,attr"`
+	Inner []byte `xml:",innerxml"`
+}
+
+// text 数据的文本内容（去掉 CDATA 和子元素标记）
+func (d graphMLData) text() string {
+	decoder := xml.NewDecoder(bytes.NewReader(d.Inner))
+	var b strings.Builder
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			break
+		}
+		if c, ok := token.(xml.CharData); ok {
+			b.Write(c)
+		}
+	}
+	return strings.TrimSpace(b.String())
+}
+
+// graphMLReader GraphML 解析状态
+type graphMLReader struct {
+	doc       *graphImportDoc
+	nodeKeys  map[string]graphMLKey
+	edgeKeys  map[string]graphMLKey
+	groupSkip int
+}
+
+// parseGraphML 解析 GraphML，嵌套的子图（yEd 分组）展开为同一层
+func parseGraphML(data []byte) (*graphImportDoc, error) {
+	var document graphMLDocument
+	if err := xml.Unmarshal(data, &document); err != nil {
+		return nil, fmt.Errorf("解析GraphML失败: %w", err)
+	}
+	if len(document.Graphs) == 0 {
+		return nil, fmt.Errorf("GraphML中没有graph元素")
+	}
+
+	r := &graphMLReader{
+		doc:      &graphImportDoc{directed: document.Graphs[0].EdgeDefault != "undirected"},
+		nodeKeys: make(map[string]graphMLKey),
+		edgeKeys: make(map[string]graphMLKey),
+	}
+	for _, key := range document.Keys {
+		if key.For == "node" || key.For == "all" || key.For == "" {
+			r.nodeKeys[key.ID] = key
+		}
+		if key.For == "edge" || key.For == "all" || key.For == "" {
+			r.edgeKeys[key.ID] = key
+		}
+	}
+
+	for i := range document.Graphs {
+		r.readGraph(&document.Graphs[i], r.doc.directed)
+	}
+	if r.groupSkip > 0 {
+		r.doc.warnings = append(r.doc.warnings, fmt.Sprintf("%d 个分组节点已展开为其包含的节点", r.groupSkip))
+	}
+	return r.doc, nil
+}
+
+func (r *graphMLReader) readGraph(graph *graphMLGraph, directed bool) {
+	if graph.EdgeDefault != "" {
+		directed = graph.EdgeDefault != "undirected"
+	}
+	for i := range graph.Nodes {
+		node := &graph.Nodes[i]
+		if node.Graph != nil {
+			r.groupSkip++
+			r.readGraph(node.Graph, directed)
+			continue
+		}
+		r.doc.nodes = append(r.doc.nodes, r.readNode(node))
+	}
+	for i := range graph.Edges {
+		r.doc.edges = append(r.doc.edges, r.readEdge(&graph.Edges[i], directed))
+	}
+}
+
+func (r *graphMLReader) readNode(source *graphMLNode) *graphImportNode {
+	node := &graphImportNode{id: source.ID, attrs: make(map[string]string)}
+	values := graphMLValues(source.Data, r.nodeKeys, func(d graphMLData) { r.readNodeGraphics(node, d) })
+
+	// Gephi 导出的 GraphML 以 x、y、z 和 r、g、b 表示位置和颜色
+	var position domain.Position
+	hasPosition := false
+	for name, value := range values {
+		lower := strings.ToLower(name)
+		switch lower {
+		case "x", "y", "z":
+			if v, err := strconv.ParseFloat(value, 64); err == nil {
+				switch lower {
+				case "x":
+					position.X, hasPosition = v, true
+				case "y":
+					position.Y, hasPosition = v, true
+				default:
+					position.Z = v
+				}
+				continue
+			}
+		case "r", "g", "b":
+			continue
+		}
+		node.attrs[name] = value
+	}
+	if hasPosition && node.position == nil {
+		node.position = &position
+	}
+	if r, g, b, ok := graphMLRGB(values); ok {
+		if _, exists := node.attrs["style.color"]; !exists {
+			node.attrs["style.color"] = fmt.Sprintf("#%02x%02x%02x", r, g, b)
+		}
+	}
+	return node
+}
+
+func (r *graphMLReader) readEdge(source *graphMLEdge, directed bool) *graphImportEdge {
+	edge := &graphImportEdge{id: source.ID, source: source.Source, target: source.Target, attrs: make(map[string]string)}
+	if source.Directed == "true" || source.Directed == "false" {
+		explicit := source.Directed == "true"
+		edge.directed = &explicit
+	}
+	values := graphMLValues(source.Data, r.edgeKeys, func(d graphMLData) { r.readEdgeGraphics(edge, directed, d) })
+	for name, value := range values {
+		if name == "Edge Label" {
+			name = "label"
+		}
+		edge.attrs[name] = value
+	}
+	if _, hasWaypoints := edge.attrs["waypoints"]; hasWaypoints {
+		edge.points = nil
+	}
+	return edge
+}
+
+// graphMLValues 按 attr.name 汇总数据（缺省值先填入），yEd 图形数据交给 graphics 处理
+func graphMLValues(data []graphMLData, keys map[string]graphMLKey, graphics func(graphMLData)) map[string]string {
+	values := make(map[string]string)
+	for _, key := range keys {
+		if key.Default != "" && key.YType == "" {
+			values[graphMLKeyName(key)] = strings.TrimSpace(key.Default)
+		}
+	}
+	for _, d := range data {
+		key, ok := keys[d.Key]
+		switch {
+		case ok && key.YType != "":
+			graphics(d)
+		case ok:
+			values[graphMLKeyName(key)] = d.text()
+		default:
+			values[d.Key] = d.text()
+		}
+	}
+	return values
+}
+
+func graphMLKeyName(key graphMLKey) string {
+	if key.Name != "" {
+		return key.Name
+	}
+	return key.ID
+}
+
+// graphMLRGB Gephi 的 r、g、b 颜色属性
+func graphMLRGB(values map[string]string) (r, g, b int, ok bool) {
+	var rgb [3]int
+	for i, name := range []string{"r", "g", "b"} {
+		v, err := strconv.Atoi(values[name])
+		if err != nil {
+			return 0, 0, 0, false
+		}
+		rgb[i] = v
+	}
+	return rgb[0], rgb[1], rgb[2], true
+}
+
+// readNodeGraphics 读取 yEd 节点图形：几何中心、标签、填充和边框
+func (r *graphMLReader) readNodeGraphics(node *graphImportNode, data graphMLData) {
+	walkXML(data.Inner, func(element xml.StartElement, text func() string) {
+		switch element.Name.Local {
+		case "Geometry":
+			x, y := xmlFloatAttr(element, "x"), xmlFloatAttr(element, "y")
+			w, h := xmlFloatAttr(element, "width"), xmlFloatAttr(element, "height")
+			node.position = &domain.Position{X: x + w/2, Y: y + h/2}
+			if w > 0 && node.attrs["style.size"] == "" {
+				node.attrs["style.size"] = formatTableFloat(roundNav2(math.Min(w, h) / 2))
+			}
+		case "NodeLabel":
+			if label := text(); label != "" {
+				node.attrs["label"] = label
+			}
+		case "Fill":
+			if color := xmlAttr(element, "color"); color != "" {
+				node.attrs["style.color"] = color
+			}
+		case "BorderStyle":
+			if color := xmlAttr(element, "color"); color != "" {
+				node.attrs["style.border_color"] = color
+			}
+		case "Shape":
+			if shape := styleShape(xmlAttr(element, "type"), func(yed, _, _ string) string { return yed }); shape != "" {
+				node.attrs["style.shape"] = shape
+			}
+		}
+	})
+}
+
+// readEdgeGraphics 读取 yEd 边图形：折线点、标签、线型和箭头
+func (r *graphMLReader) readEdgeGraphics(edge *graphImportEdge, directed bool, data graphMLData) {
+	sourceArrow, targetArrow := "", ""
+	walkXML(data.Inner, func(element xml.StartElement, text func() string) {
+		switch element.Name.Local {
+		case "Point":
+			edge.points = append(edge.points, domain.Position{X: xmlFloatAttr(element, "x"), Y: xmlFloatAttr(element, "y")})
+		case "EdgeLabel":
+			if label := text(); label != "" {
+				edge.attrs["label"] = label
+			}
+		case "LineStyle":
+			if color := xmlAttr(element, "color"); color != "" {
+				edge.attrs["style.color"] = color
+			}
+			if width := xmlAttr(element, "width"); width != "" {
+				edge.attrs["style.width"] = width
+			}
+			switch xmlAttr(element, "type") {
+			case "dashed", "dashed_dotted":
+				edge.attrs["style.style"] = "dashed"
+			case "dotted":
+				edge.attrs["style.style"] = "dotted"
+			}
+		case "Arrows":
+			sourceArrow, targetArrow = xmlAttr(element, "source"), xmlAttr(element, "target")
+		}
+	})
+
+	// 有向图中以箭头表示方向
+	if directed && edge.directed == nil && (sourceArrow != "" || targetArrow != "") {
+		hasSource, hasTarget := sourceArrow != "none", targetArrow != "none"
+		switch {
+		case hasSource && !hasTarget:
+			edge.attrs["direction"] = domain.DirectionBackward
+		case hasSource == hasTarget:
+			edge.attrs["direction"] = domain.DirectionBidirectional
+		}
+	}
+}
+
+// walkXML 遍历元素，text 读取当前元素的文本内容
+func walkXML(data []byte, visit func(element xml.StartElement, text func() string)) {
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return
+		}
+		if element, ok := token.(xml.StartElement); ok {
+			visit(element, func() string {
+				var content struct {
+					Text string `xml:",chardata"`
+				}
+				if err := decoder.DecodeElement(&content, &element); err != nil {
+					return ""
+				}
+				return strings.TrimSpace(content.Text)
+			})
+		}
+	}
+}
+
+func xmlAttr(element xml.StartElement, name string) string {
+	for _, attr := range element.Attr {
+		if attr.Name.Local == name {
+			return attr.Value
+		}
+	}
+	return ""
+}
+
+func xmlFloatAttr(element xml.StartElement, name string) float64 {
+	v, _ := strconv.ParseFloat(xmlAttr(element, name), 64)
+	return v
+}
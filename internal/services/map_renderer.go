@@ -0,0 +1,585 @@
+// Package services 地图渲染（SVG/PNG）
+//
+// 设计参考：
+// - 先将节点和路径按样式转换为像素坐标系下的场景（描边、形状、箭头、文字），再由 SVG 或光栅后端输出
+// - 视口使用世界坐标（编辑器坐标），等比缩放并在画布中居中；未指定视口时适配全部内容
+//
+// 特点：
+// - 节点按 NodeStyle 绘制形状、填充色、大小（半径）、边框和透明度
+// - 路径按 PathStyle 绘制颜色、线宽、实线/虚线/点线，曲线按 CurveType 采样，单向路径在中点绘制方向箭头
+// - 样式尺寸为编辑器单位，随缩放变化，并设有最小像素尺寸保证缩略图可见
+// - PNG 为纯 Go 抗锯齿光栅化；文字标签仅在 SVG 中输出
+package services
+
+import (
+	"bytes"
+	"fmt"
+	"image/color"
+	"image/png"
+	"math"
+	"strconv"
+	"strings"
+
+	"robot-path-editor/internal/domain"
+)
+
+// 渲染尺寸限制
+const (
+	renderDefaultWidth  = 800
+	renderDefaultHeight = 600
+	renderMaxSize       = 8192
+	renderCurveSegments = 32
+)
+
+// RenderOptions 渲染选项
+type RenderOptions struct {
+	Format     string  `json:"format,omitempty" form:"format"`         // svg（默认）或 png
+	Width      int     `json:"width,omitempty" form:"width"`           // 输出宽度（像素），默认800
+	Height     int     `json:"height,omitempty" form:"height"`         // 输出高度（像素），默认600
+	MinX       float64 `json:"min_x,omitempty" form:"min_x"`           // 视口（世界坐标），全为0时适配全部内容
+	MinY       float64 `json:"min_y,omitempty" form:"min_y"`           //
+	MaxX       float64 `json:"max_x,omitempty" form:"max_x"`           //
+	MaxY       float64 `json:"max_y,omitempty" form:"max_y"`           //
+	Padding    *int    `json:"padding,omitempty" form:"padding"`       // 适配内容时的边距（像素），默认20
+	Background string  `json:"background,omitempty" form:"background"` // 背景色，默认白色，none 为透明
+	Labels     bool    `json:"labels,omitempty" form:"labels"`         // 是否绘制节点名称（仅SVG）
+	FlipY      bool    `json:"flip_y,omitempty" form:"flip_y"`         // 世界坐标Y轴向上时翻转
+}
+
+// RenderedImage 渲染结果
+type RenderedImage struct {
+	Format      string `json:"format"`
+	ContentType string `json:"content_type"`
+	Width       int    `json:"width"`
+	Height      int    `json:"height"`
+	Data        []byte `json:"-"`
+}
+
+// normalize 校验并填充默认值
+func (o *RenderOptions) normalize() error {
+	o.Format = strings.ToLower(strings.TrimSpace(o.Format))
+	switch o.Format {
+	case "":
+		o.Format = "svg"
+	case "svg", "png":
+	default:
+		return fmt.Errorf("不支持的渲染格式: %s", o.Format)
+	}
+	if o.Width == 0 {
+		o.Width = renderDefaultWidth
+	}
+	if o.Height == 0 {
+		o.Height = renderDefaultHeight
+	}
+	if o.Width < 1 || o.Height < 1 || o.Width > renderMaxSize || o.Height > renderMaxSize {
+		return fmt.Errorf("渲染尺寸必须在 1-%d 像素之间", renderMaxSize)
+	}
+	if o.hasViewport() && (o.MaxX <= o.MinX || o.MaxY <= o.MinY) {
+		return fmt.Errorf("视口范围无效: max_x/max_y 必须大于 min_x/min_y")
+	}
+	if o.Padding == nil {
+		padding := 20
+		o.Padding = &padding
+	}
+	if *o.Padding < 0 || *o.Padding*2 >= min(o.Width, o.Height) {
+		return fmt.Errorf("边距超出画布范围")
+	}
+	return nil
+}
+
+func (o *RenderOptions) hasViewport() bool {
+	return o.MinX != 0 || o.MinY != 0 || o.MaxX != 0 || o.MaxY != 0
+}
+
+// === 场景 ===
+
+// renderStroke 折线描边
+type renderStroke struct {
+	points  []vec
+	color   color.NRGBA
+	width   float64
+	opacity float64
+	dash    []float64
+}
+
+// renderShape 填充形状，circle 为真时以 center/radius 描述，否则为多边形
+type renderShape struct {
+	circle      bool
+	center      vec
+	radius      float64
+	polygon     []vec
+	fill        color.NRGBA
+	border      color.NRGBA
+	borderWidth float64
+	opacity     float64
+}
+
+// renderLabel 文字标签
+type renderLabel struct {
+	pos  vec
+	text string
+	size float64
+}
+
+// renderScene 像素坐标系下的场景，按 strokes → arrows → shapes → labels 的顺序绘制
+type renderScene struct {
+	width, height int
+	background    color.NRGBA
+	strokes       []renderStroke
+	arrows        []renderShape
+	shapes        []renderShape
+	labels        []renderLabel
+}
+
+// renderView 世界坐标到像素坐标的变换
+type renderView struct {
+	scale, offsetX, offsetY float64
+	flipY                   bool
+}
+
+func (v renderView) point(p domain.Position) vec {
+	y := p.Y
+	if v.flipY {
+		y = -y
+	}
+	return vec{p.X*v.scale + v.offsetX, y*v.scale + v.offsetY}
+}
+
+// renderLayout 渲染一组节点和路径
+func renderLayout(nodes []*domain.Node, paths []*domain.Path, opts RenderOptions) (*RenderedImage, error) {
+	if err := opts.normalize(); err != nil {
+		return nil, err
+	}
+
+	scene, err := buildRenderScene(nodes, paths, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	image := &RenderedImage{Format: opts.Format, Width: opts.Width, Height: opts.Height}
+	switch opts.Format {
+	case "png":
+		data, err := rasterizeScene(scene)
+		if err != nil {
+			return nil, err
+		}
+		image.ContentType = "image/png"
+		image.Data = data
+	default:
+		image.ContentType = "image/svg+xml"
+		image.Data = []byte(writeSceneSVG(scene))
+	}
+	return image, nil
+}
+
+// buildRenderScene 根据样式构建场景
+func buildRenderScene(nodes []*domain.Node, paths []*domain.Path, opts RenderOptions) (*renderScene, error) {
+	background := color.NRGBA{255, 255, 255, 255}
+	if opts.Background != "" {
+		c, ok := parseRenderColor(opts.Background)
+		if !ok {
+			return nil, fmt.Errorf("无效的背景色: %s", opts.Background)
+		}
+		background = c
+	}
+	scene := &renderScene{width: opts.Width, height: opts.Height, background: background}
+
+	nodeByID := make(map[domain.NodeID]*domain.Node, len(nodes))
+	var placed []*domain.Node
+	for _, n := range nodes {
+		if !finitePosition(n.Position) {
+			continue
+		}
+		nodeByID[n.ID] = n
+		placed = append(placed, n)
+	}
+
+	// 路径采样（世界坐标）
+	type renderPath struct {
+		path    *domain.Path
+		samples []domain.Position
+	}
+	var geometries []renderPath
+	var curves [][]domain.Position
+	for _, p := range paths {
+		start, ok1 := nodeByID[p.StartNodeID]
+		end, ok2 := nodeByID[p.EndNodeID]
+		if !ok1 || !ok2 {
+			continue
+		}
+		samples := domain.SampleCurve(p.ControlPoints(start.Position, end.Position), p.CurveType, renderCurveSegments)
+		geometries = append(geometries, renderPath{p, samples})
+		curves = append(curves, samples)
+	}
+
+	view := fitRenderView(placed, curves, opts)
+
+	for _, g := range geometries {
+		points := make([]vec, 0, len(g.samples))
+		for _, s := range g.samples {
+			if p := view.point(s); p.finite() {
+				points = append(points, p)
+			}
+		}
+		if len(points) < 2 {
+			continue
+		}
+
+		style := g.path.Style
+		col := styleColor(style.Color, color.NRGBA{0x6c, 0x75, 0x7d, 255})
+		width := math.Max(styleSize(style.Width, 2)*view.scale, 1)
+		opacity := styleOpacity(style.Opacity)
+		scene.strokes = append(scene.strokes, renderStroke{
+			points:  points,
+			color:   col,
+			width:   width,
+			opacity: opacity,
+			dash:    strokeDash(style.Style, width),
+		})
+
+		if arrow := directionArrow(points, g.path.Direction, width); arrow != nil {
+			scene.arrows = append(scene.arrows, renderShape{polygon: arrow, fill: col, opacity: opacity})
+		}
+	}
+
+	for _, n := range placed {
+		center := view.point(n.Position)
+		radius := math.Max(styleSize(n.Style.Size, 10)*view.scale, 2)
+		scene.shapes = append(scene.shapes, nodeShape(n.Style, center, radius, n.Style.BorderWidth*view.scale))
+
+		if opts.Labels && n.Name != "" {
+			scene.labels = append(scene.labels, renderLabel{
+				pos:  vec{center.x, center.y + radius + 12},
+				text: n.Name,
+				size: 12,
+			})
+		}
+	}
+
+	return scene, nil
+}
+
+// fitRenderView 计算视口变换：指定视口时按视口，否则适配节点（含半径）和路径范围
+func fitRenderView(nodes []*domain.Node, curves [][]domain.Position, opts RenderOptions) renderView {
+	minX, minY, maxX, maxY := opts.MinX, opts.MinY, opts.MaxX, opts.MaxY
+	padding := 0.0
+	if opts.FlipY {
+		minY, maxY = -maxY, -minY
+	}
+
+	if !opts.hasViewport() {
+		padding = float64(*opts.Padding)
+		minX, minY = math.Inf(1), math.Inf(1)
+		maxX, maxY = math.Inf(-1), math.Inf(-1)
+		extend := func(p domain.Position, r float64) {
+			y := p.Y
+			if opts.FlipY {
+				y = -y
+			}
+			minX, maxX = math.Min(minX, p.X-r), math.Max(maxX, p.X+r)
+			minY, maxY = math.Min(minY, y-r), math.Max(maxY, y+r)
+		}
+		for _, n := range nodes {
+			extend(n.Position, styleSize(n.Style.Size, 10))
+		}
+		for _, curve := range curves {
+			for _, p := range curve {
+				if finitePosition(p) {
+					extend(p, 0)
+				}
+			}
+		}
+		if math.IsInf(minX, 0) {
+			minX, minY, maxX, maxY = 0, 0, float64(opts.Width), float64(opts.Height)
+			padding = 0
+		}
+	}
+
+	width, height := float64(opts.Width)-2*padding, float64(opts.Height)-2*padding
+	spanX, spanY := maxX-minX, maxY-minY
+	scale := 1.0
+	switch {
+	case spanX > 0 && spanY > 0:
+		scale = math.Min(width/spanX, height/spanY)
+	case spanX > 0:
+		scale = width / spanX
+	case spanY > 0:
+		scale = height / spanY
+	}
+
+	return renderView{
+		scale:   scale,
+		offsetX: float64(opts.Width)/2 - (minX+maxX)/2*scale,
+		offsetY: float64(opts.Height)/2 - (minY+maxY)/2*scale,
+		flipY:   opts.FlipY,
+	}
+}
+
+// nodeShape 按节点样式构造形状，borderWidth 为像素宽度
+func nodeShape(style domain.NodeStyle, center vec, radius, borderWidth float64) renderShape {
+	shape := renderShape{
+		center:      center,
+		radius:      radius,
+		fill:        styleColor(style.Color, color.NRGBA{0x00, 0x7b, 0xff, 255}),
+		border:      styleColor(style.BorderColor, color.NRGBA{0, 0, 0, 255}),
+		borderWidth: math.Max(borderWidth, 0),
+		opacity:     styleOpacity(style.Opacity),
+	}
+	if shape.borderWidth > 0 {
+		shape.borderWidth = math.Max(shape.borderWidth, 0.5)
+	}
+	if shape.polygon = shapePolygon(style.Shape, center, radius); shape.polygon == nil {
+		shape.circle = true
+	}
+	return shape
+}
+
+// shapePolygon 非圆形节点的轮廓，圆形返回 nil
+func shapePolygon(shape string, c vec, r float64) []vec {
+	switch strings.ToLower(shape) {
+	case "square":
+		return []vec{{c.x - r, c.y - r}, {c.x + r, c.y - r}, {c.x + r, c.y + r}, {c.x - r, c.y + r}}
+	case "rectangle", "rect":
+		h := r * 0.65
+		return []vec{{c.x - r, c.y - h}, {c.x + r, c.y - h}, {c.x + r, c.y + h}, {c.x - r, c.y + h}}
+	case "triangle":
+		return []vec{{c.x, c.y - r}, {c.x + r*0.866, c.y + r*0.5}, {c.x - r*0.866, c.y + r*0.5}}
+	case "diamond":
+		return []vec{{c.x, c.y - r}, {c.x + r, c.y}, {c.x, c.y + r}, {c.x - r, c.y}}
+	default:
+		return nil
+	}
+}
+
+// strokeDash 线型对应的虚线模式（像素）
+func strokeDash(style string, width float64) []float64 {
+	switch strings.ToLower(style) {
+	case "dashed":
+		return []float64{math.Max(width*3, 4), math.Max(width*2, 3)}
+	case "dotted":
+		return []float64{width, math.Max(width*2, 2)}
+	default:
+		return nil
+	}
+}
+
+// directionArrow 单向路径在折线长度中点处的箭头，双向路径返回 nil
+func directionArrow(points []vec, direction string, width float64) []vec {
+	reverse := false
+	normalized, _ := domain.NormalizeDirection(direction)
+	switch normalized {
+	case domain.DirectionForward:
+	case domain.DirectionBackward:
+		reverse = true
+	default:
+		return nil
+	}
+
+	total := 0.0
+	for i := 1; i < len(points); i++ {
+		total += points[i].sub(points[i-1]).length()
+	}
+	if total == 0 {
+		return nil
+	}
+
+	// 定位中点及其切线方向
+	half := total / 2
+	var tip, dir vec
+	for i := 1; i < len(points); i++ {
+		d := points[i].sub(points[i-1])
+		length := d.length()
+		if length == 0 {
+			continue
+		}
+		if half <= length || i == len(points)-1 {
+			tip = points[i-1].lerp(points[i], math.Min(half/length, 1))
+			dir = d.scale(1 / length)
+			break
+		}
+		half -= length
+	}
+	if reverse {
+		dir = dir.scale(-1)
+	}
+
+	size := math.Max(width*4, 8)
+	tip = tip.add(dir.scale(size / 2))
+	base := tip.sub(dir.scale(size))
+	normal := vec{-dir.y, dir.x}.scale(size * 0.45)
+	return []vec{tip, base.add(normal), base.sub(normal)}
+}
+
+// === 样式 ===
+
+func styleSize(v, fallback float64) float64 {
+	if v > 0 && !math.IsInf(v, 0) {
+		return v
+	}
+	return fallback
+}
+
+// styleOpacity 透明度，未设置（0）视为不透明
+func styleOpacity(v float64) float64 {
+	if v <= 0 || v > 1 || math.IsNaN(v) {
+		return 1
+	}
+	return v
+}
+
+func styleColor(s string, fallback color.NRGBA) color.NRGBA {
+	if c, ok := parseRenderColor(s); ok {
+		return c
+	}
+	return fallback
+}
+
+// 常用颜色名
+var renderNamedColors = map[string]color.NRGBA{
+	"black":       {0, 0, 0, 255},
+	"white":       {255, 255, 255, 255},
+	"red":         {255, 0, 0, 255},
+	"green":       {0, 128, 0, 255},
+	"blue":        {0, 0, 255, 255},
+	"yellow":      {255, 255, 0, 255},
+	"orange":      {255, 165, 0, 255},
+	"purple":      {128, 0, 128, 255},
+	"gray":        {128, 128, 128, 255},
+	"grey":        {128, 128, 128, 255},
+	"none":        {},
+	"transparent": {},
+}
+
+// parseRenderColor 解析 #rgb、#rrggbb、#rrggbbaa 或颜色名
+func parseRenderColor(s string) (color.NRGBA, bool) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if c, ok := renderNamedColors[s]; ok {
+		return c, true
+	}
+	if !strings.HasPrefix(s, "#") {
+		return color.NRGBA{}, false
+	}
+
+	hex := s[1:]
+	if len(hex) == 3 {
+		hex = string([]byte{hex[0], hex[0], hex[1], hex[1], hex[2], hex[2]})
+	}
+	if len(hex) == 6 {
+		hex += "ff"
+	}
+	if len(hex) != 8 {
+		return color.NRGBA{}, false
+	}
+	v, err := strconv.ParseUint(hex, 16, 32)
+	if err != nil {
+		return color.NRGBA{}, false
+	}
+	return color.NRGBA{uint8(v >> 24), uint8(v >> 16), uint8(v >> 8), uint8(v)}, true
+}
+
+func finitePosition(p domain.Position) bool {
+	return !math.IsNaN(p.X+p.Y) && !math.IsInf(p.X+p.Y, 0)
+}
+
+// === 输出 ===
+
+// svgColor 输出颜色及合成后的不透明度
+func svgColor(c color.NRGBA, opacity float64) (string, string) {
+	if c.A == 0 {
+		return "none", "0"
+	}
+	return fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B), svgNumber(float64(c.A) / 255 * opacity)
+}
+
+func svgNumber(v float64) string {
+	return strconv.FormatFloat(math.Round(v*100)/100, 'f', -1, 64)
+}
+
+func svgPoints(points []vec) string {
+	parts := make([]string, len(points))
+	for i, p := range points {
+		parts[i] = svgNumber(p.x) + "," + svgNumber(p.y)
+	}
+	return strings.Join(parts, " ")
+}
+
+// writeSceneSVG 生成 SVG 文档
+func writeSceneSVG(scene *renderScene) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, `<?xml version="1.0" encoding="UTF-8"?>`+"\n")
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d">`+"\n",
+		scene.width, scene.height, scene.width, scene.height)
+
+	if scene.background.A > 0 {
+		fill, opacity := svgColor(scene.background, 1)
+		fmt.Fprintf(&b, `  <rect width="100%%" height="100%%" fill="%s" fill-opacity="%s"/>`+"\n", fill, opacity)
+	}
+
+	b.WriteString(`  <g fill="none" stroke-linecap="round" stroke-linejoin="round">` + "\n")
+	for _, s := range scene.strokes {
+		stroke, opacity := svgColor(s.color, s.opacity)
+		fmt.Fprintf(&b, `    <polyline points="%s" stroke="%s" stroke-opacity="%s" stroke-width="%s"`,
+			svgPoints(s.points), stroke, opacity, svgNumber(s.width))
+		if len(s.dash) > 0 {
+			dash := make([]string, len(s.dash))
+			for i, d := range s.dash {
+				dash[i] = svgNumber(d)
+			}
+			fmt.Fprintf(&b, ` stroke-dasharray="%s" stroke-linecap="butt"`, strings.Join(dash, " "))
+		}
+		b.WriteString("/>\n")
+	}
+	b.WriteString("  </g>\n")
+
+	for _, shape := range append(append([]renderShape(nil), scene.arrows...), scene.shapes...) {
+		fill, fillOpacity := svgColor(shape.fill, shape.opacity)
+		attrs := fmt.Sprintf(`fill="%s" fill-opacity="%s"`, fill, fillOpacity)
+		if shape.borderWidth > 0 && shape.border.A > 0 {
+			stroke, strokeOpacity := svgColor(shape.border, shape.opacity)
+			attrs += fmt.Sprintf(` stroke="%s" stroke-opacity="%s" stroke-width="%s"`, stroke, strokeOpacity, svgNumber(shape.borderWidth))
+		}
+		if shape.circle {
+			fmt.Fprintf(&b, `  <circle cx="%s" cy="%s" r="%s" %s/>`+"\n",
+				svgNumber(shape.center.x), svgNumber(shape.center.y), svgNumber(shape.radius), attrs)
+		} else {
+			fmt.Fprintf(&b, `  <polygon points="%s" %s/>`+"\n", svgPoints(shape.polygon), attrs)
+		}
+	}
+
+	if len(scene.labels) > 0 {
+		b.WriteString(`  <g font-family="sans-serif" text-anchor="middle" fill="#333333">` + "\n")
+		for _, l := range scene.labels {
+			fmt.Fprintf(&b, `    <text x="%s" y="%s" font-size="%s">%s</text>`+"\n",
+				svgNumber(l.pos.x), svgNumber(l.pos.y), svgNumber(l.size), xmlEscape(l.text))
+		}
+		b.WriteString("  </g>\n")
+	}
+
+	b.WriteString("</svg>\n")
+	return b.String()
+}
+
+// rasterizeScene 光栅化场景并编码为 PNG
+func rasterizeScene(scene *renderScene) ([]byte, error) {
+	canvas := newRasterCanvas(scene.width, scene.height, scene.background)
+
+	for _, s := range scene.strokes {
+		canvas.stroke(s.points, s.width, s.color, s.opacity, s.dash, false)
+	}
+	for _, shape := range append(append([]renderShape(nil), scene.arrows...), scene.shapes...) {
+		outline := shape.polygon
+		if shape.circle {
+			outline = circlePolygon(shape.center, shape.radius)
+		}
+		canvas.fill([][]vec{orient(outline)}, shape.fill, shape.opacity)
+		if shape.borderWidth > 0 {
+			canvas.stroke(outline, shape.borderWidth, shape.border, shape.opacity, nil, true)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, canvas.img); err != nil {
+		return nil, fmt.Errorf("编码PNG失败: %w", err)
+	}
+	return buf.Bytes(), nil
+}
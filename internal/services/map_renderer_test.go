@@ -0,0 +1,49 @@
+package services
+
+import (
+	"testing"
+
+	"robot-path-editor/internal/domain"
+)
+
+func TestDirectionArrow(t *testing.T) {
+	// 沿 X 轴的折线，中点在 (50, 0)
+	points := []vec{{0, 0}, {40, 0}, {100, 0}}
+	tests := []struct {
+		direction string
+		wantDir   float64 // 箭头尖相对中点的 X 方向：1 正向，-1 反向，0 没有箭头
+	}{
+		{"", 0},
+		{domain.DirectionBidirectional, 0},
+		{domain.DirectionForward, 1},
+		{domain.DirectionOneWay, 1},
+		{domain.DirectionUnidirectional, 1},
+		{domain.DirectionBackward, -1},
+		{domain.DirectionReverse, -1},
+		{"sideways", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.direction, func(t *testing.T) {
+			arrow := directionArrow(points, tt.direction, 2)
+			if tt.wantDir == 0 {
+				if arrow != nil {
+					t.Errorf("directionArrow() = %v, want nil", arrow)
+				}
+				return
+			}
+			if len(arrow) != 3 {
+				t.Fatalf("len = %d, want 3", len(arrow))
+			}
+			tip, wing := arrow[0], arrow[1]
+			if (tip.x-50)*tt.wantDir <= 0 || (wing.x-tip.x)*tt.wantDir >= 0 {
+				t.Errorf("箭头 %v 的朝向与 %v 不符", arrow, tt.wantDir)
+			}
+			if tip.y != 0 {
+				t.Errorf("箭头尖 %v 不在折线上", tip)
+			}
+		})
+	}
+	if directionArrow([]vec{{1, 1}, {1, 1}}, domain.DirectionForward, 2) != nil {
+		t.Error("零长度折线不应有箭头")
+	}
+}
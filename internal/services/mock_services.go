@@ -185,8 +185,38 @@ func (s *MockDataSyncService) SyncAllDataFromExternal(ctx context.Context, mappi
 	}, nil
 }
 
+// SyncChangesFromExternal 增量拉取（Mock实现）
+func (s *MockDataSyncService) SyncChangesFromExternal(ctx context.Context, mappingID string, req IncrementalSyncRequest) (*IncrementalSyncResult, error) {
+	return nil, fmt.Errorf("内存模式下不支持外部数据同步")
+}
+
+// ListSyncQuarantine 列出隔离中的路径（Mock实现）
+func (s *MockDataSyncService) ListSyncQuarantine(ctx context.Context, mappingID string) ([]*domain.SyncQuarantine, error) {
+	return nil, fmt.Errorf("内存模式下不支持外部数据同步")
+}
+
+// DiscardSyncQuarantine 移出隔离记录（Mock实现）
+func (s *MockDataSyncService) DiscardSyncQuarantine(ctx context.Context, mappingID, pathID string) error {
+	return fmt.Errorf("内存模式下不支持外部数据同步")
+}
+
+// PlanSync 生成同步计划（Mock实现）
+func (s *MockDataSyncService) PlanSync(ctx context.Context, mappingID string) (*SyncPlan, error) {
+	return nil, fmt.Errorf("内存模式下不支持外部数据同步")
+}
+
+// ApplySyncPlan 应用同步计划（Mock实现）
+func (s *MockDataSyncService) ApplySyncPlan(ctx context.Context, mappingID string, req ApplySyncPlanRequest) (*SyncApplyResult, error) {
+	return nil, fmt.Errorf("内存模式下不支持外部数据同步")
+}
+
+// PushToExternal 推送数据到外部数据库（Mock实现）
+func (s *MockDataSyncService) PushToExternal(ctx context.Context, mappingID string, opts PushOptions) (*PushResult, error) {
+	return nil, fmt.Errorf("内存模式下不支持外部数据同步")
+}
+
 // ValidateExternalTable 验证外部数据库表结构（Mock实现）
-func (s *MockDataSyncService) ValidateExternalTable(ctx context.Context, connectionID, tableName string) (*TableValidationResult, error) {
+func (s *MockDataSyncService) ValidateExternalTable(ctx context.Context, connectionID, schema, tableName string) (*TableValidationResult, error) {
 	return &TableValidationResult{
 		Valid:   false,
 		Columns: []string{},
@@ -194,6 +224,102 @@ func (s *MockDataSyncService) ValidateExternalTable(ctx context.Context, connect
 	}, nil
 }
 
+// MockSyncJobService Mock同步任务服务实现
+type MockSyncJobService struct{}
+
+// CreateJob 创建同步任务（Mock实现）
+func (s *MockSyncJobService) CreateJob(ctx context.Context, req CreateSyncJobRequest) (*domain.SyncJob, error) {
+	return nil, fmt.Errorf("内存模式下不支持同步任务")
+}
+
+// GetJob 获取同步任务（Mock实现）
+func (s *MockSyncJobService) GetJob(ctx context.Context, id string) (*domain.SyncJob, error) {
+	return nil, fmt.Errorf("内存模式下不支持同步任务")
+}
+
+// UpdateJob 更新同步任务（Mock实现）
+func (s *MockSyncJobService) UpdateJob(ctx context.Context, req UpdateSyncJobRequest) (*domain.SyncJob, error) {
+	return nil, fmt.Errorf("内存模式下不支持同步任务")
+}
+
+// DeleteJob 删除同步任务（Mock实现）
+func (s *MockSyncJobService) DeleteJob(ctx context.Context, id string) error {
+	return fmt.Errorf("内存模式下不支持同步任务")
+}
+
+// ListJobs 列出同步任务（Mock实现）
+func (s *MockSyncJobService) ListJobs(ctx context.Context) ([]*domain.SyncJob, error) {
+	return []*domain.SyncJob{}, nil
+}
+
+// TriggerJob 立即运行同步任务（Mock实现）
+func (s *MockSyncJobService) TriggerJob(ctx context.Context, id string) (*domain.SyncJobRun, error) {
+	return nil, fmt.Errorf("内存模式下不支持同步任务")
+}
+
+// PauseJob 暂停同步任务（Mock实现）
+func (s *MockSyncJobService) PauseJob(ctx context.Context, id string) (*domain.SyncJob, error) {
+	return nil, fmt.Errorf("内存模式下不支持同步任务")
+}
+
+// ResumeJob 恢复同步任务（Mock实现）
+func (s *MockSyncJobService) ResumeJob(ctx context.Context, id string) (*domain.SyncJob, error) {
+	return nil, fmt.Errorf("内存模式下不支持同步任务")
+}
+
+// ListJobRuns 列出运行记录（Mock实现）
+func (s *MockSyncJobService) ListJobRuns(ctx context.Context, id string, limit int) ([]*domain.SyncJobRun, error) {
+	return nil, fmt.Errorf("内存模式下不支持同步任务")
+}
+
+// CancelJob 取消正在进行的运行（Mock实现）
+func (s *MockSyncJobService) CancelJob(ctx context.Context, id string) error {
+	return fmt.Errorf("内存模式下不支持同步任务")
+}
+
+// WatchJob 订阅运行进度（Mock实现）
+func (s *MockSyncJobService) WatchJob(ctx context.Context, id string) (<-chan SyncProgress, func(), error) {
+	return nil, nil, fmt.Errorf("内存模式下不支持同步任务")
+}
+
+// Start 启动调度器（Mock实现）
+func (s *MockSyncJobService) Start(ctx context.Context) error {
+	return nil
+}
+
+// Stop 停止调度器（Mock实现）
+func (s *MockSyncJobService) Stop(ctx context.Context) error {
+	return nil
+}
+
+// MockTableEditorService Mock外部表编辑服务实现
+type MockTableEditorService struct{}
+
+// ListSchemas 列出模式（Mock实现）
+func (s *MockTableEditorService) ListSchemas(ctx context.Context, connectionID string) ([]string, error) {
+	return nil, fmt.Errorf("内存模式下不支持外部表编辑")
+}
+
+// ListTables 列出表（Mock实现）
+func (s *MockTableEditorService) ListTables(ctx context.Context, connectionID, schema string) ([]ExternalTableInfo, error) {
+	return nil, fmt.Errorf("内存模式下不支持外部表编辑")
+}
+
+// DescribeTable 读取表结构（Mock实现）
+func (s *MockTableEditorService) DescribeTable(ctx context.Context, connectionID, schema, table string) (*ExternalTableSchema, error) {
+	return nil, fmt.Errorf("内存模式下不支持外部表编辑")
+}
+
+// QueryRows 分页读取行（Mock实现）
+func (s *MockTableEditorService) QueryRows(ctx context.Context, connectionID, table string, req TableRowsQuery) (*TableRowsPage, error) {
+	return nil, fmt.Errorf("内存模式下不支持外部表编辑")
+}
+
+// WriteRows 修改行（Mock实现）
+func (s *MockTableEditorService) WriteRows(ctx context.Context, connectionID, table string, req TableWriteRequest) (*TableWriteResult, error) {
+	return nil, fmt.Errorf("内存模式下不支持外部表编辑")
+}
+
 // MockTemplateService Mock模板服务实现
 type MockTemplateService struct{}
 
@@ -244,7 +370,7 @@ func (s *MockTemplateService) GetTemplatesByCategory(ctx context.Context, catego
 }
 
 // ApplyTemplate 应用模板（Mock实现）
-func (s *MockTemplateService) ApplyTemplate(ctx context.Context, templateID string, canvasConfig domain.CanvasConfig) (*ApplyTemplateResponse, error) {
+func (s *MockTemplateService) ApplyTemplate(ctx context.Context, templateID string, req ApplyTemplateRequest) (*ApplyTemplateResponse, error) {
 	return nil, fmt.Errorf("内存模式下不支持模板功能")
 }
 
@@ -259,7 +385,7 @@ func (s *MockTemplateService) CloneTemplate(ctx context.Context, templateID stri
 }
 
 // ExportTemplate 导出模板（Mock实现）
-func (s *MockTemplateService) ExportTemplate(ctx context.Context, templateID string) (*ExportTemplateResponse, error) {
+func (s *MockTemplateService) ExportTemplate(ctx context.Context, templateID string, format string) (*ExportTemplateResponse, error) {
 	return nil, fmt.Errorf("内存模式下不支持模板功能")
 }
 
@@ -267,3 +393,216 @@ func (s *MockTemplateService) ExportTemplate(ctx context.Context, templateID str
 func (s *MockTemplateService) ImportTemplate(ctx context.Context, req ImportTemplateRequest) (*domain.Template, error) {
 	return nil, fmt.Errorf("内存模式下不支持模板功能")
 }
+
+// ExportTemplatePack 导出模板包（Mock实现）
+func (s *MockTemplateService) ExportTemplatePack(ctx context.Context, opts TemplatePackExportOptions) (*TemplatePackFile, error) {
+	return nil, fmt.Errorf("内存模式下不支持模板功能")
+}
+
+// ImportTemplatePack 导入模板包（Mock实现）
+func (s *MockTemplateService) ImportTemplatePack(ctx context.Context, data []byte, opts TemplatePackImportOptions) (*TemplatePackImportResult, error) {
+	return nil, fmt.Errorf("内存模式下不支持模板功能")
+}
+
+// ListTemplateRevisions 列出模板修订（Mock实现）
+func (s *MockTemplateService) ListTemplateRevisions(ctx context.Context, templateID string) ([]TemplateRevisionSummary, error) {
+	return nil, fmt.Errorf("内存模式下不支持模板功能")
+}
+
+// GetTemplateRevision 获取模板修订（Mock实现）
+func (s *MockTemplateService) GetTemplateRevision(ctx context.Context, templateID string, revision int) (*domain.TemplateRevision, error) {
+	return nil, fmt.Errorf("内存模式下不支持模板功能")
+}
+
+// DiffTemplateRevisions 比较模板修订（Mock实现）
+func (s *MockTemplateService) DiffTemplateRevisions(ctx context.Context, templateID string, from, to int) (*TemplateDiff, error) {
+	return nil, fmt.Errorf("内存模式下不支持模板功能")
+}
+
+// RestoreTemplateRevision 恢复模板修订（Mock实现）
+func (s *MockTemplateService) RestoreTemplateRevision(ctx context.Context, templateID string, revision int, message string) (*domain.Template, error) {
+	return nil, fmt.Errorf("内存模式下不支持模板功能")
+}
+
+// MockPoseInterpolationService Mock位姿插值服务实现
+type MockPoseInterpolationService struct{}
+
+// InterpolatePath 对单条路径插值（Mock实现）
+func (s *MockPoseInterpolationService) InterpolatePath(ctx context.Context, pathID domain.PathID, opts InterpolationOptions) (*PoseSequence, error) {
+	return nil, fmt.Errorf("内存模式下不支持位姿插值")
+}
+
+// InterpolateRoute 对路线插值（Mock实现）
+func (s *MockPoseInterpolationService) InterpolateRoute(ctx context.Context, req InterpolateRouteRequest) (*PoseSequence, error) {
+	return nil, fmt.Errorf("内存模式下不支持位姿插值")
+}
+
+// MockRobotProgramService Mock机器人程序生成服务实现
+type MockRobotProgramService struct{}
+
+// RegisterDialect 注册方言（Mock实现）
+func (s *MockRobotProgramService) RegisterDialect(dialect RobotProgramDialect) error {
+	return nil
+}
+
+// ListDialects 列出方言（Mock实现）
+func (s *MockRobotProgramService) ListDialects() []RobotDialectInfo {
+	return []RobotDialectInfo{}
+}
+
+// ExportRobotProgram 导出机器人程序（Mock实现）
+func (s *MockRobotProgramService) ExportRobotProgram(ctx context.Context, req ExportRobotProgramRequest) (*RobotProgramFile, error) {
+	return nil, fmt.Errorf("内存模式下不支持机器人程序导出")
+}
+
+// MockGCodeService Mock G-code导出服务实现
+type MockGCodeService struct{}
+
+// ExportGCode 导出G-code（Mock实现）
+func (s *MockGCodeService) ExportGCode(ctx context.Context, req ExportGCodeRequest) (*GCodeFile, error) {
+	return nil, fmt.Errorf("内存模式下不支持G-code导出")
+}
+
+// MockVDA5050Service Mock VDA 5050 订单服务实现
+type MockVDA5050Service struct{}
+
+// GenerateOrder 生成订单（Mock实现）
+func (s *MockVDA5050Service) GenerateOrder(ctx context.Context, req VDA5050OrderRequest) (*VDA5050Order, error) {
+	return nil, fmt.Errorf("内存模式下不支持VDA 5050订单生成")
+}
+
+// ValidateOrder 校验订单（Mock实现，校验不依赖存储）
+func (s *MockVDA5050Service) ValidateOrder(data []byte) error {
+	return validateVDA5050Order(data)
+}
+
+// MockNav2Service Mock Nav2 互操作服务实现
+type MockNav2Service struct{}
+
+// ExportGraph 导出路由图（Mock实现）
+func (s *MockNav2Service) ExportGraph(ctx context.Context, opts Nav2GraphOptions) (*Nav2Graph, error) {
+	return nil, fmt.Errorf("内存模式下不支持Nav2路由图导出")
+}
+
+// ImportGraph 导入路由图（Mock实现）
+func (s *MockNav2Service) ImportGraph(ctx context.Context, data []byte, opts Nav2GraphOptions) (*Nav2ImportResult, error) {
+	return nil, fmt.Errorf("内存模式下不支持Nav2路由图导入")
+}
+
+// ExportNavPath 导出nav_msgs/Path（Mock实现）
+func (s *MockNav2Service) ExportNavPath(ctx context.Context, req NavPathRequest) (*NavPathFile, error) {
+	return nil, fmt.Errorf("内存模式下不支持nav_msgs/Path导出")
+}
+
+// MockOccupancyMapService Mock 占据栅格地图服务实现
+type MockOccupancyMapService struct{}
+
+// ImportMap 导入地图（Mock实现）
+func (s *MockOccupancyMapService) ImportMap(ctx context.Context, req ImportMapRequest) (*domain.OccupancyMap, error) {
+	return nil, fmt.Errorf("内存模式下不支持地图导入")
+}
+
+// ListMaps 列出地图（Mock实现）
+func (s *MockOccupancyMapService) ListMaps(ctx context.Context) ([]*domain.OccupancyMap, error) {
+	return []*domain.OccupancyMap{}, nil
+}
+
+// GetMap 获取地图（Mock实现）
+func (s *MockOccupancyMapService) GetMap(ctx context.Context, id string) (*MapDetail, error) {
+	return nil, fmt.Errorf("内存模式下不支持地图管理")
+}
+
+// DeleteMap 删除地图（Mock实现）
+func (s *MockOccupancyMapService) DeleteMap(ctx context.Context, id string) error {
+	return fmt.Errorf("内存模式下不支持地图管理")
+}
+
+// ActivateMap 设为当前地图（Mock实现）
+func (s *MockOccupancyMapService) ActivateMap(ctx context.Context, id string) error {
+	return fmt.Errorf("内存模式下不支持地图管理")
+}
+
+// RenderImage 渲染地图（Mock实现）
+func (s *MockOccupancyMapService) RenderImage(ctx context.Context, id string) ([]byte, error) {
+	return nil, fmt.Errorf("内存模式下不支持地图管理")
+}
+
+// GetTile 获取瓦片（Mock实现）
+func (s *MockOccupancyMapService) GetTile(ctx context.Context, id string, z, x, y int) ([]byte, error) {
+	return nil, fmt.Errorf("内存模式下不支持地图管理")
+}
+
+// RegenerateObstacles 重新提取障碍物（Mock实现）
+func (s *MockOccupancyMapService) RegenerateObstacles(ctx context.Context, id string, opts ObstacleOptions) (*domain.OccupancyMap, error) {
+	return nil, fmt.Errorf("内存模式下不支持地图管理")
+}
+
+// ValidateLayout 校验布局（Mock实现）
+func (s *MockOccupancyMapService) ValidateLayout(ctx context.Context, id string) (*MapValidationReport, error) {
+	return nil, fmt.Errorf("内存模式下不支持地图校验")
+}
+
+// ActiveObstacles 获取当前地图障碍物（Mock实现，内存模式下没有地图）
+func (s *MockOccupancyMapService) ActiveObstacles(ctx context.Context) ([]domain.ObstaclePolygon, error) {
+	return nil, nil
+}
+
+// MockDXFService Mock DXF 导入导出服务实现
+type MockDXFService struct{}
+
+// ImportDXF 导入DXF（Mock实现）
+func (s *MockDXFService) ImportDXF(ctx context.Context, data []byte, opts DXFImportOptions) (*DXFImportResult, error) {
+	return nil, fmt.Errorf("内存模式下不支持DXF导入")
+}
+
+// ExportDXF 导出DXF（Mock实现）
+func (s *MockDXFService) ExportDXF(ctx context.Context, opts DXFExportOptions) (*DXFFile, error) {
+	return nil, fmt.Errorf("内存模式下不支持DXF导出")
+}
+
+// MockSpreadsheetService Mock 表格导入导出服务实现
+type MockSpreadsheetService struct{}
+
+// ExportTables 导出表格（Mock实现）
+func (s *MockSpreadsheetService) ExportTables(ctx context.Context, opts TableExportOptions) (*TableExport, error) {
+	return nil, fmt.Errorf("内存模式下不支持表格导出")
+}
+
+// ImportTables 导入表格（Mock实现）
+func (s *MockSpreadsheetService) ImportTables(ctx context.Context, data []byte, opts TableImportOptions) (*TableImportResult, error) {
+	return nil, fmt.Errorf("内存模式下不支持表格导入")
+}
+
+// MockGraphService Mock 图格式导入导出服务实现
+type MockGraphService struct{}
+
+// ExportGraph 导出图（Mock实现）
+func (s *MockGraphService) ExportGraph(ctx context.Context, opts GraphExportOptions) (*GraphFile, error) {
+	return nil, fmt.Errorf("内存模式下不支持图格式导出")
+}
+
+// ImportGraph 导入图（Mock实现）
+func (s *MockGraphService) ImportGraph(ctx context.Context, data []byte, opts GraphImportOptions) (*GraphImportResult, error) {
+	return nil, fmt.Errorf("内存模式下不支持图格式导入")
+}
+
+// MockRenderService Mock 渲染服务实现
+type MockRenderService struct{}
+
+// RenderMap 渲染地图（Mock实现）
+func (s *MockRenderService) RenderMap(ctx context.Context, opts RenderOptions) (*RenderedImage, error) {
+	return nil, fmt.Errorf("内存模式下不支持地图渲染")
+}
+
+// RenderTemplate 渲染模板（Mock实现）
+func (s *MockRenderService) RenderTemplate(ctx context.Context, templateID string, opts RenderOptions) (*RenderedImage, error) {
+	return nil, fmt.Errorf("内存模式下不支持模板渲染")
+}
+
+// MockReportService Mock 地图报告服务实现
+type MockReportService struct{}
+
+// GenerateMapReport 生成地图报告（Mock实现）
+func (s *MockReportService) GenerateMapReport(ctx context.Context, opts ReportOptions) (*MapReport, error) {
+	return nil, fmt.Errorf("内存模式下不支持地图报告")
+}
@@ -0,0 +1,794 @@
+// Package services ROS 2 Nav2 互操作服务
+//
+// 设计参考：
+// - nav2_route 路径服务器的 GeoJSON 图格式（FeatureCollection，Point 为节点，MultiLineString 为有向边）
+// - nav_msgs/msg/Path 与 geometry_msgs/msg/PoseStamped
+//
+// 特点：
+// 1. Node/Path 与 Nav2 路由图双向转换，metadata 映射到 Properties
+// 2. 双向路径导出为两条有向边，导入时按 path_id 或反向边重新合并
+// 3. 路线导出为 nav_msgs/Path 风格的 YAML 或 JSON 位姿列表
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"robot-path-editor/internal/domain"
+	"robot-path-editor/internal/repositories"
+)
+
+// Nav2Service ROS 2 Nav2 互操作服务接口
+type Nav2Service interface {
+	ExportGraph(ctx context.Context, opts Nav2GraphOptions) (*Nav2Graph, error)
+	ImportGraph(ctx context.Context, data []byte, opts Nav2GraphOptions) (*Nav2ImportResult, error)
+	ExportNavPath(ctx context.Context, req NavPathRequest) (*NavPathFile, error)
+}
+
+// Nav2GraphOptions 路由图导入导出选项
+type Nav2GraphOptions struct {
+	Name               string  `json:"name,omitempty" form:"name"`
+	Frame              string  `json:"frame,omitempty" form:"frame"`                             // 默认 map
+	Scale              float64 `json:"scale,omitempty" form:"scale"`                             // 画布坐标到米的比例，默认1
+	FlipY              bool    `json:"flip_y,omitempty" form:"flip_y"`                           // 画布Y轴向下时翻转
+	CurveSegments      int     `json:"curve_segments,omitempty" form:"curve_segments"`           // 导出曲线几何的细分段数，默认16
+	MergeBidirectional *bool   `json:"merge_bidirectional,omitempty" form:"merge_bidirectional"` // 导入时合并互为反向的边，默认true
+}
+
+// Nav2Graph nav2_route GeoJSON 路由图
+type Nav2Graph struct {
+	Type          string        `json:"type"`
+	Name          string        `json:"name,omitempty"`
+	CRS           *Nav2CRS      `json:"crs,omitempty"`
+	DateGenerated string        `json:"date_generated,omitempty"`
+	Features      []Nav2Feature `json:"features"`
+}
+
+// Nav2CRS 坐标参考系
+type Nav2CRS struct {
+	Type       string            `json:"type"`
+	Properties map[string]string `json:"properties"`
+}
+
+// Nav2Feature 图要素：Point 为节点，MultiLineString/LineString 为边
+type Nav2Feature struct {
+	Type       string                `json:"type"`
+	Properties Nav2FeatureProperties `json:"properties"`
+	Geometry   Nav2Geometry          `json:"geometry"`
+}
+
+// Nav2FeatureProperties 要素属性
+type Nav2FeatureProperties struct {
+	ID          uint64                 `json:"id"`
+	StartID     *uint64                `json:"startid,omitempty"`
+	EndID       *uint64                `json:"endid,omitempty"`
+	Frame       string                 `json:"frame,omitempty"`
+	Cost        *float64               `json:"cost,omitempty"`
+	Overridable *bool                  `json:"overridable,omitempty"`
+	Metadata    map[string]interface{} `json:"metadata,omitempty"`
+	Operations  map[string]interface{} `json:"operations,omitempty"`
+}
+
+// Nav2Geometry GeoJSON 几何，坐标结构随类型不同
+type Nav2Geometry struct {
+	Type        string          `json:"type"`
+	Coordinates json.RawMessage `json:"coordinates"`
+}
+
+// Nav2ImportResult 导入结果
+type Nav2ImportResult struct {
+	NodesCreated int                      `json:"nodes_created"`
+	PathsCreated int                      `json:"paths_created"`
+	NodeIDs      map[uint64]domain.NodeID `json:"node_ids"`
+	Warnings     []string                 `json:"warnings,omitempty"`
+}
+
+// NavPathRequest 导出 nav_msgs/Path 请求
+type NavPathRequest struct {
+	RouteRequest
+	Format        string  `json:"format,omitempty"`   // yaml（默认）或 json
+	FrameID       string  `json:"frame_id,omitempty"` // 默认 map
+	Scale         float64 `json:"scale,omitempty"`
+	FlipY         bool    `json:"flip_y,omitempty"`
+	CurveSegments int     `json:"curve_segments,omitempty"`
+}
+
+// NavPath nav_msgs/Path
+type NavPath struct {
+	Header NavHeader     `json:"header" yaml:"header"`
+	Poses  []PoseStamped `json:"poses" yaml:"poses"`
+}
+
+// NavHeader std_msgs/Header
+type NavHeader struct {
+	Stamp   NavTime `json:"stamp" yaml:"stamp"`
+	FrameID string  `json:"frame_id" yaml:"frame_id"`
+}
+
+// NavTime builtin_interfaces/Time
+type NavTime struct {
+	Sec     int64  `json:"sec" yaml:"sec"`
+	Nanosec uint32 `json:"nanosec" yaml:"nanosec"`
+}
+
+// PoseStamped geometry_msgs/PoseStamped
+type PoseStamped struct {
+	Header NavHeader `json:"header" yaml:"header"`
+	Pose   NavPose   `json:"pose" yaml:"pose"`
+}
+
+// NavPose geometry_msgs/Pose
+type NavPose struct {
+	Position    NavPoint      `json:"position" yaml:"position"`
+	Orientation NavQuaternion `json:"orientation" yaml:"orientation"`
+}
+
+// NavPoint geometry_msgs/Point
+type NavPoint struct {
+	X float64 `json:"x" yaml:"x"`
+	Y float64 `json:"y" yaml:"y"`
+	Z float64 `json:"z" yaml:"z"`
+}
+
+// NavQuaternion geometry_msgs/Quaternion（ROS 字段顺序 x, y, z, w）
+type NavQuaternion struct {
+	X float64 `json:"x" yaml:"x"`
+	Y float64 `json:"y" yaml:"y"`
+	Z float64 `json:"z" yaml:"z"`
+	W float64 `json:"w" yaml:"w"`
+}
+
+// NavPathFile 导出的 nav_msgs/Path 文件
+type NavPathFile struct {
+	Filename string   `json:"filename"`
+	Format   string   `json:"format"`
+	Content  string   `json:"content"`
+	Path     *NavPath `json:"path"`
+}
+
+// Nav2 扩展属性键
+const (
+	nav2IDKey        = "nav2_id"         // 节点/正向边在路由图中的ID
+	nav2ReverseIDKey = "nav2_reverse_id" // 双向路径反向边的ID
+	nav2FrameKey     = "frame"
+	nav2OpsKey       = "operations"
+	nav2CostKey      = "cost"
+	nav2OverrideKey  = "overridable"
+)
+
+// nav2ReservedKeys 映射到要素字段而不是 metadata 的属性
+var nav2ReservedKeys = map[string]bool{
+	nav2IDKey: true, nav2ReverseIDKey: true, nav2FrameKey: true,
+	nav2OpsKey: true, nav2CostKey: true, nav2OverrideKey: true,
+}
+
+// metadata 中由编辑器写入的键
+const (
+	nav2MetaName      = "name"
+	nav2MetaType      = "type"
+	nav2MetaPathID    = "path_id"
+	nav2MetaCurveType = "curve_type"
+	nav2MetaWaypoints = "waypoints"
+)
+
+// nav2Service Nav2 互操作服务实现
+type nav2Service struct {
+	nodeRepo repositories.NodeRepository
+	pathRepo repositories.PathRepository
+	routes   *routeResolver
+}
+
+// NewNav2Service 创建新的 Nav2 互操作服务实例
+func NewNav2Service(nodeRepo repositories.NodeRepository, pathRepo repositories.PathRepository) Nav2Service {
+	return &nav2Service{
+		nodeRepo: nodeRepo,
+		pathRepo: pathRepo,
+		routes:   newRouteResolver(nodeRepo, pathRepo),
+	}
+}
+
+// === 路由图导出 ===
+
+// ExportGraph 将全部节点和路径导出为 Nav2 路由图
+func (s *nav2Service) ExportGraph(ctx context.Context, opts Nav2GraphOptions) (*Nav2Graph, error) {
+	nodes, err := s.nodeRepo.List(ctx, repositories.NodeFilter{})
+	if err != nil {
+		return nil, fmt.Errorf("获取节点列表失败: %w", err)
+	}
+	paths, err := s.pathRepo.List(ctx, repositories.PathFilter{})
+	if err != nil {
+		return nil, fmt.Errorf("获取路径列表失败: %w", err)
+	}
+	sort.Slice(nodes, func(i, j int) bool { return nodes[i].ID < nodes[j].ID })
+	sort.Slice(paths, func(i, j int) bool { return paths[i].ID < paths[j].ID })
+
+	transform := newNav2Transform(opts)
+	ids := newNav2IDAllocator()
+
+	// 优先沿用属性中记录的ID，保证往返导入导出时ID稳定
+	for _, node := range nodes {
+		ids.reserve(node.Properties, nav2IDKey)
+	}
+	for _, path := range paths {
+		ids.reserve(path.Properties, nav2IDKey)
+		ids.reserve(path.Properties, nav2ReverseIDKey)
+	}
+
+	graph := &Nav2Graph{
+		Type:          "FeatureCollection",
+		Name:          opts.Name,
+		CRS:           &Nav2CRS{Type: "name", Properties: map[string]string{"name": "urn:ogc:def:crs:EPSG::3857"}},
+		DateGenerated: time.Now().Format(time.RFC3339),
+		Features:      []Nav2Feature{},
+	}
+	if graph.Name == "" {
+		graph.Name = "robot_path_editor_graph"
+	}
+
+	nodeIDs := make(map[domain.NodeID]uint64, len(nodes))
+	positions := make(map[domain.NodeID]domain.Position, len(nodes))
+	for _, node := range nodes {
+		id := ids.assign(node.Properties, nav2IDKey)
+		nodeIDs[node.ID] = id
+		positions[node.ID] = transform.toMap(node.Position)
+
+		frame := transform.frame
+		if v, ok := propertyString(node.Properties, nav2FrameKey); ok {
+			frame = v
+		}
+		metadata := nav2Metadata(node.Properties)
+		metadata[nav2MetaName] = node.Name
+		metadata[nav2MetaType] = string(node.Type)
+
+		coordinates, _ := json.Marshal([]float64{roundNav2(positions[node.ID].X), roundNav2(positions[node.ID].Y)})
+		graph.Features = append(graph.Features, Nav2Feature{
+			Type: "Feature",
+			Properties: Nav2FeatureProperties{
+				ID:         id,
+				Frame:      frame,
+				Metadata:   metadata,
+				Operations: nav2Operations(node.Properties),
+			},
+			Geometry: Nav2Geometry{Type: "Point", Coordinates: coordinates},
+		})
+	}
+
+	for _, path := range paths {
+		startID, startOK := nodeIDs[path.StartNodeID]
+		endID, endOK := nodeIDs[path.EndNodeID]
+		if !startOK || !endOK {
+			continue
+		}
+		start, end := positions[path.StartNodeID], positions[path.EndNodeID]
+
+		if path.CanTraverse(path.StartNodeID) {
+			edge := s.edgeFeature(path, ids.assign(path.Properties, nav2IDKey), startID, endID, start, end, false, transform)
+			graph.Features = append(graph.Features, edge)
+		}
+		if path.CanTraverse(path.EndNodeID) {
+			key := nav2ReverseIDKey
+			if !path.CanTraverse(path.StartNodeID) {
+				key = nav2IDKey // 只能反向通行时只有一条边，使用主ID
+			}
+			edge := s.edgeFeature(path, ids.assign(path.Properties, key), endID, startID, start, end, true, transform)
+			graph.Features = append(graph.Features, edge)
+		}
+	}
+
+	return graph, nil
+}
+
+// edgeFeature 构建一条有向边要素，reversed 表示从路径终点走向起点
+func (s *nav2Service) edgeFeature(path *domain.Path, id, startID, endID uint64, start, end domain.Position, reversed bool, transform nav2Transform) Nav2Feature {
+	metadata := nav2Metadata(path.Properties)
+	metadata[nav2MetaName] = path.Name
+	metadata[nav2MetaPathID] = string(path.ID)
+
+	waypoints := make([]domain.Position, len(path.Waypoints))
+	for i, waypoint := range path.Waypoints {
+		waypoints[i] = transform.toMap(waypoint)
+	}
+	if path.CurveType != "" && path.CurveType != domain.CurveTypeLinear {
+		metadata[nav2MetaCurveType] = string(path.CurveType)
+	}
+	if len(waypoints) > 0 {
+		coords := make([][]float64, len(waypoints))
+		for i, waypoint := range waypoints {
+			coords[i] = []float64{roundNav2(waypoint.X), roundNav2(waypoint.Y)}
+		}
+		metadata[nav2MetaWaypoints] = coords
+	}
+
+	// 几何为按通行方向采样的曲线，供 Nav2 可视化工具显示
+	points := append([]domain.Position{start}, waypoints...)
+	points = append(points, end)
+	samples := domain.SampleCurve(points, path.CurveType, transform.curveSegments)
+	line := make([][]float64, len(samples))
+	for i, sample := range samples {
+		line[i] = []float64{roundNav2(sample.X), roundNav2(sample.Y)}
+	}
+	if reversed {
+		for i, j := 0, len(line)-1; i < j; i, j = i+1, j-1 {
+			line[i], line[j] = line[j], line[i]
+		}
+	}
+	coordinates, _ := json.Marshal([][][]float64{line})
+
+	properties := Nav2FeatureProperties{
+		ID:         id,
+		StartID:    &startID,
+		EndID:      &endID,
+		Metadata:   metadata,
+		Operations: nav2Operations(path.Properties),
+	}
+	if cost, ok := propertyFloat(path.Properties, nav2CostKey); ok {
+		properties.Cost = &cost
+	} else if path.Weight > 0 {
+		cost := path.Weight
+		properties.Cost = &cost
+	}
+	if overridable, ok := propertyBool(path.Properties, nav2OverrideKey); ok {
+		properties.Overridable = &overridable
+	}
+
+	return Nav2Feature{
+		Type:       "Feature",
+		Properties: properties,
+		Geometry:   Nav2Geometry{Type: "MultiLineString", Coordinates: coordinates},
+	}
+}
+
+// === 路由图导入 ===
+
+// nav2ImportEdge 待合并的有向边
+type nav2ImportEdge struct {
+	feature Nav2Feature
+	points  []domain.Position // 几何坐标（地图坐标系）
+	reverse *Nav2Feature      // 合并进来的反向边
+}
+
+// ImportGraph 导入 Nav2 路由图，创建对应的节点和路径
+func (s *nav2Service) ImportGraph(ctx context.Context, data []byte, opts Nav2GraphOptions) (*Nav2ImportResult, error) {
+	var graph Nav2Graph
+	if err := json.Unmarshal(data, &graph); err != nil {
+		return nil, fmt.Errorf("解析路由图失败: %w", err)
+	}
+	if graph.Type != "FeatureCollection" {
+		return nil, fmt.Errorf("路由图必须是 GeoJSON FeatureCollection，实际为: %s", graph.Type)
+	}
+
+	transform := newNav2Transform(opts)
+	merge := opts.MergeBidirectional == nil || *opts.MergeBidirectional
+	result := &Nav2ImportResult{NodeIDs: make(map[uint64]domain.NodeID)}
+
+	// 第一遍：节点
+	var edges []*nav2ImportEdge
+	for i, feature := range graph.Features {
+		switch feature.Geometry.Type {
+		case "Point":
+			var coords []float64
+			if err := json.Unmarshal(feature.Geometry.Coordinates, &coords); err != nil || len(coords) < 2 {
+				return nil, fmt.Errorf("第%d个要素的点坐标无效", i+1)
+			}
+			if _, exists := result.NodeIDs[feature.Properties.ID]; exists {
+				return nil, fmt.Errorf("节点ID重复: %d", feature.Properties.ID)
+			}
+
+			node := s.importNode(feature, domain.Position{X: coords[0], Y: coords[1]}, transform)
+			if err := s.nodeRepo.Create(ctx, node); err != nil {
+				return nil, fmt.Errorf("创建节点 %s 失败: %w", node.Name, err)
+			}
+			result.NodeIDs[feature.Properties.ID] = node.ID
+			result.NodesCreated++
+
+		case "MultiLineString", "LineString":
+			if feature.Properties.StartID == nil || feature.Properties.EndID == nil {
+				result.Warnings = append(result.Warnings, fmt.Sprintf("边 %d 缺少 startid/endid，已跳过", feature.Properties.ID))
+				continue
+			}
+			points, err := nav2LinePoints(feature.Geometry)
+			if err != nil {
+				return nil, fmt.Errorf("边 %d: %w", feature.Properties.ID, err)
+			}
+			edges = append(edges, &nav2ImportEdge{feature: feature, points: points})
+
+		default:
+			result.Warnings = append(result.Warnings, fmt.Sprintf("不支持的几何类型 %s，已跳过", feature.Geometry.Type))
+		}
+	}
+
+	// 第二遍：合并互为反向的边后创建路径
+	for _, edge := range nav2MergeEdges(edges, merge) {
+		props := edge.feature.Properties
+		startID, startOK := result.NodeIDs[*props.StartID]
+		endID, endOK := result.NodeIDs[*props.EndID]
+		if !startOK || !endOK {
+			result.Warnings = append(result.Warnings, fmt.Sprintf("边 %d 引用了不存在的节点，已跳过", props.ID))
+			continue
+		}
+
+		path := s.importPath(edge, startID, endID, transform)
+		if err := s.pathRepo.Create(ctx, path); err != nil {
+			return nil, fmt.Errorf("创建路径 %s 失败: %w", path.Name, err)
+		}
+		result.PathsCreated++
+	}
+
+	return result, nil
+}
+
+// importNode 由点要素构建节点
+func (s *nav2Service) importNode(feature Nav2Feature, position domain.Position, transform nav2Transform) *domain.Node {
+	props := feature.Properties
+	metadata := props.Metadata
+
+	name, _ := propertyString(metadata, nav2MetaName)
+	if name == "" {
+		name = fmt.Sprintf("node_%d", props.ID)
+	}
+	nodeType, _ := propertyString(metadata, nav2MetaType)
+	if nodeType == "" {
+		nodeType = "point"
+	}
+
+	node := domain.NewNode(name, nodeType)
+	node.Position = transform.fromMap(position)
+	node.Properties = nav2Properties(metadata, nav2MetaName, nav2MetaType)
+	node.Properties[nav2IDKey] = props.ID
+	if props.Frame != "" {
+		node.Properties[nav2FrameKey] = props.Frame
+	}
+	if len(props.Operations) > 0 {
+		node.Properties[nav2OpsKey] = props.Operations
+	}
+	return node
+}
+
+// importPath 由（合并后的）边要素构建路径
+func (s *nav2Service) importPath(edge *nav2ImportEdge, startID, endID domain.NodeID, transform nav2Transform) *domain.Path {
+	props := edge.feature.Properties
+	metadata := props.Metadata
+
+	name, _ := propertyString(metadata, nav2MetaName)
+	if name == "" {
+		name = fmt.Sprintf("edge_%d", props.ID)
+	}
+
+	path := domain.NewPath(name, startID, endID)
+	path.Direction = domain.DirectionForward
+	path.Properties = nav2Properties(metadata, nav2MetaName, nav2MetaPathID, nav2MetaCurveType, nav2MetaWaypoints)
+	path.Properties[nav2IDKey] = props.ID
+	if edge.reverse != nil {
+		path.Direction = domain.DirectionBidirectional
+		path.Properties[nav2ReverseIDKey] = edge.reverse.Properties.ID
+	}
+	if props.Cost != nil {
+		path.Weight = *props.Cost
+		path.Properties[nav2CostKey] = *props.Cost
+	}
+	if props.Overridable != nil {
+		path.Properties[nav2OverrideKey] = *props.Overridable
+	}
+	if len(props.Operations) > 0 {
+		path.Properties[nav2OpsKey] = props.Operations
+	}
+
+	// 优先使用编辑器写入的曲线类型和途经点，否则把几何的中间点作为折线途经点
+	if curveType, ok := propertyString(metadata, nav2MetaCurveType); ok {
+		path.CurveType = domain.CurveType(curveType)
+	}
+	if waypoints, ok := nav2Waypoints(metadata[nav2MetaWaypoints]); ok {
+		for _, waypoint := range waypoints {
+			path.Waypoints = append(path.Waypoints, transform.fromMap(waypoint))
+		}
+	} else if len(edge.points) > 2 {
+		path.CurveType = domain.CurveTypeLinear
+		for _, point := range edge.points[1 : len(edge.points)-1] {
+			path.Waypoints = append(path.Waypoints, transform.fromMap(point))
+		}
+	}
+
+	return path
+}
+
+// nav2MergeEdges 合并互为反向的边：优先按 metadata.path_id 分组，其次在 merge 为真时按起止节点配对
+func nav2MergeEdges(edges []*nav2ImportEdge, merge bool) []*nav2ImportEdge {
+	var merged []*nav2ImportEdge
+	byPathID := make(map[string]*nav2ImportEdge)
+	byEnds := make(map[[2]uint64]*nav2ImportEdge)
+
+	for _, edge := range edges {
+		props := edge.feature.Properties
+		start, end := *props.StartID, *props.EndID
+
+		if pathID, ok := propertyString(props.Metadata, nav2MetaPathID); ok {
+			if first, exists := byPathID[pathID]; exists && first.reverse == nil &&
+				*first.feature.Properties.StartID == end && *first.feature.Properties.EndID == start {
+				feature := edge.feature
+				first.reverse = &feature
+				continue
+			}
+			byPathID[pathID] = edge
+			merged = append(merged, edge)
+			continue
+		}
+
+		if merge {
+			if first, exists := byEnds[[2]uint64{end, start}]; exists && first.reverse == nil {
+				feature := edge.feature
+				first.reverse = &feature
+				delete(byEnds, [2]uint64{end, start})
+				continue
+			}
+			byEnds[[2]uint64{start, end}] = edge
+		}
+		merged = append(merged, edge)
+	}
+
+	return merged
+}
+
+// nav2LinePoints 读取 LineString / MultiLineString 坐标（多段时首尾拼接）
+func nav2LinePoints(geometry Nav2Geometry) ([]domain.Position, error) {
+	var lines [][][]float64
+	if geometry.Type == "LineString" {
+		var line [][]float64
+		if err := json.Unmarshal(geometry.Coordinates, &line); err != nil {
+			return nil, fmt.Errorf("线坐标无效: %w", err)
+		}
+		lines = [][][]float64{line}
+	} else if err := json.Unmarshal(geometry.Coordinates, &lines); err != nil {
+		return nil, fmt.Errorf("多段线坐标无效: %w", err)
+	}
+
+	var points []domain.Position
+	for _, line := range lines {
+		for _, coord := range line {
+			if len(coord) < 2 {
+				return nil, fmt.Errorf("坐标至少需要x和y")
+			}
+			point := domain.Position{X: coord[0], Y: coord[1]}
+			if n := len(points); n > 0 && points[n-1] == point {
+				continue
+			}
+			points = append(points, point)
+		}
+	}
+	return points, nil
+}
+
+// nav2Waypoints 读取 metadata 中的途经点 [[x, y], ...]
+func nav2Waypoints(raw interface{}) ([]domain.Position, bool) {
+	items, ok := raw.([]interface{})
+	if !ok || len(items) == 0 {
+		return nil, false
+	}
+	waypoints := make([]domain.Position, 0, len(items))
+	for _, item := range items {
+		coord, ok := item.([]interface{})
+		if !ok || len(coord) < 2 {
+			return nil, false
+		}
+		x, xOK := coord[0].(float64)
+		y, yOK := coord[1].(float64)
+		if !xOK || !yOK {
+			return nil, false
+		}
+		waypoints = append(waypoints, domain.Position{X: x, Y: y})
+	}
+	return waypoints, true
+}
+
+// nav2Metadata 由扩展属性生成 metadata（排除映射到要素字段的属性）
+func nav2Metadata(props map[string]interface{}) map[string]interface{} {
+	metadata := make(map[string]interface{}, len(props))
+	for key, value := range props {
+		if !nav2ReservedKeys[key] {
+			metadata[key] = value
+		}
+	}
+	return metadata
+}
+
+// nav2Properties 由 metadata 生成扩展属性（排除编辑器写入的键）
+func nav2Properties(metadata map[string]interface{}, exclude ...string) map[string]interface{} {
+	props := make(map[string]interface{}, len(metadata))
+	for key, value := range metadata {
+		props[key] = value
+	}
+	for _, key := range exclude {
+		delete(props, key)
+	}
+	return props
+}
+
+// nav2Operations 读取 operations 属性
+func nav2Operations(props map[string]interface{}) map[string]interface{} {
+	operations, _ := props[nav2OpsKey].(map[string]interface{})
+	return operations
+}
+
+// nav2IDAllocator 路由图ID分配器，节点和边共用一个ID空间
+type nav2IDAllocator struct {
+	reserved map[uint64]bool // 属性中已记录的ID
+	assigned map[uint64]bool // 本次导出已使用的ID
+	next     uint64
+}
+
+func newNav2IDAllocator() *nav2IDAllocator {
+	return &nav2IDAllocator{reserved: make(map[uint64]bool), assigned: make(map[uint64]bool)}
+}
+
+// reserve 预留属性中已记录的ID，新分配的ID会避开它们
+func (a *nav2IDAllocator) reserve(props map[string]interface{}, key string) {
+	if id, ok := nav2PropertyID(props, key); ok {
+		a.reserved[id] = true
+	}
+}
+
+// assign 分配ID：属性中记录的ID未被使用时沿用（重复时先到先得），否则分配下一个空闲ID
+func (a *nav2IDAllocator) assign(props map[string]interface{}, key string) uint64 {
+	if id, ok := nav2PropertyID(props, key); ok && !a.assigned[id] {
+		a.assigned[id] = true
+		return id
+	}
+	for a.reserved[a.next] || a.assigned[a.next] {
+		a.next++
+	}
+	a.assigned[a.next] = true
+	return a.next
+}
+
+// nav2PropertyID 读取属性中记录的非负整数ID
+func nav2PropertyID(props map[string]interface{}, key string) (uint64, bool) {
+	v, ok := propertyFloat(props, key)
+	if !ok || v < 0 || v != math.Trunc(v) {
+		return 0, false
+	}
+	return uint64(v), true
+}
+
+// nav2Transform 画布坐标与地图坐标（米）的变换
+type nav2Transform struct {
+	scale         float64
+	flipY         bool
+	frame         string
+	curveSegments int
+}
+
+func newNav2Transform(opts Nav2GraphOptions) nav2Transform {
+	t := nav2Transform{scale: 1, flipY: opts.FlipY, frame: opts.Frame, curveSegments: opts.CurveSegments}
+	if opts.Scale > 0 {
+		t.scale = opts.Scale
+	}
+	if t.frame == "" {
+		t.frame = "map"
+	}
+	if t.curveSegments <= 0 {
+		t.curveSegments = 16
+	}
+	return t
+}
+
+func (t nav2Transform) toMap(p domain.Position) domain.Position {
+	y := p.Y * t.scale
+	if t.flipY {
+		y = -y
+	}
+	return domain.Position{X: p.X * t.scale, Y: y, Z: p.Z * t.scale}
+}
+
+func (t nav2Transform) fromMap(p domain.Position) domain.Position {
+	y := p.Y / t.scale
+	if t.flipY {
+		y = -y
+	}
+	return domain.Position{X: p.X / t.scale, Y: y, Z: p.Z / t.scale}
+}
+
+// roundNav2 保留6位小数
+func roundNav2(v float64) float64 {
+	return math.Round(v*1e6) / 1e6
+}
+
+// === nav_msgs/Path 导出 ===
+
+// ExportNavPath 将路线导出为 nav_msgs/Path
+// 位姿沿曲线采样，朝向为前进方向；终点节点配置了 theta 属性（弧度）时使用该朝向
+func (s *nav2Service) ExportNavPath(ctx context.Context, req NavPathRequest) (*NavPathFile, error) {
+	format := strings.ToLower(req.Format)
+	if format == "" {
+		format = "yaml"
+	}
+	if format != "yaml" && format != "json" {
+		return nil, fmt.Errorf("不支持的格式: %s", req.Format)
+	}
+
+	route, err := s.routes.Resolve(ctx, req.RouteRequest)
+	if err != nil {
+		return nil, err
+	}
+
+	transform := newNav2Transform(Nav2GraphOptions{
+		Frame: req.FrameID, Scale: req.Scale, FlipY: req.FlipY, CurveSegments: req.CurveSegments,
+	})
+
+	points := []domain.Position{transform.toMap(route.Nodes[0].Position)}
+	for _, leg := range route.Legs {
+		start := transform.toMap(leg.From.Position)
+		end := transform.toMap(leg.To.Position)
+		legPoints := []domain.Position{start}
+		curveType := domain.CurveTypeLinear
+		if leg.Path != nil {
+			curveType = leg.Path.CurveType
+			for i := range leg.Path.Waypoints {
+				waypoint := leg.Path.Waypoints[i]
+				if leg.Reversed {
+					waypoint = leg.Path.Waypoints[len(leg.Path.Waypoints)-1-i]
+				}
+				legPoints = append(legPoints, transform.toMap(waypoint))
+			}
+		}
+		legPoints = append(legPoints, end)
+		points = append(points, domain.SampleCurve(legPoints, curveType, transform.curveSegments)[1:]...)
+	}
+
+	now := time.Now()
+	header := NavHeader{
+		Stamp:   NavTime{Sec: now.Unix(), Nanosec: uint32(now.Nanosecond())},
+		FrameID: transform.frame,
+	}
+	navPath := &NavPath{Header: header, Poses: make([]PoseStamped, 0, len(points))}
+
+	yaw := 0.0
+	for i, point := range points {
+		if i+1 < len(points) {
+			next := points[i+1]
+			if point.DistanceTo(next) > 1e-9 {
+				yaw = math.Atan2(next.Y-point.Y, next.X-point.X)
+			}
+		} else if theta, ok := propertyFloat(route.Nodes[len(route.Nodes)-1].Properties, "theta"); ok {
+			yaw = theta
+		}
+
+		q := domain.QuaternionFromEuler(0, 0, yaw*180/math.Pi)
+		navPath.Poses = append(navPath.Poses, PoseStamped{
+			Header: header,
+			Pose: NavPose{
+				Position:    NavPoint{X: roundNav2(point.X), Y: roundNav2(point.Y), Z: roundNav2(point.Z)},
+				Orientation: NavQuaternion{X: roundNav2(q.X), Y: roundNav2(q.Y), Z: roundNav2(q.Z), W: roundNav2(q.W)},
+			},
+		})
+	}
+
+	var buf bytes.Buffer
+	if format == "json" {
+		encoder := json.NewEncoder(&buf)
+		encoder.SetIndent("", "  ")
+		err = encoder.Encode(navPath)
+	} else {
+		// 与 ros2 topic echo 的输出保持一致的2空格缩进
+		encoder := yaml.NewEncoder(&buf)
+		encoder.SetIndent(2)
+		err = encoder.Encode(navPath)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("序列化路径失败: %w", err)
+	}
+	content := buf.Bytes()
+
+	return &NavPathFile{
+		Filename: "path." + format,
+		Format:   format,
+		Content:  string(content),
+		Path:     navPath,
+	}, nil
+}
@@ -0,0 +1,43 @@
+package services
+
+import (
+	"testing"
+
+	"robot-path-editor/internal/domain"
+)
+
+func TestNav2ImportPathDirection(t *testing.T) {
+	svc := &nav2Service{}
+	transform := newNav2Transform(Nav2GraphOptions{})
+	startID := domain.NodeID("start")
+	endID := domain.NodeID("end")
+
+	tests := []struct {
+		name        string
+		reverse     *Nav2Feature
+		want        string
+		wantReverse bool
+	}{
+		{name: "单向边", want: domain.DirectionForward},
+		{name: "合并反向边", reverse: &Nav2Feature{Properties: Nav2FeatureProperties{ID: 2}}, want: domain.DirectionBidirectional, wantReverse: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			edge := &nav2ImportEdge{
+				feature: Nav2Feature{Properties: Nav2FeatureProperties{ID: 1}},
+				reverse: tt.reverse,
+			}
+			path := svc.importPath(edge, startID, endID, transform)
+			if path.Direction != tt.want {
+				t.Fatalf("Direction = %q, want %q", path.Direction, tt.want)
+			}
+			if !path.CanTraverse(startID) {
+				t.Errorf("CanTraverse(start) = false, want true")
+			}
+			if got := path.CanTraverse(endID); got != tt.wantReverse {
+				t.Errorf("CanTraverse(end) = %v, want %v", got, tt.wantReverse)
+			}
+		})
+	}
+}
@@ -0,0 +1,575 @@
+// Package services 占据栅格解码、轮廓提取与瓦片渲染
+//
+// - map.yaml 与图像的解释规则与 ROS map_server 保持一致（trinary/scale/raw 三种模式）
+// - 障碍物轮廓沿占据栅格的像素边界追踪，再用 Douglas-Peucker 算法简化
+// - 瓦片金字塔：最高级为原始分辨率，每降一级边长缩小一半，降采样时障碍物优先
+package services
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"math"
+	"strconv"
+
+	"gopkg.in/yaml.v3"
+
+	"robot-path-editor/internal/domain"
+)
+
+// mapTileSize 瓦片边长（像素）
+const mapTileSize = 256
+
+// 地图图像的尺寸上限，解码前按文件头检查，避免按伪造的尺寸分配内存
+const (
+	mapImageMaxSide   = 16384
+	mapImageMaxPixels = 8192 * 8192
+)
+
+// occupancyGrid 解码后的栅格，按行存储，第0行为图像顶部
+type occupancyGrid struct {
+	width, height int
+	cells         []int8
+	occupiedMin   int8 // 不小于该值的栅格视为障碍物
+}
+
+func (g *occupancyGrid) at(col, row int) int8 {
+	return g.cells[row*g.width+col]
+}
+
+func (g *occupancyGrid) occupied(col, row int) bool {
+	if col < 0 || col >= g.width || row < 0 || row >= g.height {
+		return false
+	}
+	return g.cells[row*g.width+col] >= g.occupiedMin
+}
+
+// === map.yaml ===
+
+// mapYAML ROS map.yaml 的字段
+type mapYAML struct {
+	Image          string    `yaml:"image"`
+	Resolution     float64   `yaml:"resolution"`
+	Origin         []float64 `yaml:"origin"`
+	Negate         yaml.Node `yaml:"negate"`
+	OccupiedThresh *float64  `yaml:"occupied_thresh"`
+	FreeThresh     *float64  `yaml:"free_thresh"`
+	Mode           string    `yaml:"mode"`
+}
+
+// applyMapYAML 解析 map.yaml 并写入地图参数，返回其中引用的图像文件名
+func applyMapYAML(m *domain.OccupancyMap, data []byte) (string, error) {
+	var spec mapYAML
+	if err := yaml.Unmarshal(data, &spec); err != nil {
+		return "", fmt.Errorf("解析map.yaml失败: %w", err)
+	}
+
+	if spec.Resolution <= 0 {
+		return "", fmt.Errorf("map.yaml 缺少有效的 resolution")
+	}
+	m.Resolution = spec.Resolution
+
+	if len(spec.Origin) > 0 {
+		if len(spec.Origin) < 2 {
+			return "", fmt.Errorf("map.yaml 的 origin 至少需要 x 和 y")
+		}
+		m.Origin = domain.MapOrigin{X: spec.Origin[0], Y: spec.Origin[1]}
+		if len(spec.Origin) > 2 {
+			m.Origin.Yaw = spec.Origin[2]
+		}
+	}
+
+	// negate 在不同工具生成的文件中可能是 0/1 或 true/false
+	if spec.Negate.Value != "" {
+		value := spec.Negate.Value
+		if b, err := strconv.ParseBool(value); err == nil {
+			m.Negate = b
+		} else if n, err := strconv.Atoi(value); err == nil {
+			m.Negate = n != 0
+		} else {
+			return "", fmt.Errorf("map.yaml 的 negate 无效: %s", value)
+		}
+	}
+
+	if spec.OccupiedThresh != nil {
+		m.OccupiedThresh = *spec.OccupiedThresh
+	}
+	if spec.FreeThresh != nil {
+		m.FreeThresh = *spec.FreeThresh
+	}
+	if spec.Mode != "" {
+		m.Mode = spec.Mode
+	}
+
+	return spec.Image, nil
+}
+
+// === 图像解码 ===
+
+// decodeMapImage 解码PGM（P2/P5）或PNG图像，返回图像和格式名
+func decodeMapImage(data []byte) (image.Image, string, error) {
+	switch {
+	case bytes.HasPrefix(data, []byte("P5")), bytes.HasPrefix(data, []byte("P2")):
+		img, err := decodePGM(data)
+		return img, "pgm", err
+	case bytes.HasPrefix(data, []byte("\x89PNG")):
+		cfg, err := png.DecodeConfig(bytes.NewReader(data))
+		if err != nil {
+			return nil, "", fmt.Errorf("解码PNG失败: %w", err)
+		}
+		if err := checkMapImageSize(cfg.Width, cfg.Height); err != nil {
+			return nil, "", err
+		}
+		img, err := png.Decode(bytes.NewReader(data))
+		if err != nil {
+			return nil, "", fmt.Errorf("解码PNG失败: %w", err)
+		}
+		return img, "png", nil
+	default:
+		return nil, "", fmt.Errorf("不支持的地图图像格式，仅支持PGM和PNG")
+	}
+}
+
+// checkMapImageSize 检查图像尺寸不超过上限
+func checkMapImageSize(width, height int) error {
+	if width > mapImageMaxSide || height > mapImageMaxSide || width*height > mapImageMaxPixels {
+		return fmt.Errorf("地图图像 %dx%d 超过尺寸上限（边长 %d、共 %d 像素）", width, height, mapImageMaxSide, mapImageMaxPixels)
+	}
+	return nil
+}
+
+// decodePGM 解码PGM图像，maxval大于255时按比例缩放到8位
+func decodePGM(data []byte) (*image.Gray, error) {
+	src := bytes.NewReader(data)
+	r := bufio.NewReader(src)
+
+	magic, err := pgmToken(r)
+	if err != nil {
+		return nil, err
+	}
+	var header [3]int
+	for i := range header {
+		token, err := pgmToken(r)
+		if err != nil {
+			return nil, err
+		}
+		if header[i], err = strconv.Atoi(token); err != nil || header[i] <= 0 {
+			return nil, fmt.Errorf("PGM文件头无效: %s", token)
+		}
+	}
+	width, height, maxval := header[0], header[1], header[2]
+	if maxval > 65535 {
+		return nil, fmt.Errorf("PGM的maxval无效: %d", maxval)
+	}
+	if err := checkMapImageSize(width, height); err != nil {
+		return nil, err
+	}
+
+	// 分配前确认剩余数据足够：P5 每像素1或2字节，P2 每像素至少一位数字和一个分隔符
+	remaining := src.Len() + r.Buffered()
+	bytesPerPixel := 1
+	if maxval > 255 {
+		bytesPerPixel = 2
+	}
+	need := width * height * bytesPerPixel
+	if magic != "P5" {
+		need = 2*width*height - 1
+	}
+	if remaining < need {
+		return nil, fmt.Errorf("PGM像素数据不完整: %dx%d 的图像至少需要 %d 字节，只有 %d 字节", width, height, need, remaining)
+	}
+
+	img := image.NewGray(image.Rect(0, 0, width, height))
+	scale := func(v int) uint8 { return uint8(v * 255 / maxval) }
+
+	if magic == "P5" {
+		raw := make([]byte, need)
+		if _, err := io.ReadFull(r, raw); err != nil {
+			return nil, fmt.Errorf("PGM像素数据不完整: %w", err)
+		}
+		for i := range img.Pix {
+			v := int(raw[i])
+			if bytesPerPixel == 2 {
+				v = int(raw[2*i])<<8 | int(raw[2*i+1])
+			}
+			img.Pix[i] = scale(v)
+		}
+		return img, nil
+	}
+
+	for i := range img.Pix {
+		token, err := pgmToken(r)
+		if err != nil {
+			return nil, fmt.Errorf("PGM像素数据不完整: %w", err)
+		}
+		v, err := strconv.Atoi(token)
+		if err != nil {
+			return nil, fmt.Errorf("PGM像素值无效: %s", token)
+		}
+		img.Pix[i] = scale(v)
+	}
+	return img, nil
+}
+
+// pgmToken 读取下一个以空白分隔的标记，跳过#注释；二进制数据前恰好消耗一个空白字符
+func pgmToken(r *bufio.Reader) (string, error) {
+	var token []byte
+	for {
+		b, err := r.ReadByte()
+		if err != nil {
+			if err == io.EOF && len(token) > 0 {
+				return string(token), nil
+			}
+			return "", fmt.Errorf("PGM文件不完整")
+		}
+		switch {
+		case b == '#' && len(token) == 0:
+			if _, err := r.ReadString('\n'); err != nil {
+				return "", fmt.Errorf("PGM文件不完整")
+			}
+		case b == ' ' || b == '\t' || b == '\n' || b == '\r':
+			if len(token) > 0 {
+				return string(token), nil
+			}
+		default:
+			token = append(token, b)
+		}
+	}
+}
+
+// buildOccupancyGrid 按地图模式和阈值将图像转换为栅格
+func buildOccupancyGrid(m *domain.OccupancyMap, img image.Image) *occupancyGrid {
+	bounds := img.Bounds()
+	g := &occupancyGrid{
+		width:       bounds.Dx(),
+		height:      bounds.Dy(),
+		cells:       make([]int8, bounds.Dx()*bounds.Dy()),
+		occupiedMin: domain.CellOccupied,
+	}
+	if m.Mode == domain.MapModeRaw {
+		g.occupiedMin = int8(math.Ceil(m.OccupiedThresh * 100))
+	}
+
+	gray, isGray := img.(*image.Gray)
+	for row := 0; row < g.height; row++ {
+		for col := 0; col < g.width; col++ {
+			var shade, alpha float64 = 0, 255
+			if isGray {
+				shade = float64(gray.Pix[row*gray.Stride+col])
+			} else {
+				c := color.NRGBAModel.Convert(img.At(bounds.Min.X+col, bounds.Min.Y+row)).(color.NRGBA)
+				shade = (float64(c.R) + float64(c.G) + float64(c.B)) / 3
+				alpha = float64(c.A)
+			}
+			g.cells[row*g.width+col] = classifyCell(m, shade, alpha)
+		}
+	}
+	return g
+}
+
+// classifyCell 将像素灰度（0-255）转换为占据值，规则同 map_server
+func classifyCell(m *domain.OccupancyMap, shade, alpha float64) int8 {
+	if m.Mode == domain.MapModeRaw {
+		if shade > 100 {
+			return domain.CellUnknown
+		}
+		return int8(shade)
+	}
+
+	occ := (255 - shade) / 255
+	if m.Negate {
+		occ = shade / 255
+	}
+
+	switch {
+	case m.Mode == domain.MapModeScale && alpha < 255:
+		return domain.CellUnknown
+	case occ > m.OccupiedThresh:
+		return domain.CellOccupied
+	case occ < m.FreeThresh:
+		return domain.CellFree
+	case m.Mode == domain.MapModeScale:
+		return int8(math.Round(99 * (occ - m.FreeThresh) / (m.OccupiedThresh - m.FreeThresh)))
+	default:
+		return domain.CellUnknown
+	}
+}
+
+// === 轮廓提取 ===
+
+// gridEdge 栅格边界上的有向边，顶点坐标以左下角为原点、Y轴向上
+type gridEdge struct {
+	from, to int // 顶点索引 vy*(width+1)+vx
+	dir      int // 0:+X 1:+Y 2:-X 3:-Y
+}
+
+// extractObstacles 提取障碍物轮廓
+// 沿占据栅格与非占据栅格的分界生成有向边（障碍物在左侧），串联成闭合环后简化；
+// 外轮廓为逆时针，内孔为顺时针。tolerance 为简化容差（米），minArea 为最小面积（平方米）
+func extractObstacles(m *domain.OccupancyMap, g *occupancyGrid, tolerance, minArea float64) []domain.ObstaclePolygon {
+	w, h := g.width, g.height
+	vertex := func(vx, vy int) int { return vy*(w+1) + vx }
+
+	var edges []gridEdge
+	outgoing := make(map[int][]int)
+	addEdge := func(x0, y0, x1, y1, dir int) {
+		from := vertex(x0, y0)
+		outgoing[from] = append(outgoing[from], len(edges))
+		edges = append(edges, gridEdge{from: from, to: vertex(x1, y1), dir: dir})
+	}
+
+	for row := 0; row < h; row++ {
+		for col := 0; col < w; col++ {
+			if !g.occupied(col, row) {
+				continue
+			}
+			y0 := h - row - 1 // 栅格下边界（Y轴向上）
+			if !g.occupied(col, row+1) {
+				addEdge(col, y0, col+1, y0, 0)
+			}
+			if !g.occupied(col+1, row) {
+				addEdge(col+1, y0, col+1, y0+1, 1)
+			}
+			if !g.occupied(col, row-1) {
+				addEdge(col+1, y0+1, col, y0+1, 2)
+			}
+			if !g.occupied(col-1, row) {
+				addEdge(col, y0+1, col, y0, 3)
+			}
+		}
+	}
+
+	used := make([]bool, len(edges))
+	var obstacles []domain.ObstaclePolygon
+
+	for start := range edges {
+		if used[start] {
+			continue
+		}
+
+		// 沿边界行走，在对角接触的鞍点优先左转，使对角相邻的障碍物连成一体
+		var ring []domain.MapPoint
+		current := start
+		lastDir := -1
+		for {
+			used[current] = true
+			edge := edges[current]
+			if edge.dir != lastDir {
+				vx, vy := edge.from%(w+1), edge.from/(w+1)
+				p := m.CellToWorld(float64(vx), float64(h-vy))
+				ring = append(ring, domain.MapPoint{X: roundMicro(p.X), Y: roundMicro(p.Y)})
+				lastDir = edge.dir
+			}
+			if edge.to == edges[start].from {
+				break
+			}
+
+			next := -1
+			for _, turn := range []int{1, 0, 3} {
+				for _, candidate := range outgoing[edge.to] {
+					if !used[candidate] && edges[candidate].dir == (edge.dir+turn)%4 {
+						next = candidate
+						break
+					}
+				}
+				if next >= 0 {
+					break
+				}
+			}
+			if next < 0 {
+				break
+			}
+			current = next
+		}
+
+		// 起点恰好是方向变化点之外时，首尾可能共线
+		if len(ring) > 2 && edges[current].dir == edges[start].dir {
+			ring = ring[1:]
+		}
+
+		ring = simplifyRing(ring, tolerance)
+		if len(ring) < 3 {
+			continue
+		}
+		polygon := domain.NewObstaclePolygon(ring)
+		if polygon.Area < minArea {
+			continue
+		}
+		obstacles = append(obstacles, polygon)
+	}
+
+	return obstacles
+}
+
+// roundMicro 坐标保留到微米，消除浮点误差
+func roundMicro(v float64) float64 {
+	return math.Round(v*1e6) / 1e6
+}
+
+// simplifyRing 用 Douglas-Peucker 算法简化闭合环：以首点和离它最远的点切成两条折线分别简化
+func simplifyRing(ring []domain.MapPoint, tolerance float64) []domain.MapPoint {
+	if tolerance <= 0 || len(ring) < 4 {
+		return ring
+	}
+
+	far, farDist := 0, -1.0
+	for i, p := range ring {
+		if d := math.Hypot(p.X-ring[0].X, p.Y-ring[0].Y); d > farDist {
+			far, farDist = i, d
+		}
+	}
+
+	closed := append(append([]domain.MapPoint{}, ring...), ring[0])
+	first := simplifyPolyline(closed[:far+1], tolerance)
+	second := simplifyPolyline(closed[far:], tolerance)
+	return append(first[:len(first)-1], second[:len(second)-1]...)
+}
+
+// simplifyPolyline Douglas-Peucker 折线简化，保留首尾点
+func simplifyPolyline(points []domain.MapPoint, tolerance float64) []domain.MapPoint {
+	if len(points) < 3 {
+		return points
+	}
+
+	a, b := points[0], points[len(points)-1]
+	index, maxDist := 0, 0.0
+	for i := 1; i < len(points)-1; i++ {
+		if d := pointSegmentDistance(points[i], a, b); d > maxDist {
+			index, maxDist = i, d
+		}
+	}
+	if maxDist <= tolerance {
+		return []domain.MapPoint{a, b}
+	}
+
+	left := simplifyPolyline(points[:index+1], tolerance)
+	right := simplifyPolyline(points[index:], tolerance)
+	return append(left[:len(left)-1], right...)
+}
+
+// pointSegmentDistance 点到线段的距离
+func pointSegmentDistance(p, a, b domain.MapPoint) float64 {
+	dx, dy := b.X-a.X, b.Y-a.Y
+	lengthSq := dx*dx + dy*dy
+	if lengthSq == 0 {
+		return math.Hypot(p.X-a.X, p.Y-a.Y)
+	}
+	t := math.Max(0, math.Min(1, ((p.X-a.X)*dx+(p.Y-a.Y)*dy)/lengthSq))
+	return math.Hypot(p.X-(a.X+t*dx), p.Y-(a.Y+t*dy))
+}
+
+// === 瓦片渲染 ===
+
+// MapTileLayout 瓦片金字塔布局，供画布放置背景
+type MapTileLayout struct {
+	TileSize int              `json:"tile_size"`
+	MaxZoom  int              `json:"max_zoom"` // 该级为原始分辨率
+	Width    int              `json:"width"`
+	Height   int              `json:"height"`
+	Bounds   domain.MapBounds `json:"bounds"`
+}
+
+// mapTileLayout 计算地图的瓦片布局
+func mapTileLayout(m *domain.OccupancyMap) MapTileLayout {
+	size := math.Max(float64(m.Width), float64(m.Height))
+	maxZoom := 0
+	if size > mapTileSize {
+		maxZoom = int(math.Ceil(math.Log2(size / mapTileSize)))
+	}
+	return MapTileLayout{
+		TileSize: mapTileSize,
+		MaxZoom:  maxZoom,
+		Width:    m.Width,
+		Height:   m.Height,
+		Bounds:   m.Bounds(),
+	}
+}
+
+// 瓦片配色，与 RViz/map_server 的灰度习惯一致
+var (
+	tileOccupied = color.NRGBA{A: 255}
+	tileFree     = color.NRGBA{R: 254, G: 254, B: 254, A: 255}
+	tileUnknown  = color.NRGBA{R: 205, G: 205, B: 205, A: 255}
+)
+
+// renderMapTile 渲染瓦片 (z, x, y)，y 从图像顶部起算；超出地图的部分透明
+func renderMapTile(g *occupancyGrid, layout MapTileLayout, z, x, y int) ([]byte, error) {
+	if z < 0 || z > layout.MaxZoom {
+		return nil, fmt.Errorf("瓦片层级超出范围 [0, %d]: %d", layout.MaxZoom, z)
+	}
+	factor := 1 << (layout.MaxZoom - z)
+	span := mapTileSize * factor
+	if x < 0 || y < 0 || x*span >= g.width || y*span >= g.height {
+		return nil, fmt.Errorf("瓦片超出地图范围: %d/%d/%d", z, x, y)
+	}
+
+	img := image.NewNRGBA(image.Rect(0, 0, mapTileSize, mapTileSize))
+	for ty := 0; ty < mapTileSize; ty++ {
+		row0 := y*span + ty*factor
+		if row0 >= g.height {
+			break
+		}
+		for tx := 0; tx < mapTileSize; tx++ {
+			col0 := x*span + tx*factor
+			if col0 >= g.width {
+				break
+			}
+			img.SetNRGBA(tx, ty, tileColor(g, col0, row0, factor))
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, fmt.Errorf("编码瓦片失败: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// renderMapImage 按原始分辨率渲染整张地图
+func renderMapImage(g *occupancyGrid) ([]byte, error) {
+	img := image.NewNRGBA(image.Rect(0, 0, g.width, g.height))
+	for row := 0; row < g.height; row++ {
+		for col := 0; col < g.width; col++ {
+			img.SetNRGBA(col, row, tileColor(g, col, row, 1))
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, fmt.Errorf("编码地图图像失败: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// tileColor 计算 factor×factor 栅格块的颜色：有障碍物即为障碍物，其次空闲，否则未知
+func tileColor(g *occupancyGrid, col0, row0, factor int) color.NRGBA {
+	free, unknown := false, false
+	for row := row0; row < row0+factor && row < g.height; row++ {
+		for col := col0; col < col0+factor && col < g.width; col++ {
+			v := g.at(col, row)
+			switch {
+			case v >= g.occupiedMin:
+				return tileOccupied
+			case v == domain.CellFree:
+				free = true
+			case v == domain.CellUnknown:
+				unknown = true
+			default:
+				// scale 模式下的中间值按灰度显示
+				shade := uint8(254 - int(v)*254/100)
+				return color.NRGBA{R: shade, G: shade, B: shade, A: 255}
+			}
+		}
+	}
+	if free {
+		return tileFree
+	}
+	if unknown {
+		return tileUnknown
+	}
+	return tileUnknown
+}
@@ -0,0 +1,70 @@
+package services
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/png"
+	"strings"
+	"testing"
+)
+
+func TestDecodePGM(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		want    []uint8
+		wantErr string
+	}{
+		{name: "P5 二进制", data: "P5\n# comment\n3 1\n255\n\x00\x80\xff", want: []uint8{0, 128, 255}},
+		{name: "P5 16位按比例缩放", data: "P5 2 1 65535\n\x00\x00\xff\xff", want: []uint8{0, 255}},
+		{name: "P2 文本", data: "P2\n3 1\n100\n0 50 100\n", want: []uint8{0, 127, 255}},
+		{name: "P2 末尾没有分隔符", data: "P2 2 1 255 7 9", want: []uint8{7, 9}},
+		{name: "边长超过上限", data: "P5 100000 1 255\n", wantErr: "超过尺寸上限"},
+		{name: "像素总数超过上限", data: "P5 16384 16384 255\n", wantErr: "超过尺寸上限"},
+		{name: "P5 数据不足时不分配", data: "P5 4000 4000 255\n\x00\x00", wantErr: "至少需要 16000000 字节"},
+		{name: "P5 16位数据不足", data: "P5 2 1 65535\n\x00\x00\xff", wantErr: "像素数据不完整"},
+		{name: "P2 数据不足", data: "P2 3 1 255\n1 2", wantErr: "像素数据不完整"},
+		{name: "尺寸无效", data: "P5 0 1 255\n", wantErr: "文件头无效"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			img, err := decodePGM([]byte(tt.data))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("decodePGM() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("decodePGM() error = %v", err)
+			}
+			if !bytes.Equal(img.Pix, tt.want) {
+				t.Errorf("decodePGM() pixels = %v, want %v", img.Pix, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecodeMapImagePNGSize(t *testing.T) {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewGray(image.Rect(0, 0, 2, 2))); err != nil {
+		t.Fatal(err)
+	}
+	small := buf.Bytes()
+
+	if _, format, err := decodeMapImage(small); err != nil || format != "png" {
+		t.Fatalf("decodeMapImage() = %q, %v, want png", format, err)
+	}
+
+	// 改写 IHDR 中的尺寸并重新计算校验和，像素数据仍然只有 2x2
+	huge := append([]byte(nil), small...)
+	binary.BigEndian.PutUint32(huge[16:], 60000)
+	binary.BigEndian.PutUint32(huge[20:], 60000)
+	binary.BigEndian.PutUint32(huge[29:], crc32.ChecksumIEEE(huge[12:29]))
+	if _, _, err := decodeMapImage(huge); err == nil || !strings.Contains(err.Error(), "超过尺寸上限") {
+		t.Errorf("decodeMapImage() error = %v, want size limit error", err)
+	}
+}
@@ -0,0 +1,404 @@
+// Package services 占据栅格地图服务
+//
+// 设计参考：
+// - ROS map_server：map.yaml 描述分辨率、原点和阈值，PGM/PNG 保存栅格
+// - Web 地图的瓦片金字塔，画布按需加载背景
+//
+// 特点：
+// 1. 导入 map.yaml + PGM/PNG，按 map_server 规则解释栅格
+// 2. 由栅格提取障碍物多边形，供路径生成和布局校验使用
+// 3. 节点坐标即地图坐标（米），与栅格直接对齐
+package services
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"robot-path-editor/internal/domain"
+	"robot-path-editor/internal/repositories"
+)
+
+// OccupancyMapService 占据栅格地图服务接口
+type OccupancyMapService interface {
+	ImportMap(ctx context.Context, req ImportMapRequest) (*domain.OccupancyMap, error)
+	ListMaps(ctx context.Context) ([]*domain.OccupancyMap, error)
+	GetMap(ctx context.Context, id string) (*MapDetail, error)
+	DeleteMap(ctx context.Context, id string) error
+	ActivateMap(ctx context.Context, id string) error
+
+	// 画布背景
+	RenderImage(ctx context.Context, id string) ([]byte, error)
+	GetTile(ctx context.Context, id string, z, x, y int) ([]byte, error)
+
+	// 障碍物与校验
+	RegenerateObstacles(ctx context.Context, id string, opts ObstacleOptions) (*domain.OccupancyMap, error)
+	ValidateLayout(ctx context.Context, id string) (*MapValidationReport, error)
+
+	ObstacleSource
+}
+
+// ObstacleSource 提供当前地图的障碍物，没有当前地图时返回空
+type ObstacleSource interface {
+	ActiveObstacles(ctx context.Context) ([]domain.ObstaclePolygon, error)
+}
+
+// ImportMapRequest 导入地图请求
+type ImportMapRequest struct {
+	Name        string
+	Description string
+	YAML        []byte // map.yaml 内容
+	Image       []byte // PGM 或 PNG 图像
+	Activate    bool   // 导入后设为当前地图
+	ObstacleOptions
+}
+
+// ObstacleOptions 障碍物提取选项
+type ObstacleOptions struct {
+	SimplifyTolerance float64 `json:"simplify_tolerance,omitempty" form:"simplify_tolerance"` // 简化容差（米），默认等于分辨率
+	MinArea           float64 `json:"min_area,omitempty" form:"min_area"`                     // 最小面积（平方米），默认4个栅格
+}
+
+// MapDetail 地图详情，附带画布放置背景所需的瓦片布局
+type MapDetail struct {
+	Map   *domain.OccupancyMap `json:"map"`
+	Tiles MapTileLayout        `json:"tiles"`
+}
+
+// MapValidationReport 布局校验报告
+type MapValidationReport struct {
+	MapID        string               `json:"map_id"`
+	Valid        bool                 `json:"valid"`
+	CheckedNodes int                  `json:"checked_nodes"`
+	CheckedPaths int                  `json:"checked_paths"`
+	Issues       []MapValidationIssue `json:"issues"`
+}
+
+// MapValidationIssue 校验问题
+type MapValidationIssue struct {
+	Type     string           `json:"type"` // node_outside_map, node_in_obstacle, node_in_unknown, path_blocked
+	NodeID   domain.NodeID    `json:"node_id,omitempty"`
+	PathID   domain.PathID    `json:"path_id,omitempty"`
+	Position *domain.MapPoint `json:"position,omitempty"`
+	Message  string           `json:"message"`
+}
+
+// 校验问题类型
+const (
+	MapIssueNodeOutsideMap = "node_outside_map"
+	MapIssueNodeInObstacle = "node_in_obstacle"
+	MapIssueNodeInUnknown  = "node_in_unknown"
+	MapIssuePathBlocked    = "path_blocked"
+)
+
+// mapTileCacheLimit 瓦片缓存上限，超出后整体清空
+const mapTileCacheLimit = 2048
+
+// occupancyMapService 地图服务实现
+type occupancyMapService struct {
+	mapRepo  repositories.OccupancyMapRepository
+	nodeRepo repositories.NodeRepository
+	pathRepo repositories.PathRepository
+
+	mu    sync.Mutex
+	grids map[string]*occupancyGrid
+	tiles map[string][]byte
+}
+
+// NewOccupancyMapService 创建新的地图服务实例
+func NewOccupancyMapService(mapRepo repositories.OccupancyMapRepository, nodeRepo repositories.NodeRepository, pathRepo repositories.PathRepository) OccupancyMapService {
+	return &occupancyMapService{
+		mapRepo:  mapRepo,
+		nodeRepo: nodeRepo,
+		pathRepo: pathRepo,
+		grids:    make(map[string]*occupancyGrid),
+		tiles:    make(map[string][]byte),
+	}
+}
+
+// ImportMap 导入 map.yaml 和栅格图像
+func (s *occupancyMapService) ImportMap(ctx context.Context, req ImportMapRequest) (*domain.OccupancyMap, error) {
+	if len(req.YAML) == 0 {
+		return nil, fmt.Errorf("缺少 map.yaml")
+	}
+	if len(req.Image) == 0 {
+		return nil, fmt.Errorf("缺少地图图像")
+	}
+
+	m := domain.NewOccupancyMap(req.Name)
+	m.Description = req.Description
+	imageName, err := applyMapYAML(m, req.YAML)
+	if err != nil {
+		return nil, err
+	}
+	if m.Name == "" {
+		m.Name = strings.TrimSuffix(filepath.Base(imageName), filepath.Ext(imageName))
+		if m.Name == "" || m.Name == "." {
+			m.Name = "map"
+		}
+	}
+	if err := m.IsValid(); err != nil {
+		return nil, err
+	}
+
+	img, format, err := decodeMapImage(req.Image)
+	if err != nil {
+		return nil, err
+	}
+	m.Image = req.Image
+	m.ImageFormat = format
+	m.Width = img.Bounds().Dx()
+	m.Height = img.Bounds().Dy()
+
+	grid := buildOccupancyGrid(m, img)
+	m.Obstacles = extractObstacles(m, grid, s.tolerance(m, req.ObstacleOptions), s.minArea(m, req.ObstacleOptions))
+
+	if err := s.mapRepo.Create(ctx, m); err != nil {
+		return nil, fmt.Errorf("保存地图失败: %w", err)
+	}
+	if req.Activate {
+		if err := s.mapRepo.SetActive(ctx, m.ID); err != nil {
+			return nil, fmt.Errorf("设置当前地图失败: %w", err)
+		}
+		m.Active = true
+	}
+
+	s.mu.Lock()
+	s.grids[m.ID] = grid
+	s.mu.Unlock()
+
+	return m, nil
+}
+
+// ListMaps 列出地图
+func (s *occupancyMapService) ListMaps(ctx context.Context) ([]*domain.OccupancyMap, error) {
+	maps, err := s.mapRepo.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("获取地图列表失败: %w", err)
+	}
+	return maps, nil
+}
+
+// GetMap 获取地图详情
+func (s *occupancyMapService) GetMap(ctx context.Context, id string) (*MapDetail, error) {
+	m, err := s.mapRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return &MapDetail{Map: m, Tiles: mapTileLayout(m)}, nil
+}
+
+// DeleteMap 删除地图
+func (s *occupancyMapService) DeleteMap(ctx context.Context, id string) error {
+	if err := s.mapRepo.Delete(ctx, id); err != nil {
+		return err
+	}
+	s.invalidate(id)
+	return nil
+}
+
+// ActivateMap 设为当前地图
+func (s *occupancyMapService) ActivateMap(ctx context.Context, id string) error {
+	return s.mapRepo.SetActive(ctx, id)
+}
+
+// RenderImage 将整张地图渲染为PNG
+func (s *occupancyMapService) RenderImage(ctx context.Context, id string) ([]byte, error) {
+	_, grid, err := s.load(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return renderMapImage(grid)
+}
+
+// GetTile 获取瓦片
+func (s *occupancyMapService) GetTile(ctx context.Context, id string, z, x, y int) ([]byte, error) {
+	key := fmt.Sprintf("%s/%d/%d/%d", id, z, x, y)
+	s.mu.Lock()
+	tile, ok := s.tiles[key]
+	s.mu.Unlock()
+	if ok {
+		return tile, nil
+	}
+
+	m, grid, err := s.load(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	tile, err = renderMapTile(grid, mapTileLayout(m), z, x, y)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	if len(s.tiles) >= mapTileCacheLimit {
+		s.tiles = make(map[string][]byte)
+	}
+	s.tiles[key] = tile
+	s.mu.Unlock()
+	return tile, nil
+}
+
+// RegenerateObstacles 按新的选项重新提取障碍物
+func (s *occupancyMapService) RegenerateObstacles(ctx context.Context, id string, opts ObstacleOptions) (*domain.OccupancyMap, error) {
+	m, grid, err := s.load(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	m.Obstacles = extractObstacles(m, grid, s.tolerance(m, opts), s.minArea(m, opts))
+	m.Metadata.Version++
+	if err := s.mapRepo.Update(ctx, m); err != nil {
+		return nil, fmt.Errorf("保存障碍物失败: %w", err)
+	}
+	return m, nil
+}
+
+// ValidateLayout 校验节点和路径与地图的关系，id 为空时使用当前地图
+func (s *occupancyMapService) ValidateLayout(ctx context.Context, id string) (*MapValidationReport, error) {
+	if id == "" {
+		active, err := s.mapRepo.GetActive(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("获取当前地图失败: %w", err)
+		}
+		if active == nil {
+			return nil, fmt.Errorf("没有当前地图")
+		}
+		id = active.ID
+	}
+
+	m, grid, err := s.load(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	nodes, err := s.nodeRepo.List(ctx, repositories.NodeFilter{})
+	if err != nil {
+		return nil, fmt.Errorf("获取节点列表失败: %w", err)
+	}
+	paths, err := s.pathRepo.List(ctx, repositories.PathFilter{})
+	if err != nil {
+		return nil, fmt.Errorf("获取路径列表失败: %w", err)
+	}
+
+	report := &MapValidationReport{
+		MapID:        m.ID,
+		CheckedNodes: len(nodes),
+		CheckedPaths: len(paths),
+		Issues:       []MapValidationIssue{},
+	}
+
+	positions := make(map[domain.NodeID]domain.Position, len(nodes))
+	for _, node := range nodes {
+		positions[node.ID] = node.Position
+		point := domain.MapPoint{X: node.Position.X, Y: node.Position.Y}
+
+		col, row, ok := m.WorldToCell(point.X, point.Y)
+		issue := MapValidationIssue{NodeID: node.ID, Position: &point}
+		switch {
+		case !ok:
+			issue.Type = MapIssueNodeOutsideMap
+			issue.Message = fmt.Sprintf("节点 %s 位于地图范围之外", node.Name)
+		case grid.occupied(col, row):
+			issue.Type = MapIssueNodeInObstacle
+			issue.Message = fmt.Sprintf("节点 %s 位于障碍物内", node.Name)
+		case grid.at(col, row) == domain.CellUnknown:
+			issue.Type = MapIssueNodeInUnknown
+			issue.Message = fmt.Sprintf("节点 %s 位于未知区域", node.Name)
+		default:
+			continue
+		}
+		report.Issues = append(report.Issues, issue)
+	}
+
+	for _, path := range paths {
+		start, okStart := positions[path.StartNodeID]
+		end, okEnd := positions[path.EndNodeID]
+		if !okStart || !okEnd {
+			continue
+		}
+
+		samples := domain.SampleCurve(path.ControlPoints(start, end), path.CurveType, 32)
+		for i := 1; i < len(samples); i++ {
+			a := domain.MapPoint{X: samples[i-1].X, Y: samples[i-1].Y}
+			b := domain.MapPoint{X: samples[i].X, Y: samples[i].Y}
+			if domain.SegmentHitsObstacles(m.Obstacles, a, b) {
+				report.Issues = append(report.Issues, MapValidationIssue{
+					Type:     MapIssuePathBlocked,
+					PathID:   path.ID,
+					Position: &a,
+					Message:  fmt.Sprintf("路径 %s 穿过障碍物", path.Name),
+				})
+				break
+			}
+		}
+	}
+
+	report.Valid = len(report.Issues) == 0
+	return report, nil
+}
+
+// ActiveObstacles 获取当前地图的障碍物
+func (s *occupancyMapService) ActiveObstacles(ctx context.Context) ([]domain.ObstaclePolygon, error) {
+	active, err := s.mapRepo.GetActive(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("获取当前地图失败: %w", err)
+	}
+	if active == nil {
+		return nil, nil
+	}
+	return active.Obstacles, nil
+}
+
+// load 读取地图并解码栅格，解码结果按地图ID缓存
+func (s *occupancyMapService) load(ctx context.Context, id string) (*domain.OccupancyMap, *occupancyGrid, error) {
+	m, err := s.mapRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	s.mu.Lock()
+	grid, ok := s.grids[id]
+	s.mu.Unlock()
+	if ok {
+		return m, grid, nil
+	}
+
+	img, _, err := decodeMapImage(m.Image)
+	if err != nil {
+		return nil, nil, err
+	}
+	grid = buildOccupancyGrid(m, img)
+
+	s.mu.Lock()
+	s.grids[id] = grid
+	s.mu.Unlock()
+	return m, grid, nil
+}
+
+// invalidate 清除地图的栅格和瓦片缓存
+func (s *occupancyMapService) invalidate(id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.grids, id)
+	for key := range s.tiles {
+		if strings.HasPrefix(key, id+"/") {
+			delete(s.tiles, key)
+		}
+	}
+}
+
+func (s *occupancyMapService) tolerance(m *domain.OccupancyMap, opts ObstacleOptions) float64 {
+	if opts.SimplifyTolerance > 0 {
+		return opts.SimplifyTolerance
+	}
+	return m.Resolution
+}
+
+func (s *occupancyMapService) minArea(m *domain.OccupancyMap, opts ObstacleOptions) float64 {
+	if opts.MinArea > 0 {
+		return opts.MinArea
+	}
+	return 4 * m.Resolution * m.Resolution
+}
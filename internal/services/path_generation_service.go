@@ -9,6 +9,7 @@
 // 1. 多种路径生成算法
 // 2. 图论算法实现
 // 3. 性能优化
+// 4. 存在当前地图时，跳过穿过障碍物的直线连接
 package services
 
 import (
@@ -42,13 +43,15 @@ type PathGenerationService interface {
 type pathGenerationService struct {
 	nodeService NodeService
 	pathService PathService
+	obstacles   ObstacleSource // 可为nil，此时不做避障
 }
 
 // NewPathGenerationService 创建新的路径生成服务实例
-func NewPathGenerationService(nodeService NodeService, pathService PathService) PathGenerationService {
+func NewPathGenerationService(nodeService NodeService, pathService PathService, obstacles ObstacleSource) PathGenerationService {
 	return &pathGenerationService{
 		nodeService: nodeService,
 		pathService: pathService,
+		obstacles:   obstacles,
 	}
 }
 
@@ -161,11 +164,16 @@ func (s *pathGenerationService) GenerateFullConnectivity(ctx context.Context) ([
 		return nil, fmt.Errorf("获取节点列表失败: %v", err)
 	}
 
+	blocked, err := s.blockedFunc(ctx)
+	if err != nil {
+		return nil, err
+	}
+
 	var paths []domain.Path
 
 	for i, node1 := range nodes {
 		for j, node2 := range nodes {
-			if i < j { // 避免重复连接
+			if i < j && !blocked(node1.Position, node2.Position) { // 避免重复连接
 				distance := s.calculateDistance(node1.Position, node2.Position)
 				path := domain.Path{
 					ID:          domain.PathID(fmt.Sprintf("full_%s_%s", node1.ID, node2.ID)),
@@ -209,10 +217,15 @@ func (s *pathGenerationService) GenerateTreeStructure(ctx context.Context, rootN
 		weight   float64
 	}
 
+	blocked, err := s.blockedFunc(ctx)
+	if err != nil {
+		return nil, err
+	}
+
 	var edges []edge
 	for i, node1 := range nodes {
 		for j, node2 := range nodes {
-			if i < j {
+			if i < j && !blocked(node1.Position, node2.Position) {
 				distance := s.calculateDistance(node1.Position, node2.Position)
 				edges = append(edges, edge{
 					from:   node1.ID,
@@ -275,6 +288,11 @@ func (s *pathGenerationService) GenerateNearestNeighborPaths(ctx context.Context
 		maxNeighbors = 3 // 默认连接到最近的3个邻居
 	}
 
+	blocked, err := s.blockedFunc(ctx)
+	if err != nil {
+		return nil, err
+	}
+
 	var paths []domain.Path
 	pathSet := make(map[string]bool) // 防止重复路径
 
@@ -287,7 +305,7 @@ func (s *pathGenerationService) GenerateNearestNeighborPaths(ctx context.Context
 
 		var neighbors []neighbor
 		for _, otherNode := range nodes {
-			if node.ID != otherNode.ID {
+			if node.ID != otherNode.ID && !blocked(node.Position, otherNode.Position) {
 				distance := s.calculateDistance(node.Position, otherNode.Position)
 				neighbors = append(neighbors, neighbor{
 					nodeID:   otherNode.ID,
@@ -350,6 +368,11 @@ func (s *pathGenerationService) GenerateGridPaths(ctx context.Context, enableDia
 	// 网格路径生成逻辑
 	// 实际实现需要更复杂的逻辑来检测网格结构
 
+	blocked, err := s.blockedFunc(ctx)
+	if err != nil {
+		return nil, err
+	}
+
 	var paths []domain.Path
 	tolerance := 50.0 // 位置容差
 
@@ -366,7 +389,7 @@ func (s *pathGenerationService) GenerateGridPaths(ctx context.Context, enableDia
 			isVertical := dx < tolerance && dy > tolerance
 			isDiagonal := enableDiagonal && math.Abs(dx-dy) < tolerance
 
-			if isHorizontal || isVertical || isDiagonal {
+			if (isHorizontal || isVertical || isDiagonal) && !blocked(node1.Position, node2.Position) {
 				distance := s.calculateDistance(node1.Position, node2.Position)
 
 				pathType := "网格"
@@ -395,6 +418,21 @@ func (s *pathGenerationService) GenerateGridPaths(ctx context.Context, enableDia
 	return paths, nil
 }
 
+// blockedFunc 返回判断两节点间直线是否穿过当前地图障碍物的函数
+func (s *pathGenerationService) blockedFunc(ctx context.Context) (func(a, b domain.Position) bool, error) {
+	if s.obstacles == nil {
+		return func(a, b domain.Position) bool { return false }, nil
+	}
+	obstacles, err := s.obstacles.ActiveObstacles(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("获取地图障碍物失败: %w", err)
+	}
+	return func(a, b domain.Position) bool {
+		return domain.SegmentHitsObstacles(obstacles,
+			domain.MapPoint{X: a.X, Y: a.Y}, domain.MapPoint{X: b.X, Y: b.Y})
+	}, nil
+}
+
 // calculateDistance 计算两点之间的欧几里得距离
 func (s *pathGenerationService) calculateDistance(pos1, pos2 domain.Position) float64 {
 	dx := pos1.X - pos2.X
@@ -0,0 +1,324 @@
+// Package services 最小化 PDF 生成
+//
+// - PDF 1.4，内容流使用 FlateDecode 压缩，页面坐标以左上角为原点（写出时换算为 PDF 坐标）
+// - 文字使用非嵌入的 STSong-Light（Adobe-GB1）配合 UniGB-UTF16-H 编码，阅读器自带中文字体即可显示
+// - 透明度通过 ExtGState 实现；地图按渲染场景以矢量方式绘制
+package services
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"image/color"
+	"math"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf16"
+)
+
+// 页面尺寸（点）
+const (
+	pdfA4Width  = 595.28
+	pdfA4Height = 841.89
+)
+
+// pdfDocument PDF 文档
+type pdfDocument struct {
+	width, height float64
+	title         string
+	pages         []*pdfPage
+	alphas        map[int]bool // 使用到的透明度（千分比）
+}
+
+// pdfPage 页面内容流
+type pdfPage struct {
+	doc     *pdfDocument
+	content bytes.Buffer
+}
+
+func newPDFDocument(width, height float64, title string) *pdfDocument {
+	return &pdfDocument{width: width, height: height, title: title, alphas: make(map[int]bool)}
+}
+
+// addPage 新增页面
+func (d *pdfDocument) addPage() *pdfPage {
+	page := &pdfPage{doc: d}
+	d.pages = append(d.pages, page)
+	return page
+}
+
+// === 绘图 ===
+
+func pdfNum(v float64) string {
+	if v = math.Round(v*100) / 100; v == 0 {
+		v = 0
+	}
+	return strings.TrimRight(strings.TrimRight(fmt.Sprintf("%.2f", v), "0"), ".")
+}
+
+func (p *pdfPage) op(format string, args ...interface{}) {
+	fmt.Fprintf(&p.content, format, args...)
+	p.content.WriteByte('\n')
+}
+
+// y 换算为 PDF 坐标（原点在左下角）
+func (p *pdfPage) y(v float64) float64 {
+	return p.doc.height - v
+}
+
+// alpha 设置填充和描边透明度
+func (p *pdfPage) alpha(opacity float64) {
+	key := int(math.Round(math.Max(0, math.Min(opacity, 1)) * 1000))
+	p.doc.alphas[key] = true
+	p.op("/GS%d gs", key)
+}
+
+func (p *pdfPage) fillColor(c color.NRGBA) {
+	p.op("%s %s %s rg", pdfNum(float64(c.R)/255), pdfNum(float64(c.G)/255), pdfNum(float64(c.B)/255))
+}
+
+func (p *pdfPage) strokeColor(c color.NRGBA) {
+	p.op("%s %s %s RG", pdfNum(float64(c.R)/255), pdfNum(float64(c.G)/255), pdfNum(float64(c.B)/255))
+}
+
+// text 在基线 (x, y) 处输出文字
+func (p *pdfPage) text(x, y, size float64, c color.NRGBA, s string) {
+	if s == "" {
+		return
+	}
+	p.fillColor(c)
+	p.op("BT /F1 %s Tf %s %s Td <%s> Tj ET", pdfNum(size), pdfNum(x), pdfNum(p.y(y)), pdfHexText(s))
+}
+
+// rect 矩形，fill/stroke 为零值颜色（A=0）时不绘制对应部分
+func (p *pdfPage) rect(x, y, w, h float64, fill, stroke color.NRGBA, lineWidth float64) {
+	p.op("q")
+	p.op("%s %s %s %s re", pdfNum(x), pdfNum(p.y(y+h)), pdfNum(w), pdfNum(h))
+	p.paint(fill, stroke, lineWidth)
+	p.op("Q")
+}
+
+// line 直线
+func (p *pdfPage) line(x1, y1, x2, y2, width float64, c color.NRGBA) {
+	p.op("q")
+	p.strokeColor(c)
+	p.op("%s w %s %s m %s %s l S", pdfNum(width), pdfNum(x1), pdfNum(p.y(y1)), pdfNum(x2), pdfNum(p.y(y2)))
+	p.op("Q")
+}
+
+// paint 按填充/描边颜色结束当前路径
+func (p *pdfPage) paint(fill, stroke color.NRGBA, lineWidth float64) {
+	hasFill, hasStroke := fill.A > 0, stroke.A > 0 && lineWidth > 0
+	if hasFill {
+		p.fillColor(fill)
+	}
+	if hasStroke {
+		p.strokeColor(stroke)
+		p.op("%s w", pdfNum(lineWidth))
+	}
+	switch {
+	case hasFill && hasStroke:
+		p.op("B")
+	case hasFill:
+		p.op("f")
+	case hasStroke:
+		p.op("S")
+	default:
+		p.op("n")
+	}
+}
+
+// polyline 构造折线路径（未绘制）
+func (p *pdfPage) polyline(points []vec, closed bool) {
+	for i, pt := range points {
+		operator := "l"
+		if i == 0 {
+			operator = "m"
+		}
+		p.op("%s %s %s", pdfNum(pt.x), pdfNum(p.y(pt.y)), operator)
+	}
+	if closed {
+		p.op("h")
+	}
+}
+
+// circle 以四段贝塞尔曲线构造圆（未绘制）
+func (p *pdfPage) circle(c vec, r float64) {
+	k := r * 0.5523
+	cx, cy := c.x, p.y(c.y)
+	p.op("%s %s m", pdfNum(cx+r), pdfNum(cy))
+	p.op("%s %s %s %s %s %s c", pdfNum(cx+r), pdfNum(cy+k), pdfNum(cx+k), pdfNum(cy+r), pdfNum(cx), pdfNum(cy+r))
+	p.op("%s %s %s %s %s %s c", pdfNum(cx-k), pdfNum(cy+r), pdfNum(cx-r), pdfNum(cy+k), pdfNum(cx-r), pdfNum(cy))
+	p.op("%s %s %s %s %s %s c", pdfNum(cx-r), pdfNum(cy-k), pdfNum(cx-k), pdfNum(cy-r), pdfNum(cx), pdfNum(cy-r))
+	p.op("%s %s %s %s %s %s c", pdfNum(cx+k), pdfNum(cy-r), pdfNum(cx+r), pdfNum(cy-k), pdfNum(cx+r), pdfNum(cy))
+	p.op("h")
+}
+
+// strokePath 以场景描边样式绘制折线
+func (p *pdfPage) strokePath(s renderStroke) {
+	p.op("q")
+	p.alpha(s.opacity * float64(s.color.A) / 255)
+	p.strokeColor(s.color)
+	p.op("%s w", pdfNum(s.width))
+	if len(s.dash) > 0 {
+		dash := make([]string, len(s.dash))
+		for i, d := range s.dash {
+			dash[i] = pdfNum(d)
+		}
+		p.op("0 J 1 j [%s] 0 d", strings.Join(dash, " "))
+	} else {
+		p.op("1 J 1 j")
+	}
+	p.polyline(s.points, false)
+	p.op("S")
+	p.op("Q")
+}
+
+// fillShape 以场景形状样式绘制节点或箭头
+func (p *pdfPage) fillShape(shape renderShape) {
+	p.op("q")
+	p.alpha(shape.opacity * float64(shape.fill.A) / 255)
+	if shape.circle {
+		p.circle(shape.center, shape.radius)
+	} else {
+		p.polyline(shape.polygon, true)
+	}
+	border := shape.border
+	if shape.borderWidth <= 0 {
+		border = color.NRGBA{}
+	}
+	p.paint(shape.fill, border, shape.borderWidth)
+	p.op("Q")
+}
+
+// drawScene 在 (x, y) 处按原尺寸绘制渲染场景，超出场景范围的部分被裁剪
+func (p *pdfPage) drawScene(scene *renderScene, x, y float64) {
+	p.op("q")
+	p.op("%s %s %d %d re W n", pdfNum(x), pdfNum(p.y(y+float64(scene.height))), scene.width, scene.height)
+	p.op("1 0 0 1 %s %s cm", pdfNum(x), pdfNum(-y))
+	if scene.background.A > 0 {
+		p.rect(0, 0, float64(scene.width), float64(scene.height), scene.background, color.NRGBA{}, 0)
+	}
+	for _, s := range scene.strokes {
+		p.strokePath(s)
+	}
+	for _, shape := range scene.arrows {
+		p.fillShape(shape)
+	}
+	for _, shape := range scene.shapes {
+		p.fillShape(shape)
+	}
+	for _, l := range scene.labels {
+		p.text(l.pos.x-pdfTextWidth(l.text, l.size*0.75)/2, l.pos.y, l.size*0.75, color.NRGBA{0x33, 0x33, 0x33, 255}, l.text)
+	}
+	p.op("Q")
+}
+
+// === 文字 ===
+
+// pdfHexText 文字编码为 UTF-16BE 十六进制串
+func pdfHexText(s string) string {
+	var b strings.Builder
+	for _, unit := range utf16.Encode([]rune(s)) {
+		fmt.Fprintf(&b, "%04X", unit)
+	}
+	return b.String()
+}
+
+// pdfTextWidth 估算文字宽度：ASCII 为半角，其余为全角
+func pdfTextWidth(s string, size float64) float64 {
+	width := 0.0
+	for _, r := range s {
+		if r < 0x80 {
+			width += 0.5
+		} else {
+			width++
+		}
+	}
+	return width * size
+}
+
+// pdfFitText 超出宽度时截断并添加省略号
+func pdfFitText(s string, size, maxWidth float64) string {
+	if pdfTextWidth(s, size) <= maxWidth {
+		return s
+	}
+	runes := []rune(s)
+	for len(runes) > 0 && pdfTextWidth(string(runes)+"…", size) > maxWidth {
+		runes = runes[:len(runes)-1]
+	}
+	return string(runes) + "…"
+}
+
+// === 输出 ===
+
+// bytes 生成 PDF 文件
+func (d *pdfDocument) bytes() ([]byte, error) {
+	var out bytes.Buffer
+	var offsets []int
+
+	// 对象编号：1 目录，2 页面树，3-5 字体，6 资源，7 信息，之后每页两个对象（页面、内容）
+	object := func(body string) {
+		offsets = append(offsets, out.Len())
+		fmt.Fprintf(&out, "%d 0 obj\n%s\nendobj\n", len(offsets), body)
+	}
+	stream := func(dict string, data []byte) {
+		offsets = append(offsets, out.Len())
+		fmt.Fprintf(&out, "%d 0 obj\n<< %s /Length %d >>\nstream\n", len(offsets), dict, len(data))
+		out.Write(data)
+		out.WriteString("\nendstream\nendobj\n")
+	}
+
+	out.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+
+	const firstPage = 8
+	kids := make([]string, len(d.pages))
+	for i := range d.pages {
+		kids[i] = fmt.Sprintf("%d 0 R", firstPage+i*2)
+	}
+	object("<< /Type /Catalog /Pages 2 0 R >>")
+	object(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(d.pages)))
+	object("<< /Type /Font /Subtype /Type0 /BaseFont /STSong-Light /Encoding /UniGB-UTF16-H /DescendantFonts [4 0 R] >>")
+	object("<< /Type /Font /Subtype /CIDFontType0 /BaseFont /STSong-Light " +
+		"/CIDSystemInfo << /Registry (Adobe) /Ordering (GB1) /Supplement 2 >> " +
+		"/FontDescriptor 5 0 R /DW 1000 /W [1 95 500] >>")
+	object("<< /Type /FontDescriptor /FontName /STSong-Light /Flags 6 /FontBBox [-25 -254 1000 880] " +
+		"/ItalicAngle 0 /Ascent 880 /Descent -120 /CapHeight 880 /StemV 93 >>")
+
+	keys := make([]int, 0, len(d.alphas))
+	for key := range d.alphas {
+		keys = append(keys, key)
+	}
+	sort.Ints(keys)
+	var states strings.Builder
+	for _, key := range keys {
+		fmt.Fprintf(&states, " /GS%d << /Type /ExtGState /ca %s /CA %s >>", key, pdfNum(float64(key)/1000), pdfNum(float64(key)/1000))
+	}
+	object(fmt.Sprintf("<< /Font << /F1 3 0 R >> /ExtGState <<%s >> >>", states.String()))
+	object(fmt.Sprintf("<< /Title <FEFF%s> /Producer (robot-path-editor) /CreationDate (D:%s) >>",
+		pdfHexText(d.title), time.Now().Format("20060102150405")))
+
+	for i, page := range d.pages {
+		object(fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %s %s] /Resources 6 0 R /Contents %d 0 R >>",
+			pdfNum(d.width), pdfNum(d.height), firstPage+i*2+1))
+
+		var compressed bytes.Buffer
+		zw := zlib.NewWriter(&compressed)
+		if _, err := zw.Write(page.content.Bytes()); err != nil {
+			return nil, fmt.Errorf("压缩页面内容失败: %w", err)
+		}
+		if err := zw.Close(); err != nil {
+			return nil, fmt.Errorf("压缩页面内容失败: %w", err)
+		}
+		stream("/Filter /FlateDecode", compressed.Bytes())
+	}
+
+	xref := out.Len()
+	fmt.Fprintf(&out, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&out, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&out, "trailer\n<< /Size %d /Root 1 0 R /Info 7 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, xref)
+	return out.Bytes(), nil
+}
@@ -0,0 +1,274 @@
+// Package services 位姿插值服务
+//
+// 设计参考：
+// - 工业机器人示教器的直线(MoveL)插补：位置线性插值 + 姿态SLERP
+// - ROS MoveIt 的笛卡尔路径计算（compute_cartesian_path）
+//
+// 特点：
+// 1. 按步数或步长细分路径/路线
+// 2. 姿态使用四元数球面插值，避免欧拉角插值的奇异问题
+// 3. 不依赖关节空间的笛卡尔连续性检查（姿态翻转、万向节锁等）
+package services
+
+import (
+	"context"
+	"fmt"
+	"math"
+
+	"robot-path-editor/internal/domain"
+	"robot-path-editor/internal/repositories"
+)
+
+// PoseInterpolationService 位姿插值服务接口
+type PoseInterpolationService interface {
+	// 对单条路径插值（两端节点需配置机器人坐标）
+	InterpolatePath(ctx context.Context, pathID domain.PathID, opts InterpolationOptions) (*PoseSequence, error)
+	// 对一条路线插值
+	InterpolateRoute(ctx context.Context, req InterpolateRouteRequest) (*PoseSequence, error)
+}
+
+// InterpolationOptions 插值参数
+// Steps、StepLength、StepAngle可组合使用，每段取满足全部约束的最小步数；均未设置时每段10步
+type InterpolationOptions struct {
+	Steps        int     `json:"steps,omitempty"`          // 每段固定步数
+	StepLength   float64 `json:"step_length,omitempty"`    // 每步最大位移（与机器人坐标同单位）
+	StepAngle    float64 `json:"step_angle,omitempty"`     // 每步最大姿态变化（度）
+	MaxStepAngle float64 `json:"max_step_angle,omitempty"` // 连续性检查：单步姿态变化超过该值时报告（度）
+}
+
+// InterpolateRouteRequest 路线插值请求
+type InterpolateRouteRequest struct {
+	RouteRequest
+	InterpolationOptions
+}
+
+// InterpolatedPose 插值得到的单个位姿
+type InterpolatedPose struct {
+	Index    int           `json:"index"`
+	LegIndex int           `json:"leg_index"`
+	T        float64       `json:"t"`                 // 段内插值参数 0-1
+	NodeID   domain.NodeID `json:"node_id,omitempty"` // 关键帧（节点处）的节点ID
+	domain.Pose
+
+	// 展开后的RPY角（度），相邻位姿之间不会出现±360°跳变
+	Roll  float64 `json:"roll"`
+	Pitch float64 `json:"pitch"`
+	Yaw   float64 `json:"yaw"`
+}
+
+// ContinuityIssue 连续性检查发现的问题
+type ContinuityIssue struct {
+	Type     string        `json:"type"` // orientation_flip, orientation_ambiguous, gimbal_lock, orientation_step
+	LegIndex int           `json:"leg_index"`
+	NodeID   domain.NodeID `json:"node_id,omitempty"`
+	Angle    float64       `json:"angle"`
+	Message  string        `json:"message"`
+}
+
+// PoseSequence 插值结果
+type PoseSequence struct {
+	Poses         []InterpolatedPose `json:"poses"`
+	Issues        []ContinuityIssue  `json:"issues,omitempty"`
+	TotalLength   float64            `json:"total_length"`   // 笛卡尔总位移
+	TotalRotation float64            `json:"total_rotation"` // 姿态总旋转角（度）
+}
+
+const (
+	defaultInterpolationSteps = 10
+	maxInterpolationSteps     = 10000
+	gimbalLockTolerance       = 1.0   // 俯仰角距±90°小于该值时视为万向节锁（度）
+	ambiguousRotationAngle    = 179.0 // 最短旋转角超过该值时旋转方向不确定（度）
+)
+
+// poseInterpolationService 位姿插值服务实现
+type poseInterpolationService struct {
+	routes *routeResolver
+}
+
+// NewPoseInterpolationService 创建新的位姿插值服务实例
+func NewPoseInterpolationService(nodeRepo repositories.NodeRepository, pathRepo repositories.PathRepository) PoseInterpolationService {
+	return &poseInterpolationService{
+		routes: newRouteResolver(nodeRepo, pathRepo),
+	}
+}
+
+// InterpolatePath 对单条路径插值
+func (s *poseInterpolationService) InterpolatePath(ctx context.Context, pathID domain.PathID, opts InterpolationOptions) (*PoseSequence, error) {
+	route, err := s.routes.Resolve(ctx, RouteRequest{PathIDs: []domain.PathID{pathID}})
+	if err != nil {
+		return nil, err
+	}
+	return s.interpolate(route, opts)
+}
+
+// InterpolateRoute 对一条路线插值
+func (s *poseInterpolationService) InterpolateRoute(ctx context.Context, req InterpolateRouteRequest) (*PoseSequence, error) {
+	route, err := s.routes.Resolve(ctx, req.RouteRequest)
+	if err != nil {
+		return nil, err
+	}
+	return s.interpolate(route, req.InterpolationOptions)
+}
+
+// interpolate 对解析后的路线逐段插值
+func (s *poseInterpolationService) interpolate(route *Route, opts InterpolationOptions) (*PoseSequence, error) {
+	if len(route.Legs) == 0 {
+		return nil, fmt.Errorf("路线至少需要包含两个节点")
+	}
+	for _, node := range route.Nodes {
+		if node.RobotCoords == nil {
+			return nil, fmt.Errorf("节点 %s 未配置机器人坐标", node.Name)
+		}
+	}
+
+	result := &PoseSequence{}
+	var prev *InterpolatedPose
+
+	for legIndex, leg := range route.Legs {
+		fromCoords, toCoords := *leg.From.RobotCoords, *leg.To.RobotCoords
+		from, to := fromCoords.Pose(), toCoords.Pose()
+
+		distance := from.Position.DistanceTo(to.Position)
+		rotation := from.Orientation.AngleTo(to.Orientation)
+		result.TotalLength += distance
+		result.TotalRotation += rotation
+
+		result.Issues = append(result.Issues, checkKeyframe(legIndex, leg.From, fromCoords)...)
+		if legIndex == len(route.Legs)-1 {
+			result.Issues = append(result.Issues, checkKeyframe(legIndex, leg.To, toCoords)...)
+		}
+		result.Issues = append(result.Issues, checkLegRotation(legIndex, leg, fromCoords, toCoords, rotation)...)
+
+		steps := interpolationSteps(opts, distance, rotation)
+		if opts.MaxStepAngle > 0 && rotation/float64(steps) > opts.MaxStepAngle {
+			result.Issues = append(result.Issues, ContinuityIssue{
+				Type:     "orientation_step",
+				LegIndex: legIndex,
+				Angle:    rotation / float64(steps),
+				Message:  fmt.Sprintf("单步姿态变化 %.2f° 超过上限 %.2f°", rotation/float64(steps), opts.MaxStepAngle),
+			})
+		}
+
+		// 除第一段外，每段起点与上一段终点重合，跳过以免重复
+		start := 1
+		if legIndex == 0 {
+			start = 0
+		}
+		for i := start; i <= steps; i++ {
+			t := float64(i) / float64(steps)
+			pose := InterpolatedPose{
+				Index:    len(result.Poses),
+				LegIndex: legIndex,
+				T:        t,
+				Pose: domain.Pose{
+					Position:    domain.LerpPosition(from.Position, to.Position, t),
+					Orientation: domain.Slerp(from.Orientation, to.Orientation, t),
+				},
+			}
+			switch i {
+			case 0:
+				pose.NodeID = leg.From.ID
+			case steps:
+				pose.NodeID = leg.To.ID
+			}
+
+			pose.Roll, pose.Pitch, pose.Yaw = pose.Orientation.ToEuler()
+			if prev != nil {
+				pose.Roll = unwrapAngle(pose.Roll, prev.Roll)
+				pose.Pitch = unwrapAngle(pose.Pitch, prev.Pitch)
+				pose.Yaw = unwrapAngle(pose.Yaw, prev.Yaw)
+			}
+
+			result.Poses = append(result.Poses, pose)
+			prev = &result.Poses[len(result.Poses)-1]
+		}
+	}
+
+	return result, nil
+}
+
+// interpolationSteps 计算一段需要的插值步数
+func interpolationSteps(opts InterpolationOptions, distance, rotation float64) int {
+	steps := opts.Steps
+	if opts.StepLength > 0 {
+		if n := int(math.Ceil(distance / opts.StepLength)); n > steps {
+			steps = n
+		}
+	}
+	if opts.StepAngle > 0 {
+		if n := int(math.Ceil(rotation / opts.StepAngle)); n > steps {
+			steps = n
+		}
+	}
+	if steps <= 0 {
+		steps = defaultInterpolationSteps
+	}
+	if steps > maxInterpolationSteps {
+		steps = maxInterpolationSteps
+	}
+	return steps
+}
+
+// checkKeyframe 检查关键帧姿态（万向节锁）
+func checkKeyframe(legIndex int, node *domain.Node, coords domain.RobotCoordinates) []ContinuityIssue {
+	if math.Abs(math.Abs(coords.Pitch)-90) < gimbalLockTolerance {
+		return []ContinuityIssue{{
+			Type:     "gimbal_lock",
+			LegIndex: legIndex,
+			NodeID:   node.ID,
+			Angle:    coords.Pitch,
+			Message:  fmt.Sprintf("节点 %s 俯仰角 %.2f° 接近±90°，RPY表示存在奇异", node.Name, coords.Pitch),
+		}}
+	}
+	return nil
+}
+
+// checkLegRotation 检查一段内的姿态翻转
+// SLERP总是走最短弧，当示教的欧拉角变化超过180°时，实际旋转方向会与示教意图相反
+func checkLegRotation(legIndex int, leg RouteLeg, from, to domain.RobotCoordinates, rotation float64) []ContinuityIssue {
+	var issues []ContinuityIssue
+
+	axes := []struct {
+		name     string
+		from, to float64
+	}{
+		{"roll", from.Roll, to.Roll},
+		{"pitch", from.Pitch, to.Pitch},
+		{"yaw", from.Yaw, to.Yaw},
+	}
+	for _, axis := range axes {
+		if delta := math.Abs(axis.to - axis.from); delta > 180 {
+			issues = append(issues, ContinuityIssue{
+				Type:     "orientation_flip",
+				LegIndex: legIndex,
+				NodeID:   leg.To.ID,
+				Angle:    delta,
+				Message: fmt.Sprintf("%s -> %s 的%s变化 %.2f° 超过180°，插值将沿反方向旋转 %.2f°",
+					leg.From.Name, leg.To.Name, axis.name, delta, rotation),
+			})
+		}
+	}
+
+	if rotation > ambiguousRotationAngle {
+		issues = append(issues, ContinuityIssue{
+			Type:     "orientation_ambiguous",
+			LegIndex: legIndex,
+			NodeID:   leg.To.ID,
+			Angle:    rotation,
+			Message:  fmt.Sprintf("%s -> %s 的姿态旋转 %.2f° 接近180°，旋转方向不确定", leg.From.Name, leg.To.Name, rotation),
+		})
+	}
+
+	return issues
+}
+
+// unwrapAngle 将角度调整到与参考角相差不超过180°的等价值
+func unwrapAngle(angle, reference float64) float64 {
+	for angle-reference > 180 {
+		angle -= 360
+	}
+	for angle-reference < -180 {
+		angle += 360
+	}
+	return angle
+}
@@ -0,0 +1,175 @@
+package services
+
+import (
+	"math"
+	"testing"
+
+	"robot-path-editor/internal/domain"
+)
+
+func TestInterpolationSteps(t *testing.T) {
+	tests := []struct {
+		name               string
+		opts               InterpolationOptions
+		distance, rotation float64
+		want               int
+	}{
+		{"未设置时默认10步", InterpolationOptions{}, 100, 90, defaultInterpolationSteps},
+		{"固定步数", InterpolationOptions{Steps: 4}, 100, 90, 4},
+		{"按步长", InterpolationOptions{StepLength: 7}, 100, 0, 15},
+		{"按角度", InterpolationOptions{StepAngle: 10}, 1, 95, 10},
+		{"取满足全部约束的最小步数", InterpolationOptions{Steps: 3, StepLength: 25, StepAngle: 15}, 100, 90, 6},
+		{"原地不动仍使用默认步数", InterpolationOptions{StepLength: 1}, 0, 0, defaultInterpolationSteps},
+		{"步数上限", InterpolationOptions{StepLength: 0.001}, 1000, 0, maxInterpolationSteps},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := interpolationSteps(tt.opts, tt.distance, tt.rotation); got != tt.want {
+				t.Errorf("interpolationSteps() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUnwrapAngle(t *testing.T) {
+	tests := []struct {
+		angle, reference, want float64
+	}{
+		{10, 0, 10},
+		{-179, 179, 181},
+		{179, -179, -181},
+		{350, 0, -10},
+		{-170, 540, 550},
+	}
+	for _, tt := range tests {
+		if got := unwrapAngle(tt.angle, tt.reference); got != tt.want {
+			t.Errorf("unwrapAngle(%v, %v) = %v, want %v", tt.angle, tt.reference, got, tt.want)
+		}
+	}
+}
+
+// poseNode 创建配置了机器人坐标的节点
+func poseNode(id string, coords domain.RobotCoordinates) *domain.Node {
+	return &domain.Node{ID: domain.NodeID(id), Name: id, RobotCoords: &coords}
+}
+
+// poseRoute 依次经过各节点的路线
+func poseRoute(nodes ...*domain.Node) *Route {
+	route := &Route{Nodes: nodes}
+	for i := 1; i < len(nodes); i++ {
+		route.Legs = append(route.Legs, RouteLeg{From: nodes[i-1], To: nodes[i]})
+	}
+	return route
+}
+
+func TestInterpolate(t *testing.T) {
+	s := &poseInterpolationService{}
+
+	t.Run("位置线性插值和姿态SLERP", func(t *testing.T) {
+		a := poseNode("a", domain.RobotCoordinates{X: 0, Y: 0, Z: 100})
+		b := poseNode("b", domain.RobotCoordinates{X: 100, Y: 0, Z: 100, Yaw: 90})
+		seq, err := s.interpolate(poseRoute(a, b), InterpolationOptions{Steps: 4})
+		if err != nil {
+			t.Fatalf("interpolate() error = %v", err)
+		}
+		if len(seq.Poses) != 5 {
+			t.Fatalf("len(Poses) = %d, want 5", len(seq.Poses))
+		}
+		for i, p := range seq.Poses {
+			wantX, wantYaw := 25*float64(i), 22.5*float64(i)
+			if math.Abs(p.Position.X-wantX) > 1e-9 || math.Abs(p.Yaw-wantYaw) > 1e-6 {
+				t.Errorf("Poses[%d] = x %v yaw %v, want x %v yaw %v", i, p.Position.X, p.Yaw, wantX, wantYaw)
+			}
+		}
+		if seq.Poses[0].NodeID != "a" || seq.Poses[4].NodeID != "b" || seq.Poses[2].NodeID != "" {
+			t.Errorf("关键帧节点 = %q, %q, %q", seq.Poses[0].NodeID, seq.Poses[2].NodeID, seq.Poses[4].NodeID)
+		}
+		if math.Abs(seq.TotalLength-100) > 1e-9 || math.Abs(seq.TotalRotation-90) > 1e-6 {
+			t.Errorf("Total = %v / %v°, want 100 / 90°", seq.TotalLength, seq.TotalRotation)
+		}
+		if len(seq.Issues) != 0 {
+			t.Errorf("Issues = %+v, want none", seq.Issues)
+		}
+	})
+
+	t.Run("多段时共享节点只输出一次", func(t *testing.T) {
+		a := poseNode("a", domain.RobotCoordinates{})
+		b := poseNode("b", domain.RobotCoordinates{X: 10})
+		c := poseNode("c", domain.RobotCoordinates{X: 10, Y: 10})
+		seq, err := s.interpolate(poseRoute(a, b, c), InterpolationOptions{Steps: 2})
+		if err != nil {
+			t.Fatalf("interpolate() error = %v", err)
+		}
+		if len(seq.Poses) != 5 {
+			t.Fatalf("len(Poses) = %d, want 5", len(seq.Poses))
+		}
+		if p := seq.Poses[2]; p.NodeID != "b" || p.LegIndex != 0 || p.Index != 2 {
+			t.Errorf("Poses[2] = %+v, want 第0段终点 b", p)
+		}
+		if p := seq.Poses[3]; p.LegIndex != 1 || p.T != 0.5 {
+			t.Errorf("Poses[3] = leg %d t %v, want leg 1 t 0.5", p.LegIndex, p.T)
+		}
+	})
+
+	t.Run("跨越±180°时欧拉角连续", func(t *testing.T) {
+		a := poseNode("a", domain.RobotCoordinates{Yaw: 170})
+		b := poseNode("b", domain.RobotCoordinates{Yaw: -170})
+		seq, err := s.interpolate(poseRoute(a, b), InterpolationOptions{Steps: 4})
+		if err != nil {
+			t.Fatalf("interpolate() error = %v", err)
+		}
+		for i, p := range seq.Poses {
+			if want := 170 + 5*float64(i); math.Abs(p.Yaw-want) > 1e-6 {
+				t.Errorf("Poses[%d].Yaw = %v, want %v", i, p.Yaw, want)
+			}
+		}
+		if len(seq.Issues) != 1 || seq.Issues[0].Type != "orientation_flip" {
+			t.Errorf("Issues = %+v, want 一个 orientation_flip", seq.Issues)
+		}
+	})
+
+	t.Run("连续性检查", func(t *testing.T) {
+		tests := []struct {
+			name  string
+			from  domain.RobotCoordinates
+			to    domain.RobotCoordinates
+			opts  InterpolationOptions
+			types []string
+		}{
+			{"万向节锁", domain.RobotCoordinates{Pitch: 89.5}, domain.RobotCoordinates{X: 1}, InterpolationOptions{}, []string{"gimbal_lock"}},
+			{"终点万向节锁", domain.RobotCoordinates{}, domain.RobotCoordinates{Pitch: -90}, InterpolationOptions{}, []string{"gimbal_lock"}},
+			{"旋转方向不确定", domain.RobotCoordinates{}, domain.RobotCoordinates{Yaw: 179.5}, InterpolationOptions{}, []string{"orientation_ambiguous"}},
+			{"单步姿态变化过大", domain.RobotCoordinates{}, domain.RobotCoordinates{Yaw: 90}, InterpolationOptions{Steps: 3, MaxStepAngle: 20}, []string{"orientation_step"}},
+			{"按角度细分后不超限", domain.RobotCoordinates{}, domain.RobotCoordinates{Yaw: 90}, InterpolationOptions{StepAngle: 20, MaxStepAngle: 20}, nil},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				seq, err := s.interpolate(poseRoute(poseNode("a", tt.from), poseNode("b", tt.to)), tt.opts)
+				if err != nil {
+					t.Fatalf("interpolate() error = %v", err)
+				}
+				var types []string
+				for _, issue := range seq.Issues {
+					types = append(types, issue.Type)
+				}
+				if len(types) != len(tt.types) || (len(types) > 0 && types[0] != tt.types[0]) {
+					t.Errorf("Issues = %v, want %v", types, tt.types)
+				}
+			})
+		}
+	})
+
+	t.Run("缺少机器人坐标", func(t *testing.T) {
+		a := poseNode("a", domain.RobotCoordinates{})
+		b := &domain.Node{ID: "b", Name: "b"}
+		if _, err := s.interpolate(poseRoute(a, b), InterpolationOptions{}); err == nil {
+			t.Error("interpolate() 应返回错误")
+		}
+	})
+
+	t.Run("空路线", func(t *testing.T) {
+		if _, err := s.interpolate(&Route{}, InterpolationOptions{}); err == nil {
+			t.Error("interpolate() 应返回错误")
+		}
+	})
+}
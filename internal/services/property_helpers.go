@@ -0,0 +1,74 @@
+// Package services 扩展属性读取工具
+//
+// Node和Path的Properties来自JSON，数值可能是float64，也可能是字符串，
+// 这里统一做宽松的类型转换。
+package services
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// propertyFloat 读取数值型扩展属性
+func propertyFloat(props map[string]interface{}, key string) (float64, bool) {
+	value, ok := props[key]
+	if !ok || value == nil {
+		return 0, false
+	}
+
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case float32:
+		return float64(v), true
+	case int:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case string:
+		f, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		if err != nil {
+			return 0, false
+		}
+		return f, true
+	default:
+		return 0, false
+	}
+}
+
+// propertyString 读取字符串型扩展属性
+func propertyString(props map[string]interface{}, key string) (string, bool) {
+	value, ok := props[key]
+	if !ok || value == nil {
+		return "", false
+	}
+
+	switch v := value.(type) {
+	case string:
+		return v, v != ""
+	default:
+		return fmt.Sprint(v), true
+	}
+}
+
+// propertyBool 读取布尔型扩展属性
+func propertyBool(props map[string]interface{}, key string) (bool, bool) {
+	value, ok := props[key]
+	if !ok || value == nil {
+		return false, false
+	}
+
+	switch v := value.(type) {
+	case bool:
+		return v, true
+	case string:
+		b, err := strconv.ParseBool(strings.TrimSpace(v))
+		if err != nil {
+			return false, false
+		}
+		return b, true
+	default:
+		return false, false
+	}
+}
@@ -0,0 +1,254 @@
+// Package services 纯 Go 矢量光栅化
+//
+// - 多边形按非零环绕规则填充，每个像素行4条子扫描线、水平方向按精确覆盖长度抗锯齿
+// - 线条展开为线段四边形和圆形连接点的并集，虚线先按长度切分再展开
+// - 覆盖率写入 image.Alpha 蒙版，再以 draw.Over 合成颜色，支持透明度
+package services
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+	"sort"
+)
+
+// vec 像素坐标系中的点
+type vec struct {
+	x, y float64
+}
+
+func (a vec) add(b vec) vec             { return vec{a.x + b.x, a.y + b.y} }
+func (a vec) sub(b vec) vec             { return vec{a.x - b.x, a.y - b.y} }
+func (a vec) scale(k float64) vec       { return vec{a.x * k, a.y * k} }
+func (a vec) length() float64           { return math.Hypot(a.x, a.y) }
+func (a vec) finite() bool              { return !math.IsNaN(a.x+a.y) && !math.IsInf(a.x+a.y, 0) }
+func (a vec) lerp(b vec, t float64) vec { return vec{a.x + (b.x-a.x)*t, a.y + (b.y-a.y)*t} }
+
+// rasterSubsamples 每个像素行的子扫描线数
+const rasterSubsamples = 4
+
+// rasterCanvas 光栅画布
+type rasterCanvas struct {
+	img *image.NRGBA
+}
+
+func newRasterCanvas(width, height int, background color.NRGBA) *rasterCanvas {
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	if background.A > 0 {
+		draw.Draw(img, img.Bounds(), &image.Uniform{C: background}, image.Point{}, draw.Src)
+	}
+	return &rasterCanvas{img: img}
+}
+
+// fill 以非零环绕规则填充多边形集合
+func (c *rasterCanvas) fill(polygons [][]vec, col color.NRGBA, opacity float64) {
+	if col.A == 0 || opacity <= 0 {
+		return
+	}
+
+	// 包围盒
+	minX, minY := math.Inf(1), math.Inf(1)
+	maxX, maxY := math.Inf(-1), math.Inf(-1)
+	for _, polygon := range polygons {
+		for _, p := range polygon {
+			minX, minY = math.Min(minX, p.x), math.Min(minY, p.y)
+			maxX, maxY = math.Max(maxX, p.x), math.Max(maxY, p.y)
+		}
+	}
+	bounds := image.Rect(int(math.Floor(minX)), int(math.Floor(minY)), int(math.Ceil(maxX))+1, int(math.Ceil(maxY))+1).
+		Intersect(c.img.Bounds())
+	if bounds.Empty() {
+		return
+	}
+
+	type edge struct {
+		a, b vec
+		dir  int
+	}
+	var edges []edge
+	for _, polygon := range polygons {
+		for i := range polygon {
+			a, b := polygon[i], polygon[(i+1)%len(polygon)]
+			if a.y == b.y || !a.finite() || !b.finite() {
+				continue
+			}
+			if a.y < b.y {
+				edges = append(edges, edge{a, b, 1})
+			} else {
+				edges = append(edges, edge{b, a, -1})
+			}
+		}
+	}
+	sort.Slice(edges, func(i, j int) bool { return edges[i].a.y < edges[j].a.y })
+
+	mask := image.NewAlpha(bounds)
+	cover := make([]float64, bounds.Dx()+1)
+	type crossing struct {
+		x   float64
+		dir int
+	}
+	var crossings []crossing
+	alpha := math.Min(opacity, 1) * float64(col.A) / 255
+
+	for py := bounds.Min.Y; py < bounds.Max.Y; py++ {
+		for i := range cover {
+			cover[i] = 0
+		}
+		for s := 0; s < rasterSubsamples; s++ {
+			sy := float64(py) + (float64(s)+0.5)/rasterSubsamples
+			crossings = crossings[:0]
+			for _, e := range edges {
+				if e.a.y > sy {
+					break
+				}
+				if e.b.y <= sy {
+					continue
+				}
+				x := e.a.x + (sy-e.a.y)*(e.b.x-e.a.x)/(e.b.y-e.a.y)
+				crossings = append(crossings, crossing{x, e.dir})
+			}
+			sort.Slice(crossings, func(i, j int) bool { return crossings[i].x < crossings[j].x })
+
+			winding := 0
+			start := 0.0
+			for _, cr := range crossings {
+				before := winding
+				winding += cr.dir
+				if before == 0 && winding != 0 {
+					start = cr.x
+				} else if before != 0 && winding == 0 {
+					addCoverage(cover, start-float64(bounds.Min.X), cr.x-float64(bounds.Min.X))
+				}
+			}
+		}
+		for px := 0; px < bounds.Dx(); px++ {
+			if v := cover[px] / rasterSubsamples * alpha; v > 0 {
+				mask.Pix[(py-bounds.Min.Y)*mask.Stride+px] = uint8(math.Min(v, 1)*255 + 0.5)
+			}
+		}
+	}
+
+	solid := col
+	solid.A = 255
+	draw.DrawMask(c.img, bounds, &image.Uniform{C: solid}, image.Point{}, mask, bounds.Min, draw.Over)
+}
+
+// addCoverage 将 [x0, x1) 的水平覆盖长度累加到像素
+func addCoverage(cover []float64, x0, x1 float64) {
+	limit := float64(len(cover) - 1)
+	x0, x1 = math.Max(x0, 0), math.Min(x1, limit)
+	if x1 <= x0 {
+		return
+	}
+	i0, i1 := int(x0), int(x1)
+	if i0 == i1 {
+		cover[i0] += x1 - x0
+		return
+	}
+	cover[i0] += float64(i0+1) - x0
+	for i := i0 + 1; i < i1; i++ {
+		cover[i]++
+	}
+	cover[i1] += x1 - float64(i1)
+}
+
+// stroke 绘制折线，closed 为真时首尾相连
+func (c *rasterCanvas) stroke(points []vec, width float64, col color.NRGBA, opacity float64, dash []float64, closed bool) {
+	if len(points) < 2 || width <= 0 {
+		return
+	}
+	if closed {
+		points = append(append([]vec(nil), points...), points[0])
+	}
+	var polygons [][]vec
+	for _, run := range dashRuns(points, dash) {
+		polygons = append(polygons, strokePolygons(run, width/2)...)
+	}
+	c.fill(polygons, col, opacity)
+}
+
+// strokePolygons 线段四边形加圆形端点/连接点，统一为正方向以便非零规则求并集
+func strokePolygons(points []vec, half float64) [][]vec {
+	var polygons [][]vec
+	for i := 0; i+1 < len(points); i++ {
+		a, b := points[i], points[i+1]
+		d := b.sub(a)
+		length := d.length()
+		if length == 0 {
+			continue
+		}
+		n := vec{-d.y / length * half, d.x / length * half}
+		polygons = append(polygons, orient([]vec{a.add(n), b.add(n), b.sub(n), a.sub(n)}))
+	}
+	for _, p := range points {
+		polygons = append(polygons, circlePolygon(p, half))
+	}
+	return polygons
+}
+
+// orient 使多边形为正方向（有向面积为正）
+func orient(polygon []vec) []vec {
+	area := 0.0
+	for i := range polygon {
+		a, b := polygon[i], polygon[(i+1)%len(polygon)]
+		area += a.x*b.y - b.x*a.y
+	}
+	if area < 0 {
+		for i, j := 0, len(polygon)-1; i < j; i, j = i+1, j-1 {
+			polygon[i], polygon[j] = polygon[j], polygon[i]
+		}
+	}
+	return polygon
+}
+
+// circlePolygon 以多边形近似圆，边数随半径增加
+func circlePolygon(center vec, r float64) []vec {
+	n := int(math.Max(8, math.Min(64, r*2)))
+	polygon := make([]vec, n)
+	for i := range polygon {
+		angle := 2 * math.Pi * float64(i) / float64(n)
+		polygon[i] = vec{center.x + r*math.Cos(angle), center.y + r*math.Sin(angle)}
+	}
+	return polygon
+}
+
+// dashRuns 按虚线模式（实、空交替的长度）把折线切分为多段实线
+func dashRuns(points []vec, dash []float64) [][]vec {
+	if len(dash) == 0 {
+		return [][]vec{points}
+	}
+
+	var runs [][]vec
+	var current []vec
+	index, remaining, on := 0, dash[0], true
+	if on {
+		current = []vec{points[0]}
+	}
+	for i := 0; i+1 < len(points); i++ {
+		a, b := points[i], points[i+1]
+		length := b.sub(a).length()
+		pos := 0.0
+		for length-pos > remaining {
+			pos += remaining
+			p := a.lerp(b, pos/length)
+			if on {
+				runs = append(runs, append(current, p))
+				current = nil
+			} else {
+				current = []vec{p}
+			}
+			on = !on
+			index = (index + 1) % len(dash)
+			remaining = dash[index]
+		}
+		remaining -= length - pos
+		if on {
+			current = append(current, b)
+		}
+	}
+	if on && len(current) > 1 {
+		runs = append(runs, current)
+	}
+	return runs
+}
@@ -0,0 +1,145 @@
+// Package services 地图与模板渲染服务
+//
+// - 当前地图：渲染全部节点和路径，用于快照和报告
+// - 模板：相对位置按模板画布尺寸换算为绝对位置后渲染
+// - 缩略图：模板创建、更新和另存时自动生成 PNG 并以 data URI 写入 Preview.Thumbnail
+package services
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+
+	"robot-path-editor/internal/domain"
+	"robot-path-editor/internal/repositories"
+)
+
+// 模板缩略图尺寸
+const (
+	templateThumbnailWidth  = 320
+	templateThumbnailHeight = 180
+)
+
+// RenderService 渲染服务接口
+type RenderService interface {
+	// RenderMap 渲染当前地图的节点和路径
+	RenderMap(ctx context.Context, opts RenderOptions) (*RenderedImage, error)
+	// RenderTemplate 渲染模板
+	RenderTemplate(ctx context.Context, templateID string, opts RenderOptions) (*RenderedImage, error)
+}
+
+// renderService 渲染服务实现
+type renderService struct {
+	nodeRepo     repositories.NodeRepository
+	pathRepo     repositories.PathRepository
+	templateRepo repositories.TemplateRepository
+}
+
+// NewRenderService 创建新的渲染服务实例
+func NewRenderService(nodeRepo repositories.NodeRepository, pathRepo repositories.PathRepository, templateRepo repositories.TemplateRepository) RenderService {
+	return &renderService{
+		nodeRepo:     nodeRepo,
+		pathRepo:     pathRepo,
+		templateRepo: templateRepo,
+	}
+}
+
+// RenderMap 渲染当前地图
+func (s *renderService) RenderMap(ctx context.Context, opts RenderOptions) (*RenderedImage, error) {
+	nodes, err := s.nodeRepo.List(ctx, repositories.NodeFilter{})
+	if err != nil {
+		return nil, fmt.Errorf("获取节点列表失败: %w", err)
+	}
+	paths, err := s.pathRepo.List(ctx, repositories.PathFilter{})
+	if err != nil {
+		return nil, fmt.Errorf("获取路径列表失败: %w", err)
+	}
+
+	image, err := renderLayout(nodes, paths, opts)
+	if err != nil {
+		return nil, fmt.Errorf("渲染地图失败: %w", err)
+	}
+	return image, nil
+}
+
+// RenderTemplate 渲染模板
+func (s *renderService) RenderTemplate(ctx context.Context, templateID string, opts RenderOptions) (*RenderedImage, error) {
+	template, err := s.templateRepo.GetByID(ctx, templateID)
+	if err != nil {
+		return nil, fmt.Errorf("获取模板失败: %w", err)
+	}
+
+	nodes, paths := templateLayout(&template.TemplateData)
+	image, err := renderLayout(nodes, paths, opts)
+	if err != nil {
+		return nil, fmt.Errorf("渲染模板失败: %w", err)
+	}
+	return image, nil
+}
+
+// templateLayout 将模板数据转换为可渲染的节点和路径，参数化模板按默认值展开，画布尺寸缺省时使用 1920x1080
+func templateLayout(data *domain.TemplateData) ([]*domain.Node, []*domain.Path) {
+	if expanded, err := expandTemplatePreview(data); err == nil {
+		data = expanded
+	}
+	width, height := data.CanvasConfig.Width, data.CanvasConfig.Height
+	if width <= 0 || height <= 0 {
+		width, height = 1920, 1080
+	}
+
+	nodeIDs := make(map[string]domain.NodeID, len(data.Nodes))
+	nodes := make([]*domain.Node, 0, len(data.Nodes))
+	for _, tn := range data.Nodes {
+		node := &domain.Node{
+			ID:       domain.NodeID(tn.TemplateID),
+			Name:     tn.Name,
+			Type:     tn.Type,
+			Position: tn.RelativePosition.ToAbsolutePosition(width, height),
+			Style:    tn.Style,
+		}
+		nodeIDs[tn.TemplateID] = node.ID
+		nodes = append(nodes, node)
+	}
+
+	paths := make([]*domain.Path, 0, len(data.Paths))
+	for _, tp := range data.Paths {
+		start, ok1 := nodeIDs[tp.StartNodeTempID]
+		end, ok2 := nodeIDs[tp.EndNodeTempID]
+		if !ok1 || !ok2 {
+			continue
+		}
+		paths = append(paths, &domain.Path{
+			Name:        tp.Name,
+			StartNodeID: start,
+			EndNodeID:   end,
+			Direction:   tp.Direction,
+			CurveType:   tp.CurveType,
+			Style:       tp.Style,
+		})
+	}
+	return nodes, paths
+}
+
+// updateTemplateThumbnail 生成模板缩略图，模板为空或渲染失败时清空缩略图
+// 参数化模板的节点和路径数量按预览展开的结果统计
+func updateTemplateThumbnail(template *domain.Template) {
+	template.Preview.Thumbnail = ""
+	nodes, paths := templateLayout(&template.TemplateData)
+	if template.TemplateData.IsParameterized() {
+		template.Preview.NodeCount = len(nodes)
+		template.Preview.PathCount = len(paths)
+	}
+	if len(nodes) == 0 {
+		return
+	}
+
+	image, err := renderLayout(nodes, paths, RenderOptions{
+		Format: "png",
+		Width:  templateThumbnailWidth,
+		Height: templateThumbnailHeight,
+	})
+	if err != nil {
+		return
+	}
+	template.Preview.Thumbnail = "data:image/png;base64," + base64.StdEncoding.EncodeToString(image.Data)
+}
@@ -0,0 +1,309 @@
+// Package services 地图报告的 PDF 排版
+//
+// - A4 纵向：首页为标题、修订标识、地图和图例，随后依次为连通性、校验结果和坐标表
+// - 内容超出页面时自动换页，表格在新页重复表头
+// - 每页页脚标注修订标识和页码
+package services
+
+import (
+	"fmt"
+	"image/color"
+	"math"
+	"strings"
+
+	"robot-path-editor/internal/domain"
+)
+
+// 排版参数（点）
+const (
+	reportMargin    = 40.0
+	reportFooter    = 24.0
+	reportMapHeight = 380
+	reportRowHeight = 16.0
+
+	reportContentWide = pdfA4Width - 2*reportMargin
+)
+
+var (
+	reportTextColor  = color.NRGBA{0x21, 0x25, 0x29, 255}
+	reportMutedColor = color.NRGBA{0x6c, 0x75, 0x7d, 255}
+	reportRuleColor  = color.NRGBA{0xce, 0xd4, 0xda, 255}
+	reportHeaderFill = color.NRGBA{0xe9, 0xec, 0xef, 255}
+	reportStripeFill = color.NRGBA{0xf8, 0xf9, 0xfa, 255}
+	reportErrorColor = color.NRGBA{0xdc, 0x35, 0x45, 255}
+	reportWarnColor  = color.NRGBA{0xb5, 0x83, 0x00, 255}
+	reportOKColor    = color.NRGBA{0x19, 0x87, 0x54, 255}
+)
+
+// reportColumn 表格列
+type reportColumn struct {
+	title string
+	width float64
+	right bool // 右对齐（数值列）
+}
+
+// reportLayout 流式排版状态
+type reportLayout struct {
+	doc  *pdfDocument
+	page *pdfPage
+	y    float64
+}
+
+// newPage 换页
+func (l *reportLayout) newPage() {
+	l.page = l.doc.addPage()
+	l.y = reportMargin
+}
+
+// ensure 剩余空间不足 h 时换页
+func (l *reportLayout) ensure(h float64) {
+	if l.page == nil || l.y+h > pdfA4Height-reportMargin-reportFooter {
+		l.newPage()
+	}
+}
+
+// heading 小节标题
+func (l *reportLayout) heading(title string) {
+	l.ensure(40)
+	l.y += 18
+	l.page.text(reportMargin, l.y, 13, reportTextColor, title)
+	l.y += 5
+	l.page.line(reportMargin, l.y, reportMargin+reportContentWide, l.y, 0.5, reportRuleColor)
+	l.y += 6
+}
+
+// textLine 单行文字
+func (l *reportLayout) textLine(s string, size float64, c color.NRGBA) {
+	l.ensure(size + 5)
+	l.y += size + 4
+	l.page.text(reportMargin, l.y, size, c, pdfFitText(s, size, reportContentWide))
+}
+
+// table 表格，换页时重复表头
+func (l *reportLayout) table(columns []reportColumn, rows [][]string) {
+	const size = 9.0
+	header := func() {
+		l.page.rect(reportMargin, l.y, reportContentWide, reportRowHeight, reportHeaderFill, color.NRGBA{}, 0)
+		l.row(columns, nil, size)
+	}
+
+	l.ensure(reportRowHeight * 2)
+	header()
+	for i, row := range rows {
+		if l.y+reportRowHeight > pdfA4Height-reportMargin-reportFooter {
+			l.newPage()
+			header()
+		}
+		if i%2 == 1 {
+			l.page.rect(reportMargin, l.y, reportContentWide, reportRowHeight, reportStripeFill, color.NRGBA{}, 0)
+		}
+		l.row(columns, row, size)
+	}
+	l.page.line(reportMargin, l.y, reportMargin+reportContentWide, l.y, 0.5, reportRuleColor)
+}
+
+// row 输出一行，cells 为 nil 时输出表头
+func (l *reportLayout) row(columns []reportColumn, cells []string, size float64) {
+	x := reportMargin
+	baseline := l.y + reportRowHeight - 4.5
+	for i, column := range columns {
+		text := column.title
+		if cells != nil {
+			text = cells[i]
+		}
+		text = pdfFitText(text, size, column.width-8)
+		tx := x + 4
+		if column.right {
+			tx = x + column.width - 4 - pdfTextWidth(text, size)
+		}
+		l.page.text(tx, baseline, size, reportTextColor, text)
+		x += column.width
+	}
+	l.y += reportRowHeight
+}
+
+// writeMapReportPDF 排版并生成报告
+func writeMapReportPDF(summary *MapReportSummary, nodes []*domain.Node, paths []*domain.Path, opts ReportOptions) ([]byte, error) {
+	doc := newPDFDocument(pdfA4Width, pdfA4Height, summary.Title)
+	l := &reportLayout{doc: doc}
+	l.newPage()
+
+	// 标题和修订标识
+	l.y += 20
+	l.page.text(reportMargin, l.y, 20, reportTextColor, pdfFitText(summary.Title, 20, reportContentWide))
+	l.textLine(fmt.Sprintf("生成时间 %s    %s", summary.GeneratedAt.Format("2006-01-02 15:04:05"), reportRevisionText(summary.Revision)),
+		9, reportMutedColor)
+	l.textLine(fmt.Sprintf("节点 %d    路径 %d    路径总长 %.2f    工作站 %d    充电站 %d",
+		summary.NodeCount, summary.PathCount, summary.TotalLength, len(summary.Stations), len(summary.Chargers)),
+		9, reportMutedColor)
+	l.y += 8
+
+	// 地图
+	padding := 16
+	renderOpts := RenderOptions{
+		Format:     "svg",
+		Width:      int(math.Floor(reportContentWide)),
+		Height:     reportMapHeight,
+		Padding:    &padding,
+		Background: "none",
+		Labels:     opts.Labels,
+		FlipY:      opts.FlipY,
+	}
+	if err := renderOpts.normalize(); err != nil {
+		return nil, err
+	}
+	scene, err := buildRenderScene(nodes, paths, renderOpts)
+	if err != nil {
+		return nil, err
+	}
+	l.page.drawScene(scene, reportMargin, l.y)
+	l.page.rect(reportMargin, l.y, float64(scene.width), float64(scene.height), color.NRGBA{}, reportRuleColor, 0.75)
+	l.y += float64(scene.height)
+
+	writeReportLegend(l, summary)
+	writeReportConnectivity(l, summary)
+	writeReportIssues(l, summary)
+	writeReportNodeTable(l, "工作站", summary.Stations)
+	writeReportNodeTable(l, "充电站", summary.Chargers)
+
+	// 页脚
+	stamp := reportRevisionText(summary.Revision)
+	for i, page := range doc.pages {
+		y := pdfA4Height - reportMargin + 8
+		page.line(reportMargin, y-12, reportMargin+reportContentWide, y-12, 0.5, reportRuleColor)
+		page.text(reportMargin, y, 8, reportMutedColor, pdfFitText(summary.Title+"    "+stamp, 8, reportContentWide-80))
+		number := fmt.Sprintf("第 %d / %d 页", i+1, len(doc.pages))
+		page.text(reportMargin+reportContentWide-pdfTextWidth(number, 8), y, 8, reportMutedColor, number)
+	}
+
+	return doc.bytes()
+}
+
+// reportRevisionText 修订标识文本
+func reportRevisionText(r ReportRevision) string {
+	text := fmt.Sprintf("修订 r%d · %s", r.Revision, r.Fingerprint)
+	if !r.LastModified.IsZero() {
+		text += " · 最后修改 " + r.LastModified.Format("2006-01-02 15:04")
+	}
+	return text
+}
+
+// writeReportLegend 图例：节点类型与路径样式，每行三项
+func writeReportLegend(l *reportLayout, summary *MapReportSummary) {
+	const cellWidth = 171.0
+	l.heading("图例")
+
+	grid := func(count int, draw func(i int, x, y float64)) {
+		for i := 0; i < count; i++ {
+			if i%3 == 0 {
+				l.ensure(reportRowHeight)
+				l.y += reportRowHeight
+			}
+			draw(i, reportMargin+float64(i%3)*cellWidth, l.y-reportRowHeight/2)
+		}
+	}
+
+	grid(len(summary.NodeTypes), func(i int, x, y float64) {
+		legend := summary.NodeTypes[i]
+		l.page.fillShape(nodeShape(legend.Style, vec{x + 8, y}, 5, math.Min(legend.Style.BorderWidth, 1.5)))
+		label := fmt.Sprintf("%s (%s) × %d", legend.Label, legend.Type, legend.Count)
+		l.page.text(x+20, y+3.5, 9, reportTextColor, pdfFitText(label, 9, cellWidth-24))
+	})
+
+	grid(len(summary.PathStyles), func(i int, x, y float64) {
+		legend := summary.PathStyles[i]
+		width := math.Min(math.Max(legend.Width, 1), 4)
+		points := []vec{{x, y}, {x + 30, y}}
+		stroke := renderStroke{
+			points:  points,
+			color:   styleColor(legend.Color, color.NRGBA{0x6c, 0x75, 0x7d, 255}),
+			width:   width,
+			opacity: 1,
+			dash:    strokeDash(legend.Style, width),
+		}
+		l.page.strokePath(stroke)
+		if legend.Directed {
+			l.page.fillShape(renderShape{polygon: directionArrow(points, domain.DirectionForward, width), fill: stroke.color, opacity: 1})
+		}
+		label := fmt.Sprintf("%s × %d", legend.Label, legend.Count)
+		l.page.text(x+36, y+3.5, 9, reportTextColor, pdfFitText(label, 9, cellWidth-40))
+	})
+}
+
+// writeReportConnectivity 连通性汇总
+func writeReportConnectivity(l *reportLayout, summary *MapReportSummary) {
+	c := summary.Connectivity
+	l.heading("连通性")
+	l.textLine(fmt.Sprintf("连通区域 %d    单向路径 %d    停用/阻塞路径 %d    站点间不可达 %d 对",
+		c.Components, c.OneWayPaths, c.UnusablePaths, c.UnreachablePairs), 10, reportTextColor)
+	l.textLine("孤立节点: "+reportNameList(c.IsolatedNodes), 10, reportTextColor)
+	l.textLine("无法驶出: "+reportNameList(c.DeadEnds), 10, reportTextColor)
+	l.textLine("无法到达充电站的工作站: "+reportNameList(c.NoChargerAccess), 10, reportTextColor)
+}
+
+func reportNameList(names []string) string {
+	if len(names) == 0 {
+		return "无"
+	}
+	return strings.Join(names, "、")
+}
+
+// writeReportIssues 校验结果
+func writeReportIssues(l *reportLayout, summary *MapReportSummary) {
+	l.heading("校验结果")
+	if len(summary.Issues) == 0 {
+		l.textLine("未发现问题", 10, reportOKColor)
+		return
+	}
+
+	errors := 0
+	for _, issue := range summary.Issues {
+		if issue.Severity == ReportSeverityError {
+			errors++
+		}
+	}
+	l.textLine(fmt.Sprintf("错误 %d    警告 %d", errors, len(summary.Issues)-errors), 10, reportTextColor)
+	for _, issue := range summary.Issues {
+		prefix, c := "警告", reportWarnColor
+		if issue.Severity == ReportSeverityError {
+			prefix, c = "错误", reportErrorColor
+		}
+		l.textLine(fmt.Sprintf("[%s] %s", prefix, issue.Message), 9, c)
+	}
+}
+
+// writeReportNodeTable 坐标表
+func writeReportNodeTable(l *reportLayout, title string, rows []ReportNodeRow) {
+	l.heading(fmt.Sprintf("%s（%d）", title, len(rows)))
+	if len(rows) == 0 {
+		l.textLine("无", 10, reportMutedColor)
+		return
+	}
+
+	columns := []reportColumn{
+		{title: "名称", width: 140},
+		{title: "ID", width: 75},
+		{title: "X", width: 70, right: true},
+		{title: "Y", width: 70, right: true},
+		{title: "Z", width: 50, right: true},
+		{title: "状态", width: 60},
+		{title: "路径数", width: 50, right: true},
+	}
+	cells := make([][]string, len(rows))
+	for i, row := range rows {
+		id := string(row.ID)
+		if len(id) > 8 {
+			id = id[:8]
+		}
+		cells[i] = []string{
+			row.Name,
+			id,
+			fmt.Sprintf("%.2f", row.Position.X),
+			fmt.Sprintf("%.2f", row.Position.Y),
+			fmt.Sprintf("%.2f", row.Position.Z),
+			string(row.Status),
+			fmt.Sprintf("%d", row.Degree),
+		}
+	}
+	l.table(columns, cells)
+}
@@ -0,0 +1,22 @@
+package services
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestWriteReportLegendDirectedArrow(t *testing.T) {
+	legendOps := func(directed bool) string {
+		l := &reportLayout{doc: newPDFDocument(pdfA4Width, pdfA4Height, "legend")}
+		summary := &MapReportSummary{PathStyles: []ReportPathLegend{{Color: "#1565c0", Width: 2, Directed: directed, Label: "路径", Count: 1}}}
+		writeReportLegend(l, summary)
+		return l.page.content.String()
+	}
+
+	plain := strings.Count(legendOps(false), "Q\n")
+	directed := strings.Count(legendOps(true), "Q\n")
+	// 有向图例比无向图例多绘制一个箭头
+	if directed != plain+1 {
+		t.Errorf("directed legend shapes = %d, want %d", directed, plain+1)
+	}
+}
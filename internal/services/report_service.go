@@ -0,0 +1,520 @@
+// Package services 地图报告服务
+//
+// 设计参考：
+// - 现场验收文档：地图、图例、关键点位坐标表、连通性与校验结论集中在一份可打印的文件中
+//
+// 特点：
+// - 报告内容先汇总为 MapReportSummary，PDF 与 JSON 输出共用同一份数据
+// - 修订标识取自 ObjectMeta：版本号之和、最后修改时间以及按 ID 与版本计算的指纹
+// - 连通性按路径方向和状态计算，停用、阻塞、已删除的路径不参与可达性分析
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"robot-path-editor/internal/domain"
+	"robot-path-editor/internal/repositories"
+)
+
+// 校验问题级别
+const (
+	ReportSeverityError   = "error"
+	ReportSeverityWarning = "warning"
+)
+
+// reportMaxReachabilityIssues 不可达站点对在问题列表中最多列出的条数
+const reportMaxReachabilityIssues = 20
+
+// ReportService 地图报告服务接口
+type ReportService interface {
+	// GenerateMapReport 生成当前地图的 PDF 报告
+	GenerateMapReport(ctx context.Context, opts ReportOptions) (*MapReport, error)
+}
+
+// ReportOptions 报告选项
+type ReportOptions struct {
+	Title  string `json:"title,omitempty" form:"title"`   // 报告标题，默认“地图报告”
+	Labels bool   `json:"labels,omitempty" form:"labels"` // 地图上标注节点名称
+	FlipY  bool   `json:"flip_y,omitempty" form:"flip_y"` // 编辑器坐标Y轴向上时翻转地图
+}
+
+// MapReport 生成的报告
+type MapReport struct {
+	Filename    string            `json:"filename"`
+	ContentType string            `json:"content_type"`
+	Summary     *MapReportSummary `json:"summary"`
+	Data        []byte            `json:"-"`
+}
+
+// MapReportSummary 报告内容汇总
+type MapReportSummary struct {
+	Title        string             `json:"title"`
+	GeneratedAt  time.Time          `json:"generated_at"`
+	Revision     ReportRevision     `json:"revision"`
+	NodeCount    int                `json:"node_count"`
+	PathCount    int                `json:"path_count"`
+	TotalLength  float64            `json:"total_length"`
+	NodeTypes    []ReportNodeLegend `json:"node_types"`
+	PathStyles   []ReportPathLegend `json:"path_styles"`
+	Stations     []ReportNodeRow    `json:"stations"`
+	Chargers     []ReportNodeRow    `json:"chargers"`
+	Connectivity ReportConnectivity `json:"connectivity"`
+	Issues       []ReportIssue      `json:"issues"`
+}
+
+// ReportRevision 修订标识
+type ReportRevision struct {
+	Revision     int       `json:"revision"`      // 全部节点和路径的版本号之和
+	Fingerprint  string    `json:"fingerprint"`   // ID与版本的摘要，内容变化即改变
+	LastModified time.Time `json:"last_modified"` // 最近一次修改时间
+}
+
+// ReportNodeLegend 节点类型图例，样式取该类型的第一个节点
+type ReportNodeLegend struct {
+	Type  domain.NodeType  `json:"type"`
+	Label string           `json:"label"`
+	Count int              `json:"count"`
+	Style domain.NodeStyle `json:"style"`
+}
+
+// ReportPathLegend 路径样式图例
+type ReportPathLegend struct {
+	Color    string  `json:"color"`
+	Width    float64 `json:"width"`
+	Style    string  `json:"style"`
+	Directed bool    `json:"directed"`
+	Label    string  `json:"label"`
+	Count    int     `json:"count"`
+}
+
+// ReportNodeRow 坐标表中的一行
+type ReportNodeRow struct {
+	ID       domain.NodeID     `json:"id"`
+	Name     string            `json:"name"`
+	Position domain.Position   `json:"position"`
+	Status   domain.NodeStatus `json:"status"`
+	Degree   int               `json:"degree"` // 连接的路径数
+}
+
+// ReportConnectivity 连通性汇总
+type ReportConnectivity struct {
+	Components       int      `json:"components"`        // 连通分量数（不考虑方向）
+	IsolatedNodes    []string `json:"isolated_nodes"`    // 没有任何路径的节点
+	DeadEnds         []string `json:"dead_ends"`         // 有路径但无法驶出的节点
+	OneWayPaths      int      `json:"one_way_paths"`     // 单向路径数
+	UnusablePaths    int      `json:"unusable_paths"`    // 停用、阻塞或已删除的路径数
+	UnreachablePairs int      `json:"unreachable_pairs"` // 工作站/充电站之间不可达的有序对数
+	NoChargerAccess  []string `json:"no_charger_access"` // 无法到达任何充电站的工作站
+}
+
+// ReportIssue 校验问题
+type ReportIssue struct {
+	Severity string `json:"severity"`
+	Message  string `json:"message"`
+}
+
+// reportService 地图报告服务实现
+type reportService struct {
+	nodeRepo repositories.NodeRepository
+	pathRepo repositories.PathRepository
+}
+
+// NewReportService 创建新的地图报告服务实例
+func NewReportService(nodeRepo repositories.NodeRepository, pathRepo repositories.PathRepository) ReportService {
+	return &reportService{
+		nodeRepo: nodeRepo,
+		pathRepo: pathRepo,
+	}
+}
+
+// GenerateMapReport 生成当前地图的 PDF 报告
+func (s *reportService) GenerateMapReport(ctx context.Context, opts ReportOptions) (*MapReport, error) {
+	nodes, err := s.nodeRepo.List(ctx, repositories.NodeFilter{})
+	if err != nil {
+		return nil, fmt.Errorf("获取节点列表失败: %w", err)
+	}
+	paths, err := s.pathRepo.List(ctx, repositories.PathFilter{})
+	if err != nil {
+		return nil, fmt.Errorf("获取路径列表失败: %w", err)
+	}
+	sort.SliceStable(nodes, func(i, j int) bool { return nodes[i].Name < nodes[j].Name })
+	sort.SliceStable(paths, func(i, j int) bool { return paths[i].Name < paths[j].Name })
+
+	title := strings.TrimSpace(opts.Title)
+	if title == "" {
+		title = "地图报告"
+	}
+	summary := summarizeMap(nodes, paths)
+	summary.Title = title
+	summary.GeneratedAt = time.Now()
+
+	data, err := writeMapReportPDF(summary, nodes, paths, opts)
+	if err != nil {
+		return nil, fmt.Errorf("生成PDF报告失败: %w", err)
+	}
+
+	return &MapReport{
+		Filename:    fmt.Sprintf("map-report-%s.pdf", summary.GeneratedAt.Format("20060102-150405")),
+		ContentType: "application/pdf",
+		Summary:     summary,
+		Data:        data,
+	}, nil
+}
+
+// === 汇总 ===
+
+// 节点类型和线型的中文名称
+var (
+	reportNodeTypeLabels = map[domain.NodeType]string{
+		domain.NodeTypeStation:  "工作站",
+		domain.NodeTypeCharging: "充电站",
+		domain.NodeTypeWaypoint: "路径点",
+		domain.NodeTypePoint:    "普通点位",
+	}
+	reportNodeTypeOrder = []domain.NodeType{
+		domain.NodeTypeStation, domain.NodeTypeCharging, domain.NodeTypeWaypoint, domain.NodeTypePoint,
+	}
+	reportLineStyleLabels = map[string]string{
+		"solid":  "实线",
+		"dashed": "虚线",
+		"dotted": "点线",
+	}
+)
+
+// summarizeMap 汇总图例、坐标表、连通性和校验结果
+func summarizeMap(nodes []*domain.Node, paths []*domain.Path) *MapReportSummary {
+	summary := &MapReportSummary{
+		NodeCount: len(nodes),
+		PathCount: len(paths),
+		Revision:  reportRevision(nodes, paths),
+	}
+
+	nodeByID := make(map[domain.NodeID]*domain.Node, len(nodes))
+	for _, n := range nodes {
+		nodeByID[n.ID] = n
+	}
+
+	summary.Issues = append(summary.Issues, validateReportNodes(nodes)...)
+
+	// 有效路径：两端节点存在且不同
+	degree := make(map[domain.NodeID]int, len(nodes))
+	var valid []*domain.Path
+	pairs := make(map[[2]domain.NodeID]string)
+	for _, p := range paths {
+		if err := p.IsValid(); err != nil {
+			summary.Issues = append(summary.Issues, reportIssue(ReportSeverityError, "路径 %s: %v", reportName(p.Name, string(p.ID)), err))
+			continue
+		}
+		start, ok1 := nodeByID[p.StartNodeID]
+		end, ok2 := nodeByID[p.EndNodeID]
+		if !ok1 || !ok2 {
+			summary.Issues = append(summary.Issues, reportIssue(ReportSeverityError, "路径 %s 引用了不存在的节点", p.Name))
+			continue
+		}
+		if p.Weight > 10000 {
+			summary.Issues = append(summary.Issues, reportIssue(ReportSeverityWarning, "路径 %s 的权重 %.2f 超过10000", p.Name, p.Weight))
+		}
+
+		key := [2]domain.NodeID{p.StartNodeID, p.EndNodeID}
+		if key[1] < key[0] {
+			key[0], key[1] = key[1], key[0]
+		}
+		if other, ok := pairs[key]; ok {
+			summary.Issues = append(summary.Issues, reportIssue(ReportSeverityWarning, "路径 %s 与 %s 连接相同的节点", p.Name, other))
+		} else {
+			pairs[key] = p.Name
+		}
+
+		degree[p.StartNodeID]++
+		degree[p.EndNodeID]++
+		valid = append(valid, p)
+
+		length := p.Length
+		if length <= 0 {
+			length = domain.PolylineLength(domain.SampleCurve(p.ControlPoints(start.Position, end.Position), p.CurveType, renderCurveSegments))
+		}
+		if !math.IsNaN(length) && !math.IsInf(length, 0) {
+			summary.TotalLength += length
+		}
+	}
+
+	summary.NodeTypes = reportNodeLegends(nodes)
+	summary.PathStyles = reportPathLegends(valid)
+	for _, n := range nodes {
+		row := ReportNodeRow{ID: n.ID, Name: n.Name, Position: n.Position, Status: n.Status, Degree: degree[n.ID]}
+		switch n.Type {
+		case domain.NodeTypeStation:
+			summary.Stations = append(summary.Stations, row)
+		case domain.NodeTypeCharging:
+			summary.Chargers = append(summary.Chargers, row)
+		}
+	}
+
+	connectivity, issues := analyzeConnectivity(nodes, valid, degree)
+	summary.Connectivity = connectivity
+	summary.Issues = append(summary.Issues, issues...)
+
+	sort.SliceStable(summary.Issues, func(i, j int) bool {
+		return summary.Issues[i].Severity == ReportSeverityError && summary.Issues[j].Severity != ReportSeverityError
+	})
+	return summary
+}
+
+func reportIssue(severity, format string, args ...interface{}) ReportIssue {
+	return ReportIssue{Severity: severity, Message: fmt.Sprintf(format, args...)}
+}
+
+func reportName(name, id string) string {
+	if name != "" {
+		return name
+	}
+	return id
+}
+
+// reportRevision 由 ObjectMeta 计算修订标识
+func reportRevision(nodes []*domain.Node, paths []*domain.Path) ReportRevision {
+	var revision ReportRevision
+	entries := make([]string, 0, len(nodes)+len(paths))
+	track := func(kind, id string, meta domain.ObjectMeta) {
+		revision.Revision += meta.Version
+		if meta.UpdatedAt.After(revision.LastModified) {
+			revision.LastModified = meta.UpdatedAt
+		}
+		entries = append(entries, fmt.Sprintf("%s:%s:%d", kind, id, meta.Version))
+	}
+	for _, n := range nodes {
+		track("node", string(n.ID), n.Metadata)
+	}
+	for _, p := range paths {
+		track("path", string(p.ID), p.Metadata)
+	}
+
+	sort.Strings(entries)
+	sum := sha256.Sum256([]byte(strings.Join(entries, "\n")))
+	revision.Fingerprint = hex.EncodeToString(sum[:])[:12]
+	return revision
+}
+
+// validateReportNodes 节点自身的校验：有效性、坐标、重名
+func validateReportNodes(nodes []*domain.Node) []ReportIssue {
+	var issues []ReportIssue
+	names := make(map[string]int)
+	for _, n := range nodes {
+		if err := n.IsValid(); err != nil {
+			issues = append(issues, reportIssue(ReportSeverityError, "节点 %s: %v", n.ID, err))
+		}
+		if !finitePosition(n.Position) {
+			issues = append(issues, reportIssue(ReportSeverityError, "节点 %s 的坐标无效", reportName(n.Name, string(n.ID))))
+		}
+		if n.Name != "" {
+			names[n.Name]++
+		}
+	}
+
+	duplicated := make([]string, 0)
+	for name, count := range names {
+		if count > 1 {
+			duplicated = append(duplicated, name)
+		}
+	}
+	sort.Strings(duplicated)
+	for _, name := range duplicated {
+		issues = append(issues, reportIssue(ReportSeverityWarning, "节点名称 %s 重复 %d 次", name, names[name]))
+	}
+	return issues
+}
+
+// reportNodeLegends 按类型统计节点，已知类型在前
+func reportNodeLegends(nodes []*domain.Node) []ReportNodeLegend {
+	byType := make(map[domain.NodeType]*ReportNodeLegend)
+	for _, n := range nodes {
+		legend, ok := byType[n.Type]
+		if !ok {
+			label := reportNodeTypeLabels[n.Type]
+			if label == "" {
+				label = string(n.Type)
+			}
+			legend = &ReportNodeLegend{Type: n.Type, Label: label, Style: n.Style}
+			byType[n.Type] = legend
+		}
+		legend.Count++
+	}
+
+	rank := func(t domain.NodeType) int {
+		for i, known := range reportNodeTypeOrder {
+			if known == t {
+				return i
+			}
+		}
+		return len(reportNodeTypeOrder)
+	}
+	legends := make([]ReportNodeLegend, 0, len(byType))
+	for _, legend := range byType {
+		legends = append(legends, *legend)
+	}
+	sort.Slice(legends, func(i, j int) bool {
+		if ri, rj := rank(legends[i].Type), rank(legends[j].Type); ri != rj {
+			return ri < rj
+		}
+		return legends[i].Type < legends[j].Type
+	})
+	return legends
+}
+
+// reportPathLegends 按颜色、线宽、线型和方向分组统计路径
+func reportPathLegends(paths []*domain.Path) []ReportPathLegend {
+	var legends []ReportPathLegend
+	index := make(map[string]int)
+	for _, p := range paths {
+		style := p.Style.Style
+		if style == "" {
+			style = "solid"
+		}
+		directed := !p.CanTraverse(p.StartNodeID) || !p.CanTraverse(p.EndNodeID)
+		key := fmt.Sprintf("%s|%g|%s|%t", strings.ToLower(p.Style.Color), p.Style.Width, style, directed)
+		if i, ok := index[key]; ok {
+			legends[i].Count++
+			continue
+		}
+
+		label := reportLineStyleLabels[style]
+		if label == "" {
+			label = style
+		}
+		direction := "双向"
+		if directed {
+			direction = "单向"
+		}
+		index[key] = len(legends)
+		legends = append(legends, ReportPathLegend{
+			Color:    p.Style.Color,
+			Width:    p.Style.Width,
+			Style:    style,
+			Directed: directed,
+			Label:    fmt.Sprintf("%s %s 线宽%g", direction, label, styleSize(p.Style.Width, 2)),
+			Count:    1,
+		})
+	}
+	return legends
+}
+
+// analyzeConnectivity 连通分量、孤立点、死胡同以及工作站/充电站之间的可达性
+func analyzeConnectivity(nodes []*domain.Node, paths []*domain.Path, degree map[domain.NodeID]int) (ReportConnectivity, []ReportIssue) {
+	var result ReportConnectivity
+	var issues []ReportIssue
+
+	// 不考虑方向的连通分量
+	parent := make(map[domain.NodeID]domain.NodeID, len(nodes))
+	var find func(id domain.NodeID) domain.NodeID
+	find = func(id domain.NodeID) domain.NodeID {
+		if parent[id] != id {
+			parent[id] = find(parent[id])
+		}
+		return parent[id]
+	}
+	for _, n := range nodes {
+		parent[n.ID] = n.ID
+	}
+
+	// 可通行的有向邻接表
+	adjacency := make(map[domain.NodeID][]domain.NodeID)
+	for _, p := range paths {
+		parent[find(p.StartNodeID)] = find(p.EndNodeID)
+
+		if p.CanTraverse(p.StartNodeID) != p.CanTraverse(p.EndNodeID) {
+			result.OneWayPaths++
+		}
+		switch p.Status {
+		case domain.PathStatusInactive, domain.PathStatusBlocked, domain.PathStatusDeleted:
+			result.UnusablePaths++
+			continue
+		}
+		if p.CanTraverse(p.StartNodeID) {
+			adjacency[p.StartNodeID] = append(adjacency[p.StartNodeID], p.EndNodeID)
+		}
+		if p.CanTraverse(p.EndNodeID) {
+			adjacency[p.EndNodeID] = append(adjacency[p.EndNodeID], p.StartNodeID)
+		}
+	}
+
+	roots := make(map[domain.NodeID]bool)
+	var keyNodes, chargers []*domain.Node
+	for _, n := range nodes {
+		roots[find(n.ID)] = true
+		if degree[n.ID] == 0 {
+			result.IsolatedNodes = append(result.IsolatedNodes, n.Name)
+			issues = append(issues, reportIssue(ReportSeverityWarning, "节点 %s 没有连接任何路径", n.Name))
+		} else if len(adjacency[n.ID]) == 0 {
+			result.DeadEnds = append(result.DeadEnds, n.Name)
+			issues = append(issues, reportIssue(ReportSeverityWarning, "节点 %s 无法驶出", n.Name))
+		}
+		switch n.Type {
+		case domain.NodeTypeStation:
+			keyNodes = append(keyNodes, n)
+		case domain.NodeTypeCharging:
+			keyNodes = append(keyNodes, n)
+			chargers = append(chargers, n)
+		}
+	}
+	result.Components = len(roots)
+	if result.Components > 1 {
+		issues = append(issues, reportIssue(ReportSeverityWarning, "地图包含 %d 个互不相连的区域", result.Components))
+	}
+
+	// 工作站/充电站之间的可达性
+	listed := 0
+	for _, from := range keyNodes {
+		reachable := reachableNodes(from.ID, adjacency)
+		for _, to := range keyNodes {
+			if to.ID == from.ID || reachable[to.ID] {
+				continue
+			}
+			result.UnreachablePairs++
+			if listed < reportMaxReachabilityIssues {
+				issues = append(issues, reportIssue(ReportSeverityWarning, "%s 无法到达 %s", from.Name, to.Name))
+				listed++
+			}
+		}
+
+		if from.Type != domain.NodeTypeStation || len(chargers) == 0 {
+			continue
+		}
+		charged := false
+		for _, c := range chargers {
+			charged = charged || reachable[c.ID]
+		}
+		if !charged {
+			result.NoChargerAccess = append(result.NoChargerAccess, from.Name)
+			issues = append(issues, reportIssue(ReportSeverityWarning, "工作站 %s 无法到达任何充电站", from.Name))
+		}
+	}
+	if result.UnreachablePairs > listed {
+		issues = append(issues, reportIssue(ReportSeverityWarning, "另有 %d 对站点不可达", result.UnreachablePairs-listed))
+	}
+
+	return result, issues
+}
+
+// reachableNodes 广度优先搜索可达节点
+func reachableNodes(from domain.NodeID, adjacency map[domain.NodeID][]domain.NodeID) map[domain.NodeID]bool {
+	visited := map[domain.NodeID]bool{from: true}
+	queue := []domain.NodeID{from}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		for _, next := range adjacency[current] {
+			if !visited[next] {
+				visited[next] = true
+				queue = append(queue, next)
+			}
+		}
+	}
+	return visited
+}
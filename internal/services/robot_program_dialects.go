@@ -0,0 +1,226 @@
+// Package services 内置机器人程序方言
+//
+// - URScript（Universal Robots）：位姿单位米/弧度，姿态为旋转向量
+// - RAPID（ABB）：robtarget 使用毫米和四元数
+// - KRL（KUKA）：E6POS 使用毫米和 A/B/C（绕Z/Y/X的欧拉角，度）
+package services
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// === URScript ===
+
+// urMaxJointSpeed UR关节最大速度（rad/s），JointSpeed百分比以此为基准
+const urMaxJointSpeed = math.Pi
+
+// urScriptDialect URScript方言
+type urScriptDialect struct{}
+
+func (d *urScriptDialect) Name() string          { return "urscript" }
+func (d *urScriptDialect) Description() string   { return "Universal Robots URScript (movej/movel)" }
+func (d *urScriptDialect) FileExtension() string { return ".script" }
+
+// Generate 生成URScript程序
+func (d *urScriptDialect) Generate(program *RobotProgram) (string, error) {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "def %s():\n", program.Name)
+	writeProgramHeader(&b, "  # ", program)
+
+	for _, target := range program.Targets {
+		rx, ry, rz := target.Pose.Orientation.RotationVector()
+		pose := fmt.Sprintf("p[%.6f, %.6f, %.6f, %.6f, %.6f, %.6f]",
+			target.Pose.Position.X/1000, target.Pose.Position.Y/1000, target.Pose.Position.Z/1000,
+			rx, ry, rz)
+
+		fmt.Fprintf(&b, "  # %s\n", target.Name)
+		switch target.Motion {
+		case MotionJoint:
+			speed := urMaxJointSpeed * target.JointSpeed / 100
+			fmt.Fprintf(&b, "  movej(%s, a=%.4f, v=%.4f, r=%.4f)\n", pose, speed*1.4, speed, target.Zone/1000)
+		default:
+			fmt.Fprintf(&b, "  movel(%s, a=%.4f, v=%.4f, r=%.4f)\n",
+				pose, target.Acceleration/1000, target.Speed/1000, target.Zone/1000)
+		}
+	}
+
+	b.WriteString("end\n")
+	fmt.Fprintf(&b, "%s()\n", program.Name)
+	return b.String(), nil
+}
+
+// === ABB RAPID ===
+
+// rapidSpeeds ABB预定义speeddata（mm/s）
+var rapidSpeeds = []int{5, 10, 20, 30, 40, 50, 60, 80, 100, 150, 200, 300, 400, 500, 600, 800, 1000, 1500, 2000, 2500, 3000, 4000, 5000, 6000, 7000}
+
+// rapidZones ABB预定义zonedata（mm）
+var rapidZones = []int{0, 1, 5, 10, 15, 20, 30, 40, 50, 60, 80, 100, 150, 200}
+
+// rapidDialect ABB RAPID方言
+type rapidDialect struct{}
+
+func (d *rapidDialect) Name() string          { return "rapid" }
+func (d *rapidDialect) Description() string   { return "ABB RAPID (MoveJ/MoveL + robtarget)" }
+func (d *rapidDialect) FileExtension() string { return ".mod" }
+
+// Generate 生成RAPID模块
+func (d *rapidDialect) Generate(program *RobotProgram) (string, error) {
+	var decl, body strings.Builder
+	customSpeeds := make(map[string]bool)
+	customZones := make(map[string]bool)
+
+	for _, target := range program.Targets {
+		q := target.Pose.Orientation
+		p := target.Pose.Position
+		fmt.Fprintf(&decl, "    CONST robtarget %s:=[[%.3f,%.3f,%.3f],[%.6f,%.6f,%.6f,%.6f],[0,0,0,0],[9E+09,9E+09,9E+09,9E+09,9E+09,9E+09]];\n",
+			target.Name, p.X, p.Y, p.Z, q.W, q.X, q.Y, q.Z)
+
+		speed := rapidSpeedName(target.Speed)
+		if !isPredefined(rapidSpeeds, target.Speed) && !customSpeeds[speed] {
+			customSpeeds[speed] = true
+			fmt.Fprintf(&decl, "    CONST speeddata %s:=[%.1f,500,5000,1000];\n", speed, target.Speed)
+		}
+
+		zone := rapidZoneName(target.Zone)
+		if target.Zone > 0 && !isPredefined(rapidZones, target.Zone) && !customZones[zone] {
+			customZones[zone] = true
+			z := target.Zone
+			fmt.Fprintf(&decl, "    CONST zonedata %s:=[FALSE,%.1f,%.1f,%.1f,%.2f,%.1f,%.2f];\n",
+				zone, z, z*1.5, z*1.5, z*0.15, z*1.5, z*0.15)
+		}
+
+		instruction := "MoveL"
+		if target.Motion == MotionJoint {
+			instruction = "MoveJ"
+		}
+		fmt.Fprintf(&body, "        %s %s,%s,%s,%s\\WObj:=%s;\n",
+			instruction, target.Name, speed, zone, program.Tool, program.WorkObject)
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "MODULE %s\n", truncateIdentifier(program.Name, 32))
+	writeProgramHeader(&b, "    ! ", program)
+	b.WriteString(decl.String())
+	b.WriteString("\n    PROC main()\n")
+	b.WriteString("        ConfJ\\Off;\n")
+	b.WriteString("        ConfL\\Off;\n")
+	b.WriteString(body.String())
+	b.WriteString("    ENDPROC\n")
+	b.WriteString("ENDMODULE\n")
+	return b.String(), nil
+}
+
+// rapidSpeedName 返回速度对应的speeddata名称
+func rapidSpeedName(speed float64) string {
+	if isPredefined(rapidSpeeds, speed) {
+		return fmt.Sprintf("v%d", int(speed))
+	}
+	return "vCustom" + customDataSuffix(speed)
+}
+
+// rapidZoneName 返回过渡半径对应的zonedata名称
+func rapidZoneName(zone float64) string {
+	if zone <= 0 {
+		return "fine"
+	}
+	if isPredefined(rapidZones, zone) {
+		return fmt.Sprintf("z%d", int(zone))
+	}
+	return "zCustom" + customDataSuffix(zone)
+}
+
+// customDataSuffix 将数值转换为可用于标识符的后缀，如 250 -> "250"、12.5 -> "12_5"
+func customDataSuffix(v float64) string {
+	return strings.ReplaceAll(strconv.FormatFloat(math.Round(v*10)/10, 'f', -1, 64), ".", "_")
+}
+
+// isPredefined 判断数值是否为预定义值之一
+func isPredefined(values []int, v float64) bool {
+	for _, value := range values {
+		if float64(value) == v {
+			return true
+		}
+	}
+	return false
+}
+
+// === KUKA KRL ===
+
+// krlDialect KUKA KRL方言
+type krlDialect struct{}
+
+func (d *krlDialect) Name() string          { return "krl" }
+func (d *krlDialect) Description() string   { return "KUKA KRL (PTP/LIN)" }
+func (d *krlDialect) FileExtension() string { return ".src" }
+
+// Generate 生成KRL程序
+func (d *krlDialect) Generate(program *RobotProgram) (string, error) {
+	var b strings.Builder
+	name := truncateIdentifier(program.Name, 24)
+
+	b.WriteString("&ACCESS RVP\n&REL 1\n")
+	fmt.Fprintf(&b, "DEF %s( )\n", name)
+	writeProgramHeader(&b, "; ", program)
+	b.WriteString(";FOLD INI\n  BAS (#INITMOV,0 )\n;ENDFOLD\n\n")
+
+	var lastVel, lastAcc, lastZone, lastJoint float64 = -1, -1, -1, -1
+	for _, target := range program.Targets {
+		p := target.Pose.Position
+		c := target.Coords
+		pos := fmt.Sprintf("{X %.3f,Y %.3f,Z %.3f,A %.3f,B %.3f,C %.3f}", p.X, p.Y, p.Z, c.Yaw, c.Pitch, c.Roll)
+
+		if target.Zone != lastZone {
+			fmt.Fprintf(&b, "  $APO.CDIS = %.1f\n", target.Zone)
+			lastZone = target.Zone
+		}
+		approx := ""
+		if target.Zone > 0 {
+			approx = " C_DIS"
+		}
+
+		fmt.Fprintf(&b, "  ; %s\n", target.Name)
+		switch target.Motion {
+		case MotionJoint:
+			if target.JointSpeed != lastJoint {
+				fmt.Fprintf(&b, "  BAS (#VEL_PTP,%.0f )\n", target.JointSpeed)
+				lastJoint = target.JointSpeed
+			}
+			fmt.Fprintf(&b, "  PTP %s%s\n", pos, approx)
+		default:
+			if target.Speed != lastVel {
+				fmt.Fprintf(&b, "  $VEL.CP = %.4f\n", target.Speed/1000)
+				lastVel = target.Speed
+			}
+			if target.Acceleration != lastAcc {
+				fmt.Fprintf(&b, "  $ACC.CP = %.4f\n", target.Acceleration/1000)
+				lastAcc = target.Acceleration
+			}
+			fmt.Fprintf(&b, "  LIN %s%s\n", pos, approx)
+		}
+	}
+
+	b.WriteString("END\n")
+	return b.String(), nil
+}
+
+// === 公共工具 ===
+
+// writeProgramHeader 写入程序头注释
+func writeProgramHeader(b *strings.Builder, prefix string, program *RobotProgram) {
+	fmt.Fprintf(b, "%sGenerated by robot-path-editor at %s\n", prefix, time.Now().Format(time.RFC3339))
+	fmt.Fprintf(b, "%sTargets: %d, tool: %s, work object: %s\n", prefix, len(program.Targets), program.Tool, program.WorkObject)
+}
+
+// truncateIdentifier 截断标识符到控制器允许的最大长度
+func truncateIdentifier(name string, max int) string {
+	if len(name) > max {
+		return name[:max]
+	}
+	return name
+}
@@ -0,0 +1,308 @@
+// Package services 机器人程序生成服务
+//
+// 设计参考：
+// - RoboDK 的后处理器（Post Processor）机制
+// - 插件服务的注册表模式
+//
+// 特点：
+// 1. 方言可插拔：URScript、ABB RAPID、KUKA KRL，可注册新方言
+// 2. 运动参数来自路径扩展属性（motion、speed、joint_speed、zone、acceleration）
+// 3. 机器人坐标约定单位为毫米和度，由各方言自行转换
+package services
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"robot-path-editor/internal/domain"
+	"robot-path-editor/internal/repositories"
+)
+
+// RobotProgramService 机器人程序生成服务接口
+type RobotProgramService interface {
+	// 方言管理
+	RegisterDialect(dialect RobotProgramDialect) error
+	ListDialects() []RobotDialectInfo
+
+	// 程序生成
+	ExportRobotProgram(ctx context.Context, req ExportRobotProgramRequest) (*RobotProgramFile, error)
+}
+
+// RobotProgramDialect 机器人程序方言接口
+type RobotProgramDialect interface {
+	Name() string
+	Description() string
+	FileExtension() string
+	Generate(program *RobotProgram) (string, error)
+}
+
+// RobotDialectInfo 方言信息
+type RobotDialectInfo struct {
+	Name          string `json:"name"`
+	Description   string `json:"description"`
+	FileExtension string `json:"file_extension"`
+}
+
+// 运动类型
+const (
+	MotionJoint  = "joint"  // 关节运动：movej / MoveJ / PTP
+	MotionLinear = "linear" // 直线运动：movel / MoveL / LIN
+)
+
+// ExportRobotProgramRequest 导出机器人程序请求
+type ExportRobotProgramRequest struct {
+	RouteRequest
+	Dialect     string `json:"dialect" binding:"required"`
+	ProgramName string `json:"program_name,omitempty"`
+
+	// 路径未在属性中指定时使用的默认运动参数
+	DefaultMotion       string  `json:"default_motion,omitempty"`       // joint, linear
+	DefaultSpeed        float64 `json:"default_speed,omitempty"`        // TCP速度 mm/s
+	DefaultZone         float64 `json:"default_zone,omitempty"`         // 过渡半径 mm，0表示精确到位
+	DefaultAcceleration float64 `json:"default_acceleration,omitempty"` // TCP加速度 mm/s²
+	DefaultJointSpeed   float64 `json:"default_joint_speed,omitempty"`  // 关节运动速度百分比 1-100
+
+	Tool       string `json:"tool,omitempty"`        // 工具坐标系名称
+	WorkObject string `json:"work_object,omitempty"` // 工件坐标系名称
+}
+
+// RobotProgram 与方言无关的机器人程序中间表示
+type RobotProgram struct {
+	Name       string        `json:"name"`
+	Tool       string        `json:"tool"`
+	WorkObject string        `json:"work_object"`
+	Targets    []RobotTarget `json:"targets"`
+}
+
+// RobotTarget 程序中的一个运动目标
+type RobotTarget struct {
+	Name         string                  `json:"name"`
+	NodeID       domain.NodeID           `json:"node_id"`
+	Coords       domain.RobotCoordinates `json:"coords"`
+	Pose         domain.Pose             `json:"pose"`
+	Motion       string                  `json:"motion"`
+	Speed        float64                 `json:"speed"`        // TCP速度 mm/s
+	JointSpeed   float64                 `json:"joint_speed"`  // 关节运动速度，占最大速度的百分比
+	Zone         float64                 `json:"zone"`         // 过渡半径 mm
+	Acceleration float64                 `json:"acceleration"` // TCP加速度 mm/s²
+}
+
+// RobotProgramFile 生成的程序文件
+type RobotProgramFile struct {
+	Dialect  string        `json:"dialect"`
+	Filename string        `json:"filename"`
+	Content  string        `json:"content"`
+	Program  *RobotProgram `json:"program"`
+}
+
+const (
+	defaultRobotSpeed        = 250.0  // mm/s
+	defaultRobotZone         = 0.0    // mm
+	defaultRobotAcceleration = 1200.0 // mm/s²
+	defaultRobotJointSpeed   = 50.0   // %
+)
+
+// robotProgramService 机器人程序生成服务实现
+type robotProgramService struct {
+	routes *routeResolver
+
+	mu       sync.RWMutex
+	dialects map[string]RobotProgramDialect
+}
+
+// NewRobotProgramService 创建新的机器人程序生成服务实例，并注册内置方言
+func NewRobotProgramService(nodeRepo repositories.NodeRepository, pathRepo repositories.PathRepository) RobotProgramService {
+	s := &robotProgramService{
+		routes:   newRouteResolver(nodeRepo, pathRepo),
+		dialects: make(map[string]RobotProgramDialect),
+	}
+
+	s.RegisterDialect(&urScriptDialect{})
+	s.RegisterDialect(&rapidDialect{})
+	s.RegisterDialect(&krlDialect{})
+
+	return s
+}
+
+// RegisterDialect 注册方言
+func (s *robotProgramService) RegisterDialect(dialect RobotProgramDialect) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	name := strings.ToLower(dialect.Name())
+	if _, exists := s.dialects[name]; exists {
+		return fmt.Errorf("方言已注册: %s", name)
+	}
+	s.dialects[name] = dialect
+	return nil
+}
+
+// ListDialects 列出已注册的方言
+func (s *robotProgramService) ListDialects() []RobotDialectInfo {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	infos := make([]RobotDialectInfo, 0, len(s.dialects))
+	for _, dialect := range s.dialects {
+		infos = append(infos, RobotDialectInfo{
+			Name:          dialect.Name(),
+			Description:   dialect.Description(),
+			FileExtension: dialect.FileExtension(),
+		})
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Name < infos[j].Name })
+	return infos
+}
+
+// ExportRobotProgram 将路线导出为机器人程序
+func (s *robotProgramService) ExportRobotProgram(ctx context.Context, req ExportRobotProgramRequest) (*RobotProgramFile, error) {
+	s.mu.RLock()
+	dialect, ok := s.dialects[strings.ToLower(req.Dialect)]
+	s.mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("不支持的机器人程序方言: %s", req.Dialect)
+	}
+
+	route, err := s.routes.Resolve(ctx, req.RouteRequest)
+	if err != nil {
+		return nil, err
+	}
+
+	program, err := buildRobotProgram(route, req)
+	if err != nil {
+		return nil, err
+	}
+
+	content, err := dialect.Generate(program)
+	if err != nil {
+		return nil, fmt.Errorf("生成%s程序失败: %w", dialect.Name(), err)
+	}
+
+	return &RobotProgramFile{
+		Dialect:  dialect.Name(),
+		Filename: program.Name + dialect.FileExtension(),
+		Content:  content,
+		Program:  program,
+	}, nil
+}
+
+// buildRobotProgram 由路线构建程序中间表示
+// 第一个目标为接近点，总是使用关节运动；其余目标的运动参数取自到达它的那段路径
+func buildRobotProgram(route *Route, req ExportRobotProgramRequest) (*RobotProgram, error) {
+	program := &RobotProgram{
+		Name:       sanitizeProgramIdentifier(req.ProgramName, "RoutePath"),
+		Tool:       sanitizeProgramIdentifier(req.Tool, "tool0"),
+		WorkObject: sanitizeProgramIdentifier(req.WorkObject, "wobj0"),
+	}
+
+	defaults := RobotTarget{
+		Motion:       MotionLinear,
+		Speed:        defaultRobotSpeed,
+		Zone:         defaultRobotZone,
+		Acceleration: defaultRobotAcceleration,
+		JointSpeed:   defaultRobotJointSpeed,
+	}
+	if req.DefaultMotion != "" {
+		motion, err := normalizeMotion(req.DefaultMotion)
+		if err != nil {
+			return nil, err
+		}
+		defaults.Motion = motion
+	}
+	if req.DefaultSpeed > 0 {
+		defaults.Speed = req.DefaultSpeed
+	}
+	if req.DefaultZone > 0 {
+		defaults.Zone = req.DefaultZone
+	}
+	if req.DefaultAcceleration > 0 {
+		defaults.Acceleration = req.DefaultAcceleration
+	}
+	if req.DefaultJointSpeed > 0 {
+		defaults.JointSpeed = math.Min(req.DefaultJointSpeed, 100)
+	}
+
+	for i, node := range route.Nodes {
+		if node.RobotCoords == nil {
+			return nil, fmt.Errorf("节点 %s 未配置机器人坐标", node.Name)
+		}
+
+		target := defaults
+		target.Name = fmt.Sprintf("P%d", i+1)
+		target.NodeID = node.ID
+		target.Coords = *node.RobotCoords
+		target.Pose = node.RobotCoords.Pose()
+
+		if i == 0 {
+			target.Motion = MotionJoint
+		} else if leg := route.Legs[i-1]; leg.Path != nil {
+			if err := applyMotionProperties(&target, leg.Path.Properties); err != nil {
+				return nil, fmt.Errorf("路径 %s: %w", leg.Path.Name, err)
+			}
+		}
+
+		program.Targets = append(program.Targets, target)
+	}
+
+	if len(program.Targets) == 0 {
+		return nil, fmt.Errorf("路线中没有可生成的目标点")
+	}
+
+	return program, nil
+}
+
+// applyMotionProperties 从路径扩展属性读取运动参数
+func applyMotionProperties(target *RobotTarget, props map[string]interface{}) error {
+	if motion, ok := propertyString(props, "motion"); ok {
+		normalized, err := normalizeMotion(motion)
+		if err != nil {
+			return err
+		}
+		target.Motion = normalized
+	}
+	if speed, ok := propertyFloat(props, "speed"); ok && speed > 0 {
+		target.Speed = speed
+	}
+	if zone, ok := propertyFloat(props, "zone"); ok && zone >= 0 {
+		target.Zone = zone
+	}
+	if acc, ok := propertyFloat(props, "acceleration"); ok && acc > 0 {
+		target.Acceleration = acc
+	}
+	if jointSpeed, ok := propertyFloat(props, "joint_speed"); ok && jointSpeed > 0 {
+		target.JointSpeed = math.Min(jointSpeed, 100)
+	}
+	return nil
+}
+
+// normalizeMotion 规范化运动类型，兼容各品牌指令名
+func normalizeMotion(motion string) (string, error) {
+	switch strings.ToLower(strings.TrimSpace(motion)) {
+	case "joint", "movej", "ptp":
+		return MotionJoint, nil
+	case "linear", "movel", "lin":
+		return MotionLinear, nil
+	default:
+		return "", fmt.Errorf("不支持的运动类型: %s", motion)
+	}
+}
+
+var programIdentifierPattern = regexp.MustCompile(`[^A-Za-z0-9_]`)
+
+// sanitizeProgramIdentifier 将名称转换为机器人语言可用的标识符
+func sanitizeProgramIdentifier(name, fallback string) string {
+	name = programIdentifierPattern.ReplaceAllString(name, "_")
+	name = strings.Trim(name, "_")
+	if name == "" {
+		return fallback
+	}
+	if name[0] >= '0' && name[0] <= '9' {
+		name = "P_" + name
+	}
+	return name
+}
@@ -0,0 +1,178 @@
+// Package services 路线解析
+//
+// 路线是按通行顺序排列的一组路径（或节点），供插值、代码生成、
+// 订单生成等需要"按顺序走一遍"的功能共用。
+package services
+
+import (
+	"context"
+	"fmt"
+
+	"robot-path-editor/internal/domain"
+	"robot-path-editor/internal/repositories"
+)
+
+// RouteRequest 路线请求
+// 以有序的路径ID或有序的节点ID描述一条路线，二者提供其一即可
+type RouteRequest struct {
+	PathIDs []domain.PathID `json:"path_ids,omitempty"`
+	NodeIDs []domain.NodeID `json:"node_ids,omitempty"`
+}
+
+// RouteLeg 路线中的一段
+type RouteLeg struct {
+	Path     *domain.Path `json:"path,omitempty"` // 两节点间没有路径时为空，表示直接运动
+	From     *domain.Node `json:"from"`
+	To       *domain.Node `json:"to"`
+	Reversed bool         `json:"reversed"` // 是否逆着路径定义方向（终点→起点）通行
+}
+
+// Route 解析后的路线
+type Route struct {
+	Nodes []*domain.Node `json:"nodes"`
+	Legs  []RouteLeg     `json:"legs"`
+}
+
+// routeResolver 路线解析器
+type routeResolver struct {
+	nodeRepo repositories.NodeRepository
+	pathRepo repositories.PathRepository
+}
+
+// newRouteResolver 创建路线解析器
+func newRouteResolver(nodeRepo repositories.NodeRepository, pathRepo repositories.PathRepository) *routeResolver {
+	return &routeResolver{
+		nodeRepo: nodeRepo,
+		pathRepo: pathRepo,
+	}
+}
+
+// Resolve 解析路线请求
+func (r *routeResolver) Resolve(ctx context.Context, req RouteRequest) (*Route, error) {
+	switch {
+	case len(req.PathIDs) > 0:
+		return r.resolveByPaths(ctx, req.PathIDs)
+	case len(req.NodeIDs) > 0:
+		return r.resolveByNodes(ctx, req.NodeIDs)
+	default:
+		return nil, fmt.Errorf("路线至少需要提供path_ids或node_ids")
+	}
+}
+
+// resolveByPaths 按有序路径解析路线，相邻路径必须首尾相连
+func (r *routeResolver) resolveByPaths(ctx context.Context, pathIDs []domain.PathID) (*Route, error) {
+	paths := make([]*domain.Path, 0, len(pathIDs))
+	nodeIDs := make([]domain.NodeID, 0, len(pathIDs)*2)
+	for _, id := range pathIDs {
+		path, err := r.pathRepo.GetByID(ctx, id)
+		if err != nil {
+			return nil, fmt.Errorf("路径不存在: %w", err)
+		}
+		paths = append(paths, path)
+		nodeIDs = append(nodeIDs, path.StartNodeID, path.EndNodeID)
+	}
+
+	nodes, err := r.loadNodes(ctx, nodeIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	// 确定第一段的出发节点：多段时取不与下一段相连的那一端
+	current := paths[0].StartNodeID
+	if len(paths) > 1 {
+		next := paths[1]
+		if paths[0].StartNodeID == next.StartNodeID || paths[0].StartNodeID == next.EndNodeID {
+			current = paths[0].EndNodeID
+		}
+	} else if !paths[0].CanTraverse(current) {
+		current = paths[0].EndNodeID
+	}
+
+	route := &Route{Nodes: []*domain.Node{nodes[current]}}
+	for i, path := range paths {
+		var to domain.NodeID
+		switch current {
+		case path.StartNodeID:
+			to = path.EndNodeID
+		case path.EndNodeID:
+			to = path.StartNodeID
+		default:
+			return nil, fmt.Errorf("第%d段路径 %s 与上一段不相连", i+1, path.ID)
+		}
+		if !path.CanTraverse(current) {
+			return nil, fmt.Errorf("第%d段路径 %s 不允许从节点 %s 出发通行", i+1, path.ID, current)
+		}
+
+		route.Legs = append(route.Legs, RouteLeg{
+			Path:     path,
+			From:     nodes[current],
+			To:       nodes[to],
+			Reversed: current != path.StartNodeID,
+		})
+		route.Nodes = append(route.Nodes, nodes[to])
+		current = to
+	}
+
+	return route, nil
+}
+
+// resolveByNodes 按有序节点解析路线，相邻节点间优先选取可通行且权重最小的路径
+func (r *routeResolver) resolveByNodes(ctx context.Context, nodeIDs []domain.NodeID) (*Route, error) {
+	nodes, err := r.loadNodes(ctx, nodeIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	route := &Route{}
+	for i, id := range nodeIDs {
+		route.Nodes = append(route.Nodes, nodes[id])
+		if i == 0 {
+			continue
+		}
+
+		from, to := nodeIDs[i-1], id
+		leg := RouteLeg{From: nodes[from], To: nodes[to]}
+
+		if r.pathRepo != nil {
+			candidates, err := r.pathRepo.GetByNodes(ctx, from, to)
+			if err != nil {
+				return nil, fmt.Errorf("查询节点间路径失败: %w", err)
+			}
+			for _, path := range candidates {
+				if !path.CanTraverse(from) {
+					continue
+				}
+				if leg.Path == nil || path.Weight < leg.Path.Weight {
+					leg.Path = path
+				}
+			}
+			if leg.Path != nil {
+				leg.Reversed = leg.Path.StartNodeID != from
+			}
+		}
+
+		route.Legs = append(route.Legs, leg)
+	}
+
+	return route, nil
+}
+
+// loadNodes 批量加载节点并校验全部存在
+func (r *routeResolver) loadNodes(ctx context.Context, ids []domain.NodeID) (map[domain.NodeID]*domain.Node, error) {
+	nodes, err := r.nodeRepo.GetByIDs(ctx, ids)
+	if err != nil {
+		return nil, fmt.Errorf("获取节点失败: %w", err)
+	}
+
+	byID := make(map[domain.NodeID]*domain.Node, len(nodes))
+	for _, node := range nodes {
+		byID[node.ID] = node
+	}
+	for _, id := range ids {
+		if _, ok := byID[id]; !ok {
+			return nil, fmt.Errorf("节点不存在: %s", id)
+		}
+	}
+
+	return byID, nil
+}
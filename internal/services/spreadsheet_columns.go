@@ -0,0 +1,319 @@
+// Package services 节点/路径表格的列定义
+//
+// - 嵌套结构展开为点号分隔的列：position.x、robot_coords.yaw、style.color
+// - labels 写为 key=value;key=value，选定的扩展属性写为 properties.<key> 列
+// - 列键即默认表头，导入时同时识别列键和中文表头
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"robot-path-editor/internal/domain"
+)
+
+// tableColumn 表格列
+type tableColumn[T any] struct {
+	key      string
+	label    string
+	numeric  bool // XLSX 中写为数字
+	readOnly bool // 仅导出，导入时忽略
+	get      func(T) string
+	set      func(T, string) error
+}
+
+// 扩展属性列的前缀
+const (
+	tablePropertyPrefix      = "properties."
+	tablePropertyLabelPrefix = "属性:"
+)
+
+// nodeTableColumns 节点表的固定列
+func nodeTableColumns() []tableColumn[*domain.Node] {
+	return []tableColumn[*domain.Node]{
+		{key: "id", label: "ID",
+			get: func(n *domain.Node) string { return string(n.ID) },
+			set: func(n *domain.Node, v string) error { n.ID = domain.NodeID(v); return nil }},
+		{key: "name", label: "名称",
+			get: func(n *domain.Node) string { return n.Name },
+			set: func(n *domain.Node, v string) error { n.Name = v; return nil }},
+		{key: "type", label: "类型",
+			get: func(n *domain.Node) string { return string(n.Type) },
+			set: func(n *domain.Node, v string) error {
+				n.Type = domain.NodeType(v)
+				return checkTableEnum(v, domain.NodeTypePoint, domain.NodeTypeWaypoint, domain.NodeTypeStation, domain.NodeTypeCharging)
+			}},
+		{key: "status", label: "状态",
+			get: func(n *domain.Node) string { return string(n.Status) },
+			set: func(n *domain.Node, v string) error {
+				n.Status = domain.NodeStatus(v)
+				return checkTableEnum(v, domain.NodeStatusActive, domain.NodeStatusInactive, domain.NodeStatusDeleted, domain.NodeStatusError)
+			}},
+		floatColumn("position.x", "X坐标", func(n *domain.Node) *float64 { return &n.Position.X }),
+		floatColumn("position.y", "Y坐标", func(n *domain.Node) *float64 { return &n.Position.Y }),
+		floatColumn("position.z", "Z坐标", func(n *domain.Node) *float64 { return &n.Position.Z }),
+		robotColumn("x", "机器人X", func(c *domain.RobotCoordinates) *float64 { return &c.X }),
+		robotColumn("y", "机器人Y", func(c *domain.RobotCoordinates) *float64 { return &c.Y }),
+		robotColumn("z", "机器人Z", func(c *domain.RobotCoordinates) *float64 { return &c.Z }),
+		robotColumn("roll", "翻滚角", func(c *domain.RobotCoordinates) *float64 { return &c.Roll }),
+		robotColumn("pitch", "俯仰角", func(c *domain.RobotCoordinates) *float64 { return &c.Pitch }),
+		robotColumn("yaw", "偏航角", func(c *domain.RobotCoordinates) *float64 { return &c.Yaw }),
+		stringColumn("style.color", "颜色", func(n *domain.Node) *string { return &n.Style.Color }),
+		floatColumn("style.size", "大小", func(n *domain.Node) *float64 { return &n.Style.Size }),
+		stringColumn("style.shape", "形状", func(n *domain.Node) *string { return &n.Style.Shape }),
+		stringColumn("style.border_color", "边框颜色", func(n *domain.Node) *string { return &n.Style.BorderColor }),
+		floatColumn("style.border_width", "边框宽度", func(n *domain.Node) *float64 { return &n.Style.BorderWidth }),
+		floatColumn("style.opacity", "透明度", func(n *domain.Node) *float64 { return &n.Style.Opacity }),
+		labelsColumn(func(n *domain.Node) *map[string]string { return &n.Metadata.Labels }),
+		timeColumn("created_at", "创建时间", func(n *domain.Node) time.Time { return n.Metadata.CreatedAt }),
+		timeColumn("updated_at", "更新时间", func(n *domain.Node) time.Time { return n.Metadata.UpdatedAt }),
+		versionColumn(func(n *domain.Node) int { return n.Metadata.Version }),
+	}
+}
+
+// pathTableColumns 路径表的固定列，起止节点名称只用于导出阅读和导入时按名称关联节点
+func pathTableColumns(nodeNames map[domain.NodeID]string) []tableColumn[*domain.Path] {
+	return []tableColumn[*domain.Path]{
+		{key: "id", label: "ID",
+			get: func(p *domain.Path) string { return string(p.ID) },
+			set: func(p *domain.Path, v string) error { p.ID = domain.PathID(v); return nil }},
+		{key: "name", label: "名称",
+			get: func(p *domain.Path) string { return p.Name },
+			set: func(p *domain.Path, v string) error { p.Name = v; return nil }},
+		{key: "type", label: "类型",
+			get: func(p *domain.Path) string { return string(p.Type) },
+			set: func(p *domain.Path, v string) error {
+				p.Type = domain.PathType(v)
+				return checkTableEnum(v, domain.PathTypeNormal, domain.PathTypeCurved, domain.PathTypeRestricted)
+			}},
+		{key: "status", label: "状态",
+			get: func(p *domain.Path) string { return string(p.Status) },
+			set: func(p *domain.Path, v string) error {
+				p.Status = domain.PathStatus(v)
+				return checkTableEnum(v, domain.PathStatusActive, domain.PathStatusInactive, domain.PathStatusBlocked, domain.PathStatusDeleted)
+			}},
+		{key: "start_node_id", label: "起点ID",
+			get: func(p *domain.Path) string { return string(p.StartNodeID) },
+			set: func(p *domain.Path, v string) error { p.StartNodeID = domain.NodeID(v); return nil }},
+		{key: "start_node_name", label: "起点名称", readOnly: true,
+			get: func(p *domain.Path) string { return nodeNames[p.StartNodeID] }},
+		{key: "end_node_id", label: "终点ID",
+			get: func(p *domain.Path) string { return string(p.EndNodeID) },
+			set: func(p *domain.Path, v string) error { p.EndNodeID = domain.NodeID(v); return nil }},
+		{key: "end_node_name", label: "终点名称", readOnly: true,
+			get: func(p *domain.Path) string { return nodeNames[p.EndNodeID] }},
+		floatColumn("weight", "权重", func(p *domain.Path) *float64 { return &p.Weight }),
+		floatColumn("length", "长度", func(p *domain.Path) *float64 { return &p.Length }),
+		{key: "direction", label: "方向",
+			get: func(p *domain.Path) string { return p.Direction },
+			set: func(p *domain.Path, v string) error {
+				p.Direction = v
+				return checkTableEnum(v, domain.Directions...)
+			}},
+		{key: "curve_type", label: "曲线类型",
+			get: func(p *domain.Path) string { return string(p.CurveType) },
+			set: func(p *domain.Path, v string) error {
+				p.CurveType = domain.CurveType(v)
+				return checkTableEnum(v, domain.CurveTypeLinear, domain.CurveTypeBezier, domain.CurveTypeSpline, domain.CurveTypeArc)
+			}},
+		{key: "waypoints", label: "途经点",
+			get: func(p *domain.Path) string { return formatTableWaypoints(p.Waypoints) },
+			set: func(p *domain.Path, v string) error {
+				waypoints, err := parseTableWaypoints(v)
+				p.Waypoints = waypoints
+				return err
+			}},
+		stringColumn("style.color", "颜色", func(p *domain.Path) *string { return &p.Style.Color }),
+		floatColumn("style.width", "宽度", func(p *domain.Path) *float64 { return &p.Style.Width }),
+		stringColumn("style.style", "线型", func(p *domain.Path) *string { return &p.Style.Style }),
+		floatColumn("style.opacity", "透明度", func(p *domain.Path) *float64 { return &p.Style.Opacity }),
+		labelsColumn(func(p *domain.Path) *map[string]string { return &p.Metadata.Labels }),
+		timeColumn("created_at", "创建时间", func(p *domain.Path) time.Time { return p.Metadata.CreatedAt }),
+		timeColumn("updated_at", "更新时间", func(p *domain.Path) time.Time { return p.Metadata.UpdatedAt }),
+		versionColumn(func(p *domain.Path) int { return p.Metadata.Version }),
+	}
+}
+
+// propertyColumn 扩展属性列：字符串原样写出，其他类型写为JSON；导入时能按JSON解析的值按JSON解析
+func propertyColumn[T any](key string, props func(T) *map[string]interface{}) tableColumn[T] {
+	return tableColumn[T]{
+		key:   tablePropertyPrefix + key,
+		label: tablePropertyLabelPrefix + key,
+		get: func(row T) string {
+			value, ok := (*props(row))[key]
+			if !ok || value == nil {
+				return ""
+			}
+			if s, ok := value.(string); ok {
+				return s
+			}
+			data, _ := json.Marshal(value)
+			return string(data)
+		},
+		set: func(row T, v string) error {
+			m := props(row)
+			if *m == nil {
+				*m = make(map[string]interface{})
+			}
+			var value interface{}
+			if err := json.Unmarshal([]byte(v), &value); err != nil {
+				value = v
+			}
+			(*m)[key] = value
+			return nil
+		},
+	}
+}
+
+func stringColumn[T any](key, label string, field func(T) *string) tableColumn[T] {
+	return tableColumn[T]{
+		key: key, label: label,
+		get: func(row T) string { return *field(row) },
+		set: func(row T, v string) error { *field(row) = v; return nil },
+	}
+}
+
+func floatColumn[T any](key, label string, field func(T) *float64) tableColumn[T] {
+	return tableColumn[T]{
+		key: key, label: label, numeric: true,
+		get: func(row T) string { return formatTableFloat(*field(row)) },
+		set: func(row T, v string) error {
+			f, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				return fmt.Errorf("不是有效的数字: %s", v)
+			}
+			*field(row) = f
+			return nil
+		},
+	}
+}
+
+// robotColumn 机器人坐标列，RobotCoords 为空时导出空单元格，导入任一列时创建
+func robotColumn(key, label string, field func(*domain.RobotCoordinates) *float64) tableColumn[*domain.Node] {
+	return tableColumn[*domain.Node]{
+		key: "robot_coords." + key, label: label, numeric: true,
+		get: func(n *domain.Node) string {
+			if n.RobotCoords == nil {
+				return ""
+			}
+			return formatTableFloat(*field(n.RobotCoords))
+		},
+		set: func(n *domain.Node, v string) error {
+			f, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				return fmt.Errorf("不是有效的数字: %s", v)
+			}
+			if n.RobotCoords == nil {
+				n.RobotCoords = &domain.RobotCoordinates{}
+			}
+			*field(n.RobotCoords) = f
+			return nil
+		},
+	}
+}
+
+func labelsColumn[T any](field func(T) *map[string]string) tableColumn[T] {
+	return tableColumn[T]{
+		key: "labels", label: "标签",
+		get: func(row T) string {
+			labels := *field(row)
+			keys := make([]string, 0, len(labels))
+			for k := range labels {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+			pairs := make([]string, len(keys))
+			for i, k := range keys {
+				pairs[i] = k + "=" + labels[k]
+			}
+			return strings.Join(pairs, ";")
+		},
+		set: func(row T, v string) error {
+			labels := make(map[string]string)
+			for _, pair := range strings.Split(v, ";") {
+				if pair = strings.TrimSpace(pair); pair == "" {
+					continue
+				}
+				k, value, ok := strings.Cut(pair, "=")
+				if !ok || strings.TrimSpace(k) == "" {
+					return fmt.Errorf("标签格式应为 key=value;key=value: %s", pair)
+				}
+				labels[strings.TrimSpace(k)] = strings.TrimSpace(value)
+			}
+			*field(row) = labels
+			return nil
+		},
+	}
+}
+
+func timeColumn[T any](key, label string, field func(T) time.Time) tableColumn[T] {
+	return tableColumn[T]{
+		key: key, label: label, readOnly: true,
+		get: func(row T) string {
+			if t := field(row); !t.IsZero() {
+				return t.Format(time.RFC3339)
+			}
+			return ""
+		},
+	}
+}
+
+func versionColumn[T any](field func(T) int) tableColumn[T] {
+	return tableColumn[T]{
+		key: "version", label: "版本", numeric: true, readOnly: true,
+		get: func(row T) string { return strconv.Itoa(field(row)) },
+	}
+}
+
+// checkTableEnum 检查枚举取值
+func checkTableEnum[E ~string](value string, allowed ...E) error {
+	names := make([]string, len(allowed))
+	for i, a := range allowed {
+		if string(a) == value {
+			return nil
+		}
+		names[i] = string(a)
+	}
+	return fmt.Errorf("取值 %q 无效，可选: %s", value, strings.Join(names, ", "))
+}
+
+func formatTableFloat(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+// formatTableWaypoints 途经点写为 x,y,z;x,y,z
+func formatTableWaypoints(waypoints []domain.Position) string {
+	parts := make([]string, len(waypoints))
+	for i, p := range waypoints {
+		parts[i] = formatTableFloat(p.X) + "," + formatTableFloat(p.Y) + "," + formatTableFloat(p.Z)
+	}
+	return strings.Join(parts, ";")
+}
+
+// parseTableWaypoints 解析 x,y[,z];x,y[,z]
+func parseTableWaypoints(v string) ([]domain.Position, error) {
+	var waypoints []domain.Position
+	for _, part := range strings.Split(v, ";") {
+		if part = strings.TrimSpace(part); part == "" {
+			continue
+		}
+		coords := strings.Split(part, ",")
+		if len(coords) < 2 || len(coords) > 3 {
+			return nil, fmt.Errorf("途经点格式应为 x,y,z;x,y,z: %s", part)
+		}
+		var values [3]float64
+		for i, c := range coords {
+			f, err := strconv.ParseFloat(strings.TrimSpace(c), 64)
+			if err != nil {
+				return nil, fmt.Errorf("途经点坐标无效: %s", part)
+			}
+			values[i] = f
+		}
+		waypoints = append(waypoints, domain.Position{X: values[0], Y: values[1], Z: values[2]})
+	}
+	return waypoints, nil
+}
@@ -0,0 +1,44 @@
+package services
+
+import (
+	"testing"
+
+	"robot-path-editor/internal/domain"
+)
+
+func TestPathTableDirectionColumn(t *testing.T) {
+	var column *tableColumn[*domain.Path]
+	for _, c := range pathTableColumns(nil) {
+		if c.key == "direction" {
+			column = &c
+			break
+		}
+	}
+	if column == nil {
+		t.Fatal("pathTableColumns() has no direction column")
+	}
+
+	tests := []struct {
+		value   string
+		wantErr bool
+	}{
+		{value: domain.DirectionBidirectional},
+		{value: domain.DirectionForward},
+		{value: domain.DirectionOneWay},
+		{value: domain.DirectionUnidirectional},
+		{value: domain.DirectionBackward},
+		{value: domain.DirectionReverse},
+		{value: "sideways", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		path := &domain.Path{}
+		err := column.set(path, tt.value)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("set(%q) error = %v, wantErr %v", tt.value, err, tt.wantErr)
+		}
+		if path.Direction != tt.value {
+			t.Errorf("set(%q) Direction = %q", tt.value, path.Direction)
+		}
+	}
+}
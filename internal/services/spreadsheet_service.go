@@ -0,0 +1,891 @@
+// Package services 表格导入导出服务
+//
+// 设计参考：
+// - 数据库客户端（GoLand Database、DBeaver）的表格导入导出
+// - Excel 对 UTF-8 CSV 的识别方式（带 BOM）
+//
+// 特点：
+// 1. 节点表、路径表或两者一起导出为 CSV 或多工作表 XLSX，数据读取完成后流式写出
+// 2. 导入支持列映射、逐行校验，错误定位到工作表、行号和列
+// 3. upsert 模式按ID更新或创建，空单元格保留原值；replace 模式整表替换
+package services
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/xuri/excelize/v2"
+
+	"robot-path-editor/internal/domain"
+	"robot-path-editor/internal/repositories"
+)
+
+// SpreadsheetService 表格导入导出服务接口
+type SpreadsheetService interface {
+	ExportTables(ctx context.Context, opts TableExportOptions) (*TableExport, error)
+	ImportTables(ctx context.Context, data []byte, opts TableImportOptions) (*TableImportResult, error)
+}
+
+// 表格格式和范围
+const (
+	TableFormatCSV  = "csv"
+	TableFormatXLSX = "xlsx"
+
+	TableNodes = "nodes"
+	TablePaths = "paths"
+	TableAll   = "all"
+
+	TableImportUpsert  = "upsert"
+	TableImportReplace = "replace"
+
+	tableFileLimit = 16 << 20 // 压缩包中单个文件解压后的大小上限
+)
+
+// TableExportOptions 导出选项
+type TableExportOptions struct {
+	Format     string   `json:"format,omitempty" form:"format"`         // csv 或 xlsx（默认）
+	Table      string   `json:"table,omitempty" form:"table"`           // nodes、paths 或 all（默认）
+	Properties []string `json:"properties,omitempty" form:"properties"` // 作为列导出的扩展属性键，* 表示全部
+	Headers    string   `json:"headers,omitempty" form:"headers"`       // key（默认，列键作表头）或 label（中文表头）
+}
+
+// TableExport 待写出的导出文件
+type TableExport struct {
+	Filename    string
+	ContentType string
+	write       func(w io.Writer) error
+}
+
+// Stream 将文件内容写出
+func (e *TableExport) Stream(w io.Writer) error {
+	return e.write(w)
+}
+
+// TableImportOptions 导入选项
+type TableImportOptions struct {
+	Format      string            `json:"format,omitempty"`       // csv 或 xlsx，默认按文件内容识别
+	Table       string            `json:"table,omitempty"`        // 单表CSV的目标表，默认按表头推断
+	Mode        string            `json:"mode,omitempty"`         // upsert（默认）或 replace
+	NodeColumns map[string]string `json:"node_columns,omitempty"` // 表头 → 列键，列键为空表示忽略该列
+	PathColumns map[string]string `json:"path_columns,omitempty"`
+	Strict      bool              `json:"strict,omitempty"`  // 任一行有错误时不写入，replace 模式总是如此
+	DryRun      bool              `json:"dry_run,omitempty"` // 只校验不写入
+}
+
+// TableImportResult 导入结果
+type TableImportResult struct {
+	Mode      string           `json:"mode"`
+	Committed bool             `json:"committed"`
+	Nodes     TableImportStats `json:"nodes"`
+	Paths     TableImportStats `json:"paths"`
+	Errors    []TableRowError  `json:"errors,omitempty"`
+	Warnings  []string         `json:"warnings,omitempty"`
+}
+
+// TableImportStats 单表导入统计
+type TableImportStats struct {
+	Rows    int `json:"rows"`
+	Created int `json:"created"`
+	Updated int `json:"updated"`
+	Deleted int `json:"deleted"`
+	Failed  int `json:"failed"`
+}
+
+// TableRowError 行校验错误，Row 为表格中的行号（表头为第1行）
+type TableRowError struct {
+	Sheet   string `json:"sheet,omitempty"`
+	Row     int    `json:"row"`
+	Column  string `json:"column,omitempty"`
+	Message string `json:"message"`
+}
+
+// spreadsheetService 表格导入导出服务实现
+type spreadsheetService struct {
+	nodeRepo repositories.NodeRepository
+	pathRepo repositories.PathRepository
+}
+
+// NewSpreadsheetService 创建新的表格导入导出服务实例
+func NewSpreadsheetService(nodeRepo repositories.NodeRepository, pathRepo repositories.PathRepository) SpreadsheetService {
+	return &spreadsheetService{
+		nodeRepo: nodeRepo,
+		pathRepo: pathRepo,
+	}
+}
+
+// === 导出 ===
+
+// tableSheet 待写出的工作表
+type tableSheet struct {
+	name    string
+	headers []string
+	numeric []bool
+	rows    [][]string
+}
+
+// ExportTables 读取节点和路径并准备导出文件
+func (s *spreadsheetService) ExportTables(ctx context.Context, opts TableExportOptions) (*TableExport, error) {
+	format := strings.ToLower(opts.Format)
+	if format == "" {
+		format = TableFormatXLSX
+	}
+	if format != TableFormatCSV && format != TableFormatXLSX {
+		return nil, fmt.Errorf("不支持的导出格式: %s", opts.Format)
+	}
+	table := strings.ToLower(opts.Table)
+	if table == "" {
+		table = TableAll
+	}
+	if table != TableNodes && table != TablePaths && table != TableAll {
+		return nil, fmt.Errorf("不支持的导出表: %s", opts.Table)
+	}
+	useLabels := opts.Headers == "label"
+
+	nodes, err := s.nodeRepo.List(ctx, repositories.NodeFilter{})
+	if err != nil {
+		return nil, fmt.Errorf("获取节点列表失败: %w", err)
+	}
+	sort.SliceStable(nodes, func(i, j int) bool { return nodes[i].Name < nodes[j].Name })
+
+	var sheets []tableSheet
+	if table != TablePaths {
+		columns := nodeTableColumns()
+		keys := selectPropertyKeys(opts.Properties, nodes, func(n *domain.Node) map[string]interface{} { return n.Properties })
+		for _, key := range keys {
+			columns = append(columns, propertyColumn(key, func(n *domain.Node) *map[string]interface{} { return &n.Properties }))
+		}
+		sheets = append(sheets, buildTableSheet(tableSheetName(TableNodes, useLabels), columns, nodes, useLabels))
+	}
+	if table != TableNodes {
+		paths, err := s.pathRepo.List(ctx, repositories.PathFilter{})
+		if err != nil {
+			return nil, fmt.Errorf("获取路径列表失败: %w", err)
+		}
+		sort.SliceStable(paths, func(i, j int) bool { return paths[i].Name < paths[j].Name })
+
+		nodeNames := make(map[domain.NodeID]string, len(nodes))
+		for _, node := range nodes {
+			nodeNames[node.ID] = node.Name
+		}
+		columns := pathTableColumns(nodeNames)
+		keys := selectPropertyKeys(opts.Properties, paths, func(p *domain.Path) map[string]interface{} { return p.Properties })
+		for _, key := range keys {
+			columns = append(columns, propertyColumn(key, func(p *domain.Path) *map[string]interface{} { return &p.Properties }))
+		}
+		sheets = append(sheets, buildTableSheet(tableSheetName(TablePaths, useLabels), columns, paths, useLabels))
+	}
+
+	export := &TableExport{}
+	switch {
+	case format == TableFormatXLSX:
+		export.Filename = "layout.xlsx"
+		export.ContentType = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+		export.write = func(w io.Writer) error { return writeXLSX(w, sheets) }
+	case len(sheets) == 1:
+		export.Filename = table + ".csv"
+		export.ContentType = "text/csv; charset=utf-8"
+		export.write = func(w io.Writer) error { return writeCSV(w, sheets[0]) }
+	default:
+		// 多张表的CSV打包为zip
+		export.Filename = "layout.zip"
+		export.ContentType = "application/zip"
+		export.write = func(w io.Writer) error { return writeCSVZip(w, sheets) }
+	}
+	return export, nil
+}
+
+// selectPropertyKeys 确定导出的扩展属性列，* 表示所有行出现过的键
+func selectPropertyKeys[T any](selected []string, rows []T, props func(T) map[string]interface{}) []string {
+	var keys []string
+	seen := make(map[string]bool)
+	for _, key := range selected {
+		for _, k := range strings.Split(key, ",") {
+			if k = strings.TrimSpace(k); k == "*" {
+				keys = keys[:0]
+				for _, row := range rows {
+					for name := range props(row) {
+						seen[name] = true
+					}
+				}
+				for name := range seen {
+					keys = append(keys, name)
+				}
+				sort.Strings(keys)
+				return keys
+			} else if k != "" && !seen[k] {
+				seen[k] = true
+				keys = append(keys, k)
+			}
+		}
+	}
+	return keys
+}
+
+func tableSheetName(table string, useLabels bool) string {
+	if !useLabels {
+		return table
+	}
+	if table == TablePaths {
+		return "路径"
+	}
+	return "节点"
+}
+
+func buildTableSheet[T any](name string, columns []tableColumn[T], rows []T, useLabels bool) tableSheet {
+	sheet := tableSheet{name: name}
+	for _, column := range columns {
+		header := column.key
+		if useLabels {
+			header = column.label
+		}
+		sheet.headers = append(sheet.headers, header)
+		sheet.numeric = append(sheet.numeric, column.numeric)
+	}
+	for _, row := range rows {
+		values := make([]string, len(columns))
+		for i, column := range columns {
+			values[i] = column.get(row)
+		}
+		sheet.rows = append(sheet.rows, values)
+	}
+	return sheet
+}
+
+// writeCSV 写出带 BOM 的 UTF-8 CSV，便于 Excel 正确识别中文
+func writeCSV(w io.Writer, sheet tableSheet) error {
+	if _, err := w.Write([]byte("\ufeff")); err != nil {
+		return err
+	}
+	writer := csv.NewWriter(w)
+	if err := writer.Write(sheet.headers); err != nil {
+		return err
+	}
+	for _, row := range sheet.rows {
+		if err := writer.Write(row); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+func writeCSVZip(w io.Writer, sheets []tableSheet) error {
+	archive := zip.NewWriter(w)
+	for _, sheet := range sheets {
+		entry, err := archive.CreateHeader(&zip.FileHeader{Name: sheet.name + ".csv", Method: zip.Deflate, Modified: time.Now()})
+		if err != nil {
+			return err
+		}
+		if err := writeCSV(entry, sheet); err != nil {
+			return err
+		}
+	}
+	return archive.Close()
+}
+
+// writeXLSX 每张表一个工作表，表头加粗并冻结首行
+func writeXLSX(w io.Writer, sheets []tableSheet) error {
+	f := excelize.NewFile()
+	defer f.Close()
+
+	headerStyle, err := f.NewStyle(&excelize.Style{
+		Font: &excelize.Font{Bold: true},
+		Fill: excelize.Fill{Type: "pattern", Pattern: 1, Color: []string{"#E9ECEF"}},
+	})
+	if err != nil {
+		return err
+	}
+
+	for i, sheet := range sheets {
+		if i == 0 {
+			if err := f.SetSheetName("Sheet1", sheet.name); err != nil {
+				return err
+			}
+		} else if _, err := f.NewSheet(sheet.name); err != nil {
+			return err
+		}
+
+		stream, err := f.NewStreamWriter(sheet.name)
+		if err != nil {
+			return err
+		}
+		if err := stream.SetPanes(&excelize.Panes{Freeze: true, YSplit: 1, TopLeftCell: "A2", ActivePane: "bottomLeft"}); err != nil {
+			return err
+		}
+
+		header := make([]interface{}, len(sheet.headers))
+		for j, h := range sheet.headers {
+			header[j] = excelize.Cell{StyleID: headerStyle, Value: h}
+		}
+		if err := stream.SetRow("A1", header); err != nil {
+			return err
+		}
+
+		for r, row := range sheet.rows {
+			values := make([]interface{}, len(row))
+			for j, v := range row {
+				values[j] = v
+				if sheet.numeric[j] && v != "" {
+					var f float64
+					if _, err := fmt.Sscan(v, &f); err == nil {
+						values[j] = f
+					}
+				}
+			}
+			cell, err := excelize.CoordinatesToCellName(1, r+2)
+			if err != nil {
+				return err
+			}
+			if err := stream.SetRow(cell, values); err != nil {
+				return err
+			}
+		}
+		if err := stream.Flush(); err != nil {
+			return err
+		}
+	}
+
+	_, err = f.WriteTo(w)
+	return err
+}
+
+// === 导入 ===
+
+// importSheet 读取到的工作表
+type importSheet struct {
+	name string
+	rows [][]string
+}
+
+// tableImport 单次导入的状态
+type tableImport struct {
+	opts   TableImportOptions
+	result *TableImportResult
+}
+
+func (imp *tableImport) fail(sheet string, row int, column, message string) {
+	imp.result.Errors = append(imp.result.Errors, TableRowError{Sheet: sheet, Row: row, Column: column, Message: message})
+}
+
+// ImportTables 导入节点表和/或路径表
+func (s *spreadsheetService) ImportTables(ctx context.Context, data []byte, opts TableImportOptions) (*TableImportResult, error) {
+	mode := strings.ToLower(opts.Mode)
+	if mode == "" {
+		mode = TableImportUpsert
+	}
+	if mode != TableImportUpsert && mode != TableImportReplace {
+		return nil, fmt.Errorf("不支持的导入模式: %s", opts.Mode)
+	}
+
+	sheets, err := readImportSheets(data, strings.ToLower(opts.Format))
+	if err != nil {
+		return nil, err
+	}
+
+	imp := &tableImport{opts: opts, result: &TableImportResult{Mode: mode}}
+	var nodeSheet, pathSheet *importSheet
+	for i := range sheets {
+		sheet := &sheets[i]
+		if len(sheet.rows) == 0 {
+			continue
+		}
+		switch imp.sheetTable(sheet) {
+		case TableNodes:
+			if nodeSheet != nil {
+				imp.result.Warnings = append(imp.result.Warnings, fmt.Sprintf("工作表 %s 与 %s 都是节点表，已忽略前者", nodeSheet.name, sheet.name))
+			}
+			nodeSheet = sheet
+		case TablePaths:
+			if pathSheet != nil {
+				imp.result.Warnings = append(imp.result.Warnings, fmt.Sprintf("工作表 %s 与 %s 都是路径表，已忽略前者", pathSheet.name, sheet.name))
+			}
+			pathSheet = sheet
+		default:
+			imp.result.Warnings = append(imp.result.Warnings, fmt.Sprintf("无法识别工作表 %s，已跳过", sheet.name))
+		}
+	}
+	if nodeSheet == nil && pathSheet == nil {
+		return nil, fmt.Errorf("文件中没有可导入的节点表或路径表")
+	}
+
+	existingNodes, err := s.nodeRepo.List(ctx, repositories.NodeFilter{})
+	if err != nil {
+		return nil, fmt.Errorf("获取节点列表失败: %w", err)
+	}
+	existingPaths, err := s.pathRepo.List(ctx, repositories.PathFilter{})
+	if err != nil {
+		return nil, fmt.Errorf("获取路径列表失败: %w", err)
+	}
+	replace := mode == TableImportReplace
+
+	// 解析节点；replace 模式下节点表整体替换现有节点
+	nodeByID := make(map[domain.NodeID]*domain.Node)
+	for _, node := range existingNodes {
+		nodeByID[node.ID] = node
+	}
+	var nodeRows []*tableRow[*domain.Node]
+	if nodeSheet != nil {
+		lookup := nodeByID
+		if replace {
+			lookup = nil
+		}
+		nodeRows = parseNodeRows(imp, nodeSheet, lookup)
+		if replace {
+			nodeByID = make(map[domain.NodeID]*domain.Node)
+		}
+		for _, row := range nodeRows {
+			nodeByID[row.value.ID] = row.value
+		}
+	}
+
+	// 解析路径，端点必须是导入后仍存在的节点
+	var pathRows []*tableRow[*domain.Path]
+	if pathSheet != nil {
+		lookup := make(map[domain.PathID]*domain.Path, len(existingPaths))
+		if !replace {
+			for _, path := range existingPaths {
+				lookup[path.ID] = path
+			}
+		}
+		pathRows = parsePathRows(imp, pathSheet, lookup, nodeByID)
+	}
+
+	// replace 会删除现有数据，有错误行时一律不写入，避免丢失数据
+	if imp.opts.DryRun || ((imp.opts.Strict || replace) && len(imp.result.Errors) > 0) {
+		return imp.result, nil
+	}
+
+	// 写入：先删除，再创建/更新节点，最后创建/更新路径
+	if replace {
+		var removedPaths []domain.PathID
+		for _, path := range existingPaths {
+			_, startOK := nodeByID[path.StartNodeID]
+			_, endOK := nodeByID[path.EndNodeID]
+			if pathSheet != nil || !startOK || !endOK {
+				removedPaths = append(removedPaths, path.ID)
+			}
+		}
+		if err := s.pathRepo.DeleteBatch(ctx, removedPaths); err != nil {
+			return nil, fmt.Errorf("删除现有路径失败: %w", err)
+		}
+		imp.result.Paths.Deleted = len(removedPaths)
+
+		if nodeSheet != nil {
+			ids := make([]domain.NodeID, len(existingNodes))
+			for i, node := range existingNodes {
+				ids[i] = node.ID
+			}
+			if err := s.nodeRepo.DeleteBatch(ctx, ids); err != nil {
+				return nil, fmt.Errorf("删除现有节点失败: %w", err)
+			}
+			imp.result.Nodes.Deleted = len(ids)
+		}
+	}
+
+	for _, row := range nodeRows {
+		if row.created {
+			if err := s.nodeRepo.Create(ctx, row.value); err != nil {
+				return nil, fmt.Errorf("第%d行创建节点失败: %w", row.line, err)
+			}
+			imp.result.Nodes.Created++
+		} else {
+			row.value.UpdatedAt()
+			if err := s.nodeRepo.Update(ctx, row.value); err != nil {
+				return nil, fmt.Errorf("第%d行更新节点失败: %w", row.line, err)
+			}
+			imp.result.Nodes.Updated++
+		}
+	}
+	for _, row := range pathRows {
+		if row.created {
+			if err := s.pathRepo.Create(ctx, row.value); err != nil {
+				return nil, fmt.Errorf("第%d行创建路径失败: %w", row.line, err)
+			}
+			imp.result.Paths.Created++
+		} else {
+			row.value.UpdatedAt()
+			if err := s.pathRepo.Update(ctx, row.value); err != nil {
+				return nil, fmt.Errorf("第%d行更新路径失败: %w", row.line, err)
+			}
+			imp.result.Paths.Updated++
+		}
+	}
+
+	imp.result.Committed = true
+	return imp.result, nil
+}
+
+// sheetTable 按工作表名、导入选项或表头判断目标表
+func (imp *tableImport) sheetTable(sheet *importSheet) string {
+	switch strings.ToLower(strings.TrimSuffix(sheet.name, filepath.Ext(sheet.name))) {
+	case "nodes", "node", "节点":
+		return TableNodes
+	case "paths", "path", "路径":
+		return TablePaths
+	}
+	if imp.opts.Table == TableNodes || imp.opts.Table == TablePaths {
+		return imp.opts.Table
+	}
+	for _, header := range sheet.rows[0] {
+		header = strings.TrimSpace(header)
+		if key, ok := imp.opts.PathColumns[header]; ok {
+			header = key
+		}
+		switch strings.ToLower(header) {
+		case "start_node_id", "start_node_name", "起点id", "起点名称":
+			return TablePaths
+		}
+	}
+	return TableNodes
+}
+
+// tableRow 解析后的一行
+type tableRow[T any] struct {
+	value   T
+	line    int
+	created bool
+}
+
+// boundColumn 表头与列定义的对应
+type boundColumn[T any] struct {
+	index  int
+	header string
+	column tableColumn[T]
+}
+
+// bindColumns 按列映射、列键、中文表头依次匹配表头
+func bindColumns[T any](imp *tableImport, sheet *importSheet, columns []tableColumn[T], mapping map[string]string,
+	props func(T) *map[string]interface{}) []boundColumn[T] {
+	byName := make(map[string]tableColumn[T])
+	for _, column := range columns {
+		byName[strings.ToLower(column.key)] = column
+		byName[strings.ToLower(column.label)] = column
+	}
+
+	var bound []boundColumn[T]
+	for i, header := range sheet.rows[0] {
+		header = strings.TrimSpace(strings.TrimPrefix(header, "\ufeff"))
+		name := header
+		if key, ok := mapping[header]; ok {
+			if key == "" {
+				continue
+			}
+			name = key
+		}
+		if name == "" {
+			continue
+		}
+
+		lower := strings.ToLower(name)
+		if column, ok := byName[lower]; ok {
+			bound = append(bound, boundColumn[T]{index: i, header: header, column: column})
+			continue
+		}
+		if key, ok := strings.CutPrefix(name, tablePropertyPrefix); ok && key != "" {
+			bound = append(bound, boundColumn[T]{index: i, header: header, column: propertyColumn(key, props)})
+			continue
+		}
+		if key, ok := strings.CutPrefix(name, tablePropertyLabelPrefix); ok && key != "" {
+			bound = append(bound, boundColumn[T]{index: i, header: header, column: propertyColumn(key, props)})
+			continue
+		}
+		if sheet.name == "" {
+			imp.result.Warnings = append(imp.result.Warnings, fmt.Sprintf("列 %q 无法识别，已忽略", header))
+		} else {
+			imp.result.Warnings = append(imp.result.Warnings, fmt.Sprintf("工作表 %s 的列 %q 无法识别，已忽略", sheet.name, header))
+		}
+	}
+	return bound
+}
+
+// cell 取单元格的值，行尾缺失的单元格视为空
+func cell(row []string, index int) string {
+	if index < len(row) {
+		return strings.TrimSpace(row[index])
+	}
+	return ""
+}
+
+func isBlankRow(row []string) bool {
+	for _, v := range row {
+		if strings.TrimSpace(v) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// parseNodeRows 解析节点表，existing 为空表示全部新建
+func parseNodeRows(imp *tableImport, sheet *importSheet, existing map[domain.NodeID]*domain.Node) []*tableRow[*domain.Node] {
+	bound := bindColumns(imp, sheet, nodeTableColumns(), imp.opts.NodeColumns,
+		func(n *domain.Node) *map[string]interface{} { return &n.Properties })
+
+	var rows []*tableRow[*domain.Node]
+	seen := make(map[domain.NodeID]int)
+	for i, values := range sheet.rows[1:] {
+		line := i + 2
+		if isBlankRow(values) {
+			continue
+		}
+		imp.result.Nodes.Rows++
+		errors := len(imp.result.Errors)
+
+		var node *domain.Node
+		created := true
+		rawID, _ := boundValue(bound, values, "id")
+		id := domain.NodeID(rawID)
+		if current, ok := existing[id]; ok && id != "" {
+			copied := *current
+			copied.Properties = copyProperties(current.Properties)
+			node, created = &copied, false
+		} else {
+			node = domain.NewNode("", string(domain.NodeTypePoint))
+			if id != "" {
+				node.ID = id
+			}
+		}
+
+		for _, b := range bound {
+			if v := cell(values, b.index); v != "" && !b.column.readOnly && b.column.key != "id" {
+				if err := b.column.set(node, v); err != nil {
+					imp.fail(sheet.name, line, b.header, err.Error())
+				}
+			}
+		}
+		if len(imp.result.Errors) > errors {
+			// 单元格已有错误，不再重复报告整行校验错误
+		} else if err := node.IsValid(); err != nil {
+			imp.fail(sheet.name, line, "", err.Error())
+		}
+		if first, ok := seen[node.ID]; ok {
+			imp.fail(sheet.name, line, "id", fmt.Sprintf("ID与第%d行重复", first))
+		}
+
+		if len(imp.result.Errors) > errors {
+			imp.result.Nodes.Failed++
+			continue
+		}
+		seen[node.ID] = line
+		rows = append(rows, &tableRow[*domain.Node]{value: node, line: line, created: created})
+	}
+	return rows
+}
+
+// parsePathRows 解析路径表，起止节点ID为空时按起止节点名称关联
+func parsePathRows(imp *tableImport, sheet *importSheet, existing map[domain.PathID]*domain.Path,
+	nodes map[domain.NodeID]*domain.Node) []*tableRow[*domain.Path] {
+	bound := bindColumns(imp, sheet, pathTableColumns(nil), imp.opts.PathColumns,
+		func(p *domain.Path) *map[string]interface{} { return &p.Properties })
+
+	nodesByName := make(map[string][]domain.NodeID)
+	for _, node := range nodes {
+		nodesByName[node.Name] = append(nodesByName[node.Name], node.ID)
+	}
+	resolve := func(name string) (domain.NodeID, error) {
+		switch ids := nodesByName[name]; len(ids) {
+		case 0:
+			return "", fmt.Errorf("节点 %q 不存在", name)
+		case 1:
+			return ids[0], nil
+		default:
+			return "", fmt.Errorf("存在多个名为 %q 的节点，请改用节点ID", name)
+		}
+	}
+
+	var rows []*tableRow[*domain.Path]
+	seen := make(map[domain.PathID]int)
+	for i, values := range sheet.rows[1:] {
+		line := i + 2
+		if isBlankRow(values) {
+			continue
+		}
+		imp.result.Paths.Rows++
+		errors := len(imp.result.Errors)
+
+		var path *domain.Path
+		created := true
+		rawID, _ := boundValue(bound, values, "id")
+		id := domain.PathID(rawID)
+		if current, ok := existing[id]; ok && id != "" {
+			copied := *current
+			copied.Properties = copyProperties(current.Properties)
+			path, created = &copied, false
+		} else {
+			path = domain.NewPath("", "", "")
+			if id != "" {
+				path.ID = id
+			}
+		}
+
+		for _, b := range bound {
+			if v := cell(values, b.index); v != "" && !b.column.readOnly && b.column.key != "id" {
+				if err := b.column.set(path, v); err != nil {
+					imp.fail(sheet.name, line, b.header, err.Error())
+				}
+			}
+		}
+		for _, end := range []struct {
+			idKey, nameKey string
+			target         *domain.NodeID
+		}{
+			{"start_node_id", "start_node_name", &path.StartNodeID},
+			{"end_node_id", "end_node_name", &path.EndNodeID},
+		} {
+			name, header := boundValue(bound, values, end.nameKey)
+			if id, _ := boundValue(bound, values, end.idKey); id != "" || name == "" {
+				continue
+			}
+			nodeID, err := resolve(name)
+			if err != nil {
+				imp.fail(sheet.name, line, header, err.Error())
+				continue
+			}
+			*end.target = nodeID
+		}
+
+		if len(imp.result.Errors) > errors {
+			// 单元格已有错误，不再重复报告整行校验错误
+		} else if err := path.IsValid(); err != nil {
+			imp.fail(sheet.name, line, "", err.Error())
+		} else {
+			for _, nodeID := range []domain.NodeID{path.StartNodeID, path.EndNodeID} {
+				if _, ok := nodes[nodeID]; !ok {
+					imp.fail(sheet.name, line, "", fmt.Sprintf("节点 %s 不存在", nodeID))
+				}
+			}
+		}
+		if first, ok := seen[path.ID]; ok {
+			imp.fail(sheet.name, line, "id", fmt.Sprintf("ID与第%d行重复", first))
+		}
+
+		if len(imp.result.Errors) > errors {
+			imp.result.Paths.Failed++
+			continue
+		}
+		seen[path.ID] = line
+		rows = append(rows, &tableRow[*domain.Path]{value: path, line: line, created: created})
+	}
+	return rows
+}
+
+// boundValue 取指定列键的单元格及其表头
+func boundValue[T any](bound []boundColumn[T], values []string, key string) (string, string) {
+	for _, b := range bound {
+		if b.column.key == key {
+			return cell(values, b.index), b.header
+		}
+	}
+	return "", ""
+}
+
+func copyProperties(props map[string]interface{}) map[string]interface{} {
+	if props == nil {
+		return nil
+	}
+	copied := make(map[string]interface{}, len(props))
+	for k, v := range props {
+		copied[k] = v
+	}
+	return copied
+}
+
+// readImportSheets 读取 XLSX、CSV 或导出的 CSV 压缩包
+func readImportSheets(data []byte, format string) ([]importSheet, error) {
+	isZip := bytes.HasPrefix(data, []byte("PK\x03\x04"))
+	if format == TableFormatXLSX || (format == "" && isZip) {
+		archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+		if err != nil {
+			return nil, fmt.Errorf("读取文件失败: %w", err)
+		}
+		for _, entry := range archive.File {
+			if entry.Name == "[Content_Types].xml" {
+				return readXLSXSheets(data)
+			}
+		}
+		return readCSVZip(archive)
+	}
+	if format != "" && format != TableFormatCSV {
+		return nil, fmt.Errorf("不支持的导入格式: %s", format)
+	}
+
+	rows, err := readCSVRows(data)
+	if err != nil {
+		return nil, err
+	}
+	return []importSheet{{rows: rows}}, nil
+}
+
+func readXLSXSheets(data []byte) ([]importSheet, error) {
+	f, err := excelize.OpenReader(bytes.NewReader(data), excelize.Options{UnzipSizeLimit: 4 * tableFileLimit})
+	if err != nil {
+		return nil, fmt.Errorf("读取XLSX失败: %w", err)
+	}
+	defer f.Close()
+
+	var sheets []importSheet
+	for _, name := range f.GetSheetList() {
+		rows, err := f.GetRows(name)
+		if err != nil {
+			return nil, fmt.Errorf("读取工作表 %s 失败: %w", name, err)
+		}
+		sheets = append(sheets, importSheet{name: name, rows: rows})
+	}
+	return sheets, nil
+}
+
+func readCSVZip(archive *zip.Reader) ([]importSheet, error) {
+	var sheets []importSheet
+	for _, entry := range archive.File {
+		if !strings.EqualFold(filepath.Ext(entry.Name), ".csv") {
+			continue
+		}
+		file, err := entry.Open()
+		if err != nil {
+			return nil, fmt.Errorf("读取 %s 失败: %w", entry.Name, err)
+		}
+		data, err := io.ReadAll(io.LimitReader(file, tableFileLimit+1))
+		file.Close()
+		if err != nil {
+			return nil, fmt.Errorf("读取 %s 失败: %w", entry.Name, err)
+		}
+		if len(data) > tableFileLimit {
+			return nil, fmt.Errorf("文件 %s 超过大小上限 %d 字节", entry.Name, tableFileLimit)
+		}
+		rows, err := readCSVRows(data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", entry.Name, err)
+		}
+		sheets = append(sheets, importSheet{name: filepath.Base(entry.Name), rows: rows})
+	}
+	return sheets, nil
+}
+
+// readCSVRows 解析CSV，去掉BOM，按首行自动识别逗号、分号或制表符分隔
+func readCSVRows(data []byte) ([][]string, error) {
+	data = bytes.TrimPrefix(data, []byte("\ufeff"))
+	firstLine, _, _ := bytes.Cut(data, []byte("\n"))
+
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+	best := 0
+	for _, sep := range []rune{',', ';', '\t'} {
+		if n := bytes.Count(firstLine, []byte(string(sep))); n > best {
+			best, reader.Comma = n, sep
+		}
+	}
+
+	rows, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("解析CSV失败: %w", err)
+	}
+	return rows, nil
+}
@@ -0,0 +1,57 @@
+package services
+
+import (
+	"archive/zip"
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestReadImportSheetsCSVZip(t *testing.T) {
+	archive := func(files map[string]string) []byte {
+		var buf bytes.Buffer
+		w := zip.NewWriter(&buf)
+		for name, content := range files {
+			f, err := w.Create(name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := f.Write([]byte(content)); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := w.Close(); err != nil {
+			t.Fatal(err)
+		}
+		return buf.Bytes()
+	}
+
+	tests := []struct {
+		name       string
+		files      map[string]string
+		wantSheets int
+		wantErr    string
+	}{
+		{name: "读取CSV并跳过其他文件", files: map[string]string{"nodes.csv": "id,name\n1,A\n", "README.txt": "ignored"}, wantSheets: 1},
+		{name: "恰好等于上限", files: map[string]string{"nodes.csv": "id\n" + strings.Repeat("1", tableFileLimit-3)}, wantSheets: 1},
+		{name: "解压后超过上限", files: map[string]string{"nodes.csv": "id\n" + strings.Repeat("1", tableFileLimit)}, wantErr: "超过大小上限"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sheets, err := readImportSheets(archive(tt.files), "")
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("readImportSheets() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("readImportSheets() error = %v", err)
+			}
+			if len(sheets) != tt.wantSheets {
+				t.Errorf("readImportSheets() = %d sheets, want %d", len(sheets), tt.wantSheets)
+			}
+		})
+	}
+}
@@ -0,0 +1,116 @@
+// Package services 外部数据库的元数据查询：模式、表、列和索引
+//
+// - 各方言的结果列一致，调用方不区分数据库类型
+// - 模式名和表名一律作为参数传入，模式名为空时使用连接的默认模式
+// - SchemasQuery 返回 (模式名)；TablesQuery 返回 (表名, table 或 view)
+// - DescribeQuery 返回 (列名, 类型, 可为空, 默认值, 主键序号)，主键序号从 1 开始，不属于主键为 0
+// - IndexesQuery 返回 (索引名, 唯一, 主键, 列名, 列序号)，表达式索引的列名为 NULL
+package services
+
+import "strconv"
+
+func (mysqlDialect) SchemasQuery() string {
+	return "SELECT schema_name FROM information_schema.schemata " +
+		"WHERE schema_name NOT IN ('information_schema', 'mysql', 'performance_schema', 'sys') ORDER BY schema_name"
+}
+
+// mysqlSchemaCond 模式条件，模式名为空时使用连接的当前数据库
+func mysqlSchemaCond(column, schema string) (string, []interface{}) {
+	if schema == "" {
+		return column + " = DATABASE()", nil
+	}
+	return column + " = ?", []interface{}{schema}
+}
+
+func (mysqlDialect) TablesQuery(schema string) (string, []interface{}) {
+	cond, args := mysqlSchemaCond("table_schema", schema)
+	return "SELECT table_name, CASE table_type WHEN 'VIEW' THEN 'view' ELSE 'table' END FROM information_schema.tables " +
+		"WHERE " + cond + " AND table_type IN ('BASE TABLE', 'VIEW') ORDER BY table_name", args
+}
+
+func (mysqlDialect) DescribeQuery(schema, table string) (string, []interface{}) {
+	cond, args := mysqlSchemaCond("c.table_schema", schema)
+	return "SELECT c.column_name, c.column_type, c.is_nullable = 'YES', c.column_default, COALESCE(k.ordinal_position, 0) " +
+		"FROM information_schema.columns c LEFT JOIN information_schema.key_column_usage k " +
+		"ON k.constraint_name = 'PRIMARY' AND k.table_schema = c.table_schema AND k.table_name = c.table_name AND k.column_name = c.column_name " +
+		"WHERE " + cond + " AND c.table_name = ? ORDER BY c.ordinal_position", append(args, table)
+}
+
+func (mysqlDialect) IndexesQuery(schema, table string) (string, []interface{}) {
+	cond, args := mysqlSchemaCond("table_schema", schema)
+	return "SELECT index_name, non_unique = 0, index_name = 'PRIMARY', column_name, seq_in_index FROM information_schema.statistics " +
+		"WHERE " + cond + " AND table_name = ? ORDER BY index_name, seq_in_index", append(args, table)
+}
+
+func (sqliteDialect) SchemasQuery() string {
+	return "SELECT name FROM pragma_database_list ORDER BY seq"
+}
+
+func (sqliteDialect) TablesQuery(schema string) (string, []interface{}) {
+	if schema == "" {
+		schema = "main"
+	}
+	return "SELECT name, type FROM pragma_table_list WHERE schema = ? AND type IN ('table', 'view') " +
+		"AND name NOT LIKE 'sqlite\\_%' ESCAPE '\\' ORDER BY name", []interface{}{schema}
+}
+
+func (sqliteDialect) DescribeQuery(schema, table string) (string, []interface{}) {
+	const columns = `SELECT name, type, "notnull" = 0, dflt_value, pk `
+	if schema == "" {
+		return columns + "FROM pragma_table_info(?) ORDER BY cid", []interface{}{table}
+	}
+	return columns + "FROM pragma_table_info(?, ?) ORDER BY cid", []interface{}{table, schema}
+}
+
+// IndexesQuery 整数主键是 rowid 的别名，没有对应的索引，主键以 DescribeQuery 为准
+func (sqliteDialect) IndexesQuery(schema, table string) (string, []interface{}) {
+	const columns = `SELECT il.name, il."unique", il.origin = 'pk', ii.name, ii.seqno + 1 `
+	if schema == "" {
+		return columns + "FROM pragma_index_list(?) il JOIN pragma_index_info(il.name) ii " +
+			"ORDER BY il.name, ii.seqno", []interface{}{table}
+	}
+	return columns + "FROM pragma_index_list(?, ?) il JOIN pragma_index_info(il.name, ?) ii " +
+		"ORDER BY il.name, ii.seqno", []interface{}{table, schema, schema}
+}
+
+func (postgresDialect) SchemasQuery() string {
+	return "SELECT schema_name FROM information_schema.schemata " +
+		"WHERE schema_name <> 'information_schema' AND schema_name NOT LIKE 'pg\\_%' ORDER BY schema_name"
+}
+
+// postgresSchemaCond 模式条件，模式名为空时使用 current_schema()；参数从 $1 开始编号
+func postgresSchemaCond(column, schema string) (string, []interface{}) {
+	if schema == "" {
+		return column + " = current_schema()", nil
+	}
+	return column + " = $1", []interface{}{schema}
+}
+
+func (postgresDialect) TablesQuery(schema string) (string, []interface{}) {
+	cond, args := postgresSchemaCond("table_schema", schema)
+	return "SELECT table_name, CASE table_type WHEN 'VIEW' THEN 'view' ELSE 'table' END FROM information_schema.tables " +
+		"WHERE " + cond + " AND table_type IN ('BASE TABLE', 'VIEW') ORDER BY table_name", args
+}
+
+func (postgresDialect) DescribeQuery(schema, table string) (string, []interface{}) {
+	cond, args := postgresSchemaCond("c.table_schema", schema)
+	return "SELECT c.column_name, c.data_type, c.is_nullable = 'YES', c.column_default, COALESCE(k.ordinal_position, 0) " +
+		"FROM information_schema.columns c " +
+		"LEFT JOIN information_schema.table_constraints t " +
+		"ON t.constraint_type = 'PRIMARY KEY' AND t.table_schema = c.table_schema AND t.table_name = c.table_name " +
+		"LEFT JOIN information_schema.key_column_usage k " +
+		"ON k.constraint_schema = t.constraint_schema AND k.constraint_name = t.constraint_name AND k.column_name = c.column_name " +
+		"WHERE " + cond + " AND c.table_name = $" + strconv.Itoa(len(args)+1) + " ORDER BY c.ordinal_position", append(args, table)
+}
+
+func (postgresDialect) IndexesQuery(schema, table string) (string, []interface{}) {
+	cond, args := postgresSchemaCond("n.nspname", schema)
+	return "SELECT i.relname, ix.indisunique, ix.indisprimary, a.attname, k.ord " +
+		"FROM pg_index ix " +
+		"JOIN pg_class t ON t.oid = ix.indrelid " +
+		"JOIN pg_namespace n ON n.oid = t.relnamespace " +
+		"JOIN pg_class i ON i.oid = ix.indexrelid " +
+		"CROSS JOIN LATERAL unnest(ix.indkey::int2[]) WITH ORDINALITY AS k(attnum, ord) " +
+		"LEFT JOIN pg_attribute a ON a.attrelid = t.oid AND a.attnum = k.attnum " +
+		"WHERE " + cond + " AND t.relname = $" + strconv.Itoa(len(args)+1) + " ORDER BY i.relname, k.ord", append(args, table)
+}
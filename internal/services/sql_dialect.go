@@ -0,0 +1,482 @@
+// Package services 外部数据库的 SQL 方言与安全的语句构建
+//
+// - 方言负责驱动名、连接串、标识符引号、占位符和元数据查询（MySQL、SQLite、PostgreSQL）
+// - 表名、列名先按数据库元数据校验，只使用数据库中实际存在的名称，并加引号拼接；值一律使用占位符
+// - 筛选条件由结构化的列、运算符和值构建，运算符来自固定白名单
+// - 未指定排序时按ID列排序，保证同步结果可重复
+package services
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"robot-path-editor/internal/domain"
+)
+
+// sqlDialect 外部数据库方言
+type sqlDialect interface {
+	Name() string
+	DriverName() string
+	// DSN 由连接配置和连接属性生成连接串，见 sql_dsn.go
+	DSN(conn *domain.DatabaseConnection) (string, error)
+	// ConnectionProperties 支持的连接属性，其余属性不写入连接串
+	ConnectionProperties() []string
+	QuoteIdent(name string) string
+	Placeholder(n int) string
+	// ColumnsQuery 返回查询表结构的语句，结果为 (列名, 类型) 两列
+	ColumnsQuery(schema, table string) (string, []interface{})
+
+	// 表浏览器使用的元数据查询，见 sql_catalog.go
+	SchemasQuery() string
+	TablesQuery(schema string) (string, []interface{})
+	DescribeQuery(schema, table string) (string, []interface{})
+	IndexesQuery(schema, table string) (string, []interface{})
+}
+
+// dialectFor 按连接类型选择方言
+func dialectFor(dbType string) (sqlDialect, error) {
+	switch strings.ToLower(dbType) {
+	case "mysql":
+		return mysqlDialect{}, nil
+	case "sqlite", "sqlite3":
+		return sqliteDialect{}, nil
+	case "postgres", "postgresql":
+		return postgresDialect{}, nil
+	default:
+		return nil, fmt.Errorf("不支持的数据库类型: %s", dbType)
+	}
+}
+
+type mysqlDialect struct{}
+
+func (mysqlDialect) Name() string       { return "mysql" }
+func (mysqlDialect) DriverName() string { return "mysql" }
+func (mysqlDialect) QuoteIdent(name string) string {
+	return "`" + strings.ReplaceAll(name, "`", "``") + "`"
+}
+func (mysqlDialect) Placeholder(int) string { return "?" }
+func (mysqlDialect) ColumnsQuery(schema, table string) (string, []interface{}) {
+	if schema == "" {
+		return "SELECT column_name, data_type FROM information_schema.columns " +
+			"WHERE table_schema = DATABASE() AND table_name = ? ORDER BY ordinal_position", []interface{}{table}
+	}
+	return "SELECT column_name, data_type FROM information_schema.columns " +
+		"WHERE table_schema = ? AND table_name = ? ORDER BY ordinal_position", []interface{}{schema, table}
+}
+
+type sqliteDialect struct{}
+
+func (sqliteDialect) Name() string       { return "sqlite" }
+func (sqliteDialect) DriverName() string { return "sqlite3" }
+func (sqliteDialect) QuoteIdent(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+func (sqliteDialect) Placeholder(int) string { return "?" }
+func (sqliteDialect) ColumnsQuery(schema, table string) (string, []interface{}) {
+	if schema == "" {
+		return "SELECT name, type FROM pragma_table_info(?) ORDER BY cid", []interface{}{table}
+	}
+	return "SELECT name, type FROM pragma_table_info(?, ?) ORDER BY cid", []interface{}{table, schema}
+}
+
+type postgresDialect struct{}
+
+func (postgresDialect) Name() string       { return "postgres" }
+func (postgresDialect) DriverName() string { return "pgx" }
+func (postgresDialect) QuoteIdent(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+func (postgresDialect) Placeholder(n int) string { return "$" + strconv.Itoa(n) }
+func (postgresDialect) ColumnsQuery(schema, table string) (string, []interface{}) {
+	if schema == "" {
+		return "SELECT column_name, data_type FROM information_schema.columns " +
+			"WHERE table_schema = current_schema() AND table_name = $1 ORDER BY ordinal_position", []interface{}{table}
+	}
+	return "SELECT column_name, data_type FROM information_schema.columns " +
+		"WHERE table_schema = $1 AND table_name = $2 ORDER BY ordinal_position", []interface{}{schema, table}
+}
+
+// sqlBuilder 按方言拼接 SQL，同时生成参数内联后的展示文本
+type sqlBuilder struct {
+	dialect sqlDialect
+	query   strings.Builder
+	display strings.Builder
+	args    []interface{}
+}
+
+func newSQLBuilder(d sqlDialect) *sqlBuilder { return &sqlBuilder{dialect: d} }
+
+// sql 写入固定的 SQL 片段
+func (b *sqlBuilder) sql(s string) *sqlBuilder {
+	b.query.WriteString(s)
+	b.display.WriteString(s)
+	return b
+}
+
+// ident 写入加引号的标识符
+func (b *sqlBuilder) ident(name string) *sqlBuilder {
+	return b.sql(b.dialect.QuoteIdent(name))
+}
+
+// arg 写入占位符并记录参数
+func (b *sqlBuilder) arg(v interface{}) *sqlBuilder {
+	b.args = append(b.args, v)
+	b.query.WriteString(b.dialect.Placeholder(len(b.args)))
+	b.display.WriteString(sqlLiteral(v))
+	return b
+}
+
+// sqlLiteral 值的 SQL 字面量形式，仅用于展示
+func sqlLiteral(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return "NULL"
+	case string:
+		return "'" + strings.ReplaceAll(v, "'", "''") + "'"
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		if v {
+			return "TRUE"
+		}
+		return "FALSE"
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// externalTable 按元数据校验过的外部表
+type externalTable struct {
+	db      *sql.DB
+	dialect sqlDialect
+	schema  string
+	name    string
+	columns []string          // 数据库中的列名，按定义顺序
+	types   map[string]string // 列名 → 类型
+	lookup  map[string]string // 小写列名 → 数据库中的列名
+}
+
+// inspectExternalTable 读取表结构；表不存在时返回错误
+func inspectExternalTable(ctx context.Context, db *sql.DB, d sqlDialect, schema, name string) (*externalTable, error) {
+	if err := checkIdentifier(name); err != nil {
+		return nil, fmt.Errorf("表名无效: %w", err)
+	}
+	if schema != "" {
+		if err := checkIdentifier(schema); err != nil {
+			return nil, fmt.Errorf("模式名无效: %w", err)
+		}
+	}
+
+	query, args := d.ColumnsQuery(schema, name)
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("查询表结构失败: %w", err)
+	}
+	defer rows.Close()
+
+	t := &externalTable{
+		db:      db,
+		dialect: d,
+		schema:  schema,
+		name:    name,
+		types:   make(map[string]string),
+		lookup:  make(map[string]string),
+	}
+	for rows.Next() {
+		var column string
+		var typ sql.NullString
+		if err := rows.Scan(&column, &typ); err != nil {
+			return nil, fmt.Errorf("读取表结构失败: %w", err)
+		}
+		t.columns = append(t.columns, column)
+		t.types[column] = typ.String
+		t.lookup[strings.ToLower(column)] = column
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("读取表结构失败: %w", err)
+	}
+	if len(t.columns) == 0 {
+		return nil, fmt.Errorf("表 %s 不存在或没有列", t.displayName())
+	}
+	return t, nil
+}
+
+// checkIdentifier 标识符的基本检查，是否存在以元数据为准
+func checkIdentifier(name string) error {
+	switch {
+	case strings.TrimSpace(name) == "":
+		return fmt.Errorf("不能为空")
+	case len(name) > 128:
+		return fmt.Errorf("%q 过长", name)
+	case strings.ContainsRune(name, 0):
+		return fmt.Errorf("%q 包含非法字符", name)
+	}
+	return nil
+}
+
+func (t *externalTable) displayName() string {
+	if t.schema != "" {
+		return t.schema + "." + t.name
+	}
+	return t.name
+}
+
+// column 返回数据库中的列名（大小写不敏感匹配）
+func (t *externalTable) column(name string) (string, error) {
+	if column, ok := t.lookup[strings.ToLower(name)]; ok {
+		return column, nil
+	}
+	return "", fmt.Errorf("表 %s 中没有列 %q", t.displayName(), name)
+}
+
+// checkColumns 校验映射中引用的全部列
+func (t *externalTable) checkColumns(names ...string) error {
+	var problems []string
+	for _, name := range names {
+		if name == "" {
+			continue
+		}
+		if _, err := t.column(name); err != nil {
+			problems = append(problems, err.Error())
+		}
+	}
+	if len(problems) > 0 {
+		sort.Strings(problems)
+		return fmt.Errorf("映射的列无效: %s", strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// checkMappingQuery 不连接外部数据库即可完成的校验：同步策略、表名、模式名、字段映射、值转换和筛选运算符
+func checkMappingQuery(mapping *domain.TableMapping) error {
+	if err := checkSyncPolicies(mapping); err != nil {
+		return err
+	}
+	if mapping.TableName != "" {
+		if err := checkIdentifier(mapping.TableName); err != nil {
+			return fmt.Errorf("表名无效: %w", err)
+		}
+	}
+	if mapping.SchemaName != "" {
+		if err := checkIdentifier(mapping.SchemaName); err != nil {
+			return fmt.Errorf("模式名无效: %w", err)
+		}
+	}
+	if mapping.NodeMapping != nil {
+		if _, err := newSyncSpec(nodeSyncDef, nodeEntityMapping(mapping)); err != nil {
+			return err
+		}
+	}
+	if mapping.PathMapping != nil {
+		if _, err := newSyncSpec(pathSyncDef, pathEntityMapping(mapping)); err != nil {
+			return err
+		}
+	}
+	if mapping.NodeMapping == nil && mapping.PathMapping == nil {
+		if mapping.TableName == "" {
+			return fmt.Errorf("未指定表名")
+		}
+		return checkTableQuery(mapping.Filter, mapping.OrderBy)
+	}
+	return nil
+}
+
+// checkTableQuery 校验筛选条件和排序的结构
+func checkTableQuery(filter []domain.TableCondition, order []domain.TableOrder) error {
+	for _, c := range filter {
+		if c.Column == "" {
+			return fmt.Errorf("筛选条件缺少列名")
+		}
+		if _, ok := sqlOperators[c.Operator]; !ok {
+			return fmt.Errorf("筛选条件 %s 的运算符无效: %s", c.Column, c.Operator)
+		}
+	}
+	for _, o := range order {
+		if o.Column == "" {
+			return fmt.Errorf("排序缺少列名")
+		}
+	}
+	return nil
+}
+
+// tableRef 写入加引号的表名（含模式）
+func (t *externalTable) tableRef(b *sqlBuilder) *sqlBuilder {
+	if t.schema != "" {
+		b.ident(t.schema).sql(".")
+	}
+	return b.ident(t.name)
+}
+
+// columnRef 写入加引号的列名；未校验的名称原样加引号
+func (t *externalTable) columnRef(b *sqlBuilder, name string) *sqlBuilder {
+	if column, err := t.column(name); err == nil {
+		name = column
+	}
+	return b.ident(name)
+}
+
+// sqlOperators 筛选运算符白名单
+var sqlOperators = map[string]string{
+	"eq":       " = ",
+	"ne":       " <> ",
+	"lt":       " < ",
+	"le":       " <= ",
+	"gt":       " > ",
+	"ge":       " >= ",
+	"like":     " LIKE ",
+	"in":       " IN ",
+	"is_null":  " IS NULL",
+	"not_null": " IS NOT NULL",
+}
+
+// selectQuery 构建 SELECT 列 FROM 表 WHERE 筛选 ORDER BY 排序，未指定排序时按 idColumn 排序
+func (t *externalTable) selectQuery(columns []string, filter []domain.TableCondition, order []domain.TableOrder, idColumn string) (*sqlBuilder, error) {
+	b := newSQLBuilder(t.dialect).sql("SELECT ")
+	for i, c := range columns {
+		if i > 0 {
+			b.sql(", ")
+		}
+		t.columnRef(b, c)
+	}
+	t.tableRef(b.sql(" FROM "))
+
+	if err := t.where(b, filter); err != nil {
+		return nil, err
+	}
+
+	if len(order) == 0 && idColumn != "" {
+		order = []domain.TableOrder{{Column: idColumn}}
+	}
+	for i, o := range order {
+		if i == 0 {
+			b.sql(" ORDER BY ")
+		} else {
+			b.sql(", ")
+		}
+		t.columnRef(b, o.Column)
+		if o.Desc {
+			b.sql(" DESC")
+		}
+	}
+	return b, nil
+}
+
+// where 写入筛选条件，没有条件时不写入
+func (t *externalTable) where(b *sqlBuilder, filter []domain.TableCondition) error {
+	for i, c := range filter {
+		op, ok := sqlOperators[c.Operator]
+		if !ok {
+			return fmt.Errorf("筛选条件 %s 的运算符无效: %s", c.Column, c.Operator)
+		}
+		if i == 0 {
+			b.sql(" WHERE ")
+		} else {
+			b.sql(" AND ")
+		}
+		t.columnRef(b, c.Column).sql(op)
+		switch c.Operator {
+		case "is_null", "not_null":
+		case "in":
+			values, ok := c.Value.([]interface{})
+			if !ok || len(values) == 0 {
+				return fmt.Errorf("筛选条件 %s 的 in 需要非空数组", c.Column)
+			}
+			b.sql("(")
+			for j, v := range values {
+				if j > 0 {
+					b.sql(", ")
+				}
+				b.arg(v)
+			}
+			b.sql(")")
+		default:
+			if _, ok := c.Value.([]interface{}); ok || c.Value == nil {
+				return fmt.Errorf("筛选条件 %s 需要单个值", c.Column)
+			}
+			b.arg(c.Value)
+		}
+	}
+	return nil
+}
+
+// syncSource 表映射在外部数据库中的数据：一个连接，节点和路径各自的表
+type syncSource struct {
+	db   *sql.DB
+	node *syncSpec
+	path *syncSpec
+}
+
+// specs 已配置的实体映射，节点在前
+func (src *syncSource) specs() []*syncSpec {
+	var specs []*syncSpec
+	for _, sp := range []*syncSpec{src.node, src.path} {
+		if sp != nil {
+			specs = append(specs, sp)
+		}
+	}
+	return specs
+}
+
+// tables 涉及的外部表名
+func (src *syncSource) tables() string {
+	var names []string
+	for _, sp := range src.specs() {
+		if name := sp.table.displayName(); !containsValue(names, name) {
+			names = append(names, name)
+		}
+	}
+	return strings.Join(names, ", ")
+}
+
+func (src *syncSource) Close() error { return src.db.Close() }
+
+// openSyncSource 连接表映射的外部数据库，按实际表结构校验节点和路径的映射，调用方负责关闭
+func (s *dataSyncService) openSyncSource(ctx context.Context, mapping *domain.TableMapping) (*syncSource, error) {
+	if err := checkMappingQuery(mapping); err != nil {
+		return nil, err
+	}
+	conn, err := s.dbConnRepo.GetByID(ctx, mapping.ConnectionID)
+	if err != nil {
+		return nil, fmt.Errorf("获取数据库连接失败: %w", err)
+	}
+	d, err := dialectFor(conn.Type)
+	if err != nil {
+		return nil, err
+	}
+	db, err := s.connector.open(conn)
+	if err != nil {
+		return nil, fmt.Errorf("连接外部数据库失败: %w", err)
+	}
+
+	src := &syncSource{db: db}
+	tables := make(map[string]*externalTable)
+	open := func(def *syncEntityDef, em *syncEntityMapping) (*syncSpec, error) {
+		spec, err := newSyncSpec(def, em)
+		if err != nil {
+			return nil, err
+		}
+		t, ok := tables[em.table]
+		if !ok {
+			if t, err = inspectExternalTable(ctx, db, d, mapping.SchemaName, em.table); err != nil {
+				return nil, err
+			}
+			tables[em.table] = t
+		}
+		return spec, spec.bind(def, t)
+	}
+	if mapping.NodeMapping != nil {
+		src.node, err = open(nodeSyncDef, nodeEntityMapping(mapping))
+	}
+	if err == nil && mapping.PathMapping != nil {
+		src.path, err = open(pathSyncDef, pathEntityMapping(mapping))
+	}
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return src, nil
+}
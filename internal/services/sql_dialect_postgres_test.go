@@ -0,0 +1,143 @@
+package services
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"robot-path-editor/internal/domain"
+	"robot-path-editor/internal/testutil"
+)
+
+// TestPostgresExternalTable 通过 ExternalConnector 连接 PostgreSQL，校验元数据查询和生成的语句
+func TestPostgresExternalTable(t *testing.T) {
+	pg := testutil.Postgres(t)
+	ctx := context.Background()
+
+	conn := &domain.DatabaseConnection{
+		ID:         "pg-test",
+		Type:       "postgres",
+		Host:       pg.Host,
+		Port:       pg.Port,
+		Database:   pg.Database,
+		Username:   pg.Username,
+		Password:   pg.Password,
+		Properties: map[string]string{"application_name": "robot-path-editor-test", "legacy_option": "ignored"},
+	}
+	db, err := NewExternalConnector("", "", "").open(conn)
+	if err != nil {
+		t.Fatalf("open() error = %v", err)
+	}
+	defer db.Close()
+
+	for _, stmt := range []string{
+		`DROP SCHEMA IF EXISTS "Warehouse" CASCADE`,
+		`CREATE SCHEMA "Warehouse"`,
+		`CREATE TABLE "Warehouse"."Robot Nodes" ("NodeID" varchar(36) PRIMARY KEY, name text NOT NULL, x double precision, y double precision, "a""b" integer)`,
+		`CREATE UNIQUE INDEX robot_nodes_name ON "Warehouse"."Robot Nodes" (name)`,
+		`INSERT INTO "Warehouse"."Robot Nodes" VALUES ('n1', 'dock', 0, 0, 1), ('n2', 'shelf', 5, 2, 2), ('n3', 'charger', -1, 3, NULL)`,
+		`DROP TABLE IF EXISTS public.robot_paths`,
+		`CREATE TABLE public.robot_paths (id varchar(36) PRIMARY KEY, start_node varchar(36), end_node varchar(36))`,
+	} {
+		if _, err := db.ExecContext(ctx, stmt); err != nil {
+			t.Fatalf("准备测试数据失败 %q: %v", stmt, err)
+		}
+	}
+	t.Cleanup(func() {
+		_, _ = db.ExecContext(ctx, `DROP SCHEMA IF EXISTS "Warehouse" CASCADE`)
+		_, _ = db.ExecContext(ctx, `DROP TABLE IF EXISTS public.robot_paths`)
+	})
+
+	d := postgresDialect{}
+
+	t.Run("information_schema 列", func(t *testing.T) {
+		tests := []struct {
+			schema, table string
+			wantColumns   []string
+			wantErr       bool
+		}{
+			{"Warehouse", "Robot Nodes", []string{"NodeID", "name", "x", "y", `a"b`}, false},
+			{"", "robot_paths", []string{"id", "start_node", "end_node"}, false},
+			{"public", "robot_paths", []string{"id", "start_node", "end_node"}, false},
+			{"warehouse", "Robot Nodes", nil, true}, // 加引号的模式名区分大小写
+			{"", "missing", nil, true},
+		}
+		for _, tt := range tests {
+			tbl, err := inspectExternalTable(ctx, db, d, tt.schema, tt.table)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("inspectExternalTable(%q, %q) error = %v, wantErr %v", tt.schema, tt.table, err, tt.wantErr)
+				continue
+			}
+			if err == nil && !reflect.DeepEqual(tbl.columns, tt.wantColumns) {
+				t.Errorf("inspectExternalTable(%q, %q) columns = %v, want %v", tt.schema, tt.table, tbl.columns, tt.wantColumns)
+			}
+		}
+	})
+
+	t.Run("主键和索引", func(t *testing.T) {
+		_, desc, err := describeExternalTable(ctx, db, d, "Warehouse", "Robot Nodes")
+		if err != nil {
+			t.Fatalf("describeExternalTable() error = %v", err)
+		}
+		if !reflect.DeepEqual(desc.PrimaryKey, []string{"NodeID"}) {
+			t.Errorf("PrimaryKey = %v, want [NodeID]", desc.PrimaryKey)
+		}
+		var unique bool
+		for _, idx := range desc.Indexes {
+			if idx.Name == "robot_nodes_name" {
+				unique = idx.Unique && reflect.DeepEqual(idx.Columns, []string{"name"})
+			}
+		}
+		if !unique {
+			t.Errorf("Indexes = %+v, 缺少唯一索引 robot_nodes_name(name)", desc.Indexes)
+		}
+	})
+
+	t.Run("筛选和排序", func(t *testing.T) {
+		tbl, err := inspectExternalTable(ctx, db, d, "Warehouse", "Robot Nodes")
+		if err != nil {
+			t.Fatalf("inspectExternalTable() error = %v", err)
+		}
+		tests := []struct {
+			name   string
+			filter []domain.TableCondition
+			order  []domain.TableOrder
+			want   []string
+		}{
+			{"无筛选时按ID排序", nil, nil, []string{"n1", "n2", "n3"}},
+			{"列名大小写不敏感", []domain.TableCondition{{Column: "nodeid", Operator: "ne", Value: "n1"}}, nil, []string{"n2", "n3"}},
+			{"in 使用多个占位符", []domain.TableCondition{{Column: "name", Operator: "in", Value: []interface{}{"dock", "charger"}}}, nil, []string{"n1", "n3"}},
+			{"多个条件", []domain.TableCondition{{Column: "x", Operator: "ge", Value: 0.0}, {Column: "y", Operator: "lt", Value: 3.0}}, nil, []string{"n1", "n2"}},
+			{"含引号的列名", []domain.TableCondition{{Column: `a"b`, Operator: "is_null"}}, nil, []string{"n3"}},
+			{"值中的引号作为参数", []domain.TableCondition{{Column: "name", Operator: "eq", Value: "dock' OR '1'='1"}}, nil, nil},
+			{"倒序", nil, []domain.TableOrder{{Column: "x", Desc: true}}, []string{"n2", "n1", "n3"}},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				b, err := tbl.selectQuery([]string{"NodeID"}, tt.filter, tt.order, "NodeID")
+				if err != nil {
+					t.Fatalf("selectQuery() error = %v", err)
+				}
+				rows, err := db.QueryContext(ctx, b.query.String(), b.args...)
+				if err != nil {
+					t.Fatalf("执行 %s 失败: %v", b.query.String(), err)
+				}
+				defer rows.Close()
+				var got []string
+				for rows.Next() {
+					var id string
+					if err := rows.Scan(&id); err != nil {
+						t.Fatal(err)
+					}
+					got = append(got, id)
+				}
+				if err := rows.Err(); err != nil {
+					t.Fatal(err)
+				}
+				if !reflect.DeepEqual(got, tt.want) {
+					t.Errorf("%s = %v, want %v", b.display.String(), got, tt.want)
+				}
+			})
+		}
+	})
+}
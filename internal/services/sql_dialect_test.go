@@ -0,0 +1,308 @@
+package services
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"robot-path-editor/internal/domain"
+)
+
+func TestDialectQuoteIdent(t *testing.T) {
+	tests := []struct {
+		name    string
+		dialect sqlDialect
+		ident   string
+		want    string
+	}{
+		{"PostgreSQL 普通标识符", postgresDialect{}, "robot_nodes", `"robot_nodes"`},
+		{"PostgreSQL 保留大小写", postgresDialect{}, "NodeID", `"NodeID"`},
+		{"PostgreSQL 内嵌双引号", postgresDialect{}, `a"b`, `"a""b"`},
+		{"PostgreSQL 注入尝试", postgresDialect{}, `x"; DROP TABLE t; --`, `"x""; DROP TABLE t; --"`},
+		{"SQLite 内嵌双引号", sqliteDialect{}, `a"b`, `"a""b"`},
+		{"MySQL 内嵌反引号", mysqlDialect{}, "a`b", "`a``b`"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.dialect.QuoteIdent(tt.ident); got != tt.want {
+				t.Errorf("QuoteIdent(%q) = %s, want %s", tt.ident, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDialectPlaceholder(t *testing.T) {
+	tests := []struct {
+		dialect sqlDialect
+		n       int
+		want    string
+	}{
+		{postgresDialect{}, 1, "$1"},
+		{postgresDialect{}, 12, "$12"},
+		{mysqlDialect{}, 3, "?"},
+		{sqliteDialect{}, 3, "?"},
+	}
+	for _, tt := range tests {
+		if got := tt.dialect.Placeholder(tt.n); got != tt.want {
+			t.Errorf("%s Placeholder(%d) = %s, want %s", tt.dialect.Name(), tt.n, got, tt.want)
+		}
+	}
+}
+
+func TestPostgresColumnsQuery(t *testing.T) {
+	tests := []struct {
+		name      string
+		schema    string
+		table     string
+		wantQuery string
+		wantArgs  []interface{}
+	}{
+		{
+			name:      "未指定模式时使用 current_schema",
+			table:     "robot_nodes",
+			wantQuery: "SELECT column_name, data_type FROM information_schema.columns WHERE table_schema = current_schema() AND table_name = $1 ORDER BY ordinal_position",
+			wantArgs:  []interface{}{"robot_nodes"},
+		},
+		{
+			name:      "指定模式",
+			schema:    "warehouse",
+			table:     "robot_nodes",
+			wantQuery: "SELECT column_name, data_type FROM information_schema.columns WHERE table_schema = $1 AND table_name = $2 ORDER BY ordinal_position",
+			wantArgs:  []interface{}{"warehouse", "robot_nodes"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query, args := postgresDialect{}.ColumnsQuery(tt.schema, tt.table)
+			if query != tt.wantQuery {
+				t.Errorf("query = %s, want %s", query, tt.wantQuery)
+			}
+			if !reflect.DeepEqual(args, tt.wantArgs) {
+				t.Errorf("args = %v, want %v", args, tt.wantArgs)
+			}
+		})
+	}
+}
+
+func TestDialectFor(t *testing.T) {
+	tests := []struct {
+		dbType  string
+		want    string
+		wantErr bool
+	}{
+		{"postgres", "postgres", false},
+		{"PostgreSQL", "postgres", false},
+		{"mysql", "mysql", false},
+		{"sqlite3", "sqlite", false},
+		{"oracle", "", true},
+	}
+	for _, tt := range tests {
+		d, err := dialectFor(tt.dbType)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("dialectFor(%q) error = %v, wantErr %v", tt.dbType, err, tt.wantErr)
+			continue
+		}
+		if err == nil && d.Name() != tt.want {
+			t.Errorf("dialectFor(%q) = %s, want %s", tt.dbType, d.Name(), tt.want)
+		}
+	}
+}
+
+// testExternalTable 不连接数据库、直接给定列的外部表
+func testExternalTable(d sqlDialect, schema, name string, columns ...string) *externalTable {
+	t := &externalTable{dialect: d, schema: schema, name: name, types: map[string]string{}, lookup: map[string]string{}}
+	for _, c := range columns {
+		t.columns = append(t.columns, c)
+		t.lookup[strings.ToLower(c)] = c
+	}
+	return t
+}
+
+func TestSelectQuery(t *testing.T) {
+	tests := []struct {
+		name        string
+		dialect     sqlDialect
+		schema      string
+		columns     []string
+		filter      []domain.TableCondition
+		order       []domain.TableOrder
+		wantQuery   string
+		wantDisplay string
+		wantArgs    []interface{}
+		wantErr     string
+	}{
+		{
+			name:      "未指定排序时按ID列排序",
+			dialect:   postgresDialect{},
+			columns:   []string{"nodeid", "name"},
+			wantQuery: `SELECT "NodeID", "Name" FROM "robot nodes" ORDER BY "NodeID"`,
+		},
+		{
+			name:        "PostgreSQL 占位符按顺序编号",
+			dialect:     postgresDialect{},
+			schema:      "warehouse",
+			columns:     []string{"nodeid"},
+			filter:      []domain.TableCondition{{Column: "x", Operator: "ge", Value: 1.5}, {Column: "name", Operator: "in", Value: []interface{}{"a", "b"}}, {Column: "name", Operator: "like", Value: "dock%"}},
+			order:       []domain.TableOrder{{Column: "x", Desc: true}},
+			wantQuery:   `SELECT "NodeID" FROM "warehouse"."robot nodes" WHERE "X" >= $1 AND "Name" IN ($2, $3) AND "Name" LIKE $4 ORDER BY "X" DESC`,
+			wantDisplay: `SELECT "NodeID" FROM "warehouse"."robot nodes" WHERE "X" >= 1.5 AND "Name" IN ('a', 'b') AND "Name" LIKE 'dock%' ORDER BY "X" DESC`,
+			wantArgs:    []interface{}{1.5, "a", "b", "dock%"},
+		},
+		{
+			name:      "MySQL 使用问号和反引号",
+			dialect:   mysqlDialect{},
+			columns:   []string{"NodeID"},
+			filter:    []domain.TableCondition{{Column: "name", Operator: "ne", Value: "x"}, {Column: "x", Operator: "is_null"}, {Column: "nodeid", Operator: "not_null"}},
+			wantQuery: "SELECT `NodeID` FROM `robot nodes` WHERE `Name` <> ? AND `X` IS NULL AND `NodeID` IS NOT NULL ORDER BY `NodeID`",
+			wantArgs:  []interface{}{"x"},
+		},
+		{
+			name:        "值中的引号只作为参数",
+			dialect:     sqliteDialect{},
+			columns:     []string{"NodeID"},
+			filter:      []domain.TableCondition{{Column: "name", Operator: "eq", Value: "x' OR '1'='1"}},
+			wantQuery:   `SELECT "NodeID" FROM "robot nodes" WHERE "Name" = ? ORDER BY "NodeID"`,
+			wantDisplay: `SELECT "NodeID" FROM "robot nodes" WHERE "Name" = 'x'' OR ''1''=''1' ORDER BY "NodeID"`,
+			wantArgs:    []interface{}{"x' OR '1'='1"},
+		},
+		{
+			name:      "未知列名加引号后作为标识符",
+			dialect:   postgresDialect{},
+			columns:   []string{`x" FROM pg_shadow --`},
+			wantQuery: `SELECT "x"" FROM pg_shadow --" FROM "robot nodes" ORDER BY "NodeID"`,
+		},
+		{
+			name:    "运算符不在白名单中",
+			dialect: postgresDialect{},
+			columns: []string{"NodeID"},
+			filter:  []domain.TableCondition{{Column: "x", Operator: "= 1 OR 1 =", Value: 1}},
+			wantErr: "运算符无效",
+		},
+		{
+			name:    "in 需要非空数组",
+			dialect: postgresDialect{},
+			columns: []string{"NodeID"},
+			filter:  []domain.TableCondition{{Column: "name", Operator: "in", Value: []interface{}{}}},
+			wantErr: "非空数组",
+		},
+		{
+			name:    "比较运算需要单个值",
+			dialect: postgresDialect{},
+			columns: []string{"NodeID"},
+			filter:  []domain.TableCondition{{Column: "name", Operator: "eq", Value: []interface{}{"a"}}},
+			wantErr: "单个值",
+		},
+		{
+			name:    "比较运算不接受空值",
+			dialect: postgresDialect{},
+			columns: []string{"NodeID"},
+			filter:  []domain.TableCondition{{Column: "name", Operator: "lt"}},
+			wantErr: "单个值",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			table := testExternalTable(tt.dialect, tt.schema, "robot nodes", "NodeID", "Name", "X")
+			b, err := table.selectQuery(tt.columns, tt.filter, tt.order, "nodeid")
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("selectQuery() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("selectQuery() error = %v", err)
+			}
+			if got := b.query.String(); got != tt.wantQuery {
+				t.Errorf("query = %s\nwant    %s", got, tt.wantQuery)
+			}
+			if tt.wantDisplay != "" && b.display.String() != tt.wantDisplay {
+				t.Errorf("display = %s\nwant      %s", b.display.String(), tt.wantDisplay)
+			}
+			if !reflect.DeepEqual(b.args, tt.wantArgs) {
+				t.Errorf("args = %v, want %v", b.args, tt.wantArgs)
+			}
+		})
+	}
+}
+
+func TestCheckIdentifier(t *testing.T) {
+	tests := []struct {
+		name    string
+		ident   string
+		wantErr bool
+	}{
+		{"普通", "robot_nodes", false},
+		{"空格和引号由引号处理", `robot "nodes"`, false},
+		{"中文", "节点", false},
+		{"空", "", true},
+		{"空白", "  ", true},
+		{"NUL", "a\x00b", true},
+		{"过长", strings.Repeat("a", 129), true},
+		{"最大长度", strings.Repeat("a", 128), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := checkIdentifier(tt.ident); (err != nil) != tt.wantErr {
+				t.Errorf("checkIdentifier(%q) error = %v, wantErr %v", tt.ident, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCheckTableQuery(t *testing.T) {
+	tests := []struct {
+		name    string
+		filter  []domain.TableCondition
+		order   []domain.TableOrder
+		wantErr bool
+	}{
+		{"空", nil, nil, false},
+		{"白名单中的全部运算符", func() []domain.TableCondition {
+			var filter []domain.TableCondition
+			for op := range sqlOperators {
+				filter = append(filter, domain.TableCondition{Column: "c", Operator: op})
+			}
+			return filter
+		}(), nil, false},
+		{"运算符大小写敏感", []domain.TableCondition{{Column: "c", Operator: "EQ"}}, nil, true},
+		{"未知运算符", []domain.TableCondition{{Column: "c", Operator: "between"}}, nil, true},
+		{"SQL 片段作为运算符", []domain.TableCondition{{Column: "c", Operator: " = "}}, nil, true},
+		{"缺少列名", []domain.TableCondition{{Operator: "eq"}}, nil, true},
+		{"排序缺少列名", nil, []domain.TableOrder{{Desc: true}}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := checkTableQuery(tt.filter, tt.order); (err != nil) != tt.wantErr {
+				t.Errorf("checkTableQuery() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestExternalTableCheckColumns(t *testing.T) {
+	table := testExternalTable(postgresDialect{}, "", "robot_nodes", "NodeID", "Name")
+	tests := []struct {
+		name    string
+		columns []string
+		wantErr string
+	}{
+		{"大小写不敏感", []string{"nodeid", "NAME"}, ""},
+		{"空列名忽略", []string{"", "name"}, ""},
+		{"列出全部无效列", []string{"x", "name", "y"}, `没有列 "x"; 表 robot_nodes 中没有列 "y"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := table.checkColumns(tt.columns...)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("checkColumns() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("checkColumns() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,382 @@
+// Package services 外部数据库连接串和连接
+//
+//   - 连接属性（Properties）指定 TLS 模式、客户端证书和超时等选项，每种方言只接受固定的属性：
+//     创建、更新时提交不支持的属性直接报错；已保存的旧属性在连接时忽略并记录警告，不影响连接
+//   - MySQL：tls 为 false（默认）、true、skip-verify、preferred；指定 tls_ca、tls_cert、tls_key 时注册自定义 TLS 配置，
+//     名称包含连接ID和配置内容的摘要，内容不变时只注册一次
+//   - PostgreSQL：sslmode（默认 prefer）、sslrootcert、sslcert、sslkey、sslpassword 等原样传给 pgx
+//   - 证书和私钥属性是证书目录（security.certificate_dir）中的相对路径，连接前解析为绝对路径，不能读取目录之外的文件
+//   - SQLite 的数据库名是 SQLite 目录（security.sqlite_dir）中已存在文件的相对路径，不能打开应用自身的数据库
+//   - sslpassword 等敏感属性与密码一样加密保存、不在接口中返回
+package services
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"robot-path-editor/internal/domain"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/sirupsen/logrus"
+)
+
+// mysqlProperties MySQL 连接支持的属性
+var mysqlProperties = []string{"tls", "tls_ca", "tls_cert", "tls_key", "tls_server_name", "timeout"}
+
+// postgresProperties PostgreSQL 连接支持的属性，原样作为连接参数
+var postgresProperties = []string{
+	"sslmode", "sslrootcert", "sslcert", "sslkey", "sslpassword", "sslsni",
+	"connect_timeout", "application_name", "target_session_attrs",
+}
+
+// certificateProperties 值为证书或私钥文件的连接属性
+var certificateProperties = []string{"tls_ca", "tls_cert", "tls_key", "sslrootcert", "sslcert", "sslkey"}
+
+// postgresSSLModes libpq 的 sslmode 取值
+var postgresSSLModes = []string{"disable", "allow", "prefer", "require", "verify-ca", "verify-full"}
+
+func (mysqlDialect) ConnectionProperties() []string    { return mysqlProperties }
+func (sqliteDialect) ConnectionProperties() []string   { return nil }
+func (postgresDialect) ConnectionProperties() []string { return postgresProperties }
+
+// unknownConnectionProperties 方言不支持的连接属性，按名称排序
+func unknownConnectionProperties(d sqlDialect, conn *domain.DatabaseConnection) []string {
+	var unknown []string
+	for k := range conn.Properties {
+		if !containsValue(d.ConnectionProperties(), k) {
+			unknown = append(unknown, k)
+		}
+	}
+	sort.Strings(unknown)
+	return unknown
+}
+
+// checkConnectionProperties 检查提交的连接属性都受方言支持
+func checkConnectionProperties(d sqlDialect, conn *domain.DatabaseConnection) error {
+	unknown := unknownConnectionProperties(d, conn)
+	if len(unknown) == 0 {
+		return nil
+	}
+	if len(d.ConnectionProperties()) == 0 {
+		return fmt.Errorf("%s 连接不支持连接属性: %s", d.Name(), strings.Join(unknown, ", "))
+	}
+	return fmt.Errorf("%s 连接不支持连接属性: %s（支持 %s）", d.Name(), strings.Join(unknown, ", "), strings.Join(d.ConnectionProperties(), ", "))
+}
+
+// ExternalConnector 连接外部数据库：校验连接配置，把证书属性和 SQLite 文件解析到配置的目录中后生成连接串
+type ExternalConnector struct {
+	certificateDir string
+	sqliteDir      string
+	appDatabase    string // 应用自身的 SQLite 连接串，外部连接不能打开该文件
+}
+
+// NewExternalConnector 创建连接器：certificateDir 为空时不能使用证书文件，sqliteDir 为空时不能使用 SQLite 连接；
+// appDatabase 为应用使用 SQLite 时自身的连接串，其他数据库传空
+func NewExternalConnector(certificateDir, sqliteDir, appDatabase string) *ExternalConnector {
+	return &ExternalConnector{certificateDir: certificateDir, sqliteDir: sqliteDir, appDatabase: appDatabase}
+}
+
+// open 按方言连接到外部数据库，调用方负责关闭
+func (c *ExternalConnector) open(conn *domain.DatabaseConnection) (*sql.DB, error) {
+	dialect, err := dialectFor(conn.Type)
+	if err != nil {
+		return nil, err
+	}
+	if conn.SecretError != "" {
+		return nil, fmt.Errorf("连接的密码无法解密，请重新填写: %s", conn.SecretError)
+	}
+	if !containsValue(sql.Drivers(), dialect.DriverName()) {
+		return nil, fmt.Errorf("未注册 %s 数据库驱动", dialect.Name())
+	}
+	if unknown := unknownConnectionProperties(dialect, conn); len(unknown) > 0 {
+		logrus.WithFields(logrus.Fields{"connection": conn.ID, "properties": unknown}).Warn("忽略不支持的连接属性")
+	}
+
+	dsn, err := c.dsn(dialect, conn)
+	if err != nil {
+		return nil, err
+	}
+	db, err := sql.Open(dialect.DriverName(), dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	// 测试连接
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return db, nil
+}
+
+// check 检查数据库类型和连接属性（TLS 模式、证书文件等）能生成连接串；
+// checkProperties 为 true 时（属性由请求提交）不支持的属性也报错
+func (c *ExternalConnector) check(conn *domain.DatabaseConnection, checkProperties bool) error {
+	dialect, err := dialectFor(conn.Type)
+	if err != nil {
+		return err
+	}
+	if checkProperties {
+		if err := checkConnectionProperties(dialect, conn); err != nil {
+			return fmt.Errorf("连接配置无效: %w", err)
+		}
+	}
+	if _, err := c.dsn(dialect, conn); err != nil {
+		return fmt.Errorf("连接配置无效: %w", err)
+	}
+	return nil
+}
+
+// dsn 把证书属性和 SQLite 数据库文件替换为配置目录中的绝对路径后生成连接串，不修改 conn
+func (c *ExternalConnector) dsn(dialect sqlDialect, conn *domain.DatabaseConnection) (string, error) {
+	resolved := *conn
+	if _, ok := dialect.(sqliteDialect); ok {
+		path, err := c.sqlitePath(conn.Database)
+		if err != nil {
+			return "", fmt.Errorf("数据库文件: %w", err)
+		}
+		resolved.Database = path
+	}
+	resolved.Properties = make(map[string]string, len(conn.Properties))
+	for k, v := range conn.Properties {
+		if v != "" && containsValue(certificateProperties, k) && containsValue(dialect.ConnectionProperties(), k) {
+			path, err := c.certificatePath(v)
+			if err != nil {
+				return "", fmt.Errorf("连接属性 %s: %w", k, err)
+			}
+			v = path
+		}
+		resolved.Properties[k] = v
+	}
+	return dialect.DSN(&resolved)
+}
+
+// certificatePath 解析证书目录中的文件
+func (c *ExternalConnector) certificatePath(name string) (string, error) {
+	if c.certificateDir == "" {
+		return "", fmt.Errorf("未配置证书目录（security.certificate_dir），不能使用证书文件")
+	}
+	return confinedPath(c.certificateDir, "证书目录", name)
+}
+
+// sqlitePath 解析 SQLite 目录中已存在的数据库文件，拒绝应用自身的数据库及其日志文件
+func (c *ExternalConnector) sqlitePath(name string) (string, error) {
+	if c.sqliteDir == "" {
+		return "", fmt.Errorf("未配置 SQLite 目录（security.sqlite_dir），不能使用 SQLite 连接")
+	}
+	path, err := confinedPath(c.sqliteDir, "SQLite 目录", name)
+	if err != nil {
+		return "", err
+	}
+	info, err := os.Stat(path)
+	if err != nil || !info.Mode().IsRegular() {
+		return "", fmt.Errorf("%s 不是数据库文件", name)
+	}
+	if c.isAppDatabase(path, info) {
+		return "", fmt.Errorf("不能打开应用自身的数据库")
+	}
+	return path, nil
+}
+
+// isAppDatabase path 是否为应用自身的 SQLite 数据库文件，或其 -wal、-shm、-journal 文件
+func (c *ExternalConnector) isAppDatabase(path string, info os.FileInfo) bool {
+	app := sqliteFileName(c.appDatabase)
+	if app == "" {
+		return false
+	}
+	if appInfo, err := os.Stat(app); err == nil && os.SameFile(info, appInfo) {
+		return true
+	}
+	if abs, err := filepath.Abs(app); err == nil {
+		app = abs
+	}
+	if resolved, err := filepath.EvalSymlinks(app); err == nil {
+		app = resolved
+	}
+	return path == app || strings.HasPrefix(path, app+"-")
+}
+
+// sqliteFileName 取 SQLite 连接串中的文件路径，内存数据库返回空
+func sqliteFileName(dsn string) string {
+	name := strings.TrimPrefix(dsn, "file:")
+	if i := strings.IndexByte(name, '?'); i >= 0 {
+		name = name[:i]
+	}
+	if name == ":memory:" {
+		return ""
+	}
+	return name
+}
+
+// confinedPath 解析 dir 中的文件；拒绝绝对路径、.. 和指向目录之外的符号链接
+func confinedPath(dir, label, name string) (string, error) {
+	if !filepath.IsLocal(name) {
+		return "", fmt.Errorf("%s 必须是%s中的相对路径", name, label)
+	}
+	dir, err := filepath.Abs(dir)
+	if err == nil {
+		dir, err = filepath.EvalSymlinks(dir)
+	}
+	if err != nil {
+		return "", fmt.Errorf("%s不可用: %w", label, err)
+	}
+	path, err := filepath.EvalSymlinks(filepath.Join(dir, name))
+	if err != nil {
+		return "", fmt.Errorf("%s中没有文件 %s", label, name)
+	}
+	if rel, err := filepath.Rel(dir, path); err != nil || !filepath.IsLocal(rel) {
+		return "", fmt.Errorf("%s 不在%s中", name, label)
+	}
+	return path, nil
+}
+
+// DSN 用驱动的配置结构生成，用户名、密码中的特殊字符不影响解析
+func (mysqlDialect) DSN(conn *domain.DatabaseConnection) (string, error) {
+	cfg := mysql.NewConfig()
+	cfg.User = conn.Username
+	cfg.Passwd = conn.Password
+	cfg.Net = "tcp"
+	cfg.Addr = net.JoinHostPort(conn.Host, strconv.Itoa(conn.Port))
+	cfg.DBName = conn.Database
+	cfg.ParseTime = true
+	cfg.Loc = time.Local
+	cfg.Params = map[string]string{"charset": "utf8mb4"}
+
+	if v := conn.Properties["timeout"]; v != "" {
+		timeout, err := time.ParseDuration(v)
+		if err != nil {
+			return "", fmt.Errorf("连接属性 timeout 无效: %w", err)
+		}
+		cfg.Timeout = timeout
+	}
+	if err := applyMySQLTLS(cfg, conn); err != nil {
+		return "", err
+	}
+	return cfg.FormatDSN(), nil
+}
+
+// mysqlTLSConfigs 已注册的 TLS 配置名称。驱动的注册表是全局的，名称由连接ID和配置内容的摘要组成，
+// 同一版本只注册一次，并发连接不会互相覆盖；配置变化时注册新名称，旧名称保留给仍在使用的连接
+var mysqlTLSConfigs = struct {
+	sync.Mutex
+	registered map[string]bool
+}{registered: make(map[string]bool)}
+
+// applyMySQLTLS 设置 DSN 的 tls 参数；指定了证书时注册自定义 TLS 配置，证书路径已由 ExternalConnector 解析
+func applyMySQLTLS(cfg *mysql.Config, conn *domain.DatabaseConnection) error {
+	mode := strings.ToLower(conn.Properties["tls"])
+	switch mode {
+	case "", "false", "true", "skip-verify", "preferred":
+	default:
+		return fmt.Errorf("连接属性 tls 无效: %s（可选 false、true、skip-verify、preferred）", mode)
+	}
+	ca, cert, key := conn.Properties["tls_ca"], conn.Properties["tls_cert"], conn.Properties["tls_key"]
+	if ca == "" && cert == "" && key == "" {
+		cfg.TLSConfig = mode
+		return nil
+	}
+	if mode == "false" {
+		return fmt.Errorf("指定了证书时连接属性 tls 不能为 false")
+	}
+	if (cert == "") != (key == "") {
+		return fmt.Errorf("客户端证书 tls_cert 和私钥 tls_key 需要同时指定")
+	}
+
+	config := &tls.Config{
+		ServerName:         conn.Host,
+		InsecureSkipVerify: mode == "skip-verify",
+	}
+	if name := conn.Properties["tls_server_name"]; name != "" {
+		config.ServerName = name
+	}
+	digest := sha256.New()
+	fmt.Fprintf(digest, "%s\x00%s\x00", mode, config.ServerName)
+	if ca != "" {
+		pem, err := os.ReadFile(ca)
+		if err != nil {
+			return fmt.Errorf("读取CA证书失败: %w", err)
+		}
+		config.RootCAs = x509.NewCertPool()
+		if !config.RootCAs.AppendCertsFromPEM(pem) {
+			return fmt.Errorf("CA证书 %s 中没有有效的 PEM 证书", filepath.Base(ca))
+		}
+		digest.Write(pem)
+	}
+	digest.Write([]byte{0})
+	if cert != "" {
+		certPEM, err := os.ReadFile(cert)
+		if err != nil {
+			return fmt.Errorf("读取客户端证书失败: %w", err)
+		}
+		keyPEM, err := os.ReadFile(key)
+		if err != nil {
+			return fmt.Errorf("读取客户端私钥失败: %w", err)
+		}
+		pair, err := tls.X509KeyPair(certPEM, keyPEM)
+		if err != nil {
+			return fmt.Errorf("加载客户端证书失败: %w", err)
+		}
+		config.Certificates = []tls.Certificate{pair}
+		digest.Write(certPEM)
+		digest.Write([]byte{0})
+		digest.Write(keyPEM)
+	}
+
+	name := "conn-" + conn.ID + "-" + hex.EncodeToString(digest.Sum(nil)[:6])
+	mysqlTLSConfigs.Lock()
+	defer mysqlTLSConfigs.Unlock()
+	if !mysqlTLSConfigs.registered[name] {
+		if err := mysql.RegisterTLSConfig(name, config); err != nil {
+			return fmt.Errorf("注册 TLS 配置失败: %w", err)
+		}
+		mysqlTLSConfigs.registered[name] = true
+	}
+	cfg.TLSConfig = name
+	// 自定义配置的名称占用了 tls 参数，preferred 改由 allowFallbackToPlaintext 表示
+	cfg.AllowFallbackToPlaintext = mode == "preferred"
+	return nil
+}
+
+// DSN SQLite 连接串为数据库文件的 file: URI，mode=rw 只打开已存在的文件；不支持连接属性
+func (sqliteDialect) DSN(conn *domain.DatabaseConnection) (string, error) {
+	return (&url.URL{Scheme: "file", Path: conn.Database, RawQuery: "mode=rw"}).String(), nil
+}
+
+// DSN 使用 URL 形式，用户名和密码中的特殊字符会被转义；sslmode 默认 prefer（服务器支持时使用 TLS），其余支持的属性原样作为连接参数
+func (postgresDialect) DSN(conn *domain.DatabaseConnection) (string, error) {
+	params := url.Values{"sslmode": {"prefer"}}
+	for _, k := range postgresProperties {
+		if v := conn.Properties[k]; v != "" {
+			params.Set(k, v)
+		}
+	}
+	if !containsValue(postgresSSLModes, params.Get("sslmode")) {
+		return "", fmt.Errorf("连接属性 sslmode 无效: %s（可选 %s）", params.Get("sslmode"), strings.Join(postgresSSLModes, "、"))
+	}
+	if (params.Get("sslcert") == "") != (params.Get("sslkey") == "") {
+		return "", fmt.Errorf("客户端证书 sslcert 和私钥 sslkey 需要同时指定")
+	}
+
+	u := url.URL{
+		Scheme:   "postgres",
+		User:     url.UserPassword(conn.Username, conn.Password),
+		Host:     net.JoinHostPort(conn.Host, strconv.Itoa(conn.Port)),
+		Path:     "/" + conn.Database,
+		RawQuery: params.Encode(),
+	}
+	return u.String(), nil
+}
@@ -0,0 +1,374 @@
+package services
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"database/sql"
+	"encoding/pem"
+	"math/big"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/jackc/pgx/v5"
+
+	"robot-path-editor/internal/domain"
+)
+
+func TestPostgresDSN(t *testing.T) {
+	base := domain.DatabaseConnection{
+		Host:     "db.example.com",
+		Port:     5433,
+		Database: "robots",
+		Username: "robot",
+		Password: "secret",
+	}
+
+	tests := []struct {
+		name       string
+		username   string
+		password   string
+		properties map[string]string
+		wantErr    string
+		wantParams map[string]string // 连接串 URL 中的参数，空值表示不应出现
+	}{
+		{
+			name:       "默认 sslmode 为 prefer",
+			wantParams: map[string]string{"sslmode": "prefer"},
+		},
+		{
+			name:     "用户名和密码中的特殊字符被转义",
+			username: "ro@bot:1",
+			password: "p@ss:w/rd?#%&= 中文",
+		},
+		{
+			name:       "支持的属性写入连接参数",
+			properties: map[string]string{"sslmode": "require", "application_name": "path editor", "connect_timeout": "5"},
+			wantParams: map[string]string{"sslmode": "require", "application_name": "path editor", "connect_timeout": "5"},
+		},
+		{
+			name:       "不支持的属性被忽略",
+			properties: map[string]string{"options": "-c search_path=evil", "host": "attacker"},
+			wantParams: map[string]string{"sslmode": "prefer", "options": "", "host": ""},
+		},
+		{
+			name:       "sslmode 无效",
+			properties: map[string]string{"sslmode": "always"},
+			wantErr:    "sslmode 无效",
+		},
+		{
+			name:       "sslcert 缺少 sslkey",
+			properties: map[string]string{"sslmode": "verify-full", "sslcert": "/certs/client.pem"},
+			wantErr:    "需要同时指定",
+		},
+		{
+			name:       "sslkey 缺少 sslcert",
+			properties: map[string]string{"sslkey": "/certs/client.key"},
+			wantErr:    "需要同时指定",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn := base
+			conn.Properties = tt.properties
+			if tt.username != "" {
+				conn.Username = tt.username
+			}
+			if tt.password != "" {
+				conn.Password = tt.password
+			}
+
+			dsn, err := postgresDialect{}.DSN(&conn)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("DSN() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("DSN() error = %v", err)
+			}
+
+			// 用 pgx 解析，确认特殊字符不会破坏连接串结构
+			cfg, err := pgx.ParseConfig(dsn)
+			if err != nil {
+				t.Fatalf("pgx.ParseConfig(%q) error = %v", dsn, err)
+			}
+			if cfg.Host != conn.Host || cfg.Port != uint16(conn.Port) || cfg.Database != conn.Database {
+				t.Errorf("地址 = %s:%d/%s, want %s:%d/%s", cfg.Host, cfg.Port, cfg.Database, conn.Host, conn.Port, conn.Database)
+			}
+			if cfg.User != conn.Username || cfg.Password != conn.Password {
+				t.Errorf("用户 = %q/%q, want %q/%q", cfg.User, cfg.Password, conn.Username, conn.Password)
+			}
+
+			params := parseDSNQuery(t, dsn)
+			for k, want := range tt.wantParams {
+				if got := params[k]; got != want {
+					t.Errorf("参数 %s = %q, want %q", k, got, want)
+				}
+			}
+		})
+	}
+}
+
+// parseDSNQuery 取 URL 形式连接串的查询参数
+func parseDSNQuery(t *testing.T, dsn string) map[string]string {
+	t.Helper()
+	u, err := url.Parse(dsn)
+	if err != nil {
+		t.Fatalf("url.Parse(%q) error = %v", dsn, err)
+	}
+	params := make(map[string]string)
+	for k := range u.Query() {
+		params[k] = u.Query().Get(k)
+	}
+	return params
+}
+
+func TestCheckConnectionProperties(t *testing.T) {
+	tests := []struct {
+		name       string
+		dialect    sqlDialect
+		properties map[string]string
+		wantErr    bool
+	}{
+		{"PostgreSQL 支持的属性", postgresDialect{}, map[string]string{"sslmode": "require", "sslsni": "1"}, false},
+		{"PostgreSQL 不支持的属性", postgresDialect{}, map[string]string{"options": "-c x=y"}, true},
+		{"MySQL 属性不能用于 PostgreSQL", postgresDialect{}, map[string]string{"tls": "true"}, true},
+		{"SQLite 不支持属性", sqliteDialect{}, map[string]string{"sslmode": "disable"}, true},
+		{"没有属性", sqliteDialect{}, nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkConnectionProperties(tt.dialect, &domain.DatabaseConnection{Properties: tt.properties})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("checkConnectionProperties() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestExternalConnectorCheck(t *testing.T) {
+	legacy := map[string]string{"sslmode": "require", "options": "-c search_path=legacy"}
+
+	tests := []struct {
+		name            string
+		properties      map[string]string
+		checkProperties bool
+		wantErr         bool
+	}{
+		{name: "提交不支持的属性", properties: legacy, checkProperties: true, wantErr: true},
+		{name: "保留的旧属性被忽略", properties: legacy},
+		{name: "提交支持的属性", properties: map[string]string{"sslmode": "require"}, checkProperties: true},
+		{name: "旧属性仍需能生成连接串", properties: map[string]string{"sslmode": "always"}, wantErr: true},
+		{name: "证书需要配置证书目录", properties: map[string]string{"sslrootcert": "ca.pem"}, checkProperties: true, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn := &domain.DatabaseConnection{
+				Type:       "postgres",
+				Host:       "db.example.com",
+				Port:       5432,
+				Database:   "robots",
+				Properties: tt.properties,
+			}
+			err := NewExternalConnector("", "", "").check(conn, tt.checkProperties)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("check() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCertificatePath(t *testing.T) {
+	root := t.TempDir()
+	dir := filepath.Join(root, "certs")
+	for _, name := range []string{filepath.Join(dir, "client", "client.pem"), filepath.Join(root, "outside.pem")} {
+		if err := os.MkdirAll(filepath.Dir(name), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(name, []byte("pem"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Symlink(filepath.Join(root, "outside.pem"), filepath.Join(dir, "escape.pem")); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		dir     string
+		file    string
+		wantErr string
+	}{
+		{name: "目录中的文件", dir: dir, file: "client/client.pem"},
+		{name: "未配置证书目录", file: "client/client.pem", wantErr: "未配置证书目录"},
+		{name: "绝对路径", dir: dir, file: filepath.Join(root, "outside.pem"), wantErr: "相对路径"},
+		{name: "上级目录", dir: dir, file: "../outside.pem", wantErr: "相对路径"},
+		{name: "指向目录外的符号链接", dir: dir, file: "escape.pem", wantErr: "不在证书目录中"},
+		{name: "文件不存在", dir: dir, file: "missing.pem", wantErr: "没有文件"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path, err := NewExternalConnector(tt.dir, "", "").certificatePath(tt.file)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("certificatePath(%q) error = %v, want %q", tt.file, err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("certificatePath(%q) error = %v", tt.file, err)
+			}
+			want, _ := filepath.EvalSymlinks(filepath.Join(dir, tt.file))
+			if path != want {
+				t.Errorf("certificatePath(%q) = %q, want %q", tt.file, path, want)
+			}
+		})
+	}
+}
+
+func TestApplyMySQLTLSRegistersOnce(t *testing.T) {
+	ca := filepath.Join(t.TempDir(), "ca.pem")
+	writeTestCertificate(t, ca)
+
+	conn := &domain.DatabaseConnection{ID: "mysql-tls", Host: "db.example.com", Properties: map[string]string{"tls": "true", "tls_ca": ca}}
+	name := func() string {
+		cfg := mysql.NewConfig()
+		if err := applyMySQLTLS(cfg, conn); err != nil {
+			t.Fatalf("applyMySQLTLS() error = %v", err)
+		}
+		return cfg.TLSConfig
+	}
+
+	first := name()
+	if second := name(); second != first {
+		t.Errorf("配置不变时 TLS 配置名 = %q, want %q", second, first)
+	}
+	conn.Properties["tls_server_name"] = "replica.example.com"
+	if changed := name(); changed == first {
+		t.Errorf("配置变化后 TLS 配置名仍为 %q", changed)
+	}
+}
+
+// writeTestCertificate 写入自签名的 PEM 证书
+func writeTestCertificate(t *testing.T, path string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "test ca"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		IsCA:         true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSQLitePath(t *testing.T) {
+	root := t.TempDir()
+	dir := filepath.Join(root, "sqlite")
+	appDB := filepath.Join(dir, "data.db")
+	for _, name := range []string{filepath.Join(dir, "site", "robots.db"), appDB, appDB + "-wal", filepath.Join(root, "outside.db")} {
+		if err := os.MkdirAll(filepath.Dir(name), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(name, nil, 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Symlink(appDB, filepath.Join(dir, "alias.db")); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		dir     string
+		app     string
+		file    string
+		wantErr string
+	}{
+		{name: "目录中的文件", dir: dir, app: appDB, file: "site/robots.db"},
+		{name: "未配置 SQLite 目录", file: "site/robots.db", wantErr: "未配置 SQLite 目录"},
+		{name: "绝对路径", dir: dir, file: filepath.Join(root, "outside.db"), wantErr: "相对路径"},
+		{name: "上级目录", dir: dir, file: "../outside.db", wantErr: "相对路径"},
+		{name: "文件不存在时不创建", dir: dir, file: "new.db", wantErr: "没有文件"},
+		{name: "目录不是数据库文件", dir: dir, file: "site", wantErr: "不是数据库文件"},
+		{name: "应用自身的数据库", dir: dir, app: appDB, file: "data.db", wantErr: "应用自身的数据库"},
+		{name: "应用数据库的 URI 连接串", dir: dir, app: "file:" + appDB + "?cache=shared", file: "data.db", wantErr: "应用自身的数据库"},
+		{name: "指向应用数据库的符号链接", dir: dir, app: appDB, file: "alias.db", wantErr: "应用自身的数据库"},
+		{name: "应用数据库的 WAL 文件", dir: dir, app: appDB, file: "data.db-wal", wantErr: "应用自身的数据库"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path, err := NewExternalConnector("", tt.dir, tt.app).sqlitePath(tt.file)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("sqlitePath(%q) error = %v, want %q", tt.file, err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("sqlitePath(%q) error = %v", tt.file, err)
+			}
+			want, _ := filepath.EvalSymlinks(filepath.Join(dir, tt.file))
+			if path != want {
+				t.Errorf("sqlitePath(%q) = %q, want %q", tt.file, path, want)
+			}
+		})
+	}
+}
+
+func TestExternalConnectorOpenSQLite(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "sqlite dir")
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	name := "robots #1.db"
+	seed, err := sql.Open("sqlite3", filepath.Join(dir, name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := seed.Exec(`CREATE TABLE nodes (id TEXT PRIMARY KEY)`); err != nil {
+		t.Fatal(err)
+	}
+	seed.Close()
+
+	connector := NewExternalConnector("", dir, "")
+	db, err := connector.open(&domain.DatabaseConnection{Type: "sqlite", Database: name})
+	if err != nil {
+		t.Fatalf("open() error = %v", err)
+	}
+	defer db.Close()
+	var count int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM nodes`).Scan(&count); err != nil {
+		t.Errorf("查询已有的表失败: %v", err)
+	}
+
+	if _, err := connector.open(&domain.DatabaseConnection{Type: "sqlite", Database: "missing.db"}); err == nil {
+		t.Error("open(missing.db) error = nil, want error")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "missing.db")); !os.IsNotExist(err) {
+		t.Errorf("打开不存在的数据库后创建了文件: %v", err)
+	}
+}
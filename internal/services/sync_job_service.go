@@ -0,0 +1,613 @@
+// Package services 后台同步任务
+//
+// 设计参考：
+// - Kubernetes CronJob：任务定义与运行记录分离，同一任务不并发运行，只保留最近的运行记录
+// - Kubernetes Controller 的调谐循环：每轮从数据库读取任务，派发到期的任务，睡到最近的下次运行时间
+//
+// 特点：
+// - 任务按 cron 表达式或轮询间隔运行，有水位列时增量拉取，否则全量拉取；与上次同步一致的行跳过
+// - 下次运行时间在派发前写入数据库，服务停机期间错过的运行在启动后补跑一次
+// - 手动触发不受暂停影响；暂停不会中断正在进行的运行
+// - 随应用启动和停止：Stop 取消正在进行的运行并等待记录结果，启动时把上次未结束的运行记为失败
+// - 正在进行的运行可以取消和订阅进度；取消时已提交的批和水位保留，运行记为 canceled
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"robot-path-editor/internal/domain"
+	"robot-path-editor/internal/repositories"
+)
+
+// SyncJobService 同步任务服务接口
+type SyncJobService interface {
+	// 任务管理
+	CreateJob(ctx context.Context, req CreateSyncJobRequest) (*domain.SyncJob, error)
+	GetJob(ctx context.Context, id string) (*domain.SyncJob, error)
+	UpdateJob(ctx context.Context, req UpdateSyncJobRequest) (*domain.SyncJob, error)
+	DeleteJob(ctx context.Context, id string) error
+	ListJobs(ctx context.Context) ([]*domain.SyncJob, error)
+
+	// 运行控制
+	TriggerJob(ctx context.Context, id string) (*domain.SyncJobRun, error)
+	PauseJob(ctx context.Context, id string) (*domain.SyncJob, error)
+	ResumeJob(ctx context.Context, id string) (*domain.SyncJob, error)
+	ListJobRuns(ctx context.Context, id string, limit int) ([]*domain.SyncJobRun, error)
+	CancelJob(ctx context.Context, id string) error
+	// WatchJob 订阅正在进行的运行的进度，运行结束时通道关闭；unwatch 提前取消订阅
+	WatchJob(ctx context.Context, id string) (progress <-chan SyncProgress, unwatch func(), err error)
+
+	// 调度器生命周期，随应用启动和停止
+	Start(ctx context.Context) error
+	Stop(ctx context.Context) error
+}
+
+// CreateSyncJobRequest 创建同步任务请求
+type CreateSyncJobRequest struct {
+	Name            string   `json:"name"`
+	MappingID       string   `json:"mapping_id" binding:"required"`
+	Cron            string   `json:"cron,omitempty"`
+	IntervalSeconds int      `json:"interval_seconds,omitempty"`
+	Entities        []string `json:"entities,omitempty"`
+	WatermarkColumn string   `json:"watermark_column,omitempty"`
+	Paused          bool     `json:"paused,omitempty"`
+}
+
+// UpdateSyncJobRequest 更新同步任务请求
+type UpdateSyncJobRequest struct {
+	ID              string   `json:"-"`
+	Name            *string  `json:"name,omitempty"`
+	Cron            *string  `json:"cron,omitempty"` // 指定 cron 时清除轮询间隔，反之亦然
+	IntervalSeconds *int     `json:"interval_seconds,omitempty"`
+	Entities        []string `json:"entities,omitempty"`
+	WatermarkColumn *string  `json:"watermark_column,omitempty"` // 修改后水位清空
+	ResetWatermarks bool     `json:"reset_watermarks,omitempty"` // 清空水位，下次全量拉取
+}
+
+// 运行的触发方式
+const (
+	SyncTriggerSchedule = "schedule"
+	SyncTriggerManual   = "manual"
+)
+
+// ErrSyncJobRunning 任务已有运行在进行，同一任务不并发运行
+var ErrSyncJobRunning = errors.New("同步任务正在运行")
+
+// ErrSyncJobNotRunning 任务没有正在进行的运行
+var ErrSyncJobNotRunning = errors.New("同步任务没有正在进行的运行")
+
+const (
+	syncJobRunHistory = 100         // 每个任务保留的运行记录数
+	syncSchedulerPoll = time.Minute // 调度循环的最长睡眠，及时发现表映射删除等其他途径的修改
+)
+
+// syncJobService 同步任务服务实现
+type syncJobService struct {
+	jobRepo          repositories.SyncJobRepository
+	tableMappingRepo repositories.TableMappingRepository
+	dataSyncService  DataSyncService
+	log              *logrus.Entry
+
+	mu      sync.Mutex
+	ctx     context.Context // 调度器运行期间有效，Stop 时取消
+	cancel  context.CancelFunc
+	running map[string]*activeSyncRun // 任务ID → 正在进行的运行
+	runs    sync.WaitGroup
+	wake    chan struct{}
+	done    chan struct{}
+}
+
+// activeSyncRun 正在进行的运行，由 mu 保护
+type activeSyncRun struct {
+	cancel   context.CancelFunc
+	progress map[string]SyncProgress // 实体 → 最近的进度
+	watchers []chan SyncProgress
+}
+
+// NewSyncJobService 创建新的同步任务服务实例
+func NewSyncJobService(
+	jobRepo repositories.SyncJobRepository,
+	tableMappingRepo repositories.TableMappingRepository,
+	dataSyncService DataSyncService,
+) SyncJobService {
+	return &syncJobService{
+		jobRepo:          jobRepo,
+		tableMappingRepo: tableMappingRepo,
+		dataSyncService:  dataSyncService,
+		log:              logrus.WithField("component", "sync-jobs"),
+		running:          make(map[string]*activeSyncRun),
+		wake:             make(chan struct{}, 1),
+	}
+}
+
+// CreateJob 创建同步任务
+func (s *syncJobService) CreateJob(ctx context.Context, req CreateSyncJobRequest) (*domain.SyncJob, error) {
+	if _, err := s.tableMappingRepo.GetByID(ctx, req.MappingID); err != nil {
+		return nil, fmt.Errorf("表映射不存在: %w", err)
+	}
+	job := &domain.SyncJob{
+		ID:              generateID(),
+		Name:            req.Name,
+		MappingID:       req.MappingID,
+		Cron:            req.Cron,
+		IntervalSeconds: req.IntervalSeconds,
+		Entities:        req.Entities,
+		WatermarkColumn: req.WatermarkColumn,
+		Paused:          req.Paused,
+	}
+	if err := s.reschedule(job, time.Now()); err != nil {
+		return nil, err
+	}
+
+	if err := s.jobRepo.Create(ctx, job); err != nil {
+		return nil, fmt.Errorf("创建同步任务失败: %w", err)
+	}
+	s.notify()
+	return job, nil
+}
+
+// GetJob 获取同步任务
+func (s *syncJobService) GetJob(ctx context.Context, id string) (*domain.SyncJob, error) {
+	return s.jobRepo.GetByID(ctx, id)
+}
+
+// UpdateJob 更新同步任务配置，不影响正在进行的运行
+func (s *syncJobService) UpdateJob(ctx context.Context, req UpdateSyncJobRequest) (*domain.SyncJob, error) {
+	job, err := s.jobRepo.GetByID(ctx, req.ID)
+	if err != nil {
+		return nil, fmt.Errorf("获取同步任务失败: %w", err)
+	}
+
+	// 更新非空字段
+	columns := []string{"name", "cron", "interval_seconds", "entities", "watermark_column", "next_run_at", "updated_at"}
+	if req.Name != nil {
+		job.Name = *req.Name
+	}
+	if req.Cron != nil {
+		job.Cron = *req.Cron
+		if job.Cron != "" && req.IntervalSeconds == nil {
+			job.IntervalSeconds = 0
+		}
+	}
+	if req.IntervalSeconds != nil {
+		job.IntervalSeconds = *req.IntervalSeconds
+		if job.IntervalSeconds != 0 && req.Cron == nil {
+			job.Cron = ""
+		}
+	}
+	if req.Entities != nil {
+		job.Entities = req.Entities
+	}
+	if req.WatermarkColumn != nil && *req.WatermarkColumn != job.WatermarkColumn {
+		job.WatermarkColumn = *req.WatermarkColumn
+		req.ResetWatermarks = true
+	}
+	if req.ResetWatermarks {
+		job.Watermarks = nil
+		columns = append(columns, "watermarks")
+	}
+	if err := s.reschedule(job, time.Now()); err != nil {
+		return nil, err
+	}
+
+	job.UpdatedAt = time.Now()
+	if err := s.jobRepo.UpdateColumns(ctx, job, columns...); err != nil {
+		return nil, fmt.Errorf("更新同步任务失败: %w", err)
+	}
+	s.notify()
+	return job, nil
+}
+
+// DeleteJob 删除同步任务及其运行记录，正在进行的运行被取消
+func (s *syncJobService) DeleteJob(ctx context.Context, id string) error {
+	if err := s.jobRepo.Delete(ctx, id); err != nil {
+		return fmt.Errorf("删除同步任务失败: %w", err)
+	}
+	s.mu.Lock()
+	if active, ok := s.running[id]; ok {
+		active.cancel()
+	}
+	s.mu.Unlock()
+	return nil
+}
+
+// ListJobs 列出同步任务
+func (s *syncJobService) ListJobs(ctx context.Context) ([]*domain.SyncJob, error) {
+	return s.jobRepo.List(ctx)
+}
+
+// TriggerJob 立即在后台运行一次，返回运行记录；任务正在运行时返回错误
+func (s *syncJobService) TriggerJob(ctx context.Context, id string) (*domain.SyncJobRun, error) {
+	job, err := s.jobRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("获取同步任务失败: %w", err)
+	}
+	return s.startRun(job, SyncTriggerManual)
+}
+
+// PauseJob 暂停定时运行
+func (s *syncJobService) PauseJob(ctx context.Context, id string) (*domain.SyncJob, error) {
+	return s.setPaused(ctx, id, true)
+}
+
+// ResumeJob 恢复定时运行，从现在起计算下次运行时间
+func (s *syncJobService) ResumeJob(ctx context.Context, id string) (*domain.SyncJob, error) {
+	return s.setPaused(ctx, id, false)
+}
+
+func (s *syncJobService) setPaused(ctx context.Context, id string, paused bool) (*domain.SyncJob, error) {
+	job, err := s.jobRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("获取同步任务失败: %w", err)
+	}
+	if job.Paused == paused {
+		return job, nil
+	}
+	job.Paused = paused
+	if err := s.reschedule(job, time.Now()); err != nil {
+		return nil, err
+	}
+	job.UpdatedAt = time.Now()
+	if err := s.jobRepo.UpdateColumns(ctx, job, "paused", "next_run_at", "updated_at"); err != nil {
+		return nil, fmt.Errorf("更新同步任务失败: %w", err)
+	}
+	s.notify()
+	return job, nil
+}
+
+// ListJobRuns 按时间倒序列出任务的运行记录
+func (s *syncJobService) ListJobRuns(ctx context.Context, id string, limit int) ([]*domain.SyncJobRun, error) {
+	if _, err := s.jobRepo.GetByID(ctx, id); err != nil {
+		return nil, fmt.Errorf("获取同步任务失败: %w", err)
+	}
+	if limit <= 0 || limit > syncJobRunHistory {
+		limit = syncJobRunHistory
+	}
+	return s.jobRepo.ListRuns(ctx, id, limit)
+}
+
+// CancelJob 取消正在进行的运行，不等待运行结束
+func (s *syncJobService) CancelJob(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	active, ok := s.running[id]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrSyncJobNotRunning, id)
+	}
+	active.cancel()
+	return nil
+}
+
+// WatchJob 订阅正在进行的运行的进度，先收到各实体最近的进度；接收太慢时丢弃中间的进度
+func (s *syncJobService) WatchJob(ctx context.Context, id string) (<-chan SyncProgress, func(), error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	active, ok := s.running[id]
+	if !ok {
+		return nil, nil, fmt.Errorf("%w: %s", ErrSyncJobNotRunning, id)
+	}
+	ch := make(chan SyncProgress, 16)
+	for _, entity := range []string{"node", "path"} {
+		if p, ok := active.progress[entity]; ok {
+			ch <- p
+		}
+	}
+	active.watchers = append(active.watchers, ch)
+	unwatch := func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		for i, w := range active.watchers {
+			if w == ch {
+				active.watchers = append(active.watchers[:i], active.watchers[i+1:]...)
+				close(ch)
+				return
+			}
+		}
+	}
+	return ch, unwatch, nil
+}
+
+// publish 记录运行的进度并转发给订阅者
+func (s *syncJobService) publish(jobID string, p SyncProgress) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	active, ok := s.running[jobID]
+	if !ok {
+		return
+	}
+	active.progress[p.Entity] = p
+	for _, ch := range active.watchers {
+		select {
+		case ch <- p:
+		default:
+		}
+	}
+}
+
+// reschedule 校验任务配置并计算下次运行时间，暂停的任务没有下次运行时间
+func (s *syncJobService) reschedule(job *domain.SyncJob, now time.Time) error {
+	schedule, err := parseSyncSchedule(job.Cron, job.IntervalSeconds)
+	if err != nil {
+		return err
+	}
+	for _, entity := range job.Entities {
+		if entity != "node" && entity != "path" {
+			return fmt.Errorf("不支持的同步实体: %s", entity)
+		}
+	}
+	if job.WatermarkColumn != "" {
+		if err := checkIdentifier(job.WatermarkColumn); err != nil {
+			return fmt.Errorf("水位列无效: %w", err)
+		}
+	}
+
+	job.NextRunAt = nil
+	if !job.Paused {
+		if next := schedule.next(now); !next.IsZero() {
+			job.NextRunAt = &next
+		}
+	}
+	return nil
+}
+
+// Start 启动调度循环，不阻塞
+func (s *syncJobService) Start(ctx context.Context) error {
+	s.mu.Lock()
+	if s.cancel != nil {
+		s.mu.Unlock()
+		return nil
+	}
+	s.ctx, s.cancel = context.WithCancel(ctx)
+	s.done = make(chan struct{})
+	s.mu.Unlock()
+
+	// 上次停机时未结束的运行
+	stale, err := s.jobRepo.ListRunsByStatus(ctx, domain.SyncRunRunning)
+	if err != nil {
+		s.log.WithError(err).Warn("读取未结束的同步运行失败")
+	}
+	for _, run := range stale {
+		run.Status = domain.SyncRunFailed
+		run.Errors = append(run.Errors, "服务停止，运行中断")
+		if run.FinishedAt == nil {
+			now := time.Now()
+			run.FinishedAt = &now
+		}
+		if err := s.jobRepo.UpdateRun(ctx, run); err != nil {
+			s.log.WithError(err).WithField("run", run.ID).Warn("更新中断的同步运行失败")
+		}
+	}
+
+	go s.loop()
+	s.log.Info("同步任务调度器已启动")
+	return nil
+}
+
+// Stop 停止调度循环，取消正在进行的运行并等待其记录结果
+func (s *syncJobService) Stop(ctx context.Context) error {
+	s.mu.Lock()
+	if s.cancel == nil {
+		s.mu.Unlock()
+		return nil
+	}
+	// 持锁取消，之后 startRun 不会再派发新的运行
+	s.cancel()
+	done := s.done
+	s.mu.Unlock()
+
+	stopped := make(chan struct{})
+	go func() {
+		<-done
+		s.runs.Wait()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+		s.log.Info("同步任务调度器已停止")
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("等待同步任务结束超时: %w", ctx.Err())
+	}
+}
+
+// notify 任务变化后唤醒调度循环重新计算
+func (s *syncJobService) notify() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// loop 调度循环：派发到期的任务，睡到最近的下次运行时间
+func (s *syncJobService) loop() {
+	defer close(s.done)
+	for {
+		timer := time.NewTimer(s.dispatchDue(time.Now()))
+		select {
+		case <-s.ctx.Done():
+			timer.Stop()
+			return
+		case <-s.wake:
+			timer.Stop()
+		case <-timer.C:
+		}
+	}
+}
+
+// dispatchDue 派发到期的任务，返回距最近一次运行的等待时间
+func (s *syncJobService) dispatchDue(now time.Time) time.Duration {
+	wait := syncSchedulerPoll
+	jobs, err := s.jobRepo.List(s.ctx)
+	if err != nil {
+		if s.ctx.Err() == nil {
+			s.log.WithError(err).Warn("读取同步任务失败")
+		}
+		return wait
+	}
+	for _, job := range jobs {
+		if job.Paused || job.NextRunAt == nil {
+			continue
+		}
+		if !job.NextRunAt.After(now) {
+			if err := s.reschedule(job, now); err != nil {
+				s.log.WithError(err).WithField("job", job.ID).Warn("同步任务配置无效")
+				continue
+			}
+			if err := s.jobRepo.UpdateColumns(s.ctx, job, "next_run_at"); err != nil {
+				s.log.WithError(err).WithField("job", job.ID).Warn("更新下次运行时间失败")
+				continue
+			}
+			if _, err := s.startRun(job, SyncTriggerSchedule); err != nil {
+				s.log.WithError(err).WithField("job", job.ID).Info("跳过本次定时运行")
+			}
+		}
+		if job.NextRunAt != nil {
+			if d := job.NextRunAt.Sub(now); d < wait {
+				wait = d
+			}
+		}
+	}
+	return wait
+}
+
+// startRun 创建运行记录并在后台执行同步
+func (s *syncJobService) startRun(job *domain.SyncJob, trigger string) (*domain.SyncJobRun, error) {
+	s.mu.Lock()
+	if s.ctx == nil || s.ctx.Err() != nil {
+		s.mu.Unlock()
+		return nil, fmt.Errorf("同步任务调度器未运行")
+	}
+	if _, ok := s.running[job.ID]; ok {
+		s.mu.Unlock()
+		return nil, fmt.Errorf("%w: %s", ErrSyncJobRunning, job.ID)
+	}
+	ctx, cancel := context.WithCancel(s.ctx)
+	s.running[job.ID] = &activeSyncRun{cancel: cancel, progress: make(map[string]SyncProgress)}
+	s.runs.Add(1)
+	s.mu.Unlock()
+
+	run := &domain.SyncJobRun{
+		ID:        generateID(),
+		JobID:     job.ID,
+		MappingID: job.MappingID,
+		Trigger:   trigger,
+		Status:    domain.SyncRunRunning,
+		StartedAt: time.Now(),
+	}
+	if err := s.jobRepo.CreateRun(ctx, run); err != nil {
+		s.finishRun(job.ID, cancel)
+		return nil, fmt.Errorf("创建运行记录失败: %w", err)
+	}
+
+	snapshot := *run
+	go s.execute(ctx, cancel, job, run)
+	return &snapshot, nil
+}
+
+func (s *syncJobService) finishRun(jobID string, cancel context.CancelFunc) {
+	cancel()
+	s.mu.Lock()
+	if active, ok := s.running[jobID]; ok {
+		for _, ch := range active.watchers {
+			close(ch)
+		}
+		active.watchers = nil
+		delete(s.running, jobID)
+	}
+	s.mu.Unlock()
+	s.runs.Done()
+}
+
+// execute 执行一次同步并记录结果、推进水位
+func (s *syncJobService) execute(ctx context.Context, cancel context.CancelFunc, job *domain.SyncJob, run *domain.SyncJobRun) {
+	defer s.finishRun(job.ID, cancel)
+
+	// 开始时的水位，结束时只写入本次推进的实体
+	start := make(map[string]string, len(job.Watermarks))
+	for entity, mark := range job.Watermarks {
+		start[entity] = mark
+	}
+	progressCtx := WithSyncProgress(ctx, func(p SyncProgress) { s.publish(job.ID, p) })
+	result, err := s.dataSyncService.SyncChangesFromExternal(progressCtx, job.MappingID, IncrementalSyncRequest{
+		Entities:        job.Entities,
+		WatermarkColumn: job.WatermarkColumn,
+		Watermarks:      job.Watermarks,
+	})
+	finished := time.Now()
+	run.FinishedAt = &finished
+	run.DurationMs = finished.Sub(run.StartedAt).Milliseconds()
+	switch {
+	case ctx.Err() != nil:
+		run.Status = domain.SyncRunCanceled
+		run.Errors = append(run.Errors, "运行被取消")
+	case err != nil:
+		run.Status = domain.SyncRunFailed
+		run.Errors = append(run.Errors, err.Error())
+	case len(result.Errors) > 0:
+		run.Status = domain.SyncRunPartial
+	default:
+		run.Status = domain.SyncRunSuccess
+	}
+	// 取消或失败时结果为已提交的部分，计数和水位同样记录
+	if result != nil {
+		run.NodesCreated, run.NodesUpdated = result.NodesCreated, result.NodesUpdated
+		run.PathsCreated, run.PathsUpdated = result.PathsCreated, result.PathsUpdated
+		run.NodesDeleted, run.PathsDeleted = result.NodesDeleted, result.PathsDeleted
+		run.Orphans = len(result.Orphans)
+		run.Errors = append(run.Errors, result.Errors...)
+		run.Watermarks = result.Watermarks
+	}
+
+	// 用独立的上下文记录结果，服务停止时也能写入
+	saveCtx, cancelSave := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancelSave()
+	log := s.log.WithFields(logrus.Fields{"job": job.ID, "run": run.ID, "status": run.Status})
+	if err := s.jobRepo.UpdateRun(saveCtx, run); err != nil {
+		log.WithError(err).Warn("保存同步运行记录失败")
+	}
+	job.LastRunAt = &run.StartedAt
+	job.LastStatus = run.Status
+	if err := s.jobRepo.UpdateColumns(saveCtx, job, "last_run_at", "last_status"); err != nil {
+		log.WithError(err).Warn("更新同步任务状态失败")
+	}
+	if result != nil {
+		// 运行期间水位列被修改或水位被清空时，以当前任务为准
+		err := s.jobRepo.AdvanceWatermarks(saveCtx, job.ID, func(current *domain.SyncJob) map[string]string {
+			if current.WatermarkColumn != job.WatermarkColumn {
+				return current.Watermarks
+			}
+			return advanceWatermarks(current.Watermarks, start, result.Watermarks)
+		})
+		if err != nil {
+			log.WithError(err).Warn("更新同步水位失败")
+		}
+	}
+	if err := s.jobRepo.PruneRuns(saveCtx, job.ID, syncJobRunHistory); err != nil {
+		log.WithError(err).Warn("清理同步运行记录失败")
+	}
+	log.WithField("duration_ms", run.DurationMs).Info("同步任务运行结束")
+}
+
+// advanceWatermarks 只写入本次运行推进的实体水位；运行期间被清空或修改过的实体保持当前值
+func advanceWatermarks(current, start, end map[string]string) map[string]string {
+	merged := make(map[string]string, len(current)+len(end))
+	for entity, mark := range current {
+		merged[entity] = mark
+	}
+	for entity, mark := range end {
+		if mark == start[entity] || current[entity] != start[entity] {
+			continue
+		}
+		merged[entity] = mark
+	}
+	if len(merged) == 0 {
+		return nil
+	}
+	return merged
+}
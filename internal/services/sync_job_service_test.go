@@ -0,0 +1,57 @@
+package services
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestAdvanceWatermarks(t *testing.T) {
+	tests := []struct {
+		name                string
+		current, start, end map[string]string
+		want                map[string]string
+	}{
+		{
+			name:    "推进本次运行的水位",
+			current: map[string]string{"node": "1", "path": "5"},
+			start:   map[string]string{"node": "1", "path": "5"},
+			end:     map[string]string{"node": "3", "path": "5"},
+			want:    map[string]string{"node": "3", "path": "5"},
+		},
+		{
+			name:    "运行期间清空的水位不被覆盖",
+			current: nil,
+			start:   map[string]string{"node": "1"},
+			end:     map[string]string{"node": "3"},
+			want:    nil,
+		},
+		{
+			name:    "只保留其他实体被清空前推进的水位",
+			current: map[string]string{"path": "5"},
+			start:   map[string]string{"node": "1", "path": "5"},
+			end:     map[string]string{"node": "3", "path": "7"},
+			want:    map[string]string{"path": "7"},
+		},
+		{
+			name:    "首次运行写入水位",
+			current: nil,
+			start:   map[string]string{},
+			end:     map[string]string{"node": "3"},
+			want:    map[string]string{"node": "3"},
+		},
+		{
+			name:    "未推进时保持当前值",
+			current: map[string]string{"node": "2"},
+			start:   map[string]string{"node": "1"},
+			end:     map[string]string{"node": "1"},
+			want:    map[string]string{"node": "2"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := advanceWatermarks(tt.current, tt.start, tt.end); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("advanceWatermarks() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
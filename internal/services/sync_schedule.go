@@ -0,0 +1,190 @@
+// Package services 同步任务的调度时间计算
+//
+// 设计参考：
+// - Unix cron 的五段表达式：分 时 日 月 周，支持 *、列表、范围和步长
+// - 日和周都指定时任一匹配即可，与 cron 一致
+// - 另支持 @hourly、@daily、@weekly、@monthly 等简写和固定间隔
+package services
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// syncSchedule 计算下一次运行时间
+type syncSchedule interface {
+	next(after time.Time) time.Time
+}
+
+// intervalSchedule 固定间隔
+type intervalSchedule time.Duration
+
+func (d intervalSchedule) next(after time.Time) time.Time { return after.Add(time.Duration(d)) }
+
+// cronSchedule 五段 cron 表达式，每段为允许值的位集合
+type cronSchedule struct {
+	minute, hour, dom, month, dow uint64
+	domAny, dowAny                bool // 日、周为 * 时只按另一段匹配
+}
+
+// cronField 一段的取值范围
+type cronField struct {
+	name     string
+	min, max int
+}
+
+var cronFields = []cronField{
+	{"分钟", 0, 59},
+	{"小时", 0, 23},
+	{"日", 1, 31},
+	{"月", 1, 12},
+	{"星期", 0, 7}, // 0 和 7 都表示周日
+}
+
+var cronMacros = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// minSyncInterval 轮询间隔下限，避免频繁查询外部数据库
+const minSyncInterval = 10 * time.Second
+
+// parseSyncSchedule 解析任务的调度配置，cron 与间隔必须且只能指定一个
+func parseSyncSchedule(cron string, intervalSeconds int) (syncSchedule, error) {
+	cron = strings.TrimSpace(cron)
+	switch {
+	case cron != "" && intervalSeconds != 0:
+		return nil, fmt.Errorf("cron 和 interval_seconds 只能指定一个")
+	case cron != "":
+		return parseCron(cron)
+	case intervalSeconds < 0:
+		return nil, fmt.Errorf("interval_seconds 不能为负数")
+	case intervalSeconds > 0:
+		interval := time.Duration(intervalSeconds) * time.Second
+		if interval < minSyncInterval {
+			return nil, fmt.Errorf("轮询间隔不能小于 %s", minSyncInterval)
+		}
+		return intervalSchedule(interval), nil
+	default:
+		return nil, fmt.Errorf("需要指定 cron 或 interval_seconds")
+	}
+}
+
+// parseCron 解析五段 cron 表达式或简写
+func parseCron(expr string) (*cronSchedule, error) {
+	if macro, ok := cronMacros[strings.ToLower(expr)]; ok {
+		expr = macro
+	}
+	parts := strings.Fields(expr)
+	if len(parts) != len(cronFields) {
+		return nil, fmt.Errorf("cron 表达式 %q 需要 5 段（分 时 日 月 周）", expr)
+	}
+	bits := make([]uint64, len(parts))
+	for i, part := range parts {
+		b, err := parseCronField(part, cronFields[i])
+		if err != nil {
+			return nil, fmt.Errorf("cron 表达式 %q 无效: %w", expr, err)
+		}
+		bits[i] = b
+	}
+	// 周日统一为 0
+	if bits[4]&(1<<7) != 0 {
+		bits[4] = bits[4]&^(1<<7) | 1
+	}
+	return &cronSchedule{
+		minute: bits[0],
+		hour:   bits[1],
+		dom:    bits[2],
+		month:  bits[3],
+		dow:    bits[4],
+		domAny: parts[2] == "*" || parts[2] == "?",
+		dowAny: parts[4] == "*" || parts[4] == "?",
+	}, nil
+}
+
+// parseCronField 解析一段：* 、n、a-b、*/s、a-b/s，以逗号分隔多项
+func parseCronField(s string, f cronField) (uint64, error) {
+	var bits uint64
+	for _, item := range strings.Split(s, ",") {
+		rng, step := item, 1
+		if i := strings.Index(item, "/"); i >= 0 {
+			n, err := strconv.Atoi(item[i+1:])
+			if err != nil || n <= 0 {
+				return 0, fmt.Errorf("%s的步长 %q 无效", f.name, item[i+1:])
+			}
+			rng, step = item[:i], n
+		}
+
+		lo, hi := f.min, f.max
+		switch {
+		case rng == "*" || rng == "?":
+		case strings.Contains(rng, "-"):
+			a, b, _ := strings.Cut(rng, "-")
+			var err1, err2 error
+			lo, err1 = strconv.Atoi(a)
+			hi, err2 = strconv.Atoi(b)
+			if err1 != nil || err2 != nil || lo > hi {
+				return 0, fmt.Errorf("%s的范围 %q 无效", f.name, rng)
+			}
+		default:
+			n, err := strconv.Atoi(rng)
+			if err != nil {
+				return 0, fmt.Errorf("%s的值 %q 无效", f.name, rng)
+			}
+			lo = n
+			if step == 1 {
+				hi = n
+			}
+		}
+		if lo < f.min || hi > f.max {
+			return 0, fmt.Errorf("%s的取值应在 %d-%d 之间: %q", f.name, f.min, f.max, item)
+		}
+		for v := lo; v <= hi; v += step {
+			bits |= 1 << uint(v)
+		}
+	}
+	return bits, nil
+}
+
+// next 返回 after 之后（不含）第一个匹配的整分钟；五年内没有匹配时返回零值
+func (c *cronSchedule) next(after time.Time) time.Time {
+	t := after.Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(5, 0, 0)
+	for t.Before(limit) {
+		switch {
+		case c.month&(1<<uint(t.Month())) == 0:
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+		case !c.dayMatches(t):
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+		case c.hour&(1<<uint(t.Hour())) == 0:
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+		case c.minute&(1<<uint(t.Minute())) == 0:
+			t = t.Add(time.Minute)
+		default:
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+func (c *cronSchedule) dayMatches(t time.Time) bool {
+	dom := c.dom&(1<<uint(t.Day())) != 0
+	dow := c.dow&(1<<uint(t.Weekday())) != 0
+	switch {
+	case c.domAny && c.dowAny:
+		return true
+	case c.domAny:
+		return dow
+	case c.dowAny:
+		return dom
+	default:
+		return dom || dow
+	}
+}
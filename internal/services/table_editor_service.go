@@ -0,0 +1,724 @@
+// Package services 外部数据库的通用表编辑器
+//
+// 设计参考：
+// - GoLand / DataGrip 的表格编辑器：分页浏览，修改先在客户端暂存，提交时生成语句并在一个事务中执行
+//
+// 特点：
+// - 在数据库连接上列出模式和表，查看列（类型、可为空、默认值、主键）和索引
+// - 表名、列名按元数据校验后加引号，值一律使用占位符；筛选和排序与表映射使用同样的结构
+// - 分页未指定排序时按主键排序，翻页稳定；没有主键的表只能浏览和插入
+// - 一次提交的全部修改在一个事务中执行，任一失败整体回滚；update、delete 按完整主键定位，行不存在时失败
+// - 预览（dry_run）同样在事务中执行后回滚，返回每条语句、影响行数和修改前后的行
+package services
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"robot-path-editor/internal/domain"
+	"robot-path-editor/internal/repositories"
+)
+
+// TableEditorService 外部表浏览和编辑服务接口
+type TableEditorService interface {
+	ListSchemas(ctx context.Context, connectionID string) ([]string, error)
+	ListTables(ctx context.Context, connectionID, schema string) ([]ExternalTableInfo, error)
+	DescribeTable(ctx context.Context, connectionID, schema, table string) (*ExternalTableSchema, error)
+	// QueryRows 分页读取行
+	QueryRows(ctx context.Context, connectionID, table string, req TableRowsQuery) (*TableRowsPage, error)
+	// WriteRows 在一个事务中执行插入、按主键更新和删除
+	WriteRows(ctx context.Context, connectionID, table string, req TableWriteRequest) (*TableWriteResult, error)
+}
+
+// ExternalTableInfo 表或视图
+type ExternalTableInfo struct {
+	Name string `json:"name"`
+	Type string `json:"type"` // table 或 view
+}
+
+// ExternalTableSchema 表结构
+type ExternalTableSchema struct {
+	Schema     string           `json:"schema,omitempty"`
+	Name       string           `json:"name"`
+	Columns    []ExternalColumn `json:"columns"`
+	PrimaryKey []string         `json:"primary_key"` // 按主键中的顺序
+	Indexes    []ExternalIndex  `json:"indexes"`
+}
+
+// ExternalColumn 列定义
+type ExternalColumn struct {
+	Name       string  `json:"name"`
+	Type       string  `json:"type"`
+	Nullable   bool    `json:"nullable"`
+	Default    *string `json:"default"` // 默认值表达式，没有默认值时为 null
+	PrimaryKey bool    `json:"primary_key"`
+}
+
+// ExternalIndex 索引定义
+type ExternalIndex struct {
+	Name    string   `json:"name"`
+	Columns []string `json:"columns"` // 表达式索引中的表达式列为空字符串
+	Unique  bool     `json:"unique"`
+	Primary bool     `json:"primary"`
+}
+
+// TableRowsQuery 分页查询
+type TableRowsQuery struct {
+	Schema   string                  `json:"schema,omitempty"`
+	Filter   []domain.TableCondition `json:"filter,omitempty"`
+	OrderBy  []domain.TableOrder     `json:"order_by,omitempty"` // 为空时按主键排序
+	Page     int                     `json:"page,omitempty"`     // 从 1 开始
+	PageSize int                     `json:"page_size,omitempty"`
+}
+
+// TableRowsPage 一页行数据
+type TableRowsPage struct {
+	Columns  []string                 `json:"columns"`
+	Rows     []map[string]interface{} `json:"rows"`
+	Total    int64                    `json:"total"` // 满足筛选条件的行数
+	Page     int                      `json:"page"`
+	PageSize int                      `json:"page_size"`
+}
+
+// 行修改类型
+const (
+	TableOpInsert = "insert"
+	TableOpUpdate = "update"
+	TableOpDelete = "delete"
+)
+
+// TableChange 一项行修改
+type TableChange struct {
+	Op     string                 `json:"op"`
+	Key    map[string]interface{} `json:"key,omitempty"`    // 主键列 → 值，update、delete 必须给出完整主键
+	Values map[string]interface{} `json:"values,omitempty"` // insert、update 的列值
+}
+
+// TableWriteRequest 行修改请求
+type TableWriteRequest struct {
+	Schema  string        `json:"schema,omitempty"`
+	DryRun  bool          `json:"dry_run"`
+	Changes []TableChange `json:"changes"`
+}
+
+// TableWriteResult 行修改结果
+type TableWriteResult struct {
+	DryRun     bool             `json:"dry_run"`
+	Table      string           `json:"table"`
+	Inserted   int              `json:"inserted"`
+	Updated    int              `json:"updated"`
+	Deleted    int              `json:"deleted"`
+	Statements []TableStatement `json:"statements"`
+}
+
+// TableStatement 一条已执行的语句
+type TableStatement struct {
+	Op           string                 `json:"op"`
+	SQL          string                 `json:"sql"` // 参数内联后的 SQL，仅用于展示
+	RowsAffected int64                  `json:"rows_affected"`
+	Before       map[string]interface{} `json:"before,omitempty"` // update、delete 前的行
+	After        map[string]interface{} `json:"after,omitempty"`  // insert、update 后的行；自增主键取不到时为空
+}
+
+const (
+	tableDefaultPageSize = 50
+	tableMaxPageSize     = 1000
+)
+
+// tableEditorService 外部表编辑服务实现
+type tableEditorService struct {
+	dbConnRepo repositories.DatabaseConnectionRepository
+	connector  *ExternalConnector
+}
+
+// NewTableEditorService 创建新的外部表编辑服务实例
+func NewTableEditorService(dbConnRepo repositories.DatabaseConnectionRepository, connector *ExternalConnector) TableEditorService {
+	return &tableEditorService{dbConnRepo: dbConnRepo, connector: connector}
+}
+
+// open 连接外部数据库，调用方负责关闭
+func (s *tableEditorService) open(ctx context.Context, connectionID string) (*sql.DB, sqlDialect, error) {
+	conn, err := s.dbConnRepo.GetByID(ctx, connectionID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("获取数据库连接失败: %w", err)
+	}
+	d, err := dialectFor(conn.Type)
+	if err != nil {
+		return nil, nil, err
+	}
+	db, err := s.connector.open(conn)
+	if err != nil {
+		return nil, nil, fmt.Errorf("连接外部数据库失败: %w", err)
+	}
+	return db, d, nil
+}
+
+// ListSchemas 列出模式（SQLite 为已附加的数据库）
+func (s *tableEditorService) ListSchemas(ctx context.Context, connectionID string) ([]string, error) {
+	db, d, err := s.open(ctx, connectionID)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	rows, err := db.QueryContext(ctx, d.SchemasQuery())
+	if err != nil {
+		return nil, fmt.Errorf("查询模式失败: %w", err)
+	}
+	defer rows.Close()
+	schemas := []string{}
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, fmt.Errorf("读取模式失败: %w", err)
+		}
+		schemas = append(schemas, name)
+	}
+	return schemas, rows.Err()
+}
+
+// ListTables 列出模式中的表和视图
+func (s *tableEditorService) ListTables(ctx context.Context, connectionID, schema string) ([]ExternalTableInfo, error) {
+	if schema != "" {
+		if err := checkIdentifier(schema); err != nil {
+			return nil, fmt.Errorf("模式名无效: %w", err)
+		}
+	}
+	db, d, err := s.open(ctx, connectionID)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	query, args := d.TablesQuery(schema)
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("查询表失败: %w", err)
+	}
+	defer rows.Close()
+	tables := []ExternalTableInfo{}
+	for rows.Next() {
+		var t ExternalTableInfo
+		if err := rows.Scan(&t.Name, &t.Type); err != nil {
+			return nil, fmt.Errorf("读取表失败: %w", err)
+		}
+		tables = append(tables, t)
+	}
+	return tables, rows.Err()
+}
+
+// DescribeTable 读取列和索引
+func (s *tableEditorService) DescribeTable(ctx context.Context, connectionID, schema, table string) (*ExternalTableSchema, error) {
+	db, d, err := s.open(ctx, connectionID)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	_, desc, err := describeExternalTable(ctx, db, d, schema, table)
+	return desc, err
+}
+
+// describeExternalTable 校验表并读取列、主键和索引
+func describeExternalTable(ctx context.Context, db *sql.DB, d sqlDialect, schema, name string) (*externalTable, *ExternalTableSchema, error) {
+	t, err := inspectExternalTable(ctx, db, d, schema, name)
+	if err != nil {
+		return nil, nil, err
+	}
+	desc := &ExternalTableSchema{Schema: schema, Name: name, Columns: []ExternalColumn{}, PrimaryKey: []string{}, Indexes: []ExternalIndex{}}
+
+	query, args := d.DescribeQuery(schema, name)
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, nil, fmt.Errorf("查询表结构失败: %w", err)
+	}
+	defer rows.Close()
+	pk := make(map[int64]string)
+	for rows.Next() {
+		var c ExternalColumn
+		var typ, dflt sql.NullString
+		var nullable interface{}
+		var position sql.NullInt64
+		if err := rows.Scan(&c.Name, &typ, &nullable, &dflt, &position); err != nil {
+			return nil, nil, fmt.Errorf("读取表结构失败: %w", err)
+		}
+		c.Type, c.Nullable = typ.String, sqlBool(nullable)
+		if dflt.Valid {
+			c.Default = &dflt.String
+		}
+		if position.Int64 > 0 {
+			c.PrimaryKey = true
+			pk[position.Int64] = c.Name
+		}
+		desc.Columns = append(desc.Columns, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, fmt.Errorf("读取表结构失败: %w", err)
+	}
+	positions := make([]int64, 0, len(pk))
+	for p := range pk {
+		positions = append(positions, p)
+	}
+	sort.Slice(positions, func(i, j int) bool { return positions[i] < positions[j] })
+	for _, p := range positions {
+		desc.PrimaryKey = append(desc.PrimaryKey, pk[p])
+	}
+
+	query, args = d.IndexesQuery(schema, name)
+	irows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, nil, fmt.Errorf("查询索引失败: %w", err)
+	}
+	defer irows.Close()
+	for irows.Next() {
+		var index string
+		var unique, primary interface{}
+		var column sql.NullString
+		var position int64
+		if err := irows.Scan(&index, &unique, &primary, &column, &position); err != nil {
+			return nil, nil, fmt.Errorf("读取索引失败: %w", err)
+		}
+		if n := len(desc.Indexes); n == 0 || desc.Indexes[n-1].Name != index {
+			desc.Indexes = append(desc.Indexes, ExternalIndex{Name: index, Unique: sqlBool(unique), Primary: sqlBool(primary)})
+		}
+		last := &desc.Indexes[len(desc.Indexes)-1]
+		last.Columns = append(last.Columns, column.String)
+	}
+	if err := irows.Err(); err != nil {
+		return nil, nil, fmt.Errorf("读取索引失败: %w", err)
+	}
+	return t, desc, nil
+}
+
+// sqlBool 元数据查询中的布尔值，各驱动分别返回 bool、整数或文本
+func sqlBool(v interface{}) bool {
+	switch b := v.(type) {
+	case bool:
+		return b
+	case int64:
+		return b != 0
+	case []byte:
+		return sqlBool(string(b))
+	case string:
+		return b == "1" || strings.EqualFold(b, "t") || strings.EqualFold(b, "true") || strings.EqualFold(b, "yes")
+	}
+	return false
+}
+
+// QueryRows 按筛选条件和排序分页读取行
+func (s *tableEditorService) QueryRows(ctx context.Context, connectionID, table string, req TableRowsQuery) (*TableRowsPage, error) {
+	if err := checkTableQuery(req.Filter, req.OrderBy); err != nil {
+		return nil, err
+	}
+	page, size := req.Page, req.PageSize
+	if page <= 0 {
+		page = 1
+	}
+	if size <= 0 {
+		size = tableDefaultPageSize
+	}
+	if size > tableMaxPageSize {
+		return nil, fmt.Errorf("每页最多 %d 行", tableMaxPageSize)
+	}
+
+	db, d, err := s.open(ctx, connectionID)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+	t, desc, err := describeExternalTable(ctx, db, d, req.Schema, table)
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range req.Filter {
+		if _, err := t.column(c.Column); err != nil {
+			return nil, err
+		}
+	}
+	for _, o := range req.OrderBy {
+		if _, err := t.column(o.Column); err != nil {
+			return nil, err
+		}
+	}
+
+	count := newSQLBuilder(d).sql("SELECT COUNT(*) FROM ")
+	t.tableRef(count)
+	if err := t.where(count, req.Filter); err != nil {
+		return nil, err
+	}
+	result := &TableRowsPage{Columns: t.columns, Rows: []map[string]interface{}{}, Page: page, PageSize: size}
+	if err := db.QueryRowContext(ctx, count.query.String(), count.args...).Scan(&result.Total); err != nil {
+		return nil, fmt.Errorf("统计行数失败: %w", err)
+	}
+
+	order := req.OrderBy
+	if len(order) == 0 {
+		for _, c := range desc.PrimaryKey {
+			order = append(order, domain.TableOrder{Column: c})
+		}
+	}
+	query, err := t.selectQuery(t.columns, req.Filter, order, "")
+	if err != nil {
+		return nil, err
+	}
+	query.sql(" LIMIT ").arg(size).sql(" OFFSET ").arg((page - 1) * size)
+	rows, err := db.QueryContext(ctx, query.query.String(), query.args...)
+	if err != nil {
+		return nil, fmt.Errorf("查询外部数据库失败: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		row, err := scanTableRow(rows, t.columns)
+		if err != nil {
+			return nil, err
+		}
+		result.Rows = append(result.Rows, row)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("读取外部数据失败: %w", err)
+	}
+	return result, nil
+}
+
+// scanTableRow 读取一行，文本以字符串返回，非 UTF-8 的二进制值保持字节（JSON 中为 base64）
+func scanTableRow(rows *sql.Rows, columns []string) (map[string]interface{}, error) {
+	values := make([]interface{}, len(columns))
+	ptrs := make([]interface{}, len(columns))
+	for i := range values {
+		ptrs[i] = &values[i]
+	}
+	if err := rows.Scan(ptrs...); err != nil {
+		return nil, fmt.Errorf("读取外部数据失败: %w", err)
+	}
+	row := make(map[string]interface{}, len(columns))
+	for i, c := range columns {
+		if b, ok := values[i].([]byte); ok && utf8.Valid(b) {
+			values[i] = string(b)
+		}
+		row[c] = values[i]
+	}
+	return row, nil
+}
+
+// tableWrite 校验过的一项修改，列名均为数据库中的列名
+type tableWrite struct {
+	op     string
+	key    []tableValue // 主键顺序
+	values []tableValue // 表定义顺序
+}
+
+type tableValue struct {
+	column string
+	value  interface{}
+}
+
+// WriteRows 在一个事务中按顺序执行修改，任一失败整体回滚；DryRun 时执行后回滚
+func (s *tableEditorService) WriteRows(ctx context.Context, connectionID, table string, req TableWriteRequest) (*TableWriteResult, error) {
+	if len(req.Changes) == 0 {
+		return nil, fmt.Errorf("没有要执行的修改")
+	}
+	db, d, err := s.open(ctx, connectionID)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+	t, desc, err := describeExternalTable(ctx, db, d, req.Schema, table)
+	if err != nil {
+		return nil, err
+	}
+
+	// 先校验全部修改，再开始事务
+	writes := make([]tableWrite, len(req.Changes))
+	for i, c := range req.Changes {
+		if writes[i], err = newTableWrite(t, desc.PrimaryKey, c); err != nil {
+			return nil, fmt.Errorf("第 %d 项修改无效: %w", i+1, err)
+		}
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("开始外部数据库事务失败: %w", err)
+	}
+	defer tx.Rollback()
+
+	result := &TableWriteResult{DryRun: req.DryRun, Table: t.displayName(), Statements: []TableStatement{}}
+	for i, w := range writes {
+		stmt, err := w.exec(ctx, tx, t, desc.PrimaryKey)
+		if err != nil {
+			return nil, fmt.Errorf("第 %d 项修改（%s）失败，已回滚: %w", i+1, w.op, err)
+		}
+		switch w.op {
+		case TableOpInsert:
+			result.Inserted++
+		case TableOpUpdate:
+			result.Updated++
+		case TableOpDelete:
+			result.Deleted++
+		}
+		result.Statements = append(result.Statements, stmt)
+	}
+
+	if req.DryRun {
+		return result, nil
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("提交外部数据库事务失败: %w", err)
+	}
+	return result, nil
+}
+
+// newTableWrite 校验修改类型、列名和主键
+func newTableWrite(t *externalTable, primaryKey []string, c TableChange) (tableWrite, error) {
+	w := tableWrite{op: c.Op}
+	values, err := tableValues(t, c.Values)
+	if err != nil {
+		return w, err
+	}
+	switch c.Op {
+	case TableOpInsert:
+		if len(values) == 0 {
+			return w, fmt.Errorf("insert 需要列值")
+		}
+		w.values = values
+		return w, nil
+	case TableOpUpdate:
+		if len(values) == 0 {
+			return w, fmt.Errorf("update 需要列值")
+		}
+		w.values = values
+	case TableOpDelete:
+	default:
+		return w, fmt.Errorf("不支持的修改类型: %s", c.Op)
+	}
+
+	if len(primaryKey) == 0 {
+		return w, fmt.Errorf("表 %s 没有主键，不能执行 %s", t.displayName(), c.Op)
+	}
+	key, err := tableValues(t, c.Key)
+	if err != nil {
+		return w, err
+	}
+	given := make(map[string]interface{}, len(key))
+	for _, v := range key {
+		given[v.column] = v.value
+	}
+	for _, column := range primaryKey {
+		v, ok := given[column]
+		if !ok || v == nil {
+			return w, fmt.Errorf("缺少主键列 %s 的值", column)
+		}
+		w.key = append(w.key, tableValue{column: column, value: v})
+	}
+	if len(key) != len(primaryKey) {
+		return w, fmt.Errorf("key 只能包含主键列 %s", strings.Join(primaryKey, ", "))
+	}
+	return w, nil
+}
+
+// tableValues 把请求中的列值换成数据库中的列名，按表定义排序
+func tableValues(t *externalTable, m map[string]interface{}) ([]tableValue, error) {
+	byColumn := make(map[string]interface{}, len(m))
+	for name, v := range m {
+		column, err := t.column(name)
+		if err != nil {
+			return nil, err
+		}
+		if _, dup := byColumn[column]; dup {
+			return nil, fmt.Errorf("列 %s 重复", column)
+		}
+		value, err := tableArg(v)
+		if err != nil {
+			return nil, fmt.Errorf("列 %s 的值无效: %w", column, err)
+		}
+		byColumn[column] = value
+	}
+	var values []tableValue
+	for _, column := range t.columns {
+		if v, ok := byColumn[column]; ok {
+			values = append(values, tableValue{column: column, value: v})
+		}
+	}
+	return values, nil
+}
+
+// tableArg JSON 值作为语句参数：整数值的数字按整数传入，对象和数组序列化为 JSON 文本
+func tableArg(v interface{}) (interface{}, error) {
+	switch x := v.(type) {
+	case float64:
+		if x == math.Trunc(x) && math.Abs(x) < 1<<53 {
+			return int64(x), nil
+		}
+		return x, nil
+	case map[string]interface{}, []interface{}:
+		b, err := json.Marshal(x)
+		if err != nil {
+			return nil, err
+		}
+		return string(b), nil
+	}
+	return v, nil
+}
+
+// exec 在事务中执行一项修改，返回语句、影响行数和修改前后的行
+func (w tableWrite) exec(ctx context.Context, tx *sql.Tx, t *externalTable, primaryKey []string) (TableStatement, error) {
+	stmt := TableStatement{Op: w.op}
+	if w.op != TableOpInsert {
+		before, err := readTableRow(ctx, tx, t, w.key)
+		if err != nil {
+			return stmt, err
+		}
+		if before == nil {
+			return stmt, fmt.Errorf("行不存在: %s", formatTableKey(w.key))
+		}
+		stmt.Before = before
+	}
+
+	b := newSQLBuilder(t.dialect)
+	switch w.op {
+	case TableOpInsert:
+		t.tableRef(b.sql("INSERT INTO ")).sql(" (")
+		for i, v := range w.values {
+			if i > 0 {
+				b.sql(", ")
+			}
+			t.columnRef(b, v.column)
+		}
+		b.sql(") VALUES (")
+		for i, v := range w.values {
+			if i > 0 {
+				b.sql(", ")
+			}
+			b.arg(v.value)
+		}
+		b.sql(")")
+	case TableOpUpdate:
+		t.tableRef(b.sql("UPDATE ")).sql(" SET ")
+		for i, v := range w.values {
+			if i > 0 {
+				b.sql(", ")
+			}
+			t.columnRef(b, v.column).sql(" = ").arg(v.value)
+		}
+		if err := t.where(b, tableKeyFilter(w.key)); err != nil {
+			return stmt, err
+		}
+	case TableOpDelete:
+		t.tableRef(b.sql("DELETE FROM "))
+		if err := t.where(b, tableKeyFilter(w.key)); err != nil {
+			return stmt, err
+		}
+	}
+	stmt.SQL = b.display.String()
+	if w.op == TableOpDelete {
+		res, err := tx.ExecContext(ctx, b.query.String(), b.args...)
+		if err != nil {
+			return stmt, err
+		}
+		stmt.RowsAffected, _ = res.RowsAffected()
+		return stmt, nil
+	}
+
+	// 修改后的行：主键取修改后的值；插入时未给出的自增主键，PostgreSQL 用 RETURNING 取回，其余用 LastInsertId
+	after := afterTableKey(w, primaryKey)
+	if after == nil && len(primaryKey) > 0 && t.dialect.Name() == "postgres" {
+		b.sql(" RETURNING ")
+		for i, column := range primaryKey {
+			if i > 0 {
+				b.sql(", ")
+			}
+			t.columnRef(b, column)
+		}
+		values := make([]interface{}, len(primaryKey))
+		ptrs := make([]interface{}, len(primaryKey))
+		for i := range values {
+			ptrs[i] = &values[i]
+		}
+		if err := tx.QueryRowContext(ctx, b.query.String(), b.args...).Scan(ptrs...); err != nil {
+			return stmt, err
+		}
+		stmt.RowsAffected = 1
+		for i, column := range primaryKey {
+			after = append(after, tableValue{column: column, value: values[i]})
+		}
+	} else {
+		res, err := tx.ExecContext(ctx, b.query.String(), b.args...)
+		if err != nil {
+			return stmt, err
+		}
+		stmt.RowsAffected, _ = res.RowsAffected()
+		if after == nil && len(primaryKey) == 1 {
+			if id, err := res.LastInsertId(); err == nil && id != 0 {
+				after = []tableValue{{column: primaryKey[0], value: id}}
+			}
+		}
+	}
+	if after != nil {
+		row, err := readTableRow(ctx, tx, t, after)
+		if err != nil {
+			return stmt, err
+		}
+		stmt.After = row
+	}
+	return stmt, nil
+}
+
+// afterTableKey 修改后行的主键，插入时未给出完整主键返回 nil
+func afterTableKey(w tableWrite, primaryKey []string) []tableValue {
+	if len(primaryKey) == 0 {
+		return nil
+	}
+	set := make(map[string]interface{}, len(w.values))
+	for _, v := range w.values {
+		set[v.column] = v.value
+	}
+	key := make([]tableValue, 0, len(primaryKey))
+	for i, column := range primaryKey {
+		if v, ok := set[column]; ok && v != nil {
+			key = append(key, tableValue{column: column, value: v})
+		} else if w.op == TableOpUpdate {
+			key = append(key, w.key[i])
+		} else {
+			return nil
+		}
+	}
+	return key
+}
+
+func tableKeyFilter(key []tableValue) []domain.TableCondition {
+	filter := make([]domain.TableCondition, len(key))
+	for i, v := range key {
+		filter[i] = domain.TableCondition{Column: v.column, Operator: "eq", Value: v.value}
+	}
+	return filter
+}
+
+func formatTableKey(key []tableValue) string {
+	parts := make([]string, len(key))
+	for i, v := range key {
+		parts[i] = fmt.Sprintf("%s=%v", v.column, v.value)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// readTableRow 在事务中按主键读取一行，不存在时返回 nil
+func readTableRow(ctx context.Context, tx *sql.Tx, t *externalTable, key []tableValue) (map[string]interface{}, error) {
+	query, err := t.selectQuery(t.columns, tableKeyFilter(key), nil, "")
+	if err != nil {
+		return nil, err
+	}
+	rows, err := tx.QueryContext(ctx, query.query.String(), query.args...)
+	if err != nil {
+		return nil, fmt.Errorf("读取行失败: %w", err)
+	}
+	defer rows.Close()
+	if !rows.Next() {
+		return nil, rows.Err()
+	}
+	return scanTableRow(rows, t.columns)
+}
@@ -0,0 +1,337 @@
+// Package services 模板应用
+//
+// - 相对坐标经仿射变换（缩放、旋转、平移）得到地图坐标；不指定变换时按画布宽高缩放，与原有行为一致
+// - 锚点：模板节点绑定到已有节点，连到该模板节点的路径改为连到已有节点
+// - 合并：与已有节点或先放置的新节点距离不超过容差的节点合并为一个，合并后首尾相同或重复的路径跳过
+// - Persist 为 true 时新节点、新路径和模板使用次数在同一事务中保存，任一失败整体回滚
+// - Revision 大于0时使用该修订的内容，模板之后的修改不影响已固定的调用方
+package services
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"robot-path-editor/internal/domain"
+	"robot-path-editor/internal/repositories"
+)
+
+// templatePlacement 模板节点放置到地图的结果
+type templatePlacement struct {
+	id       domain.NodeID
+	position domain.Position
+}
+
+// nodeGrid 按容差分格的节点索引，用于查找重合节点
+type nodeGrid struct {
+	cell  float64
+	cells map[[2]int64][]templatePlacement
+}
+
+func newNodeGrid(cell float64) *nodeGrid {
+	return &nodeGrid{cell: cell, cells: make(map[[2]int64][]templatePlacement)}
+}
+
+func (g *nodeGrid) key(p domain.Position) [2]int64 {
+	return [2]int64{int64(math.Floor(p.X / g.cell)), int64(math.Floor(p.Y / g.cell))}
+}
+
+func (g *nodeGrid) add(id domain.NodeID, p domain.Position) {
+	k := g.key(p)
+	g.cells[k] = append(g.cells[k], templatePlacement{id: id, position: p})
+}
+
+// nearest 返回距离不超过格宽的最近节点
+func (g *nodeGrid) nearest(p domain.Position) (domain.NodeID, bool) {
+	k := g.key(p)
+	best, bestDist := domain.NodeID(""), math.Inf(1)
+	for dx := int64(-1); dx <= 1; dx++ {
+		for dy := int64(-1); dy <= 1; dy++ {
+			for _, candidate := range g.cells[[2]int64{k[0] + dx, k[1] + dy}] {
+				if d := candidate.position.DistanceTo(p); d <= g.cell && d < bestDist {
+					best, bestDist = candidate.id, d
+				}
+			}
+		}
+	}
+	return best, best != ""
+}
+
+// ApplyTemplate 应用模板：按参数展开，变换到地图坐标，处理锚点与合并，可选在一个事务中保存
+func (s *templateService) ApplyTemplate(ctx context.Context, templateID string, req ApplyTemplateRequest) (*ApplyTemplateResponse, error) {
+	// 获取模板
+	template, err := s.templateRepo.GetByID(ctx, templateID)
+	if err != nil {
+		return nil, fmt.Errorf("获取模板失败: %w", err)
+	}
+
+	// 固定到指定修订时使用该修订的内容
+	source := &template.TemplateData
+	if req.Revision > 0 {
+		revision, err := s.templateRevision(ctx, template, req.Revision)
+		if err != nil {
+			return nil, err
+		}
+		source = &revision.TemplateData
+	}
+
+	// 按参数展开
+	data, err := expandTemplateData(source, req.Parameters)
+	if err != nil {
+		return nil, err
+	}
+
+	transform, err := resolveTemplateTransform(req)
+	if err != nil {
+		return nil, err
+	}
+	if math.IsNaN(req.MergeTolerance) || req.MergeTolerance < 0 || math.IsInf(req.MergeTolerance, 0) {
+		return nil, fmt.Errorf("合并容差必须是非负数")
+	}
+
+	anchors, err := s.resolveTemplateAnchors(ctx, data, req.Anchors)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &ApplyTemplateResponse{
+		NodeIDMapping: make(map[string]string),
+		PathIDMapping: make(map[string]string),
+	}
+
+	// 转换模板节点为实际节点
+	var existing, placed *nodeGrid
+	if req.MergeTolerance > 0 {
+		nodes, err := s.nodeRepo.List(ctx, repositories.NodeFilter{})
+		if err != nil {
+			return nil, fmt.Errorf("获取节点列表失败: %w", err)
+		}
+		existing, placed = newNodeGrid(req.MergeTolerance), newNodeGrid(req.MergeTolerance)
+		for _, n := range nodes {
+			existing.add(n.ID, n.Position)
+		}
+	}
+
+	usesExisting := false
+	for _, templateNode := range data.Nodes {
+		if anchor, ok := anchors[templateNode.TemplateID]; ok {
+			response.NodeIDMapping[templateNode.TemplateID] = anchor.String()
+			if response.AnchoredNodes == nil {
+				response.AnchoredNodes = make(map[string]string)
+			}
+			response.AnchoredNodes[templateNode.TemplateID] = anchor.String()
+			usesExisting = true
+			continue
+		}
+
+		position := transform(templateNode.RelativePosition)
+		if existing != nil {
+			target, ok := existing.nearest(position)
+			if ok {
+				usesExisting = true
+			} else {
+				target, ok = placed.nearest(position)
+			}
+			if ok {
+				response.NodeIDMapping[templateNode.TemplateID] = target.String()
+				if response.MergedNodes == nil {
+					response.MergedNodes = make(map[string]string)
+				}
+				response.MergedNodes[templateNode.TemplateID] = target.String()
+				continue
+			}
+		}
+
+		node := domain.NewNode(templateNode.Name, string(templateNode.Type))
+		node.Position = position
+		if templateNode.Style != (domain.NodeStyle{}) {
+			node.Style = templateNode.Style
+		}
+		node.Properties = templateNode.Properties
+		if placed != nil {
+			placed.add(node.ID, position)
+		}
+
+		// 保存ID映射
+		response.NodeIDMapping[templateNode.TemplateID] = node.ID.String()
+		response.Nodes = append(response.Nodes, *node)
+	}
+
+	// 连接已有节点时跳过与已有路径重复的路径
+	connected := make(map[[2]domain.NodeID]bool)
+	if usesExisting {
+		paths, err := s.pathRepo.List(ctx, repositories.PathFilter{})
+		if err != nil {
+			return nil, fmt.Errorf("获取路径列表失败: %w", err)
+		}
+		for _, p := range paths {
+			markConnected(connected, p.StartNodeID, p.EndNodeID, p.Direction)
+		}
+	}
+
+	// 转换模板路径为实际路径
+	for _, templatePath := range data.Paths {
+		startNodeID, ok1 := response.NodeIDMapping[templatePath.StartNodeTempID]
+		endNodeID, ok2 := response.NodeIDMapping[templatePath.EndNodeTempID]
+		if !ok1 || !ok2 || startNodeID == endNodeID {
+			response.SkippedPaths = append(response.SkippedPaths, templatePath.TemplateID)
+			continue
+		}
+		start, end := domain.NodeID(startNodeID), domain.NodeID(endNodeID)
+		if connected[[2]domain.NodeID{start, end}] ||
+			isBidirectional(templatePath.Direction) && connected[[2]domain.NodeID{end, start}] {
+			response.SkippedPaths = append(response.SkippedPaths, templatePath.TemplateID)
+			continue
+		}
+
+		path := domain.NewPath(templatePath.Name, start, end)
+		if templatePath.Type != "" {
+			path.Type = templatePath.Type
+		}
+		if templatePath.Direction != "" {
+			path.Direction = templatePath.Direction
+		}
+		if templatePath.CurveType != "" {
+			path.CurveType = templatePath.CurveType
+		}
+		if templatePath.Style != (domain.PathStyle{}) {
+			path.Style = templatePath.Style
+		}
+		path.Properties = templatePath.Properties
+		markConnected(connected, start, end, path.Direction)
+
+		// 保存ID映射
+		response.PathIDMapping[templatePath.TemplateID] = path.ID.String()
+		response.Paths = append(response.Paths, *path)
+	}
+
+	// 增加使用次数，保存时与节点和路径在同一事务中
+	template.IncrementUsage()
+	if req.Persist {
+		err := s.transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+			for i := range response.Nodes {
+				if err := s.nodeRepo.Create(ctx, &response.Nodes[i]); err != nil {
+					return fmt.Errorf("保存节点 %s 失败: %w", response.Nodes[i].Name, err)
+				}
+			}
+			for i := range response.Paths {
+				if err := s.pathRepo.Create(ctx, &response.Paths[i]); err != nil {
+					return fmt.Errorf("保存路径 %s 失败: %w", response.Paths[i].Name, err)
+				}
+			}
+			return s.templateRepo.Update(ctx, template)
+		})
+		if err != nil {
+			return nil, fmt.Errorf("应用模板失败，已回滚: %w", err)
+		}
+		response.Persisted = true
+	} else {
+		s.templateRepo.Update(ctx, template)
+	}
+
+	response.Revision = template.Metadata.Version
+	if req.Revision > 0 {
+		response.Revision = req.Revision
+	}
+	response.Message = fmt.Sprintf("已应用模板 '%s'，创建了 %d 个节点和 %d 条路径", template.Name, len(response.Nodes), len(response.Paths))
+	if n := len(response.AnchoredNodes) + len(response.MergedNodes); n > 0 {
+		response.Message += fmt.Sprintf("，%d 个节点绑定或合并到已有节点", n)
+	}
+	if len(response.SkippedPaths) > 0 {
+		response.Message += fmt.Sprintf("，跳过 %d 条路径", len(response.SkippedPaths))
+	}
+	return response, nil
+}
+
+// resolveTemplateTransform 生成相对坐标到地图坐标的变换
+func resolveTemplateTransform(req ApplyTemplateRequest) (func(domain.RelativePosition) domain.Position, error) {
+	t := TemplateTransform{ScaleX: float64(req.Width), ScaleY: float64(req.Height), ScaleZ: 100}
+	if req.Transform != nil {
+		t.Origin = req.Transform.Origin
+		t.Rotation = req.Transform.Rotation
+		t.FlipY = req.Transform.FlipY
+		if req.Transform.ScaleX != 0 {
+			t.ScaleX = req.Transform.ScaleX
+		}
+		if req.Transform.ScaleY != 0 {
+			t.ScaleY = req.Transform.ScaleY
+		}
+		if req.Transform.ScaleZ != 0 {
+			t.ScaleZ = req.Transform.ScaleZ
+		}
+	}
+	for _, v := range []float64{t.Origin.X, t.Origin.Y, t.Origin.Z, t.Rotation, t.ScaleX, t.ScaleY, t.ScaleZ} {
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return nil, fmt.Errorf("变换参数必须是有限数值")
+		}
+	}
+
+	rad := t.Rotation * math.Pi / 180
+	sin, cos := math.Sincos(rad)
+	flip := 1.0
+	if t.FlipY {
+		flip = -1
+	}
+	return func(rp domain.RelativePosition) domain.Position {
+		x, y := rp.X*t.ScaleX, flip*rp.Y*t.ScaleY
+		return domain.Position{
+			X: t.Origin.X + x*cos - y*sin,
+			Y: t.Origin.Y + x*sin + y*cos,
+			Z: t.Origin.Z + rp.Z*t.ScaleZ,
+		}
+	}, nil
+}
+
+// resolveTemplateAnchors 检查锚点：模板节点存在（展开后的ID），目标节点存在
+func (s *templateService) resolveTemplateAnchors(ctx context.Context, data *domain.TemplateData, anchors map[string]domain.NodeID) (map[string]domain.NodeID, error) {
+	if len(anchors) == 0 {
+		return nil, nil
+	}
+
+	templateNodes := make(map[string]bool, len(data.Nodes))
+	for _, n := range data.Nodes {
+		templateNodes[n.TemplateID] = true
+	}
+	var problems []string
+	ids := make([]domain.NodeID, 0, len(anchors))
+	for templateID, nodeID := range anchors {
+		if !templateNodes[templateID] {
+			problems = append(problems, fmt.Sprintf("模板中没有节点 %s", templateID))
+		}
+		ids = append(ids, nodeID)
+	}
+
+	nodes, err := s.nodeRepo.GetByIDs(ctx, ids)
+	if err != nil {
+		return nil, fmt.Errorf("获取锚点节点失败: %w", err)
+	}
+	found := make(map[domain.NodeID]bool, len(nodes))
+	for _, n := range nodes {
+		found[n.ID] = true
+	}
+	for templateID, nodeID := range anchors {
+		if !found[nodeID] {
+			problems = append(problems, fmt.Sprintf("锚点 %s 绑定的节点 %s 不存在", templateID, nodeID))
+		}
+	}
+	if len(problems) > 0 {
+		sort.Strings(problems)
+		return nil, fmt.Errorf("锚点无效: %s", strings.Join(problems, "; "))
+	}
+	return anchors, nil
+}
+
+// markConnected 记录已连接的节点对，双向路径两个方向都记录
+func markConnected(connected map[[2]domain.NodeID]bool, start, end domain.NodeID, direction string) {
+	connected[[2]domain.NodeID{start, end}] = true
+	if isBidirectional(direction) {
+		connected[[2]domain.NodeID{end, start}] = true
+	}
+}
+
+func isBidirectional(direction string) bool {
+	normalized, _ := domain.NormalizeDirection(direction)
+	return normalized == domain.DirectionBidirectional
+}
@@ -0,0 +1,28 @@
+package services
+
+import (
+	"testing"
+
+	"robot-path-editor/internal/domain"
+)
+
+func TestIsBidirectional(t *testing.T) {
+	tests := []struct {
+		direction string
+		want      bool
+	}{
+		{direction: "", want: true},
+		{direction: domain.DirectionBidirectional, want: true},
+		{direction: domain.DirectionForward},
+		{direction: domain.DirectionOneWay},
+		{direction: domain.DirectionUnidirectional},
+		{direction: domain.DirectionBackward},
+		{direction: domain.DirectionReverse},
+	}
+
+	for _, tt := range tests {
+		if got := isBidirectional(tt.direction); got != tt.want {
+			t.Errorf("isBidirectional(%q) = %v, want %v", tt.direction, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,525 @@
+// Package services 模板表达式
+//
+// - 数值、字符串（单引号或双引号）、布尔值和变量（模板参数、循环变量）
+// - 运算符按优先级从低到高：?:、||、&&、== != < <= > >=、+ -、* / %、一元 - !
+// - + 任一侧为字符串时拼接；函数：min、max、abs、floor、ceil、round、sqrt、sin、cos、pad(n, 宽度)，宽度为 0-32
+// - 字符串插值：名称和ID中的 {表达式} 替换为结果，{{ 和 }} 表示花括号本身
+package services
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// exprPadLimit pad 的最大宽度
+const exprPadLimit = 32
+
+// exprScope 变量作用域，内层覆盖外层
+type exprScope struct {
+	vars   map[string]interface{}
+	parent *exprScope
+}
+
+func (s *exprScope) lookup(name string) (interface{}, bool) {
+	for scope := s; scope != nil; scope = scope.parent {
+		if v, ok := scope.vars[name]; ok {
+			return v, true
+		}
+	}
+	return nil, false
+}
+
+// exprNode 表达式语法树节点，求值结果为 float64、string 或 bool
+type exprNode interface {
+	eval(scope *exprScope) (interface{}, error)
+}
+
+type exprLiteral struct{ value interface{} }
+
+type exprVar struct{ name string }
+
+type exprUnary struct {
+	op      string
+	operand exprNode
+}
+
+type exprBinary struct {
+	op          string
+	left, right exprNode
+}
+
+type exprTernary struct {
+	cond, then, otherwise exprNode
+}
+
+type exprCall struct {
+	name string
+	args []exprNode
+}
+
+func (n exprLiteral) eval(*exprScope) (interface{}, error) { return n.value, nil }
+
+func (n exprVar) eval(scope *exprScope) (interface{}, error) {
+	if v, ok := scope.lookup(n.name); ok {
+		return v, nil
+	}
+	return nil, fmt.Errorf("未定义的变量 %s", n.name)
+}
+
+func (n exprUnary) eval(scope *exprScope) (interface{}, error) {
+	v, err := n.operand.eval(scope)
+	if err != nil {
+		return nil, err
+	}
+	if n.op == "!" {
+		return !exprTruthy(v), nil
+	}
+	f, err := exprNumber(v)
+	if err != nil {
+		return nil, err
+	}
+	return -f, nil
+}
+
+func (n exprTernary) eval(scope *exprScope) (interface{}, error) {
+	cond, err := n.cond.eval(scope)
+	if err != nil {
+		return nil, err
+	}
+	if exprTruthy(cond) {
+		return n.then.eval(scope)
+	}
+	return n.otherwise.eval(scope)
+}
+
+func (n exprBinary) eval(scope *exprScope) (interface{}, error) {
+	left, err := n.left.eval(scope)
+	if err != nil {
+		return nil, err
+	}
+	// 逻辑运算短路
+	switch n.op {
+	case "&&":
+		if !exprTruthy(left) {
+			return false, nil
+		}
+		right, err := n.right.eval(scope)
+		if err != nil {
+			return nil, err
+		}
+		return exprTruthy(right), nil
+	case "||":
+		if exprTruthy(left) {
+			return true, nil
+		}
+		right, err := n.right.eval(scope)
+		if err != nil {
+			return nil, err
+		}
+		return exprTruthy(right), nil
+	}
+
+	right, err := n.right.eval(scope)
+	if err != nil {
+		return nil, err
+	}
+
+	_, leftString := left.(string)
+	_, rightString := right.(string)
+	switch n.op {
+	case "==", "!=":
+		equal := left == right
+		if !leftString && !rightString {
+			if a, err := exprNumber(left); err == nil {
+				if b, err := exprNumber(right); err == nil {
+					equal = a == b
+				}
+			}
+		}
+		return equal == (n.op == "=="), nil
+	case "+":
+		if leftString || rightString {
+			return formatExprValue(left) + formatExprValue(right), nil
+		}
+	}
+
+	if leftString && rightString {
+		a, b := left.(string), right.(string)
+		switch n.op {
+		case "<":
+			return a < b, nil
+		case "<=":
+			return a <= b, nil
+		case ">":
+			return a > b, nil
+		case ">=":
+			return a >= b, nil
+		}
+	}
+
+	a, err := exprNumber(left)
+	if err != nil {
+		return nil, err
+	}
+	b, err := exprNumber(right)
+	if err != nil {
+		return nil, err
+	}
+	switch n.op {
+	case "+":
+		return a + b, nil
+	case "-":
+		return a - b, nil
+	case "*":
+		return a * b, nil
+	case "/":
+		if b == 0 {
+			return nil, fmt.Errorf("除数为零")
+		}
+		return a / b, nil
+	case "%":
+		if b == 0 {
+			return nil, fmt.Errorf("除数为零")
+		}
+		return math.Mod(a, b), nil
+	case "<":
+		return a < b, nil
+	case "<=":
+		return a <= b, nil
+	case ">":
+		return a > b, nil
+	case ">=":
+		return a >= b, nil
+	}
+	return nil, fmt.Errorf("未知运算符 %s", n.op)
+}
+
+func (n exprCall) eval(scope *exprScope) (interface{}, error) {
+	args := make([]float64, len(n.args))
+	for i, arg := range n.args {
+		v, err := arg.eval(scope)
+		if err != nil {
+			return nil, err
+		}
+		if args[i], err = exprNumber(v); err != nil {
+			return nil, fmt.Errorf("%s 的第 %d 个参数: %w", n.name, i+1, err)
+		}
+	}
+
+	unary := map[string]func(float64) float64{
+		"abs": math.Abs, "floor": math.Floor, "ceil": math.Ceil, "round": math.Round,
+		"sqrt": math.Sqrt, "sin": math.Sin, "cos": math.Cos,
+	}
+	if fn, ok := unary[n.name]; ok {
+		if len(args) != 1 {
+			return nil, fmt.Errorf("%s 需要1个参数", n.name)
+		}
+		return fn(args[0]), nil
+	}
+
+	switch n.name {
+	case "min", "max":
+		if len(args) == 0 {
+			return nil, fmt.Errorf("%s 至少需要1个参数", n.name)
+		}
+		result := args[0]
+		for _, v := range args[1:] {
+			if (n.name == "min") == (v < result) {
+				result = v
+			}
+		}
+		return result, nil
+	case "pad":
+		if len(args) != 2 {
+			return nil, fmt.Errorf("pad 需要2个参数")
+		}
+		if width := args[1]; width != math.Trunc(width) || width < 0 || width > exprPadLimit {
+			return nil, fmt.Errorf("pad 的宽度必须是 0-%d 的整数，实际为 %s", exprPadLimit, formatExprValue(width))
+		}
+		return fmt.Sprintf("%0*d", int(args[1]), int64(math.Round(args[0]))), nil
+	}
+	return nil, fmt.Errorf("未知函数 %s", n.name)
+}
+
+// exprNumber 转换为数值，布尔值视为 0/1
+func exprNumber(v interface{}) (float64, error) {
+	switch value := v.(type) {
+	case float64:
+		return value, nil
+	case bool:
+		if value {
+			return 1, nil
+		}
+		return 0, nil
+	}
+	return 0, fmt.Errorf("%q 不是数值", formatExprValue(v))
+}
+
+// exprTruthy 条件真值：非零数值、非空字符串、true
+func exprTruthy(v interface{}) bool {
+	switch value := v.(type) {
+	case bool:
+		return value
+	case float64:
+		return value != 0
+	case string:
+		return value != ""
+	}
+	return false
+}
+
+// formatExprValue 插值输出：整数不带小数点
+func formatExprValue(v interface{}) string {
+	switch value := v.(type) {
+	case float64:
+		if value == math.Trunc(value) && math.Abs(value) < 1e15 {
+			return strconv.FormatInt(int64(value), 10)
+		}
+		return strconv.FormatFloat(value, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(value)
+	case string:
+		return value
+	}
+	return fmt.Sprint(v)
+}
+
+// === 解析 ===
+
+type exprToken struct {
+	kind  byte // n 数值、s 字符串、i 标识符、o 运算符
+	text  string
+	value float64
+}
+
+// parseExpr 解析表达式
+func parseExpr(src string) (exprNode, error) {
+	tokens, err := tokenizeExpr(src)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) == 0 {
+		return nil, fmt.Errorf("表达式为空")
+	}
+	p := &exprParser{tokens: tokens}
+	node, err := p.ternary()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.tokens) {
+		return nil, fmt.Errorf("多余的内容 %q", p.tokens[p.pos].text)
+	}
+	return node, nil
+}
+
+func tokenizeExpr(src string) ([]exprToken, error) {
+	var tokens []exprToken
+	runes := []rune(src)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case unicode.IsDigit(r) || r == '.' && i+1 < len(runes) && unicode.IsDigit(runes[i+1]):
+			j := i
+			for j < len(runes) && (unicode.IsDigit(runes[j]) || runes[j] == '.' ||
+				(runes[j] == 'e' || runes[j] == 'E') ||
+				(runes[j] == '+' || runes[j] == '-') && j > i && (runes[j-1] == 'e' || runes[j-1] == 'E')) {
+				j++
+			}
+			v, err := strconv.ParseFloat(string(runes[i:j]), 64)
+			if err != nil {
+				return nil, fmt.Errorf("无效的数值 %q", string(runes[i:j]))
+			}
+			tokens = append(tokens, exprToken{kind: 'n', text: string(runes[i:j]), value: v})
+			i = j
+		case unicode.IsLetter(r) || r == '_':
+			j := i
+			for j < len(runes) && (unicode.IsLetter(runes[j]) || unicode.IsDigit(runes[j]) || runes[j] == '_') {
+				j++
+			}
+			tokens = append(tokens, exprToken{kind: 'i', text: string(runes[i:j])})
+			i = j
+		case r == '"' || r == '\'':
+			j := i + 1
+			var b strings.Builder
+			for ; j < len(runes) && runes[j] != r; j++ {
+				if runes[j] == '\\' && j+1 < len(runes) {
+					j++
+				}
+				b.WriteRune(runes[j])
+			}
+			if j >= len(runes) {
+				return nil, fmt.Errorf("字符串缺少结束引号")
+			}
+			tokens = append(tokens, exprToken{kind: 's', text: b.String()})
+			i = j + 1
+		default:
+			op := string(r)
+			if i+1 < len(runes) {
+				switch two := string(runes[i : i+2]); two {
+				case "&&", "||", "==", "!=", "<=", ">=":
+					op = two
+				}
+			}
+			if len(op) == 1 && !strings.ContainsRune("+-*/%<>!?:(),", r) {
+				return nil, fmt.Errorf("无效的字符 %q", op)
+			}
+			tokens = append(tokens, exprToken{kind: 'o', text: op})
+			i += len([]rune(op))
+		}
+	}
+	return tokens, nil
+}
+
+type exprParser struct {
+	tokens []exprToken
+	pos    int
+}
+
+func (p *exprParser) peekOp(ops ...string) string {
+	if p.pos < len(p.tokens) && p.tokens[p.pos].kind == 'o' {
+		for _, op := range ops {
+			if p.tokens[p.pos].text == op {
+				return op
+			}
+		}
+	}
+	return ""
+}
+
+func (p *exprParser) expect(op string) error {
+	if p.peekOp(op) == "" {
+		return fmt.Errorf("缺少 %q", op)
+	}
+	p.pos++
+	return nil
+}
+
+func (p *exprParser) ternary() (exprNode, error) {
+	cond, err := p.binary(0)
+	if err != nil {
+		return nil, err
+	}
+	if p.peekOp("?") == "" {
+		return cond, nil
+	}
+	p.pos++
+	then, err := p.ternary()
+	if err != nil {
+		return nil, err
+	}
+	if err := p.expect(":"); err != nil {
+		return nil, err
+	}
+	otherwise, err := p.ternary()
+	if err != nil {
+		return nil, err
+	}
+	return exprTernary{cond: cond, then: then, otherwise: otherwise}, nil
+}
+
+// exprLevels 二元运算符优先级，从低到高
+var exprLevels = [][]string{
+	{"||"},
+	{"&&"},
+	{"==", "!=", "<", "<=", ">", ">="},
+	{"+", "-"},
+	{"*", "/", "%"},
+}
+
+func (p *exprParser) binary(level int) (exprNode, error) {
+	if level == len(exprLevels) {
+		return p.unary()
+	}
+	left, err := p.binary(level + 1)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op := p.peekOp(exprLevels[level]...)
+		if op == "" {
+			return left, nil
+		}
+		p.pos++
+		right, err := p.binary(level + 1)
+		if err != nil {
+			return nil, err
+		}
+		left = exprBinary{op: op, left: left, right: right}
+	}
+}
+
+func (p *exprParser) unary() (exprNode, error) {
+	if op := p.peekOp("-", "!", "+"); op != "" {
+		p.pos++
+		operand, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		if op == "+" {
+			return operand, nil
+		}
+		return exprUnary{op: op, operand: operand}, nil
+	}
+	return p.primary()
+}
+
+func (p *exprParser) primary() (exprNode, error) {
+	if p.pos >= len(p.tokens) {
+		return nil, fmt.Errorf("表达式不完整")
+	}
+	tok := p.tokens[p.pos]
+	p.pos++
+	switch tok.kind {
+	case 'n':
+		return exprLiteral{value: tok.value}, nil
+	case 's':
+		return exprLiteral{value: tok.text}, nil
+	case 'i':
+		switch tok.text {
+		case "true", "false":
+			return exprLiteral{value: tok.text == "true"}, nil
+		case "pi":
+			return exprLiteral{value: math.Pi}, nil
+		}
+		if p.peekOp("(") == "" {
+			return exprVar{name: tok.text}, nil
+		}
+		p.pos++
+		call := exprCall{name: tok.text}
+		if p.peekOp(")") != "" {
+			p.pos++
+			return call, nil
+		}
+		for {
+			arg, err := p.ternary()
+			if err != nil {
+				return nil, err
+			}
+			call.args = append(call.args, arg)
+			if p.peekOp(",") != "" {
+				p.pos++
+				continue
+			}
+			if err := p.expect(")"); err != nil {
+				return nil, err
+			}
+			return call, nil
+		}
+	}
+	if tok.text == "(" {
+		node, err := p.ternary()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(")"); err != nil {
+			return nil, err
+		}
+		return node, nil
+	}
+	return nil, fmt.Errorf("意外的 %q", tok.text)
+}
@@ -0,0 +1,47 @@
+package services
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestExprPad(t *testing.T) {
+	tests := []struct {
+		src     string
+		want    string
+		wantErr string
+	}{
+		{src: "pad(7, 3)", want: "007"},
+		{src: "pad(1234, 2)", want: "1234"},
+		{src: "pad(5, 0)", want: "5"},
+		{src: "pad(2.6, 2)", want: "03"},
+		{src: "pad(1, 32)", want: strings.Repeat("0", 31) + "1"},
+		{src: "pad(1, 33)", wantErr: "0-32"},
+		{src: "pad(1, 1000000000)", wantErr: "0-32"},
+		{src: "pad(1, -5)", wantErr: "0-32"},
+		{src: "pad(1, 1.5)", wantErr: "0-32"},
+		{src: "pad(1)", wantErr: "需要2个参数"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.src, func(t *testing.T) {
+			node, err := parseExpr(tt.src)
+			if err != nil {
+				t.Fatalf("parseExpr(%q) error = %v", tt.src, err)
+			}
+			got, err := node.eval(&exprScope{})
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("eval(%q) error = %v, want %q", tt.src, err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("eval(%q) error = %v", tt.src, err)
+			}
+			if got != tt.want {
+				t.Errorf("eval(%q) = %v, want %q", tt.src, got, tt.want)
+			}
+		})
+	}
+}
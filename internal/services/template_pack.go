@@ -0,0 +1,475 @@
+// Package services 模板包（zip）
+//
+// - manifest.json 记录包格式版本和每个模板的文件名、缩略图、摘要与 SHA-256
+// - templates/ 下为单个模板文件（JSON 或 YAML，与单模板导出格式一致），thumbnails/ 下为 PNG 缩略图
+// - 导入时逐个模板独立校验和保存，单个模板失败不影响其他模板；名称冲突可改名、跳过或覆盖
+package services
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image/png"
+	"io"
+	"path"
+	"strings"
+	"time"
+
+	"robot-path-editor/internal/domain"
+	"robot-path-editor/internal/repositories"
+)
+
+// 模板包格式标识与版本
+const (
+	TemplatePackFormat  = "robot-path-editor/template-pack"
+	TemplatePackVersion = 1
+
+	templatePackManifest  = "manifest.json"
+	templatePackFileLimit = 16 << 20 // 单个文件解压后的大小上限
+)
+
+// 名称冲突处理方式
+const (
+	TemplateConflictRename  = "rename"
+	TemplateConflictSkip    = "skip"
+	TemplateConflictReplace = "replace"
+)
+
+// 模板包导入状态
+const (
+	TemplatePackItemCreated  = "created"
+	TemplatePackItemReplaced = "replaced"
+	TemplatePackItemSkipped  = "skipped"
+	TemplatePackItemFailed   = "failed"
+)
+
+// TemplatePackExportOptions 模板包导出选项，IDs 与 Category 都为空时导出全部模板
+type TemplatePackExportOptions struct {
+	IDs      []string `form:"ids" json:"ids"` // 支持重复参数或逗号分隔
+	Category string   `form:"category" json:"category"`
+	Format   string   `form:"format" json:"format"` // 包内模板文件格式：json（默认）、yaml
+}
+
+// TemplatePackFile 模板包文件
+type TemplatePackFile struct {
+	Filename    string `json:"filename"`
+	ContentType string `json:"content_type"`
+	Count       int    `json:"count"`
+	Data        []byte `json:"-"`
+}
+
+// TemplatePackImportOptions 模板包导入选项
+type TemplatePackImportOptions struct {
+	DryRun     bool   `form:"dry_run" json:"dry_run"`         // 仅校验并报告处理结果，不保存
+	OnConflict string `form:"on_conflict" json:"on_conflict"` // rename（默认）、skip、replace
+}
+
+// TemplatePackImportResult 模板包导入结果
+type TemplatePackImportResult struct {
+	DryRun   bool                     `json:"dry_run"`
+	Total    int                      `json:"total"`
+	Created  int                      `json:"created"`
+	Replaced int                      `json:"replaced"`
+	Skipped  int                      `json:"skipped"`
+	Failed   int                      `json:"failed"`
+	Items    []TemplatePackImportItem `json:"items"`
+}
+
+// TemplatePackImportItem 单个模板的导入结果
+type TemplatePackImportItem struct {
+	File       string            `json:"file"`
+	Name       string            `json:"name"`
+	Status     string            `json:"status"`
+	TemplateID domain.TemplateID `json:"template_id,omitempty"`
+	Renamed    bool              `json:"renamed,omitempty"`
+	Error      string            `json:"error,omitempty"`
+	Problems   []string          `json:"problems,omitempty"`
+}
+
+// templatePackManifestFile 清单
+type templatePackManifestFile struct {
+	Format        string              `json:"format"`
+	FormatVersion int                 `json:"format_version"`
+	CreatedAt     time.Time           `json:"created_at"`
+	Templates     []templatePackEntry `json:"templates"`
+}
+
+// templatePackEntry 清单中的模板条目
+type templatePackEntry struct {
+	File       string            `json:"file"`
+	Thumbnail  string            `json:"thumbnail,omitempty"`
+	Name       string            `json:"name"`
+	Category   string            `json:"category,omitempty"`
+	LayoutType domain.LayoutType `json:"layout_type"`
+	NodeCount  int               `json:"node_count"`
+	PathCount  int               `json:"path_count"`
+	SHA256     string            `json:"sha256"`
+}
+
+// ExportTemplatePack 导出模板包
+func (s *templateService) ExportTemplatePack(ctx context.Context, opts TemplatePackExportOptions) (*TemplatePackFile, error) {
+	format := strings.ToLower(strings.TrimSpace(opts.Format))
+	switch format {
+	case "", TemplateEncodingJSON:
+		format = TemplateEncodingJSON
+	case TemplateEncodingYAML, "yml":
+		format = TemplateEncodingYAML
+	default:
+		return nil, fmt.Errorf("不支持的模板格式: %s", opts.Format)
+	}
+
+	templates, err := s.packTemplates(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	if len(templates) == 0 {
+		return nil, fmt.Errorf("没有可导出的模板")
+	}
+
+	now := time.Now()
+	manifest := templatePackManifestFile{
+		Format:        TemplatePackFormat,
+		FormatVersion: TemplatePackVersion,
+		CreatedAt:     now.UTC().Truncate(time.Second),
+	}
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for i, template := range templates {
+		content, err := encodeTemplateDocument(newTemplateDocument(template), format)
+		if err != nil {
+			return nil, err
+		}
+		base := fmt.Sprintf("%03d-%s", i+1, templateSlug(template.Name))
+		sum := sha256.Sum256(content)
+		entry := templatePackEntry{
+			File:       "templates/" + base + "." + format,
+			Name:       template.Name,
+			Category:   template.Category,
+			LayoutType: template.LayoutType,
+			NodeCount:  len(template.TemplateData.Nodes),
+			PathCount:  len(template.TemplateData.Paths),
+			SHA256:     hex.EncodeToString(sum[:]),
+		}
+		if err := writeZipEntry(zw, entry.File, now, content); err != nil {
+			return nil, err
+		}
+		if thumbnail := templateThumbnailPNG(template); thumbnail != nil {
+			entry.Thumbnail = "thumbnails/" + base + ".png"
+			if err := writeZipEntry(zw, entry.Thumbnail, now, thumbnail); err != nil {
+				return nil, err
+			}
+		}
+		manifest.Templates = append(manifest.Templates, entry)
+	}
+
+	manifestData, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("序列化模板包清单失败: %w", err)
+	}
+	if err := writeZipEntry(zw, templatePackManifest, now, append(manifestData, '\n')); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, fmt.Errorf("生成模板包失败: %w", err)
+	}
+
+	name := "templates"
+	if opts.Category != "" {
+		name += "-" + templateSlug(opts.Category)
+	}
+	return &TemplatePackFile{
+		Filename:    fmt.Sprintf("%s-%s.zip", name, now.Format("20060102-150405")),
+		ContentType: "application/zip",
+		Count:       len(templates),
+		Data:        buf.Bytes(),
+	}, nil
+}
+
+// packTemplates 按导出选项选取模板，指定ID时保持请求顺序
+func (s *templateService) packTemplates(ctx context.Context, opts TemplatePackExportOptions) ([]*domain.Template, error) {
+	var ids []string
+	for _, value := range opts.IDs {
+		for _, id := range strings.Split(value, ",") {
+			if id = strings.TrimSpace(id); id != "" {
+				ids = append(ids, id)
+			}
+		}
+	}
+
+	if len(ids) == 0 {
+		templates, err := s.templateRepo.List(ctx, repositories.ListTemplatesOptions{
+			Category:  opts.Category,
+			SortBy:    "name",
+			SortOrder: "asc",
+		})
+		if err != nil {
+			return nil, fmt.Errorf("获取模板列表失败: %w", err)
+		}
+		return templates, nil
+	}
+
+	templates := make([]*domain.Template, 0, len(ids))
+	seen := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		template, err := s.templateRepo.GetByID(ctx, id)
+		if err != nil {
+			return nil, fmt.Errorf("获取模板 %s 失败: %w", id, err)
+		}
+		if opts.Category != "" && template.Category != opts.Category {
+			continue
+		}
+		templates = append(templates, template)
+	}
+	return templates, nil
+}
+
+// templateThumbnailPNG 取模板缩略图，缺失时重新生成；模板为空时返回 nil
+func templateThumbnailPNG(template *domain.Template) []byte {
+	const prefix = "data:image/png;base64,"
+	if strings.HasPrefix(template.Preview.Thumbnail, prefix) {
+		if data, err := base64.StdEncoding.DecodeString(template.Preview.Thumbnail[len(prefix):]); err == nil {
+			return data
+		}
+	}
+
+	copied := *template
+	updateTemplateThumbnail(&copied)
+	if !strings.HasPrefix(copied.Preview.Thumbnail, prefix) {
+		return nil
+	}
+	data, err := base64.StdEncoding.DecodeString(copied.Preview.Thumbnail[len(prefix):])
+	if err != nil {
+		return nil
+	}
+	return data
+}
+
+func writeZipEntry(zw *zip.Writer, name string, modified time.Time, data []byte) error {
+	w, err := zw.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: modified})
+	if err != nil {
+		return fmt.Errorf("写入 %s 失败: %w", name, err)
+	}
+	if _, err := w.Write(data); err != nil {
+		return fmt.Errorf("写入 %s 失败: %w", name, err)
+	}
+	return nil
+}
+
+// ImportTemplatePack 导入模板包
+func (s *templateService) ImportTemplatePack(ctx context.Context, data []byte, opts TemplatePackImportOptions) (*TemplatePackImportResult, error) {
+	switch opts.OnConflict {
+	case "":
+		opts.OnConflict = TemplateConflictRename
+	case TemplateConflictRename, TemplateConflictSkip, TemplateConflictReplace:
+	default:
+		return nil, fmt.Errorf("无效的冲突处理方式: %s（可选 rename、skip、replace）", opts.OnConflict)
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("读取模板包失败: %w", err)
+	}
+	files := make(map[string]*zip.File, len(zr.File))
+	for _, f := range zr.File {
+		files[f.Name] = f
+	}
+
+	manifest, err := readTemplatePackManifest(files)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &TemplatePackImportResult{DryRun: opts.DryRun, Total: len(manifest.Templates)}
+	reserved := make(map[string]bool) // 本次导入已占用的名称
+	for _, entry := range manifest.Templates {
+		item := s.importPackEntry(ctx, files, entry, opts, reserved)
+		switch item.Status {
+		case TemplatePackItemCreated:
+			result.Created++
+		case TemplatePackItemReplaced:
+			result.Replaced++
+		case TemplatePackItemSkipped:
+			result.Skipped++
+		default:
+			result.Failed++
+		}
+		result.Items = append(result.Items, item)
+	}
+	return result, nil
+}
+
+// readTemplatePackManifest 读取并检查清单
+func readTemplatePackManifest(files map[string]*zip.File) (*templatePackManifestFile, error) {
+	f, ok := files[templatePackManifest]
+	if !ok {
+		return nil, fmt.Errorf("模板包缺少 %s", templatePackManifest)
+	}
+	data, err := readZipEntry(f)
+	if err != nil {
+		return nil, err
+	}
+
+	var manifest templatePackManifestFile
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("解析模板包清单失败: %w", err)
+	}
+	if manifest.Format != TemplatePackFormat {
+		return nil, fmt.Errorf("不支持的模板包格式: %q", manifest.Format)
+	}
+	if manifest.FormatVersion > TemplatePackVersion {
+		return nil, fmt.Errorf("模板包版本 %d 高于当前支持的版本 %d，请升级后再导入", manifest.FormatVersion, TemplatePackVersion)
+	}
+	if len(manifest.Templates) == 0 {
+		return nil, fmt.Errorf("模板包中没有模板")
+	}
+	return &manifest, nil
+}
+
+// importPackEntry 导入清单中的单个模板
+func (s *templateService) importPackEntry(ctx context.Context, files map[string]*zip.File, entry templatePackEntry, opts TemplatePackImportOptions, reserved map[string]bool) TemplatePackImportItem {
+	item := TemplatePackImportItem{File: entry.File, Name: entry.Name}
+	fail := func(err error) TemplatePackImportItem {
+		item.Status = TemplatePackItemFailed
+		item.Error = err.Error()
+		var validation *TemplateValidationError
+		if errors.As(err, &validation) {
+			item.Problems = validation.Problems
+		}
+		return item
+	}
+
+	f, ok := files[entry.File]
+	if !ok {
+		return fail(fmt.Errorf("模板包中缺少文件 %s", entry.File))
+	}
+	content, err := readZipEntry(f)
+	if err != nil {
+		return fail(err)
+	}
+	if entry.SHA256 != "" {
+		sum := sha256.Sum256(content)
+		if !strings.EqualFold(hex.EncodeToString(sum[:]), entry.SHA256) {
+			return fail(fmt.Errorf("文件 %s 校验和不匹配", entry.File))
+		}
+	}
+
+	encoding := TemplateEncodingJSON
+	if ext := strings.ToLower(path.Ext(entry.File)); ext == ".yaml" || ext == ".yml" {
+		encoding = TemplateEncodingYAML
+	}
+	doc, err := parseTemplateDocument(content, encoding)
+	if err != nil {
+		return fail(err)
+	}
+	template := templateFromDocument(doc)
+	item.Name = template.Name
+	if thumbnail := readPackThumbnail(files, entry.Thumbnail); thumbnail != "" {
+		template.Preview.Thumbnail = thumbnail
+	}
+
+	existing, err := s.templateRepo.GetByName(ctx, template.Name)
+	if err != nil {
+		return fail(fmt.Errorf("检查模板名称失败: %w", err))
+	}
+	conflict := existing != nil || reserved[template.Name]
+	var previous *domain.Template
+	switch {
+	case conflict && opts.OnConflict == TemplateConflictSkip:
+		item.Status = TemplatePackItemSkipped
+		if existing != nil {
+			item.TemplateID = existing.ID
+		}
+		return item
+	case conflict && opts.OnConflict == TemplateConflictReplace && existing != nil:
+		previous = existing
+		template.ID = existing.ID
+		template.UsageCount = existing.UsageCount
+		template.Metadata.CreatedAt = existing.Metadata.CreatedAt
+		template.Metadata.Version = existing.Metadata.Version + 1
+		item.Status = TemplatePackItemReplaced
+	case conflict:
+		name, err := s.availableTemplateName(ctx, template.Name, reserved)
+		if err != nil {
+			return fail(err)
+		}
+		template.Name = name
+		item.Name = name
+		item.Renamed = true
+		item.Status = TemplatePackItemCreated
+	default:
+		item.Status = TemplatePackItemCreated
+	}
+	reserved[template.Name] = true
+	item.TemplateID = template.ID
+
+	if opts.DryRun {
+		return item
+	}
+	if err := s.saveTemplateVersion(ctx, template, previous, "从模板包导入"); err != nil {
+		return fail(fmt.Errorf("保存模板失败: %w", err))
+	}
+	return item
+}
+
+// availableTemplateName 生成不冲突的名称：名称 (2)、名称 (3)…
+func (s *templateService) availableTemplateName(ctx context.Context, name string, reserved map[string]bool) (string, error) {
+	for i := 2; i < 1000; i++ {
+		candidate := fmt.Sprintf("%s (%d)", name, i)
+		if reserved[candidate] {
+			continue
+		}
+		existing, err := s.templateRepo.GetByName(ctx, candidate)
+		if err != nil {
+			return "", fmt.Errorf("检查模板名称失败: %w", err)
+		}
+		if existing == nil {
+			return candidate, nil
+		}
+	}
+	return "", fmt.Errorf("无法为模板 %s 生成不重复的名称", name)
+}
+
+// readPackThumbnail 读取包内缩略图，不是有效 PNG 时忽略
+func readPackThumbnail(files map[string]*zip.File, name string) string {
+	f, ok := files[name]
+	if name == "" || !ok {
+		return ""
+	}
+	data, err := readZipEntry(f)
+	if err != nil {
+		return ""
+	}
+	if _, err := png.DecodeConfig(bytes.NewReader(data)); err != nil {
+		return ""
+	}
+	return "data:image/png;base64," + base64.StdEncoding.EncodeToString(data)
+}
+
+// readZipEntry 读取 zip 条目，限制解压后的大小
+func readZipEntry(f *zip.File) ([]byte, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return nil, fmt.Errorf("读取 %s 失败: %w", f.Name, err)
+	}
+	defer rc.Close()
+
+	data, err := io.ReadAll(io.LimitReader(rc, templatePackFileLimit+1))
+	if err != nil {
+		return nil, fmt.Errorf("读取 %s 失败: %w", f.Name, err)
+	}
+	if len(data) > templatePackFileLimit {
+		return nil, fmt.Errorf("文件 %s 超过大小上限 %d 字节", f.Name, templatePackFileLimit)
+	}
+	return data, nil
+}
@@ -0,0 +1,467 @@
+// Package services 参数化模板展开
+//
+// - 参数值按定义校验类型、范围和可选值，未提供时使用默认值
+// - 顶层和重复块中的节点、路径：ID、名称、端点和字符串属性支持 {表达式} 插值，位置支持表达式，when 为假时跳过
+// - 重复块可嵌套，内层可以引用外层的循环变量；展开结果是不含参数的普通模板数据
+package services
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"robot-path-editor/internal/domain"
+)
+
+// templateExpandLimit 展开后节点与路径总数上限，重复块的每次迭代也计入，空的嵌套重复块同样受限
+const templateExpandLimit = 10000
+
+// TemplateExpandError 模板参数或展开错误
+type TemplateExpandError struct {
+	Problems []string `json:"problems"`
+}
+
+// Error 实现 error 接口
+func (e *TemplateExpandError) Error() string {
+	return fmt.Sprintf("模板展开失败: %s", strings.Join(e.Problems, "; "))
+}
+
+// templateExpander 展开状态，表达式解析结果按源文本缓存
+type templateExpander struct {
+	cache    map[string]exprNode
+	nodes    []domain.TemplateNode
+	paths    []domain.TemplatePath
+	nodeIDs  map[string]bool
+	elements int
+}
+
+// expandTemplateData 按参数值展开模板，values 为 nil 时全部使用默认值；普通模板原样返回
+func expandTemplateData(data *domain.TemplateData, values map[string]interface{}) (*domain.TemplateData, error) {
+	if !data.IsParameterized() {
+		if len(values) > 0 {
+			return nil, &TemplateExpandError{Problems: []string{"模板没有定义参数"}}
+		}
+		return data, nil
+	}
+
+	resolved, err := resolveTemplateParameters(data.Parameters, values)
+	if err != nil {
+		return nil, err
+	}
+
+	e := &templateExpander{cache: make(map[string]exprNode), nodeIDs: make(map[string]bool)}
+	scope := &exprScope{vars: resolved}
+	if err := e.expandBlock(data.Nodes, data.Paths, data.Repeats, scope, "template_data"); err != nil {
+		return nil, &TemplateExpandError{Problems: []string{err.Error()}}
+	}
+
+	// 重复块中的路径可能引用后展开的节点，全部展开后再检查端点
+	for i, p := range e.paths {
+		for _, end := range []string{p.StartNodeTempID, p.EndNodeTempID} {
+			if !e.nodeIDs[end] {
+				return nil, &TemplateExpandError{Problems: []string{
+					fmt.Sprintf("展开后的路径 %s（第 %d 条）引用了不存在的节点 %q", p.TemplateID, i+1, end),
+				}}
+			}
+		}
+	}
+
+	expanded := *data
+	expanded.Nodes = e.nodes
+	expanded.Paths = e.paths
+	expanded.Parameters = nil
+	expanded.Repeats = nil
+	if expanded.Nodes == nil {
+		expanded.Nodes = []domain.TemplateNode{}
+	}
+	if expanded.Paths == nil {
+		expanded.Paths = []domain.TemplatePath{}
+	}
+	return &expanded, nil
+}
+
+// expandTemplatePreview 用于预览、缩略图和校验的展开：参数取默认值，没有默认值的参数取示例值
+// （数值取下限或1，字符串取第一个可选值，布尔值取 false）
+func expandTemplatePreview(data *domain.TemplateData) (*domain.TemplateData, error) {
+	if !data.IsParameterized() {
+		return data, nil
+	}
+	values := make(map[string]interface{})
+	for _, param := range data.Parameters {
+		if param.Default != nil || !isExprIdentifier(param.Name) {
+			continue
+		}
+		switch param.Type {
+		case domain.TemplateParamInt, domain.TemplateParamFloat:
+			v := 1.0
+			if param.Min != nil && v < *param.Min {
+				v = *param.Min
+			}
+			if param.Max != nil && v > *param.Max {
+				v = *param.Max
+			}
+			values[param.Name] = v
+		case domain.TemplateParamString:
+			v := ""
+			if len(param.Options) > 0 {
+				v = param.Options[0]
+			}
+			values[param.Name] = v
+		case domain.TemplateParamBool:
+			values[param.Name] = false
+		}
+	}
+	return expandTemplateData(data, values)
+}
+
+// resolveTemplateParameters 合并参数值与默认值并校验，返回全部问题
+func resolveTemplateParameters(params []domain.TemplateParameter, values map[string]interface{}) (map[string]interface{}, error) {
+	var problems []string
+	resolved := make(map[string]interface{}, len(params))
+	declared := make(map[string]bool, len(params))
+
+	for _, param := range params {
+		if param.Name == "" || !isExprIdentifier(param.Name) {
+			problems = append(problems, fmt.Sprintf("参数名 %q 无效（只能包含字母、数字和下划线，且不能以数字开头）", param.Name))
+			continue
+		}
+		if declared[param.Name] {
+			problems = append(problems, fmt.Sprintf("参数 %s 重复定义", param.Name))
+			continue
+		}
+		declared[param.Name] = true
+
+		value, ok := values[param.Name]
+		if !ok || value == nil {
+			value = param.Default
+		}
+		if value == nil {
+			problems = append(problems, fmt.Sprintf("缺少参数 %s", param.Name))
+			continue
+		}
+		v, err := coerceTemplateParameter(param, value)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("参数 %s: %v", param.Name, err))
+			continue
+		}
+		resolved[param.Name] = v
+	}
+
+	for name := range values {
+		if !declared[name] {
+			problems = append(problems, fmt.Sprintf("未知参数 %s", name))
+		}
+	}
+	if len(problems) > 0 {
+		return nil, &TemplateExpandError{Problems: problems}
+	}
+	return resolved, nil
+}
+
+// coerceTemplateParameter 按参数类型转换并检查范围
+func coerceTemplateParameter(param domain.TemplateParameter, value interface{}) (interface{}, error) {
+	switch param.Type {
+	case domain.TemplateParamInt, domain.TemplateParamFloat:
+		var f float64
+		switch v := value.(type) {
+		case float64:
+			f = v
+		case int:
+			f = float64(v)
+		case string:
+			if _, err := fmt.Sscan(v, &f); err != nil {
+				return nil, fmt.Errorf("%q 不是数值", v)
+			}
+		default:
+			return nil, fmt.Errorf("必须是数值")
+		}
+		if math.IsNaN(f) || math.IsInf(f, 0) {
+			return nil, fmt.Errorf("必须是有限数值")
+		}
+		if param.Type == domain.TemplateParamInt && f != math.Trunc(f) {
+			return nil, fmt.Errorf("必须是整数")
+		}
+		if param.Min != nil && f < *param.Min {
+			return nil, fmt.Errorf("不能小于 %s", formatExprValue(*param.Min))
+		}
+		if param.Max != nil && f > *param.Max {
+			return nil, fmt.Errorf("不能大于 %s", formatExprValue(*param.Max))
+		}
+		return f, nil
+	case domain.TemplateParamString:
+		s, ok := value.(string)
+		if !ok {
+			return nil, fmt.Errorf("必须是字符串")
+		}
+		if len(param.Options) > 0 && !containsValue(param.Options, s) {
+			return nil, fmt.Errorf("必须是 %s 之一", strings.Join(param.Options, "、"))
+		}
+		return s, nil
+	case domain.TemplateParamBool:
+		switch v := value.(type) {
+		case bool:
+			return v, nil
+		case string:
+			if v == "true" || v == "false" {
+				return v == "true", nil
+			}
+		}
+		return nil, fmt.Errorf("必须是布尔值")
+	}
+	return nil, fmt.Errorf("无效的参数类型 %q（可选 int、float、string、bool）", param.Type)
+}
+
+func isExprIdentifier(name string) bool {
+	for i, r := range name {
+		if !(r == '_' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || i > 0 && r >= '0' && r <= '9') {
+			return false
+		}
+	}
+	switch name {
+	case "true", "false", "pi":
+		return false
+	}
+	return true
+}
+
+// expandBlock 展开一层节点、路径和重复块
+func (e *templateExpander) expandBlock(nodes []domain.TemplateNode, paths []domain.TemplatePath, repeats []domain.TemplateRepeat, scope *exprScope, where string) error {
+	for i := range nodes {
+		if err := e.expandNode(nodes[i], scope); err != nil {
+			return fmt.Errorf("%s.nodes[%d]: %w", where, i, err)
+		}
+	}
+	for i := range paths {
+		if err := e.expandPath(paths[i], scope); err != nil {
+			return fmt.Errorf("%s.paths[%d]: %w", where, i, err)
+		}
+	}
+	for i, repeat := range repeats {
+		if err := e.expandRepeat(repeat, scope, fmt.Sprintf("%s.repeats[%d]", where, i)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// expandRepeat 展开重复块
+func (e *templateExpander) expandRepeat(repeat domain.TemplateRepeat, scope *exprScope, where string) error {
+	name := repeat.Var
+	if name == "" {
+		name = "i"
+	}
+	if !isExprIdentifier(name) {
+		return fmt.Errorf("%s.var: 变量名 %q 无效", where, name)
+	}
+
+	from := 0.0
+	if repeat.From != "" {
+		v, err := e.number(repeat.From, scope)
+		if err != nil {
+			return fmt.Errorf("%s.from: %w", where, err)
+		}
+		from = v
+	}
+	count, err := e.number(repeat.Count, scope)
+	if err != nil {
+		return fmt.Errorf("%s.count: %w", where, err)
+	}
+	if count != math.Trunc(count) || count < 0 {
+		return fmt.Errorf("%s.count: 必须是非负整数，实际为 %s", where, formatExprValue(count))
+	}
+	if count > templateExpandLimit {
+		return fmt.Errorf("%s.count: 不能超过 %d", where, templateExpandLimit)
+	}
+
+	for k := 0; k < int(count); k++ {
+		if err := e.count(); err != nil {
+			return err
+		}
+		inner := &exprScope{vars: map[string]interface{}{name: from + float64(k)}, parent: scope}
+		if err := e.expandBlock(repeat.Nodes, repeat.Paths, repeat.Repeats, inner, fmt.Sprintf("%s（%s=%s）", where, name, formatExprValue(from+float64(k)))); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (e *templateExpander) expandNode(node domain.TemplateNode, scope *exprScope) error {
+	if ok, err := e.condition(node.When, scope); err != nil || !ok {
+		return err
+	}
+	if err := e.count(); err != nil {
+		return err
+	}
+
+	var err error
+	if node.TemplateID, err = e.interpolate(node.TemplateID, scope); err != nil {
+		return fmt.Errorf("template_id: %w", err)
+	}
+	if node.Name, err = e.interpolate(node.Name, scope); err != nil {
+		return fmt.Errorf("name: %w", err)
+	}
+	if node.Properties, err = e.interpolateProperties(node.Properties, scope); err != nil {
+		return err
+	}
+	if expr := node.PositionExpr; expr != nil {
+		for _, axis := range []struct {
+			name   string
+			source domain.TemplateExpr
+			target *float64
+		}{
+			{"x", expr.X, &node.RelativePosition.X},
+			{"y", expr.Y, &node.RelativePosition.Y},
+			{"z", expr.Z, &node.RelativePosition.Z},
+		} {
+			if axis.source == "" {
+				continue
+			}
+			v, err := e.number(axis.source, scope)
+			if err != nil {
+				return fmt.Errorf("position_expr.%s: %w", axis.name, err)
+			}
+			*axis.target = v
+		}
+		node.PositionExpr = nil
+	}
+	node.When = ""
+
+	if node.TemplateID == "" {
+		return fmt.Errorf("template_id 不能为空")
+	}
+	if e.nodeIDs[node.TemplateID] {
+		return fmt.Errorf("展开后的节点ID %q 重复，请在 template_id 中使用循环变量，例如 rack-{i}", node.TemplateID)
+	}
+	e.nodeIDs[node.TemplateID] = true
+	e.nodes = append(e.nodes, node)
+	return nil
+}
+
+func (e *templateExpander) expandPath(path domain.TemplatePath, scope *exprScope) error {
+	if ok, err := e.condition(path.When, scope); err != nil || !ok {
+		return err
+	}
+	if err := e.count(); err != nil {
+		return err
+	}
+
+	var err error
+	for _, field := range []struct {
+		name  string
+		value *string
+	}{
+		{"template_id", &path.TemplateID},
+		{"name", &path.Name},
+		{"start_node_temp_id", &path.StartNodeTempID},
+		{"end_node_temp_id", &path.EndNodeTempID},
+	} {
+		if *field.value, err = e.interpolate(*field.value, scope); err != nil {
+			return fmt.Errorf("%s: %w", field.name, err)
+		}
+	}
+	if path.Properties, err = e.interpolateProperties(path.Properties, scope); err != nil {
+		return err
+	}
+	path.When = ""
+	e.paths = append(e.paths, path)
+	return nil
+}
+
+func (e *templateExpander) count() error {
+	e.elements++
+	if e.elements > templateExpandLimit {
+		return fmt.Errorf("展开后的节点、路径和重复次数超过 %d 个", templateExpandLimit)
+	}
+	return nil
+}
+
+// eval 求值，解析结果缓存
+func (e *templateExpander) eval(src string, scope *exprScope) (interface{}, error) {
+	node, ok := e.cache[src]
+	if !ok {
+		var err error
+		if node, err = parseExpr(src); err != nil {
+			return nil, fmt.Errorf("表达式 %q 无效: %w", src, err)
+		}
+		e.cache[src] = node
+	}
+	v, err := node.eval(scope)
+	if err != nil {
+		return nil, fmt.Errorf("表达式 %q: %w", src, err)
+	}
+	return v, nil
+}
+
+func (e *templateExpander) number(expr domain.TemplateExpr, scope *exprScope) (float64, error) {
+	v, err := e.eval(string(expr), scope)
+	if err != nil {
+		return 0, err
+	}
+	f, err := exprNumber(v)
+	if err != nil {
+		return 0, fmt.Errorf("表达式 %q: %w", expr, err)
+	}
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return 0, fmt.Errorf("表达式 %q 的结果不是有限数值", expr)
+	}
+	return f, nil
+}
+
+func (e *templateExpander) condition(expr domain.TemplateExpr, scope *exprScope) (bool, error) {
+	if expr == "" {
+		return true, nil
+	}
+	v, err := e.eval(string(expr), scope)
+	if err != nil {
+		return false, fmt.Errorf("when: %w", err)
+	}
+	return exprTruthy(v), nil
+}
+
+// interpolate 替换字符串中的 {表达式}
+func (e *templateExpander) interpolate(s string, scope *exprScope) (string, error) {
+	if !strings.ContainsAny(s, "{}") {
+		return s, nil
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		switch {
+		case strings.HasPrefix(s[i:], "{{"), strings.HasPrefix(s[i:], "}}"):
+			b.WriteByte(s[i])
+			i++
+		case s[i] == '{':
+			end := strings.IndexByte(s[i:], '}')
+			if end < 0 {
+				return "", fmt.Errorf("%q 中缺少 }", s)
+			}
+			v, err := e.eval(s[i+1:i+end], scope)
+			if err != nil {
+				return "", err
+			}
+			b.WriteString(formatExprValue(v))
+			i += end
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+	return b.String(), nil
+}
+
+// interpolateProperties 对字符串属性插值，返回新的属性表
+func (e *templateExpander) interpolateProperties(props map[string]interface{}, scope *exprScope) (map[string]interface{}, error) {
+	if len(props) == 0 {
+		return props, nil
+	}
+	result := make(map[string]interface{}, len(props))
+	for key, value := range props {
+		if s, ok := value.(string); ok {
+			interpolated, err := e.interpolate(s, scope)
+			if err != nil {
+				return nil, fmt.Errorf("properties.%s: %w", key, err)
+			}
+			value = interpolated
+		}
+		result[key] = value
+	}
+	return result, nil
+}
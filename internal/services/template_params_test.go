@@ -0,0 +1,53 @@
+package services
+
+import (
+	"strings"
+	"testing"
+
+	"robot-path-editor/internal/domain"
+)
+
+func TestExpandTemplateDataLimit(t *testing.T) {
+	node := domain.TemplateNode{TemplateID: "n-{i}-{j}", Name: "N"}
+
+	tests := []struct {
+		name      string
+		repeats   []domain.TemplateRepeat
+		wantNodes int
+		wantErr   string
+	}{
+		{
+			name:      "迭代和节点都计入上限",
+			repeats:   []domain.TemplateRepeat{{Count: "100", Repeats: []domain.TemplateRepeat{{Var: "j", Count: "40", Nodes: []domain.TemplateNode{node}}}}},
+			wantNodes: 4000,
+		},
+		{
+			name:    "节点未超限但加上迭代次数超限",
+			repeats: []domain.TemplateRepeat{{Count: "100", Repeats: []domain.TemplateRepeat{{Var: "j", Count: "60", Nodes: []domain.TemplateNode{node}}}}},
+			wantErr: "超过 10000",
+		},
+		{
+			name:    "空的嵌套重复块",
+			repeats: []domain.TemplateRepeat{{Count: "10000", Repeats: []domain.TemplateRepeat{{Var: "j", Count: "10000", Repeats: []domain.TemplateRepeat{{Var: "k", Count: "10000"}}}}}},
+			wantErr: "超过 10000",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expanded, err := expandTemplateData(&domain.TemplateData{Repeats: tt.repeats}, nil)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expandTemplateData() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("expandTemplateData() error = %v", err)
+			}
+			if len(expanded.Nodes) != tt.wantNodes {
+				t.Errorf("expandTemplateData() = %d nodes, want %d", len(expanded.Nodes), tt.wantNodes)
+			}
+		})
+	}
+}
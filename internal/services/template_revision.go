@@ -0,0 +1,339 @@
+// Package services 模板修订
+//
+// - 每次创建、更新、恢复模板都追加一条不可变修订，修订号即模板版本号，与模板在同一事务中保存
+// - 升级前已存在的模板没有修订，第一次更新时先补建更新前版本的修订
+// - 差异按模板节点ID、路径ID和参数名匹配，变更字段取 JSON 字段名
+// - 恢复旧修订不改写历史，而是以旧内容生成一个新版本
+// - 使用次数等统计字段的变化不产生修订
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"robot-path-editor/internal/domain"
+)
+
+// TemplateRevisionSummary 修订列表项（变更日志）
+type TemplateRevisionSummary struct {
+	Revision  int       `json:"revision"`
+	Name      string    `json:"name"`
+	Message   string    `json:"message"`
+	Changes   string    `json:"changes"`
+	NodeCount int       `json:"node_count"`
+	PathCount int       `json:"path_count"`
+	CreatedBy string    `json:"created_by"`
+	CreatedAt time.Time `json:"created_at"`
+	Current   bool      `json:"current"`
+}
+
+// TemplateDiff 两个修订之间的差异
+type TemplateDiff struct {
+	TemplateID     domain.TemplateID `json:"template_id"`
+	From           int               `json:"from"`
+	To             int               `json:"to"`
+	Fields         []string          `json:"fields"` // 变化的模板属性
+	Nodes          TemplateDiffSet   `json:"nodes"`
+	Paths          TemplateDiffSet   `json:"paths"`
+	Parameters     TemplateDiffSet   `json:"parameters"`
+	RepeatsChanged bool              `json:"repeats_changed"`
+	Summary        string            `json:"summary"`
+}
+
+// TemplateDiffSet 一类元素的增删改
+type TemplateDiffSet struct {
+	Added   []string             `json:"added"`
+	Removed []string             `json:"removed"`
+	Changed []TemplateDiffChange `json:"changed"`
+}
+
+// TemplateDiffChange 单个元素的变化字段
+type TemplateDiffChange struct {
+	ID     string   `json:"id"`
+	Fields []string `json:"fields"`
+}
+
+// Empty 没有任何变化
+func (d *TemplateDiffSet) Empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Changed) == 0
+}
+
+// saveTemplateVersion 保存模板和对应修订；previous 为更新前的模板，创建时为 nil
+func (s *templateService) saveTemplateVersion(ctx context.Context, template, previous *domain.Template, message string) error {
+	return s.transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		revision := domain.NewTemplateRevision(template, message)
+		if previous == nil {
+			if err := s.templateRepo.Create(ctx, template); err != nil {
+				return err
+			}
+			revision.Changes = "创建模板"
+			return s.templateRepo.CreateRevision(ctx, revision)
+		}
+
+		base, err := s.templateRepo.GetRevision(ctx, string(previous.ID), previous.Metadata.Version)
+		if err != nil {
+			return fmt.Errorf("获取模板修订失败: %w", err)
+		}
+		if base == nil {
+			base = domain.NewTemplateRevision(previous, "更新前的版本（补建）")
+			if err := s.templateRepo.CreateRevision(ctx, base); err != nil {
+				return fmt.Errorf("补建模板修订失败: %w", err)
+			}
+		}
+		if err := s.templateRepo.Update(ctx, template); err != nil {
+			return err
+		}
+		revision.Changes = diffTemplateRevisions(base, revision).Summary
+		return s.templateRepo.CreateRevision(ctx, revision)
+	})
+}
+
+// ListTemplateRevisions 列出模板的修订历史，新的在前
+func (s *templateService) ListTemplateRevisions(ctx context.Context, templateID string) ([]TemplateRevisionSummary, error) {
+	template, err := s.templateRepo.GetByID(ctx, templateID)
+	if err != nil {
+		return nil, fmt.Errorf("获取模板失败: %w", err)
+	}
+	revisions, err := s.templateRepo.ListRevisions(ctx, templateID)
+	if err != nil {
+		return nil, fmt.Errorf("获取模板修订失败: %w", err)
+	}
+
+	// 没有修订的旧模板以当前内容作为唯一一条记录
+	if len(revisions) == 0 || revisions[0].Revision < template.Metadata.Version {
+		revisions = append([]*domain.TemplateRevision{domain.NewTemplateRevision(template, "")}, revisions...)
+	}
+
+	summaries := make([]TemplateRevisionSummary, 0, len(revisions))
+	for _, r := range revisions {
+		summaries = append(summaries, TemplateRevisionSummary{
+			Revision:  r.Revision,
+			Name:      r.Name,
+			Message:   r.Message,
+			Changes:   r.Changes,
+			NodeCount: len(r.TemplateData.Nodes),
+			PathCount: len(r.TemplateData.Paths),
+			CreatedBy: r.CreatedBy,
+			CreatedAt: r.CreatedAt,
+			Current:   r.Revision == template.Metadata.Version,
+		})
+	}
+	return summaries, nil
+}
+
+// GetTemplateRevision 获取指定修订的完整内容
+func (s *templateService) GetTemplateRevision(ctx context.Context, templateID string, revision int) (*domain.TemplateRevision, error) {
+	template, err := s.templateRepo.GetByID(ctx, templateID)
+	if err != nil {
+		return nil, fmt.Errorf("获取模板失败: %w", err)
+	}
+	return s.templateRevision(ctx, template, revision)
+}
+
+// DiffTemplateRevisions 比较两个修订，to 为 0 时与当前版本比较
+func (s *templateService) DiffTemplateRevisions(ctx context.Context, templateID string, from, to int) (*TemplateDiff, error) {
+	template, err := s.templateRepo.GetByID(ctx, templateID)
+	if err != nil {
+		return nil, fmt.Errorf("获取模板失败: %w", err)
+	}
+	if to == 0 {
+		to = template.Metadata.Version
+	}
+	base, err := s.templateRevision(ctx, template, from)
+	if err != nil {
+		return nil, err
+	}
+	target, err := s.templateRevision(ctx, template, to)
+	if err != nil {
+		return nil, err
+	}
+	return diffTemplateRevisions(base, target), nil
+}
+
+// RestoreTemplateRevision 以旧修订的内容生成新版本，历史修订保持不变
+func (s *templateService) RestoreTemplateRevision(ctx context.Context, templateID string, revision int, message string) (*domain.Template, error) {
+	template, err := s.templateRepo.GetByID(ctx, templateID)
+	if err != nil {
+		return nil, fmt.Errorf("获取模板失败: %w", err)
+	}
+	source, err := s.templateRevision(ctx, template, revision)
+	if err != nil {
+		return nil, err
+	}
+	if revision == template.Metadata.Version {
+		return nil, fmt.Errorf("修订 %d 已是当前版本", revision)
+	}
+
+	previous := *template
+	template.Name = source.Name
+	template.Description = source.Description
+	template.Category = source.Category
+	template.Tags = source.Tags
+	template.LayoutType = source.LayoutType
+	template.LayoutConfig = source.LayoutConfig
+	template.TemplateData = source.TemplateData
+	template.UpdatePreview()
+	updateTemplateThumbnail(template)
+	template.Metadata.Version++
+
+	if err := template.IsValid(); err != nil {
+		return nil, fmt.Errorf("模板验证失败: %w", err)
+	}
+	if strings.TrimSpace(message) == "" {
+		message = fmt.Sprintf("恢复到修订 %d", revision)
+	}
+	if err := s.saveTemplateVersion(ctx, template, &previous, message); err != nil {
+		return nil, fmt.Errorf("恢复模板失败: %w", err)
+	}
+	return template, nil
+}
+
+// templateRevision 读取修订；当前版本没有修订时（升级前的模板）以当前内容代替
+func (s *templateService) templateRevision(ctx context.Context, template *domain.Template, revision int) (*domain.TemplateRevision, error) {
+	if revision <= 0 {
+		return nil, fmt.Errorf("修订号必须是正整数")
+	}
+	r, err := s.templateRepo.GetRevision(ctx, string(template.ID), revision)
+	if err != nil {
+		return nil, fmt.Errorf("获取模板修订失败: %w", err)
+	}
+	if r == nil && revision == template.Metadata.Version {
+		r = domain.NewTemplateRevision(template, "")
+	}
+	if r == nil {
+		return nil, fmt.Errorf("模板 %s 没有修订 %d", template.ID, revision)
+	}
+	return r, nil
+}
+
+// diffTemplateRevisions 计算两个修订的差异
+func diffTemplateRevisions(from, to *domain.TemplateRevision) *TemplateDiff {
+	diff := &TemplateDiff{
+		TemplateID: to.TemplateID,
+		From:       from.Revision,
+		To:         to.Revision,
+		Fields:     []string{},
+	}
+
+	fields := []struct {
+		name string
+		a, b interface{}
+	}{
+		{"name", from.Name, to.Name},
+		{"description", from.Description, to.Description},
+		{"category", from.Category, to.Category},
+		{"tags", from.Tags, to.Tags},
+		{"layout_type", from.LayoutType, to.LayoutType},
+		{"layout_config", from.LayoutConfig, to.LayoutConfig},
+		{"canvas_config", from.TemplateData.CanvasConfig, to.TemplateData.CanvasConfig},
+	}
+	for _, f := range fields {
+		if !jsonEqual(f.a, f.b) {
+			diff.Fields = append(diff.Fields, f.name)
+		}
+	}
+
+	diff.Nodes = diffTemplateItems(from.TemplateData.Nodes, to.TemplateData.Nodes, func(n domain.TemplateNode) string { return n.TemplateID })
+	diff.Paths = diffTemplateItems(from.TemplateData.Paths, to.TemplateData.Paths, func(p domain.TemplatePath) string { return p.TemplateID })
+	diff.Parameters = diffTemplateItems(from.TemplateData.Parameters, to.TemplateData.Parameters, func(p domain.TemplateParameter) string { return p.Name })
+	diff.RepeatsChanged = !jsonEqual(from.TemplateData.Repeats, to.TemplateData.Repeats)
+	diff.Summary = diff.summary()
+	return diff
+}
+
+// diffTemplateItems 按ID匹配两组元素，逐个 JSON 字段比较
+func diffTemplateItems[T any](from, to []T, key func(T) string) TemplateDiffSet {
+	set := TemplateDiffSet{Added: []string{}, Removed: []string{}, Changed: []TemplateDiffChange{}}
+	before := make(map[string]T, len(from))
+	for _, item := range from {
+		before[key(item)] = item
+	}
+	seen := make(map[string]bool, len(to))
+	for _, item := range to {
+		id := key(item)
+		seen[id] = true
+		old, ok := before[id]
+		if !ok {
+			set.Added = append(set.Added, id)
+			continue
+		}
+		if changed := changedJSONFields(old, item); len(changed) > 0 {
+			set.Changed = append(set.Changed, TemplateDiffChange{ID: id, Fields: changed})
+		}
+	}
+	for _, item := range from {
+		if id := key(item); !seen[id] {
+			set.Removed = append(set.Removed, id)
+		}
+	}
+	return set
+}
+
+// changedJSONFields 返回两个对象序列化后值不同的字段名
+func changedJSONFields(a, b interface{}) []string {
+	var fa, fb map[string]json.RawMessage
+	ra, _ := json.Marshal(a)
+	rb, _ := json.Marshal(b)
+	json.Unmarshal(ra, &fa)
+	json.Unmarshal(rb, &fb)
+
+	var changed []string
+	for name, va := range fa {
+		if vb, ok := fb[name]; !ok || !bytes.Equal(va, vb) {
+			changed = append(changed, name)
+		}
+	}
+	for name := range fb {
+		if _, ok := fa[name]; !ok {
+			changed = append(changed, name)
+		}
+	}
+	sort.Strings(changed)
+	return changed
+}
+
+// jsonEqual 按序列化结果比较，map 键顺序不影响结果
+func jsonEqual(a, b interface{}) bool {
+	ra, _ := json.Marshal(a)
+	rb, _ := json.Marshal(b)
+	return bytes.Equal(ra, rb)
+}
+
+// summary 生成一行变更摘要，如 "节点 +2 -1 ~3；路径 +1；属性 name"
+func (d *TemplateDiff) summary() string {
+	var parts []string
+	for _, s := range []struct {
+		label string
+		set   *TemplateDiffSet
+	}{{"节点", &d.Nodes}, {"路径", &d.Paths}, {"参数", &d.Parameters}} {
+		if s.set.Empty() {
+			continue
+		}
+		part := s.label
+		if n := len(s.set.Added); n > 0 {
+			part += fmt.Sprintf(" +%d", n)
+		}
+		if n := len(s.set.Removed); n > 0 {
+			part += fmt.Sprintf(" -%d", n)
+		}
+		if n := len(s.set.Changed); n > 0 {
+			part += fmt.Sprintf(" ~%d", n)
+		}
+		parts = append(parts, part)
+	}
+	if d.RepeatsChanged {
+		parts = append(parts, "重复块变更")
+	}
+	if len(d.Fields) > 0 {
+		parts = append(parts, "属性 "+strings.Join(d.Fields, ", "))
+	}
+	if len(parts) == 0 {
+		return "无内容变更"
+	}
+	return strings.Join(parts, "；")
+}
@@ -4,6 +4,7 @@ package services
 import (
 	"context"
 	"fmt"
+	"strings"
 
 	"robot-path-editor/internal/domain"
 	"robot-path-editor/internal/repositories"
@@ -24,15 +25,25 @@ type TemplateService interface {
 	GetTemplatesByCategory(ctx context.Context, category string) ([]*domain.Template, error)
 
 	// 模板应用
-	ApplyTemplate(ctx context.Context, templateID string, canvasConfig domain.CanvasConfig) (*ApplyTemplateResponse, error)
+	ApplyTemplate(ctx context.Context, templateID string, req ApplyTemplateRequest) (*ApplyTemplateResponse, error)
 
 	// 从当前画布保存为模板
 	SaveAsTemplate(ctx context.Context, req SaveAsTemplateRequest) (*domain.Template, error)
 
 	// 模板复制和导入导出
 	CloneTemplate(ctx context.Context, templateID string, newName string) (*domain.Template, error)
-	ExportTemplate(ctx context.Context, templateID string) (*ExportTemplateResponse, error)
+	ExportTemplate(ctx context.Context, templateID string, format string) (*ExportTemplateResponse, error)
 	ImportTemplate(ctx context.Context, req ImportTemplateRequest) (*domain.Template, error)
+
+	// 模板包（多个模板及缩略图打包为 zip）
+	ExportTemplatePack(ctx context.Context, opts TemplatePackExportOptions) (*TemplatePackFile, error)
+	ImportTemplatePack(ctx context.Context, data []byte, opts TemplatePackImportOptions) (*TemplatePackImportResult, error)
+
+	// 修订历史、差异和恢复
+	ListTemplateRevisions(ctx context.Context, templateID string) ([]TemplateRevisionSummary, error)
+	GetTemplateRevision(ctx context.Context, templateID string, revision int) (*domain.TemplateRevision, error)
+	DiffTemplateRevisions(ctx context.Context, templateID string, from, to int) (*TemplateDiff, error)
+	RestoreTemplateRevision(ctx context.Context, templateID string, revision int, message string) (*domain.Template, error)
 }
 
 // CreateTemplateRequest 创建模板请求
@@ -58,6 +69,7 @@ type UpdateTemplateRequest struct {
 	IsPublic     *bool                  `json:"is_public,omitempty"`
 	Status       *domain.TemplateStatus `json:"status,omitempty"`
 	TemplateData *domain.TemplateData   `json:"template_data,omitempty"`
+	Message      string                 `json:"message,omitempty"` // 修改说明，记录到修订历史
 }
 
 // ListTemplatesRequest 列出模板请求
@@ -83,15 +95,45 @@ type ListTemplatesResponse struct {
 	TotalPages int                `json:"total_pages"`
 }
 
+// ApplyTemplateRequest 应用模板请求
+type ApplyTemplateRequest struct {
+	Width      int                    `json:"width"`
+	Height     int                    `json:"height"`
+	Parameters map[string]interface{} `json:"parameters,omitempty"` // 参数化模板的参数值，未提供的使用默认值
+	Revision   int                    `json:"revision,omitempty"`   // 使用指定修订的内容，0 表示当前版本
+
+	// 放置到地图：变换、锚点绑定和重合节点合并，Persist 为 true 时在一个事务中保存
+	Transform      *TemplateTransform       `json:"transform,omitempty"`
+	Anchors        map[string]domain.NodeID `json:"anchors,omitempty"`         // 模板节点ID → 已有节点ID
+	MergeTolerance float64                  `json:"merge_tolerance,omitempty"` // 大于0时合并距离不超过该值的节点（地图单位）
+	Persist        bool                     `json:"persist"`
+}
+
+// TemplateTransform 模板到地图的仿射变换：先按尺寸缩放相对坐标，再绕原点旋转，最后平移到 Origin
+type TemplateTransform struct {
+	Origin   domain.Position `json:"origin"`   // 模板坐标 (0,0) 在地图中的位置
+	Rotation float64         `json:"rotation"` // 逆时针旋转角度（度）
+	ScaleX   float64         `json:"scale_x"`  // 模板宽度对应的地图单位，默认为 Width
+	ScaleY   float64         `json:"scale_y"`  // 模板高度对应的地图单位，默认为 Height
+	ScaleZ   float64         `json:"scale_z"`  // 相对Z对应的地图单位，默认100
+	FlipY    bool            `json:"flip_y"`   // 地图Y轴向上时翻转模板的Y方向
+}
+
 // ApplyTemplateResponse 应用模板响应
 type ApplyTemplateResponse struct {
-	Nodes []domain.Node `json:"nodes"`
+	Nodes []domain.Node `json:"nodes"` // 新建的节点（不含锚定和合并到已有节点的模板节点）
 	Paths []domain.Path `json:"paths"`
 
 	// ID映射表，用于前端更新引用
 	NodeIDMapping map[string]string `json:"node_id_mapping"`
 	PathIDMapping map[string]string `json:"path_id_mapping"`
 
+	AnchoredNodes map[string]string `json:"anchored_nodes,omitempty"` // 绑定到锚点的模板节点
+	MergedNodes   map[string]string `json:"merged_nodes,omitempty"`   // 合并到重合节点的模板节点
+	SkippedPaths  []string          `json:"skipped_paths,omitempty"`  // 合并后首尾相同或与已有路径重复而跳过的模板路径
+	Persisted     bool              `json:"persisted"`
+	Revision      int               `json:"revision"` // 实际使用的模板修订
+
 	Message string `json:"message"`
 }
 
@@ -116,13 +158,15 @@ type ExportTemplateResponse struct {
 	ExportFormat string           `json:"export_format"` // json, yaml
 	Content      string           `json:"content"`
 	Filename     string           `json:"filename"`
+	ContentType  string           `json:"content_type"`
 }
 
 // ImportTemplateRequest 导入模板请求
 type ImportTemplateRequest struct {
 	Content string `json:"content" binding:"required"`
-	Format  string `json:"format"` // json, yaml
-	Name    string `json:"name,omitempty"`
+	Format  string `json:"format"`         // json, yaml，为空时按内容识别
+	Name    string `json:"name,omitempty"` // 覆盖文件中的模板名称
+	DryRun  bool   `json:"dry_run"`        // 仅校验，不保存
 }
 
 // templateService 模板服务实现
@@ -130,6 +174,7 @@ type templateService struct {
 	templateRepo repositories.TemplateRepository
 	nodeRepo     repositories.NodeRepository
 	pathRepo     repositories.PathRepository
+	transactor   repositories.Transactor
 }
 
 // NewTemplateService 创建新的模板服务实例
@@ -137,11 +182,13 @@ func NewTemplateService(
 	templateRepo repositories.TemplateRepository,
 	nodeRepo repositories.NodeRepository,
 	pathRepo repositories.PathRepository,
+	transactor repositories.Transactor,
 ) TemplateService {
 	return &templateService{
 		templateRepo: templateRepo,
 		nodeRepo:     nodeRepo,
 		pathRepo:     pathRepo,
+		transactor:   transactor,
 	}
 }
 
@@ -159,14 +206,18 @@ func (s *templateService) CreateTemplate(ctx context.Context, req CreateTemplate
 
 	// 更新预览信息
 	template.UpdatePreview()
+	updateTemplateThumbnail(template)
 
 	// 验证模板
 	if err := template.IsValid(); err != nil {
 		return nil, fmt.Errorf("模板验证失败: %w", err)
 	}
+	if _, err := expandTemplatePreview(&template.TemplateData); err != nil {
+		return nil, fmt.Errorf("模板验证失败: %w", err)
+	}
 
 	// 保存到数据库
-	err := s.templateRepo.Create(ctx, template)
+	err := s.saveTemplateVersion(ctx, template, nil, "")
 	if err != nil {
 		return nil, fmt.Errorf("创建模板失败: %w", err)
 	}
@@ -186,6 +237,7 @@ func (s *templateService) UpdateTemplate(ctx context.Context, req UpdateTemplate
 	if err != nil {
 		return nil, fmt.Errorf("获取模板失败: %w", err)
 	}
+	previous := *template
 
 	// 更新字段
 	if req.Name != nil {
@@ -212,6 +264,7 @@ func (s *templateService) UpdateTemplate(ctx context.Context, req UpdateTemplate
 	if req.TemplateData != nil {
 		template.TemplateData = *req.TemplateData
 		template.UpdatePreview()
+		updateTemplateThumbnail(template)
 	}
 
 	// 更新元数据
@@ -221,8 +274,11 @@ func (s *templateService) UpdateTemplate(ctx context.Context, req UpdateTemplate
 	if err := template.IsValid(); err != nil {
 		return nil, fmt.Errorf("模板验证失败: %w", err)
 	}
+	if _, err := expandTemplatePreview(&template.TemplateData); err != nil {
+		return nil, fmt.Errorf("模板验证失败: %w", err)
+	}
 
-	err = s.templateRepo.Update(ctx, template)
+	err = s.saveTemplateVersion(ctx, template, &previous, req.Message)
 	if err != nil {
 		return nil, fmt.Errorf("更新模板失败: %w", err)
 	}
@@ -300,78 +356,6 @@ func (s *templateService) GetTemplatesByCategory(ctx context.Context, category s
 	return s.templateRepo.GetByCategory(ctx, category)
 }
 
-// ApplyTemplate 应用模板
-func (s *templateService) ApplyTemplate(ctx context.Context, templateID string, canvasConfig domain.CanvasConfig) (*ApplyTemplateResponse, error) {
-	// 获取模板
-	template, err := s.templateRepo.GetByID(ctx, templateID)
-	if err != nil {
-		return nil, fmt.Errorf("获取模板失败: %w", err)
-	}
-
-	// 增加使用次数
-	template.IncrementUsage()
-	s.templateRepo.Update(ctx, template)
-
-	// 创建ID映射表
-	nodeIDMapping := make(map[string]string)
-	pathIDMapping := make(map[string]string)
-
-	// 转换模板节点为实际节点
-	var nodes []domain.Node
-	for _, templateNode := range template.TemplateData.Nodes {
-		node := domain.NewNode(templateNode.Name, string(templateNode.Type))
-
-		// 转换相对位置为绝对位置
-		node.Position = templateNode.RelativePosition.ToAbsolutePosition(
-			canvasConfig.Width, canvasConfig.Height,
-		)
-
-		node.Style = templateNode.Style
-		node.Properties = templateNode.Properties
-
-		// 保存ID映射
-		nodeIDMapping[templateNode.TemplateID] = node.ID.String()
-
-		nodes = append(nodes, *node)
-	}
-
-	// 转换模板路径为实际路径
-	var paths []domain.Path
-	for _, templatePath := range template.TemplateData.Paths {
-		startNodeID, ok1 := nodeIDMapping[templatePath.StartNodeTempID]
-		endNodeID, ok2 := nodeIDMapping[templatePath.EndNodeTempID]
-
-		if !ok1 || !ok2 {
-			continue // 跳过无效的路径
-		}
-
-		path := domain.NewPath(
-			templatePath.Name,
-			domain.NodeID(startNodeID),
-			domain.NodeID(endNodeID),
-		)
-
-		path.Type = templatePath.Type
-		path.Direction = templatePath.Direction
-		path.CurveType = templatePath.CurveType
-		path.Style = templatePath.Style
-		path.Properties = templatePath.Properties
-
-		// 保存ID映射
-		pathIDMapping[templatePath.TemplateID] = path.ID.String()
-
-		paths = append(paths, *path)
-	}
-
-	return &ApplyTemplateResponse{
-		Nodes:         nodes,
-		Paths:         paths,
-		NodeIDMapping: nodeIDMapping,
-		PathIDMapping: pathIDMapping,
-		Message:       fmt.Sprintf("已应用模板 '%s'，创建了 %d 个节点和 %d 条路径", template.Name, len(nodes), len(paths)),
-	}, nil
-}
-
 // SaveAsTemplate 保存为模板
 func (s *templateService) SaveAsTemplate(ctx context.Context, req SaveAsTemplateRequest) (*domain.Template, error) {
 	template := domain.NewTemplate(req.Name, req.Description, req.LayoutType)
@@ -436,10 +420,11 @@ func (s *templateService) SaveAsTemplate(ctx context.Context, req SaveAsTemplate
 	}
 
 	template.UpdatePreview()
+	updateTemplateThumbnail(template)
 	template.Status = domain.TemplateStatusActive
 
 	// 保存模板
-	err := s.templateRepo.Create(ctx, template)
+	err := s.saveTemplateVersion(ctx, template, nil, "从画布保存")
 	if err != nil {
 		return nil, fmt.Errorf("保存模板失败: %w", err)
 	}
@@ -463,7 +448,7 @@ func (s *templateService) CloneTemplate(ctx context.Context, templateID string,
 	clone.Metadata.Version = 1
 
 	// 保存克隆
-	err = s.templateRepo.Create(ctx, &clone)
+	err = s.saveTemplateVersion(ctx, &clone, nil, fmt.Sprintf("克隆自 %s", original.Name))
 	if err != nil {
 		return nil, fmt.Errorf("克隆模板失败: %w", err)
 	}
@@ -471,25 +456,58 @@ func (s *templateService) CloneTemplate(ctx context.Context, templateID string,
 	return &clone, nil
 }
 
-// ExportTemplate 导出模板
-func (s *templateService) ExportTemplate(ctx context.Context, templateID string) (*ExportTemplateResponse, error) {
+// ExportTemplate 导出模板为带版本号的 JSON 或 YAML 文件
+func (s *templateService) ExportTemplate(ctx context.Context, templateID string, format string) (*ExportTemplateResponse, error) {
 	template, err := s.templateRepo.GetByID(ctx, templateID)
 	if err != nil {
 		return nil, fmt.Errorf("获取模板失败: %w", err)
 	}
 
-	// 这里可以实现JSON或YAML格式的导出
-	// 简化实现，返回JSON格式
+	format = strings.ToLower(strings.TrimSpace(format))
+	if format == "yml" {
+		format = TemplateEncodingYAML
+	}
+	if format == "" {
+		format = TemplateEncodingJSON
+	}
+	content, err := encodeTemplateDocument(newTemplateDocument(template), format)
+	if err != nil {
+		return nil, err
+	}
+
+	contentType := "application/json"
+	if format == TemplateEncodingYAML {
+		contentType = "application/yaml"
+	}
 	return &ExportTemplateResponse{
 		Template:     template,
-		ExportFormat: "json",
-		Filename:     fmt.Sprintf("template_%s.json", template.Name),
+		ExportFormat: format,
+		Content:      string(content),
+		Filename:     templateFilename(template.Name, format),
+		ContentType:  contentType,
 	}, nil
 }
 
-// ImportTemplate 导入模板
+// ImportTemplate 导入模板文件，旧版本文件自动迁移，校验通过后创建为新模板
 func (s *templateService) ImportTemplate(ctx context.Context, req ImportTemplateRequest) (*domain.Template, error) {
-	// 这里可以实现从JSON或YAML导入模板的逻辑
-	// 简化实现，直接返回错误
-	return nil, fmt.Errorf("导入功能待实现")
+	doc, err := parseTemplateDocument([]byte(req.Content), req.Format)
+	if err != nil {
+		return nil, err
+	}
+	if name := strings.TrimSpace(req.Name); name != "" {
+		doc.Template.Name = name
+	}
+
+	template := templateFromDocument(doc)
+	if err := template.IsValid(); err != nil {
+		return nil, fmt.Errorf("模板验证失败: %w", err)
+	}
+	if req.DryRun {
+		return template, nil
+	}
+
+	if err := s.saveTemplateVersion(ctx, template, nil, "导入"); err != nil {
+		return nil, fmt.Errorf("导入模板失败: %w", err)
+	}
+	return template, nil
 }
@@ -0,0 +1,472 @@
+// Package services 模板文件格式（JSON/YAML）
+//
+// 设计参考：
+// - Kubernetes 清单的 apiVersion/kind：文件自带格式标识和版本号，旧版本按步骤迁移到当前版本
+//
+// 特点：
+// - JSON 与 YAML 共用同一结构，YAML 保持与 JSON 相同的字段顺序
+// - 只导出可移植的内容（名称、分类、布局、模板数据、标签注解），不含ID、使用次数和缩略图
+// - 导入前先迁移再严格解析（拒绝未知字段），随后校验引用关系和取值范围，一次返回全部问题；参数化模板按预览值展开后校验
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"robot-path-editor/internal/domain"
+)
+
+// 模板文件格式标识与版本
+const (
+	TemplateFileFormat    = "robot-path-editor/template"
+	TemplateFormatVersion = 1
+)
+
+// 模板文件编码
+const (
+	TemplateEncodingJSON = "json"
+	TemplateEncodingYAML = "yaml"
+)
+
+// TemplateDocument 模板文件
+type TemplateDocument struct {
+	Format        string       `json:"format"`
+	FormatVersion int          `json:"format_version"`
+	ExportedAt    time.Time    `json:"exported_at"`
+	Template      TemplateBody `json:"template"`
+}
+
+// TemplateBody 模板文件中的模板内容
+type TemplateBody struct {
+	Name         string                 `json:"name"`
+	Description  string                 `json:"description,omitempty"`
+	Category     string                 `json:"category,omitempty"`
+	Tags         []string               `json:"tags,omitempty"`
+	LayoutType   domain.LayoutType      `json:"layout_type"`
+	LayoutConfig map[string]interface{} `json:"layout_config,omitempty"`
+	IsPublic     bool                   `json:"is_public,omitempty"`
+	Revision     int                    `json:"revision,omitempty"` // 导出时的模板版本号，仅供参考
+	CreatedBy    string                 `json:"created_by,omitempty"`
+	Labels       map[string]string      `json:"labels,omitempty"`
+	Annotations  map[string]string      `json:"annotations,omitempty"`
+	TemplateData domain.TemplateData    `json:"template_data"`
+}
+
+// TemplateValidationError 模板文件校验错误，包含全部问题
+type TemplateValidationError struct {
+	Problems []string `json:"problems"`
+}
+
+// Error 实现 error 接口
+func (e *TemplateValidationError) Error() string {
+	return fmt.Sprintf("模板文件校验失败: %s", strings.Join(e.Problems, "; "))
+}
+
+// templateMigrations 旧版本升级步骤，键为源版本，升级后版本号加一
+var templateMigrations = map[int]func(doc map[string]interface{}) (map[string]interface{}, error){
+	0: migrateTemplateV0,
+}
+
+// === 导出 ===
+
+// newTemplateDocument 由模板生成可移植的文件内容
+func newTemplateDocument(t *domain.Template) *TemplateDocument {
+	return &TemplateDocument{
+		Format:        TemplateFileFormat,
+		FormatVersion: TemplateFormatVersion,
+		ExportedAt:    time.Now().UTC().Truncate(time.Second),
+		Template: TemplateBody{
+			Name:         t.Name,
+			Description:  t.Description,
+			Category:     t.Category,
+			Tags:         t.Tags,
+			LayoutType:   t.LayoutType,
+			LayoutConfig: t.LayoutConfig,
+			IsPublic:     t.IsPublic,
+			Revision:     t.Metadata.Version,
+			CreatedBy:    t.Metadata.CreatedBy,
+			Labels:       t.Metadata.Labels,
+			Annotations:  t.Metadata.Annotations,
+			TemplateData: t.TemplateData,
+		},
+	}
+}
+
+// encodeTemplateDocument 按编码输出文件内容
+func encodeTemplateDocument(doc *TemplateDocument, encoding string) ([]byte, error) {
+	data, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("序列化模板失败: %w", err)
+	}
+
+	switch encoding {
+	case "", TemplateEncodingJSON:
+		return append(data, '\n'), nil
+	case TemplateEncodingYAML, "yml":
+		return jsonToYAML(data)
+	default:
+		return nil, fmt.Errorf("不支持的模板格式: %s", encoding)
+	}
+}
+
+// jsonToYAML 将 JSON 转为块风格 YAML，保持字段顺序
+func jsonToYAML(data []byte) ([]byte, error) {
+	var node yaml.Node
+	if err := yaml.Unmarshal(data, &node); err != nil {
+		return nil, fmt.Errorf("转换YAML失败: %w", err)
+	}
+	var reset func(n *yaml.Node)
+	reset = func(n *yaml.Node) {
+		n.Style = 0
+		for _, child := range n.Content {
+			reset(child)
+		}
+	}
+	reset(&node)
+
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(&node); err != nil {
+		return nil, fmt.Errorf("转换YAML失败: %w", err)
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, fmt.Errorf("转换YAML失败: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// templateFilename 导出文件名
+func templateFilename(name, encoding string) string {
+	if encoding == "yml" {
+		encoding = TemplateEncodingYAML
+	}
+	if encoding == "" {
+		encoding = TemplateEncodingJSON
+	}
+	return fmt.Sprintf("%s.template.%s", templateSlug(name), encoding)
+}
+
+// templateSlug 文件名中可用的模板名称，保留中文，其他符号替换为连字符
+func templateSlug(name string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.TrimSpace(name) {
+		if r == '-' || r == '_' || r == '.' || (r >= '0' && r <= '9') || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || r > 0x7f && r != 0xfeff {
+			b.WriteRune(r)
+			dash = false
+		} else if !dash && b.Len() > 0 {
+			b.WriteByte('-')
+			dash = true
+		}
+	}
+	slug := strings.Trim(b.String(), "-.")
+	if slug == "" {
+		slug = "template"
+	}
+	return slug
+}
+
+// === 导入 ===
+
+// parseTemplateDocument 解析、迁移并校验模板文件，encoding 为空时按内容识别
+func parseTemplateDocument(data []byte, encoding string) (*TemplateDocument, error) {
+	data = bytes.TrimPrefix(data, []byte("\ufeff"))
+	if len(bytes.TrimSpace(data)) == 0 {
+		return nil, fmt.Errorf("模板文件内容为空")
+	}
+
+	raw, err := decodeTemplateRaw(data, encoding)
+	if err != nil {
+		return nil, err
+	}
+
+	if format, ok := raw["format"].(string); ok && format != TemplateFileFormat {
+		if format == TemplatePackFormat {
+			return nil, fmt.Errorf("这是模板包清单，请使用模板包导入")
+		}
+		return nil, fmt.Errorf("不支持的文件格式: %s", format)
+	}
+
+	version := 0
+	if v, ok := raw["format_version"]; ok {
+		number, ok := v.(float64)
+		if !ok || number != math.Trunc(number) || number < 0 {
+			return nil, &TemplateValidationError{Problems: []string{"format_version: 必须是非负整数"}}
+		}
+		version = int(number)
+	}
+	if version > TemplateFormatVersion {
+		return nil, fmt.Errorf("模板文件版本 %d 高于当前支持的版本 %d，请升级后再导入", version, TemplateFormatVersion)
+	}
+	for version < TemplateFormatVersion {
+		migrate, ok := templateMigrations[version]
+		if !ok {
+			return nil, fmt.Errorf("不支持从版本 %d 迁移模板文件", version)
+		}
+		if raw, err = migrate(raw); err != nil {
+			return nil, fmt.Errorf("迁移模板文件（版本 %d）失败: %w", version, err)
+		}
+		version++
+		raw["format_version"] = float64(version)
+	}
+
+	normalized, err := json.Marshal(raw)
+	if err != nil {
+		return nil, fmt.Errorf("解析模板文件失败: %w", err)
+	}
+	decoder := json.NewDecoder(bytes.NewReader(normalized))
+	decoder.DisallowUnknownFields()
+	var doc TemplateDocument
+	if err := decoder.Decode(&doc); err != nil {
+		return nil, &TemplateValidationError{Problems: []string{strings.TrimPrefix(err.Error(), "json: ")}}
+	}
+
+	if problems := validateTemplateDocument(&doc); len(problems) > 0 {
+		return nil, &TemplateValidationError{Problems: problems}
+	}
+	return &doc, nil
+}
+
+// decodeTemplateRaw 解码为通用结构，YAML 的数值和嵌套结构统一转为 JSON 等价形式
+func decodeTemplateRaw(data []byte, encoding string) (map[string]interface{}, error) {
+	encoding = strings.ToLower(strings.TrimSpace(encoding))
+	if encoding == "" {
+		encoding = TemplateEncodingYAML
+		if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '{' {
+			encoding = TemplateEncodingJSON
+		}
+	}
+
+	var raw map[string]interface{}
+	switch encoding {
+	case TemplateEncodingJSON:
+		if err := json.Unmarshal(data, &raw); err != nil {
+			return nil, fmt.Errorf("解析JSON失败: %w", err)
+		}
+	case TemplateEncodingYAML, "yml":
+		var value interface{}
+		if err := yaml.Unmarshal(data, &value); err != nil {
+			return nil, fmt.Errorf("解析YAML失败: %w", err)
+		}
+		converted, err := json.Marshal(value)
+		if err != nil {
+			return nil, fmt.Errorf("解析YAML失败: %w", err)
+		}
+		if err := json.Unmarshal(converted, &raw); err != nil {
+			return nil, fmt.Errorf("模板文件的根节点必须是对象")
+		}
+	default:
+		return nil, fmt.Errorf("不支持的模板格式: %s", encoding)
+	}
+	if raw == nil {
+		return nil, fmt.Errorf("模板文件的根节点必须是对象")
+	}
+	return raw, nil
+}
+
+// migrateTemplateV0 迁移无版本号的旧文件：
+// 旧版导出接口的响应（{"export": {"template": ...}} 或 {"template": ...}）以及直接保存的模板对象
+func migrateTemplateV0(raw map[string]interface{}) (map[string]interface{}, error) {
+	if export, ok := raw["export"].(map[string]interface{}); ok {
+		raw = export
+	}
+	template := raw
+	if nested, ok := raw["template"].(map[string]interface{}); ok {
+		template = nested
+	}
+	if _, ok := template["template_data"]; !ok {
+		return nil, fmt.Errorf("未找到 template_data")
+	}
+
+	body := make(map[string]interface{})
+	for _, key := range []string{"name", "description", "category", "tags", "layout_type", "layout_config", "is_public", "template_data"} {
+		if v, ok := template[key]; ok && v != nil {
+			body[key] = v
+		}
+	}
+	if meta, ok := template["metadata"].(map[string]interface{}); ok {
+		for from, to := range map[string]string{"version": "revision", "created_by": "created_by", "labels": "labels", "annotations": "annotations"} {
+			if v, ok := meta[from]; ok && v != nil && v != "" {
+				body[to] = v
+			}
+		}
+	}
+
+	return map[string]interface{}{
+		"format":         TemplateFileFormat,
+		"format_version": float64(0),
+		"exported_at":    time.Now().UTC().Truncate(time.Second).Format(time.RFC3339),
+		"template":       body,
+	}, nil
+}
+
+// 合法取值
+var (
+	templateLayoutTypes = []domain.LayoutType{
+		domain.LayoutTypeTree, domain.LayoutTypeGrid, domain.LayoutTypeCircular, domain.LayoutTypeForce,
+		domain.LayoutTypePipeline, domain.LayoutTypeHierarchy, domain.LayoutTypeRadial, domain.LayoutTypeCustom,
+	}
+	templateCurveTypes = []domain.CurveType{"", domain.CurveTypeLinear, domain.CurveTypeBezier, domain.CurveTypeSpline, domain.CurveTypeArc}
+)
+
+func containsValue[T comparable](values []T, v T) bool {
+	for _, candidate := range values {
+		if candidate == v {
+			return true
+		}
+	}
+	return false
+}
+
+// validateTemplateDocument 校验模板内容，返回全部问题
+func validateTemplateDocument(doc *TemplateDocument) []string {
+	var problems []string
+	fail := func(path, format string, args ...interface{}) {
+		problems = append(problems, path+": "+fmt.Sprintf(format, args...))
+	}
+
+	if doc.Format != TemplateFileFormat {
+		fail("format", "必须为 %s", TemplateFileFormat)
+	}
+
+	t := &doc.Template
+	switch name := strings.TrimSpace(t.Name); {
+	case name == "":
+		fail("template.name", "不能为空")
+	case len([]rune(name)) > 100:
+		fail("template.name", "长度不能超过100个字符")
+	}
+	if len([]rune(t.Category)) > 50 {
+		fail("template.category", "长度不能超过50个字符")
+	}
+	if !containsValue(templateLayoutTypes, t.LayoutType) {
+		fail("template.layout_type", "无效的布局类型 %q", t.LayoutType)
+	}
+
+	data := &t.TemplateData
+	if data.CanvasConfig.Width < 0 || data.CanvasConfig.Height < 0 {
+		fail("template.template_data.canvas_config", "画布尺寸不能为负数")
+	}
+
+	// 参数化模板按预览值展开后检查节点和路径
+	if data.IsParameterized() {
+		expanded, err := expandTemplatePreview(data)
+		if err != nil {
+			var expandErr *TemplateExpandError
+			if errors.As(err, &expandErr) {
+				for _, problem := range expandErr.Problems {
+					if strings.HasPrefix(problem, "template_data.") {
+						problems = append(problems, "template."+problem)
+					} else {
+						fail("template.template_data", "%s", problem)
+					}
+				}
+			} else {
+				fail("template.template_data", "%v", err)
+			}
+			return problems
+		}
+		data = expanded
+	}
+
+	nodeIDs := make(map[string]bool, len(data.Nodes))
+	for i, n := range data.Nodes {
+		path := fmt.Sprintf("template.template_data.nodes[%d]", i)
+		switch {
+		case n.TemplateID == "":
+			fail(path+".template_id", "不能为空")
+		case nodeIDs[n.TemplateID]:
+			fail(path+".template_id", "重复的ID %q", n.TemplateID)
+		}
+		nodeIDs[n.TemplateID] = true
+		if strings.TrimSpace(n.Name) == "" {
+			fail(path+".name", "不能为空")
+		}
+		if len(n.Type) > 20 {
+			fail(path+".type", "长度不能超过20个字符")
+		}
+		p := n.RelativePosition
+		if math.IsNaN(p.X+p.Y+p.Z) || math.IsInf(p.X+p.Y+p.Z, 0) {
+			fail(path+".relative_position", "坐标必须是有限数值")
+		}
+		if n.Style.Size < 0 {
+			fail(path+".style.size", "不能为负数")
+		}
+		if n.Style.Opacity < 0 || n.Style.Opacity > 1 {
+			fail(path+".style.opacity", "必须在0-1之间")
+		}
+	}
+
+	pathIDs := make(map[string]bool, len(data.Paths))
+	for i, p := range data.Paths {
+		path := fmt.Sprintf("template.template_data.paths[%d]", i)
+		switch {
+		case p.TemplateID == "":
+			fail(path+".template_id", "不能为空")
+		case pathIDs[p.TemplateID]:
+			fail(path+".template_id", "重复的ID %q", p.TemplateID)
+		}
+		pathIDs[p.TemplateID] = true
+		if strings.TrimSpace(p.Name) == "" {
+			fail(path+".name", "不能为空")
+		}
+		if !nodeIDs[p.StartNodeTempID] || p.StartNodeTempID == "" {
+			fail(path+".start_node_temp_id", "引用了不存在的节点 %q", p.StartNodeTempID)
+		}
+		if !nodeIDs[p.EndNodeTempID] || p.EndNodeTempID == "" {
+			fail(path+".end_node_temp_id", "引用了不存在的节点 %q", p.EndNodeTempID)
+		}
+		if p.StartNodeTempID != "" && p.StartNodeTempID == p.EndNodeTempID {
+			fail(path, "起始节点和结束节点不能相同")
+		}
+		if _, ok := domain.NormalizeDirection(p.Direction); !ok {
+			fail(path+".direction", "无效的方向 %q", p.Direction)
+		}
+		if !containsValue(templateCurveTypes, p.CurveType) {
+			fail(path+".curve_type", "无效的曲线类型 %q", p.CurveType)
+		}
+		if p.Style.Width < 0 {
+			fail(path+".style.width", "不能为负数")
+		}
+		if p.Style.Opacity < 0 || p.Style.Opacity > 1 {
+			fail(path+".style.opacity", "必须在0-1之间")
+		}
+	}
+
+	return problems
+}
+
+// templateFromDocument 由文件内容创建新模板（新ID、版本从1开始）
+func templateFromDocument(doc *TemplateDocument) *domain.Template {
+	body := &doc.Template
+	template := domain.NewTemplate(strings.TrimSpace(body.Name), body.Description, body.LayoutType)
+	if body.Category != "" {
+		template.Category = body.Category
+	}
+	template.Tags = body.Tags
+	template.LayoutConfig = body.LayoutConfig
+	template.IsPublic = body.IsPublic
+	template.TemplateData = body.TemplateData
+	if template.TemplateData.Nodes == nil {
+		template.TemplateData.Nodes = []domain.TemplateNode{}
+	}
+	if template.TemplateData.Paths == nil {
+		template.TemplateData.Paths = []domain.TemplatePath{}
+	}
+	template.Metadata.CreatedBy = body.CreatedBy
+	template.Metadata.Labels = body.Labels
+	template.Metadata.Annotations = body.Annotations
+	template.Status = domain.TemplateStatusActive
+
+	template.UpdatePreview()
+	updateTemplateThumbnail(template)
+	return template
+}
@@ -0,0 +1,57 @@
+package services
+
+import (
+	"strings"
+	"testing"
+
+	"robot-path-editor/internal/domain"
+)
+
+func TestValidateTemplateDocumentDirection(t *testing.T) {
+	tests := []struct {
+		direction string
+		wantErr   bool
+	}{
+		{direction: ""},
+		{direction: domain.DirectionBidirectional},
+		{direction: domain.DirectionForward},
+		{direction: domain.DirectionOneWay},
+		{direction: domain.DirectionUnidirectional},
+		{direction: domain.DirectionBackward},
+		{direction: domain.DirectionReverse},
+		{direction: "sideways", wantErr: true},
+		{direction: "Forward", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.direction, func(t *testing.T) {
+			doc := &TemplateDocument{
+				Format: TemplateFileFormat,
+				Template: TemplateBody{
+					Name:       "方向",
+					LayoutType: domain.LayoutTypeGrid,
+					TemplateData: domain.TemplateData{
+						Nodes: []domain.TemplateNode{
+							{TemplateID: "a", Name: "A"},
+							{TemplateID: "b", Name: "B"},
+						},
+						Paths: []domain.TemplatePath{
+							{TemplateID: "ab", Name: "AB", StartNodeTempID: "a", EndNodeTempID: "b", Direction: tt.direction},
+						},
+					},
+				},
+			}
+
+			problems := validateTemplateDocument(doc)
+			gotErr := false
+			for _, problem := range problems {
+				if strings.HasPrefix(problem, "template.template_data.paths[0].direction") {
+					gotErr = true
+				}
+			}
+			if gotErr != tt.wantErr {
+				t.Errorf("validateTemplateDocument() direction problem = %v, want %v (problems %v)", gotErr, tt.wantErr, problems)
+			}
+		})
+	}
+}
@@ -0,0 +1,532 @@
+// Package services VDA 5050 订单生成服务
+//
+// 设计参考：
+// - VDA 5050 v2.0 order 消息（order.schema.json）
+// - 主控下发订单的 base/horizon 机制
+//
+// 特点：
+// 1. 路线节点映射为 order 节点（偶数 sequenceId），路径映射为边（奇数 sequenceId）
+// 2. 曲线几何精确转换为 NURBS 轨迹
+// 3. 动作与速度参数取自节点/路径的扩展属性
+// 4. 生成的订单在返回前按 schema 约束做本地校验
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+
+	"robot-path-editor/internal/domain"
+	"robot-path-editor/internal/repositories"
+)
+
+// VDA5050Service VDA 5050 订单服务接口
+type VDA5050Service interface {
+	GenerateOrder(ctx context.Context, req VDA5050OrderRequest) (*VDA5050Order, error)
+	ValidateOrder(data []byte) error
+}
+
+// VDA5050Version 生成订单使用的协议版本
+const VDA5050Version = "2.0.0"
+
+// VDA 5050 动作阻塞类型
+const (
+	VDA5050BlockingNone = "NONE"
+	VDA5050BlockingSoft = "SOFT"
+	VDA5050BlockingHard = "HARD"
+)
+
+// VDA5050OrderRequest 生成订单请求
+type VDA5050OrderRequest struct {
+	RouteRequest
+	Manufacturer  string `json:"manufacturer" binding:"required"`
+	SerialNumber  string `json:"serial_number" binding:"required"`
+	OrderID       string `json:"order_id,omitempty"` // 为空时自动生成
+	OrderUpdateID uint32 `json:"order_update_id,omitempty"`
+	ZoneSetID     string `json:"zone_set_id,omitempty"`
+
+	MapID           string  `json:"map_id,omitempty"`            // 节点未配置 map_id 属性时使用
+	Scale           float64 `json:"scale,omitempty"`             // 画布坐标到米的比例，默认1
+	FlipY           bool    `json:"flip_y,omitempty"`            // 画布Y轴向下时翻转为地图坐标系
+	ReleasedNodes   int     `json:"released_nodes,omitempty"`    // base 中的节点数，0 表示全部下发
+	DefaultMaxSpeed float64 `json:"default_max_speed,omitempty"` // 路径未配置 max_speed 时的最大速度 m/s
+}
+
+// VDA5050Order order 消息
+type VDA5050Order struct {
+	HeaderID      uint32        `json:"headerId"`
+	Timestamp     string        `json:"timestamp"`
+	Version       string        `json:"version"`
+	Manufacturer  string        `json:"manufacturer"`
+	SerialNumber  string        `json:"serialNumber"`
+	OrderID       string        `json:"orderId"`
+	OrderUpdateID uint32        `json:"orderUpdateId"`
+	ZoneSetID     string        `json:"zoneSetId,omitempty"`
+	Nodes         []VDA5050Node `json:"nodes"`
+	Edges         []VDA5050Edge `json:"edges"`
+}
+
+// VDA5050Node 订单节点
+type VDA5050Node struct {
+	NodeID          string               `json:"nodeId"`
+	SequenceID      uint32               `json:"sequenceId"`
+	NodeDescription string               `json:"nodeDescription,omitempty"`
+	Released        bool                 `json:"released"`
+	NodePosition    *VDA5050NodePosition `json:"nodePosition,omitempty"`
+	Actions         []VDA5050Action      `json:"actions"`
+}
+
+// VDA5050NodePosition 节点位置
+type VDA5050NodePosition struct {
+	X                     float64  `json:"x"`
+	Y                     float64  `json:"y"`
+	Theta                 *float64 `json:"theta,omitempty"`
+	AllowedDeviationXY    *float64 `json:"allowedDeviationXY,omitempty"`
+	AllowedDeviationTheta *float64 `json:"allowedDeviationTheta,omitempty"`
+	MapID                 string   `json:"mapId"`
+	MapDescription        string   `json:"mapDescription,omitempty"`
+}
+
+// VDA5050Edge 订单边
+type VDA5050Edge struct {
+	EdgeID           string             `json:"edgeId"`
+	SequenceID       uint32             `json:"sequenceId"`
+	EdgeDescription  string             `json:"edgeDescription,omitempty"`
+	Released         bool               `json:"released"`
+	StartNodeID      string             `json:"startNodeId"`
+	EndNodeID        string             `json:"endNodeId"`
+	MaxSpeed         *float64           `json:"maxSpeed,omitempty"`
+	MaxHeight        *float64           `json:"maxHeight,omitempty"`
+	MinHeight        *float64           `json:"minHeight,omitempty"`
+	Orientation      *float64           `json:"orientation,omitempty"`
+	OrientationType  string             `json:"orientationType,omitempty"`
+	Direction        string             `json:"direction,omitempty"`
+	RotationAllowed  *bool              `json:"rotationAllowed,omitempty"`
+	MaxRotationSpeed *float64           `json:"maxRotationSpeed,omitempty"`
+	Trajectory       *VDA5050Trajectory `json:"trajectory,omitempty"`
+	Length           *float64           `json:"length,omitempty"`
+	Actions          []VDA5050Action    `json:"actions"`
+}
+
+// VDA5050Trajectory NURBS 轨迹
+type VDA5050Trajectory struct {
+	Degree        float64               `json:"degree"`
+	KnotVector    []float64             `json:"knotVector"`
+	ControlPoints []VDA5050ControlPoint `json:"controlPoints"`
+}
+
+// VDA5050ControlPoint NURBS 控制点
+type VDA5050ControlPoint struct {
+	X      float64 `json:"x"`
+	Y      float64 `json:"y"`
+	Weight float64 `json:"weight"`
+}
+
+// VDA5050Action 动作
+type VDA5050Action struct {
+	ActionType        string                   `json:"actionType"`
+	ActionID          string                   `json:"actionId"`
+	ActionDescription string                   `json:"actionDescription,omitempty"`
+	BlockingType      string                   `json:"blockingType"`
+	ActionParameters  []VDA5050ActionParameter `json:"actionParameters,omitempty"`
+}
+
+// VDA5050ActionParameter 动作参数
+type VDA5050ActionParameter struct {
+	Key   string      `json:"key"`
+	Value interface{} `json:"value"`
+}
+
+// vda5050Service VDA 5050 订单服务实现
+type vda5050Service struct {
+	routes   *routeResolver
+	headerID uint32
+}
+
+// NewVDA5050Service 创建新的 VDA 5050 订单服务实例
+func NewVDA5050Service(nodeRepo repositories.NodeRepository, pathRepo repositories.PathRepository) VDA5050Service {
+	return &vda5050Service{
+		routes: newRouteResolver(nodeRepo, pathRepo),
+	}
+}
+
+// GenerateOrder 由路线生成 order 消息
+func (s *vda5050Service) GenerateOrder(ctx context.Context, req VDA5050OrderRequest) (*VDA5050Order, error) {
+	route, err := s.routes.Resolve(ctx, req.RouteRequest)
+	if err != nil {
+		return nil, err
+	}
+
+	builder := newVDA5050OrderBuilder(req)
+	order := &VDA5050Order{
+		HeaderID:      atomic.AddUint32(&s.headerID, 1) - 1,
+		Timestamp:     time.Now().UTC().Format("2006-01-02T15:04:05.000Z"),
+		Version:       VDA5050Version,
+		Manufacturer:  req.Manufacturer,
+		SerialNumber:  req.SerialNumber,
+		OrderID:       req.OrderID,
+		OrderUpdateID: req.OrderUpdateID,
+		ZoneSetID:     req.ZoneSetID,
+		Nodes:         []VDA5050Node{},
+		Edges:         []VDA5050Edge{},
+	}
+	if order.OrderID == "" {
+		order.OrderID = uuid.New().String()
+	}
+
+	released := len(route.Nodes)
+	if req.ReleasedNodes > 0 && req.ReleasedNodes < released {
+		released = req.ReleasedNodes
+	}
+
+	for i, node := range route.Nodes {
+		orderNode, err := builder.node(node, uint32(2*i), i < released)
+		if err != nil {
+			return nil, fmt.Errorf("节点 %s: %w", node.Name, err)
+		}
+		order.Nodes = append(order.Nodes, orderNode)
+
+		if i == 0 {
+			continue
+		}
+		leg := route.Legs[i-1]
+		edge, err := builder.edge(leg, uint32(2*i-1), i < released)
+		if err != nil {
+			return nil, fmt.Errorf("路径 %s: %w", leg.Path.Name, err)
+		}
+		order.Edges = append(order.Edges, edge)
+	}
+
+	data, err := json.Marshal(order)
+	if err != nil {
+		return nil, fmt.Errorf("序列化订单失败: %w", err)
+	}
+	if err := s.ValidateOrder(data); err != nil {
+		return nil, fmt.Errorf("生成的订单未通过校验: %w", err)
+	}
+
+	return order, nil
+}
+
+// ValidateOrder 按 order.schema.json 的约束校验订单JSON
+func (s *vda5050Service) ValidateOrder(data []byte) error {
+	return validateVDA5050Order(data)
+}
+
+// vda5050OrderBuilder 订单元素构建器，负责坐标变换和属性映射
+type vda5050OrderBuilder struct {
+	mapID           string
+	scale           float64
+	flipY           bool
+	defaultMaxSpeed float64
+}
+
+// newVDA5050OrderBuilder 按请求参数创建构建器
+func newVDA5050OrderBuilder(req VDA5050OrderRequest) *vda5050OrderBuilder {
+	b := &vda5050OrderBuilder{
+		mapID:           req.MapID,
+		scale:           1,
+		flipY:           req.FlipY,
+		defaultMaxSpeed: req.DefaultMaxSpeed,
+	}
+	if req.Scale > 0 {
+		b.scale = req.Scale
+	}
+	if b.mapID == "" {
+		b.mapID = "default"
+	}
+	return b
+}
+
+// mapPosition 将画布坐标变换为地图坐标（米）
+func (b *vda5050OrderBuilder) mapPosition(p domain.Position) domain.Position {
+	y := p.Y * b.scale
+	if b.flipY {
+		y = -y
+	}
+	return domain.Position{X: p.X * b.scale, Y: y}
+}
+
+// mapAngle 把编辑器中的角度转换到地图坐标系：翻转Y轴后旋转方向相反
+func (b *vda5050OrderBuilder) mapAngle(angle float64) float64 {
+	if b.flipY {
+		angle = -angle
+	}
+	return normalizeRadians(angle)
+}
+
+// node 构建订单节点
+// 节点属性：map_id、map_description、theta（弧度）、allowed_deviation_xy、allowed_deviation_theta、actions/action
+func (b *vda5050OrderBuilder) node(node *domain.Node, sequenceID uint32, released bool) (VDA5050Node, error) {
+	props := node.Properties
+	position := b.mapPosition(node.Position)
+
+	nodePosition := &VDA5050NodePosition{
+		X:     roundVDA(position.X),
+		Y:     roundVDA(position.Y),
+		MapID: b.mapID,
+	}
+	if mapID, ok := propertyString(props, "map_id"); ok {
+		nodePosition.MapID = mapID
+	}
+	if description, ok := propertyString(props, "map_description"); ok {
+		nodePosition.MapDescription = description
+	}
+	if theta, ok := propertyFloat(props, "theta"); ok {
+		theta = b.mapAngle(theta)
+		nodePosition.Theta = &theta
+	}
+	nodePosition.AllowedDeviationXY = optionalProperty(props, "allowed_deviation_xy")
+	nodePosition.AllowedDeviationTheta = optionalProperty(props, "allowed_deviation_theta")
+
+	actions, err := vda5050Actions(props)
+	if err != nil {
+		return VDA5050Node{}, err
+	}
+
+	return VDA5050Node{
+		NodeID:          string(node.ID),
+		SequenceID:      sequenceID,
+		NodeDescription: node.Name,
+		Released:        released,
+		NodePosition:    nodePosition,
+		Actions:         actions,
+	}, nil
+}
+
+// edge 构建订单边
+// 路径属性：max_speed（m/s）、max_height、min_height、orientation（弧度）、orientation_type、
+// direction、rotation_allowed、max_rotation_speed、actions/action
+func (b *vda5050OrderBuilder) edge(leg RouteLeg, sequenceID uint32, released bool) (VDA5050Edge, error) {
+	edge := VDA5050Edge{
+		SequenceID:  sequenceID,
+		Released:    released,
+		StartNodeID: string(leg.From.ID),
+		EndNodeID:   string(leg.To.ID),
+		Actions:     []VDA5050Action{},
+	}
+
+	start := b.mapPosition(leg.From.Position)
+	end := b.mapPosition(leg.To.Position)
+
+	if leg.Path == nil {
+		edge.EdgeID = fmt.Sprintf("%s-%s", leg.From.ID, leg.To.ID)
+		length := roundVDA(start.DistanceTo(end))
+		edge.Length = &length
+		if b.defaultMaxSpeed > 0 {
+			speed := b.defaultMaxSpeed
+			edge.MaxSpeed = &speed
+		}
+		return edge, nil
+	}
+
+	path := leg.Path
+	props := path.Properties
+	edge.EdgeID = string(path.ID)
+	edge.EdgeDescription = path.Name
+
+	// From/To 已是通行顺序，逆向通行时只需反转途经点
+	points := []domain.Position{start}
+	for i := range path.Waypoints {
+		waypoint := path.Waypoints[i]
+		if leg.Reversed {
+			waypoint = path.Waypoints[len(path.Waypoints)-1-i]
+		}
+		points = append(points, b.mapPosition(waypoint))
+	}
+	points = append(points, end)
+
+	// 直线边不需要轨迹
+	if len(points) > 2 || (path.CurveType != "" && path.CurveType != domain.CurveTypeLinear) {
+		edge.Trajectory = vda5050Trajectory(domain.CurveNURBS(points, path.CurveType))
+	}
+	length := roundVDA(domain.PolylineLength(domain.SampleCurve(points, path.CurveType, 32)))
+	edge.Length = &length
+
+	if speed, ok := propertyFloat(props, "max_speed"); ok && speed > 0 {
+		edge.MaxSpeed = &speed
+	} else if b.defaultMaxSpeed > 0 {
+		speed := b.defaultMaxSpeed
+		edge.MaxSpeed = &speed
+	}
+	edge.MaxHeight = optionalProperty(props, "max_height")
+	edge.MinHeight = optionalProperty(props, "min_height")
+	edge.MaxRotationSpeed = optionalProperty(props, "max_rotation_speed")
+	if orientation, ok := propertyFloat(props, "orientation"); ok {
+		orientation = b.mapAngle(orientation)
+		edge.Orientation = &orientation
+	}
+	if orientationType, ok := propertyString(props, "orientation_type"); ok {
+		edge.OrientationType = strings.ToUpper(orientationType)
+	}
+	if direction, ok := propertyString(props, "direction"); ok {
+		edge.Direction = direction
+	}
+	if rotationAllowed, ok := propertyBool(props, "rotation_allowed"); ok {
+		edge.RotationAllowed = &rotationAllowed
+	}
+
+	actions, err := vda5050Actions(props)
+	if err != nil {
+		return VDA5050Edge{}, err
+	}
+	edge.Actions = actions
+
+	return edge, nil
+}
+
+// vda5050Trajectory 将 NURBS 转换为 VDA 5050 轨迹
+func vda5050Trajectory(nurbs domain.NURBS) *VDA5050Trajectory {
+	trajectory := &VDA5050Trajectory{
+		Degree:        float64(nurbs.Degree),
+		KnotVector:    make([]float64, len(nurbs.Knots)),
+		ControlPoints: make([]VDA5050ControlPoint, len(nurbs.ControlPoints)),
+	}
+	for i, knot := range nurbs.Knots {
+		trajectory.KnotVector[i] = roundVDA(knot)
+	}
+	for i, point := range nurbs.ControlPoints {
+		trajectory.ControlPoints[i] = VDA5050ControlPoint{
+			X:      roundVDA(point.X),
+			Y:      roundVDA(point.Y),
+			Weight: roundVDA(nurbs.Weights[i]),
+		}
+	}
+	return trajectory
+}
+
+// vda5050Actions 读取动作属性
+// actions 为对象数组，字段 action_type、blocking_type（默认HARD）、description、parameters（对象或key/value数组）；
+// action 为字符串时表示单个无参数动作
+func vda5050Actions(props map[string]interface{}) ([]VDA5050Action, error) {
+	actions := []VDA5050Action{}
+
+	if actionType, ok := propertyString(props, "action"); ok {
+		actions = append(actions, VDA5050Action{
+			ActionType:   actionType,
+			ActionID:     uuid.New().String(),
+			BlockingType: VDA5050BlockingHard,
+		})
+	}
+
+	raw, ok := props["actions"]
+	if !ok || raw == nil {
+		return actions, nil
+	}
+	items, ok := raw.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("actions 属性必须是数组")
+	}
+
+	for i, item := range items {
+		fields, ok := item.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("actions[%d] 必须是对象", i)
+		}
+
+		action := VDA5050Action{
+			ActionID:     uuid.New().String(),
+			BlockingType: VDA5050BlockingHard,
+		}
+		if v, ok := firstProperty(fields, "action_type", "actionType"); ok {
+			action.ActionType = v
+		} else {
+			return nil, fmt.Errorf("actions[%d] 缺少 action_type", i)
+		}
+		if v, ok := firstProperty(fields, "action_id", "actionId"); ok {
+			action.ActionID = v
+		}
+		if v, ok := firstProperty(fields, "blocking_type", "blockingType"); ok {
+			action.BlockingType = strings.ToUpper(v)
+		}
+		if v, ok := firstProperty(fields, "description", "actionDescription"); ok {
+			action.ActionDescription = v
+		}
+
+		params, err := vda5050ActionParameters(fields["parameters"])
+		if err != nil {
+			return nil, fmt.Errorf("actions[%d]: %w", i, err)
+		}
+		action.ActionParameters = params
+
+		actions = append(actions, action)
+	}
+
+	return actions, nil
+}
+
+// vda5050ActionParameters 将参数对象或 key/value 数组转换为动作参数列表
+func vda5050ActionParameters(raw interface{}) ([]VDA5050ActionParameter, error) {
+	switch v := raw.(type) {
+	case nil:
+		return nil, nil
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		params := make([]VDA5050ActionParameter, 0, len(keys))
+		for _, key := range keys {
+			params = append(params, VDA5050ActionParameter{Key: key, Value: v[key]})
+		}
+		return params, nil
+	case []interface{}:
+		params := make([]VDA5050ActionParameter, 0, len(v))
+		for i, item := range v {
+			fields, ok := item.(map[string]interface{})
+			if !ok {
+				return nil, fmt.Errorf("parameters[%d] 必须是对象", i)
+			}
+			key, ok := propertyString(fields, "key")
+			if !ok {
+				return nil, fmt.Errorf("parameters[%d] 缺少 key", i)
+			}
+			params = append(params, VDA5050ActionParameter{Key: key, Value: fields["value"]})
+		}
+		return params, nil
+	default:
+		return nil, fmt.Errorf("parameters 必须是对象或数组")
+	}
+}
+
+// firstProperty 按顺序读取第一个存在的字符串属性
+func firstProperty(props map[string]interface{}, keys ...string) (string, bool) {
+	for _, key := range keys {
+		if v, ok := propertyString(props, key); ok {
+			return v, true
+		}
+	}
+	return "", false
+}
+
+// optionalProperty 读取可选数值属性
+func optionalProperty(props map[string]interface{}, key string) *float64 {
+	if v, ok := propertyFloat(props, key); ok {
+		return &v
+	}
+	return nil
+}
+
+// normalizeRadians 将角度规范到 [-π, π]
+func normalizeRadians(angle float64) float64 {
+	for angle > math.Pi {
+		angle -= 2 * math.Pi
+	}
+	for angle < -math.Pi {
+		angle += 2 * math.Pi
+	}
+	return angle
+}
+
+// roundVDA 保留6位小数，避免浮点噪声
+func roundVDA(v float64) float64 {
+	return math.Round(v*1e6) / 1e6
+}
@@ -0,0 +1,41 @@
+package services
+
+import (
+	"math"
+	"testing"
+
+	"robot-path-editor/internal/domain"
+)
+
+func TestVDA5050NodeTheta(t *testing.T) {
+	tests := []struct {
+		name  string
+		flipY bool
+		theta float64
+		want  float64
+	}{
+		{name: "不翻转", theta: math.Pi / 2, want: math.Pi / 2},
+		{name: "翻转Y轴取反", flipY: true, theta: math.Pi / 2, want: -math.Pi / 2},
+		{name: "翻转后归一化", flipY: true, theta: -3 * math.Pi / 2, want: -math.Pi / 2},
+		{name: "超出范围归一化", theta: 3 * math.Pi / 2, want: -math.Pi / 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := newVDA5050OrderBuilder(VDA5050OrderRequest{FlipY: tt.flipY})
+			node := domain.NewNode("A", string(domain.NodeTypeStation))
+			node.Properties = map[string]interface{}{"theta": tt.theta}
+
+			got, err := b.node(node, 0, true)
+			if err != nil {
+				t.Fatalf("node() error = %v", err)
+			}
+			if got.NodePosition.Theta == nil || math.Abs(*got.NodePosition.Theta-tt.want) > 1e-9 {
+				t.Errorf("theta = %v, want %v", got.NodePosition.Theta, tt.want)
+			}
+			if orientation := b.mapAngle(tt.theta); math.Abs(orientation-tt.want) > 1e-9 {
+				t.Errorf("mapAngle(%v) = %v, want %v", tt.theta, orientation, tt.want)
+			}
+		})
+	}
+}
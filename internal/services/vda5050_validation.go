@@ -0,0 +1,389 @@
+// Package services VDA 5050 订单本地校验
+//
+// 按 VDA 5050 v2.0 order.schema.json 校验必填字段、JSON类型、枚举和取值范围，
+// 并补充 schema 无法表达的订单语义：
+// - 节点 sequenceId 为偶数、边为奇数且连续递增
+// - 边的起止节点与相邻节点一致
+// - base（released）在前、horizon 在后
+// - NURBS 节点向量长度 = 控制点数 + 次数 + 1，且在 [0,1] 内单调不减
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+)
+
+// VDA5050ValidationError 订单校验错误，包含全部违规项
+type VDA5050ValidationError struct {
+	Violations []string `json:"violations"`
+}
+
+// Error 实现 error 接口
+func (e *VDA5050ValidationError) Error() string {
+	return fmt.Sprintf("VDA 5050 订单校验失败: %s", strings.Join(e.Violations, "; "))
+}
+
+var vda5050BlockingTypes = []string{VDA5050BlockingNone, VDA5050BlockingSoft, VDA5050BlockingHard}
+
+var vda5050OrientationTypes = []string{"GLOBAL", "TANGENTIAL"}
+
+// validateVDA5050Order 校验订单JSON
+func validateVDA5050Order(data []byte) error {
+	var raw interface{}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return &VDA5050ValidationError{Violations: []string{fmt.Sprintf("无效的JSON: %v", err)}}
+	}
+
+	v := &vda5050Validator{actionIDs: make(map[string]string)}
+	v.order(raw)
+
+	if len(v.violations) > 0 {
+		return &VDA5050ValidationError{Violations: v.violations}
+	}
+	return nil
+}
+
+// vda5050Validator 校验器，收集全部违规项而不是遇到第一个就返回
+type vda5050Validator struct {
+	violations []string
+	actionIDs  map[string]string
+}
+
+func (v *vda5050Validator) fail(path, format string, args ...interface{}) {
+	v.violations = append(v.violations, path+": "+fmt.Sprintf(format, args...))
+}
+
+// order 校验订单根对象
+func (v *vda5050Validator) order(raw interface{}) {
+	obj := v.object(raw, "order")
+	if obj == nil {
+		return
+	}
+
+	v.integer(obj, "headerId", "order", true, 0, math.MaxUint32)
+	if timestamp, ok := v.str(obj, "timestamp", "order", true); ok {
+		if _, err := time.Parse(time.RFC3339Nano, timestamp); err != nil {
+			v.fail("order.timestamp", "不是ISO8601时间: %s", timestamp)
+		}
+	}
+	v.str(obj, "version", "order", true)
+	v.str(obj, "manufacturer", "order", true)
+	v.str(obj, "serialNumber", "order", true)
+	if orderID, ok := v.str(obj, "orderId", "order", true); ok && orderID == "" {
+		v.fail("order.orderId", "不能为空")
+	}
+	v.integer(obj, "orderUpdateId", "order", true, 0, math.MaxUint32)
+	v.str(obj, "zoneSetId", "order", false)
+
+	nodes := v.array(obj, "nodes", "order", true)
+	edges := v.array(obj, "edges", "order", true)
+	if nodes == nil || edges == nil {
+		return
+	}
+	if len(nodes) == 0 {
+		v.fail("order.nodes", "至少需要一个节点")
+		return
+	}
+	if len(edges) != len(nodes)-1 {
+		v.fail("order.edges", "边数应为节点数减一（节点%d，边%d）", len(nodes), len(edges))
+	}
+
+	type element struct {
+		id       string
+		sequence float64
+		released bool
+	}
+	nodeInfo := make([]element, len(nodes))
+	for i, raw := range nodes {
+		path := fmt.Sprintf("order.nodes[%d]", i)
+		id, sequence, released := v.node(raw, path)
+		nodeInfo[i] = element{id, sequence, released}
+		if sequence >= 0 && int(sequence)%2 != 0 {
+			v.fail(path+".sequenceId", "节点的sequenceId必须为偶数")
+		}
+		if i > 0 && sequence >= 0 && nodeInfo[i-1].sequence >= 0 && sequence != nodeInfo[i-1].sequence+2 {
+			v.fail(path+".sequenceId", "sequenceId不连续")
+		}
+		if i > 0 && released && !nodeInfo[i-1].released {
+			v.fail(path+".released", "horizon 节点之后不能再出现 released 节点")
+		}
+	}
+	if !nodeInfo[0].released {
+		v.fail("order.nodes[0].released", "第一个节点必须已下发")
+	}
+
+	for i, raw := range edges {
+		path := fmt.Sprintf("order.edges[%d]", i)
+		sequence, released, startID, endID := v.edge(raw, path)
+		if i+1 >= len(nodeInfo) {
+			continue
+		}
+		if sequence >= 0 && sequence != nodeInfo[i].sequence+1 {
+			v.fail(path+".sequenceId", "应为相邻节点sequenceId加一")
+		}
+		if startID != nodeInfo[i].id {
+			v.fail(path+".startNodeId", "与前一节点不一致")
+		}
+		if endID != nodeInfo[i+1].id {
+			v.fail(path+".endNodeId", "与后一节点不一致")
+		}
+		if released != nodeInfo[i+1].released {
+			v.fail(path+".released", "边的下发状态必须与其终点节点一致")
+		}
+	}
+}
+
+// node 校验节点，返回 nodeId、sequenceId（无效时为-1）和 released
+func (v *vda5050Validator) node(raw interface{}, path string) (string, float64, bool) {
+	obj := v.object(raw, path)
+	if obj == nil {
+		return "", -1, false
+	}
+
+	id, _ := v.str(obj, "nodeId", path, true)
+	sequence, ok := v.integer(obj, "sequenceId", path, true, 0, math.MaxUint32)
+	if !ok {
+		sequence = -1
+	}
+	v.str(obj, "nodeDescription", path, false)
+	released, _ := v.boolean(obj, "released", path, true)
+
+	if rawPosition, ok := obj["nodePosition"]; ok {
+		positionPath := path + ".nodePosition"
+		if position := v.object(rawPosition, positionPath); position != nil {
+			v.number(position, "x", positionPath, true, math.Inf(-1), math.Inf(1))
+			v.number(position, "y", positionPath, true, math.Inf(-1), math.Inf(1))
+			v.number(position, "theta", positionPath, false, -math.Pi, math.Pi)
+			v.number(position, "allowedDeviationXY", positionPath, false, 0, math.Inf(1))
+			v.number(position, "allowedDeviationTheta", positionPath, false, 0, math.Pi)
+			v.str(position, "mapId", positionPath, true)
+			v.str(position, "mapDescription", positionPath, false)
+		}
+	}
+
+	v.actions(obj, path)
+	return id, sequence, released
+}
+
+// edge 校验边，返回 sequenceId（无效时为-1）、released、起止节点
+func (v *vda5050Validator) edge(raw interface{}, path string) (float64, bool, string, string) {
+	obj := v.object(raw, path)
+	if obj == nil {
+		return -1, false, "", ""
+	}
+
+	v.str(obj, "edgeId", path, true)
+	sequence, ok := v.integer(obj, "sequenceId", path, true, 0, math.MaxUint32)
+	if !ok {
+		sequence = -1
+	} else if int(sequence)%2 != 1 {
+		v.fail(path+".sequenceId", "边的sequenceId必须为奇数")
+	}
+	v.str(obj, "edgeDescription", path, false)
+	released, _ := v.boolean(obj, "released", path, true)
+	startID, _ := v.str(obj, "startNodeId", path, true)
+	endID, _ := v.str(obj, "endNodeId", path, true)
+
+	v.number(obj, "maxSpeed", path, false, 0, math.Inf(1))
+	v.number(obj, "maxHeight", path, false, 0, math.Inf(1))
+	v.number(obj, "minHeight", path, false, 0, math.Inf(1))
+	v.number(obj, "orientation", path, false, -math.Pi, math.Pi)
+	v.enum(obj, "orientationType", path, vda5050OrientationTypes)
+	v.str(obj, "direction", path, false)
+	v.boolean(obj, "rotationAllowed", path, false)
+	v.number(obj, "maxRotationSpeed", path, false, 0, math.Inf(1))
+	v.number(obj, "length", path, false, 0, math.Inf(1))
+
+	if rawTrajectory, ok := obj["trajectory"]; ok {
+		v.trajectory(rawTrajectory, path+".trajectory")
+	}
+
+	v.actions(obj, path)
+	return sequence, released, startID, endID
+}
+
+// trajectory 校验NURBS轨迹
+func (v *vda5050Validator) trajectory(raw interface{}, path string) {
+	obj := v.object(raw, path)
+	if obj == nil {
+		return
+	}
+
+	degree, degreeOK := v.number(obj, "degree", path, true, 1, math.Inf(1))
+	knots := v.array(obj, "knotVector", path, true)
+	points := v.array(obj, "controlPoints", path, true)
+
+	previous := 0.0
+	for i, raw := range knots {
+		knot, ok := raw.(float64)
+		if !ok || knot < 0 || knot > 1 {
+			v.fail(fmt.Sprintf("%s.knotVector[%d]", path, i), "必须是[0,1]内的数值")
+			continue
+		}
+		if knot < previous {
+			v.fail(fmt.Sprintf("%s.knotVector[%d]", path, i), "节点向量必须单调不减")
+		}
+		previous = knot
+	}
+
+	for i, raw := range points {
+		pointPath := fmt.Sprintf("%s.controlPoints[%d]", path, i)
+		if point := v.object(raw, pointPath); point != nil {
+			v.number(point, "x", pointPath, true, math.Inf(-1), math.Inf(1))
+			v.number(point, "y", pointPath, true, math.Inf(-1), math.Inf(1))
+			if weight, ok := v.number(point, "weight", pointPath, false, 0, math.Inf(1)); ok && weight == 0 {
+				v.fail(pointPath+".weight", "必须大于0")
+			}
+		}
+	}
+
+	if degreeOK && knots != nil && points != nil {
+		if len(points) < int(degree)+1 {
+			v.fail(path+".controlPoints", "控制点数至少为次数加一")
+		}
+		if len(knots) != len(points)+int(degree)+1 {
+			v.fail(path+".knotVector", "长度应为控制点数+次数+1（期望%d，实际%d）", len(points)+int(degree)+1, len(knots))
+		}
+	}
+}
+
+// actions 校验动作列表，actionId 在整个订单内必须唯一
+func (v *vda5050Validator) actions(obj map[string]interface{}, path string) {
+	actions := v.array(obj, "actions", path, true)
+	for i, raw := range actions {
+		actionPath := fmt.Sprintf("%s.actions[%d]", path, i)
+		action := v.object(raw, actionPath)
+		if action == nil {
+			continue
+		}
+
+		v.str(action, "actionType", actionPath, true)
+		if id, ok := v.str(action, "actionId", actionPath, true); ok {
+			if previous, exists := v.actionIDs[id]; exists {
+				v.fail(actionPath+".actionId", "与 %s 重复", previous)
+			}
+			v.actionIDs[id] = actionPath
+		}
+		v.str(action, "actionDescription", actionPath, false)
+		if _, ok := action["blockingType"]; !ok {
+			v.fail(actionPath+".blockingType", "缺少必填字段")
+		}
+		v.enum(action, "blockingType", actionPath, vda5050BlockingTypes)
+
+		if _, ok := action["actionParameters"]; ok {
+			for j, raw := range v.array(action, "actionParameters", actionPath, false) {
+				paramPath := fmt.Sprintf("%s.actionParameters[%d]", actionPath, j)
+				if param := v.object(raw, paramPath); param != nil {
+					v.str(param, "key", paramPath, true)
+					if _, ok := param["value"]; !ok {
+						v.fail(paramPath+".value", "缺少必填字段")
+					}
+				}
+			}
+		}
+	}
+}
+
+// === 基础类型校验 ===
+
+func (v *vda5050Validator) object(raw interface{}, path string) map[string]interface{} {
+	obj, ok := raw.(map[string]interface{})
+	if !ok {
+		v.fail(path, "必须是对象")
+		return nil
+	}
+	return obj
+}
+
+func (v *vda5050Validator) str(obj map[string]interface{}, key, path string, required bool) (string, bool) {
+	raw, ok := obj[key]
+	if !ok {
+		if required {
+			v.fail(path+"."+key, "缺少必填字段")
+		}
+		return "", false
+	}
+	s, ok := raw.(string)
+	if !ok {
+		v.fail(path+"."+key, "必须是字符串")
+		return "", false
+	}
+	return s, true
+}
+
+func (v *vda5050Validator) boolean(obj map[string]interface{}, key, path string, required bool) (bool, bool) {
+	raw, ok := obj[key]
+	if !ok {
+		if required {
+			v.fail(path+"."+key, "缺少必填字段")
+		}
+		return false, false
+	}
+	b, ok := raw.(bool)
+	if !ok {
+		v.fail(path+"."+key, "必须是布尔值")
+		return false, false
+	}
+	return b, true
+}
+
+func (v *vda5050Validator) number(obj map[string]interface{}, key, path string, required bool, min, max float64) (float64, bool) {
+	raw, ok := obj[key]
+	if !ok {
+		if required {
+			v.fail(path+"."+key, "缺少必填字段")
+		}
+		return 0, false
+	}
+	n, ok := raw.(float64)
+	if !ok {
+		v.fail(path+"."+key, "必须是数值")
+		return 0, false
+	}
+	if n < min || n > max {
+		v.fail(path+"."+key, "超出取值范围 [%g, %g]: %g", min, max, n)
+		return n, false
+	}
+	return n, true
+}
+
+func (v *vda5050Validator) integer(obj map[string]interface{}, key, path string, required bool, min, max float64) (float64, bool) {
+	n, ok := v.number(obj, key, path, required, min, max)
+	if ok && n != math.Trunc(n) {
+		v.fail(path+"."+key, "必须是整数")
+		return n, false
+	}
+	return n, ok
+}
+
+func (v *vda5050Validator) array(obj map[string]interface{}, key, path string, required bool) []interface{} {
+	raw, ok := obj[key]
+	if !ok {
+		if required {
+			v.fail(path+"."+key, "缺少必填字段")
+		}
+		return nil
+	}
+	items, ok := raw.([]interface{})
+	if !ok {
+		v.fail(path+"."+key, "必须是数组")
+		return nil
+	}
+	return items
+}
+
+func (v *vda5050Validator) enum(obj map[string]interface{}, key, path string, values []string) {
+	s, ok := v.str(obj, key, path, false)
+	if !ok {
+		return
+	}
+	for _, value := range values {
+		if s == value {
+			return
+		}
+	}
+	v.fail(path+"."+key, "取值必须为 %s 之一: %s", strings.Join(values, "/"), s)
+}
@@ -0,0 +1,106 @@
+// Package testutil 测试辅助
+//
+// - Postgres 提供集成测试用的 PostgreSQL：设置 PG_HOST 时使用已有服务器，否则启动嵌入式 PostgreSQL
+// - 两者都不可用时（如无法下载二进制、以 root 运行）跳过测试，不影响 go test ./...
+package testutil
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"strconv"
+	"testing"
+	"time"
+
+	embeddedpostgres "github.com/fergusstrange/embedded-postgres"
+)
+
+// PostgresServer 测试用 PostgreSQL 的连接参数
+type PostgresServer struct {
+	Host     string
+	Port     int
+	Database string
+	Username string
+	Password string
+}
+
+// DSN URL 形式的连接串，用于 GORM 和 pgx
+func (s PostgresServer) DSN() string {
+	u := url.URL{
+		Scheme:   "postgres",
+		User:     url.UserPassword(s.Username, s.Password),
+		Host:     net.JoinHostPort(s.Host, strconv.Itoa(s.Port)),
+		Path:     "/" + s.Database,
+		RawQuery: "sslmode=disable",
+	}
+	return u.String()
+}
+
+// Postgres 返回可用的 PostgreSQL，不可用时跳过测试；-short 时也跳过
+// PG_HOST、PG_PORT、PG_DATABASE、PG_USER、PG_PASSWORD 与 tests/api 的手工脚本一致
+func Postgres(t testing.TB) PostgresServer {
+	t.Helper()
+	if testing.Short() {
+		t.Skip("-short 模式跳过 PostgreSQL 集成测试")
+	}
+
+	if host := os.Getenv("PG_HOST"); host != "" {
+		s := PostgresServer{
+			Host:     host,
+			Port:     5432,
+			Database: envOr("PG_DATABASE", "postgres"),
+			Username: envOr("PG_USER", "postgres"),
+			Password: os.Getenv("PG_PASSWORD"),
+		}
+		if port, err := strconv.Atoi(os.Getenv("PG_PORT")); err == nil && port > 0 {
+			s.Port = port
+		}
+		return s
+	}
+
+	port, err := freePort()
+	if err != nil {
+		t.Skipf("没有可用端口，跳过 PostgreSQL 集成测试: %v", err)
+	}
+	s := PostgresServer{Host: "127.0.0.1", Port: port, Database: "robot_test", Username: "robot", Password: "p@ss:w/rd?"}
+
+	dir := t.TempDir()
+	var log bytes.Buffer
+	pg := embeddedpostgres.NewDatabase(embeddedpostgres.DefaultConfig().
+		Port(uint32(port)).
+		Database(s.Database).
+		Username(s.Username).
+		Password(s.Password).
+		RuntimePath(dir + "/runtime").
+		DataPath(dir + "/data").
+		StartTimeout(30 * time.Second).
+		Logger(&log))
+	if err := pg.Start(); err != nil {
+		t.Skipf("嵌入式 PostgreSQL 不可用，跳过集成测试（可设置 PG_HOST 使用已有服务器）: %v", err)
+	}
+	t.Cleanup(func() {
+		if err := pg.Stop(); err != nil {
+			t.Logf("停止嵌入式 PostgreSQL 失败: %v\n%s", err, log.String())
+		}
+	})
+	return s
+}
+
+func envOr(key, fallback string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return fallback
+}
+
+// freePort 由系统分配一个空闲端口
+func freePort() (int, error) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return 0, fmt.Errorf("监听端口失败: %w", err)
+	}
+	defer l.Close()
+	return l.Addr().(*net.TCPAddr).Port, nil
+}
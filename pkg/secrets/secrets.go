@@ -0,0 +1,139 @@
+// Package secrets 提供敏感配置的静态加密
+//
+// 设计参考：
+// - Grafana的数据源密码加密（secret_key + 信封格式）
+// - Kubernetes的 EncryptionConfiguration（当前密钥加密，历史密钥只用于解密）
+//
+// 特点：
+// 1. AES-256-GCM 加密，密文格式为 enc:v1:<密钥ID>:<base64(随机数|密文)>
+// 2. 密钥ID取密钥 SHA-256 的前 8 位十六进制，解密时按ID选择密钥，支持密钥轮换
+// 3. 加密时绑定上下文（如记录ID），密文不能挪到其他记录使用
+// 4. 未配置密钥时按明文读写，配置密钥后可把已有明文重新加密
+package secrets
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// prefix 密文前缀，不带前缀的值按明文处理
+const prefix = "enc:v1:"
+
+// Keyring 当前主密钥和用于解密的历史密钥；nil 或未配置当前密钥时不加密
+type Keyring struct {
+	current string // 当前密钥ID，为空时不加密
+	keys    map[string]cipher.AEAD
+}
+
+// NewKeyring 由当前主密钥和历史密钥创建密钥环，密钥为 32 字节的 base64 或十六进制编码
+func NewKeyring(current string, previous []string) (*Keyring, error) {
+	k := &Keyring{keys: make(map[string]cipher.AEAD)}
+	for i, encoded := range append([]string{current}, previous...) {
+		if strings.TrimSpace(encoded) == "" {
+			continue
+		}
+		id, aead, err := parseKey(encoded)
+		if err != nil {
+			if i == 0 {
+				return nil, fmt.Errorf("主密钥无效: %w", err)
+			}
+			return nil, fmt.Errorf("第 %d 个历史密钥无效: %w", i, err)
+		}
+		if i == 0 {
+			k.current = id
+		}
+		k.keys[id] = aead
+	}
+	return k, nil
+}
+
+// parseKey 解码密钥并计算密钥ID
+func parseKey(encoded string) (string, cipher.AEAD, error) {
+	encoded = strings.TrimSpace(encoded)
+	key, err := hex.DecodeString(encoded)
+	if err != nil || len(key) != 32 {
+		key, err = base64.StdEncoding.DecodeString(encoded)
+	}
+	if err != nil || len(key) != 32 {
+		return "", nil, fmt.Errorf("需要 32 字节的 base64 或十六进制编码密钥")
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return "", nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return "", nil, err
+	}
+	sum := sha256.Sum256(key)
+	return hex.EncodeToString(sum[:4]), aead, nil
+}
+
+// Enabled 是否配置了当前主密钥
+func (k *Keyring) Enabled() bool {
+	return k != nil && k.current != ""
+}
+
+// IsEncrypted 值是否为密文
+func IsEncrypted(value string) bool {
+	return strings.HasPrefix(value, prefix)
+}
+
+// Seal 用当前主密钥加密，context 在解密时必须一致；未配置主密钥或值为空时原样返回
+func (k *Keyring) Seal(plaintext, context string) (string, error) {
+	if !k.Enabled() || plaintext == "" {
+		return plaintext, nil
+	}
+	aead := k.keys[k.current]
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("生成随机数失败: %w", err)
+	}
+	sealed := aead.Seal(nonce, nonce, []byte(plaintext), []byte(context))
+	return prefix + k.current + ":" + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// Open 解密 Seal 生成的密文，明文原样返回
+func (k *Keyring) Open(value, context string) (string, error) {
+	if !IsEncrypted(value) {
+		return value, nil
+	}
+	id, encoded, ok := strings.Cut(strings.TrimPrefix(value, prefix), ":")
+	if !ok {
+		return "", fmt.Errorf("密文格式无效")
+	}
+	var aead cipher.AEAD
+	if k != nil {
+		aead = k.keys[id]
+	}
+	if aead == nil {
+		return "", fmt.Errorf("缺少密钥 %s，请在主密钥或历史密钥中配置", id)
+	}
+	sealed, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil || len(sealed) < aead.NonceSize() {
+		return "", fmt.Errorf("密文格式无效")
+	}
+	nonce, data := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+	plaintext, err := aead.Open(nil, nonce, data, []byte(context))
+	if err != nil {
+		return "", fmt.Errorf("解密失败（密钥 %s）: %w", id, err)
+	}
+	return string(plaintext), nil
+}
+
+// NeedsRotation 值是否需要重新加密：配置主密钥时明文和历史密钥的密文需要，未配置时密文需要解密为明文
+func (k *Keyring) NeedsRotation(value string) bool {
+	if value == "" {
+		return false
+	}
+	if !k.Enabled() {
+		return IsEncrypted(value)
+	}
+	return !strings.HasPrefix(value, prefix+k.current+":")
+}
@@ -0,0 +1,226 @@
+package secrets
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"strings"
+	"testing"
+)
+
+var (
+	key1 = hex.EncodeToString([]byte("0123456789abcdef0123456789abcdef"))
+	key2 = base64.StdEncoding.EncodeToString([]byte("fedcba9876543210fedcba9876543210"))
+)
+
+func mustKeyring(t *testing.T, current string, previous ...string) *Keyring {
+	t.Helper()
+	k, err := NewKeyring(current, previous)
+	if err != nil {
+		t.Fatalf("NewKeyring() error = %v", err)
+	}
+	return k
+}
+
+func TestNewKeyring(t *testing.T) {
+	tests := []struct {
+		name        string
+		current     string
+		previous    []string
+		wantEnabled bool
+		wantErr     string
+	}{
+		{"十六进制密钥", key1, nil, true, ""},
+		{"base64 密钥", key2, nil, true, ""},
+		{"首尾空白", "  " + key1 + "\n", nil, true, ""},
+		{"未配置", "", nil, false, ""},
+		{"只有历史密钥", "", []string{key1}, false, ""},
+		{"长度不足", hex.EncodeToString([]byte("short")), nil, false, "主密钥无效"},
+		{"不是编码后的密钥", "not-a-key", nil, false, "主密钥无效"},
+		{"历史密钥无效", key1, []string{key2, "bad"}, false, "第 2 个历史密钥无效"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			k, err := NewKeyring(tt.current, tt.previous)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("NewKeyring() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("NewKeyring() error = %v", err)
+			}
+			if k.Enabled() != tt.wantEnabled {
+				t.Errorf("Enabled() = %v, want %v", k.Enabled(), tt.wantEnabled)
+			}
+		})
+	}
+}
+
+func TestSealOpen(t *testing.T) {
+	k := mustKeyring(t, key1)
+	tests := []struct {
+		name      string
+		plaintext string
+		context   string
+	}{
+		{"普通密码", "p@ssw0rd", "conn-1"},
+		{"Unicode", "密码🔑", "conn-1"},
+		{"连接属性", "client-key-pass", "conn-1/sslpassword"},
+		{"空上下文", "secret", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sealed, err := k.Seal(tt.plaintext, tt.context)
+			if err != nil {
+				t.Fatalf("Seal() error = %v", err)
+			}
+			if !IsEncrypted(sealed) || strings.Contains(sealed, tt.plaintext) {
+				t.Fatalf("Seal() = %q, 应为不含明文的密文", sealed)
+			}
+			again, _ := k.Seal(tt.plaintext, tt.context)
+			if again == sealed {
+				t.Error("两次加密结果相同，随机数未生效")
+			}
+			opened, err := k.Open(sealed, tt.context)
+			if err != nil {
+				t.Fatalf("Open() error = %v", err)
+			}
+			if opened != tt.plaintext {
+				t.Errorf("Open() = %q, want %q", opened, tt.plaintext)
+			}
+		})
+	}
+}
+
+func TestOpenContextBinding(t *testing.T) {
+	k := mustKeyring(t, key1)
+	sealed, err := k.Seal("secret", "conn-1")
+	if err != nil {
+		t.Fatalf("Seal() error = %v", err)
+	}
+	tests := []struct {
+		name    string
+		context string
+		wantErr bool
+	}{
+		{"相同记录", "conn-1", false},
+		{"挪到其他记录", "conn-2", true},
+		{"挪到属性", "conn-1/sslpassword", true},
+		{"空上下文", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := k.Open(sealed, tt.context); (err != nil) != tt.wantErr {
+				t.Errorf("Open(%q) error = %v, wantErr %v", tt.context, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestOpenInvalid(t *testing.T) {
+	k := mustKeyring(t, key1)
+	sealed, _ := k.Seal("secret", "c")
+	id := strings.Split(sealed, ":")[2]
+	tests := []struct {
+		name    string
+		keyring *Keyring
+		value   string
+		wantErr string
+	}{
+		{"缺少分隔符", k, prefix + "abc", "格式无效"},
+		{"不是 base64", k, prefix + id + ":!!!", "格式无效"},
+		{"长度不足随机数", k, prefix + id + ":" + base64.StdEncoding.EncodeToString([]byte("x")), "格式无效"},
+		{"密文被篡改", k, sealed[:len(sealed)-4] + "AAA=", "解密失败"},
+		{"未知密钥", k, prefix + "00000000:" + strings.Split(sealed, ":")[3], "缺少密钥 00000000"},
+		{"其他密钥环", mustKeyring(t, key2), sealed, "缺少密钥 " + id},
+		{"未配置密钥", nil, sealed, "缺少密钥"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := tt.keyring.Open(tt.value, "c")
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Open() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestPlaintextPassthrough(t *testing.T) {
+	tests := []struct {
+		name    string
+		keyring *Keyring
+		value   string
+	}{
+		{"nil 密钥环", nil, "secret"},
+		{"未配置主密钥", mustKeyring(t, ""), "secret"},
+		{"空值不加密", mustKeyring(t, key1), ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sealed, err := tt.keyring.Seal(tt.value, "c")
+			if err != nil || sealed != tt.value {
+				t.Errorf("Seal() = %q, %v, want %q", sealed, err, tt.value)
+			}
+			opened, err := tt.keyring.Open(tt.value, "c")
+			if err != nil || opened != tt.value {
+				t.Errorf("Open() = %q, %v, want %q", opened, err, tt.value)
+			}
+		})
+	}
+	// 配置密钥后仍能读取旧的明文
+	if opened, err := mustKeyring(t, key1).Open("legacy", "c"); err != nil || opened != "legacy" {
+		t.Errorf("Open(明文) = %q, %v, want legacy", opened, err)
+	}
+}
+
+// TestRotation 换主密钥后旧密文可用历史密钥解密，NeedsRotation 标出需要重新加密的值
+func TestRotation(t *testing.T) {
+	old := mustKeyring(t, key1)
+	sealedOld, _ := old.Seal("secret", "conn-1")
+
+	rotated := mustKeyring(t, key2, key1)
+	sealedNew, _ := rotated.Seal("secret", "conn-1")
+
+	tests := []struct {
+		name         string
+		keyring      *Keyring
+		value        string
+		wantRotation bool
+		wantOpen     string
+		wantOpenErr  bool
+	}{
+		{"历史密钥的密文", rotated, sealedOld, true, "secret", false},
+		{"当前密钥的密文", rotated, sealedNew, false, "secret", false},
+		{"明文需要加密", rotated, "plain", true, "plain", false},
+		{"空值", rotated, "", false, "", false},
+		{"去掉历史密钥后无法解密", mustKeyring(t, key2), sealedOld, true, "", true},
+		{"未配置主密钥时密文需要解密", mustKeyring(t, "", key1), sealedOld, true, "secret", false},
+		{"未配置主密钥时明文不变", mustKeyring(t, "", key1), "plain", false, "plain", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.keyring.NeedsRotation(tt.value); got != tt.wantRotation {
+				t.Errorf("NeedsRotation() = %v, want %v", got, tt.wantRotation)
+			}
+			opened, err := tt.keyring.Open(tt.value, "conn-1")
+			if (err != nil) != tt.wantOpenErr {
+				t.Fatalf("Open() error = %v, wantErr %v", err, tt.wantOpenErr)
+			}
+			if err != nil {
+				return
+			}
+			if opened != tt.wantOpen {
+				t.Errorf("Open() = %q, want %q", opened, tt.wantOpen)
+			}
+			// 重新加密后使用当前密钥，不再需要轮换
+			resealed, err := tt.keyring.Seal(opened, "conn-1")
+			if err != nil {
+				t.Fatalf("Seal() error = %v", err)
+			}
+			if tt.keyring.NeedsRotation(resealed) {
+				t.Errorf("重新加密后 NeedsRotation(%q) = true", resealed)
+			}
+		})
+	}
+}
@@ -7,6 +7,9 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
+	"os"
+	"strconv"
 	"time"
 )
 
@@ -71,6 +74,9 @@ func main() {
 	// 测试外部表验证
 	testTableValidation()
 
+	// 测试PostgreSQL外部表（需要本地PostgreSQL）
+	testPostgresTable()
+
 	fmt.Println("\n✅ 所有API测试完成！")
 }
 
@@ -251,3 +257,62 @@ func testTableValidation() {
 	body, _ := io.ReadAll(resp.Body)
 	fmt.Printf("✅ 验证外部表响应: %s\n", string(body))
 }
+
+// testPostgresTable 连接本地PostgreSQL并验证表结构
+// 通过 PG_HOST、PG_PORT、PG_DATABASE、PG_USER、PG_PASSWORD、PG_TABLE 指定，未设置 PG_HOST 时跳过
+func testPostgresTable() {
+	fmt.Println("\n🐘 测试PostgreSQL外部表...")
+
+	host := os.Getenv("PG_HOST")
+	if host == "" {
+		fmt.Println("⏭️  未设置PG_HOST，跳过")
+		return
+	}
+	port, _ := strconv.Atoi(os.Getenv("PG_PORT"))
+	if port == 0 {
+		port = 5432
+	}
+	table := os.Getenv("PG_TABLE")
+	if table == "" {
+		table = "robot_nodes"
+	}
+
+	conn := CreateConnectionRequest{
+		Name:     "测试PostgreSQL连接",
+		Type:     "postgres",
+		Host:     host,
+		Port:     port,
+		Database: os.Getenv("PG_DATABASE"),
+		Username: os.Getenv("PG_USER"),
+		Password: os.Getenv("PG_PASSWORD"),
+	}
+	connJSON, _ := json.Marshal(conn)
+	resp, err := http.Post(baseURL+"/database/connections", "application/json", bytes.NewBuffer(connJSON))
+	if err != nil {
+		fmt.Printf("❌ 创建PostgreSQL连接失败: %v\n", err)
+		return
+	}
+	defer resp.Body.Close()
+
+	var created struct {
+		Connection struct {
+			ID string `json:"id"`
+		} `json:"connection"`
+	}
+	body, _ := io.ReadAll(resp.Body)
+	if err := json.Unmarshal(body, &created); err != nil || created.Connection.ID == "" {
+		fmt.Printf("❌ 创建PostgreSQL连接响应异常: %s\n", string(body))
+		return
+	}
+
+	query := url.Values{"connection_id": {created.Connection.ID}, "table_name": {table}}
+	resp, err = http.Get(baseURL + "/sync/validate-table?" + query.Encode())
+	if err != nil {
+		fmt.Printf("❌ 验证PostgreSQL表失败: %v\n", err)
+		return
+	}
+	defer resp.Body.Close()
+
+	body, _ = io.ReadAll(resp.Body)
+	fmt.Printf("✅ 验证PostgreSQL表响应: %s\n", string(body))
+}